@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 )
@@ -11,10 +12,24 @@ type Config struct {
 	Port           string
 	AllowedOrigins string
 	FrontendURL    string
+	PublicURL      string // Externally reachable base URL of this API (used for embed links)
+
+	// Instance administrators (comma-separated emails, e.g. for instance-wide templates)
+	InstanceAdminEmails string
+
+	// Database ("mongodb", "postgres" or "sqlite")
+	DatabaseDriver string
+	// PostgreSQL connection string, or the database file path when DatabaseDriver is "sqlite"
+	DatabaseURL string
+
+	// Directory for the state of a single-node deployment (SQLite database, local files, generated keys)
+	DataDir string
 
 	// MongoDB
 	MongoDBURI      string
 	MongoDBDatabase string
+	// Apply pending migrations when the server starts (otherwise run `migrate up`)
+	MigrateOnStartup bool
 
 	// JWT
 	JWTSecret          string
@@ -26,31 +41,67 @@ type Config struct {
 	GoogleClientSecret string
 	GoogleRedirectURL  string
 
+	// File storage ("gcs" or "local")
+	StorageDriver string
 	// Google Cloud Storage (uses Application Default Credentials)
 	GCSBucketName string
 
+	// File holding the encryption master key, generated on first boot (ENCRYPTION_MASTER_KEY takes precedence)
+	EncryptionKeyFile string
+
 	// Rate Limiting
 	RateLimitGlobal int
 	RateLimitAuth   int
 
+	// Trash (days before trashed items are purged, unless a workspace overrides it)
+	TrashRetentionDays int
+	TrashPurgeInterval time.Duration
+
+	// Webhooks (how often queued deliveries are sent; new events are sent right away)
+	WebhookPollInterval time.Duration
+
+	// Email ("smtp", "file" to write .eml files for development, or "" to send none)
+	MailDriver       string
+	MailFrom         string
+	MailDir          string // Directory of the file driver (defaults to DataDir/mail)
+	MailPollInterval time.Duration
+	// SMTP server; leave the username empty for a local catcher such as Mailpit (localhost:1025)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// Notification digests (how often unread notifications are emailed; 0 disables them)
+	NotificationDigestInterval time.Duration
+
 	// Live Collaboration Service
-	LiveCollabURL          string
-	LiveCollabWSURL        string
-	LiveCollabJWTSecret    string
-	LiveCollabTokenIssuer  string
+	LiveCollabURL           string
+	LiveCollabWSURL         string
+	LiveCollabJWTSecret     string
+	LiveCollabTokenIssuer   string
 	LiveCollabTokenAudience string
 }
 
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		// Server
 		Port:           getEnv("PORT", "8080"),
 		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:3000"),
 		FrontendURL:    getEnv("FRONTEND_URL", "http://localhost:3000"),
+		PublicURL:      getEnv("PUBLIC_URL", "http://localhost:8080"),
+
+		// Instance administrators
+		InstanceAdminEmails: getEnv("INSTANCE_ADMIN_EMAILS", ""),
+
+		// Database
+		DatabaseDriver: getEnv("DATABASE_DRIVER", "mongodb"),
+		DatabaseURL:    getEnv("DATABASE_URL", ""),
+		DataDir:        getEnv("DATA_DIR", "./data"),
 
 		// MongoDB
-		MongoDBURI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-		MongoDBDatabase: getEnv("MONGODB_DATABASE", "flowstry"),
+		MongoDBURI:       getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+		MongoDBDatabase:  getEnv("MONGODB_DATABASE", "flowstry"),
+		MigrateOnStartup: getEnvBool("MIGRATE_ON_STARTUP", true),
 
 		// JWT
 		JWTSecret:          getEnv("JWT_SECRET", "change-me-in-production"),
@@ -62,13 +113,37 @@ func Load() *Config {
 		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/auth/google/callback"),
 
-		// Google Cloud Storage (uses ADC on Cloud Run)
+		// File storage; Google Cloud Storage uses ADC on Cloud Run
+		StorageDriver: getEnv("STORAGE_DRIVER", "gcs"),
 		GCSBucketName: getEnv("GCS_BUCKET_NAME", ""),
 
+		// Encryption
+		EncryptionKeyFile: getEnv("ENCRYPTION_KEY_FILE", ""),
+
 		// Rate Limiting
 		RateLimitGlobal: getEnvInt("RATE_LIMIT_GLOBAL", 100),
 		RateLimitAuth:   getEnvInt("RATE_LIMIT_AUTH", 20),
 
+		// Trash
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeInterval: getDurationEnv("TRASH_PURGE_INTERVAL", time.Hour),
+
+		// Webhooks
+		WebhookPollInterval: getDurationEnv("WEBHOOK_POLL_INTERVAL", 10*time.Second),
+
+		// Email
+		MailDriver:       getEnv("MAIL_DRIVER", ""),
+		MailFrom:         getEnv("MAIL_FROM", "Flowstry <no-reply@localhost>"),
+		MailDir:          getEnv("MAIL_DIR", ""),
+		MailPollInterval: getDurationEnv("MAIL_POLL_INTERVAL", 10*time.Second),
+		SMTPHost:         getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+
+		// Notification digests
+		NotificationDigestInterval: getDurationEnv("NOTIFICATION_DIGEST_INTERVAL", 24*time.Hour),
+
 		// Live Collaboration Service
 		LiveCollabURL:           getEnv("LIVE_COLLAB_URL", "http://localhost:8081"),
 		LiveCollabWSURL:         getEnv("LIVE_COLLAB_WS_URL", ""),
@@ -76,6 +151,22 @@ func Load() *Config {
 		LiveCollabTokenIssuer:   getEnv("LIVE_COLLAB_TOKEN_ISSUER", "flowstry-backend"),
 		LiveCollabTokenAudience: getEnv("LIVE_COLLAB_TOKEN_AUDIENCE", "live-collab-service"),
 	}
+
+	// Embedded single-node mode keeps everything under DataDir unless configured otherwise
+	if cfg.DatabaseDriver == "sqlite" {
+		if cfg.DatabaseURL == "" {
+			cfg.DatabaseURL = filepath.Join(cfg.DataDir, "flowstry.db")
+		}
+		if cfg.EncryptionKeyFile == "" {
+			cfg.EncryptionKeyFile = filepath.Join(cfg.DataDir, "master.key")
+		}
+	}
+
+	if cfg.MailDir == "" {
+		cfg.MailDir = filepath.Join(cfg.DataDir, "mail")
+	}
+
+	return cfg
 }
 
 func getEnv(key, defaultValue string) string {
@@ -94,6 +185,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
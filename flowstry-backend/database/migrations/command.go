@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Usage describes the migrate command
+const Usage = `usage: flowstry-backend migrate <command>
+
+commands:
+  up         apply all pending migrations (and sync MongoDB indexes)
+  down [n]   revert the last n applied migrations (default 1)
+  status     list migrations and whether they are applied`
+
+// Migrator applies, reverts and lists versioned migrations. Runner implements it for MongoDB;
+// SQL backends provide their own.
+type Migrator interface {
+	// Up applies every pending migration and returns the ones applied
+	Up(ctx context.Context) ([]Status, error)
+	// Down reverts the last steps applied migrations and returns the ones reverted
+	Down(ctx context.Context, steps int) ([]Status, error)
+	Status(ctx context.Context) ([]Status, error)
+}
+
+// RunCommand executes a migrate subcommand (up, down [n], status), writing progress to out
+func RunCommand(ctx context.Context, runner Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", Usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := runner.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Fprintf(out, "reverted %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+		return nil
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%4d  %-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], Usage)
+	}
+}
@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes declares every index the application relies on, by collection.
+// SyncIndexes creates whatever is missing; changing or dropping an existing index needs a migration.
+var indexes = map[string][]mongo.IndexModel{
+	"users": {
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "google_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"google_id": bson.M{"$type": "string"}}),
+		},
+	},
+	"refresh_tokens": {
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			// TTL index for auto-expiry
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"personal_access_tokens": {
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	},
+	"workspaces": {
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	},
+	"workspace_members": {
+		{
+			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	},
+	"workspace_invites": {
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+		},
+		{
+			// TTL index for auto-expiry
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"folders": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "parent_folder_id", Value: 1}, {Key: "deleted_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "deleted_at", Value: 1}, {Key: "updated_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "trash_batch_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+	"diagrams": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "deleted_at", Value: 1}, {Key: "updated_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "folder_id", Value: 1}, {Key: "deleted_at", Value: 1}},
+		},
+		{
+			// Multikey index backing tag filters
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "tags", Value: 1}},
+		},
+		{
+			// Template gallery
+			Keys:    bson.D{{Key: "template_scope", Value: 1}, {Key: "workspace_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"template_scope": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "trash_batch_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+	"workspace_tags": {
+		{
+			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+	"share_links": {
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "diagram_id", Value: 1}},
+		},
+	},
+	"embed_tokens": {
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "diagram_id", Value: 1}},
+		},
+	},
+	"diagram_access": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "diagram_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_opened_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "diagram_id", Value: 1}},
+		},
+	},
+	"diagram_stars": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "diagram_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "diagram_id", Value: 1}},
+		},
+	},
+	"audit_log": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "target_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	},
+	"activity": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "target_id", Value: 1}, {Key: "updated_at", Value: -1}},
+		},
+	},
+	"comments": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "diagram_id", Value: 1}, {Key: "thread_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	},
+	"mentions": {
+		{
+			Keys:    bson.D{{Key: "source", Value: 1}, {Key: "source_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "diagram_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	},
+	"notifications": {
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "read_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "read_at", Value: 1}, {Key: "digested_at", Value: 1}, {Key: "user_id", Value: 1}, {Key: "updated_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}},
+		},
+	},
+	"email_outbox": {
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "sent_at", Value: 1}},
+		},
+	},
+	"webhooks": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	},
+	"webhook_deliveries": {
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "completed_at", Value: 1}},
+		},
+	},
+}
+
+// SyncIndexes creates the declared indexes that do not exist yet
+func SyncIndexes(ctx context.Context, db *mongo.Database) error {
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("creating indexes on %s: %w", collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package migrations applies versioned schema changes to the MongoDB database and keeps the
+// declared indexes in sync. Applied versions are recorded in the schema_migrations collection.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockID               = "migrate"
+	// lockTTL bounds how long a crashed run can block others. A live run renews the lock
+	// every lockTTL/4, so it stays held however long the migrations take.
+	lockTTL = 2 * time.Minute
+)
+
+var (
+	ErrLocked         = errors.New("another migration run is in progress")
+	ErrIrreversible   = errors.New("migration cannot be reverted")
+	ErrUnknownVersion = errors.New("database has migrations this binary does not know about")
+)
+
+// Migration is a single versioned schema change. Down may be nil for changes that cannot be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// record is the schema_migrations document of an applied migration
+type record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Runner applies and reverts migrations against a database
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	lockTTL    time.Duration
+}
+
+// NewRunner creates a runner for all registered migrations
+func NewRunner(db *mongo.Database) *Runner {
+	return newRunner(db, registry)
+}
+
+func newRunner(db *mongo.Database, list []Migration) *Runner {
+	sorted := append([]Migration(nil), list...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Runner{db: db, migrations: sorted, lockTTL: lockTTL}
+}
+
+// Up applies every pending migration in version order, then syncs the declared indexes.
+// It returns the migrations that were applied.
+func (r *Runner) Up(ctx context.Context) ([]Status, error) {
+	var applied []Status
+	err := r.withLock(ctx, func() error {
+		done, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := m.Up(ctx, r.db); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			appliedAt := time.Now()
+			_, err := r.db.Collection(migrationsCollection).InsertOne(ctx, record{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: appliedAt,
+			})
+			if err != nil {
+				return fmt.Errorf("recording migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, Status{Version: m.Version, Name: m.Name, AppliedAt: &appliedAt})
+		}
+
+		return SyncIndexes(ctx, r.db)
+	})
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations, newest first. It returns the migrations reverted.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Status, error) {
+	var reverted []Status
+	err := r.withLock(ctx, func() error {
+		done, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, ErrIrreversible)
+			}
+			if err := m.Down(ctx, r.db); err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			if _, err := r.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+				return fmt.Errorf("unrecording migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, Status{Version: m.Version, Name: m.Name})
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied (nil when pending)
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	done, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		s := Status{Version: m.Version, Name: m.Name}
+		if rec, ok := done[m.Version]; ok {
+			appliedAt := rec.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// applied returns the recorded migrations keyed by version
+func (r *Runner) applied(ctx context.Context) (map[int]record, error) {
+	cursor, err := r.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool, len(r.migrations))
+	for _, m := range r.migrations {
+		known[m.Version] = true
+	}
+
+	done := make(map[int]record, len(records))
+	for _, rec := range records {
+		if !known[rec.Version] {
+			return nil, fmt.Errorf("%w (version %d)", ErrUnknownVersion, rec.Version)
+		}
+		done[rec.Version] = rec
+	}
+	return done, nil
+}
+
+// withLock runs fn while holding the cluster-wide migration lock
+func (r *Runner) withLock(ctx context.Context, fn func() error) error {
+	locks := r.db.Collection(lockCollection)
+	owner := primitive.NewObjectID()
+	now := time.Now()
+
+	// Matches only a missing or expired lock; a live lock makes the upsert hit the duplicate _id
+	err := locks.FindOneAndUpdate(ctx,
+		bson.M{"_id": lockID, "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": owner, "locked_at": now, "expires_at": now.Add(r.lockTTL)}},
+		options.FindOneAndUpdate().SetUpsert(true),
+	).Err()
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrLocked
+		}
+		return err
+	}
+
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		r.renewLock(ctx, locks, owner, stop)
+	}()
+
+	defer func() {
+		close(stop)
+		<-renewed
+		// Release with a fresh context so a cancelled run still unlocks
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, _ = locks.DeleteOne(releaseCtx, bson.M{"_id": lockID, "owner": owner})
+	}()
+
+	return fn()
+}
+
+// renewLock pushes the lock's expiry forward until stop is closed. A failed renewal is retried
+// on the next tick; the lock only lapses if renewals keep failing for a whole lockTTL.
+func (r *Runner) renewLock(ctx context.Context, locks *mongo.Collection, owner primitive.ObjectID, stop <-chan struct{}) {
+	ticker := time.NewTicker(r.lockTTL / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			_, _ = locks.UpdateOne(ctx,
+				bson.M{"_id": lockID, "owner": owner},
+				bson.M{"$set": bson.M{"expires_at": now.Add(r.lockTTL)}},
+			)
+		}
+	}
+}
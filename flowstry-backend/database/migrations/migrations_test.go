@@ -0,0 +1,197 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testURIEnv names the MongoDB server the integration tests run against, e.g.
+// mongodb://localhost:27017
+const testURIEnv = "FLOWSTRY_TEST_MONGODB_URI"
+
+// openTestDatabase returns a fresh database that is dropped when the test ends
+func openTestDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv(testURIEnv)
+	if uri == "" {
+		t.Skipf("%s not set", testURIEnv)
+	}
+	ctx := context.Background()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db := client.Database("flowstry_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return db
+}
+
+// testMigrations mirrors the registry: version 1 cannot be reverted, version 2 can
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 2,
+			Name:    "add_flag",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection("things").UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"flag": true}})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection("things").UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"flag": ""}})
+				return err
+			},
+		},
+		{
+			Version: 1,
+			Name:    "seed_things",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection("things").InsertOne(ctx, bson.M{"name": "first"})
+				return err
+			},
+		},
+	}
+}
+
+func versions(statuses []Status) []int {
+	var list []int
+	for _, s := range statuses {
+		list = append(list, s.Version)
+	}
+	return list
+}
+
+func TestRunner(t *testing.T) {
+	db := openTestDatabase(t)
+	ctx := context.Background()
+	runner := newRunner(db, testMigrations())
+
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(statuses); len(got) != 2 || got[0] != 1 || got[1] != 2 || statuses[0].AppliedAt != nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("status before up = %+v", statuses)
+	}
+
+	// Up applies the pending migrations in version order, and only once
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(applied); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("applied = %v", got)
+	}
+	if n, _ := db.Collection("things").CountDocuments(ctx, bson.M{"flag": true}); n != 1 {
+		t.Errorf("migrated documents = %d, want 1", n)
+	}
+	if applied, err := runner.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second up = %v, %v", applied, err)
+	}
+
+	statuses, err = runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("status of %d_%s: not applied", s.Version, s.Name)
+		}
+	}
+
+	// Down reverts the newest migration; version 1 has no Down and stays applied
+	reverted, err := runner.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(reverted); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("reverted = %v", got)
+	}
+	if n, _ := db.Collection("things").CountDocuments(ctx, bson.M{"flag": bson.M{"$exists": true}}); n != 0 {
+		t.Errorf("documents still flagged = %d", n)
+	}
+	if _, err := runner.Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("down past version 1: err = %v, want ErrIrreversible", err)
+	}
+	statuses, err = runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("status after down = %+v", statuses)
+	}
+
+	// A database migrated by a newer binary is refused
+	if _, err := newRunner(db, testMigrations()[:1]).Status(ctx); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("unknown version: err = %v", err)
+	}
+}
+
+func TestRunnerLock(t *testing.T) {
+	db := openTestDatabase(t)
+	ctx := context.Background()
+	locks := db.Collection(lockCollection)
+
+	// A live lock held by another run blocks Up and Down
+	_, err := locks.InsertOne(ctx, bson.M{"_id": lockID, "owner": primitive.NewObjectID(), "expires_at": time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := newRunner(db, testMigrations())
+	if _, err := runner.Up(ctx); !errors.Is(err, ErrLocked) {
+		t.Fatalf("up while locked: err = %v", err)
+	}
+	if _, err := runner.Down(ctx, 1); !errors.Is(err, ErrLocked) {
+		t.Fatalf("down while locked: err = %v", err)
+	}
+
+	// An expired lock, left by a crashed run, is taken over and released afterwards
+	if _, err := locks.UpdateOne(ctx, bson.M{"_id": lockID}, bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("up over an expired lock: %v", err)
+	}
+	if n, _ := locks.CountDocuments(ctx, bson.M{}); n != 0 {
+		t.Errorf("lock kept after the run")
+	}
+}
+
+func TestRunnerRenewsLock(t *testing.T) {
+	db := openTestDatabase(t)
+	ctx := context.Background()
+
+	// A run that outlasts the lock TTL keeps the lock, so a second run cannot start
+	var second error
+	slow := []Migration{{
+		Version: 1,
+		Name:    "slow",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			time.Sleep(time.Second)
+			other := newRunner(db, nil)
+			other.lockTTL = 200 * time.Millisecond
+			_, second = other.Up(ctx)
+			return nil
+		},
+	}}
+	runner := newRunner(db, slow)
+	runner.lockTTL = 200 * time.Millisecond
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(second, ErrLocked) {
+		t.Errorf("second run: err = %v, want ErrLocked", second)
+	}
+}
@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// registry lists every migration. Versions are never reused or reordered once released.
+var registry = []Migration{
+	{
+		Version: 1,
+		Name:    "reconcile_folder_membership",
+		Up:      reconcileFolderMembership,
+	},
+	{
+		Version: 2,
+		Name:    "scope_personal_access_tokens",
+		Up:      scopePersonalAccessTokens,
+		Down:    unscopePersonalAccessTokens,
+	},
+}
+
+// reconcileFolderMembership folds the legacy folders.diagram_ids arrays into diagrams.folder_id and
+// drops them. A diagram that already has a folder keeps it; one listed in several folders goes to
+// the most recently updated one. Diagrams pointing at folders that no longer exist move to the root.
+func reconcileFolderMembership(ctx context.Context, db *mongo.Database) error {
+	folders := db.Collection("folders")
+	diagrams := db.Collection("diagrams")
+
+	cursor, err := folders.Find(ctx,
+		bson.M{"diagram_ids": bson.M{"$exists": true}, "deleted_at": nil},
+		options.Find().
+			SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+			SetProjection(bson.M{"workspace_id": 1, "diagram_ids": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID          primitive.ObjectID   `bson:"_id"`
+			WorkspaceID primitive.ObjectID   `bson:"workspace_id"`
+			DiagramIDs  []primitive.ObjectID `bson:"diagram_ids"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			return err
+		}
+		if len(legacy.DiagramIDs) == 0 {
+			continue
+		}
+
+		if _, err := diagrams.UpdateMany(ctx, bson.M{
+			"_id":          bson.M{"$in": legacy.DiagramIDs},
+			"workspace_id": legacy.WorkspaceID,
+			"folder_id":    nil,
+		}, bson.M{"$set": bson.M{"folder_id": legacy.ID}}); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if _, err := folders.UpdateMany(ctx,
+		bson.M{"diagram_ids": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"diagram_ids": ""}},
+	); err != nil {
+		return err
+	}
+
+	existing, err := folders.Distinct(ctx, "_id", bson.M{})
+	if err != nil {
+		return err
+	}
+	_, err = diagrams.UpdateMany(ctx,
+		bson.M{"folder_id": bson.M{"$nin": append(existing, nil)}},
+		bson.M{"$set": bson.M{"folder_id": nil}},
+	)
+	return err
+}
+
+// scopePersonalAccessTokens grants every scope to personal access tokens created before scopes existed,
+// as they had the full power of their user
+func scopePersonalAccessTokens(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("personal_access_tokens").UpdateMany(ctx,
+		bson.M{"scopes": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"scopes": bson.A{"diagrams:read", "diagrams:write", "members:manage", "workspaces:admin"}}},
+	)
+	return err
+}
+
+func unscopePersonalAccessTokens(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("personal_access_tokens").UpdateMany(ctx,
+		bson.M{}, bson.M{"$unset": bson.M{"scopes": ""}},
+	)
+	return err
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// FileSender writes each message to an .eml file instead of sending it, for development.
+// The files open in any mail client.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender creates a sender writing into dir
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+// unsafeFileChars matches the characters replaced in file names
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+// Send writes one message
+func (f *FileSender) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := buildMessage(f.from, msg, now)
+	if err != nil {
+		return permanent(err)
+	}
+
+	name := now.UTC().Format("20060102-150405.000000000") + "-" + unsafeFileChars.ReplaceAllString(msg.To, "_") + ".eml"
+	path := filepath.Join(f.dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote email %q to %s\n", msg.Subject, path)
+	return nil
+}
@@ -0,0 +1,189 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/repository/memory"
+)
+
+// fakeSender fails with the queued errors, then succeeds
+type fakeSender struct {
+	errs []error
+	sent []*Message
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg *Message) error {
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func newTestMailer(t *testing.T, sender Sender) (*Mailer, *repository.Store) {
+	t.Helper()
+	store := memory.New()
+	m, err := New(store.Outbox, sender, "https://app.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, store
+}
+
+func TestTemplates(t *testing.T) {
+	m, store := newTestMailer(t, &fakeSender{})
+	ctx := context.Background()
+
+	expires := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	if err := m.QueueInvite(ctx, "ana@example.com", "Omar", "<Team>", "editor", "tok123", expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.QueueWelcome(ctx, "ana@example.com", "Ana"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.QueuePasswordReset(ctx, "ana@example.com", "Ana", "reset1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	items := []DigestItem{{Message: "Omar edited Payments Flow", Count: 3, At: expires}, {Message: "Omar removed you from Team", Count: 1, At: expires}}
+	if err := m.QueueDigest(ctx, "ana@example.com", "Ana", items); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.QueueMention(ctx, "ana@example.com", "Omar", "a comment on Payments Flow", "Team", "ws1", "d1"); err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := store.Outbox.Due(ctx, time.Now(), 10)
+	if err != nil || len(queued) != 5 {
+		t.Fatalf("queued = %v, %v", queued, err)
+	}
+	byTemplate := map[string]*repository.OutboxEmail{}
+	for _, e := range queued {
+		byTemplate[e.Template] = e
+	}
+
+	invite := byTemplate[TemplateInvite]
+	if invite.Subject != "Omar invited you to <Team> on Flowstry" {
+		t.Errorf("invite subject = %q", invite.Subject)
+	}
+	for _, body := range []string{invite.Text, invite.HTML} {
+		if !strings.Contains(body, "https://app.example.com/invites/tok123") || !strings.Contains(body, "Mar 4, 2026 at 15:30 UTC") {
+			t.Errorf("invite body misses the accept link or expiry:\n%s", body)
+		}
+	}
+	if !strings.Contains(invite.HTML, "&lt;Team&gt;") || strings.Contains(invite.HTML, "<Team>") {
+		t.Errorf("invite HTML does not escape the workspace name")
+	}
+
+	if got := byTemplate[TemplatePasswordReset]; !strings.Contains(got.Text, "https://app.example.com/reset-password?token=reset1") ||
+		!strings.Contains(got.Text, "expires in 1 hour.") {
+		t.Errorf("password reset text =\n%s", got.Text)
+	}
+	digest := byTemplate[TemplateDigest]
+	if digest.Subject != "You have 2 new notifications on Flowstry" || !strings.Contains(digest.Text, "- Omar edited Payments Flow (3 times)") {
+		t.Errorf("digest = %q\n%s", digest.Subject, digest.Text)
+	}
+	if welcome := byTemplate[TemplateWelcome]; welcome.Subject != "Welcome to Flowstry" || !strings.Contains(welcome.HTML, `href="https://app.example.com"`) {
+		t.Errorf("welcome = %q\n%s", welcome.Subject, welcome.HTML)
+	}
+	mention := byTemplate[TemplateMention]
+	if mention.Subject != "Omar mentioned you in a comment on Payments Flow" ||
+		!strings.Contains(mention.HTML, `href="https://app.example.com/workspace?workspaceId=ws1&amp;diagramId=d1"`) {
+		t.Errorf("mention = %q\n%s", mention.Subject, mention.HTML)
+	}
+}
+
+func TestSendDueRetries(t *testing.T) {
+	sender := &fakeSender{errs: []error{errors.New("connection refused")}}
+	m, store := newTestMailer(t, sender)
+	ctx := context.Background()
+
+	if err := m.QueueWelcome(ctx, "ana@example.com", "Ana"); err != nil {
+		t.Fatal(err)
+	}
+	if sent, failed, err := m.SendDue(ctx); err != nil || sent != 0 || failed != 0 {
+		t.Fatalf("first pass = %d sent, %d failed, %v", sent, failed, err)
+	}
+
+	// The failed attempt is retried after the first backoff
+	if due, _ := store.Outbox.Due(ctx, time.Now(), 10); len(due) != 0 {
+		t.Fatalf("retry due immediately: %+v", due)
+	}
+	due, err := store.Outbox.Due(ctx, time.Now().Add(firstRetryDelay+time.Second), 10)
+	if err != nil || len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "connection refused" {
+		t.Fatalf("retry = %+v, %v", due, err)
+	}
+
+	if err := store.Outbox.MarkRetry(ctx, due[0].ID, 1, time.Now(), due[0].LastError); err != nil {
+		t.Fatal(err)
+	}
+	if sent, failed, err := m.SendDue(ctx); err != nil || sent != 1 || failed != 0 {
+		t.Fatalf("second pass = %d sent, %d failed, %v", sent, failed, err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].To != "ana@example.com" {
+		t.Fatalf("sent = %+v", sender.sent)
+	}
+}
+
+func TestSendDueGivesUpOnPermanentFailure(t *testing.T) {
+	sender := &fakeSender{errs: []error{&textproto.Error{Code: 550, Msg: "mailbox unavailable"}}}
+	m, store := newTestMailer(t, sender)
+	ctx := context.Background()
+
+	if err := m.QueueWelcome(ctx, "gone@example.com", "Gone"); err != nil {
+		t.Fatal(err)
+	}
+	if sent, failed, err := m.SendDue(ctx); err != nil || sent != 0 || failed != 1 {
+		t.Fatalf("SendDue = %d sent, %d failed, %v", sent, failed, err)
+	}
+	if due, _ := store.Outbox.Due(ctx, time.Now().Add(24*time.Hour), 10); len(due) != 0 {
+		t.Fatalf("failed email is still due: %+v", due)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 7: time.Hour, 20: time.Hour} {
+		if got := retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	sender, err := NewFileSender(dir, "Flowstry <no-reply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{To: "ana@example.com", Subject: "Grüße", Text: "Hello", HTML: "<p>Hello</p>"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*-ana@example.com.eml"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: <ana@example.com>", "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=", "multipart/alternative", "<p>Hello</p>", "@example.com>"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message misses %q:\n%s", want, data)
+		}
+	}
+
+	if err := sender.Send(context.Background(), &Message{To: "not an address"}); !isPermanent(err) {
+		t.Errorf("invalid recipient: err = %v, want a permanent error", err)
+	}
+}
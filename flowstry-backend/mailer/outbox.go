@@ -0,0 +1,204 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/repository"
+)
+
+const (
+	// maxAttempts is how many times an email is tried before it is marked failed
+	maxAttempts = 8
+	// firstRetryDelay doubles after each failed attempt, up to maxRetryDelay
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = time.Hour
+	// sendTimeout bounds one attempt; a claimed email becomes due again after it
+	sendTimeout = time.Minute
+	// sendBatchSize is how many due emails one pass claims at a time
+	sendBatchSize = 50
+	// sentRetention is how long delivered emails are kept in the outbox
+	sentRetention = 7 * 24 * time.Hour
+)
+
+// Mailer queues emails in the outbox and delivers them in the background
+type Mailer struct {
+	outbox      repository.OutboxRepository
+	sender      Sender
+	templates   *Templates
+	frontendURL string
+}
+
+// New creates a mailer; links in emails point at frontendURL
+func New(outbox repository.OutboxRepository, sender Sender, frontendURL string) (*Mailer, error) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return &Mailer{
+		outbox:      outbox,
+		sender:      sender,
+		templates:   templates,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}, nil
+}
+
+// Enqueue renders a template and queues the email for delivery
+func (m *Mailer) Enqueue(ctx context.Context, template, to string, data interface{}) error {
+	msg, err := m.templates.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return m.outbox.Enqueue(ctx, &repository.OutboxEmail{
+		Template:      template,
+		To:            msg.To,
+		Subject:       msg.Subject,
+		Text:          msg.Text,
+		HTML:          msg.HTML,
+		Status:        repository.OutboxPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+// QueueInvite emails an invitation; the accept link carries the raw invite token
+func (m *Mailer) QueueInvite(ctx context.Context, to, inviterName, workspaceName, role, token string, expiresAt time.Time) error {
+	return m.Enqueue(ctx, TemplateInvite, to, InviteData{
+		InviterName:   inviterName,
+		WorkspaceName: workspaceName,
+		Role:          role,
+		AcceptURL:     m.frontendURL + "/invites/" + token,
+		ExpiresAt:     expiresAt,
+	})
+}
+
+// QueueWelcome welcomes a new user
+func (m *Mailer) QueueWelcome(ctx context.Context, to, name string) error {
+	return m.Enqueue(ctx, TemplateWelcome, to, WelcomeData{Name: name, AppURL: m.frontendURL})
+}
+
+// QueuePasswordReset emails a password reset link carrying the raw reset token
+func (m *Mailer) QueuePasswordReset(ctx context.Context, to, name, token string, expiresIn time.Duration) error {
+	return m.Enqueue(ctx, TemplatePasswordReset, to, PasswordResetData{
+		Name:      name,
+		ResetURL:  m.frontendURL + "/reset-password?token=" + token,
+		ExpiresIn: expiresIn,
+	})
+}
+
+// QueueDigest emails a summary of unread notifications
+func (m *Mailer) QueueDigest(ctx context.Context, to, name string, items []DigestItem) error {
+	return m.Enqueue(ctx, TemplateDigest, to, DigestData{Name: name, InboxURL: m.frontendURL + "/notifications", Items: items})
+}
+
+// QueueMention tells a member they were mentioned on a diagram; where describes the diagram or comment
+func (m *Mailer) QueueMention(ctx context.Context, to, actorName, where, workspaceName, workspaceID, diagramID string) error {
+	return m.Enqueue(ctx, TemplateMention, to, MentionData{
+		ActorName:     actorName,
+		Where:         where,
+		WorkspaceName: workspaceName,
+		DiagramURL:    m.frontendURL + "/workspace?workspaceId=" + workspaceID + "&diagramId=" + diagramID,
+	})
+}
+
+// SendDue delivers the emails that are due and returns how many were sent and how many failed for good.
+// Failed attempts are retried with exponential backoff.
+func (m *Mailer) SendDue(ctx context.Context) (sent, failed int, err error) {
+	for {
+		now := time.Now()
+		due, err := m.outbox.Due(ctx, now, sendBatchSize)
+		if err != nil {
+			return sent, failed, err
+		}
+
+		for _, email := range due {
+			// Another instance may have claimed it since Due
+			err := m.outbox.Claim(ctx, email.ID, now, now.Add(sendTimeout))
+			if errors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return sent, failed, err
+			}
+			delivered, err := m.deliver(ctx, email)
+			if err != nil {
+				return sent, failed, err
+			}
+			if delivered {
+				sent++
+			} else if email.Status == repository.OutboxFailed {
+				failed++
+			}
+		}
+
+		if int64(len(due)) < sendBatchSize {
+			return sent, failed, nil
+		}
+	}
+}
+
+// deliver makes one attempt at sending a claimed email and records its outcome
+func (m *Mailer) deliver(ctx context.Context, email *repository.OutboxEmail) (bool, error) {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	sendErr := m.sender.Send(sendCtx, &Message{To: email.To, Subject: email.Subject, Text: email.Text, HTML: email.HTML})
+	cancel()
+
+	email.Attempts++
+	if sendErr == nil {
+		return true, m.outbox.MarkSent(ctx, email.ID, email.Attempts, time.Now())
+	}
+
+	fmt.Printf("Warning: Failed to send %s email to %s (attempt %d): %v\n", email.Template, email.To, email.Attempts, sendErr)
+	if isPermanent(sendErr) || email.Attempts >= maxAttempts {
+		email.Status = repository.OutboxFailed
+		return false, m.outbox.MarkFailed(ctx, email.ID, email.Attempts, sendErr.Error())
+	}
+	return false, m.outbox.MarkRetry(ctx, email.ID, email.Attempts, time.Now().Add(retryDelay(email.Attempts)), sendErr.Error())
+}
+
+// retryDelay is the wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// StartWorker runs SendDue in the background every interval and prunes delivered emails,
+// skipping runs while connected reports false
+func (m *Mailer) StartWorker(interval time.Duration, connected func() bool) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if connected() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+				sent, failed, err := m.SendDue(ctx)
+				if err != nil {
+					fmt.Printf("Warning: Email outbox run failed: %v\n", err)
+				} else if sent+failed > 0 {
+					fmt.Printf("Email outbox sent %d emails, %d failed\n", sent, failed)
+				}
+				if _, err := m.outbox.DeleteSentBefore(ctx, time.Now().Add(-sentRetention)); err != nil {
+					fmt.Printf("Warning: Failed to prune the email outbox: %v\n", err)
+				}
+				cancel()
+			}
+			<-ticker.C
+		}
+	}()
+}
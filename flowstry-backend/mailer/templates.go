@@ -0,0 +1,144 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names; each has a <name>.txt holding the subject and plain text body and a <name>.html body
+const (
+	TemplateInvite        = "invite"
+	TemplateWelcome       = "welcome"
+	TemplatePasswordReset = "password_reset"
+	TemplateDigest        = "digest"
+	TemplateMention       = "mention"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// InviteData fills the invite template
+type InviteData struct {
+	InviterName   string
+	WorkspaceName string
+	Role          string
+	AcceptURL     string
+	ExpiresAt     time.Time
+}
+
+// WelcomeData fills the welcome template
+type WelcomeData struct {
+	Name   string
+	AppURL string
+}
+
+// PasswordResetData fills the password reset template
+type PasswordResetData struct {
+	Name      string
+	ResetURL  string
+	ExpiresIn time.Duration
+}
+
+// DigestData fills the notification digest template
+type DigestData struct {
+	Name     string
+	InboxURL string
+	Items    []DigestItem
+}
+
+// MentionData fills the mention template
+type MentionData struct {
+	ActorName     string
+	Where         string
+	WorkspaceName string
+	DiagramURL    string
+}
+
+// DigestItem is one notification of a digest
+type DigestItem struct {
+	Message string
+	Count   int
+	At      time.Time
+}
+
+// templateFuncs are available to every template
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.UTC().Format("Jan 2, 2006 at 15:04 UTC")
+	},
+	"duration": formatDuration,
+	"button": func(label, url string) map[string]string {
+		return map[string]string{"Label": label, "URL": url}
+	},
+}
+
+// formatDuration writes a duration in the largest whole unit, e.g. "24 hours" or "30 minutes"
+func formatDuration(d time.Duration) string {
+	unit, n := "minute", int(d/time.Minute)
+	if d >= time.Hour && d%time.Hour == 0 {
+		unit, n = "hour", int(d/time.Hour)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// emailTemplate is the parsed text and HTML of one template
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates renders the embedded email templates
+type Templates struct {
+	byName map[string]*emailTemplate
+}
+
+// LoadTemplates parses the embedded templates
+func LoadTemplates() (*Templates, error) {
+	t := &Templates{byName: map[string]*emailTemplate{}}
+	for _, name := range []string{TemplateInvite, TemplateWelcome, TemplatePasswordReset, TemplateDigest, TemplateMention} {
+		text, err := texttemplate.New(name+".txt").Funcs(templateFuncs).ParseFS(templateFS, "templates/"+name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("parse %s.txt: %w", name, err)
+		}
+		html, err := htmltemplate.New("layout.html").Funcs(templateFuncs).ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parse %s.html: %w", name, err)
+		}
+		t.byName[name] = &emailTemplate{text: text, html: html}
+	}
+	return t, nil
+}
+
+// Render renders a template for the recipient to
+func (t *Templates) Render(name, to string, data interface{}) (*Message, error) {
+	tmpl, ok := t.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("render %s.txt: %w", name, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("render %s.html: %w", name, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
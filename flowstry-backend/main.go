@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/flowstry/flowstry-backend/config"
 	"github.com/flowstry/flowstry-backend/database"
+	"github.com/flowstry/flowstry-backend/database/migrations"
+	"github.com/flowstry/flowstry-backend/mailer"
 	"github.com/flowstry/flowstry-backend/middleware"
 	"github.com/flowstry/flowstry-backend/modules/auth"
 	authServices "github.com/flowstry/flowstry-backend/modules/auth/services"
 	"github.com/flowstry/flowstry-backend/modules/workspace"
 	workspaceServices "github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/openapi"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/repository/mongorepo"
+	"github.com/flowstry/flowstry-backend/repository/sqlrepo"
 	"github.com/flowstry/flowstry-backend/storage"
+	"github.com/flowstry/flowstry-backend/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -32,15 +42,61 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	// Connect to MongoDB
-	if err := database.Connect(cfg.MongoDBURI, cfg.MongoDBDatabase); err != nil {
-		log.Printf("Warning: Failed to connect to MongoDB: %v", err)
-		log.Println("Server will start but database operations will fail")
+	// Connect to the configured database
+	backend, err := openBackend(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
 	}
+	store := backend.store
 
-	// Initialize GCS client (optional - may not be configured in development)
-	var gcsClient *storage.GCSClient
-	if cfg.GCSBucketName != "" {
+	// `flowstry-backend migrate <up|down|status>` runs migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if !store.Connected() {
+			log.Fatal("Cannot run migrations without a database connection")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		err := migrations.RunCommand(ctx, backend.migrator, os.Args[2:], os.Stdout)
+		cancel()
+		backend.close()
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Apply pending migrations and sync indexes
+	if cfg.MigrateOnStartup && store.Connected() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		applied, err := backend.migrator.Up(ctx)
+		cancel()
+		for _, m := range applied {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
+	// Initialize file storage (GCS is optional - may not be configured in development)
+	var fileStorage storage.Storage
+	switch cfg.StorageDriver {
+	case "local":
+		signingKey, _, err := utils.LoadOrCreateKey(filepath.Join(cfg.DataDir, "storage.key"), 32)
+		if err != nil {
+			log.Fatalf("Failed to load storage signing key: %v", err)
+		}
+		dir := filepath.Join(cfg.DataDir, "files")
+		local, err := storage.NewLocalStorage(dir, cfg.PublicURL, signingKey)
+		if err != nil {
+			log.Fatalf("Failed to initialize local storage: %v", err)
+		}
+		fileStorage = local
+		log.Printf("Storing files in %s", dir)
+	case "gcs", "":
+		if cfg.GCSBucketName == "" {
+			log.Println("GCS not configured - file storage disabled")
+			break
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		client, err := storage.NewGCSClient(ctx, cfg.GCSBucketName)
 		cancel()
@@ -48,17 +104,48 @@ func main() {
 			log.Printf("Warning: Failed to create GCS client: %v", err)
 			log.Println("File storage operations will fail")
 		} else {
-			gcsClient = client
+			fileStorage = client
 			log.Printf("Connected to GCS bucket: %s", cfg.GCSBucketName)
 		}
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q (expected gcs or local)", cfg.StorageDriver)
+	}
+
+	// Initialize the mailer (optional - without MAIL_DRIVER no emails are sent)
+	var mail *mailer.Mailer
+	if cfg.MailDriver != "" {
+		var sender mailer.Sender
+		switch cfg.MailDriver {
+		case "smtp":
+			sender = mailer.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+			log.Printf("Sending email through %s:%s", cfg.SMTPHost, cfg.SMTPPort)
+		case "file":
+			files, err := mailer.NewFileSender(cfg.MailDir, cfg.MailFrom)
+			if err != nil {
+				log.Fatalf("Failed to initialize the mail directory: %v", err)
+			}
+			sender = files
+			log.Printf("Writing emails to %s", cfg.MailDir)
+		default:
+			log.Fatalf("Unknown MAIL_DRIVER %q (expected smtp or file)", cfg.MailDriver)
+		}
+		mail, err = mailer.New(store.Outbox, sender, cfg.FrontendURL)
+		if err != nil {
+			log.Fatalf("Failed to load email templates: %v", err)
+		}
+		mail.StartWorker(cfg.MailPollInterval, store.Connected)
 	} else {
-		log.Println("GCS not configured - file storage disabled")
+		log.Println("MAIL_DRIVER not set - emails disabled")
 	}
 
 	// Initialize services
-	authService := authServices.NewAuthService(cfg)
+	authService := authServices.NewAuthService(cfg, store.Users, store.RefreshTokens, store.AccessTokens)
+	if mail != nil {
+		authService.SetMailer(mail)
+	}
 	googleService := authServices.NewGoogleService(cfg)
 	liveCollabService := workspaceServices.NewLiveCollabService(
+		store.Users,
 		cfg.LiveCollabURL,
 		cfg.LiveCollabWSURL,
 		cfg.LiveCollabJWTSecret,
@@ -72,13 +159,17 @@ func main() {
 		BodyLimit: 50 * 1024 * 1024, // 50MB max body size for file uploads
 	})
 
+	// Unversioned API paths are served as /v1; this runs first so routing restarts before any middleware
+	spec := openapi.New("Flowstry API", apiVersion, "/v1")
+	app.Use(spec.Unversioned())
+
 	// Middleware
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.AllowedOrigins,
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Share-Password",
 		AllowCredentials: true, // Required for cookies to work cross-origin
 	}))
 
@@ -95,13 +186,15 @@ func main() {
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"status":   "ok",
-			"database": database.IsConnected(),
+			"database": store.Connected(),
 		})
 	})
 
 	// Setup module routes
-	auth.SetupRoutes(app, authService, googleService)
-	workspace.SetupRoutes(app, authService, gcsClient, liveCollabService)
+	setupAPI(app, spec, cfg, store, authService, googleService, fileStorage, liveCollabService, mail)
+	if local, ok := fileStorage.(*storage.LocalStorage); ok {
+		local.SetupRoutes(app)
+	}
 
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -110,10 +203,10 @@ func main() {
 	go func() {
 		<-c
 		log.Println("Gracefully shutting down...")
-		if gcsClient != nil {
-			_ = gcsClient.Close()
+		if fileStorage != nil {
+			_ = fileStorage.Close()
 		}
-		database.Disconnect()
+		backend.close()
 		_ = app.Shutdown()
 	}()
 
@@ -123,3 +216,73 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// apiVersion is the version of the API described by the OpenAPI document
+const apiVersion = "1.0.0"
+
+// setupAPI registers the module routes under the spec's base path and serves the spec at /openapi.json
+func setupAPI(app *fiber.App, spec *openapi.Spec, cfg *config.Config, store *repository.Store, authService *authServices.AuthService, googleService *authServices.GoogleService, fileStorage storage.Storage, liveCollabService *workspaceServices.LiveCollabService, mail *mailer.Mailer) {
+	api := app.Group(spec.BasePath())
+	auth.SetupRoutes(api, spec, authService, googleService)
+	workspace.SetupRoutes(api, spec, cfg, store, authService, fileStorage, liveCollabService, mail)
+	app.Get("/openapi.json", spec.Handler())
+}
+
+// backend is the persistence layer selected by DATABASE_DRIVER
+type backend struct {
+	store    *repository.Store
+	migrator migrations.Migrator
+	close    func()
+}
+
+// openBackend connects to the configured database and wires its repositories and migrator
+func openBackend(cfg *config.Config) (*backend, error) {
+	switch cfg.DatabaseDriver {
+	case "mongodb", "":
+		if err := database.Connect(cfg.MongoDBURI, cfg.MongoDBDatabase); err != nil {
+			return nil, fmt.Errorf("connect to MongoDB: %w (set DATABASE_DRIVER=sqlite to run without external services)", err)
+		}
+		return &backend{
+			store:    mongorepo.New(),
+			migrator: migrations.NewRunner(database.GetDatabase()),
+			close:    database.Disconnect,
+		}, nil
+
+	case "postgres":
+		if cfg.DatabaseURL == "" {
+			return nil, fmt.Errorf("DATABASE_URL is required when DATABASE_DRIVER is postgres")
+		}
+		db, err := sqlrepo.Open(cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return sqlBackend(db, "PostgreSQL")
+
+	case "sqlite":
+		db, err := sqlrepo.OpenSQLite(cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using SQLite database: %s", cfg.DatabaseURL)
+		return sqlBackend(db, "SQLite")
+
+	default:
+		return nil, fmt.Errorf("unknown DATABASE_DRIVER %q (expected mongodb, postgres or sqlite)", cfg.DatabaseDriver)
+	}
+}
+
+// sqlBackend wraps an opened SQL database, failing when it cannot be reached
+func sqlBackend(db *sql.DB, name string) (*backend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := db.PingContext(ctx)
+	cancel()
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connect to %s: %w", name, err)
+	}
+	return &backend{
+		store:    sqlrepo.New(db),
+		migrator: sqlrepo.NewMigrator(db),
+		close:    func() { _ = db.Close() },
+	}, nil
+}
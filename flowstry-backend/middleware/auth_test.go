@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/config"
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/modules/auth/services"
+	"github.com/flowstry/flowstry-backend/repository/memory"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTokenScopesAndRestrictions(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	authService := services.NewAuthService(&config.Config{JWTSecret: "secret", AccessTokenExpiry: time.Hour},
+		store.Users, store.RefreshTokens, store.AccessTokens)
+	user := &models.User{Email: "bot@example.com", Name: "Bot", AuthProvider: models.AuthProviderEmail}
+	if err := authService.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	session, err := authService.GenerateAccessToken(user.ID.Hex(), user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceID := primitive.NewObjectID()
+	newToken := func(workspace string, scopes ...models.TokenScope) string {
+		t.Helper()
+		token, err := authService.CreatePersonalAccessToken(ctx, user.ID, &models.CreatePersonalAccessTokenRequest{
+			Name: "bot", Scopes: scopes, WorkspaceID: workspace,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token.Secret
+	}
+	reader := newToken("", models.ScopeDiagramsRead)
+	writer := newToken("", models.ScopeDiagramsWrite)
+	admin := newToken("", models.ScopeWorkspacesAdmin)
+	restricted := newToken(workspaceID.Hex(), models.ScopeDiagramsWrite)
+	if _, err := authService.CreatePersonalAccessToken(ctx, user.ID, &models.CreatePersonalAccessTokenRequest{Name: "none"}); err == nil {
+		t.Error("created a token without scopes")
+	}
+
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) }
+	app := fiber.New()
+	workspaces := app.Group("/workspaces", AuthMiddleware(authService), TokenWorkspace())
+	workspaces.Get("/:id/diagrams", RequireScope(models.ScopeDiagramsRead), ok)
+	workspaces.Post("/:id/diagrams", RequireScope(models.ScopeDiagramsWrite), ok)
+	workspaces.Delete("/:id/members/:userId", RequireScope(models.ScopeMembersManage), ok)
+	workspaces.Delete("/:id", RequireScope(models.ScopeWorkspacesAdmin), ok)
+	app.Get("/search", AuthMiddleware(authService), UnrestrictedToken(), ok)
+	app.Get("/tokens", AuthMiddleware(authService), SessionOnly(), ok)
+
+	other := primitive.NewObjectID().Hex()
+	tests := []struct {
+		name, token, method, path string
+		want                      int
+	}{
+		{"session reads", session, http.MethodGet, "/workspaces/" + other + "/diagrams", http.StatusNoContent},
+		{"session deletes workspace", session, http.MethodDelete, "/workspaces/" + other, http.StatusNoContent},
+		{"reader reads", reader, http.MethodGet, "/workspaces/" + other + "/diagrams", http.StatusNoContent},
+		{"reader cannot write", reader, http.MethodPost, "/workspaces/" + other + "/diagrams", http.StatusForbidden},
+		{"reader cannot delete workspace", reader, http.MethodDelete, "/workspaces/" + other, http.StatusForbidden},
+		{"writer reads", writer, http.MethodGet, "/workspaces/" + other + "/diagrams", http.StatusNoContent},
+		{"writer writes", writer, http.MethodPost, "/workspaces/" + other + "/diagrams", http.StatusNoContent},
+		{"writer cannot manage members", writer, http.MethodDelete, "/workspaces/" + other + "/members/" + other, http.StatusForbidden},
+		{"admin manages members", admin, http.MethodDelete, "/workspaces/" + other + "/members/" + other, http.StatusNoContent},
+		{"admin deletes workspace", admin, http.MethodDelete, "/workspaces/" + other, http.StatusNoContent},
+		{"admin cannot read diagrams", admin, http.MethodGet, "/workspaces/" + other + "/diagrams", http.StatusForbidden},
+		{"restricted in its workspace", restricted, http.MethodPost, "/workspaces/" + workspaceID.Hex() + "/diagrams", http.StatusNoContent},
+		{"restricted elsewhere", restricted, http.MethodGet, "/workspaces/" + other + "/diagrams", http.StatusForbidden},
+		{"restricted searching", restricted, http.MethodGet, "/search", http.StatusForbidden},
+		{"unrestricted searching", reader, http.MethodGet, "/search", http.StatusNoContent},
+		{"token managing tokens", admin, http.MethodGet, "/tokens", http.StatusForbidden},
+		{"session managing tokens", session, http.MethodGet, "/tokens", http.StatusNoContent},
+		{"unknown token", models.PersonalAccessTokenPrefix + "unknown", http.MethodGet, "/search", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
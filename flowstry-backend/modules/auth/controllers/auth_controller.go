@@ -135,9 +135,7 @@ func (ac *AuthController) SignUp(c *fiber.Ctx) error {
 	// Set httpOnly cookies
 	ac.setAuthCookies(c, accessToken, refreshToken)
 
-	return utils.CreatedResponse(c, fiber.Map{
-		"user": user.ToResponse(),
-	})
+	return utils.CreatedResponse(c, &models.SessionResponse{User: user.ToResponse()})
 }
 
 // SignIn handles user login
@@ -185,9 +183,7 @@ func (ac *AuthController) SignIn(c *fiber.Ctx) error {
 	// Set httpOnly cookies
 	ac.setAuthCookies(c, accessToken, refreshToken)
 
-	return utils.SuccessResponse(c, fiber.Map{
-		"user": user.ToResponse(),
-	})
+	return utils.SuccessResponse(c, &models.SessionResponse{User: user.ToResponse()})
 }
 
 // Refresh handles token refresh
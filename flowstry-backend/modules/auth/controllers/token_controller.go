@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/modules/auth/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenController handles personal access token endpoints
+type TokenController struct {
+	authService *services.AuthService
+}
+
+// NewTokenController creates a new token controller
+func NewTokenController(authService *services.AuthService) *TokenController {
+	return &TokenController{authService: authService}
+}
+
+// List returns the current user's personal access tokens
+func (tc *TokenController) List(c *fiber.Ctx) error {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok || userIDStr == "" {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return utils.Unauthorized(c, "Invalid user ID")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := tc.authService.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list tokens")
+	}
+
+	return utils.SuccessResponse(c, tokens)
+}
+
+// Create creates a personal access token; the response carries the token, which is not shown again
+func (tc *TokenController) Create(c *fiber.Ctx) error {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok || userIDStr == "" {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return utils.Unauthorized(c, "Invalid user ID")
+	}
+
+	var req models.CreatePersonalAccessTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := tc.authService.CreatePersonalAccessToken(ctx, userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTokenName), errors.Is(err, services.ErrInvalidTokenExpiry),
+			errors.Is(err, services.ErrInvalidTokenScopes), errors.Is(err, services.ErrInvalidTokenWorkspace),
+			errors.Is(err, services.ErrTooManyTokens):
+			return utils.BadRequest(c, err.Error())
+		}
+		return utils.InternalError(c, "Failed to create token")
+	}
+
+	return utils.CreatedResponse(c, token)
+}
+
+// Revoke deletes a personal access token
+func (tc *TokenController) Revoke(c *fiber.Ctx) error {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok || userIDStr == "" {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return utils.Unauthorized(c, "Invalid user ID")
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid token ID")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := tc.authService.RevokePersonalAccessToken(ctx, userID, tokenID); err != nil {
+		if errors.Is(err, services.ErrTokenNotFound) {
+			return utils.NotFound(c, "Token not found")
+		}
+		return utils.InternalError(c, "Failed to revoke token")
+	}
+
+	return utils.SuccessMessageResponse(c, "Token revoked successfully")
+}
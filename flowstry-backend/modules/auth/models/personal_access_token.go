@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, telling it apart from access JWTs
+const PersonalAccessTokenPrefix = "fpat_"
+
+// TokenScope is a permission granted to a personal access token. A token can do what its scopes
+// allow and its owner's workspace role permits, never more.
+type TokenScope string
+
+const (
+	// ScopeDiagramsRead reads workspaces and their diagrams, folders, comments and members
+	ScopeDiagramsRead TokenScope = "diagrams:read"
+	// ScopeDiagramsWrite creates, edits, shares and deletes diagrams and their content; it includes diagrams:read
+	ScopeDiagramsWrite TokenScope = "diagrams:write"
+	// ScopeMembersManage invites, removes and changes the roles of members
+	ScopeMembersManage TokenScope = "members:manage"
+	// ScopeWorkspacesAdmin creates, renames and deletes workspaces and manages their audit log and webhooks;
+	// it includes members:manage
+	ScopeWorkspacesAdmin TokenScope = "workspaces:admin"
+)
+
+// TokenScopes lists every scope
+var TokenScopes = []TokenScope{ScopeDiagramsRead, ScopeDiagramsWrite, ScopeMembersManage, ScopeWorkspacesAdmin}
+
+// IsValid checks if the scope exists
+func (s TokenScope) IsValid() bool {
+	for _, scope := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Grants reports whether holding this scope allows what required allows
+func (s TokenScope) Grants(required TokenScope) bool {
+	switch {
+	case s == required:
+		return true
+	case s == ScopeDiagramsWrite:
+		return required == ScopeDiagramsRead
+	case s == ScopeWorkspacesAdmin:
+		return required == ScopeMembersManage
+	}
+	return false
+}
+
+// PersonalAccessToken lets scripts call the API as a user without signing in.
+// Only a hash of the token is stored; a token restricted to a workspace cannot reach any other.
+type PersonalAccessToken struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"-"`
+	Name        string              `bson:"name" json:"name"`
+	Token       string              `bson:"token" json:"-"` // Hashed token
+	WorkspaceID *primitive.ObjectID `bson:"workspace_id,omitempty" json:"workspace_id,omitempty"`
+	Scopes      []TokenScope        `bson:"scopes" json:"scopes"`
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`
+	LastUsedAt  *time.Time          `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// IsExpired returns true if the token has expired
+func (t *PersonalAccessToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// Allows reports whether one of the token's scopes grants the required scope
+func (t *PersonalAccessToken) Allows(required TokenScope) bool {
+	for _, scope := range t.Scopes {
+		if scope.Grants(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalAccessTokenWithSecret is a token together with its raw value, returned only when it is created
+type PersonalAccessTokenWithSecret struct {
+	*PersonalAccessToken
+	Secret string `json:"token"`
+}
+
+// CreatePersonalAccessTokenRequest represents the request to create a personal access token
+type CreatePersonalAccessTokenRequest struct {
+	Name string `json:"name"`
+	// Scopes must name at least one scope
+	Scopes []TokenScope `json:"scopes"`
+	// ExpiresInDays defaults to 30
+	ExpiresInDays int `json:"expires_in_days,omitempty"`
+	// WorkspaceID optionally restricts the token to one workspace
+	WorkspaceID string `json:"workspace_id,omitempty"`
+}
@@ -19,6 +19,11 @@ type UserPreferences struct {
 	Theme string `bson:"theme" json:"theme"` // "light" | "dark"
 }
 
+// NotificationPreferences chooses which in-app notifications a user receives; every type is on unless muted
+type NotificationPreferences struct {
+	Muted []string `bson:"muted,omitempty" json:"muted"`
+}
+
 // User represents a user in the system
 type User struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -29,8 +34,10 @@ type User struct {
 	AuthProvider AuthProvider       `bson:"auth_provider" json:"auth_provider"`
 	GoogleID     string             `bson:"google_id,omitempty" json:"-"`
 	Preferences  UserPreferences    `bson:"preferences" json:"preferences"`
-	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	// Notifications is kept apart from Preferences, which clients replace as a whole
+	Notifications NotificationPreferences `bson:"notification_preferences" json:"notification_preferences"`
+	CreatedAt     time.Time               `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time               `bson:"updated_at" json:"updated_at"`
 }
 
 // SignUpRequest represents the signup request body
@@ -58,6 +65,11 @@ type AuthResponse struct {
 	User         *User  `json:"user"`
 }
 
+// SessionResponse is returned when signing up or in; the tokens are set as cookies
+type SessionResponse struct {
+	User *UserResponse `json:"user"`
+}
+
 // UserResponse represents a safe user response (without sensitive data)
 // UserResponse represents a safe user response (without sensitive data)
 type UserResponse struct {
@@ -1,29 +1,69 @@
 package auth
 
 import (
+	"net/http"
+
 	"github.com/flowstry/flowstry-backend/middleware"
 	"github.com/flowstry/flowstry-backend/modules/auth/controllers"
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
 	"github.com/flowstry/flowstry-backend/modules/auth/services"
+	"github.com/flowstry/flowstry-backend/openapi"
 	"github.com/gofiber/fiber/v2"
 )
 
-// SetupRoutes configures auth routes
-func SetupRoutes(app *fiber.App, authService *services.AuthService, googleService *services.GoogleService) {
+// SetupRoutes configures auth routes on the versioned API router
+func SetupRoutes(api fiber.Router, spec *openapi.Spec, authService *services.AuthService, googleService *services.GoogleService) {
 	controller := controllers.NewAuthController(authService, googleService)
+	tokenController := controllers.NewTokenController(authService)
 
-	auth := app.Group("/auth")
+	auth := spec.Group(api, "/auth", "Auth")
 
 	// Public routes
-	auth.Post("/signup", controller.SignUp)
-	auth.Post("/signin", controller.SignIn)
-	auth.Post("/refresh", controller.Refresh)
-	auth.Post("/logout", controller.Logout)
+	auth.Post("/signup", openapi.Operation{
+		Summary: "Create an account and sign in", Public: true, Status: http.StatusCreated,
+		Body: models.SignUpRequest{}, Response: models.SessionResponse{},
+	}, controller.SignUp)
+	auth.Post("/signin", openapi.Operation{
+		Summary: "Sign in with email and password", Public: true,
+		Body: models.SignInRequest{}, Response: models.SessionResponse{},
+	}, controller.SignIn)
+	auth.Post("/refresh", openapi.Operation{
+		Summary: "Refresh the access token", Public: true, Body: models.RefreshRequest{},
+		Description: "The refresh token is read from the refresh_token cookie, or the body when there is none.",
+	}, controller.Refresh)
+	auth.Post("/logout", openapi.Operation{
+		Summary: "Sign out and revoke the refresh token", Public: true, Body: models.RefreshRequest{},
+	}, controller.Logout)
 
 	// Google OAuth routes
-	auth.Get("/google", controller.GoogleAuth)
-	auth.Get("/google/callback", controller.GoogleCallback)
+	auth.Get("/google", openapi.Operation{
+		Summary: "Start signing in with Google", Public: true, Status: http.StatusFound,
+		Params: []openapi.Param{{Name: "redirect_url", Description: "Frontend URL to return to"}},
+	}, controller.GoogleAuth)
+	auth.Get("/google/callback", openapi.Operation{
+		Summary: "Complete signing in with Google", Public: true, Status: http.StatusFound,
+		Params: []openapi.Param{{Name: "code"}, {Name: "state"}},
+	}, controller.GoogleCallback)
 
 	// Protected routes
-	auth.Get("/me", middleware.AuthMiddleware(authService), controller.Me)
-	auth.Put("/preferences", middleware.AuthMiddleware(authService), controller.UpdatePreferences)
+	auth.Get("/me", openapi.Operation{
+		Summary: "Get the current user", Response: models.UserResponse{},
+	}, middleware.AuthMiddleware(authService), controller.Me)
+	auth.Put("/preferences", openapi.Operation{
+		Summary: "Update the current user's preferences", Scope: models.ScopeDiagramsWrite, Body: models.UserPreferences{},
+	}, middleware.AuthMiddleware(authService), middleware.UnrestrictedToken(), controller.UpdatePreferences)
+
+	// Personal access tokens (managed from a signed-in session only)
+	tokens := spec.Group(api, "/auth/tokens", "Personal access tokens", middleware.AuthMiddleware(authService), middleware.SessionOnly())
+	tokens.Get("/", openapi.Operation{
+		Summary: "List personal access tokens", Response: []models.PersonalAccessToken{},
+	}, tokenController.List)
+	tokens.Post("/", openapi.Operation{
+		Summary: "Create a personal access token", Status: http.StatusCreated,
+		Description: "The token is only returned by this call.",
+		Body:        models.CreatePersonalAccessTokenRequest{}, Response: models.PersonalAccessTokenWithSecret{},
+	}, tokenController.Create)
+	tokens.Delete("/:id", openapi.Operation{
+		Summary: "Revoke a personal access token",
+	}, tokenController.Revoke)
 }
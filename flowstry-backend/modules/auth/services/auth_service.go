@@ -6,15 +6,17 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/flowstry/flowstry-backend/config"
-	"github.com/flowstry/flowstry-backend/database"
+	"github.com/flowstry/flowstry-backend/mailer"
 	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/repository"
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -24,6 +26,26 @@ var (
 	ErrUserNotFound     = errors.New("user not found")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailExists      = errors.New("email already exists")
+
+	ErrTokenNotFound         = errors.New("token not found")
+	ErrInvalidTokenName      = errors.New("token name is required and must be at most 100 characters")
+	ErrInvalidTokenExpiry    = errors.New("token expiry must be between 1 and 365 days")
+	ErrInvalidTokenScopes    = errors.New("choose at least one scope: diagrams:read, diagrams:write, members:manage or workspaces:admin")
+	ErrInvalidTokenWorkspace = errors.New("invalid workspace ID")
+	ErrTooManyTokens         = errors.New("too many personal access tokens")
+)
+
+const (
+	// maxTokenNameLength bounds personal access token names
+	maxTokenNameLength = 100
+	// defaultTokenExpiryDays applies when a token is created without an expiry
+	defaultTokenExpiryDays = 30
+	// maxTokenExpiryDays caps how long a personal access token lives
+	maxTokenExpiryDays = 365
+	// maxTokensPerUser caps the personal access tokens one user can hold
+	maxTokensPerUser = 50
+	// tokenTouchInterval throttles last-used writes for busy tokens
+	tokenTouchInterval = time.Minute
 )
 
 // Claims represents JWT claims
@@ -35,12 +57,31 @@ type Claims struct {
 
 // AuthService handles authentication operations
 type AuthService struct {
-	cfg *config.Config
+	cfg    *config.Config
+	users  repository.UserRepository
+	tokens repository.RefreshTokenRepository
+	pats   repository.PersonalAccessTokenRepository
+	mailer *mailer.Mailer
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(cfg *config.Config) *AuthService {
-	return &AuthService{cfg: cfg}
+func NewAuthService(cfg *config.Config, users repository.UserRepository, tokens repository.RefreshTokenRepository, pats repository.PersonalAccessTokenRepository) *AuthService {
+	return &AuthService{cfg: cfg, users: users, tokens: tokens, pats: pats}
+}
+
+// SetMailer sets the mailer that welcomes new users (for dependency injection)
+func (s *AuthService) SetMailer(m *mailer.Mailer) {
+	s.mailer = m
+}
+
+// sendWelcomeEmail queues the welcome email of a new user; signing up succeeds without it
+func (s *AuthService) sendWelcomeEmail(ctx context.Context, user *models.User) {
+	if s.mailer == nil {
+		return
+	}
+	if err := s.mailer.QueueWelcome(ctx, user.Email, user.Name); err != nil {
+		fmt.Printf("Warning: Failed to email welcome to user %s: %v\n", user.ID.Hex(), err)
+	}
 }
 
 // GenerateAccessToken creates a new JWT access token
@@ -59,6 +100,12 @@ func (s *AuthService) GenerateAccessToken(userID, email string) (string, error)
 	return token.SignedString([]byte(s.cfg.JWTSecret))
 }
 
+// hashToken returns the hash an opaque token is stored under
+func hashToken(rawToken string) string {
+	hash := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(hash[:])
+}
+
 // GenerateRefreshToken creates a new refresh token and stores it in the database
 func (s *AuthService) GenerateRefreshToken(ctx context.Context, userID primitive.ObjectID) (string, error) {
 	// Generate random token
@@ -68,26 +115,16 @@ func (s *AuthService) GenerateRefreshToken(ctx context.Context, userID primitive
 	}
 	rawToken := hex.EncodeToString(tokenBytes)
 
-	// Hash token for storage
-	hash := sha256.Sum256([]byte(rawToken))
-	hashedToken := hex.EncodeToString(hash[:])
-
 	// Store in database
 	refreshToken := &models.RefreshToken{
 		UserID:    userID,
-		Token:     hashedToken,
+		Token:     hashToken(rawToken),
 		ExpiresAt: time.Now().Add(s.cfg.RefreshTokenExpiry),
 		CreatedAt: time.Now(),
 		Revoked:   false,
 	}
 
-	collection := database.GetCollection("refresh_tokens")
-	if collection == nil {
-		return "", errors.New("database not connected")
-	}
-
-	_, err := collection.InsertOne(ctx, refreshToken)
-	if err != nil {
+	if err := s.tokens.Create(ctx, refreshToken); err != nil {
 		return "", err
 	}
 
@@ -120,19 +157,9 @@ func (s *AuthService) ValidateAccessToken(tokenString string) (*Claims, error) {
 
 // ValidateRefreshToken validates a refresh token and returns the user ID
 func (s *AuthService) ValidateRefreshToken(ctx context.Context, rawToken string) (primitive.ObjectID, error) {
-	// Hash the token
-	hash := sha256.Sum256([]byte(rawToken))
-	hashedToken := hex.EncodeToString(hash[:])
-
-	collection := database.GetCollection("refresh_tokens")
-	if collection == nil {
-		return primitive.NilObjectID, errors.New("database not connected")
-	}
-
-	var refreshToken models.RefreshToken
-	err := collection.FindOne(ctx, bson.M{"token": hashedToken}).Decode(&refreshToken)
+	refreshToken, err := s.tokens.GetByHash(ctx, hashToken(rawToken))
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return primitive.NilObjectID, ErrInvalidToken
 		}
 		return primitive.NilObjectID, err
@@ -151,82 +178,42 @@ func (s *AuthService) ValidateRefreshToken(ctx context.Context, rawToken string)
 
 // RevokeRefreshToken revokes a refresh token
 func (s *AuthService) RevokeRefreshToken(ctx context.Context, rawToken string) error {
-	hash := sha256.Sum256([]byte(rawToken))
-	hashedToken := hex.EncodeToString(hash[:])
-
-	collection := database.GetCollection("refresh_tokens")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"token": hashedToken},
-		bson.M{"$set": bson.M{"revoked": true}},
-	)
-	return err
+	return s.tokens.Revoke(ctx, hashToken(rawToken))
 }
 
 // RevokeAllUserTokens revokes all refresh tokens for a user
 func (s *AuthService) RevokeAllUserTokens(ctx context.Context, userID primitive.ObjectID) error {
-	collection := database.GetCollection("refresh_tokens")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	_, err := collection.UpdateMany(
-		ctx,
-		bson.M{"user_id": userID},
-		bson.M{"$set": bson.M{"revoked": true}},
-	)
-	return err
+	return s.tokens.RevokeAllForUser(ctx, userID)
 }
 
 // GetUserByID retrieves a user by ID
 func (s *AuthService) GetUserByID(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	user, err := s.users.Get(ctx, userID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // GetUserByEmail retrieves a user by email
 func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	user, err := s.users.GetByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // CreateUser creates a new user
 func (s *AuthService) CreateUser(ctx context.Context, user *models.User) error {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
 	// Check if email exists
 	existing, _ := s.GetUserByEmail(ctx, user.Email)
 	if existing != nil {
@@ -236,31 +223,26 @@ func (s *AuthService) CreateUser(ctx context.Context, user *models.User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	result, err := collection.InsertOne(ctx, user)
-	if err != nil {
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrEmailExists
+		}
 		return err
 	}
-
-	user.ID = result.InsertedID.(primitive.ObjectID)
+	s.sendWelcomeEmail(ctx, user)
 	return nil
 }
 
 // GetOrCreateGoogleUser finds or creates a user from Google OAuth
 func (s *AuthService) GetOrCreateGoogleUser(ctx context.Context, googleID, email, name, avatarURL string) (*models.User, error) {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
 	// Try to find by Google ID first
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"google_id": googleID}).Decode(&user)
+	user, err := s.users.GetByGoogleID(ctx, googleID)
 	if err == nil {
-		return &user, nil
+		return user, nil
 	}
 
 	// Try to find by email
-	err = collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	user, err = s.users.GetByEmail(ctx, email)
 	if err == nil {
 		// Link Google account to existing user
 		user.GoogleID = googleID
@@ -268,20 +250,10 @@ func (s *AuthService) GetOrCreateGoogleUser(ctx context.Context, googleID, email
 		if user.AvatarURL == "" {
 			user.AvatarURL = avatarURL
 		}
-		_, err = collection.UpdateOne(
-			ctx,
-			bson.M{"_id": user.ID},
-			bson.M{"$set": bson.M{
-				"google_id":     googleID,
-				"auth_provider": models.AuthProviderGoogle,
-				"avatar_url":    user.AvatarURL,
-				"updated_at":    time.Now(),
-			}},
-		)
-		if err != nil {
+		if err := s.users.LinkGoogle(ctx, user.ID, googleID, user.AvatarURL); err != nil {
 			return nil, err
 		}
-		return &user, nil
+		return user, nil
 	}
 
 	// Create new user
@@ -295,31 +267,146 @@ func (s *AuthService) GetOrCreateGoogleUser(ctx context.Context, googleID, email
 		UpdatedAt:    time.Now(),
 	}
 
-	result, err := collection.InsertOne(ctx, newUser)
-	if err != nil {
+	if err := s.users.Create(ctx, newUser); err != nil {
 		return nil, err
 	}
-
-	newUser.ID = result.InsertedID.(primitive.ObjectID)
+	s.sendWelcomeEmail(ctx, newUser)
 	return newUser, nil
 }
 
 // UpdateUserPreferences updates a user's preferences
 func (s *AuthService) UpdateUserPreferences(ctx context.Context, userID primitive.ObjectID, prefs models.UserPreferences) error {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": userID},
-		bson.M{
-			"$set": bson.M{
-				"preferences": prefs,
-				"updated_at":  time.Now(),
-			},
-		},
-	)
+	return s.users.UpdatePreferences(ctx, userID, prefs)
+}
+
+// CreatePersonalAccessToken creates a personal access token for a user and returns it with its raw value,
+// which is not stored and cannot be shown again
+func (s *AuthService) CreatePersonalAccessToken(ctx context.Context, userID primitive.ObjectID, req *models.CreatePersonalAccessTokenRequest) (*models.PersonalAccessTokenWithSecret, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		return nil, ErrInvalidTokenName
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultTokenExpiryDays
+	}
+	if days < 1 || days > maxTokenExpiryDays {
+		return nil, ErrInvalidTokenExpiry
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	var workspaceID *primitive.ObjectID
+	if req.WorkspaceID != "" {
+		id, err := primitive.ObjectIDFromHex(req.WorkspaceID)
+		if err != nil {
+			return nil, ErrInvalidTokenWorkspace
+		}
+		workspaceID = &id
+	}
+
+	existing, err := s.pats.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxTokensPerUser {
+		return nil, ErrTooManyTokens
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, err
+	}
+	rawToken := models.PersonalAccessTokenPrefix + hex.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	token := &models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        name,
+		Token:       hashToken(rawToken),
+		WorkspaceID: workspaceID,
+		Scopes:      scopes,
+		ExpiresAt:   now.AddDate(0, 0, days),
+		CreatedAt:   now,
+	}
+	if err := s.pats.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	return &models.PersonalAccessTokenWithSecret{PersonalAccessToken: token, Secret: rawToken}, nil
+}
+
+// normalizeScopes validates requested scopes and returns them without duplicates, in the order of models.TokenScopes
+func normalizeScopes(requested []models.TokenScope) ([]models.TokenScope, error) {
+	if len(requested) == 0 {
+		return nil, ErrInvalidTokenScopes
+	}
+	wanted := make(map[models.TokenScope]bool, len(requested))
+	for _, scope := range requested {
+		if !scope.IsValid() {
+			return nil, ErrInvalidTokenScopes
+		}
+		wanted[scope] = true
+	}
+
+	var scopes []models.TokenScope
+	for _, scope := range models.TokenScopes {
+		if wanted[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// ListPersonalAccessTokens returns the personal access tokens of a user, newest first
+func (s *AuthService) ListPersonalAccessTokens(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	tokens, err := s.pats.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []*models.PersonalAccessToken{}
+	}
+	return tokens, nil
+}
+
+// RevokePersonalAccessToken deletes a personal access token of a user
+func (s *AuthService) RevokePersonalAccessToken(ctx context.Context, userID, id primitive.ObjectID) error {
+	err := s.pats.Delete(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrTokenNotFound
+	}
 	return err
 }
+
+// ValidatePersonalAccessToken checks a personal access token and returns the claims of its owner
+// along with the token, recording when it was used
+func (s *AuthService) ValidatePersonalAccessToken(ctx context.Context, rawToken string) (*Claims, *models.PersonalAccessToken, error) {
+	token, err := s.pats.GetByHash(ctx, hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	if token.IsExpired() {
+		return nil, nil, ErrExpiredToken
+	}
+
+	user, err := s.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= tokenTouchInterval {
+		if err := s.pats.Touch(ctx, token.ID, now); err != nil {
+			fmt.Printf("Warning: Failed to record use of personal access token %s: %v\n", token.ID.Hex(), err)
+		}
+		token.LastUsedAt = &now
+	}
+	return &Claims{UserID: user.ID.Hex(), Email: user.Email}, token, nil
+}
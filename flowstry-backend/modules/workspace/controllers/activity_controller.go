@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityController handles the activity feed endpoints
+type ActivityController struct {
+	activityService *services.ActivityService
+	diagramService  *services.DiagramService
+	memberService   *services.MemberService
+}
+
+// NewActivityController creates a new activity controller
+func NewActivityController(activityService *services.ActivityService, diagramService *services.DiagramService, memberService *services.MemberService) *ActivityController {
+	return &ActivityController{
+		activityService: activityService,
+		diagramService:  diagramService,
+		memberService:   memberService,
+	}
+}
+
+// Workspace lists one page of a workspace's activity feed (any member)
+func (ac *ActivityController) Workspace(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	filter := &models.ActivityFilter{}
+	if filter.ActorID, err = parseObjectIDQuery(c, "actor_id", userID); err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !ac.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	entries, nextCursor, err := ac.activityService.List(ctx, workspaceID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list activity")
+	}
+
+	return utils.PaginatedResponse(c, entries, nextCursor)
+}
+
+// Diagram lists one page of the activity on a diagram (any member)
+func (ac *ActivityController) Diagram(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !ac.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+	if _, err := ac.diagramService.GetByID(ctx, diagramID, workspaceID); err != nil {
+		if err == services.ErrDiagramNotFound {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to get diagram")
+	}
+
+	entries, nextCursor, err := ac.activityService.List(ctx, workspaceID, &models.ActivityFilter{TargetID: &diagramID}, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list activity")
+	}
+
+	return utils.PaginatedResponse(c, entries, nextCursor)
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditController handles the workspace audit log endpoints (Admin+ only)
+type AuditController struct {
+	auditService  *services.AuditService
+	memberService *services.MemberService
+}
+
+// NewAuditController creates a new audit controller
+func NewAuditController(auditService *services.AuditService, memberService *services.MemberService) *AuditController {
+	return &AuditController{
+		auditService:  auditService,
+		memberService: memberService,
+	}
+}
+
+// parseAuditFilter reads the audit log filters (action, actor_id, target_id, created range)
+func parseAuditFilter(c *fiber.Ctx, userID primitive.ObjectID) (*models.AuditFilter, error) {
+	var err error
+	filter := &models.AuditFilter{}
+	for _, action := range strings.Split(c.Query("action"), ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			filter.Actions = append(filter.Actions, models.EventType(action))
+		}
+	}
+	if filter.ActorID, err = parseObjectIDQuery(c, "actor_id", userID); err != nil {
+		return nil, err
+	}
+	if filter.TargetID, err = parseObjectIDQuery(c, "target_id", userID); err != nil {
+		return nil, err
+	}
+	if filter.Created, err = parseDateRange(c, "created"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// List lists one page of a workspace's audit log, newest first by default
+func (ac *AuditController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	filter, err := parseAuditFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !ac.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only workspace admins can view the audit log")
+	}
+
+	entries, nextCursor, err := ac.auditService.List(ctx, workspaceID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list audit log")
+	}
+
+	return utils.PaginatedResponse(c, entries, nextCursor)
+}
+
+// Export downloads every audit entry matching the filters as CSV or JSON Lines (?format=csv|jsonl)
+func (ac *AuditController) Export(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	filter, err := parseAuditFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	format := c.Query("format", services.AuditExportCSV)
+
+	// Exports walk the whole log, so they get more time than a single page
+	ctx, cancel := requestContext(c, 60*time.Second)
+	defer cancel()
+
+	if !ac.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only workspace admins can export the audit log")
+	}
+
+	var buf bytes.Buffer
+	if err := ac.auditService.Export(ctx, workspaceID, filter, format, &buf); err != nil {
+		if errors.Is(err, services.ErrInvalidExportFormat) {
+			return utils.BadRequest(c, "Invalid format (use csv or jsonl)")
+		}
+		return utils.InternalError(c, "Failed to export audit log")
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == services.AuditExportJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", "attachment; filename=\"audit-"+workspaceID.Hex()+"."+format+"\"")
+	return c.Send(buf.Bytes())
+}
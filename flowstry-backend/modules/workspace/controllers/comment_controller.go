@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentController handles comment threads on diagrams
+type CommentController struct {
+	commentService *services.CommentService
+	memberService  *services.MemberService
+}
+
+// NewCommentController creates a new comment controller
+func NewCommentController(commentService *services.CommentService, memberService *services.MemberService) *CommentController {
+	return &CommentController{
+		commentService: commentService,
+		memberService:  memberService,
+	}
+}
+
+// commentError maps comment service errors to responses
+func commentError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrDiagramNotFound):
+		return utils.NotFound(c, "Diagram not found")
+	case errors.Is(err, services.ErrCommentNotFound):
+		return utils.NotFound(c, "Comment not found")
+	case errors.Is(err, services.ErrInvalidComment),
+		errors.Is(err, services.ErrInvalidAnchor),
+		errors.Is(err, services.ErrNotCommentThread):
+		return utils.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrNotCommentAuthor):
+		return utils.Forbidden(c, err.Error())
+	default:
+		return utils.InternalError(c, fallback)
+	}
+}
+
+// List lists one page of a diagram's threads with their replies (any member).
+// ?resolved=true|false and ?shape_id= narrow the listing.
+func (cc *CommentController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	filter := &models.CommentFilter{ShapeID: c.Query("shape_id")}
+	if raw := c.Query("resolved"); raw != "" {
+		resolved, err := strconv.ParseBool(raw)
+		if err != nil {
+			return utils.BadRequest(c, "Invalid resolved filter (use true or false)")
+		}
+		filter.Resolved = &resolved
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	threads, nextCursor, err := cc.commentService.ListThreads(ctx, workspaceID, diagramID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return commentError(c, err, "Failed to list comments")
+	}
+
+	return utils.PaginatedResponse(c, threads, nextCursor)
+}
+
+// Get returns a thread with its replies (any member)
+func (cc *CommentController) Get(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid comment ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	thread, err := cc.commentService.GetThread(ctx, workspaceID, diagramID, commentID)
+	if err != nil {
+		return commentError(c, err, "Failed to get comment")
+	}
+
+	return utils.SuccessResponse(c, thread)
+}
+
+// Create starts a thread on a diagram, optionally anchored to a shape or canvas point (Editor+)
+func (cc *CommentController) Create(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to comment")
+	}
+
+	thread, err := cc.commentService.Create(ctx, workspaceID, diagramID, userID, &req)
+	if err != nil {
+		return commentError(c, err, "Failed to create comment")
+	}
+
+	return utils.CreatedResponse(c, thread)
+}
+
+// Reply adds a reply to a thread (Editor+)
+func (cc *CommentController) Reply(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid comment ID")
+	}
+
+	var req models.CommentBodyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to comment")
+	}
+
+	reply, err := cc.commentService.Reply(ctx, workspaceID, diagramID, commentID, userID, &req)
+	if err != nil {
+		return commentError(c, err, "Failed to reply")
+	}
+
+	return utils.CreatedResponse(c, reply)
+}
+
+// Edit changes the body of the caller's own comment (Editor+)
+func (cc *CommentController) Edit(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid comment ID")
+	}
+
+	var req models.CommentBodyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to comment")
+	}
+
+	comment, err := cc.commentService.Edit(ctx, workspaceID, diagramID, commentID, userID, &req)
+	if err != nil {
+		return commentError(c, err, "Failed to update comment")
+	}
+
+	return utils.SuccessResponse(c, comment)
+}
+
+// Delete removes a comment, or a thread with its replies. Editors may delete their own comments,
+// admins anyone's.
+func (cc *CommentController) Delete(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid comment ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to delete comments")
+	}
+	moderate := cc.memberService.CanDeleteContent(ctx, workspaceID, userID)
+
+	if err := cc.commentService.Delete(ctx, workspaceID, diagramID, commentID, userID, moderate); err != nil {
+		return commentError(c, err, "Failed to delete comment")
+	}
+
+	return utils.SuccessMessageResponse(c, "Comment deleted")
+}
+
+// Resolve marks a thread resolved (Editor+)
+func (cc *CommentController) Resolve(c *fiber.Ctx) error {
+	return cc.setResolved(c, true)
+}
+
+// Reopen clears the resolution of a thread (Editor+)
+func (cc *CommentController) Reopen(c *fiber.Ctx) error {
+	return cc.setResolved(c, false)
+}
+
+func (cc *CommentController) setResolved(c *fiber.Ctx, resolved bool) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid comment ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !cc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to resolve comments")
+	}
+
+	var thread *models.CommentThread
+	if resolved {
+		thread, err = cc.commentService.Resolve(ctx, workspaceID, diagramID, commentID, userID)
+	} else {
+		thread, err = cc.commentService.Reopen(ctx, workspaceID, diagramID, commentID, userID)
+	}
+	if err != nil {
+		return commentError(c, err, "Failed to update comment")
+	}
+
+	return utils.SuccessResponse(c, thread)
+}
@@ -19,14 +19,27 @@ type DiagramController struct {
 	diagramService   *services.DiagramService
 	workspaceService *services.WorkspaceService
 	memberService    *services.MemberService
+	accessService    *services.AccessService
+	starService      *services.StarService
+	trashService     *services.TrashService
 }
 
 // NewDiagramController creates a new diagram controller
-func NewDiagramController(diagramService *services.DiagramService, workspaceService *services.WorkspaceService, memberService *services.MemberService) *DiagramController {
+func NewDiagramController(diagramService *services.DiagramService, workspaceService *services.WorkspaceService, memberService *services.MemberService, accessService *services.AccessService, starService *services.StarService, trashService *services.TrashService) *DiagramController {
 	return &DiagramController{
 		diagramService:   diagramService,
 		workspaceService: workspaceService,
 		memberService:    memberService,
+		accessService:    accessService,
+		starService:      starService,
+		trashService:     trashService,
+	}
+}
+
+// recordOpen records that the user opened a diagram; failures never block the request
+func (dc *DiagramController) recordOpen(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) {
+	if err := dc.accessService.RecordOpen(ctx, userID, workspaceID, diagramID); err != nil {
+		fmt.Printf("Warning: failed to record diagram access: %v\n", err)
 	}
 }
 
@@ -42,7 +55,7 @@ func (dc *DiagramController) Create(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := requestContext(c, 30*time.Second)
 	defer cancel()
 
 	// Verify user can create diagrams (Owner/Admin only)
@@ -124,7 +137,7 @@ func (dc *DiagramController) Create(c *fiber.Ctx) error {
 	return utils.CreatedResponse(c, resp)
 }
 
-// List lists all diagrams in a workspace
+// List lists one page of diagrams in a workspace
 func (dc *DiagramController) List(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -136,7 +149,16 @@ func (dc *DiagramController) List(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter, err := parseDiagramFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := dc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -146,7 +168,15 @@ func (dc *DiagramController) List(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Access denied")
 	}
 
-	diagrams, err := dc.diagramService.List(ctx, workspaceID)
+	diagrams, nextCursor, err := dc.diagramService.List(ctx, workspaceID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list diagrams")
+	}
+
+	starred, err := dc.starService.StarredSet(ctx, userID, []primitive.ObjectID{workspaceID})
 	if err != nil {
 		return utils.InternalError(c, "Failed to list diagrams")
 	}
@@ -154,6 +184,7 @@ func (dc *DiagramController) List(c *fiber.Ctx) error {
 	responses := make([]*models.DiagramResponse, len(diagrams))
 	for i, d := range diagrams {
 		resp := d.ToResponse()
+		_, resp.Starred = starred[d.ID]
 		if d.Thumbnail != "" {
 			fmt.Printf("DEBUG: Found thumbnail for diagram %s: %s\n", d.ID.Hex(), d.Thumbnail)
 			if url, err := dc.diagramService.GetThumbnailURL(ctx, d.Thumbnail); err == nil {
@@ -168,7 +199,7 @@ func (dc *DiagramController) List(c *fiber.Ctx) error {
 		responses[i] = resp
 	}
 
-	return utils.SuccessResponse(c, responses)
+	return utils.PaginatedResponse(c, responses, nextCursor)
 }
 
 // ListRecent lists recent diagrams across all accessible workspaces
@@ -183,7 +214,12 @@ func (dc *DiagramController) ListRecent(c *fiber.Ctx) error {
 		limit = 12
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	tags, err := parseTagFilter(c)
+	if err != nil {
+		return tagValidationError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	workspaces, _, err := dc.workspaceService.List(ctx, userID)
@@ -201,7 +237,12 @@ func (dc *DiagramController) ListRecent(c *fiber.Ctx) error {
 		workspaceMap[w.ID] = w.Name
 	}
 
-	diagrams, err := dc.diagramService.ListRecent(ctx, workspaceIDs, limit)
+	diagrams, openedAt, err := dc.accessService.ListRecent(ctx, userID, workspaceIDs, limit, &models.DiagramFilter{Tags: tags})
+	if err != nil {
+		return utils.InternalError(c, "Failed to list recent diagrams")
+	}
+
+	starred, err := dc.starService.StarredSet(ctx, userID, workspaceIDs)
 	if err != nil {
 		return utils.InternalError(c, "Failed to list recent diagrams")
 	}
@@ -215,9 +256,17 @@ func (dc *DiagramController) ListRecent(c *fiber.Ctx) error {
 			FolderID:      d.FolderID,
 			Name:          d.Name,
 			Thumbnail:     d.Thumbnail,
+			Tags:          d.TagList(),
 			CreatedAt:     d.CreatedAt,
 			UpdatedAt:     d.UpdatedAt,
 		}
+		if t, ok := openedAt[d.ID]; ok {
+			resp.LastOpenedAt = &t
+		}
+		if t, ok := starred[d.ID]; ok {
+			resp.Starred = true
+			resp.StarredAt = &t
+		}
 		if d.Thumbnail != "" {
 			if url, err := dc.diagramService.GetThumbnailURL(ctx, d.Thumbnail); err == nil {
 				resp.ThumbnailURL = url
@@ -246,7 +295,7 @@ func (dc *DiagramController) Get(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := dc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -264,7 +313,12 @@ func (dc *DiagramController) Get(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to get diagram")
 	}
 
+	dc.recordOpen(ctx, userID, workspaceID, diagramID)
+
 	resp := diagram.ToResponse()
+	if starred, err := dc.starService.IsStarred(ctx, userID, workspaceID, diagram.ID); err == nil {
+		resp.Starred = starred
+	}
 	if diagram.Thumbnail != "" {
 		if url, err := dc.diagramService.GetThumbnailURL(ctx, diagram.Thumbnail); err == nil {
 			resp.ThumbnailURL = url
@@ -291,7 +345,7 @@ func (dc *DiagramController) Download(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := requestContext(c, 60*time.Second)
 	defer cancel()
 
 	if err := dc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -309,6 +363,8 @@ func (dc *DiagramController) Download(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to download diagram")
 	}
 
+	dc.recordOpen(ctx, userID, workspaceID, diagramID)
+
 	c.Set("Content-Type", "application/octet-stream")
 	c.Set("Content-Disposition", "attachment; filename=\""+diagram.Name+".flowstry\"")
 	return c.Send(data)
@@ -331,7 +387,7 @@ func (dc *DiagramController) Update(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := requestContext(c, 30*time.Second)
 	defer cancel()
 
 	// Verify user can edit diagrams (Owner/Admin/Editor)
@@ -361,6 +417,13 @@ func (dc *DiagramController) Update(c *fiber.Ctx) error {
 			sanitized := utils.SanitizeString(*req.Name, 100)
 			req.Name = &sanitized
 		}
+		if req.Tags != nil {
+			tags, err := services.NormalizeTags(*req.Tags)
+			if err != nil {
+				return tagValidationError(c, err)
+			}
+			req.Tags = &tags
+		}
 
 		diagram, err := dc.diagramService.Update(ctx, diagramID, workspaceID, &req)
 		if err != nil {
@@ -371,6 +434,9 @@ func (dc *DiagramController) Update(c *fiber.Ctx) error {
 		}
 
 		resp := diagram.ToResponse()
+		if starred, err := dc.starService.IsStarred(ctx, userID, workspaceID, diagram.ID); err == nil {
+			resp.Starred = starred
+		}
 		if diagram.Thumbnail != "" {
 			if url, err := dc.diagramService.GetThumbnailURL(ctx, diagram.Thumbnail); err == nil {
 				resp.ThumbnailURL = url
@@ -406,6 +472,9 @@ func (dc *DiagramController) Update(c *fiber.Ctx) error {
 	}
 
 	resp := diagram.ToResponse()
+	if starred, err := dc.starService.IsStarred(ctx, userID, workspaceID, diagram.ID); err == nil {
+		resp.Starred = starred
+	}
 	if diagram.Thumbnail != "" {
 		if url, err := dc.diagramService.GetThumbnailURL(ctx, diagram.Thumbnail); err == nil {
 			resp.ThumbnailURL = url
@@ -431,7 +500,7 @@ func (dc *DiagramController) Delete(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := requestContext(c, 30*time.Second)
 	defer cancel()
 
 	// Verify user can delete diagrams (Owner or Admin)
@@ -467,7 +536,7 @@ func (dc *DiagramController) Restore(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := requestContext(c, 30*time.Second)
 	defer cancel()
 
 	// Verify user can restore diagrams (Owner or Admin)
@@ -503,7 +572,7 @@ func (dc *DiagramController) HardDelete(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := requestContext(c, 30*time.Second)
 	defer cancel()
 
 	// Verify user can permanently delete diagrams (Owner/Admin only)
@@ -522,7 +591,7 @@ func (dc *DiagramController) HardDelete(c *fiber.Ctx) error {
 	return utils.SuccessMessageResponse(c, "Diagram permanently deleted")
 }
 
-// ListTrash lists all diagrams in trash
+// ListTrash lists one page of diagrams in trash
 func (dc *DiagramController) ListTrash(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -534,7 +603,16 @@ func (dc *DiagramController) ListTrash(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter, err := parseDiagramFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := dc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -544,7 +622,15 @@ func (dc *DiagramController) ListTrash(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Access denied")
 	}
 
-	diagrams, err := dc.diagramService.ListTrash(ctx, workspaceID)
+	diagrams, nextCursor, err := dc.diagramService.ListTrash(ctx, workspaceID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list trash")
+	}
+
+	retention, err := dc.trashService.Retention(ctx, workspaceID)
 	if err != nil {
 		return utils.InternalError(c, "Failed to list trash")
 	}
@@ -552,9 +638,10 @@ func (dc *DiagramController) ListTrash(c *fiber.Ctx) error {
 	responses := make([]*models.DiagramResponse, len(diagrams))
 	for i, d := range diagrams {
 		responses[i] = d.ToResponse()
+		responses[i].PurgeAt = services.PurgeAt(d.DeletedAt, retention)
 	}
 
-	return utils.SuccessResponse(c, responses)
+	return utils.PaginatedResponse(c, responses, nextCursor)
 }
 
 // GetUploadURL generates a signed URL for uploading a file
@@ -574,7 +661,7 @@ func (dc *DiagramController) GetUploadURL(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := dc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -595,10 +682,10 @@ func (dc *DiagramController) GetUploadURL(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to generate upload URL")
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{
-		"upload_url":  url,
-		"object_name": objectName,
-		"expires_in":  900, // 15 minutes
+	return utils.SuccessResponse(c, &models.UploadURLResponse{
+		UploadURL:  url,
+		ObjectName: objectName,
+		ExpiresIn:  900, // 15 minutes
 	})
 }
 
@@ -619,7 +706,7 @@ func (dc *DiagramController) GetDownloadURL(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := dc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -637,8 +724,232 @@ func (dc *DiagramController) GetDownloadURL(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to generate download URL")
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{
-		"download_url": url,
-		"expires_in":   3600, // 60 minutes
+	dc.recordOpen(ctx, userID, workspaceID, diagramID)
+
+	return utils.SuccessResponse(c, &models.DownloadURLResponse{
+		DownloadURL: url,
+		ExpiresIn:   3600, // 60 minutes
 	})
 }
+
+// Star adds a diagram to the user's starred diagrams
+func (dc *DiagramController) Star(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !dc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	if _, err := dc.starService.Star(ctx, userID, workspaceID, diagramID); err != nil {
+		if errors.Is(err, services.ErrDiagramNotFound) {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to star diagram")
+	}
+
+	return utils.SuccessMessageResponse(c, "Diagram starred")
+}
+
+// Unstar removes a diagram from the user's starred diagrams
+func (dc *DiagramController) Unstar(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !dc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	if err := dc.starService.Unstar(ctx, userID, workspaceID, diagramID); err != nil {
+		if errors.Is(err, services.ErrStarNotFound) {
+			return utils.NotFound(c, "Diagram is not starred")
+		}
+		return utils.InternalError(c, "Failed to unstar diagram")
+	}
+
+	return utils.SuccessMessageResponse(c, "Diagram unstarred")
+}
+
+// ListStarred lists the user's starred diagrams across all accessible workspaces
+func (dc *DiagramController) ListStarred(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	workspaces, _, err := dc.workspaceService.List(ctx, userID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list workspaces")
+	}
+	if len(workspaces) == 0 {
+		return utils.SuccessResponse(c, []*models.RecentDiagramResponse{})
+	}
+
+	workspaceIDs := make([]primitive.ObjectID, 0, len(workspaces))
+	workspaceMap := make(map[primitive.ObjectID]string, len(workspaces))
+	for _, w := range workspaces {
+		workspaceIDs = append(workspaceIDs, w.ID)
+		workspaceMap[w.ID] = w.Name
+	}
+
+	diagrams, starredAt, err := dc.starService.ListStarred(ctx, userID, workspaceIDs)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list starred diagrams")
+	}
+
+	responses := make([]*models.RecentDiagramResponse, len(diagrams))
+	for i, d := range diagrams {
+		t := starredAt[d.ID]
+		resp := &models.RecentDiagramResponse{
+			ID:            d.ID,
+			WorkspaceID:   d.WorkspaceID,
+			WorkspaceName: workspaceMap[d.WorkspaceID],
+			FolderID:      d.FolderID,
+			Name:          d.Name,
+			Thumbnail:     d.Thumbnail,
+			Tags:          d.TagList(),
+			Starred:       true,
+			StarredAt:     &t,
+			CreatedAt:     d.CreatedAt,
+			UpdatedAt:     d.UpdatedAt,
+		}
+		if d.Thumbnail != "" {
+			if url, err := dc.diagramService.GetThumbnailURL(ctx, d.Thumbnail); err == nil {
+				resp.ThumbnailURL = url
+			}
+		}
+		responses[i] = resp
+	}
+
+	return utils.SuccessResponse(c, responses)
+}
+
+// Move moves a diagram into a folder, or to the workspace root
+func (dc *DiagramController) Move(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !dc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to move diagrams")
+	}
+
+	var req models.MoveDiagramRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	diagram, err := dc.diagramService.Move(ctx, diagramID, workspaceID, req.FolderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDiagramNotFound):
+			return utils.NotFound(c, "Diagram not found")
+		case errors.Is(err, services.ErrInvalidParentFolder):
+			return utils.BadRequest(c, "Target folder not found in this workspace")
+		}
+		return utils.InternalError(c, "Failed to move diagram")
+	}
+
+	return utils.SuccessResponse(c, diagram.ToResponse())
+}
+
+// MoveMany moves several diagrams into a folder, or to the workspace root, in one request
+func (dc *DiagramController) MoveMany(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	var req models.MoveDiagramsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	if len(req.DiagramIDs) == 0 {
+		return utils.BadRequest(c, "At least one diagram ID is required")
+	}
+	if len(req.DiagramIDs) > maxBulkMoveDiagrams {
+		return utils.BadRequest(c, fmt.Sprintf("At most %d diagrams can be moved at once", maxBulkMoveDiagrams))
+	}
+
+	diagramIDs := make([]primitive.ObjectID, 0, len(req.DiagramIDs))
+	for _, idStr := range req.DiagramIDs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return utils.BadRequest(c, "Invalid diagram ID: "+idStr)
+		}
+		diagramIDs = append(diagramIDs, id)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !dc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to move diagrams")
+	}
+
+	moved, err := dc.diagramService.MoveMany(ctx, workspaceID, diagramIDs, req.FolderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDiagramNotFound):
+			return utils.NotFound(c, "One or more diagrams not found")
+		case errors.Is(err, services.ErrInvalidParentFolder):
+			return utils.BadRequest(c, "Target folder not found in this workspace")
+		}
+		return utils.InternalError(c, "Failed to move diagrams")
+	}
+
+	return utils.SuccessResponse(c, &models.MoveDiagramsResponse{Moved: moved})
+}
@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// embedCacheMaxAge is how long consumers may reuse a snapshot before revalidating with the ETag
+	embedCacheMaxAge = 60
+	// Default and maximum dimensions of embedded snapshots
+	defaultEmbedWidth  = 800
+	defaultEmbedHeight = 600
+	maxEmbedDimension  = 4096
+)
+
+// EmbedController handles embed token management, the SVG embed endpoint and oEmbed
+type EmbedController struct {
+	embedService  *services.EmbedService
+	memberService *services.MemberService
+}
+
+// NewEmbedController creates a new embed controller
+func NewEmbedController(embedService *services.EmbedService, memberService *services.MemberService) *EmbedController {
+	return &EmbedController{
+		embedService:  embedService,
+		memberService: memberService,
+	}
+}
+
+// Create creates a new embed token for a diagram
+func (ec *EmbedController) Create(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	var req models.CreateEmbedTokenRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	if len(req.Label) > 100 {
+		return utils.BadRequest(c, "Label must be at most 100 characters")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	// Only editors can publish diagrams for embedding
+	if !ec.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to embed diagrams")
+	}
+
+	token, rawToken, err := ec.embedService.Create(ctx, workspaceID, diagramID, userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrDiagramNotFound) {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to create embed token")
+	}
+
+	resp := token.ToResponse()
+	resp.Token = rawToken
+	resp.EmbedURL = ec.embedService.BuildURL(rawToken)
+	return utils.CreatedResponse(c, resp)
+}
+
+// List lists all embed tokens of a diagram
+func (ec *EmbedController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !ec.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage embeds")
+	}
+
+	tokens, err := ec.embedService.List(ctx, workspaceID, diagramID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list embed tokens")
+	}
+
+	responses := make([]*models.EmbedTokenResponse, len(tokens))
+	for i, t := range tokens {
+		responses[i] = t.ToResponse()
+	}
+
+	return utils.SuccessResponse(c, responses)
+}
+
+// Revoke revokes an embed token
+func (ec *EmbedController) Revoke(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(c.Params("embedId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid embed ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !ec.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage embeds")
+	}
+
+	err = ec.embedService.Revoke(ctx, workspaceID, diagramID, tokenID)
+	if err != nil {
+		if err == services.ErrEmbedTokenNotFound {
+			return utils.NotFound(c, "Embed token not found")
+		}
+		return utils.InternalError(c, "Failed to revoke embed token")
+	}
+
+	return utils.SuccessMessageResponse(c, "Embed token revoked")
+}
+
+// Embed renders the diagram behind an embed token as an SVG snapshot (public)
+// GET /embed/:token?maxwidth=&maxheight=
+func (ec *EmbedController) Embed(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return utils.BadRequest(c, "Token is required")
+	}
+
+	maxWidth, maxHeight, ok := parseEmbedSize(c)
+	if !ok {
+		return utils.BadRequest(c, "maxwidth and maxheight must be between 1 and 4096")
+	}
+
+	ctx, cancel := requestContext(c, 20*time.Second)
+	defer cancel()
+
+	_, diagram, err := ec.embedService.Resolve(ctx, token)
+	if err != nil {
+		if err == services.ErrEmbedTokenNotFound {
+			return utils.NotFound(c, "Embed not found")
+		}
+		return utils.InternalError(c, "Failed to open embed")
+	}
+
+	// The ETag only depends on the diagram version and the requested size, so unchanged diagrams
+	// are revalidated for free
+	etag := fmt.Sprintf(`W/"%s-v%d-%dx%d"`, diagram.ID.Hex(), diagram.Version, maxWidth, maxHeight)
+	c.Set("ETag", etag)
+	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=300", embedCacheMaxAge))
+	c.Set("Last-Modified", diagram.UpdatedAt.UTC().Format(http.TimeFormat))
+	if c.Get("If-None-Match") == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	snapshot, err := ec.embedService.Render(ctx, diagram, maxWidth, maxHeight)
+	if err != nil {
+		return renderError(c, err)
+	}
+
+	// Snapshots are served as standalone documents; forbid scripts and allow framing by any wiki
+	c.Set("Content-Security-Policy", "default-src 'none'; img-src https: http: data:; style-src 'unsafe-inline'")
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("Referrer-Policy", "no-referrer")
+	c.Set(fiber.HeaderContentType, "image/svg+xml; charset=utf-8")
+	return c.Send(snapshot.SVG)
+}
+
+// OEmbed implements the oEmbed provider endpoint for embed URLs (public)
+// GET /oembed?url=&format=json&maxwidth=&maxheight=
+func (ec *EmbedController) OEmbed(c *fiber.Ctx) error {
+	if format := c.Query("format", "json"); format != "json" {
+		return utils.ErrorResponse(c, fiber.StatusNotImplemented, "Only the json format is supported")
+	}
+
+	token, err := ec.embedService.TokenFromURL(c.Query("url"))
+	if err != nil {
+		return utils.NotFound(c, "Unknown embed URL")
+	}
+
+	maxWidth, maxHeight, ok := parseEmbedSize(c)
+	if !ok {
+		return utils.BadRequest(c, "maxwidth and maxheight must be between 1 and 4096")
+	}
+
+	ctx, cancel := requestContext(c, 20*time.Second)
+	defer cancel()
+
+	_, diagram, err := ec.embedService.Resolve(ctx, token)
+	if err != nil {
+		if err == services.ErrEmbedTokenNotFound {
+			return utils.NotFound(c, "Embed not found")
+		}
+		return utils.InternalError(c, "Failed to open embed")
+	}
+
+	snapshot, err := ec.embedService.Render(ctx, diagram, maxWidth, maxHeight)
+	if err != nil {
+		return renderError(c, err)
+	}
+
+	src := ec.embedService.BuildURL(token)
+	if c.Query("maxwidth") != "" || c.Query("maxheight") != "" {
+		src += fmt.Sprintf("?maxwidth=%d&maxheight=%d", maxWidth, maxHeight)
+	}
+
+	resp := &models.OEmbedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        diagram.Name,
+		ProviderName: "Flowstry",
+		ProviderURL:  ec.embedService.ProviderURL(),
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" title="%s" style="border:0" loading="lazy" sandbox></iframe>`,
+			html.EscapeString(src), snapshot.Width, snapshot.Height, html.EscapeString(diagram.Name)),
+		Width:           snapshot.Width,
+		Height:          snapshot.Height,
+		ThumbnailURL:    src,
+		ThumbnailWidth:  snapshot.Width,
+		ThumbnailHeight: snapshot.Height,
+		CacheAge:        embedCacheMaxAge,
+	}
+
+	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", embedCacheMaxAge))
+	// oEmbed consumers expect the bare oEmbed object, not the API envelope
+	return c.JSON(resp)
+}
+
+// renderError reports a failed snapshot render for the embed and oEmbed endpoints
+func renderError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrWorkspaceNotEncrypted) {
+		return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "Diagram cannot be rendered")
+	}
+	return utils.InternalError(c, "Failed to render diagram")
+}
+
+// parseEmbedSize reads the optional maxwidth/maxheight query parameters
+func parseEmbedSize(c *fiber.Ctx) (int, int, bool) {
+	width, height := defaultEmbedWidth, defaultEmbedHeight
+
+	if v := c.Query("maxwidth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxEmbedDimension {
+			return 0, 0, false
+		}
+		width = n
+	}
+	if v := c.Query("maxheight"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxEmbedDimension {
+			return 0, 0, false
+		}
+		height = n
+	}
+
+	return width, height, true
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRenderError(t *testing.T) {
+	cases := []struct {
+		err     error
+		status  int
+		message string
+	}{
+		{fmt.Errorf("read diagram: %w", services.ErrWorkspaceNotEncrypted), fiber.StatusUnprocessableEntity, "Diagram cannot be rendered"},
+		{errors.New("storage unavailable"), fiber.StatusInternalServerError, "Failed to render diagram"},
+	}
+
+	for _, tc := range cases {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return renderError(c, tc.err) })
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body utils.Response
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status || body.Error != tc.message {
+			t.Errorf("%v: %d %q, want %d %q", tc.err, resp.StatusCode, body.Error, tc.status, tc.message)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package controllers
 
 import (
-	"context"
+	"strings"
 	"time"
 
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
@@ -40,7 +40,7 @@ func NewFilesController(
 	}
 }
 
-// List returns all folders and diagrams in a workspace
+// List returns one page of folders and diagrams in a workspace (folders first)
 // GET /workspaces/:id/files
 func (fc *FilesController) List(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
@@ -53,7 +53,43 @@ func (fc *FilesController) List(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	diagramFilter, err := parseDiagramFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	// Folders in a folder listing are its subfolders
+	folderFilter := &models.FolderFilter{
+		Query:     diagramFilter.Query,
+		ParentID:  diagramFilter.FolderID,
+		RootOnly:  diagramFilter.RootOnly,
+		CreatedBy: diagramFilter.CreatedBy,
+		Created:   diagramFilter.Created,
+		Updated:   diagramFilter.Updated,
+	}
+
+	// The listing pages through folders first, then diagrams; the cursor records the phase
+	// Without a limit or cursor every folder and diagram is returned, as before pagination
+	limit := page.Limit
+	if limit <= 0 && page.Cursor != "" {
+		limit = models.DefaultPageSize
+	}
+	if limit > models.MaxPageSize {
+		limit = models.MaxPageSize
+	}
+	phase, cursor := filesPhaseFolders, page.Cursor
+	if page.Cursor != "" {
+		phase, cursor, err = splitFilesCursor(page.Cursor)
+		if err != nil {
+			return listQueryError(c, err)
+		}
+	}
+
+	ctx, cancel := requestContext(c, 15*time.Second)
 	defer cancel()
 
 	// Verify workspace ownership
@@ -64,36 +100,99 @@ func (fc *FilesController) List(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Access denied")
 	}
 
-	// Get all folders
-	folders, err := fc.folderService.List(ctx, workspaceID)
-	if err != nil {
-		return utils.InternalError(c, "Failed to list folders")
-	}
+	folderResponses := []*models.FolderResponse{}
+	diagramResponses := []*models.DiagramResponse{}
+	nextCursor := ""
 
-	folderResponses := make([]*models.FolderResponse, len(folders))
-	for i, f := range folders {
-		folderResponses[i] = f.ToResponse()
+	if phase == filesPhaseFolders {
+		folderSort := page.Sort
+		if folderSort == models.SortBySize {
+			folderSort = models.SortByName
+		}
+		folders, folderNext, err := fc.folderService.List(ctx, workspaceID, folderFilter, &models.PageRequest{
+			Limit:  limit,
+			Cursor: cursor,
+			Sort:   folderSort,
+			Order:  page.Order,
+		})
+		if err != nil {
+			if isListQueryError(err) {
+				return listQueryError(c, err)
+			}
+			return utils.InternalError(c, "Failed to list folders")
+		}
+		if err := fc.folderService.AttachDiagramCounts(ctx, workspaceID, folders...); err != nil {
+			return utils.InternalError(c, "Failed to list folders")
+		}
+
+		for _, f := range folders {
+			folderResponses = append(folderResponses, f.ToResponse())
+		}
+
+		if folderNext != "" {
+			return utils.PaginatedResponse(c, &WorkspaceFilesResponse{
+				Folders:  folderResponses,
+				Diagrams: diagramResponses,
+			}, filesPhaseFolders+folderNext)
+		}
+
+		// Folders are exhausted; fill the rest of the page with diagrams
+		phase, cursor = filesPhaseDiagrams, ""
+		if limit > 0 {
+			limit -= int64(len(folders))
+			if limit == 0 {
+				return utils.PaginatedResponse(c, &WorkspaceFilesResponse{
+					Folders:  folderResponses,
+					Diagrams: diagramResponses,
+				}, filesPhaseDiagrams)
+			}
+		}
 	}
 
-	// Get all diagrams
-	diagrams, err := fc.diagramService.List(ctx, workspaceID)
+	diagrams, diagramNext, err := fc.diagramService.List(ctx, workspaceID, diagramFilter, &models.PageRequest{
+		Limit:  limit,
+		Cursor: cursor,
+		Sort:   page.Sort,
+		Order:  page.Order,
+	})
 	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
 		return utils.InternalError(c, "Failed to list diagrams")
 	}
 
-	diagramResponses := make([]*models.DiagramResponse, len(diagrams))
-	for i, d := range diagrams {
+	for _, d := range diagrams {
 		resp := d.ToResponse()
 		if d.Thumbnail != "" {
 			if url, err := fc.diagramService.GetThumbnailURL(ctx, d.Thumbnail); err == nil {
 				resp.ThumbnailURL = url
 			}
 		}
-		diagramResponses[i] = resp
+		diagramResponses = append(diagramResponses, resp)
+	}
+	if diagramNext != "" {
+		nextCursor = filesPhaseDiagrams + diagramNext
 	}
 
-	return utils.SuccessResponse(c, &WorkspaceFilesResponse{
+	return utils.PaginatedResponse(c, &WorkspaceFilesResponse{
 		Folders:  folderResponses,
 		Diagrams: diagramResponses,
-	})
+	}, nextCursor)
+}
+
+// Files listing cursor prefixes: which collection the next page continues in
+const (
+	filesPhaseFolders  = "f."
+	filesPhaseDiagrams = "d."
+)
+
+// splitFilesCursor separates a files listing cursor into its phase and inner cursor
+func splitFilesCursor(cursor string) (string, string, error) {
+	for _, phase := range []string{filesPhaseFolders, filesPhaseDiagrams} {
+		if strings.HasPrefix(cursor, phase) {
+			return phase, strings.TrimPrefix(cursor, phase), nil
+		}
+	}
+	return "", "", services.ErrInvalidCursor
 }
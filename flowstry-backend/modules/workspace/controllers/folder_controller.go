@@ -1,7 +1,8 @@
 package controllers
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
@@ -11,19 +12,24 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// maxBulkMoveDiagrams caps how many diagrams one move request can touch
+const maxBulkMoveDiagrams = 500
+
 // FolderController handles folder endpoints
 type FolderController struct {
 	folderService    *services.FolderService
 	workspaceService *services.WorkspaceService
 	memberService    *services.MemberService
+	trashService     *services.TrashService
 }
 
 // NewFolderController creates a new folder controller
-func NewFolderController(folderService *services.FolderService, workspaceService *services.WorkspaceService, memberService *services.MemberService) *FolderController {
+func NewFolderController(folderService *services.FolderService, workspaceService *services.WorkspaceService, memberService *services.MemberService, trashService *services.TrashService) *FolderController {
 	return &FolderController{
 		folderService:    folderService,
 		workspaceService: workspaceService,
 		memberService:    memberService,
+		trashService:     trashService,
 	}
 }
 
@@ -39,7 +45,7 @@ func (fc *FolderController) Create(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Verify user can create folders (Owner, Admin, or Editor)
@@ -57,8 +63,11 @@ func (fc *FolderController) Create(c *fiber.Ctx) error {
 	}
 	req.Name = utils.SanitizeString(req.Name, 100)
 
-	folder, err := fc.folderService.Create(ctx, workspaceID, &req)
+	folder, err := fc.folderService.Create(ctx, userID, workspaceID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidParentFolder) {
+			return utils.BadRequest(c, "Parent folder not found in this workspace")
+		}
 		return utils.InternalError(c, "Failed to create folder")
 	}
 
@@ -77,7 +86,16 @@ func (fc *FolderController) List(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter, err := parseFolderFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := fc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -87,8 +105,15 @@ func (fc *FolderController) List(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Access denied")
 	}
 
-	folders, err := fc.folderService.List(ctx, workspaceID)
+	folders, nextCursor, err := fc.folderService.List(ctx, workspaceID, filter, page)
 	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list folders")
+	}
+
+	if err := fc.folderService.AttachDiagramCounts(ctx, workspaceID, folders...); err != nil {
 		return utils.InternalError(c, "Failed to list folders")
 	}
 
@@ -97,7 +122,7 @@ func (fc *FolderController) List(c *fiber.Ctx) error {
 		responses[i] = f.ToResponse()
 	}
 
-	return utils.SuccessResponse(c, responses)
+	return utils.PaginatedResponse(c, responses, nextCursor)
 }
 
 // Get retrieves a folder by ID
@@ -117,7 +142,7 @@ func (fc *FolderController) Get(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid folder ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := fc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -135,6 +160,10 @@ func (fc *FolderController) Get(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to get folder")
 	}
 
+	if err := fc.folderService.AttachDiagramCounts(ctx, workspaceID, folder); err != nil {
+		return utils.InternalError(c, "Failed to get folder")
+	}
+
 	return utils.SuccessResponse(c, folder.ToResponse())
 }
 
@@ -155,7 +184,7 @@ func (fc *FolderController) Update(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid folder ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Verify user can update folders (Owner, Admin, or Editor)
@@ -175,16 +204,126 @@ func (fc *FolderController) Update(c *fiber.Ctx) error {
 
 	folder, err := fc.folderService.Update(ctx, folderID, workspaceID, &req)
 	if err != nil {
-		if err == services.ErrFolderNotFound {
+		return folderMoveError(c, err, "Failed to update folder")
+	}
+
+	return utils.SuccessResponse(c, folder.ToResponse())
+}
+
+// Move moves a folder under another folder, or to the workspace root
+func (fc *FolderController) Move(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	folderID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid folder ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !fc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to move folders")
+	}
+
+	var req models.MoveFolderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	folder, err := fc.folderService.Move(ctx, folderID, workspaceID, req.ParentFolderID)
+	if err != nil {
+		return folderMoveError(c, err, "Failed to move folder")
+	}
+
+	return utils.SuccessResponse(c, folder.ToResponse())
+}
+
+// Tree returns the folder hierarchy of a workspace with diagram counts
+func (fc *FolderController) Tree(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !fc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	tree, err := fc.folderService.Tree(ctx, workspaceID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to load folder tree")
+	}
+
+	return utils.SuccessResponse(c, tree)
+}
+
+// Breadcrumbs returns the path from the workspace root to a folder
+func (fc *FolderController) Breadcrumbs(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	folderID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid folder ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !fc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	crumbs, err := fc.folderService.Breadcrumbs(ctx, folderID, workspaceID)
+	if err != nil {
+		if errors.Is(err, services.ErrFolderNotFound) {
 			return utils.NotFound(c, "Folder not found")
 		}
-		return utils.InternalError(c, "Failed to update folder")
+		return utils.InternalError(c, "Failed to load breadcrumbs")
 	}
 
-	return utils.SuccessResponse(c, folder.ToResponse())
+	return utils.SuccessResponse(c, crumbs)
+}
+
+// folderMoveError maps folder and move errors to a response
+func folderMoveError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrFolderNotFound):
+		return utils.NotFound(c, "Folder not found")
+	case errors.Is(err, services.ErrInvalidParentFolder):
+		return utils.BadRequest(c, "Target folder not found in this workspace")
+	case errors.Is(err, services.ErrFolderCycle):
+		return utils.BadRequest(c, "Cannot move a folder into itself or one of its subfolders")
+	default:
+		return utils.InternalError(c, fallback)
+	}
 }
 
-// Delete deletes a folder
+// Delete moves a folder, its subfolders and their diagrams to trash
 func (fc *FolderController) Delete(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -201,7 +340,7 @@ func (fc *FolderController) Delete(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid folder ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Verify user can delete folders (Owner or Admin)
@@ -237,7 +376,7 @@ func (fc *FolderController) Restore(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid folder ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Verify user can restore folders (Owner or Admin)
@@ -256,7 +395,7 @@ func (fc *FolderController) Restore(c *fiber.Ctx) error {
 	return utils.SuccessMessageResponse(c, "Folder restored successfully")
 }
 
-// HardDelete permanently deletes a folder
+// HardDelete permanently deletes a folder and everything in it
 func (fc *FolderController) HardDelete(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -273,7 +412,8 @@ func (fc *FolderController) HardDelete(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid folder ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Subfolders and their diagrams' storage objects are removed too
+	ctx, cancel := requestContext(c, 60*time.Second)
 	defer cancel()
 
 	// Verify user can permanently delete folders (Owner/Admin only)
@@ -292,7 +432,7 @@ func (fc *FolderController) HardDelete(c *fiber.Ctx) error {
 	return utils.SuccessMessageResponse(c, "Folder permanently deleted")
 }
 
-// ListTrash lists all folders in trash
+// ListTrash lists one page of folders in trash
 func (fc *FolderController) ListTrash(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -304,7 +444,16 @@ func (fc *FolderController) ListTrash(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter, err := parseFolderFilter(c, userID)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if err := fc.workspaceService.VerifyAccess(ctx, workspaceID, userID); err != nil {
@@ -314,7 +463,15 @@ func (fc *FolderController) ListTrash(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Access denied")
 	}
 
-	folders, err := fc.folderService.ListTrash(ctx, workspaceID)
+	folders, nextCursor, err := fc.folderService.ListTrash(ctx, workspaceID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list trash")
+	}
+
+	retention, err := fc.trashService.Retention(ctx, workspaceID)
 	if err != nil {
 		return utils.InternalError(c, "Failed to list trash")
 	}
@@ -322,12 +479,13 @@ func (fc *FolderController) ListTrash(c *fiber.Ctx) error {
 	responses := make([]*models.FolderResponse, len(folders))
 	for i, f := range folders {
 		responses[i] = f.ToResponse()
+		responses[i].PurgeAt = services.PurgeAt(f.DeletedAt, retention)
 	}
 
-	return utils.SuccessResponse(c, responses)
+	return utils.PaginatedResponse(c, responses, nextCursor)
 }
 
-// AddDiagrams adds diagrams to a folder
+// AddDiagrams moves diagrams into a folder
 func (fc *FolderController) AddDiagrams(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -344,7 +502,7 @@ func (fc *FolderController) AddDiagrams(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid folder ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Verify user can add diagrams to folders (Owner, Admin, or Editor)
@@ -360,6 +518,9 @@ func (fc *FolderController) AddDiagrams(c *fiber.Ctx) error {
 	if len(req.DiagramIDs) == 0 {
 		return utils.BadRequest(c, "At least one diagram ID is required")
 	}
+	if len(req.DiagramIDs) > maxBulkMoveDiagrams {
+		return utils.BadRequest(c, fmt.Sprintf("At most %d diagrams can be moved at once", maxBulkMoveDiagrams))
+	}
 
 	diagramIDs := make([]primitive.ObjectID, 0, len(req.DiagramIDs))
 	for _, idStr := range req.DiagramIDs {
@@ -375,13 +536,16 @@ func (fc *FolderController) AddDiagrams(c *fiber.Ctx) error {
 		if err == services.ErrFolderNotFound {
 			return utils.NotFound(c, "Folder not found")
 		}
+		if err == services.ErrDiagramNotFound {
+			return utils.NotFound(c, "One or more diagrams not found")
+		}
 		return utils.InternalError(c, "Failed to add diagrams to folder")
 	}
 
 	return utils.SuccessMessageResponse(c, "Diagrams added to folder")
 }
 
-// RemoveDiagram removes a diagram from a folder
+// RemoveDiagram moves a diagram out of a folder to the workspace root
 func (fc *FolderController) RemoveDiagram(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -403,7 +567,7 @@ func (fc *FolderController) RemoveDiagram(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Verify user can remove diagrams from folders (Owner, Admin, or Editor)
@@ -416,6 +580,9 @@ func (fc *FolderController) RemoveDiagram(c *fiber.Ctx) error {
 		if err == services.ErrFolderNotFound {
 			return utils.NotFound(c, "Folder not found")
 		}
+		if err == services.ErrDiagramNotInFolder {
+			return utils.NotFound(c, "Diagram is not in this folder")
+		}
 		return utils.InternalError(c, "Failed to remove diagram from folder")
 	}
 
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"context"
 	"strings"
 	"time"
 
@@ -55,7 +54,7 @@ func (ic *InviteController) Create(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid role. Must be admin, editor, or viewer")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Check if user can manage members
@@ -77,7 +76,7 @@ func (ic *InviteController) Create(c *fiber.Ctx) error {
 	return utils.CreatedResponse(c, invite.ToResponse())
 }
 
-// List lists all pending invites for a workspace
+// List lists one page of pending invites for a workspace
 func (ic *InviteController) List(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -89,7 +88,19 @@ func (ic *InviteController) List(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter := &models.InviteFilter{}
+	if filter.InvitedBy, err = parseObjectIDQuery(c, "created_by", userID); err != nil {
+		return listQueryError(c, err)
+	}
+	if filter.Created, err = parseDateRange(c, "created"); err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Check if user can manage members
@@ -97,12 +108,15 @@ func (ic *InviteController) List(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Only owners and admins can view invites")
 	}
 
-	invites, err := ic.inviteService.ListPendingInvites(ctx, workspaceID)
+	invites, nextCursor, err := ic.inviteService.ListPendingInvites(ctx, workspaceID, filter, page)
 	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
 		return utils.InternalError(c, "Failed to list invites")
 	}
 
-	return utils.SuccessResponse(c, invites)
+	return utils.PaginatedResponse(c, invites, nextCursor)
 }
 
 // Revoke revokes a pending invite
@@ -122,7 +136,7 @@ func (ic *InviteController) Revoke(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid invite ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Check if user can manage members
@@ -148,7 +162,7 @@ func (ic *InviteController) GetByToken(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Token is required")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	details, err := ic.inviteService.GetInviteDetails(ctx, token)
@@ -183,7 +197,7 @@ func (ic *InviteController) Accept(c *fiber.Ctx) error {
 		return utils.Unauthorized(c, "User email not found")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	member, err := ic.inviteService.AcceptInvite(ctx, token, userID, userEmail)
@@ -203,10 +217,10 @@ func (ic *InviteController) Accept(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to accept invite")
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{
-		"message":      "Invite accepted successfully",
-		"workspace_id": member.WorkspaceID,
-		"role":         member.Role,
+	return utils.SuccessResponse(c, &models.AcceptInviteResponse{
+		Message:     "Invite accepted successfully",
+		WorkspaceID: member.WorkspaceID,
+		Role:        member.Role,
 	})
 }
 
@@ -217,7 +231,7 @@ func (ic *InviteController) ListUserInvites(c *fiber.Ctx) error {
 		return utils.Unauthorized(c, "User email not found")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	invites, err := ic.inviteService.ListUserInvites(ctx, userEmail)
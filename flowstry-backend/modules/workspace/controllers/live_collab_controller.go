@@ -1,10 +1,10 @@
 package controllers
 
 import (
-	"context"
 	"errors"
 	"time"
 
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
 	"github.com/flowstry/flowstry-backend/modules/workspace/services"
 	"github.com/flowstry/flowstry-backend/utils"
 	"github.com/gofiber/fiber/v2"
@@ -50,7 +50,7 @@ func (lc *LiveCollabController) GetStatus(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if !lc.memberService.CanView(ctx, workspaceID, userID) {
@@ -74,9 +74,9 @@ func (lc *LiveCollabController) GetStatus(c *fiber.Ctx) error {
 		return utils.ServiceUnavailable(c, "Live collaboration is not configured")
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{
-		"enabled": memberCount > 1,
-		"ws_url":  wsURL,
+	return utils.SuccessResponse(c, &models.LiveStatusResponse{
+		Enabled: memberCount > 1,
+		WSURL:   wsURL,
 	})
 }
 
@@ -97,7 +97,7 @@ func (lc *LiveCollabController) GetToken(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid diagram ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	if !lc.memberService.CanView(ctx, workspaceID, userID) {
@@ -137,9 +137,9 @@ func (lc *LiveCollabController) GetToken(c *fiber.Ctx) error {
 		return utils.ServiceUnavailable(c, "Live collaboration is not configured")
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{
-		"token":      token,
-		"expires_in": int(liveCollabTokenTTL.Seconds()),
-		"ws_url":     wsURL,
+	return utils.SuccessResponse(c, &models.LiveTokenResponse{
+		Token:     token,
+		ExpiresIn: int(liveCollabTokenTTL.Seconds()),
+		WSURL:     wsURL,
 	})
 }
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"context"
 	"time"
 
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
@@ -11,6 +10,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	defaultMemberSearchLimit = 8
+	maxMemberSearchLimit     = 25
+)
+
 // MemberController handles workspace member endpoints
 type MemberController struct {
 	memberService *services.MemberService
@@ -23,7 +27,7 @@ func NewMemberController(memberService *services.MemberService) *MemberControlle
 	}
 }
 
-// List lists all members of a workspace
+// List lists one page of members of a workspace
 func (mc *MemberController) List(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -35,7 +39,19 @@ func (mc *MemberController) List(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter := &models.MemberFilter{Role: models.WorkspaceRole(c.Query("role")), Query: c.Query("q")}
+	if filter.Role != "" && !filter.Role.IsValid() {
+		return utils.BadRequest(c, "Invalid role")
+	}
+	if filter.Joined, err = parseDateRange(c, "joined"); err != nil {
+		return listQueryError(c, err)
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Check if user has access to the workspace
@@ -43,11 +59,49 @@ func (mc *MemberController) List(c *fiber.Ctx) error {
 		return utils.Forbidden(c, "Access denied")
 	}
 
-	members, err := mc.memberService.ListMembers(ctx, workspaceID)
+	members, nextCursor, err := mc.memberService.ListMembers(ctx, workspaceID, filter, page)
 	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
 		return utils.InternalError(c, "Failed to list members")
 	}
 
+	return utils.PaginatedResponse(c, members, nextCursor)
+}
+
+// Search returns the members whose email, name or a word of their name starts with ?q=,
+// by name, for @mention autocompletion (any member)
+func (mc *MemberController) Search(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	limit := c.QueryInt("limit", defaultMemberSearchLimit)
+	if limit <= 0 || limit > maxMemberSearchLimit {
+		limit = maxMemberSearchLimit
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !mc.memberService.HasAccess(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	members, _, err := mc.memberService.ListMembers(ctx, workspaceID,
+		&models.MemberFilter{Query: c.Query("q")},
+		&models.PageRequest{Limit: int64(limit), Sort: models.SortByName, Order: models.SortAsc})
+	if err != nil {
+		return utils.InternalError(c, "Failed to search members")
+	}
+
 	return utils.SuccessResponse(c, members)
 }
 
@@ -68,7 +122,7 @@ func (mc *MemberController) Remove(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid user ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Check if user can manage members
@@ -116,7 +170,7 @@ func (mc *MemberController) UpdateRole(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid role")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	// Check if user can manage members
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MentionController handles @mention lookups
+type MentionController struct {
+	mentionService *services.MentionService
+	memberService  *services.MemberService
+}
+
+// NewMentionController creates a new mention controller
+func NewMentionController(mentionService *services.MentionService, memberService *services.MemberService) *MentionController {
+	return &MentionController{
+		mentionService: mentionService,
+		memberService:  memberService,
+	}
+}
+
+// Resolve returns the members a draft text mentions and the mentioned emails of people
+// outside the workspace, so the client can offer to invite them (any member)
+func (mc *MentionController) Resolve(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	var req models.ResolveMentionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !mc.memberService.HasAccess(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	result, err := mc.mentionService.Resolve(ctx, workspaceID, req.Text)
+	if err != nil {
+		return utils.InternalError(c, "Failed to resolve mentions")
+	}
+
+	return utils.SuccessResponse(c, result)
+}
+
+// ListForDiagram lists who is mentioned on a diagram and where, oldest first (any member)
+func (mc *MentionController) ListForDiagram(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !mc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	mentions, err := mc.mentionService.ListForDiagram(ctx, workspaceID, diagramID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list mentions")
+	}
+
+	return utils.SuccessResponse(c, mentions)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationController handles the current user's notification center
+type NotificationController struct {
+	notificationService *services.NotificationService
+}
+
+// NewNotificationController creates a new notification controller
+func NewNotificationController(notificationService *services.NotificationService) *NotificationController {
+	return &NotificationController{notificationService: notificationService}
+}
+
+// List lists one page of the user's notifications, most recent first (?unread=true for unread only)
+func (nc *NotificationController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	filter := &models.NotificationFilter{UnreadOnly: c.QueryBool("unread")}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	notifications, nextCursor, err := nc.notificationService.List(ctx, userID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return utils.InternalError(c, "Failed to list notifications")
+	}
+
+	return utils.PaginatedResponse(c, notifications, nextCursor)
+}
+
+// UnreadCount returns how many unread notifications the user has, e.g. for a badge
+func (nc *NotificationController) UnreadCount(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	count, err := nc.notificationService.CountUnread(ctx, userID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to count notifications")
+	}
+
+	return utils.SuccessResponse(c, &models.UnreadCountResponse{Unread: count})
+}
+
+// MarkRead marks one notification read
+func (nc *NotificationController) MarkRead(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	notificationID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid notification ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	marked, err := nc.notificationService.MarkRead(ctx, userID, []primitive.ObjectID{notificationID})
+	if err != nil {
+		return utils.InternalError(c, "Failed to mark notification read")
+	}
+
+	return utils.SuccessResponse(c, &models.MarkReadResponse{Marked: marked})
+}
+
+// MarkAllRead marks every notification of the user read
+func (nc *NotificationController) MarkAllRead(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	marked, err := nc.notificationService.MarkAllRead(ctx, userID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to mark notifications read")
+	}
+
+	return utils.SuccessResponse(c, &models.MarkReadResponse{Marked: marked})
+}
+
+// GetPreferences returns the user's notification preferences
+func (nc *NotificationController) GetPreferences(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	prefs, err := nc.notificationService.GetPreferences(ctx, userID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to get notification preferences")
+	}
+
+	return utils.SuccessResponse(c, prefs)
+}
+
+// UpdatePreferences replaces the notification types the user has muted
+func (nc *NotificationController) UpdatePreferences(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	var req models.NotificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	prefs, err := nc.notificationService.UpdatePreferences(ctx, userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidNotificationType) {
+			return utils.BadRequest(c, "Invalid notification type")
+		}
+		return utils.InternalError(c, "Failed to update notification preferences")
+	}
+
+	return utils.SuccessResponse(c, prefs)
+}
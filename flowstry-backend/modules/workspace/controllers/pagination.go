@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parsePageRequest reads the limit, cursor, sort and order query params
+func parsePageRequest(c *fiber.Ctx) (*models.PageRequest, error) {
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return nil, errors.New("limit must be positive")
+	}
+
+	return &models.PageRequest{
+		Limit:  int64(limit),
+		Cursor: c.Query("cursor"),
+		Sort:   models.SortField(c.Query("sort")),
+		Order:  models.SortOrder(c.Query("order")),
+	}, nil
+}
+
+// parseDateRange reads <name>_after and <name>_before (RFC 3339 or YYYY-MM-DD)
+func parseDateRange(c *fiber.Ctx, name string) (models.DateRange, error) {
+	var r models.DateRange
+	for _, bound := range []string{"after", "before"} {
+		key := name + "_" + bound
+		raw := c.Query(key)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", raw)
+			if err != nil {
+				return r, fmt.Errorf("invalid %s", key)
+			}
+		}
+		if bound == "after" {
+			r.After = &t
+		} else {
+			r.Before = &t
+		}
+	}
+	return r, nil
+}
+
+// parseObjectIDQuery reads an optional ObjectID query param; "me" resolves to the caller
+func parseObjectIDQuery(c *fiber.Ctx, name string, userID primitive.ObjectID) (*primitive.ObjectID, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	if raw == "me" {
+		return &userID, nil
+	}
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &id, nil
+}
+
+// parseDiagramFilter reads the diagram listing filters (q, tags, folder_id, created_by, date ranges)
+func parseDiagramFilter(c *fiber.Ctx, userID primitive.ObjectID) (*models.DiagramFilter, error) {
+	tags, err := parseTagFilter(c)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.DiagramFilter{Query: c.Query("q"), Tags: tags}
+	if c.Query("folder_id") == "root" {
+		filter.RootOnly = true
+	} else if filter.FolderID, err = parseObjectIDQuery(c, "folder_id", userID); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBy, err = parseObjectIDQuery(c, "created_by", userID); err != nil {
+		return nil, err
+	}
+	if filter.Created, err = parseDateRange(c, "created"); err != nil {
+		return nil, err
+	}
+	if filter.Updated, err = parseDateRange(c, "updated"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseFolderFilter reads the folder listing filters (q, parent_id, created_by, date ranges)
+func parseFolderFilter(c *fiber.Ctx, userID primitive.ObjectID) (*models.FolderFilter, error) {
+	var err error
+	filter := &models.FolderFilter{Query: c.Query("q")}
+	if c.Query("parent_id") == "root" {
+		filter.RootOnly = true
+	} else if filter.ParentID, err = parseObjectIDQuery(c, "parent_id", userID); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBy, err = parseObjectIDQuery(c, "created_by", userID); err != nil {
+		return nil, err
+	}
+	if filter.Created, err = parseDateRange(c, "created"); err != nil {
+		return nil, err
+	}
+	if filter.Updated, err = parseDateRange(c, "updated"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// listQueryError maps pagination and filter errors to a 400 response
+func listQueryError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTag), errors.Is(err, services.ErrTooManyTags):
+		return tagValidationError(c, err)
+	case errors.Is(err, services.ErrInvalidCursor):
+		return utils.BadRequest(c, "Invalid cursor")
+	case errors.Is(err, services.ErrInvalidSort):
+		return utils.BadRequest(c, "Invalid sort field")
+	case errors.Is(err, services.ErrInvalidOrder):
+		return utils.BadRequest(c, "Invalid sort order (use asc or desc)")
+	default:
+		return utils.BadRequest(c, err.Error())
+	}
+}
+
+// isListQueryError reports whether a service error was caused by bad pagination params
+func isListQueryError(err error) bool {
+	return errors.Is(err, services.ErrInvalidCursor) ||
+		errors.Is(err, services.ErrInvalidSort) ||
+		errors.Is(err, services.ErrInvalidOrder)
+}
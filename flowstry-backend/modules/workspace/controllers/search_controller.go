@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	minSearchQueryLen  = 2
+	maxSearchQueryLen  = 100
+)
+
+// SearchController handles search across the caller's workspaces
+type SearchController struct {
+	searchService    *services.SearchService
+	workspaceService *services.WorkspaceService
+}
+
+// NewSearchController creates a new search controller
+func NewSearchController(searchService *services.SearchService, workspaceService *services.WorkspaceService) *SearchController {
+	return &SearchController{
+		searchService:    searchService,
+		workspaceService: workspaceService,
+	}
+}
+
+// Search ranks diagrams and folders matching the query
+// GET /search?q=&workspace_id=&content=&limit=
+func (sc *SearchController) Search(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) < minSearchQueryLen || len(query) > maxSearchQueryLen {
+		return utils.BadRequest(c, "Query must be between 2 and 100 characters")
+	}
+
+	limit := c.QueryInt("limit", defaultSearchLimit)
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	var workspaceFilter *primitive.ObjectID
+	if raw := c.Query("workspace_id"); raw != "" {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return utils.BadRequest(c, "Invalid workspace ID")
+		}
+		workspaceFilter = &id
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	// Scope to workspaces the caller is a member of
+	workspaces, _, err := sc.workspaceService.List(ctx, userID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list workspaces")
+	}
+
+	workspaceIDs := make([]primitive.ObjectID, 0, len(workspaces))
+	workspaceNames := make(map[primitive.ObjectID]string, len(workspaces))
+	for _, w := range workspaces {
+		if workspaceFilter != nil && w.ID != *workspaceFilter {
+			continue
+		}
+		workspaceIDs = append(workspaceIDs, w.ID)
+		workspaceNames[w.ID] = w.Name
+	}
+	if workspaceFilter != nil && len(workspaceIDs) == 0 {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	results, err := sc.searchService.Search(ctx, workspaceIDs, query, services.SearchOptions{
+		IncludeContent: c.QueryBool("content", true),
+		Limit:          limit,
+	})
+	if err != nil {
+		return utils.InternalError(c, "Failed to search")
+	}
+
+	for _, r := range results {
+		r.WorkspaceName = workspaceNames[r.WorkspaceID]
+	}
+	if results == nil {
+		results = []*models.SearchResult{}
+	}
+
+	return utils.SuccessResponse(c, results)
+}
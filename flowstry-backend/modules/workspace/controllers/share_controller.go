@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxShareLinkExpiryHours caps share link lifetime at one year
+const maxShareLinkExpiryHours = 365 * 24
+
+// SharePasswordHeader carries the password for protected share links.
+// A header is used instead of a query parameter so the password is not written to access logs.
+const SharePasswordHeader = "X-Share-Password"
+
+// ShareController handles diagram share link endpoints
+type ShareController struct {
+	shareService   *services.ShareService
+	diagramService *services.DiagramService
+	memberService  *services.MemberService
+}
+
+// NewShareController creates a new share controller
+func NewShareController(shareService *services.ShareService, diagramService *services.DiagramService, memberService *services.MemberService) *ShareController {
+	return &ShareController{
+		shareService:   shareService,
+		diagramService: diagramService,
+		memberService:  memberService,
+	}
+}
+
+// Create creates a new share link for a diagram
+func (sc *ShareController) Create(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	var req models.CreateShareLinkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxShareLinkExpiryHours {
+		return utils.BadRequest(c, "expires_in must be between 0 and 8760 hours")
+	}
+	if req.Password != "" {
+		if valid, msg := utils.ValidatePassword(req.Password); !valid {
+			return utils.BadRequest(c, msg)
+		}
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	// Verify user can share diagrams (Owner, Admin, or Editor)
+	if !sc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to share diagrams")
+	}
+
+	link, rawToken, key, err := sc.shareService.Create(ctx, workspaceID, diagramID, userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrDiagramNotFound) {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		if errors.Is(err, services.ErrNoDiagramContent) {
+			return utils.BadRequest(c, "Diagram has no content to share")
+		}
+		return utils.InternalError(c, "Failed to create share link")
+	}
+
+	resp := link.ToResponse()
+	resp.Token = rawToken
+	resp.Key = key
+	resp.URL = sc.shareService.BuildURL(rawToken, key)
+	return utils.CreatedResponse(c, resp)
+}
+
+// List lists all share links of a diagram
+func (sc *ShareController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !sc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage share links")
+	}
+
+	links, err := sc.shareService.List(ctx, workspaceID, diagramID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list share links")
+	}
+
+	responses := make([]*models.ShareLinkResponse, len(links))
+	for i, l := range links {
+		responses[i] = l.ToResponse()
+	}
+
+	return utils.SuccessResponse(c, responses)
+}
+
+// Revoke revokes a share link
+func (sc *ShareController) Revoke(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(c.Params("shareId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid share link ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !sc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage share links")
+	}
+
+	err = sc.shareService.Revoke(ctx, workspaceID, diagramID, linkID)
+	if err != nil {
+		if err == services.ErrShareLinkNotFound {
+			return utils.NotFound(c, "Share link not found")
+		}
+		return utils.InternalError(c, "Failed to revoke share link")
+	}
+
+	return utils.SuccessMessageResponse(c, "Share link revoked")
+}
+
+// GetShared returns a shared diagram's metadata and a short-lived download URL for the link's
+// snapshot, which the viewer decrypts with the key from the link's URL fragment (public)
+// GET /share/:token
+func (sc *ShareController) GetShared(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return utils.BadRequest(c, "Token is required")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	link, diagram, err := sc.shareService.Resolve(ctx, token, c.Get(SharePasswordHeader))
+	if err != nil {
+		switch err {
+		case services.ErrShareLinkNotFound:
+			return utils.NotFound(c, "Share link not found")
+		case services.ErrShareLinkExpired:
+			return utils.ErrorResponse(c, fiber.StatusGone, "Share link has expired")
+		case services.ErrSharePasswordRequired:
+			return utils.Unauthorized(c, "Password required")
+		case services.ErrSharePasswordInvalid:
+			return utils.Forbidden(c, "Invalid password")
+		}
+		return utils.InternalError(c, "Failed to open share link")
+	}
+
+	downloadURL, err := sc.shareService.SnapshotURL(ctx, link)
+	if err != nil {
+		return utils.InternalError(c, "Failed to generate download URL")
+	}
+
+	resp := &models.SharedDiagramResponse{
+		Name:        diagram.Name,
+		Description: diagram.Description,
+		Version:     link.Version,
+		FileSize:    link.SnapshotSize,
+		DownloadURL: downloadURL,
+		ExpiresIn:   int(services.ShareDownloadURLExpiry.Seconds()),
+		UpdatedAt:   link.CreatedAt,
+	}
+	if diagram.Thumbnail != "" {
+		if url, err := sc.diagramService.GetThumbnailURL(ctx, diagram.Thumbnail); err == nil {
+			resp.ThumbnailURL = url
+		}
+	}
+
+	// Shared content must never be cached by intermediaries; the signed URL is short-lived
+	c.Set("Cache-Control", "no-store")
+	c.Set("Referrer-Policy", "no-referrer")
+	return utils.SuccessResponse(c, resp)
+}
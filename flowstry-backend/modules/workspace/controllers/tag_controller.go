@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxBulkTagDiagrams caps how many diagrams one bulk tag request can touch
+const maxBulkTagDiagrams = 500
+
+// TagController handles workspace tag definitions and bulk tagging
+type TagController struct {
+	tagService    *services.TagService
+	memberService *services.MemberService
+}
+
+// NewTagController creates a new tag controller
+func NewTagController(tagService *services.TagService, memberService *services.MemberService) *TagController {
+	return &TagController{
+		tagService:    tagService,
+		memberService: memberService,
+	}
+}
+
+// parseTagFilter reads the comma-separated ?tags= filter
+func parseTagFilter(c *fiber.Ctx) ([]string, error) {
+	raw := c.Query("tags")
+	if raw == "" {
+		return nil, nil
+	}
+	return services.NormalizeTags(strings.Split(raw, ","))
+}
+
+// List lists all tag definitions of a workspace
+func (tc *TagController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	tags, err := tc.tagService.List(ctx, workspaceID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list tags")
+	}
+
+	return utils.SuccessResponse(c, tags)
+}
+
+// Create creates a tag definition
+func (tc *TagController) Create(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	var req models.CreateTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	if req.Color != "" && !utils.ValidateHexColor(req.Color) {
+		return utils.BadRequest(c, "Color must be a hex color like #1a2b3c")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage tags")
+	}
+
+	tag, err := tc.tagService.Create(ctx, workspaceID, &req)
+	if err != nil {
+		switch err {
+		case services.ErrInvalidTag:
+			return utils.BadRequest(c, "Tag name must be between 1 and 40 characters")
+		case services.ErrTagExists:
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Tag already exists")
+		}
+		return utils.InternalError(c, "Failed to create tag")
+	}
+
+	return utils.CreatedResponse(c, tag.ToResponse())
+}
+
+// Update renames or recolors a tag definition
+func (tc *TagController) Update(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	tagID, err := primitive.ObjectIDFromHex(c.Params("tagId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid tag ID")
+	}
+
+	var req models.UpdateTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	if req.Color != nil && !utils.ValidateHexColor(*req.Color) {
+		return utils.BadRequest(c, "Color must be a hex color like #1a2b3c")
+	}
+
+	ctx, cancel := requestContext(c, 30*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage tags")
+	}
+
+	tag, err := tc.tagService.Update(ctx, workspaceID, tagID, &req)
+	if err != nil {
+		switch err {
+		case services.ErrTagNotFound:
+			return utils.NotFound(c, "Tag not found")
+		case services.ErrInvalidTag:
+			return utils.BadRequest(c, "Tag name must be between 1 and 40 characters")
+		case services.ErrTagExists:
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Tag already exists")
+		}
+		return utils.InternalError(c, "Failed to update tag")
+	}
+
+	return utils.SuccessResponse(c, tag.ToResponse())
+}
+
+// Delete deletes a tag definition and removes it from all diagrams
+func (tc *TagController) Delete(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	tagID, err := primitive.ObjectIDFromHex(c.Params("tagId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid tag ID")
+	}
+
+	ctx, cancel := requestContext(c, 30*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage tags")
+	}
+
+	if err := tc.tagService.Delete(ctx, workspaceID, tagID); err != nil {
+		if err == services.ErrTagNotFound {
+			return utils.NotFound(c, "Tag not found")
+		}
+		return utils.InternalError(c, "Failed to delete tag")
+	}
+
+	return utils.SuccessMessageResponse(c, "Tag deleted")
+}
+
+// BulkUpdate adds and removes tags on several diagrams
+// POST /workspaces/:workspaceId/diagrams/tags
+func (tc *TagController) BulkUpdate(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	var req models.BulkTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	if len(req.DiagramIDs) == 0 || len(req.DiagramIDs) > maxBulkTagDiagrams {
+		return utils.BadRequest(c, "diagram_ids must contain between 1 and 500 IDs")
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		return utils.BadRequest(c, "Nothing to add or remove")
+	}
+
+	diagramIDs := make([]primitive.ObjectID, 0, len(req.DiagramIDs))
+	for _, idStr := range req.DiagramIDs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return utils.BadRequest(c, "Invalid diagram ID: "+idStr)
+		}
+		diagramIDs = append(diagramIDs, id)
+	}
+
+	add, err := services.NormalizeTags(req.Add)
+	if err != nil {
+		return tagValidationError(c, err)
+	}
+	remove, err := services.NormalizeTags(req.Remove)
+	if err != nil {
+		return tagValidationError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 30*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to edit diagrams")
+	}
+
+	modified, err := tc.tagService.BulkUpdate(ctx, workspaceID, diagramIDs, add, remove)
+	if err != nil {
+		return utils.InternalError(c, "Failed to update tags")
+	}
+
+	return utils.SuccessResponse(c, &models.BulkTagResponse{Modified: modified})
+}
+
+// tagValidationError maps tag normalization errors to a 400 response
+func tagValidationError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrTooManyTags) {
+		return utils.BadRequest(c, "A diagram can have at most 20 tags")
+	}
+	return utils.BadRequest(c, "Tags must be between 1 and 40 characters")
+}
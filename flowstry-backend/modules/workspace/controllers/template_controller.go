@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/middleware"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateController handles the template gallery and template instantiation
+type TemplateController struct {
+	templateService *services.TemplateService
+	diagramService  *services.DiagramService
+	memberService   *services.MemberService
+}
+
+// NewTemplateController creates a new template controller
+func NewTemplateController(templateService *services.TemplateService, diagramService *services.DiagramService, memberService *services.MemberService) *TemplateController {
+	return &TemplateController{
+		templateService: templateService,
+		diagramService:  diagramService,
+		memberService:   memberService,
+	}
+}
+
+// List returns the template gallery of a workspace
+func (tc *TemplateController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	templates, err := tc.templateService.List(ctx, workspaceID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to list templates")
+	}
+
+	responses := make([]*models.TemplateResponse, len(templates))
+	for i, t := range templates {
+		resp := t.ToTemplateResponse()
+		if t.Thumbnail != "" {
+			if url, err := tc.diagramService.GetThumbnailURL(ctx, t.Thumbnail); err == nil {
+				resp.ThumbnailURL = url
+			}
+		}
+		responses[i] = resp
+	}
+
+	return utils.SuccessResponse(c, responses)
+}
+
+// Get returns a single template together with the placeholders it expects
+func (tc *TemplateController) Get(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid template ID")
+	}
+
+	ctx, cancel := requestContext(c, 30*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanView(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Access denied")
+	}
+
+	template, err := tc.templateService.Get(ctx, workspaceID, templateID)
+	if err != nil {
+		if err == services.ErrTemplateNotFound {
+			return utils.NotFound(c, "Template not found")
+		}
+		return utils.InternalError(c, "Failed to get template")
+	}
+
+	placeholders, err := tc.templateService.Placeholders(ctx, template)
+	if err != nil {
+		return utils.InternalError(c, "Failed to read template")
+	}
+
+	resp := template.ToTemplateResponse()
+	resp.Placeholders = placeholders
+	if template.Thumbnail != "" {
+		if url, err := tc.diagramService.GetThumbnailURL(ctx, template.Thumbnail); err == nil {
+			resp.ThumbnailURL = url
+		}
+	}
+
+	return utils.SuccessResponse(c, resp)
+}
+
+// Instantiate creates a new diagram from a template
+func (tc *TemplateController) Instantiate(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid template ID")
+	}
+
+	var req models.InstantiateTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	if req.Name == "" {
+		return utils.BadRequest(c, "Name is required")
+	}
+	req.Name = utils.SanitizeString(req.Name, 100)
+
+	if req.FolderID != "" {
+		if _, err := primitive.ObjectIDFromHex(req.FolderID); err != nil {
+			return utils.BadRequest(c, "Invalid folder ID")
+		}
+	}
+
+	ctx, cancel := requestContext(c, 30*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanCreate(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to create diagrams")
+	}
+
+	diagram, err := tc.templateService.Instantiate(ctx, userID, workspaceID, templateID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTemplateNotFound):
+			return utils.NotFound(c, "Template not found")
+		case errors.Is(err, services.ErrFolderNotFound):
+			return utils.NotFound(c, "Folder not found")
+		}
+		return utils.InternalError(c, "Failed to create diagram from template")
+	}
+
+	return utils.CreatedResponse(c, diagram.ToResponse())
+}
+
+// Mark marks a diagram as a workspace or instance-wide template
+func (tc *TemplateController) Mark(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	req := models.MarkTemplateRequest{Scope: models.TemplateScopeWorkspace}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	if !req.Scope.IsValid() {
+		return utils.BadRequest(c, "Scope must be 'workspace' or 'instance'")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage templates")
+	}
+	if req.Scope == models.TemplateScopeInstance && !tc.templateService.IsInstanceAdmin(middleware.GetUserEmail(c)) {
+		return utils.Forbidden(c, "Only instance administrators can publish instance-wide templates")
+	}
+
+	diagram, err := tc.diagramService.GetByID(ctx, diagramID, workspaceID)
+	if err != nil {
+		if err == services.ErrDiagramNotFound {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to get diagram")
+	}
+	// Downgrading an instance template removes it from every workspace
+	if diagram.TemplateScope == models.TemplateScopeInstance && !tc.templateService.IsInstanceAdmin(middleware.GetUserEmail(c)) {
+		return utils.Forbidden(c, "Only instance administrators can change instance-wide templates")
+	}
+
+	diagram, err = tc.templateService.SetScope(ctx, workspaceID, diagramID, req.Scope)
+	if err != nil {
+		if err == services.ErrDiagramNotFound {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to mark template")
+	}
+
+	return utils.SuccessResponse(c, diagram.ToResponse())
+}
+
+// Unmark removes a diagram from the template gallery
+func (tc *TemplateController) Unmark(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	diagramID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid diagram ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanEdit(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "You don't have permission to manage templates")
+	}
+
+	diagram, err := tc.diagramService.GetByID(ctx, diagramID, workspaceID)
+	if err != nil {
+		if err == services.ErrDiagramNotFound {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to get diagram")
+	}
+	if diagram.TemplateScope == models.TemplateScopeInstance && !tc.templateService.IsInstanceAdmin(middleware.GetUserEmail(c)) {
+		return utils.Forbidden(c, "Only instance administrators can change instance-wide templates")
+	}
+
+	diagram, err = tc.templateService.Unmark(ctx, workspaceID, diagramID)
+	if err != nil {
+		if err == services.ErrDiagramNotFound {
+			return utils.NotFound(c, "Diagram not found")
+		}
+		return utils.InternalError(c, "Failed to unmark template")
+	}
+
+	return utils.SuccessResponse(c, diagram.ToResponse())
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrashController handles workspace-wide trash endpoints
+type TrashController struct {
+	trashService  *services.TrashService
+	memberService *services.MemberService
+}
+
+// NewTrashController creates a new trash controller
+func NewTrashController(trashService *services.TrashService, memberService *services.MemberService) *TrashController {
+	return &TrashController{
+		trashService:  trashService,
+		memberService: memberService,
+	}
+}
+
+// Empty permanently deletes everything in a workspace's trash (Owner/Admin only)
+func (tc *TrashController) Empty(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	ctx, cancel := requestContext(c, 120*time.Second)
+	defer cancel()
+
+	if !tc.memberService.CanDeleteContent(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only owners and admins can empty the trash")
+	}
+
+	result, err := tc.trashService.Empty(ctx, workspaceID)
+	if err != nil {
+		return utils.InternalError(c, "Failed to empty trash")
+	}
+
+	return utils.SuccessResponse(c, result)
+}
@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookController handles workspace webhooks and their delivery log (Admin+)
+type WebhookController struct {
+	webhookService *services.WebhookService
+	memberService  *services.MemberService
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService *services.WebhookService, memberService *services.MemberService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		memberService:  memberService,
+	}
+}
+
+// webhookError maps webhook service errors to responses
+func webhookError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		return utils.NotFound(c, "Webhook not found")
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		return utils.NotFound(c, "Delivery not found")
+	case errors.Is(err, services.ErrWebhookEncryption):
+		return utils.ServiceUnavailable(c, err.Error())
+	case errors.Is(err, services.ErrInvalidWebhookURL),
+		errors.Is(err, services.ErrPrivateWebhookURL),
+		errors.Is(err, services.ErrInvalidWebhookEvents),
+		errors.Is(err, services.ErrTooManyWebhooks),
+		errors.Is(err, services.ErrWebhookDisabled),
+		errors.Is(err, services.ErrDeliveryPending):
+		return utils.BadRequest(c, err.Error())
+	default:
+		return utils.InternalError(c, fallback)
+	}
+}
+
+// List lists the webhooks of a workspace
+func (wc *WebhookController) List(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	webhooks, err := wc.webhookService.List(ctx, workspaceID)
+	if err != nil {
+		return webhookError(c, err, "Failed to list webhooks")
+	}
+
+	return utils.SuccessResponse(c, webhooks)
+}
+
+// Create registers a webhook; the response carries the signing secret, which is not shown again
+func (wc *WebhookController) Create(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	webhook, err := wc.webhookService.Create(ctx, workspaceID, userID, &req)
+	if err != nil {
+		return webhookError(c, err, "Failed to create webhook")
+	}
+
+	return utils.CreatedResponse(c, webhook)
+}
+
+// Get returns a webhook
+func (wc *WebhookController) Get(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	webhook, err := wc.webhookService.Get(ctx, workspaceID, webhookID)
+	if err != nil {
+		return webhookError(c, err, "Failed to get webhook")
+	}
+
+	return utils.SuccessResponse(c, webhook)
+}
+
+// Update changes a webhook's URL, description, events or enabled state
+func (wc *WebhookController) Update(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	webhook, err := wc.webhookService.Update(ctx, workspaceID, webhookID, &req)
+	if err != nil {
+		return webhookError(c, err, "Failed to update webhook")
+	}
+
+	return utils.SuccessResponse(c, webhook)
+}
+
+// RotateSecret replaces a webhook's signing secret and returns the new one
+func (wc *WebhookController) RotateSecret(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	webhook, err := wc.webhookService.RotateSecret(ctx, workspaceID, webhookID)
+	if err != nil {
+		return webhookError(c, err, "Failed to rotate webhook secret")
+	}
+
+	return utils.SuccessResponse(c, webhook)
+}
+
+// Delete removes a webhook with its delivery log
+func (wc *WebhookController) Delete(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	if err := wc.webhookService.Delete(ctx, workspaceID, webhookID); err != nil {
+		return webhookError(c, err, "Failed to delete webhook")
+	}
+
+	return utils.SuccessMessageResponse(c, "Webhook deleted")
+}
+
+// ListDeliveries lists one page of a webhook's delivery log, newest first; ?status= narrows it
+func (wc *WebhookController) ListDeliveries(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	filter := &models.WebhookDeliveryFilter{Status: models.WebhookDeliveryStatus(c.Query("status"))}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return utils.BadRequest(c, "Invalid status (use pending, succeeded or failed)")
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return listQueryError(c, err)
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	deliveries, nextCursor, err := wc.webhookService.ListDeliveries(ctx, workspaceID, webhookID, filter, page)
+	if err != nil {
+		if isListQueryError(err) {
+			return listQueryError(c, err)
+		}
+		return webhookError(c, err, "Failed to list deliveries")
+	}
+
+	return utils.PaginatedResponse(c, deliveries, nextCursor)
+}
+
+// GetDelivery returns one delivery with its payload and last response
+func (wc *WebhookController) GetDelivery(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Params("deliveryId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid delivery ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	delivery, err := wc.webhookService.GetDelivery(ctx, workspaceID, webhookID, deliveryID)
+	if err != nil {
+		return webhookError(c, err, "Failed to get delivery")
+	}
+
+	return utils.SuccessResponse(c, delivery)
+}
+
+// Redeliver queues a completed delivery again with the same payload
+func (wc *WebhookController) Redeliver(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "User not authenticated")
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(c.Params("workspaceId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid workspace ID")
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid webhook ID")
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Params("deliveryId"))
+	if err != nil {
+		return utils.BadRequest(c, "Invalid delivery ID")
+	}
+
+	ctx, cancel := requestContext(c, 10*time.Second)
+	defer cancel()
+
+	if !wc.memberService.CanManageMembers(ctx, workspaceID, userID) {
+		return utils.Forbidden(c, "Only admins can manage webhooks")
+	}
+
+	delivery, err := wc.webhookService.Redeliver(ctx, workspaceID, webhookID, deliveryID)
+	if err != nil {
+		return webhookError(c, err, "Failed to redeliver")
+	}
+
+	return utils.CreatedResponse(c, delivery)
+}
@@ -43,7 +43,7 @@ func (wc *WorkspaceController) Create(c *fiber.Ctx) error {
 	}
 	req.Name = utils.SanitizeString(req.Name, 100)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	workspace, err := wc.workspaceService.Create(ctx, userID, &req)
@@ -63,7 +63,7 @@ func (wc *WorkspaceController) List(c *fiber.Ctx) error {
 		return utils.Unauthorized(c, "User not authenticated")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	workspaces, roleMap, err := wc.workspaceService.List(ctx, userID)
@@ -95,7 +95,7 @@ func (wc *WorkspaceController) Get(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	workspace, role, err := wc.workspaceService.GetByIDWithRole(ctx, workspaceID, userID)
@@ -136,7 +136,7 @@ func (wc *WorkspaceController) Update(c *fiber.Ctx) error {
 		req.Name = &sanitized
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	workspace, err := wc.workspaceService.Update(ctx, workspaceID, userID, &req)
@@ -147,6 +147,9 @@ func (wc *WorkspaceController) Update(c *fiber.Ctx) error {
 		if err == services.ErrForbidden {
 			return utils.Forbidden(c, "Only owners and admins can update workspace settings")
 		}
+		if err == services.ErrInvalidRetention {
+			return utils.BadRequest(c, "Trash retention must be -1 (keep forever), 0 (instance default) or up to 3650 days")
+		}
 		return utils.InternalError(c, "Failed to update workspace")
 	}
 
@@ -167,7 +170,7 @@ func (wc *WorkspaceController) Delete(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	err = wc.workspaceService.Delete(ctx, workspaceID, userID)
@@ -194,6 +197,21 @@ func getUserIDFromContext(c *fiber.Ctx) (primitive.ObjectID, error) {
 	return primitive.ObjectIDFromHex(userIDStr)
 }
 
+// requestContext returns the context of a request, bounded by timeout. Changes made with it
+// are attributed in emitted events to the authenticated user, if any, and their client.
+func requestContext(c *fiber.Ctx, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if userID, err := getUserIDFromContext(c); err == nil {
+		ctx = services.WithActor(ctx, &models.Actor{
+			UserID:    userID,
+			Email:     middleware.GetUserEmail(c),
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+		})
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // GetKey retrieves the workspace encryption key
 func (wc *WorkspaceController) GetKey(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
@@ -206,7 +224,7 @@ func (wc *WorkspaceController) GetKey(c *fiber.Ctx) error {
 		return utils.BadRequest(c, "Invalid workspace ID")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := requestContext(c, 10*time.Second)
 	defer cancel()
 
 	key, err := wc.workspaceService.GetWorkspaceKey(ctx, workspaceID, userID)
@@ -220,7 +238,7 @@ func (wc *WorkspaceController) GetKey(c *fiber.Ctx) error {
 		return utils.InternalError(c, "Failed to get workspace key")
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{"key": key})
+	return utils.SuccessResponse(c, &models.WorkspaceKeyResponse{Key: key})
 }
 
 
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityEntry is one item of a workspace's activity feed. Names are captured when the
+// change happens. Consecutive saves of a diagram by one user are collapsed into a single
+// session entry: Count grows and UpdatedAt moves forward with each save.
+type ActivityEntry struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
+	ActorID     *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorName   string              `bson:"actor_name,omitempty" json:"actor_name,omitempty"`
+	Type        EventType           `bson:"type" json:"type"`
+	TargetType  TargetType          `bson:"target_type" json:"target_type"`
+	TargetID    primitive.ObjectID  `bson:"target_id" json:"target_id"`
+	TargetName  string              `bson:"target_name,omitempty" json:"target_name,omitempty"`
+	// Details holds what the summary needs besides the names, e.g. the old name or the destination folder
+	Details   map[string]string `bson:"details,omitempty" json:"details,omitempty"`
+	Count     int               `bson:"count" json:"count"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
+
+	// Summary is the human-readable sentence, rendered when the feed is read
+	Summary string `bson:"-" json:"summary"`
+}
+
+// ActivityFilter narrows activity feed listings
+type ActivityFilter struct {
+	ActorID  *primitive.ObjectID
+	TargetID *primitive.ObjectID
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry is one append-only record of a change to a workspace.
+// Entries without an actor were made by the server itself, e.g. the trash purge job.
+type AuditEntry struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
+	ActorID     *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorEmail  string              `bson:"actor_email,omitempty" json:"actor_email,omitempty"`
+	IP          string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent   string              `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Action      EventType           `bson:"action" json:"action"`
+	TargetType  TargetType          `bson:"target_type" json:"target_type"`
+	TargetID    primitive.ObjectID  `bson:"target_id" json:"target_id"`
+	Before      map[string]string   `bson:"before,omitempty" json:"before,omitempty"`
+	After       map[string]string   `bson:"after,omitempty" json:"after,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// AuditFilter narrows audit log listings and exports
+type AuditFilter struct {
+	// Actions keeps entries with one of these actions (any action when empty)
+	Actions  []EventType
+	ActorID  *primitive.ObjectID
+	TargetID *primitive.ObjectID
+	Created  DateRange
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentAnchor pins a thread to the canvas. With a ShapeID, X and Y track the shape's position
+// as of the last saved revision, so the thread stays where the shape was if it is deleted;
+// without one they are a plain canvas coordinate.
+type CommentAnchor struct {
+	ShapeID string  `bson:"shape_id,omitempty" json:"shape_id,omitempty"`
+	X       float64 `bson:"x" json:"x"`
+	Y       float64 `bson:"y" json:"y"`
+}
+
+// Comment is a comment on a diagram. Thread roots carry the anchor and the resolved state;
+// replies point at their root through ThreadID. Author names are captured when the comment is written.
+type Comment struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
+	DiagramID   primitive.ObjectID  `bson:"diagram_id" json:"diagram_id"`
+	ThreadID    *primitive.ObjectID `bson:"thread_id,omitempty" json:"thread_id,omitempty"`
+	AuthorID    primitive.ObjectID  `bson:"author_id" json:"author_id"`
+	AuthorName  string              `bson:"author_name" json:"author_name"`
+	Body        string              `bson:"body" json:"body"`
+	Anchor      *CommentAnchor      `bson:"anchor,omitempty" json:"anchor,omitempty"`
+	ResolvedAt  *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	ResolvedBy  *primitive.ObjectID `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	EditedAt    *time.Time          `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	// UpdatedAt moves forward on edits, replies and resolution changes
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	// Mentions reports who the body mentions; only set in responses to writing a comment
+	Mentions *MentionResult `bson:"-" json:"mentions,omitempty"`
+}
+
+// IsThread reports whether the comment starts a thread rather than replying to one
+func (c *Comment) IsThread() bool {
+	return c.ThreadID == nil
+}
+
+// CommentThread is a thread root with its replies, oldest first
+type CommentThread struct {
+	*Comment
+	Replies []*Comment `json:"replies"`
+}
+
+// CommentFilter narrows thread listings
+type CommentFilter struct {
+	// Resolved keeps resolved (true) or open (false) threads; nil keeps both
+	Resolved *bool
+	// ShapeID keeps threads anchored to one shape
+	ShapeID string
+}
+
+// CreateCommentRequest starts a thread; without an anchor it is about the diagram as a whole
+type CreateCommentRequest struct {
+	Body   string         `json:"body"`
+	Anchor *CommentAnchor `json:"anchor,omitempty"`
+}
+
+// CommentBodyRequest carries the text of a reply or an edit
+type CommentBodyRequest struct {
+	Body string `json:"body"`
+}
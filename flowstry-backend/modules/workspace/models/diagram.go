@@ -8,18 +8,28 @@ import (
 
 // Diagram represents a diagram stored in a workspace
 type Diagram struct {
-	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
-	WorkspaceID  primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
-	FolderID     *primitive.ObjectID `bson:"folder_id,omitempty" json:"folder_id,omitempty"`
-	Name         string              `bson:"name" json:"name"`
-	Description  string              `bson:"description,omitempty" json:"description,omitempty"`
-	FileURL      string              `bson:"file_url" json:"file_url"`
-	FileSize     int64               `bson:"file_size" json:"file_size"`
-	Thumbnail    string              `bson:"thumbnail,omitempty" json:"thumbnail,omitempty"`
-	Version      int                 `bson:"version" json:"version"`
-	DeletedAt    *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
-	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID   primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
+	FolderID      *primitive.ObjectID `bson:"folder_id,omitempty" json:"folder_id,omitempty"`
+	Name          string              `bson:"name" json:"name"`
+	Description   string              `bson:"description,omitempty" json:"description,omitempty"`
+	FileURL       string              `bson:"file_url" json:"file_url"`
+	FileSize      int64               `bson:"file_size" json:"file_size"`
+	Thumbnail     string              `bson:"thumbnail,omitempty" json:"thumbnail,omitempty"`
+	Version       int                 `bson:"version" json:"version"`
+	TemplateScope TemplateScope       `bson:"template_scope,omitempty" json:"template_scope,omitempty"`
+	Tags          []string            `bson:"tags,omitempty" json:"tags"`
+	CreatedBy     *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	// SearchContent is shape text extracted for search (only for workspaces that opted in)
+	SearchContent string     `bson:"search_content,omitempty" json:"-"`
+	DeletedAt     *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt     time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `bson:"updated_at" json:"updated_at"`
+
+	// TrashBatchID groups items trashed by one delete so they are restored together
+	TrashBatchID *primitive.ObjectID `bson:"trash_batch_id,omitempty" json:"trash_batch_id,omitempty"`
+	// DeletedWithParent marks items trashed because an ancestor folder was deleted
+	DeletedWithParent bool `bson:"deleted_with_parent,omitempty" json:"-"`
 }
 
 // CreateDiagramRequest represents the request to create a diagram
@@ -29,29 +39,48 @@ type CreateDiagramRequest struct {
 	FolderID    string `json:"folder_id,omitempty"`
 }
 
+// MoveDiagramRequest represents the request to move a diagram ("" = workspace root)
+type MoveDiagramRequest struct {
+	FolderID string `json:"folder_id"`
+}
+
+// MoveDiagramsRequest represents the request to move several diagrams ("" = workspace root)
+type MoveDiagramsRequest struct {
+	DiagramIDs []string `json:"diagram_ids"`
+	FolderID   string   `json:"folder_id"`
+}
+
 // UpdateDiagramRequest represents the request to update a diagram
 type UpdateDiagramRequest struct {
 	Name        *string `json:"name,omitempty"`
 	Description *string `json:"description,omitempty"`
 	Thumbnail   *string `json:"thumbnail,omitempty"`
 	FileURL     *string `json:"file_url,omitempty"`
+	// Tags replaces the diagram's tags when set
+	Tags *[]string `json:"tags,omitempty"`
 }
 
 // DiagramResponse represents the diagram response
 type DiagramResponse struct {
-	ID           primitive.ObjectID  `json:"id"`
-	WorkspaceID  primitive.ObjectID  `json:"workspace_id"`
-	FolderID     *primitive.ObjectID `json:"folder_id,omitempty"`
-	Name         string              `json:"name"`
-	Description  string              `json:"description,omitempty"`
-	FileURL      string              `json:"file_url"`
-	FileSize     int64               `json:"file_size"`
-	Thumbnail    string              `json:"thumbnail,omitempty"`
-	ThumbnailURL string              `json:"thumbnail_url,omitempty"`
-	Version      int                 `json:"version"`
-	DeletedAt    *time.Time          `json:"deleted_at"` // Removed omitempty for debugging
-	CreatedAt    time.Time           `json:"created_at"`
-	UpdatedAt    time.Time           `json:"updated_at"`
+	ID            primitive.ObjectID  `json:"id"`
+	WorkspaceID   primitive.ObjectID  `json:"workspace_id"`
+	FolderID      *primitive.ObjectID `json:"folder_id,omitempty"`
+	Name          string              `json:"name"`
+	Description   string              `json:"description,omitempty"`
+	FileURL       string              `json:"file_url"`
+	FileSize      int64               `json:"file_size"`
+	Thumbnail     string              `json:"thumbnail,omitempty"`
+	ThumbnailURL  string              `json:"thumbnail_url,omitempty"`
+	Version       int                 `json:"version"`
+	TemplateScope TemplateScope       `json:"template_scope,omitempty"`
+	Tags          []string            `json:"tags"`
+	Starred       bool                `json:"starred"`
+	CreatedBy     *primitive.ObjectID `json:"created_by,omitempty"`
+	DeletedAt     *time.Time          `json:"deleted_at"` // Removed omitempty for debugging
+	TrashBatchID  *primitive.ObjectID `json:"trash_batch_id,omitempty"`
+	PurgeAt       *time.Time          `json:"purge_at,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
 }
 
 // RecentDiagramResponse represents a recent diagram with workspace context
@@ -63,24 +92,71 @@ type RecentDiagramResponse struct {
 	Name          string              `json:"name"`
 	Thumbnail     string              `json:"thumbnail,omitempty"`
 	ThumbnailURL  string              `json:"thumbnail_url,omitempty"`
+	Tags          []string            `json:"tags"`
+	Starred       bool                `json:"starred"`
+	LastOpenedAt  *time.Time          `json:"last_opened_at,omitempty"`
+	StarredAt     *time.Time          `json:"starred_at,omitempty"`
 	CreatedAt     time.Time           `json:"created_at"`
 	UpdatedAt     time.Time           `json:"updated_at"`
 }
 
+// MoveDiagramsResponse reports how many diagrams a bulk move changed
+type MoveDiagramsResponse struct {
+	Moved int64 `json:"moved"`
+}
+
+// UploadURLResponse carries a signed URL to upload a diagram file or thumbnail
+type UploadURLResponse struct {
+	UploadURL  string `json:"upload_url"`
+	ObjectName string `json:"object_name"`
+	ExpiresIn  int    `json:"expires_in"` // Seconds
+}
+
+// DownloadURLResponse carries a signed URL to download a diagram file
+type DownloadURLResponse struct {
+	DownloadURL string `json:"download_url"`
+	ExpiresIn   int    `json:"expires_in"` // Seconds
+}
+
+// LiveStatusResponse tells whether live collaboration is available for a diagram
+type LiveStatusResponse struct {
+	Enabled bool   `json:"enabled"`
+	WSURL   string `json:"ws_url"`
+}
+
+// LiveTokenResponse carries a token to join a diagram's live collaboration session
+type LiveTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"` // Seconds
+	WSURL     string `json:"ws_url"`
+}
+
 // ToResponse converts Diagram to DiagramResponse
 func (d *Diagram) ToResponse() *DiagramResponse {
 	return &DiagramResponse{
-		ID:           d.ID,
-		WorkspaceID:  d.WorkspaceID,
-		FolderID:     d.FolderID,
-		Name:         d.Name,
-		Description:  d.Description,
-		FileURL:      d.FileURL,
-		FileSize:     d.FileSize,
-		Thumbnail:    d.Thumbnail,
-		Version:      d.Version,
-		DeletedAt:    d.DeletedAt,
-		CreatedAt:    d.CreatedAt,
-		UpdatedAt:    d.UpdatedAt,
+		ID:            d.ID,
+		WorkspaceID:   d.WorkspaceID,
+		FolderID:      d.FolderID,
+		Name:          d.Name,
+		Description:   d.Description,
+		FileURL:       d.FileURL,
+		FileSize:      d.FileSize,
+		Thumbnail:     d.Thumbnail,
+		Version:       d.Version,
+		TemplateScope: d.TemplateScope,
+		Tags:          d.TagList(),
+		CreatedBy:     d.CreatedBy,
+		DeletedAt:     d.DeletedAt,
+		TrashBatchID:  d.TrashBatchID,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
+
+// TagList returns the diagram's tags, never nil
+func (d *Diagram) TagList() []string {
+	if d.Tags == nil {
+		return []string{}
 	}
+	return d.Tags
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiagramAccess records when a user last opened a diagram (one record per user and diagram)
+type DiagramAccess struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	WorkspaceID  primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	DiagramID    primitive.ObjectID `bson:"diagram_id" json:"diagram_id"`
+	LastOpenedAt time.Time          `bson:"last_opened_at" json:"last_opened_at"`
+	OpenCount    int64              `bson:"open_count" json:"open_count"`
+}
+
+// DiagramStar marks a diagram as a favorite of a user
+type DiagramStar struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	DiagramID   primitive.ObjectID `bson:"diagram_id" json:"diagram_id"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
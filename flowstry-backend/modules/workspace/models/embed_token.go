@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmbedToken grants unauthenticated, render-only access to a diagram snapshot
+// (used by oEmbed consumers such as wikis). Only the SHA-256 hash of the token is stored.
+type EmbedToken struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	DiagramID   primitive.ObjectID `bson:"diagram_id" json:"diagram_id"`
+	TokenHash   string             `bson:"token_hash" json:"-"`
+	Label       string             `bson:"label,omitempty" json:"label,omitempty"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	RevokedAt   *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	LastUsedAt  *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CreateEmbedTokenRequest represents the request to create an embed token
+type CreateEmbedTokenRequest struct {
+	Label string `json:"label,omitempty"`
+}
+
+// EmbedTokenResponse represents an embed token for API responses
+type EmbedTokenResponse struct {
+	ID         primitive.ObjectID `json:"id"`
+	DiagramID  primitive.ObjectID `json:"diagram_id"`
+	Label      string             `json:"label,omitempty"`
+	Token      string             `json:"token,omitempty"`
+	EmbedURL   string             `json:"embed_url,omitempty"`
+	CreatedBy  primitive.ObjectID `json:"created_by"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty"`
+	Active     bool               `json:"active"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// ToResponse converts EmbedToken to EmbedTokenResponse
+func (t *EmbedToken) ToResponse() *EmbedTokenResponse {
+	return &EmbedTokenResponse{
+		ID:         t.ID,
+		DiagramID:  t.DiagramID,
+		Label:      t.Label,
+		CreatedBy:  t.CreatedBy,
+		RevokedAt:  t.RevokedAt,
+		LastUsedAt: t.LastUsedAt,
+		Active:     t.RevokedAt == nil,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// OEmbedResponse is the oEmbed 1.0 "rich" response
+type OEmbedResponse struct {
+	Type            string `json:"type"`
+	Version         string `json:"version"`
+	Title           string `json:"title,omitempty"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+	CacheAge        int    `json:"cache_age,omitempty"`
+}
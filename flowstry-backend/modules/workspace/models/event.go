@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventType identifies a change emitted by the workspace services
+type EventType string
+
+const (
+	EventWorkspaceCreated    EventType = "workspace.created"
+	EventWorkspaceUpdated    EventType = "workspace.updated"
+	EventWorkspaceDeleted    EventType = "workspace.deleted"
+	EventWorkspaceKeyFetched EventType = "workspace.key_fetched"
+
+	EventMemberAdded       EventType = "member.added"
+	EventMemberRemoved     EventType = "member.removed"
+	EventMemberRoleChanged EventType = "member.role_changed"
+	EventMemberMentioned   EventType = "member.mentioned"
+
+	EventInviteCreated  EventType = "invite.created"
+	EventInviteRevoked  EventType = "invite.revoked"
+	EventInviteAccepted EventType = "invite.accepted"
+
+	EventDiagramCreated     EventType = "diagram.created"
+	EventDiagramUpdated     EventType = "diagram.updated"
+	EventDiagramMoved       EventType = "diagram.moved"
+	EventDiagramDeleted     EventType = "diagram.deleted"
+	EventDiagramRestored    EventType = "diagram.restored"
+	EventDiagramHardDeleted EventType = "diagram.hard_deleted"
+
+	EventFolderCreated     EventType = "folder.created"
+	EventFolderUpdated     EventType = "folder.updated"
+	EventFolderMoved       EventType = "folder.moved"
+	EventFolderDeleted     EventType = "folder.deleted"
+	EventFolderRestored    EventType = "folder.restored"
+	EventFolderHardDeleted EventType = "folder.hard_deleted"
+
+	EventCommentCreated  EventType = "comment.created"
+	EventCommentUpdated  EventType = "comment.updated"
+	EventCommentDeleted  EventType = "comment.deleted"
+	EventCommentResolved EventType = "comment.resolved"
+	EventCommentReopened EventType = "comment.reopened"
+
+	EventWebhookCreated       EventType = "webhook.created"
+	EventWebhookUpdated       EventType = "webhook.updated"
+	EventWebhookDeleted       EventType = "webhook.deleted"
+	EventWebhookSecretRotated EventType = "webhook.secret_rotated"
+	EventWebhookDisabled      EventType = "webhook.disabled"
+)
+
+// TargetType is the kind of object an event is about
+type TargetType string
+
+const (
+	TargetWorkspace TargetType = "workspace"
+	TargetMember    TargetType = "member"
+	TargetInvite    TargetType = "invite"
+	TargetDiagram   TargetType = "diagram"
+	TargetFolder    TargetType = "folder"
+	TargetComment   TargetType = "comment"
+	TargetWebhook   TargetType = "webhook"
+)
+
+// Actor is who caused a change, as seen on the request that made it
+type Actor struct {
+	UserID    primitive.ObjectID
+	Email     string
+	IP        string
+	UserAgent string
+}
+
+// Event describes a change made through the workspace services, delivered to subscribers
+// such as the audit log. Before and after hold the changed fields as display strings.
+// Events without an actor were made by the server itself, e.g. the trash purge job.
+type Event struct {
+	Type        EventType
+	WorkspaceID primitive.ObjectID
+	Actor       *Actor
+	TargetType  TargetType
+	TargetID    primitive.ObjectID
+	Before      map[string]string
+	After       map[string]string
+	CreatedAt   time.Time
+}
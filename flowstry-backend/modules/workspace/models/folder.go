@@ -16,9 +16,15 @@ type Folder struct {
 	Description    string              `bson:"description,omitempty" json:"description,omitempty"`
 	Color          string              `bson:"color,omitempty" json:"color,omitempty"`
 	DiagramCount   int64               `bson:"-" json:"diagram_count"`
+	CreatedBy      *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
 	DeletedAt      *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+
+	// TrashBatchID groups items trashed by one delete so they are restored together
+	TrashBatchID *primitive.ObjectID `bson:"trash_batch_id,omitempty" json:"trash_batch_id,omitempty"`
+	// DeletedWithParent marks items trashed because an ancestor folder was deleted
+	DeletedWithParent bool `bson:"deleted_with_parent,omitempty" json:"-"`
 }
 
 // CreateFolderRequest represents the request to create a folder
@@ -34,9 +40,16 @@ type UpdateFolderRequest struct {
 	Name        *string `json:"name,omitempty"`
 	Description *string `json:"description,omitempty"`
 	Color       *string `json:"color,omitempty"`
+	// ParentFolderID moves the folder when set; "" moves it to the workspace root
+	ParentFolderID *string `json:"parent_folder_id,omitempty"`
+}
+
+// MoveFolderRequest represents the request to move a folder ("" = workspace root)
+type MoveFolderRequest struct {
+	ParentFolderID string `json:"parent_folder_id"`
 }
 
-// AddDiagramsRequest represents the request to add diagrams to a folder
+// AddDiagramsRequest represents the request to move diagrams into a folder
 type AddDiagramsRequest struct {
 	DiagramIDs []string `json:"diagram_ids"`
 }
@@ -49,6 +62,11 @@ type FolderResponse struct {
 	Name           string              `json:"name"`
 	Description    string              `json:"description,omitempty"`
 	Color          string              `json:"color,omitempty"`
+	DiagramCount   int64               `json:"diagram_count"`
+	CreatedBy      *primitive.ObjectID `json:"created_by,omitempty"`
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty"`
+	TrashBatchID   *primitive.ObjectID `json:"trash_batch_id,omitempty"`
+	PurgeAt        *time.Time          `json:"purge_at,omitempty"`
 	CreatedAt      time.Time           `json:"created_at"`
 	UpdatedAt      time.Time           `json:"updated_at"`
 }
@@ -62,7 +80,36 @@ func (f *Folder) ToResponse() *FolderResponse {
 		Name:           f.Name,
 		Description:    f.Description,
 		Color:          f.Color,
+		DiagramCount:   f.DiagramCount,
+		CreatedBy:      f.CreatedBy,
+		DeletedAt:      f.DeletedAt,
+		TrashBatchID:   f.TrashBatchID,
 		CreatedAt:      f.CreatedAt,
 		UpdatedAt:      f.UpdatedAt,
 	}
 }
+
+// FolderTreeNode is a folder with its subfolders and diagram counts
+type FolderTreeNode struct {
+	ID             primitive.ObjectID  `json:"id"`
+	ParentFolderID *primitive.ObjectID `json:"parent_folder_id,omitempty"`
+	Name           string              `json:"name"`
+	Color          string              `json:"color,omitempty"`
+	// DiagramCount counts diagrams directly in the folder, TotalDiagramCount includes subfolders
+	DiagramCount      int64             `json:"diagram_count"`
+	TotalDiagramCount int64             `json:"total_diagram_count"`
+	Children          []*FolderTreeNode `json:"children"`
+}
+
+// FolderTreeResponse is the folder hierarchy of a workspace
+type FolderTreeResponse struct {
+	Folders []*FolderTreeNode `json:"folders"`
+	// RootDiagramCount counts diagrams that are not in any folder
+	RootDiagramCount int64 `json:"root_diagram_count"`
+}
+
+// Breadcrumb is one step of the path to a folder
+type Breadcrumb struct {
+	ID   primitive.ObjectID `json:"id"`
+	Name string             `json:"name"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MentionSource is the text a mention was written in
+type MentionSource string
+
+const (
+	MentionInDescription MentionSource = "description" // A diagram's description; the source is the diagram
+	MentionInContent     MentionSource = "content"     // The shape text of a diagram; the source is the diagram
+	MentionInComment     MentionSource = "comment"     // A comment body; the source is the comment
+)
+
+// Mention records that a source on a diagram mentions a workspace member.
+// Members are mentioned as @[Label](userID), as inserted from the member search, or by @email.
+type Mention struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	DiagramID   primitive.ObjectID `bson:"diagram_id" json:"diagram_id"`
+	Source      MentionSource      `bson:"source" json:"source"`
+	SourceID    primitive.ObjectID `bson:"source_id" json:"source_id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// MentionResult reports the members a text mentions. Invitable lists the mentioned emails
+// that belong to no member, for the client to offer an invite.
+type MentionResult struct {
+	Mentioned []primitive.ObjectID `json:"mentioned"`
+	Invitable []string             `json:"invitable,omitempty"`
+}
+
+// ResolveMentionsRequest is a draft text whose mentions to resolve
+type ResolveMentionsRequest struct {
+	Text string `json:"text"`
+}
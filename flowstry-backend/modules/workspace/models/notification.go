@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationType is the kind of change a user is notified about
+type NotificationType string
+
+const (
+	NotificationInvite         NotificationType = "invite"          // You were invited to a workspace
+	NotificationRoleChanged    NotificationType = "role_changed"    // Your role in a workspace changed
+	NotificationMemberRemoved  NotificationType = "member_removed"  // You were removed from a workspace
+	NotificationDiagramEdited  NotificationType = "diagram_edited"  // Someone edited a diagram you created
+	NotificationDiagramDeleted NotificationType = "diagram_deleted" // Someone deleted a diagram you created
+	NotificationMentioned      NotificationType = "mentioned"       // Someone mentioned you in a diagram or comment
+)
+
+// NotificationTypes lists every notification type, e.g. for validating preferences
+var NotificationTypes = []NotificationType{
+	NotificationInvite,
+	NotificationRoleChanged,
+	NotificationMemberRemoved,
+	NotificationDiagramEdited,
+	NotificationDiagramDeleted,
+	NotificationMentioned,
+}
+
+// IsValid checks if the notification type is known
+func (t NotificationType) IsValid() bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification is one item of a user's in-app inbox. Repeated edits of a diagram collapse into
+// the unread notification about it: Count grows and UpdatedAt moves forward.
+type Notification struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"-"`
+	WorkspaceID primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
+	Type        NotificationType    `bson:"type" json:"type"`
+	ActorID     *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorName   string              `bson:"actor_name,omitempty" json:"actor_name,omitempty"`
+	TargetType  TargetType          `bson:"target_type" json:"target_type"`
+	TargetID    primitive.ObjectID  `bson:"target_id" json:"target_id"`
+	Message     string              `bson:"message" json:"message"`
+	Count       int                 `bson:"count" json:"count"`
+	ReadAt      *time.Time          `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+	// DigestedAt is when the notification was emailed in a digest; bumping it clears this
+	DigestedAt *time.Time `bson:"digested_at,omitempty" json:"-"`
+}
+
+// NotificationFilter narrows notification listings
+type NotificationFilter struct {
+	UnreadOnly bool
+}
+
+// UnreadCountResponse carries the number of unread notifications
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+// MarkReadResponse reports how many notifications were marked read
+type MarkReadResponse struct {
+	Marked int64 `json:"marked"`
+}
+
+// NotificationPreferencesRequest replaces the notification types a user has muted
+type NotificationPreferencesRequest struct {
+	Muted []NotificationType `json:"muted"`
+}
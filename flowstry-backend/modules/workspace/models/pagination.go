@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Page size limits shared by all paginated listings
+const (
+	DefaultPageSize int64 = 50
+	MaxPageSize     int64 = 200
+)
+
+// SortField identifies what a listing is ordered by
+type SortField string
+
+const (
+	SortByName    SortField = "name"
+	SortByCreated SortField = "created"
+	SortByUpdated SortField = "updated"
+	SortBySize    SortField = "size"
+	SortByDeleted SortField = "deleted"
+)
+
+// SortOrder is the direction of a listing
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// PageRequest describes one page of a cursor-paginated listing.
+// Cursor is the opaque next_cursor returned with the previous page.
+type PageRequest struct {
+	Limit  int64
+	Cursor string
+	Sort   SortField
+	Order  SortOrder
+}
+
+// DateRange restricts a listing to items whose timestamp falls within [After, Before)
+type DateRange struct {
+	After  *time.Time
+	Before *time.Time
+}
+
+// IsZero reports whether the range has no bounds
+func (r DateRange) IsZero() bool {
+	return r.After == nil && r.Before == nil
+}
+
+// MemberFilter narrows member listings
+type MemberFilter struct {
+	Role   WorkspaceRole
+	Joined DateRange
+	// Query keeps members whose email, name or a word of their name starts with it
+	Query string
+}
+
+// InviteFilter narrows pending invite listings
+type InviteFilter struct {
+	InvitedBy *primitive.ObjectID
+	Created   DateRange
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiagramFilter narrows diagram listings
+type DiagramFilter struct {
+	// Query matches diagram names (case-insensitive substring)
+	Query string
+	// Tags restricts results to diagrams carrying all of these tags
+	Tags []string
+	// FolderID restricts results to one folder; RootOnly to diagrams outside any folder
+	FolderID  *primitive.ObjectID
+	RootOnly  bool
+	CreatedBy *primitive.ObjectID
+	Created   DateRange
+	Updated   DateRange
+}
+
+// FolderFilter narrows folder listings
+type FolderFilter struct {
+	// Query matches folder names (case-insensitive substring)
+	Query string
+	// ParentID restricts results to children of one folder; RootOnly to top-level folders
+	ParentID  *primitive.ObjectID
+	RootOnly  bool
+	CreatedBy *primitive.ObjectID
+	Created   DateRange
+	Updated   DateRange
+}
+
+// SearchResultType identifies what a search result points to
+type SearchResultType string
+
+const (
+	SearchResultDiagram SearchResultType = "diagram"
+	SearchResultFolder  SearchResultType = "folder"
+)
+
+// SearchResult represents a ranked search hit
+type SearchResult struct {
+	Type          SearchResultType    `json:"type"`
+	ID            primitive.ObjectID  `json:"id"`
+	WorkspaceID   primitive.ObjectID  `json:"workspace_id"`
+	WorkspaceName string              `json:"workspace_name"`
+	FolderID      *primitive.ObjectID `json:"folder_id,omitempty"`
+	Name          string              `json:"name"`
+	Description   string              `json:"description,omitempty"`
+	// Snippet is the matching line of shape text when the match came from diagram content
+	Snippet   string    `json:"snippet,omitempty"`
+	Score     int       `json:"score"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
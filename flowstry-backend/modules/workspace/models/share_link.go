@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLink is a public, read-only link to a single diagram.
+// Only the SHA-256 hash of the token is stored; the raw token is returned once on creation.
+type ShareLink struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID  primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	DiagramID    primitive.ObjectID `bson:"diagram_id" json:"diagram_id"`
+	TokenHash    string             `bson:"token_hash" json:"-"`
+	PasswordHash string             `bson:"password_hash,omitempty" json:"-"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	ExpiresAt    *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt    *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	LastViewedAt *time.Time         `bson:"last_viewed_at,omitempty" json:"last_viewed_at,omitempty"`
+	ViewCount    int64              `bson:"view_count" json:"view_count"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+
+	// SnapshotPath is the copy of the diagram the link serves, encrypted with a key of its own
+	// that only the link's URL carries, so a link never exposes the workspace key
+	SnapshotPath string `bson:"snapshot_path" json:"-"`
+	SnapshotSize int64  `bson:"snapshot_size" json:"-"`
+	// Version is the diagram version in the snapshot
+	Version int `bson:"version" json:"version"`
+}
+
+// IsExpired checks if the share link has passed its expiry time
+func (l *ShareLink) IsExpired() bool {
+	return l.ExpiresAt != nil && time.Now().After(*l.ExpiresAt)
+}
+
+// IsActive returns true if the link is neither revoked nor expired
+func (l *ShareLink) IsActive() bool {
+	return l.RevokedAt == nil && !l.IsExpired()
+}
+
+// CreateShareLinkRequest represents the request to create a share link
+type CreateShareLinkRequest struct {
+	// ExpiresIn is the lifetime of the link in hours (0 = never expires)
+	ExpiresIn int    `json:"expires_in,omitempty"`
+	Password  string `json:"password,omitempty"`
+}
+
+// ShareLinkResponse represents a share link for API responses.
+// Token, Key (which decrypts the snapshot) and URL are only returned when the link is created.
+type ShareLinkResponse struct {
+	ID                primitive.ObjectID `json:"id"`
+	DiagramID         primitive.ObjectID `json:"diagram_id"`
+	Token             string             `json:"token,omitempty"`
+	Key               string             `json:"key,omitempty"`
+	URL               string             `json:"url,omitempty"`
+	Version           int                `json:"version"`
+	PasswordProtected bool               `json:"password_protected"`
+	CreatedBy         primitive.ObjectID `json:"created_by"`
+	ExpiresAt         *time.Time         `json:"expires_at,omitempty"`
+	RevokedAt         *time.Time         `json:"revoked_at,omitempty"`
+	LastViewedAt      *time.Time         `json:"last_viewed_at,omitempty"`
+	ViewCount         int64              `json:"view_count"`
+	Active            bool               `json:"active"`
+	CreatedAt         time.Time          `json:"created_at"`
+}
+
+// ToResponse converts ShareLink to ShareLinkResponse
+func (l *ShareLink) ToResponse() *ShareLinkResponse {
+	return &ShareLinkResponse{
+		ID:                l.ID,
+		DiagramID:         l.DiagramID,
+		PasswordProtected: l.PasswordHash != "",
+		Version:           l.Version,
+		CreatedBy:         l.CreatedBy,
+		ExpiresAt:         l.ExpiresAt,
+		RevokedAt:         l.RevokedAt,
+		LastViewedAt:      l.LastViewedAt,
+		ViewCount:         l.ViewCount,
+		Active:            l.IsActive(),
+		CreatedAt:         l.CreatedAt,
+	}
+}
+
+// SharedDiagramResponse is returned by the public share endpoint. It describes the link's
+// snapshot: the diagram version taken when the link was created.
+type SharedDiagramResponse struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	Version      int       `json:"version"`
+	FileSize     int64     `json:"file_size"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	DownloadURL  string    `json:"download_url"`
+	ExpiresIn    int       `json:"expires_in"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
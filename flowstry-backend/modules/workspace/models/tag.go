@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultTagColor is used for tags that are created implicitly by tagging a diagram
+const DefaultTagColor = "#6b7280"
+
+// WorkspaceTag is a workspace-level tag definition. Diagrams reference tags by name.
+type WorkspaceTag struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	Name        string             `bson:"name" json:"name"`
+	Color       string             `bson:"color" json:"color"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateTagRequest represents the request to create a tag definition
+type CreateTagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// UpdateTagRequest represents the request to rename or recolor a tag
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Color *string `json:"color,omitempty"`
+}
+
+// BulkTagRequest adds and/or removes tags on several diagrams at once
+type BulkTagRequest struct {
+	DiagramIDs []string `json:"diagram_ids"`
+	Add        []string `json:"add,omitempty"`
+	Remove     []string `json:"remove,omitempty"`
+}
+
+// BulkTagResponse reports how many diagrams a bulk tag update changed
+type BulkTagResponse struct {
+	Modified int64 `json:"modified"`
+}
+
+// TagResponse represents a tag definition with its usage
+type TagResponse struct {
+	ID           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	Color        string             `json:"color"`
+	DiagramCount int64              `json:"diagram_count"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// ToResponse converts WorkspaceTag to TagResponse
+func (t *WorkspaceTag) ToResponse() *TagResponse {
+	return &TagResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Color:     t.Color,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateScope defines where a template diagram is offered
+type TemplateScope string
+
+const (
+	// TemplateScopeWorkspace templates are available inside their own workspace
+	TemplateScopeWorkspace TemplateScope = "workspace"
+	// TemplateScopeInstance templates are available in every workspace (instance admins only)
+	TemplateScopeInstance TemplateScope = "instance"
+)
+
+// IsValid checks if the scope is a valid template scope
+func (s TemplateScope) IsValid() bool {
+	return s == TemplateScopeWorkspace || s == TemplateScopeInstance
+}
+
+// MarkTemplateRequest represents the request to mark a diagram as a template
+type MarkTemplateRequest struct {
+	Scope TemplateScope `json:"scope"`
+}
+
+// InstantiateTemplateRequest represents the request to create a diagram from a template
+type InstantiateTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	FolderID    string `json:"folder_id,omitempty"`
+	// Values fills {{placeholder}} tokens in shape text, e.g. {"service": "payments"}
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// TemplateResponse represents a template in the gallery
+type TemplateResponse struct {
+	ID           primitive.ObjectID `json:"id"`
+	WorkspaceID  primitive.ObjectID `json:"workspace_id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description,omitempty"`
+	Scope        TemplateScope      `json:"scope"`
+	ThumbnailURL string             `json:"thumbnail_url,omitempty"`
+	Placeholders []string           `json:"placeholders,omitempty"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// ToTemplateResponse converts a template Diagram to TemplateResponse
+func (d *Diagram) ToTemplateResponse() *TemplateResponse {
+	return &TemplateResponse{
+		ID:          d.ID,
+		WorkspaceID: d.WorkspaceID,
+		Name:        d.Name,
+		Description: d.Description,
+		Scope:       d.TemplateScope,
+		UpdatedAt:   d.UpdatedAt,
+	}
+}
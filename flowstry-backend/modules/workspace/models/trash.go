@@ -0,0 +1,16 @@
+package models
+
+// MaxTrashRetentionDays caps the per-workspace trash retention period
+const MaxTrashRetentionDays = 3650
+
+// Special values of Workspace.TrashRetentionDays
+const (
+	TrashRetentionDefault = 0
+	TrashRetentionForever = -1
+)
+
+// EmptyTrashResponse reports what emptying a workspace trash removed
+type EmptyTrashResponse struct {
+	FoldersDeleted  int `json:"folders_deleted"`
+	DiagramsDeleted int `json:"diagrams_deleted"`
+}
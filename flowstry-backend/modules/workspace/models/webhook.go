@@ -0,0 +1,148 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookEvents are the event types a webhook can subscribe to
+var WebhookEvents = []EventType{
+	EventDiagramCreated,
+	EventDiagramUpdated,
+	EventDiagramDeleted,
+	EventMemberAdded,
+	EventMemberRemoved,
+	EventInviteAccepted,
+	EventCommentCreated,
+}
+
+// IsWebhookEvent reports whether webhooks can subscribe to an event type
+func IsWebhookEvent(t EventType) bool {
+	for _, e := range WebhookEvents {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is an endpoint a workspace admin registered to receive events.
+// Deliveries are signed with a secret that is shown once and stored encrypted with the master key.
+// After too many failed attempts in a row the webhook is disabled until an admin enables it again.
+type Webhook struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID     primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	URL             string             `bson:"url" json:"url"`
+	Description     string             `bson:"description" json:"description"`
+	Events          []EventType        `bson:"events" json:"events"`
+	EncryptedSecret []byte             `bson:"secret" json:"-"`
+	Enabled         bool               `bson:"enabled" json:"enabled"`
+	// ConsecutiveFailures counts the failed delivery attempts since the last successful one
+	ConsecutiveFailures int                `bson:"consecutive_failures" json:"consecutive_failures"`
+	DisabledReason      string             `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	CreatedBy           primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook subscribes to an event type
+func (w *Webhook) Subscribes(t EventType) bool {
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookWithSecret carries the signing secret, returned only when a webhook is created or its secret rotated
+type WebhookWithSecret struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryStatus is the state of a delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending" // Waiting for its next attempt
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed" // Gave up after the last attempt
+)
+
+// IsValid checks if the delivery status is valid
+func (s WebhookDeliveryStatus) IsValid() bool {
+	switch s {
+	case WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryFailed:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to a webhook, kept as the webhook's delivery log.
+// The payload is stored as sent, so a redelivery carries the same body and event ID.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	WorkspaceID   primitive.ObjectID    `bson:"workspace_id" json:"workspace_id"`
+	WebhookID     primitive.ObjectID    `bson:"webhook_id" json:"webhook_id"`
+	EventType     EventType             `bson:"event_type" json:"event_type"`
+	Payload       string                `bson:"payload" json:"payload"`
+	Status        WebhookDeliveryStatus `bson:"status" json:"status"`
+	Attempts      int                   `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time             `bson:"next_attempt_at" json:"next_attempt_at"`
+	// ResponseStatus and ResponseBody (truncated) are from the last attempt that got a response
+	ResponseStatus int                 `bson:"response_status,omitempty" json:"response_status,omitempty"`
+	ResponseBody   string              `bson:"response_body,omitempty" json:"response_body,omitempty"`
+	LastError      string              `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	RedeliveryOf   *primitive.ObjectID `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	CompletedAt    *time.Time          `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// WebhookDeliveryFilter narrows delivery listings
+type WebhookDeliveryFilter struct {
+	Status WebhookDeliveryStatus
+}
+
+// CreateWebhookRequest represents the request to register a webhook
+type CreateWebhookRequest struct {
+	URL         string      `json:"url"`
+	Description string      `json:"description"`
+	Events      []EventType `json:"events"`
+}
+
+// UpdateWebhookRequest represents the request to change a webhook; omitted fields are left alone.
+// Enabling a webhook clears its failure count.
+type UpdateWebhookRequest struct {
+	URL         *string     `json:"url,omitempty"`
+	Description *string     `json:"description,omitempty"`
+	Events      []EventType `json:"events,omitempty"`
+	Enabled     *bool       `json:"enabled,omitempty"`
+}
+
+// WebhookPayload is the JSON body of a delivery. ID identifies the event and is kept by
+// redeliveries, so receivers can drop duplicates.
+type WebhookPayload struct {
+	ID          primitive.ObjectID `json:"id"`
+	Type        EventType          `json:"type"`
+	CreatedAt   time.Time          `json:"created_at"`
+	WorkspaceID primitive.ObjectID `json:"workspace_id"`
+	// Actor is nil for changes made by the server itself
+	Actor  *WebhookActor     `json:"actor"`
+	Target WebhookTarget     `json:"target"`
+	Before map[string]string `json:"before,omitempty"`
+	After  map[string]string `json:"after,omitempty"`
+}
+
+// WebhookActor is who caused the event
+type WebhookActor struct {
+	ID    primitive.ObjectID `json:"id"`
+	Email string             `json:"email"`
+}
+
+// WebhookTarget is the object the event is about
+type WebhookTarget struct {
+	Type TargetType         `json:"type"`
+	ID   primitive.ObjectID `json:"id"`
+}
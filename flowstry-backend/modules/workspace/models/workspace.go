@@ -15,8 +15,12 @@ type Workspace struct {
 	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
 	EncryptedKey []byte             `bson:"encrypted_key" json:"-"`
-	DiagramCount int64              `bson:"-" json:"diagram_count"`
-	FolderCount  int64              `bson:"-" json:"folder_count"`
+	// ContentSearchEnabled opts the workspace into indexing decrypted shape text for search
+	ContentSearchEnabled bool `bson:"content_search_enabled,omitempty" json:"content_search_enabled"`
+	// TrashRetentionDays overrides the instance trash retention (0 = instance default, -1 = keep forever)
+	TrashRetentionDays int   `bson:"trash_retention_days,omitempty" json:"trash_retention_days"`
+	DiagramCount       int64 `bson:"-" json:"diagram_count"`
+	FolderCount        int64 `bson:"-" json:"folder_count"`
 }
 
 // CreateWorkspaceRequest represents the request to create a workspace
@@ -27,31 +31,42 @@ type CreateWorkspaceRequest struct {
 
 // UpdateWorkspaceRequest represents the request to update a workspace
 type UpdateWorkspaceRequest struct {
-	Name        *string `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
+	Name                 *string `json:"name,omitempty"`
+	Description          *string `json:"description,omitempty"`
+	ContentSearchEnabled *bool   `json:"content_search_enabled,omitempty"`
+	TrashRetentionDays   *int    `json:"trash_retention_days,omitempty"`
 }
 
 // WorkspaceResponse represents the workspace response
 type WorkspaceResponse struct {
-	ID           primitive.ObjectID `json:"id"`
-	Name         string             `json:"name"`
-	Description  string             `json:"description,omitempty"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
-	DiagramCount int64              `json:"diagram_count"`
-	FolderCount  int64              `json:"folder_count"`
-	UserRole     WorkspaceRole      `json:"user_role,omitempty"`
+	ID                   primitive.ObjectID `json:"id"`
+	Name                 string             `json:"name"`
+	Description          string             `json:"description,omitempty"`
+	CreatedAt            time.Time          `json:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at"`
+	DiagramCount         int64              `json:"diagram_count"`
+	FolderCount          int64              `json:"folder_count"`
+	ContentSearchEnabled bool               `json:"content_search_enabled"`
+	TrashRetentionDays   int                `json:"trash_retention_days"`
+	UserRole             WorkspaceRole      `json:"user_role,omitempty"`
+}
+
+// WorkspaceKeyResponse carries the workspace's content encryption key
+type WorkspaceKeyResponse struct {
+	Key []byte `json:"key"`
 }
 
 // ToResponse converts Workspace to WorkspaceResponse
 func (w *Workspace) ToResponse() *WorkspaceResponse {
 	return &WorkspaceResponse{
-		ID:           w.ID,
-		Name:         w.Name,
-		Description:  w.Description,
-		CreatedAt:    w.CreatedAt,
-		UpdatedAt:    w.UpdatedAt,
-		DiagramCount: w.DiagramCount,
-		FolderCount:  w.FolderCount,
+		ID:                   w.ID,
+		Name:                 w.Name,
+		Description:          w.Description,
+		CreatedAt:            w.CreatedAt,
+		UpdatedAt:            w.UpdatedAt,
+		DiagramCount:         w.DiagramCount,
+		FolderCount:          w.FolderCount,
+		ContentSearchEnabled: w.ContentSearchEnabled,
+		TrashRetentionDays:   w.TrashRetentionDays,
 	}
 }
@@ -59,6 +59,13 @@ func (i *WorkspaceInvite) ToResponse() *WorkspaceInviteResponse {
 	}
 }
 
+// AcceptInviteResponse is returned when an invite is accepted
+type AcceptInviteResponse struct {
+	Message     string             `json:"message"`
+	WorkspaceID primitive.ObjectID `json:"workspace_id"`
+	Role        WorkspaceRole      `json:"role"`
+}
+
 // InviteDetailsResponse is returned when fetching invite by token (for accept flow)
 type InviteDetailsResponse struct {
 	ID            primitive.ObjectID `json:"id"`
@@ -0,0 +1,590 @@
+// Package render produces static snapshots of diagram documents on the server.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	canvasPadding      = 24.0
+	defaultFill        = "#ffffff"
+	defaultStroke      = "#575757"
+	defaultStrokeWidth = 2.0
+	defaultTextColor   = "#1f1f1f"
+	defaultFontSize    = 16.0
+	defaultFontFamily  = "Inter, Helvetica, Arial, sans-serif"
+	lineHeightFactor   = 1.25
+)
+
+// Options controls the rendered SVG
+type Options struct {
+	Title      string
+	Background string
+	// MaxWidth and MaxHeight scale the output (keeping aspect ratio) when set
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Result holds a rendered snapshot and its natural size
+type Result struct {
+	SVG    []byte
+	Width  int
+	Height int
+}
+
+type point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type document struct {
+	Name   string  `json:"name"`
+	Shapes []shape `json:"shapes"`
+}
+
+type shape struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Intent     intent     `json:"intent"`
+	Layout     layout     `json:"layout"`
+	Appearance appearance `json:"appearance"`
+}
+
+type intent struct {
+	Text               string                 `json:"text"`
+	LabelText          string                 `json:"labelText"`
+	ImageURL           string                 `json:"imageUrl"`
+	Points             []point                `json:"points"`
+	Data               map[string]interface{} `json:"data"`
+	StartArrowheadType string                 `json:"startArrowheadType"`
+	EndArrowheadType   string                 `json:"endArrowheadType"`
+	LabelPosition      *float64               `json:"labelPosition"`
+}
+
+type layout struct {
+	X              float64 `json:"x"`
+	Y              float64 `json:"y"`
+	Width          float64 `json:"width"`
+	Height         float64 `json:"height"`
+	ConnectorType  string  `json:"connectorType"`
+	StartPoint     *point  `json:"startPoint"`
+	EndPoint       *point  `json:"endPoint"`
+	PointsStraight []point `json:"pointsStraight"`
+	PointsBent     []point `json:"pointsBent"`
+	PointsCurved   []point `json:"pointsCurved"`
+}
+
+type appearance struct {
+	Fill          string   `json:"fill"`
+	FillOpacity   *float64 `json:"fillOpacity"`
+	FillStyle     string   `json:"fillStyle"`
+	Stroke        string   `json:"stroke"`
+	StrokeWidth   *float64 `json:"strokeWidth"`
+	StrokeOpacity *float64 `json:"strokeOpacity"`
+	StrokeStyle   string   `json:"strokeStyle"`
+	TextColor     string   `json:"textColor"`
+	FontSize      float64  `json:"fontSize"`
+	FontFamily    string   `json:"fontFamily"`
+	FontWeight    string   `json:"fontWeight"`
+	FontStyle     string   `json:"fontStyle"`
+	TextAlign     string   `json:"textAlign"`
+	Opacity       *float64 `json:"opacity"`
+}
+
+// SVG renders a diagram JSON document (the decrypted .flowstry content) as an SVG image
+func SVG(documentJSON []byte, opts Options) (*Result, error) {
+	var doc document
+	if err := json.Unmarshal(documentJSON, &doc); err != nil {
+		return nil, fmt.Errorf("invalid diagram document: %w", err)
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = doc.Name
+	}
+	background := opts.Background
+	if background == "" {
+		background = defaultFill
+	}
+
+	minX, minY, maxX, maxY := bounds(doc.Shapes)
+	minX -= canvasPadding
+	minY -= canvasPadding
+	maxX += canvasPadding
+	maxY += canvasPadding
+	viewWidth := maxX - minX
+	viewHeight := maxY - minY
+
+	width, height := fit(viewWidth, viewHeight, opts.MaxWidth, opts.MaxHeight)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s" width="%d" height="%d" role="img">`,
+		num(minX), num(minY), num(viewWidth), num(viewHeight), width, height)
+	if title != "" {
+		fmt.Fprintf(&buf, `<title>%s</title>`, escape(title))
+	}
+	fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`,
+		num(minX), num(minY), num(viewWidth), num(viewHeight), escape(background))
+
+	// Frames are containers and sit behind everything else
+	for i := range doc.Shapes {
+		if doc.Shapes[i].Type == "frame" {
+			renderShape(&buf, &doc.Shapes[i], i)
+		}
+	}
+	for i := range doc.Shapes {
+		if doc.Shapes[i].Type != "frame" {
+			renderShape(&buf, &doc.Shapes[i], i)
+		}
+	}
+
+	buf.WriteString(`</svg>`)
+
+	return &Result{SVG: buf.Bytes(), Width: width, Height: height}, nil
+}
+
+// bounds computes the extent of all shapes in canvas coordinates
+func bounds(shapes []shape) (float64, float64, float64, float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	include := func(x, y float64) {
+		minX = math.Min(minX, x)
+		minY = math.Min(minY, y)
+		maxX = math.Max(maxX, x)
+		maxY = math.Max(maxY, y)
+	}
+
+	for i := range shapes {
+		s := &shapes[i]
+		if s.Type == "connector" {
+			for _, p := range connectorPoints(s) {
+				include(p.X, p.Y)
+			}
+			continue
+		}
+		include(s.Layout.X, s.Layout.Y)
+		include(s.Layout.X+s.Layout.Width, s.Layout.Y+s.Layout.Height)
+		if s.Type == "frame" && frameLabel(s) != "" {
+			include(s.Layout.X, s.Layout.Y-fontSize(s)*lineHeightFactor-4)
+		}
+	}
+
+	if math.IsInf(minX, 1) {
+		return 0, 0, 200, 100
+	}
+	return minX, minY, maxX, maxY
+}
+
+// fit scales the natural size down to the requested maximum dimensions
+func fit(width, height float64, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > float64(maxWidth) {
+		scale = math.Min(scale, float64(maxWidth)/width)
+	}
+	if maxHeight > 0 && height > float64(maxHeight) {
+		scale = math.Min(scale, float64(maxHeight)/height)
+	}
+	return int(math.Max(1, math.Round(width*scale))), int(math.Max(1, math.Round(height*scale)))
+}
+
+func renderShape(buf *bytes.Buffer, s *shape, index int) {
+	if s.Appearance.Opacity != nil && *s.Appearance.Opacity < 1 {
+		fmt.Fprintf(buf, `<g opacity="%s">`, num(*s.Appearance.Opacity))
+		defer buf.WriteString(`</g>`)
+	}
+
+	l := s.Layout
+	switch s.Type {
+	case "rectangle":
+		fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="4"%s/>`,
+			num(l.X), num(l.Y), num(l.Width), num(l.Height), shapeStyle(s))
+		renderText(buf, s, s.Intent.Text)
+	case "ellipse":
+		fmt.Fprintf(buf, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s/>`,
+			num(l.X+l.Width/2), num(l.Y+l.Height/2), num(l.Width/2), num(l.Height/2), shapeStyle(s))
+		renderText(buf, s, s.Intent.Text)
+	case "diamond", "triangle", "triangle-down", "triangle-right", "triangle-left", "hexagon", "pentagon", "octagon":
+		fmt.Fprintf(buf, `<polygon points="%s"%s/>`, polygonPoints(s), shapeStyle(s))
+		renderText(buf, s, s.Intent.Text)
+	case "frame":
+		fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="8" fill="%s" fill-opacity="0.35" stroke="%s" stroke-width="%s"%s/>`,
+			num(l.X), num(l.Y), num(l.Width), num(l.Height),
+			escape(colorOr(s.Appearance.Fill, "#f4f4f5")), escape(colorOr(s.Appearance.Stroke, "#a1a1aa")),
+			num(strokeWidth(s)), dashAttr(s.Appearance.StrokeStyle))
+		if label := frameLabel(s); label != "" {
+			fmt.Fprintf(buf, `<text x="%s" y="%s" font-family="%s" font-size="%s" font-weight="600" fill="%s">%s</text>`,
+				num(l.X), num(l.Y-6), escape(fontFamily(s)), num(fontSize(s)), escape(colorOr(s.Appearance.TextColor, defaultTextColor)), escape(label))
+		}
+	case "image":
+		if safeImageURL(s.Intent.ImageURL) {
+			fmt.Fprintf(buf, `<image href="%s" x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="xMidYMid meet"/>`,
+				escape(s.Intent.ImageURL), num(l.X), num(l.Y), num(l.Width), num(l.Height))
+		} else {
+			fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="#f4f4f5" stroke="#d4d4d8"/>`,
+				num(l.X), num(l.Y), num(l.Width), num(l.Height))
+		}
+	case "freehand":
+		if len(s.Intent.Points) > 0 {
+			fmt.Fprintf(buf, `<path d="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"%s/>`,
+				smoothPath(s.Intent.Points), escape(colorOr(s.Appearance.Stroke, defaultStroke)), num(strokeWidth(s)), opacityAttr("stroke-opacity", s.Appearance.StrokeOpacity))
+		}
+	case "connector":
+		renderConnector(buf, s, index)
+	case "service-card", "todo-card", "react":
+		fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="10" fill="%s" stroke="%s" stroke-width="1.5"/>`,
+			num(l.X), num(l.Y), num(l.Width), num(l.Height),
+			escape(colorOr(s.Appearance.Fill, defaultFill)), escape(colorOr(s.Appearance.Stroke, "#d4d4d8")))
+		renderText(buf, s, cardTitle(s))
+	default:
+		// Unknown shape types are drawn as their bounding box so the layout stays readable
+		if l.Width > 0 && l.Height > 0 {
+			fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s"%s/>`,
+				num(l.X), num(l.Y), num(l.Width), num(l.Height), shapeStyle(s))
+			renderText(buf, s, s.Intent.Text)
+		}
+	}
+}
+
+func renderConnector(buf *bytes.Buffer, s *shape, index int) {
+	points := connectorPoints(s)
+	if len(points) < 2 {
+		return
+	}
+
+	stroke := colorOr(s.Appearance.Stroke, defaultStroke)
+	markers := ""
+	if def, ref := marker(fmt.Sprintf("m%d-start", index), s.Intent.StartArrowheadType, stroke, true); def != "" {
+		buf.WriteString(def)
+		markers += ` marker-start="` + ref + `"`
+	}
+	if def, ref := marker(fmt.Sprintf("m%d-end", index), s.Intent.EndArrowheadType, stroke, false); def != "" {
+		buf.WriteString(def)
+		markers += ` marker-end="` + ref + `"`
+	}
+
+	var d string
+	if s.Layout.ConnectorType == "curved" {
+		d = smoothPath(points)
+	} else {
+		d = polylinePath(points)
+	}
+
+	fmt.Fprintf(buf, `<path d="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round"%s%s%s/>`,
+		d, escape(stroke), num(strokeWidth(s)), dashAttr(s.Appearance.StrokeStyle), opacityAttr("stroke-opacity", s.Appearance.StrokeOpacity), markers)
+
+	if text := strings.TrimSpace(s.Intent.Text); text != "" {
+		ratio := 0.5
+		if s.Intent.LabelPosition != nil {
+			ratio = math.Max(0, math.Min(1, *s.Intent.LabelPosition))
+		}
+		at := pointAlong(points, ratio)
+		lines := strings.Split(text, "\n")
+		size := fontSize(s)
+		height := float64(len(lines)) * size * lineHeightFactor
+		maxLen := 0
+		for _, line := range lines {
+			if len(line) > maxLen {
+				maxLen = len(line)
+			}
+		}
+		width := float64(maxLen)*size*0.6 + 8
+		fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="#ffffff" rx="3"/>`,
+			num(at.X-width/2), num(at.Y-height/2), num(width), num(height))
+		writeLines(buf, s, lines, at.X, at.Y, "middle")
+	}
+}
+
+// connectorPoints returns the routed points of a connector in canvas coordinates
+func connectorPoints(s *shape) []point {
+	l := s.Layout
+	var pts []point
+	switch l.ConnectorType {
+	case "bent":
+		pts = l.PointsBent
+	case "curved":
+		pts = l.PointsCurved
+	default:
+		pts = l.PointsStraight
+	}
+	if len(pts) >= 2 {
+		return pts
+	}
+	if l.StartPoint != nil && l.EndPoint != nil {
+		return []point{*l.StartPoint, *l.EndPoint}
+	}
+	return nil
+}
+
+func marker(id, kind, color string, start bool) (string, string) {
+	if kind == "" || kind == "none" {
+		return "", ""
+	}
+
+	orient := "auto"
+	if start {
+		orient = "auto-start-reverse"
+	}
+
+	var body string
+	switch kind {
+	case "filled-triangle":
+		body = fmt.Sprintf(`<path d="M0,0 L10,5 L0,10 z" fill="%s"/>`, escape(color))
+	case "hollow-triangle":
+		body = fmt.Sprintf(`<path d="M0,0 L10,5 L0,10 z" fill="#ffffff" stroke="%s" stroke-width="1.2"/>`, escape(color))
+	case "filled-diamond":
+		body = fmt.Sprintf(`<path d="M0,5 L5,0 L10,5 L5,10 z" fill="%s"/>`, escape(color))
+	case "hollow-diamond":
+		body = fmt.Sprintf(`<path d="M0,5 L5,0 L10,5 L5,10 z" fill="#ffffff" stroke="%s" stroke-width="1.2"/>`, escape(color))
+	case "circle":
+		body = fmt.Sprintf(`<circle cx="5" cy="5" r="4" fill="#ffffff" stroke="%s" stroke-width="1.2"/>`, escape(color))
+	case "filled-circle":
+		body = fmt.Sprintf(`<circle cx="5" cy="5" r="4" fill="%s"/>`, escape(color))
+	case "bar":
+		body = fmt.Sprintf(`<path d="M9,0 L9,10" stroke="%s" stroke-width="1.5"/>`, escape(color))
+	default:
+		// open-arrow, half arrows and crow's foot notations fall back to an open arrow
+		body = fmt.Sprintf(`<path d="M0,0 L10,5 L0,10" fill="none" stroke="%s" stroke-width="1.5"/>`, escape(color))
+	}
+
+	def := fmt.Sprintf(`<defs><marker id="%s" viewBox="0 0 10 10" refX="9" refY="5" markerWidth="8" markerHeight="8" orient="%s" markerUnits="userSpaceOnUse">%s</marker></defs>`,
+		id, orient, body)
+	return def, "url(#" + id + ")"
+}
+
+func polygonPoints(s *shape) string {
+	l := s.Layout
+	x, y, w, h := l.X, l.Y, l.Width, l.Height
+	var pts []point
+
+	switch s.Type {
+	case "diamond":
+		pts = []point{{x + w/2, y}, {x + w, y + h/2}, {x + w/2, y + h}, {x, y + h/2}}
+	case "triangle":
+		pts = []point{{x + w/2, y}, {x + w, y + h}, {x, y + h}}
+	case "triangle-down":
+		pts = []point{{x, y}, {x + w, y}, {x + w/2, y + h}}
+	case "triangle-right":
+		pts = []point{{x, y}, {x + w, y + h/2}, {x, y + h}}
+	case "triangle-left":
+		pts = []point{{x + w, y}, {x + w, y + h}, {x, y + h/2}}
+	default:
+		sides, offset := 6, 0.0
+		switch s.Type {
+		case "pentagon":
+			sides, offset = 5, -math.Pi/2
+		case "octagon":
+			sides, offset = 8, math.Pi/8
+		}
+		cx, cy := x+w/2, y+h/2
+		for i := 0; i < sides; i++ {
+			angle := offset + 2*math.Pi*float64(i)/float64(sides)
+			pts = append(pts, point{cx + w/2*math.Cos(angle), cy + h/2*math.Sin(angle)})
+		}
+	}
+
+	parts := make([]string, len(pts))
+	for i, p := range pts {
+		parts[i] = num(p.X) + "," + num(p.Y)
+	}
+	return strings.Join(parts, " ")
+}
+
+func polylinePath(points []point) string {
+	var b strings.Builder
+	for i, p := range points {
+		if i == 0 {
+			b.WriteString("M")
+		} else {
+			b.WriteString(" L")
+		}
+		b.WriteString(num(p.X) + " " + num(p.Y))
+	}
+	return b.String()
+}
+
+// smoothPath draws a quadratic curve through the midpoints of consecutive points
+func smoothPath(points []point) string {
+	if len(points) < 3 {
+		if len(points) == 1 {
+			p := points[0]
+			return fmt.Sprintf("M%s %s L%s %s", num(p.X), num(p.Y), num(p.X+0.1), num(p.Y+0.1))
+		}
+		return polylinePath(points)
+	}
+
+	var b strings.Builder
+	b.WriteString("M" + num(points[0].X) + " " + num(points[0].Y))
+	for i := 1; i < len(points)-1; i++ {
+		mid := point{(points[i].X + points[i+1].X) / 2, (points[i].Y + points[i+1].Y) / 2}
+		b.WriteString(" Q" + num(points[i].X) + " " + num(points[i].Y) + " " + num(mid.X) + " " + num(mid.Y))
+	}
+	last := points[len(points)-1]
+	b.WriteString(" L" + num(last.X) + " " + num(last.Y))
+	return b.String()
+}
+
+// pointAlong returns the point at the given ratio of a polyline's total length
+func pointAlong(points []point, ratio float64) point {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+	}
+	target := total * ratio
+	for i := 1; i < len(points); i++ {
+		seg := math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+		if seg > 0 && target <= seg {
+			t := target / seg
+			return point{points[i-1].X + (points[i].X-points[i-1].X)*t, points[i-1].Y + (points[i].Y-points[i-1].Y)*t}
+		}
+		target -= seg
+	}
+	return points[len(points)-1]
+}
+
+func renderText(buf *bytes.Buffer, s *shape, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	l := s.Layout
+	anchor := "middle"
+	x := l.X + l.Width/2
+	switch s.Appearance.TextAlign {
+	case "left":
+		anchor, x = "start", l.X+8
+	case "right":
+		anchor, x = "end", l.X+l.Width-8
+	}
+
+	writeLines(buf, s, strings.Split(text, "\n"), x, l.Y+l.Height/2, anchor)
+}
+
+// writeLines writes vertically centered multi-line text around (x, cy)
+func writeLines(buf *bytes.Buffer, s *shape, lines []string, x, cy float64, anchor string) {
+	size := fontSize(s)
+	lineHeight := size * lineHeightFactor
+	startY := cy - lineHeight*float64(len(lines)-1)/2
+
+	weight := ""
+	if s.Appearance.FontWeight == "bold" {
+		weight = ` font-weight="bold"`
+	}
+	style := ""
+	if s.Appearance.FontStyle == "italic" {
+		style = ` font-style="italic"`
+	}
+
+	fmt.Fprintf(buf, `<text font-family="%s" font-size="%s" fill="%s" text-anchor="%s" dominant-baseline="central"%s%s>`,
+		escape(fontFamily(s)), num(size), escape(colorOr(s.Appearance.TextColor, defaultTextColor)), anchor, weight, style)
+	for i, line := range lines {
+		fmt.Fprintf(buf, `<tspan x="%s" y="%s">%s</tspan>`, num(x), num(startY+float64(i)*lineHeight), escape(line))
+	}
+	buf.WriteString(`</text>`)
+}
+
+func shapeStyle(s *shape) string {
+	a := s.Appearance
+	fill := colorOr(a.Fill, defaultFill)
+	if a.FillStyle == "none" {
+		fill = "none"
+	}
+	stroke := colorOr(a.Stroke, defaultStroke)
+	if a.StrokeStyle == "none" {
+		stroke = "none"
+	}
+
+	return fmt.Sprintf(` fill="%s" stroke="%s" stroke-width="%s"%s%s%s`,
+		escape(fill), escape(stroke), num(strokeWidth(s)), dashAttr(a.StrokeStyle),
+		opacityAttr("fill-opacity", a.FillOpacity), opacityAttr("stroke-opacity", a.StrokeOpacity))
+}
+
+func dashAttr(style string) string {
+	switch style {
+	case "dashed":
+		return ` stroke-dasharray="8 6"`
+	case "dotted":
+		return ` stroke-dasharray="2 4"`
+	}
+	return ""
+}
+
+func opacityAttr(name string, value *float64) string {
+	if value == nil || *value >= 1 {
+		return ""
+	}
+	return fmt.Sprintf(` %s="%s"`, name, num(math.Max(0, *value)))
+}
+
+func strokeWidth(s *shape) float64 {
+	if s.Appearance.StrokeWidth != nil && *s.Appearance.StrokeWidth >= 0 {
+		return *s.Appearance.StrokeWidth
+	}
+	return defaultStrokeWidth
+}
+
+func fontSize(s *shape) float64 {
+	if s.Appearance.FontSize > 0 {
+		return s.Appearance.FontSize
+	}
+	return defaultFontSize
+}
+
+func fontFamily(s *shape) string {
+	if s.Appearance.FontFamily != "" {
+		return s.Appearance.FontFamily
+	}
+	return defaultFontFamily
+}
+
+func frameLabel(s *shape) string {
+	if s.Intent.LabelText != "" {
+		return s.Intent.LabelText
+	}
+	return s.Intent.Text
+}
+
+func cardTitle(s *shape) string {
+	for _, key := range []string{"serviceName", "title", "name"} {
+		if v, ok := s.Intent.Data[key].(string); ok && strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return s.Intent.Text
+}
+
+func colorOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func safeImageURL(url string) bool {
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "data:image/")
+}
+
+func num(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&#39;",
+)
+
+func escape(s string) string {
+	return xmlEscaper.Replace(s)
+}
@@ -2,104 +2,612 @@ package workspace
 
 import (
 	"fmt"
+	"mime/multipart"
+	"net/http"
 
+	"github.com/flowstry/flowstry-backend/config"
+	"github.com/flowstry/flowstry-backend/mailer"
 	"github.com/flowstry/flowstry-backend/middleware"
+	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
 	"github.com/flowstry/flowstry-backend/modules/auth/services"
 	"github.com/flowstry/flowstry-backend/modules/workspace/controllers"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
 	workspaceServices "github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/openapi"
+	"github.com/flowstry/flowstry-backend/repository"
 	"github.com/flowstry/flowstry-backend/storage"
 	"github.com/gofiber/fiber/v2"
 )
 
-// SetupRoutes configures workspace routes
-func SetupRoutes(app *fiber.App, authService *services.AuthService, gcsClient *storage.GCSClient, liveCollabService *workspaceServices.LiveCollabService) {
+// SetupRoutes configures workspace routes on the versioned API router
+func SetupRoutes(api fiber.Router, spec *openapi.Spec, cfg *config.Config, store *repository.Store, authService *services.AuthService, fileStorage storage.Storage, liveCollabService *workspaceServices.LiveCollabService, mail *mailer.Mailer) {
 	// Initialize services
-	encryptionService, err := workspaceServices.NewEncryptionService()
+	encryptionService, err := workspaceServices.NewEncryptionService(cfg.EncryptionKeyFile)
 	if err != nil {
 		fmt.Printf("Warning: Failed to initialize encryption service: %v\n", err)
 	}
 
-	workspaceService := workspaceServices.NewWorkspaceService()
+	workspaceService := workspaceServices.NewWorkspaceService(store.Workspaces, store.Members, store.Folders, store.Diagrams)
 	workspaceService.SetEncryptionService(encryptionService)
 
-	memberService := workspaceServices.NewMemberService()
-	inviteService := workspaceServices.NewInviteService(memberService)
-	folderService := workspaceServices.NewFolderService()
-	diagramService := workspaceServices.NewDiagramService(gcsClient, folderService)
+	memberService := workspaceServices.NewMemberService(store.Members, store.Workspaces, store.Users)
+	inviteService := workspaceServices.NewInviteService(store.Invites, store.Workspaces, store.Users, memberService)
+	if mail != nil {
+		inviteService.SetMailer(mail)
+	}
+	folderService := workspaceServices.NewFolderService(store.Folders, store.Diagrams)
+	diagramService := workspaceServices.NewDiagramService(store.Diagrams, fileStorage, folderService)
+	folderService.SetDiagramService(diagramService)
+	trashService := workspaceServices.NewTrashService(store.Workspaces, store.Folders, store.Diagrams, diagramService, folderService, cfg.TrashRetentionDays)
+	trashService.StartPurgeJob(cfg.TrashPurgeInterval, store.Connected)
+	contentService := workspaceServices.NewContentService(store.Workspaces, fileStorage, encryptionService)
+	shareService := workspaceServices.NewShareService(store.ShareLinks, diagramService, contentService, fileStorage, cfg.FrontendURL)
+	diagramService.SetShareService(shareService)
+	embedService := workspaceServices.NewEmbedService(store.EmbedTokens, diagramService, contentService, cfg.PublicURL)
+	diagramService.SetEmbedService(embedService)
+	templateService := workspaceServices.NewTemplateService(store.Diagrams, diagramService, contentService, cfg.InstanceAdminEmails)
+	searchService := workspaceServices.NewSearchService(store.Workspaces, store.Folders, store.Diagrams, contentService)
+	diagramService.SetSearchService(searchService)
+	tagService := workspaceServices.NewTagService(store.Tags, store.Diagrams)
+	diagramService.SetTagService(tagService)
+	accessService := workspaceServices.NewAccessService(store.Access, diagramService)
+	diagramService.SetAccessService(accessService)
+	starService := workspaceServices.NewStarService(store.Stars, diagramService)
+	diagramService.SetStarService(starService)
+	commentService := workspaceServices.NewCommentService(store.Comments, store.Users, diagramService, contentService)
+	diagramService.SetCommentService(commentService)
+	mentionService := workspaceServices.NewMentionService(store.Mentions, store.Users, memberService, contentService)
+	diagramService.SetMentionService(mentionService)
+	commentService.SetMentionService(mentionService)
+
+	// Changes are emitted as events for the audit log, activity feed and notifications
+	events := workspaceServices.NewEventBus()
+	workspaceService.SetEventBus(events)
+	memberService.SetEventBus(events)
+	inviteService.SetEventBus(events)
+	folderService.SetEventBus(events)
+	diagramService.SetEventBus(events)
+	commentService.SetEventBus(events)
+	mentionService.SetEventBus(events)
+	auditService := workspaceServices.NewAuditService(store.Audit)
+	events.Subscribe(auditService.Record)
+	activityService := workspaceServices.NewActivityService(store.Activity, store.Users, store.Folders, store.Diagrams)
+	events.Subscribe(activityService.Record)
+	notificationService := workspaceServices.NewNotificationService(store.Notifications, store.Users, store.Workspaces, store.Diagrams, memberService)
+	if mail != nil {
+		notificationService.SetMailer(mail)
+	}
+	events.Subscribe(notificationService.Record)
+	notificationService.StartDigestJob(cfg.NotificationDigestInterval, store.Connected)
+	webhookService := workspaceServices.NewWebhookService(store.Webhooks, store.Deliveries, encryptionService)
+	webhookService.SetEventBus(events)
+	events.Subscribe(webhookService.Record)
+	webhookService.StartWorker(cfg.WebhookPollInterval, store.Connected)
 
 	// Set member service on workspace service for RBAC
 	workspaceService.SetMemberService(memberService)
 	workspaceService.SetInviteService(inviteService)
+	workspaceService.SetShareService(shareService)
+	workspaceService.SetEmbedService(embedService)
+	workspaceService.SetSearchService(searchService)
+	workspaceService.SetTagService(tagService)
+	workspaceService.SetAccessService(accessService)
+	workspaceService.SetStarService(starService)
+	workspaceService.SetCommentService(commentService)
+	workspaceService.SetMentionService(mentionService)
+	workspaceService.SetWebhookService(webhookService)
 
 	// Initialize controllers
 	workspaceController := controllers.NewWorkspaceController(workspaceService, memberService)
 	memberController := controllers.NewMemberController(memberService)
 	inviteController := controllers.NewInviteController(inviteService, memberService)
-	folderController := controllers.NewFolderController(folderService, workspaceService, memberService)
-	diagramController := controllers.NewDiagramController(diagramService, workspaceService, memberService)
+	folderController := controllers.NewFolderController(folderService, workspaceService, memberService, trashService)
+	diagramController := controllers.NewDiagramController(diagramService, workspaceService, memberService, accessService, starService, trashService)
 	filesController := controllers.NewFilesController(folderService, diagramService, workspaceService, memberService)
 	liveCollabController := controllers.NewLiveCollabController(diagramService, memberService, liveCollabService)
+	shareController := controllers.NewShareController(shareService, diagramService, memberService)
+	embedController := controllers.NewEmbedController(embedService, memberService)
+	templateController := controllers.NewTemplateController(templateService, diagramService, memberService)
+	searchController := controllers.NewSearchController(searchService, workspaceService)
+	tagController := controllers.NewTagController(tagService, memberService)
+	trashController := controllers.NewTrashController(trashService, memberService)
+	auditController := controllers.NewAuditController(auditService, memberService)
+	activityController := controllers.NewActivityController(activityService, diagramService, memberService)
+	notificationController := controllers.NewNotificationController(notificationService)
+	commentController := controllers.NewCommentController(commentService, memberService)
+	mentionController := controllers.NewMentionController(mentionService, memberService)
+	webhookController := controllers.NewWebhookController(webhookService, memberService)
 
-	// Protected routes - require authentication
-	workspaces := app.Group("/workspaces", middleware.AuthMiddleware(authService))
+	// Protected routes - require authentication; workspace-restricted tokens only reach their own workspace.
+	// Personal access tokens also need the scope each route names; role checks apply either way.
+	workspaces := spec.Group(api, "/workspaces", "Workspaces", middleware.AuthMiddleware(authService), middleware.TokenWorkspace())
 
 	// Workspace routes
-	workspaces.Get("/", workspaceController.List)
-	workspaces.Post("/", workspaceController.Create)
-	workspaces.Get("/recents", diagramController.ListRecent)
-	workspaces.Get("/:id", workspaceController.Get)
-	workspaces.Get("/:id/key", workspaceController.GetKey)
-	workspaces.Put("/:id", workspaceController.Update)
-	workspaces.Delete("/:id", workspaceController.Delete)
-	workspaces.Get("/:workspaceId/trash", diagramController.ListTrash) // Workspace trash
+	workspaces.Get("/", openapi.Operation{
+		Summary: "List the user's workspaces", Scope: read, Response: []models.WorkspaceResponse{},
+	}, workspaceController.List)
+	workspaces.Post("/", openapi.Operation{
+		Summary: "Create a workspace", Scope: admin, Status: http.StatusCreated,
+		Body: models.CreateWorkspaceRequest{}, Response: models.WorkspaceResponse{},
+	}, workspaceController.Create)
+
+	// Recent and starred diagrams across workspaces (registered before /:id, which would match them)
+	recent := workspaces.Tagged("Diagrams")
+	recent.Get("/recents", openapi.Operation{
+		Summary: "List recently opened diagrams across workspaces", Scope: read, Response: []models.RecentDiagramResponse{},
+		Params: []openapi.Param{
+			{Name: "limit", Type: "integer", Description: "Number of diagrams (12 by default)"},
+			tagsParam,
+		},
+	}, diagramController.ListRecent)
+	recent.Get("/starred", openapi.Operation{
+		Summary: "List starred diagrams across workspaces", Scope: read, Response: []models.RecentDiagramResponse{},
+	}, diagramController.ListStarred)
+
+	workspaces.Get("/:id", openapi.Operation{
+		Summary: "Get a workspace", Scope: read, Response: models.WorkspaceResponse{},
+	}, workspaceController.Get)
+	workspaces.Get("/:id/key", openapi.Operation{
+		Summary: "Get the workspace's content encryption key", Scope: read, Response: models.WorkspaceKeyResponse{},
+	}, workspaceController.GetKey)
+	workspaces.Put("/:id", openapi.Operation{
+		Summary: "Update a workspace", Scope: admin,
+		Body: models.UpdateWorkspaceRequest{}, Response: models.WorkspaceResponse{},
+	}, workspaceController.Update)
+	workspaces.Delete("/:id", openapi.Operation{
+		Summary: "Delete a workspace and everything in it", Scope: admin,
+	}, workspaceController.Delete)
+
+	// Trash
+	trash := workspaces.Tagged("Trash")
+	trash.Get("/:workspaceId/trash", openapi.Operation{
+		Summary: "List trashed diagrams", Scope: read, Paginated: true, Params: diagramFilterParams,
+		Response: []models.DiagramResponse{},
+	}, diagramController.ListTrash)
+	trash.Delete("/:workspaceId/trash", openapi.Operation{
+		Summary: "Empty the trash", Scope: admin, Response: models.EmptyTrashResponse{},
+	}, trashController.Empty)
+	trash.Get("/:workspaceId/folders/trash", openapi.Operation{
+		Summary: "List trashed folders", Scope: read, Paginated: true, Params: folderFilterParams,
+		Response: []models.FolderResponse{},
+	}, folderController.ListTrash)
 
 	// Files route - get all folders and diagrams in a workspace
-	workspaces.Get("/:id/files", filesController.List)
+	workspaces.Get("/:id/files", openapi.Operation{
+		Summary: "List folders, then diagrams, of a workspace", Scope: read, Paginated: true, Params: diagramFilterParams,
+		Response: controllers.WorkspaceFilesResponse{},
+	}, filesController.List)
 
 	// Member routes (within workspace)
-	workspaces.Get("/:id/members", memberController.List)
-	workspaces.Delete("/:id/members/:userId", memberController.Remove)
-	workspaces.Put("/:id/members/:userId/role", memberController.UpdateRole)
+	members := workspaces.Tagged("Members")
+	members.Get("/:id/members", openapi.Operation{
+		Summary: "List members", Scope: read, Paginated: true, Response: []models.WorkspaceMemberResponse{},
+		Params: append([]openapi.Param{
+			{Name: "q", Description: "Matches the start of the email, name or a word of the name"},
+			{Name: "role", Enum: roles},
+		}, dateRangeParams("joined")...),
+	}, memberController.List)
+	members.Get("/:id/members/search", openapi.Operation{
+		Summary: "Search members to mention", Scope: read, Response: []models.WorkspaceMemberResponse{},
+		Params: []openapi.Param{
+			{Name: "q", Description: "Matches the start of the email, name or a word of the name"},
+			{Name: "limit", Type: "integer"},
+		},
+	}, memberController.Search)
+	members.Delete("/:id/members/:userId", openapi.Operation{
+		Summary: "Remove a member", Scope: membersManage,
+	}, memberController.Remove)
+	members.Put("/:id/members/:userId/role", openapi.Operation{
+		Summary: "Change a member's role", Scope: membersManage, Body: models.UpdateMemberRoleRequest{},
+	}, memberController.UpdateRole)
+
+	// Audit log routes (Admin+ only)
+	audit := workspaces.Tagged("Audit log")
+	audit.Get("/:id/audit", openapi.Operation{
+		Summary: "List the audit log", Scope: admin, Paginated: true, Params: auditFilterParams,
+		Response: []models.AuditEntry{},
+	}, auditController.List)
+	audit.Get("/:id/audit/export", openapi.Operation{
+		Summary: "Export the audit log", Scope: admin, Produces: []string{"text/csv", "application/x-ndjson"},
+		Params: append([]openapi.Param{{Name: "format", Enum: []string{"csv", "jsonl"}}}, auditFilterParams...),
+	}, auditController.Export)
+
+	// Activity feed (any member)
+	activity := workspaces.Tagged("Activity")
+	activity.Get("/:id/activity", openapi.Operation{
+		Summary: "List the workspace's activity", Scope: read, Paginated: true, Response: []models.ActivityEntry{},
+		Params: []openapi.Param{{Name: "actor_id", Description: "User ID, or me"}},
+	}, activityController.Workspace)
+	activity.Get("/:workspaceId/diagrams/:id/activity", openapi.Operation{
+		Summary: "List a diagram's activity", Scope: read, Paginated: true, Response: []models.ActivityEntry{},
+	}, activityController.Diagram)
 
 	// Invite routes (within workspace)
-	workspaces.Post("/:id/invites", inviteController.Create)
-	workspaces.Get("/:id/invites", inviteController.List)
-	workspaces.Delete("/:id/invites/:inviteId", inviteController.Revoke)
+	workspaceInvites := workspaces.Tagged("Invites")
+	workspaceInvites.Post("/:id/invites", openapi.Operation{
+		Summary: "Invite someone by email", Scope: membersManage, Status: http.StatusCreated,
+		Body: models.CreateInviteRequest{}, Response: models.WorkspaceInviteResponse{},
+	}, inviteController.Create)
+	workspaceInvites.Get("/:id/invites", openapi.Operation{
+		Summary: "List pending invites", Scope: membersManage, Paginated: true, Response: []models.WorkspaceInviteResponse{},
+		Params: append([]openapi.Param{{Name: "created_by", Description: "User ID, or me"}}, dateRangeParams("created")...),
+	}, inviteController.List)
+	workspaceInvites.Delete("/:id/invites/:inviteId", openapi.Operation{
+		Summary: "Revoke an invite", Scope: membersManage,
+	}, inviteController.Revoke)
 
 	// Folder routes (within workspace)
-	workspaces.Get("/:workspaceId/folders/trash", folderController.ListTrash) // Folder trash
-	workspaces.Get("/:workspaceId/folders", folderController.List)
-	workspaces.Post("/:workspaceId/folders", folderController.Create)
-	workspaces.Get("/:workspaceId/folders/:id", folderController.Get)
-	workspaces.Put("/:workspaceId/folders/:id", folderController.Update)
-	workspaces.Delete("/:workspaceId/folders/:id", folderController.Delete)
-	workspaces.Post("/:workspaceId/folders/:id/restore", folderController.Restore)
-	workspaces.Delete("/:workspaceId/folders/:id/permanent", folderController.HardDelete)
-	workspaces.Post("/:workspaceId/folders/:id/diagrams", folderController.AddDiagrams)
-	workspaces.Delete("/:workspaceId/folders/:id/diagrams/:diagramId", folderController.RemoveDiagram)
+	folders := workspaces.Tagged("Folders")
+	folders.Get("/:workspaceId/folders/tree", openapi.Operation{
+		Summary: "Get the folder tree", Scope: read, Response: models.FolderTreeResponse{},
+	}, folderController.Tree)
+	folders.Get("/:workspaceId/folders", openapi.Operation{
+		Summary: "List folders", Scope: read, Paginated: true, Params: folderFilterParams, Response: []models.FolderResponse{},
+	}, folderController.List)
+	folders.Post("/:workspaceId/folders", openapi.Operation{
+		Summary: "Create a folder", Scope: write, Status: http.StatusCreated,
+		Body: models.CreateFolderRequest{}, Response: models.FolderResponse{},
+	}, folderController.Create)
+	folders.Get("/:workspaceId/folders/:id", openapi.Operation{
+		Summary: "Get a folder", Scope: read, Response: models.FolderResponse{},
+	}, folderController.Get)
+	folders.Put("/:workspaceId/folders/:id", openapi.Operation{
+		Summary: "Update a folder", Scope: write, Body: models.UpdateFolderRequest{}, Response: models.FolderResponse{},
+	}, folderController.Update)
+	folders.Post("/:workspaceId/folders/:id/move", openapi.Operation{
+		Summary: "Move a folder", Scope: write, Body: models.MoveFolderRequest{}, Response: models.FolderResponse{},
+	}, folderController.Move)
+	folders.Get("/:workspaceId/folders/:id/breadcrumbs", openapi.Operation{
+		Summary: "Get the path from the root to a folder", Scope: read, Response: []models.Breadcrumb{},
+	}, folderController.Breadcrumbs)
+	folders.Delete("/:workspaceId/folders/:id", openapi.Operation{
+		Summary: "Move a folder and its contents to the trash", Scope: write,
+	}, folderController.Delete)
+	folders.Post("/:workspaceId/folders/:id/restore", openapi.Operation{
+		Summary: "Restore a folder from the trash", Scope: write,
+	}, folderController.Restore)
+	folders.Delete("/:workspaceId/folders/:id/permanent", openapi.Operation{
+		Summary: "Delete a trashed folder permanently", Scope: write,
+	}, folderController.HardDelete)
+	folders.Post("/:workspaceId/folders/:id/diagrams", openapi.Operation{
+		Summary: "Add diagrams to a folder", Scope: write, Body: models.AddDiagramsRequest{},
+	}, folderController.AddDiagrams)
+	folders.Delete("/:workspaceId/folders/:id/diagrams/:diagramId", openapi.Operation{
+		Summary: "Remove a diagram from a folder", Scope: write,
+	}, folderController.RemoveDiagram)
+
+	// Tag routes (within workspace)
+	tags := workspaces.Tagged("Tags")
+	tags.Get("/:workspaceId/tags", openapi.Operation{
+		Summary: "List tags", Scope: read, Response: []models.TagResponse{},
+	}, tagController.List)
+	tags.Post("/:workspaceId/tags", openapi.Operation{
+		Summary: "Create a tag", Scope: write, Status: http.StatusCreated,
+		Body: models.CreateTagRequest{}, Response: models.TagResponse{},
+	}, tagController.Create)
+	tags.Put("/:workspaceId/tags/:tagId", openapi.Operation{
+		Summary: "Rename or recolor a tag", Scope: write, Body: models.UpdateTagRequest{}, Response: models.TagResponse{},
+	}, tagController.Update)
+	tags.Delete("/:workspaceId/tags/:tagId", openapi.Operation{
+		Summary: "Delete a tag and remove it from diagrams", Scope: write,
+	}, tagController.Delete)
+	tags.Post("/:workspaceId/diagrams/tags", openapi.Operation{
+		Summary: "Add and remove tags on several diagrams", Scope: write,
+		Body: models.BulkTagRequest{}, Response: models.BulkTagResponse{},
+	}, tagController.BulkUpdate)
 
 	// Diagram routes (within workspace)
-	workspaces.Get("/:workspaceId/diagrams", diagramController.List)
-	workspaces.Post("/:workspaceId/diagrams", diagramController.Create)
-	workspaces.Get("/:workspaceId/diagrams/:id", diagramController.Get)
-	workspaces.Get("/:workspaceId/diagrams/:id/download", diagramController.Download)
-	workspaces.Put("/:workspaceId/diagrams/:id", diagramController.Update)
-	workspaces.Delete("/:workspaceId/diagrams/:id", diagramController.Delete)
-	workspaces.Post("/:workspaceId/diagrams/:id/restore", diagramController.Restore)
-	workspaces.Delete("/:workspaceId/diagrams/:id/permanent", diagramController.HardDelete)
+	diagrams := workspaces.Tagged("Diagrams")
+	diagrams.Get("/:workspaceId/diagrams", openapi.Operation{
+		Summary: "List diagrams", Scope: read, Paginated: true, Params: diagramFilterParams,
+		Response: []models.DiagramResponse{},
+	}, diagramController.List)
+	diagrams.Post("/:workspaceId/diagrams", openapi.Operation{
+		Summary: "Create a diagram", Scope: write, Status: http.StatusCreated,
+		Form: createDiagramForm{}, Response: models.DiagramResponse{},
+	}, diagramController.Create)
+	diagrams.Post("/:workspaceId/diagrams/move", openapi.Operation{
+		Summary: "Move several diagrams", Scope: write,
+		Body: models.MoveDiagramsRequest{}, Response: models.MoveDiagramsResponse{},
+	}, diagramController.MoveMany)
+	diagrams.Get("/:workspaceId/diagrams/:id", openapi.Operation{
+		Summary: "Get a diagram", Scope: read, Response: models.DiagramResponse{},
+	}, diagramController.Get)
+	diagrams.Get("/:workspaceId/diagrams/:id/download", openapi.Operation{
+		Summary: "Download a diagram file", Scope: read, Produces: []string{fiber.MIMEOctetStream},
+	}, diagramController.Download)
+	diagrams.Put("/:workspaceId/diagrams/:id", openapi.Operation{
+		Summary: "Update a diagram's details (JSON) or replace its file (multipart)", Scope: write,
+		Body: models.UpdateDiagramRequest{}, Form: diagramFileForm{}, Response: models.DiagramResponse{},
+	}, diagramController.Update)
+	diagrams.Post("/:workspaceId/diagrams/:id/move", openapi.Operation{
+		Summary: "Move a diagram", Scope: write, Body: models.MoveDiagramRequest{}, Response: models.DiagramResponse{},
+	}, diagramController.Move)
+	diagrams.Delete("/:workspaceId/diagrams/:id", openapi.Operation{
+		Summary: "Move a diagram to the trash", Scope: write,
+	}, diagramController.Delete)
+	diagrams.Post("/:workspaceId/diagrams/:id/restore", openapi.Operation{
+		Summary: "Restore a diagram from the trash", Scope: write,
+	}, diagramController.Restore)
+	diagrams.Delete("/:workspaceId/diagrams/:id/permanent", openapi.Operation{
+		Summary: "Delete a trashed diagram permanently", Scope: write,
+	}, diagramController.HardDelete)
+	diagrams.Post("/:workspaceId/diagrams/:id/star", openapi.Operation{
+		Summary: "Star a diagram", Scope: write,
+	}, diagramController.Star)
+	diagrams.Delete("/:workspaceId/diagrams/:id/star", openapi.Operation{
+		Summary: "Unstar a diagram", Scope: write,
+	}, diagramController.Unstar)
+
+	// Signed URL routes
+	diagrams.Get("/:workspaceId/diagrams/:id/upload-url", openapi.Operation{
+		Summary: "Get a signed URL to upload a diagram file or thumbnail", Scope: write, Response: models.UploadURLResponse{},
+		Params: []openapi.Param{{Name: "type", Enum: []string{"diagram", "thumbnail"}}},
+	}, diagramController.GetUploadURL)
+	diagrams.Get("/:workspaceId/diagrams/:id/download-url", openapi.Operation{
+		Summary: "Get a signed URL to download a diagram file", Scope: read, Response: models.DownloadURLResponse{},
+	}, diagramController.GetDownloadURL)
+
+	// Template routes (within workspace)
+	templates := workspaces.Tagged("Templates")
+	templates.Get("/:workspaceId/templates", openapi.Operation{
+		Summary: "List templates available in a workspace", Scope: read, Response: []models.TemplateResponse{},
+	}, templateController.List)
+	templates.Get("/:workspaceId/templates/:id", openapi.Operation{
+		Summary: "Get a template", Scope: read, Response: models.TemplateResponse{},
+	}, templateController.Get)
+	templates.Post("/:workspaceId/templates/:id/instantiate", openapi.Operation{
+		Summary: "Create a diagram from a template", Scope: write, Status: http.StatusCreated,
+		Body: models.InstantiateTemplateRequest{}, Response: models.DiagramResponse{},
+	}, templateController.Instantiate)
+	templates.Put("/:workspaceId/diagrams/:id/template", openapi.Operation{
+		Summary: "Make a diagram a template", Scope: write, Body: models.MarkTemplateRequest{}, Response: models.DiagramResponse{},
+	}, templateController.Mark)
+	templates.Delete("/:workspaceId/diagrams/:id/template", openapi.Operation{
+		Summary: "Stop using a diagram as a template", Scope: write, Response: models.DiagramResponse{},
+	}, templateController.Unmark)
 
 	// Live collaboration routes (within diagram)
-	workspaces.Get("/:workspaceId/diagrams/:diagramId/live", liveCollabController.GetStatus)
-	workspaces.Get("/:workspaceId/diagrams/:diagramId/live/token", liveCollabController.GetToken)
+	live := workspaces.Tagged("Live collaboration")
+	live.Get("/:workspaceId/diagrams/:diagramId/live", openapi.Operation{
+		Summary: "Check whether live collaboration is available", Scope: read, Response: models.LiveStatusResponse{},
+	}, liveCollabController.GetStatus)
+	live.Get("/:workspaceId/diagrams/:diagramId/live/token", openapi.Operation{
+		Summary: "Get a token to join live collaboration", Scope: write, Response: models.LiveTokenResponse{},
+	}, liveCollabController.GetToken)
 
-	// Signed URL routes
-	workspaces.Get("/:workspaceId/diagrams/:id/upload-url", diagramController.GetUploadURL)
-	workspaces.Get("/:workspaceId/diagrams/:id/download-url", diagramController.GetDownloadURL)
+	// Share link routes (within diagram)
+	sharing := workspaces.Tagged("Sharing")
+	sharing.Get("/:workspaceId/diagrams/:id/shares", openapi.Operation{
+		Summary: "List share links", Scope: read, Response: []models.ShareLinkResponse{},
+	}, shareController.List)
+	sharing.Post("/:workspaceId/diagrams/:id/shares", openapi.Operation{
+		Summary: "Create a share link", Scope: write, Status: http.StatusCreated,
+		Description: "The link's token and URL are only returned by this call.",
+		Body:        models.CreateShareLinkRequest{}, Response: models.ShareLinkResponse{},
+	}, shareController.Create)
+	sharing.Delete("/:workspaceId/diagrams/:id/shares/:shareId", openapi.Operation{
+		Summary: "Revoke a share link", Scope: write,
+	}, shareController.Revoke)
+
+	// Embed token routes (within diagram)
+	sharing.Get("/:workspaceId/diagrams/:id/embeds", openapi.Operation{
+		Summary: "List embed tokens", Scope: read, Response: []models.EmbedTokenResponse{},
+	}, embedController.List)
+	sharing.Post("/:workspaceId/diagrams/:id/embeds", openapi.Operation{
+		Summary: "Create an embed token", Scope: write, Status: http.StatusCreated,
+		Description: "The token and embed URL are only returned by this call.",
+		Body:        models.CreateEmbedTokenRequest{}, Response: models.EmbedTokenResponse{},
+	}, embedController.Create)
+	sharing.Delete("/:workspaceId/diagrams/:id/embeds/:embedId", openapi.Operation{
+		Summary: "Revoke an embed token", Scope: write,
+	}, embedController.Revoke)
+
+	// Comment routes (within diagram)
+	comments := workspaces.Tagged("Comments")
+	comments.Get("/:workspaceId/diagrams/:id/comments", openapi.Operation{
+		Summary: "List comment threads", Scope: read, Paginated: true, Response: []models.CommentThread{},
+		Params: []openapi.Param{
+			{Name: "resolved", Type: "boolean"},
+			{Name: "shape_id", Description: "Only threads anchored to this shape"},
+		},
+	}, commentController.List)
+	comments.Post("/:workspaceId/diagrams/:id/comments", openapi.Operation{
+		Summary: "Start a comment thread", Scope: write, Status: http.StatusCreated,
+		Body: models.CreateCommentRequest{}, Response: models.CommentThread{},
+	}, commentController.Create)
+	comments.Get("/:workspaceId/diagrams/:id/comments/:commentId", openapi.Operation{
+		Summary: "Get a comment thread", Scope: read, Response: models.CommentThread{},
+	}, commentController.Get)
+	comments.Put("/:workspaceId/diagrams/:id/comments/:commentId", openapi.Operation{
+		Summary: "Edit a comment", Scope: write, Body: models.CommentBodyRequest{}, Response: models.Comment{},
+	}, commentController.Edit)
+	comments.Delete("/:workspaceId/diagrams/:id/comments/:commentId", openapi.Operation{
+		Summary: "Delete a comment", Scope: write,
+	}, commentController.Delete)
+	comments.Post("/:workspaceId/diagrams/:id/comments/:commentId/replies", openapi.Operation{
+		Summary: "Reply to a thread", Scope: write, Status: http.StatusCreated,
+		Body: models.CommentBodyRequest{}, Response: models.Comment{},
+	}, commentController.Reply)
+	comments.Post("/:workspaceId/diagrams/:id/comments/:commentId/resolve", openapi.Operation{
+		Summary: "Resolve a thread", Scope: write, Response: models.CommentThread{},
+	}, commentController.Resolve)
+	comments.Post("/:workspaceId/diagrams/:id/comments/:commentId/reopen", openapi.Operation{
+		Summary: "Reopen a resolved thread", Scope: write, Response: models.CommentThread{},
+	}, commentController.Reopen)
+
+	// Mention routes
+	comments.Post("/:id/mentions/resolve", openapi.Operation{
+		Summary: "Resolve the @mentions in a text", Scope: read,
+		Body: models.ResolveMentionsRequest{}, Response: models.MentionResult{},
+	}, mentionController.Resolve)
+	comments.Get("/:workspaceId/diagrams/:id/mentions", openapi.Operation{
+		Summary: "List the mentions in a diagram", Scope: read, Response: []models.Mention{},
+	}, mentionController.ListForDiagram)
+
+	// Webhook routes (Admin+)
+	webhooks := workspaces.Tagged("Webhooks")
+	webhooks.Get("/:id/webhooks", openapi.Operation{
+		Summary: "List webhooks", Scope: admin, Response: []models.Webhook{},
+	}, webhookController.List)
+	webhooks.Post("/:id/webhooks", openapi.Operation{
+		Summary: "Create a webhook", Scope: admin, Status: http.StatusCreated,
+		Description: "The signing secret is only returned by this call and when it is rotated.",
+		Body:        models.CreateWebhookRequest{}, Response: models.WebhookWithSecret{},
+	}, webhookController.Create)
+	webhooks.Get("/:workspaceId/webhooks/:id", openapi.Operation{
+		Summary: "Get a webhook", Scope: admin, Response: models.Webhook{},
+	}, webhookController.Get)
+	webhooks.Put("/:workspaceId/webhooks/:id", openapi.Operation{
+		Summary: "Update a webhook", Scope: admin, Body: models.UpdateWebhookRequest{}, Response: models.Webhook{},
+	}, webhookController.Update)
+	webhooks.Delete("/:workspaceId/webhooks/:id", openapi.Operation{
+		Summary: "Delete a webhook", Scope: admin,
+	}, webhookController.Delete)
+	webhooks.Post("/:workspaceId/webhooks/:id/rotate-secret", openapi.Operation{
+		Summary: "Rotate a webhook's signing secret", Scope: admin, Response: models.WebhookWithSecret{},
+	}, webhookController.RotateSecret)
+	webhooks.Get("/:workspaceId/webhooks/:id/deliveries", openapi.Operation{
+		Summary: "List a webhook's deliveries", Scope: admin, Paginated: true, Response: []models.WebhookDelivery{},
+		Params: []openapi.Param{{Name: "status", Enum: []string{
+			string(models.WebhookDeliveryPending), string(models.WebhookDeliverySucceeded), string(models.WebhookDeliveryFailed),
+		}}},
+	}, webhookController.ListDeliveries)
+	webhooks.Get("/:workspaceId/webhooks/:id/deliveries/:deliveryId", openapi.Operation{
+		Summary: "Get a delivery", Scope: admin, Response: models.WebhookDelivery{},
+	}, webhookController.GetDelivery)
+	webhooks.Post("/:workspaceId/webhooks/:id/deliveries/:deliveryId/redeliver", openapi.Operation{
+		Summary: "Send a delivery again", Scope: admin, Status: http.StatusCreated, Response: models.WebhookDelivery{},
+	}, webhookController.Redeliver)
+
+	// Search across all of the user's workspaces
+	search := spec.Group(api, "/search", "Search", middleware.AuthMiddleware(authService), middleware.UnrestrictedToken())
+	search.Get("/", openapi.Operation{
+		Summary: "Search diagrams and folders across workspaces", Scope: read, Response: []models.SearchResult{},
+		Params: []openapi.Param{
+			{Name: "q", Required: true},
+			{Name: "workspace_id", Description: "Only search this workspace"},
+			{Name: "limit", Type: "integer"},
+			{Name: "content", Type: "boolean", Description: "Also match diagram contents (true by default)"},
+		},
+	}, searchController.Search)
 
 	// Invite routes (authenticated, outside workspace context)
-	invites := app.Group("/invites", middleware.AuthMiddleware(authService))
-	invites.Get("/", inviteController.ListUserInvites)        // List user's pending invites
-	invites.Get("/:token", inviteController.GetByToken)       // Get invite details
-	invites.Post("/:token/accept", inviteController.Accept)   // Accept invite
+	invites := spec.Group(api, "/invites", "Invites", middleware.AuthMiddleware(authService), middleware.UnrestrictedToken())
+	invites.Get("/", openapi.Operation{
+		Summary: "List the user's pending invites", Scope: read, Response: []models.WorkspaceInviteResponse{},
+	}, inviteController.ListUserInvites)
+	invites.Get("/:token", openapi.Operation{
+		Summary: "Get an invite", Scope: read, Response: models.InviteDetailsResponse{},
+	}, inviteController.GetByToken)
+	invites.Post("/:token/accept", openapi.Operation{
+		Summary: "Accept an invite", Scope: membersManage, Response: models.AcceptInviteResponse{},
+	}, inviteController.Accept)
+
+	// Notification center of the current user
+	notifications := spec.Group(api, "/notifications", "Notifications", middleware.AuthMiddleware(authService), middleware.UnrestrictedToken())
+	notifications.Get("/", openapi.Operation{
+		Summary: "List notifications", Scope: read, Paginated: true, Response: []models.Notification{},
+		Params: []openapi.Param{{Name: "unread", Type: "boolean", Description: "Only unread notifications"}},
+	}, notificationController.List)
+	notifications.Get("/unread-count", openapi.Operation{
+		Summary: "Count unread notifications", Scope: read, Response: models.UnreadCountResponse{},
+	}, notificationController.UnreadCount)
+	notifications.Post("/read-all", openapi.Operation{
+		Summary: "Mark every notification read", Scope: write, Response: models.MarkReadResponse{},
+	}, notificationController.MarkAllRead)
+	notifications.Get("/preferences", openapi.Operation{
+		Summary: "Get notification preferences", Scope: read, Response: authModels.NotificationPreferences{},
+	}, notificationController.GetPreferences)
+	notifications.Put("/preferences", openapi.Operation{
+		Summary: "Update notification preferences", Scope: write,
+		Body: models.NotificationPreferencesRequest{}, Response: authModels.NotificationPreferences{},
+	}, notificationController.UpdatePreferences)
+	notifications.Post("/:id/read", openapi.Operation{
+		Summary: "Mark a notification read", Scope: write, Response: models.MarkReadResponse{},
+	}, notificationController.MarkRead)
+
+	// Public share links (unauthenticated, rate limited per IP)
+	shared := spec.Group(api, "/share", "Sharing", middleware.NewEndpointLimiter(cfg.RateLimitAuth, "share"))
+	shared.Get("/:token", openapi.Operation{
+		Summary: "Open a share link", Public: true, Response: models.SharedDiagramResponse{},
+		Params: []openapi.Param{{Name: controllers.SharePasswordHeader, In: "header", Description: "Password of a protected link"}},
+	}, shareController.GetShared)
+
+	// Embeddable snapshots and oEmbed discovery (unauthenticated, rate limited per IP)
+	embedLimiter := middleware.NewEndpointLimiter(cfg.RateLimitGlobal, "embed")
+	embeds := spec.Group(api, "/embed", "Sharing", embedLimiter)
+	embeds.Get("/:token", openapi.Operation{
+		Summary: "Render an embedded diagram", Public: true, Produces: []string{"image/svg+xml"},
+		Params: embedSizeParams,
+	}, embedController.Embed)
+	oembed := spec.Group(api, "/oembed", "Sharing", embedLimiter)
+	oembed.Get("/", openapi.Operation{
+		Summary: "Describe an embed URL for oEmbed consumers", Public: true, Raw: true, Response: models.OEmbedResponse{},
+		Params: append([]openapi.Param{
+			{Name: "url", Required: true, Description: "Embed URL"},
+			{Name: "format", Enum: []string{"json"}},
+		}, embedSizeParams...),
+	}, embedController.OEmbed)
+}
+
+// Token scopes required by personal access tokens
+const (
+	read          = authModels.ScopeDiagramsRead
+	write         = authModels.ScopeDiagramsWrite
+	membersManage = authModels.ScopeMembersManage
+	admin         = authModels.ScopeWorkspacesAdmin
+)
+
+// Query parameters of the listing filters (see controllers/pagination.go)
+var (
+	roles     = []string{string(models.RoleOwner), string(models.RoleAdmin), string(models.RoleEditor), string(models.RoleViewer)}
+	tagsParam = openapi.Param{Name: "tags", Description: "Comma-separated tags, all of which must be present"}
+
+	diagramFilterParams = append(append([]openapi.Param{
+		{Name: "q", Description: "Matches diagram names"},
+		tagsParam,
+		{Name: "folder_id", Description: "Folder ID, or root for the workspace root"},
+		{Name: "created_by", Description: "User ID, or me"},
+	}, dateRangeParams("created")...), dateRangeParams("updated")...)
+
+	folderFilterParams = append(append([]openapi.Param{
+		{Name: "q", Description: "Matches folder names"},
+		{Name: "parent_id", Description: "Parent folder ID, or root for the workspace root"},
+		{Name: "created_by", Description: "User ID, or me"},
+	}, dateRangeParams("created")...), dateRangeParams("updated")...)
+
+	auditFilterParams = append([]openapi.Param{
+		{Name: "action", Description: "Comma-separated event types"},
+		{Name: "actor_id", Description: "User ID, or me"},
+		{Name: "target_id"},
+	}, dateRangeParams("created")...)
+
+	embedSizeParams = []openapi.Param{
+		{Name: "maxwidth", Type: "integer"},
+		{Name: "maxheight", Type: "integer"},
+	}
+)
+
+// dateRangeParams documents the <name>_after and <name>_before filters
+func dateRangeParams(name string) []openapi.Param {
+	return []openapi.Param{
+		{Name: name + "_after", Description: "RFC 3339 time or YYYY-MM-DD"},
+		{Name: name + "_before", Description: "RFC 3339 time or YYYY-MM-DD"},
+	}
+}
+
+// createDiagramForm is the multipart body of diagram creation; metadata is a JSON object
+// with name, description and folder_id, used instead of the separate fields when present
+type createDiagramForm struct {
+	Metadata    string                `form:"metadata,omitempty"`
+	Name        string                `form:"name,omitempty"`
+	Description string                `form:"description,omitempty"`
+	FolderID    string                `form:"folder_id,omitempty"`
+	File        *multipart.FileHeader `form:"file,omitempty"`
 }
 
+// diagramFileForm is the multipart body replacing a diagram's file
+type diagramFileForm struct {
+	File *multipart.FileHeader `form:"file"`
+}
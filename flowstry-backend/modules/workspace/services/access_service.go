@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccessService records which diagrams each user opened, powering "recently opened"
+type AccessService struct {
+	access         repository.AccessRepository
+	diagramService *DiagramService
+}
+
+// NewAccessService creates a new access service
+func NewAccessService(access repository.AccessRepository, diagramService *DiagramService) *AccessService {
+	return &AccessService{
+		access:         access,
+		diagramService: diagramService,
+	}
+}
+
+// RecordOpen marks a diagram as opened by the user now
+func (s *AccessService) RecordOpen(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) error {
+	return s.access.RecordOpen(ctx, userID, workspaceID, diagramID, time.Now())
+}
+
+// ListRecent returns the user's most recently opened diagrams in the given workspaces,
+// newest first, together with when each was last opened
+func (s *AccessService) ListRecent(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID, limit int64, filter *models.DiagramFilter) ([]*models.Diagram, map[primitive.ObjectID]time.Time, error) {
+	if limit > models.MaxPageSize {
+		limit = models.MaxPageSize
+	}
+
+	// Over-fetch access records since some diagrams may be trashed or filtered out
+	window := limit * 3
+	if window < 50 {
+		window = 50
+	}
+
+	records, err := s.access.ListRecent(ctx, userID, workspaceIDs, window)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return []*models.Diagram{}, map[primitive.ObjectID]time.Time{}, nil
+	}
+
+	openedAt := make(map[primitive.ObjectID]time.Time, len(records))
+	ids := make([]primitive.ObjectID, len(records))
+	for i, r := range records {
+		ids[i] = r.DiagramID
+		openedAt[r.DiagramID] = r.LastOpenedAt
+	}
+
+	found, err := s.diagramService.ListByIDs(ctx, workspaceIDs, ids, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Restore the access order
+	byID := make(map[primitive.ObjectID]*models.Diagram, len(found))
+	for _, d := range found {
+		byID[d.ID] = d
+	}
+	result := make([]*models.Diagram, 0, limit)
+	for _, id := range ids {
+		if d, ok := byID[id]; ok {
+			result = append(result, d)
+			if int64(len(result)) == limit {
+				break
+			}
+		}
+	}
+
+	return result, openedAt, nil
+}
+
+// DeleteForDiagram removes all access records of a diagram (used on permanent deletion)
+func (s *AccessService) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return s.access.DeleteForDiagram(ctx, workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes all access records of a workspace (used when deleting workspace)
+func (s *AccessService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.access.DeleteAllForWorkspace(ctx, workspaceID)
+}
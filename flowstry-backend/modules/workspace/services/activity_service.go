@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// activitySessionGap is how long after a save the next save of the same diagram by the same user still joins its session
+const activitySessionGap = 30 * time.Minute
+
+// activityEvents lists the events shown in the feed; the rest are only audited
+var activityEvents = map[models.EventType]bool{
+	models.EventWorkspaceCreated:   true,
+	models.EventWorkspaceUpdated:   true,
+	models.EventMemberAdded:        true,
+	models.EventMemberRemoved:      true,
+	models.EventMemberRoleChanged:  true,
+	models.EventDiagramCreated:     true,
+	models.EventDiagramUpdated:     true,
+	models.EventDiagramMoved:       true,
+	models.EventDiagramDeleted:     true,
+	models.EventDiagramRestored:    true,
+	models.EventDiagramHardDeleted: true,
+	models.EventFolderCreated:      true,
+	models.EventFolderUpdated:      true,
+	models.EventFolderMoved:        true,
+	models.EventFolderDeleted:      true,
+	models.EventFolderRestored:     true,
+	models.EventFolderHardDeleted:  true,
+}
+
+// ActivityService builds the human-friendly activity feed of workspaces and diagrams
+type ActivityService struct {
+	activity repository.ActivityRepository
+	users    repository.UserRepository
+	folders  repository.FolderRepository
+	diagrams repository.DiagramRepository
+}
+
+// NewActivityService creates a new activity service
+func NewActivityService(activity repository.ActivityRepository, users repository.UserRepository, folders repository.FolderRepository, diagrams repository.DiagramRepository) *ActivityService {
+	return &ActivityService{
+		activity: activity,
+		users:    users,
+		folders:  folders,
+		diagrams: diagrams,
+	}
+}
+
+// Record adds an event to the feed; subscribe it to the workspace EventBus.
+// Like the audit log, failures are logged because the change has already been made.
+func (s *ActivityService) Record(ctx context.Context, event *models.Event) {
+	if err := s.record(ctx, event); err != nil {
+		fmt.Printf("Warning: Failed to add %s on %s %s to the activity feed: %v\n", event.Type, event.TargetType, event.TargetID.Hex(), err)
+	}
+}
+
+func (s *ActivityService) record(ctx context.Context, event *models.Event) error {
+	if event.Type == models.EventWorkspaceDeleted {
+		return s.activity.DeleteAllForWorkspace(ctx, event.WorkspaceID)
+	}
+	if !activityEvents[event.Type] {
+		return nil
+	}
+
+	details := activityDetails(event)
+	if event.Type == models.EventDiagramUpdated && details == nil {
+		extended, err := s.extendSession(ctx, event)
+		if err != nil || extended {
+			return err
+		}
+	}
+	if event.Type == models.EventDiagramMoved || event.Type == models.EventFolderMoved {
+		details = map[string]string{"folder_name": s.folderName(ctx, event)}
+	}
+
+	entry := &models.ActivityEntry{
+		WorkspaceID: event.WorkspaceID,
+		Type:        event.Type,
+		TargetType:  event.TargetType,
+		TargetID:    event.TargetID,
+		TargetName:  s.targetName(ctx, event),
+		Details:     details,
+		Count:       1,
+		CreatedAt:   event.CreatedAt,
+		UpdatedAt:   event.CreatedAt,
+	}
+	if event.Actor != nil {
+		entry.ActorID = &event.Actor.UserID
+		entry.ActorName = s.userName(ctx, event.Actor.UserID, event.Actor.Email)
+	}
+	return s.activity.Append(ctx, entry)
+}
+
+// extendSession folds a save into the diagram's latest entry when that is a recent save session by the same user
+func (s *ActivityService) extendSession(ctx context.Context, event *models.Event) (bool, error) {
+	if event.Actor == nil {
+		return false, nil
+	}
+	latest, err := s.activity.Latest(ctx, event.WorkspaceID, event.TargetID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if latest.Type != models.EventDiagramUpdated || latest.Details != nil ||
+		latest.ActorID == nil || *latest.ActorID != event.Actor.UserID ||
+		event.CreatedAt.Sub(latest.UpdatedAt) > activitySessionGap {
+		return false, nil
+	}
+	return true, s.activity.Extend(ctx, event.WorkspaceID, latest.ID, event.CreatedAt)
+}
+
+// activityDetails picks the values a summary needs from an event; nil for a plain diagram save
+func activityDetails(event *models.Event) map[string]string {
+	switch event.Type {
+	case models.EventWorkspaceUpdated, models.EventDiagramUpdated, models.EventFolderUpdated:
+		if old, ok := event.Before["name"]; ok {
+			return map[string]string{"old_name": old}
+		}
+	case models.EventMemberAdded, models.EventMemberRoleChanged:
+		return map[string]string{"role": event.After["role"]}
+	}
+	return nil
+}
+
+// targetName finds the display name of an event's target, preferring the name the event carries
+func (s *ActivityService) targetName(ctx context.Context, event *models.Event) string {
+	if name := event.After["name"]; name != "" {
+		return name
+	}
+	if name := event.Before["name"]; name != "" {
+		return name
+	}
+
+	switch event.TargetType {
+	case models.TargetMember:
+		return s.userName(ctx, event.TargetID, "")
+	case models.TargetDiagram:
+		if d, err := s.diagrams.Get(ctx, event.WorkspaceID, event.TargetID, repository.AnyTrash); err == nil {
+			return d.Name
+		}
+	case models.TargetFolder:
+		if f, err := s.folders.Get(ctx, event.WorkspaceID, event.TargetID, repository.AnyTrash); err == nil {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// folderName finds the name of the folder a diagram or folder was moved to; "" is the workspace root
+func (s *ActivityService) folderName(ctx context.Context, event *models.Event) string {
+	hex := event.After["folder_id"]
+	if event.TargetType == models.TargetFolder {
+		hex = event.After["parent_folder_id"]
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return ""
+	}
+	folder, err := s.folders.Get(ctx, event.WorkspaceID, id, repository.AnyTrash)
+	if err != nil {
+		return ""
+	}
+	return folder.Name
+}
+
+// userName returns a user's display name, falling back to their email
+func (s *ActivityService) userName(ctx context.Context, userID primitive.ObjectID, email string) string {
+	if user, err := s.users.Get(ctx, userID); err == nil {
+		if user.Name != "" {
+			return user.Name
+		}
+		return user.Email
+	}
+	return email
+}
+
+// activityPageSpec lists the sort options for activity feeds
+var activityPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByUpdated},
+	defaultSort:  models.SortByUpdated,
+	defaultOrder: models.SortDesc,
+}
+
+// List lists one page of a workspace's feed, most recent first; filter.TargetID narrows it to one diagram.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *ActivityService) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.ActivityFilter, page *models.PageRequest) ([]*models.ActivityEntry, string, error) {
+	p, err := activityPageSpec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, err := s.activity.List(ctx, workspaceID, filter, q)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, e := range entries {
+		e.Summary = activitySummary(e)
+	}
+
+	entries, more := splitPage(entries, p.limit)
+	if !more {
+		return entries, "", nil
+	}
+	last := entries[len(entries)-1]
+	return entries, p.cursorAfter(last.UpdatedAt, last.ID), nil
+}
+
+// activitySummary renders an entry as a sentence, e.g. "Ana edited Payments Flow"
+func activitySummary(e *models.ActivityEntry) string {
+	actor := e.ActorName
+	if actor == "" {
+		actor = "Someone"
+	}
+	target := e.TargetName
+	if e.TargetType == models.TargetFolder {
+		target = "the folder " + target
+	}
+	self := e.ActorID != nil && *e.ActorID == e.TargetID
+
+	switch e.Type {
+	case models.EventWorkspaceCreated:
+		return actor + " created the workspace"
+	case models.EventWorkspaceUpdated:
+		if _, ok := e.Details["old_name"]; ok {
+			return actor + " renamed the workspace to " + e.TargetName
+		}
+		return actor + " updated the workspace settings"
+	case models.EventMemberAdded:
+		if self {
+			return actor + " joined the workspace"
+		}
+		return fmt.Sprintf("%s added %s as %s", actor, target, e.Details["role"])
+	case models.EventMemberRemoved:
+		if self {
+			return actor + " left the workspace"
+		}
+		return actor + " removed " + target
+	case models.EventMemberRoleChanged:
+		return fmt.Sprintf("%s changed %s's role to %s", actor, target, e.Details["role"])
+	case models.EventDiagramCreated, models.EventFolderCreated:
+		return actor + " created " + target
+	case models.EventDiagramUpdated, models.EventFolderUpdated:
+		if old, ok := e.Details["old_name"]; ok {
+			return fmt.Sprintf("%s renamed %s to %s", actor, old, e.TargetName)
+		}
+		if e.Count > 1 {
+			return fmt.Sprintf("%s edited %s (%d saves)", actor, target, e.Count)
+		}
+		return actor + " edited " + target
+	case models.EventDiagramMoved, models.EventFolderMoved:
+		if folder := e.Details["folder_name"]; folder != "" {
+			return fmt.Sprintf("%s moved %s to %s", actor, target, folder)
+		}
+		return fmt.Sprintf("%s moved %s to the workspace root", actor, target)
+	case models.EventDiagramDeleted, models.EventFolderDeleted:
+		return actor + " moved " + target + " to the trash"
+	case models.EventDiagramRestored, models.EventFolderRestored:
+		return actor + " restored " + target
+	case models.EventDiagramHardDeleted, models.EventFolderHardDeleted:
+		if e.ActorID == nil {
+			// Purged from the trash after the retention period
+			return target + " was permanently deleted from the trash"
+		}
+		return actor + " permanently deleted " + target
+	}
+	return fmt.Sprintf("%s changed %s", actor, target)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestActivitySessions(t *testing.T) {
+	tw := newTestWorkspace(t)
+	activity := NewActivityService(tw.store.Activity, tw.store.Users, tw.store.Folders, tw.store.Diagrams)
+	events := NewEventBus()
+	events.Subscribe(activity.Record)
+
+	diagram := &models.Diagram{WorkspaceID: tw.id, Name: "Payments Flow", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := tw.store.Diagrams.Create(context.Background(), diagram); err != nil {
+		t.Fatal(err)
+	}
+	diagramID := diagram.ID
+	save := func(user primitive.ObjectID, before, after map[string]string) {
+		ctx := WithActor(context.Background(), &models.Actor{UserID: user})
+		events.Emit(ctx, tw.id, models.EventDiagramUpdated, models.TargetDiagram, diagramID, before, after)
+	}
+
+	version := map[string]string{"version": "2"}
+	save(tw.editor.ID, version, version)
+	save(tw.editor.ID, version, version)
+	save(tw.editor.ID, version, version)
+	// Another user's save starts a new session, and so does a rename
+	save(tw.admin.ID, version, version)
+	save(tw.admin.ID, map[string]string{"name": "Payments"}, map[string]string{"name": "Payments Flow"})
+	save(tw.admin.ID, version, version)
+
+	entries, _, err := activity.List(context.Background(), tw.id, &models.ActivityFilter{TargetID: &diagramID}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"admin@example.com edited Payments Flow",
+		"admin@example.com renamed Payments to Payments Flow",
+		"admin@example.com edited Payments Flow",
+		"editor@example.com edited Payments Flow (3 saves)",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Summary != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, e.Summary, want[i])
+		}
+	}
+}
+
+func TestActivitySessionGap(t *testing.T) {
+	tw := newTestWorkspace(t)
+	activity := NewActivityService(tw.store.Activity, tw.store.Users, tw.store.Folders, tw.store.Diagrams)
+	ctx := context.Background()
+
+	diagramID := primitive.NewObjectID()
+	start := time.Now()
+	for _, at := range []time.Time{start, start.Add(activitySessionGap), start.Add(2*activitySessionGap + time.Second)} {
+		activity.Record(ctx, &models.Event{
+			Type: models.EventDiagramUpdated, WorkspaceID: tw.id, Actor: &models.Actor{UserID: tw.editor.ID},
+			TargetType: models.TargetDiagram, TargetID: diagramID, CreatedAt: at,
+		})
+	}
+
+	entries, _, err := activity.List(ctx, tw.id, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Count != 1 || entries[1].Count != 2 {
+		t.Fatalf("entries = %+v, want a session of 2 saves followed by a new one", entries)
+	}
+}
+
+func TestActivitySummaries(t *testing.T) {
+	ana, ben := primitive.NewObjectID(), primitive.NewObjectID()
+	tests := []struct {
+		entry models.ActivityEntry
+		want  string
+	}{
+		{models.ActivityEntry{ActorID: &ben, ActorName: "Ben", Type: models.EventMemberAdded, TargetType: models.TargetMember, TargetID: ben, TargetName: "Ben"}, "Ben joined the workspace"},
+		{models.ActivityEntry{ActorID: &ana, ActorName: "Ana", Type: models.EventMemberRoleChanged, TargetType: models.TargetMember, TargetID: ben, TargetName: "Ben", Details: map[string]string{"role": "admin"}}, "Ana changed Ben's role to admin"},
+		{models.ActivityEntry{ActorID: &ben, ActorName: "Ben", Type: models.EventDiagramMoved, TargetType: models.TargetDiagram, TargetName: "Payments Flow", Details: map[string]string{"folder_name": "Archive"}}, "Ben moved Payments Flow to Archive"},
+		{models.ActivityEntry{ActorID: &ana, ActorName: "Ana", Type: models.EventFolderDeleted, TargetType: models.TargetFolder, TargetName: "Drafts"}, "Ana moved the folder Drafts to the trash"},
+		{models.ActivityEntry{Type: models.EventDiagramHardDeleted, TargetType: models.TargetDiagram, TargetName: "Old"}, "Old was permanently deleted from the trash"},
+	}
+	for _, tt := range tests {
+		if got := activitySummary(&tt.entry); got != tt.want {
+			t.Errorf("summary = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidExportFormat = errors.New("invalid export format")
+)
+
+// Audit log export formats
+const (
+	AuditExportCSV   = "csv"
+	AuditExportJSONL = "jsonl"
+)
+
+// AuditService records and queries the append-only workspace audit log
+type AuditService struct {
+	audit repository.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(audit repository.AuditRepository) *AuditService {
+	return &AuditService{audit: audit}
+}
+
+// Record appends an event to the audit log; subscribe it to the workspace EventBus.
+// The change it describes has already happened, so a failure to record it is logged rather than returned.
+func (s *AuditService) Record(ctx context.Context, event *models.Event) {
+	entry := &models.AuditEntry{
+		WorkspaceID: event.WorkspaceID,
+		Action:      event.Type,
+		TargetType:  event.TargetType,
+		TargetID:    event.TargetID,
+		Before:      event.Before,
+		After:       event.After,
+		CreatedAt:   event.CreatedAt,
+	}
+	if actor := event.Actor; actor != nil {
+		entry.ActorID = &actor.UserID
+		entry.ActorEmail = actor.Email
+		entry.IP = actor.IP
+		entry.UserAgent = actor.UserAgent
+	}
+
+	if err := s.audit.Append(ctx, entry); err != nil {
+		fmt.Printf("Warning: Failed to record %s on %s %s: %v\n", event.Type, event.TargetType, event.TargetID.Hex(), err)
+	}
+}
+
+// auditPageSpec lists the sort options for audit log listings
+var auditPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByCreated},
+	defaultSort:  models.SortByCreated,
+	defaultOrder: models.SortDesc,
+}
+
+// List lists one page of a workspace's audit entries, newest first by default.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *AuditService) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.AuditFilter, page *models.PageRequest) ([]*models.AuditEntry, string, error) {
+	p, err := auditPageSpec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, err := s.audit.List(ctx, workspaceID, filter, q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, more := splitPage(entries, p.limit)
+	if !more {
+		return entries, "", nil
+	}
+	last := entries[len(entries)-1]
+	return entries, p.cursorAfter(last.CreatedAt, last.ID), nil
+}
+
+// Export writes every entry matching filter to w, oldest first, as CSV or JSON Lines
+func (s *AuditService) Export(ctx context.Context, workspaceID primitive.ObjectID, filter *models.AuditFilter, format string, w io.Writer) error {
+	var write func(*models.AuditEntry) error
+	var flush func() error
+
+	switch format {
+	case AuditExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(auditCSVHeader); err != nil {
+			return err
+		}
+		write = func(e *models.AuditEntry) error { return cw.Write(auditCSVRecord(e)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case AuditExportJSONL:
+		enc := json.NewEncoder(w)
+		write = func(e *models.AuditEntry) error { return enc.Encode(e) }
+		flush = func() error { return nil }
+	default:
+		return ErrInvalidExportFormat
+	}
+
+	page := &repository.PageQuery{Sort: models.SortByCreated, Order: models.SortAsc, Limit: models.MaxPageSize}
+	for {
+		entries, err := s.audit.List(ctx, workspaceID, filter, page)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := write(e); err != nil {
+				return err
+			}
+		}
+		if int64(len(entries)) < page.Limit {
+			break
+		}
+		last := entries[len(entries)-1]
+		page.After = &repository.Keyset{Value: last.CreatedAt, ID: last.ID}
+	}
+	return flush()
+}
+
+var auditCSVHeader = []string{
+	"id", "created_at", "action", "actor_id", "actor_email", "ip", "user_agent",
+	"target_type", "target_id", "before", "after",
+}
+
+// auditCSVRecord flattens an entry into a CSV row; before and after values are JSON objects
+func auditCSVRecord(e *models.AuditEntry) []string {
+	actorID := ""
+	if e.ActorID != nil {
+		actorID = e.ActorID.Hex()
+	}
+	return []string{
+		e.ID.Hex(), e.CreatedAt.UTC().Format(time.RFC3339Nano), string(e.Action), actorID, e.ActorEmail, e.IP, e.UserAgent,
+		string(e.TargetType), e.TargetID.Hex(), auditValuesJSON(e.Before), auditValuesJSON(e.After),
+	}
+}
+
+func auditValuesJSON(values map[string]string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
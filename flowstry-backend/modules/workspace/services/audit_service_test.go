@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+)
+
+// auditLog returns a workspace's audit entries, oldest first
+func (tw *testWorkspace) auditLog(t *testing.T, filter *models.AuditFilter) []*models.AuditEntry {
+	t.Helper()
+	var entries []*models.AuditEntry
+	page := &models.PageRequest{Order: models.SortAsc}
+	for {
+		got, next, err := tw.audit.List(context.Background(), tw.id, filter, page)
+		if err != nil {
+			t.Fatalf("list audit log: %v", err)
+		}
+		entries = append(entries, got...)
+		if next == "" {
+			return entries
+		}
+		page.Cursor = next
+	}
+}
+
+func TestEventMemberChanges(t *testing.T) {
+	tw := newTestWorkspace(t)
+	actor := &models.Actor{UserID: tw.owner.ID, Email: tw.owner.Email, IP: "203.0.113.7", UserAgent: "test"}
+	ctx := WithActor(context.Background(), actor)
+
+	if err := tw.members.UpdateRole(ctx, tw.id, tw.editor.ID, models.RoleViewer, tw.owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.members.RemoveMember(ctx, tw.id, tw.viewer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := tw.auditLog(t, &models.AuditFilter{
+		Actions: []models.EventType{models.EventMemberRoleChanged, models.EventMemberRemoved},
+	})
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	changed, removed := entries[0], entries[1]
+	if changed.TargetID != tw.editor.ID || changed.Before["role"] != string(models.RoleEditor) || changed.After["role"] != string(models.RoleViewer) {
+		t.Errorf("role change entry = %+v", changed)
+	}
+	if removed.TargetID != tw.viewer.ID || removed.Before["role"] != string(models.RoleViewer) || removed.After != nil {
+		t.Errorf("removal entry = %+v", removed)
+	}
+	for _, e := range entries {
+		if e.ActorID == nil || *e.ActorID != actor.UserID || e.ActorEmail != actor.Email || e.IP != actor.IP || e.UserAgent != actor.UserAgent {
+			t.Errorf("%s: actor = %v %q %q %q, want the request's", e.Action, e.ActorID, e.ActorEmail, e.IP, e.UserAgent)
+		}
+	}
+}
+
+func TestAuditFailedChangeNotRecorded(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	before := len(tw.auditLog(t, nil))
+	if err := tw.members.UpdateRole(ctx, tw.id, tw.editor.ID, models.RoleAdmin, tw.admin.ID); !errors.Is(err, ErrInsufficientRole) {
+		t.Fatalf("UpdateRole err = %v, want %v", err, ErrInsufficientRole)
+	}
+	if after := len(tw.auditLog(t, nil)); after != before {
+		t.Errorf("rejected change added %d entries", after-before)
+	}
+}
+
+func TestAuditExport(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := tw.audit.Export(ctx, tw.id, nil, AuditExportCSV, &buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	// Header, workspace creation and the three fixture members
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5", len(rows))
+	}
+	if rows[1][2] != string(models.EventWorkspaceCreated) {
+		t.Errorf("first entry action = %q, want %q", rows[1][2], models.EventWorkspaceCreated)
+	}
+
+	if err := tw.audit.Export(ctx, tw.id, nil, "xml", &buf); !errors.Is(err, ErrInvalidExportFormat) {
+		t.Errorf("Export(xml) err = %v, want %v", err, ErrInvalidExportFormat)
+	}
+}
@@ -0,0 +1,499 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrInvalidComment   = errors.New("comment must be between 1 and 10000 characters")
+	ErrInvalidAnchor    = errors.New("invalid comment anchor")
+	ErrNotCommentThread = errors.New("only a thread can be resolved or reopened")
+	ErrNotCommentAuthor = errors.New("only the author can change a comment")
+)
+
+const (
+	// maxCommentLength caps the characters of a comment body
+	maxCommentLength = 10000
+	// maxShapeIDLength caps the length of an anchor's shape ID
+	maxShapeIDLength = 128
+	// reanchorTimeout bounds a single background re-anchoring run
+	reanchorTimeout = 2 * time.Minute
+)
+
+// CommentService manages comment threads on diagrams and keeps their anchors in step with the saved content
+type CommentService struct {
+	comments       repository.CommentRepository
+	users          repository.UserRepository
+	diagramService *DiagramService
+	contentService *ContentService
+	mentionService *MentionService
+	reanchorSlots  chan struct{}
+	events         *EventBus
+}
+
+// NewCommentService creates a new comment service
+func NewCommentService(comments repository.CommentRepository, users repository.UserRepository, diagramService *DiagramService, contentService *ContentService) *CommentService {
+	return &CommentService{
+		comments:       comments,
+		users:          users,
+		diagramService: diagramService,
+		contentService: contentService,
+		reanchorSlots:  make(chan struct{}, maxConcurrentIndexing),
+	}
+}
+
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *CommentService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// SetMentionService sets the service that records the mentions in comments (for dependency injection)
+func (s *CommentService) SetMentionService(ms *MentionService) {
+	s.mentionService = ms
+}
+
+// emit publishes a change to a comment
+func (s *CommentService) emit(ctx context.Context, comment *models.Comment, eventType models.EventType, before, after map[string]string) {
+	if s.events == nil {
+		return
+	}
+	if after == nil {
+		after = map[string]string{}
+	}
+	after["diagram_id"] = comment.DiagramID.Hex()
+	if comment.ThreadID != nil {
+		after["thread_id"] = comment.ThreadID.Hex()
+	}
+	s.events.Emit(ctx, comment.WorkspaceID, eventType, models.TargetComment, comment.ID, before, after)
+}
+
+// normalizeBody trims a comment body and checks its length
+func normalizeBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > maxCommentLength {
+		return "", ErrInvalidComment
+	}
+	return body, nil
+}
+
+// normalizeAnchor trims an anchor's shape ID and checks its coordinates are finite
+func normalizeAnchor(anchor *models.CommentAnchor) (*models.CommentAnchor, error) {
+	if anchor == nil {
+		return nil, nil
+	}
+	a := *anchor
+	a.ShapeID = strings.TrimSpace(a.ShapeID)
+	if len(a.ShapeID) > maxShapeIDLength ||
+		math.IsNaN(a.X) || math.IsInf(a.X, 0) || math.IsNaN(a.Y) || math.IsInf(a.Y, 0) {
+		return nil, ErrInvalidAnchor
+	}
+	return &a, nil
+}
+
+// Create starts a thread on a live diagram
+func (s *CommentService) Create(ctx context.Context, workspaceID, diagramID, authorID primitive.ObjectID, req *models.CreateCommentRequest) (*models.CommentThread, error) {
+	body, err := normalizeBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	anchor, err := normalizeAnchor(req.Anchor)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.diagramService.GetByID(ctx, diagramID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	comment := s.newComment(ctx, workspaceID, diagramID, authorID, body)
+	comment.Anchor = anchor
+	if err := s.comments.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	after := map[string]string{}
+	if anchor != nil && anchor.ShapeID != "" {
+		after["shape_id"] = anchor.ShapeID
+	}
+	s.emit(ctx, comment, models.EventCommentCreated, nil, after)
+	s.recordMentions(ctx, comment)
+	return &models.CommentThread{Comment: comment, Replies: []*models.Comment{}}, nil
+}
+
+// Reply adds a reply to a thread; replying to a reply answers its thread
+func (s *CommentService) Reply(ctx context.Context, workspaceID, diagramID, commentID, authorID primitive.ObjectID, req *models.CommentBodyRequest) (*models.Comment, error) {
+	body, err := normalizeBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.diagramService.GetByID(ctx, diagramID, workspaceID); err != nil {
+		return nil, err
+	}
+	target, err := s.get(ctx, workspaceID, diagramID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	threadID := target.ID
+	if !target.IsThread() {
+		threadID = *target.ThreadID
+	}
+
+	reply := s.newComment(ctx, workspaceID, diagramID, authorID, body)
+	reply.ThreadID = &threadID
+	if err := s.comments.Create(ctx, reply); err != nil {
+		return nil, err
+	}
+	// A reply is activity on its thread
+	if err := s.comments.Update(ctx, workspaceID, diagramID, threadID, &repository.CommentUpdate{UpdatedAt: reply.CreatedAt}); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	s.emit(ctx, reply, models.EventCommentCreated, nil, nil)
+	s.recordMentions(ctx, reply)
+	return reply, nil
+}
+
+func (s *CommentService) newComment(ctx context.Context, workspaceID, diagramID, authorID primitive.ObjectID, body string) *models.Comment {
+	now := time.Now()
+	return &models.Comment{
+		WorkspaceID: workspaceID,
+		DiagramID:   diagramID,
+		AuthorID:    authorID,
+		AuthorName:  s.authorName(ctx, authorID),
+		Body:        body,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// authorName returns a user's display name, falling back to their email
+func (s *CommentService) authorName(ctx context.Context, userID primitive.ObjectID) string {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.Email
+}
+
+func (s *CommentService) get(ctx context.Context, workspaceID, diagramID, commentID primitive.ObjectID) (*models.Comment, error) {
+	comment, err := s.comments.Get(ctx, workspaceID, diagramID, commentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+// GetThread returns a thread with its replies
+func (s *CommentService) GetThread(ctx context.Context, workspaceID, diagramID, threadID primitive.ObjectID) (*models.CommentThread, error) {
+	comment, err := s.get(ctx, workspaceID, diagramID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if !comment.IsThread() {
+		return nil, ErrCommentNotFound
+	}
+
+	threads, err := s.withReplies(ctx, workspaceID, diagramID, []*models.Comment{comment})
+	if err != nil {
+		return nil, err
+	}
+	return threads[0], nil
+}
+
+// commentPageSpec lists the sort options for thread listings
+var commentPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByCreated, models.SortByUpdated},
+	defaultSort:  models.SortByCreated,
+	defaultOrder: models.SortAsc,
+}
+
+// ListThreads lists one page of a diagram's threads with their replies, oldest first by default.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *CommentService) ListThreads(ctx context.Context, workspaceID, diagramID primitive.ObjectID, filter *models.CommentFilter, page *models.PageRequest) ([]*models.CommentThread, string, error) {
+	p, err := commentPageSpec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
+	}
+
+	roots, err := s.comments.ListThreads(ctx, workspaceID, diagramID, filter, q)
+	if err != nil {
+		return nil, "", err
+	}
+	roots, more := splitPage(roots, p.limit)
+
+	threads, err := s.withReplies(ctx, workspaceID, diagramID, roots)
+	if err != nil {
+		return nil, "", err
+	}
+	if !more {
+		return threads, "", nil
+	}
+	last := roots[len(roots)-1]
+	if p.sort == models.SortByUpdated {
+		return threads, p.cursorAfter(last.UpdatedAt, last.ID), nil
+	}
+	return threads, p.cursorAfter(last.CreatedAt, last.ID), nil
+}
+
+// withReplies attaches their replies to thread roots
+func (s *CommentService) withReplies(ctx context.Context, workspaceID, diagramID primitive.ObjectID, roots []*models.Comment) ([]*models.CommentThread, error) {
+	threads := make([]*models.CommentThread, len(roots))
+	if len(roots) == 0 {
+		return threads, nil
+	}
+
+	byID := make(map[primitive.ObjectID]*models.CommentThread, len(roots))
+	ids := make([]primitive.ObjectID, len(roots))
+	for i, root := range roots {
+		threads[i] = &models.CommentThread{Comment: root, Replies: []*models.Comment{}}
+		byID[root.ID] = threads[i]
+		ids[i] = root.ID
+	}
+
+	replies, err := s.comments.ListReplies(ctx, workspaceID, diagramID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if thread, ok := byID[*reply.ThreadID]; ok {
+			thread.Replies = append(thread.Replies, reply)
+		}
+	}
+	return threads, nil
+}
+
+// Edit changes the body of a comment; only its author may
+func (s *CommentService) Edit(ctx context.Context, workspaceID, diagramID, commentID, userID primitive.ObjectID, req *models.CommentBodyRequest) (*models.Comment, error) {
+	body, err := normalizeBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.get(ctx, workspaceID, diagramID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, ErrNotCommentAuthor
+	}
+	if comment.Body == body {
+		return comment, nil
+	}
+
+	now := time.Now()
+	if err := s.comments.Update(ctx, workspaceID, diagramID, commentID, &repository.CommentUpdate{Body: &body, EditedAt: &now, UpdatedAt: now}); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+
+	s.emit(ctx, comment, models.EventCommentUpdated, nil, nil)
+	comment.Body = body
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+	s.recordMentions(ctx, comment)
+	return comment, nil
+}
+
+// recordMentions updates who a comment mentions and reports them on it; the comment is saved either way
+func (s *CommentService) recordMentions(ctx context.Context, comment *models.Comment) {
+	if s.mentionService == nil {
+		return
+	}
+	result, err := s.mentionService.Record(ctx, comment.WorkspaceID, comment.DiagramID, models.MentionInComment, comment.ID, comment.Body)
+	if err != nil {
+		fmt.Printf("Warning: Failed to record mentions of comment %s: %v\n", comment.ID.Hex(), err)
+		return
+	}
+	comment.Mentions = result
+}
+
+// Delete removes a comment, and a thread with all its replies. Only the author may, unless moderate is set
+// (workspace admins clean up after others).
+func (s *CommentService) Delete(ctx context.Context, workspaceID, diagramID, commentID, userID primitive.ObjectID, moderate bool) error {
+	comment, err := s.get(ctx, workspaceID, diagramID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID && !moderate {
+		return ErrNotCommentAuthor
+	}
+
+	deleted := []primitive.ObjectID{comment.ID}
+	if comment.IsThread() {
+		replies, err := s.comments.ListReplies(ctx, workspaceID, diagramID, deleted)
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			deleted = append(deleted, reply.ID)
+		}
+	}
+
+	if err := s.comments.Delete(ctx, workspaceID, diagramID, commentID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrCommentNotFound
+		}
+		return err
+	}
+	if s.mentionService != nil {
+		_ = s.mentionService.DeleteForComments(ctx, workspaceID, deleted)
+	}
+	s.emit(ctx, comment, models.EventCommentDeleted, nil, nil)
+	return nil
+}
+
+// Resolve marks a thread resolved; resolving a resolved thread changes nothing
+func (s *CommentService) Resolve(ctx context.Context, workspaceID, diagramID, threadID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	return s.setResolved(ctx, workspaceID, diagramID, threadID, userID, true)
+}
+
+// Reopen clears the resolution of a thread
+func (s *CommentService) Reopen(ctx context.Context, workspaceID, diagramID, threadID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	return s.setResolved(ctx, workspaceID, diagramID, threadID, userID, false)
+}
+
+func (s *CommentService) setResolved(ctx context.Context, workspaceID, diagramID, threadID, userID primitive.ObjectID, resolved bool) (*models.CommentThread, error) {
+	thread, err := s.get(ctx, workspaceID, diagramID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if !thread.IsThread() {
+		return nil, ErrNotCommentThread
+	}
+
+	if (thread.ResolvedAt != nil) != resolved {
+		update := &repository.CommentUpdate{Resolved: &resolved, ResolvedBy: userID, UpdatedAt: time.Now()}
+		if err := s.comments.Update(ctx, workspaceID, diagramID, threadID, update); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrCommentNotFound
+			}
+			return nil, err
+		}
+		if resolved {
+			s.emit(ctx, thread, models.EventCommentResolved, nil, nil)
+		} else {
+			s.emit(ctx, thread, models.EventCommentReopened, nil, nil)
+		}
+	}
+	return s.GetThread(ctx, workspaceID, diagramID, threadID)
+}
+
+// ScheduleReanchor updates the shape anchors of a diagram's threads in the background after a save
+func (s *CommentService) ScheduleReanchor(diagram *models.Diagram) {
+	if diagram == nil {
+		return
+	}
+	d := *diagram
+
+	go func() {
+		s.reanchorSlots <- struct{}{}
+		defer func() { <-s.reanchorSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), reanchorTimeout)
+		defer cancel()
+
+		if err := s.Reanchor(ctx, &d); err != nil {
+			fmt.Printf("Warning: Failed to re-anchor comments of diagram %s: %v\n", d.ID.Hex(), err)
+		}
+	}()
+}
+
+// Reanchor moves the threads anchored to a shape along with it. Threads whose shape is gone from
+// the saved revision fall back to the shape's last known coordinates. Threads started after the
+// revision was saved are left alone, since their shape may not have been saved yet.
+func (s *CommentService) Reanchor(ctx context.Context, diagram *models.Diagram) error {
+	threads, err := s.comments.ListShapeAnchored(ctx, diagram.WorkspaceID, diagram.ID)
+	if err != nil || len(threads) == 0 {
+		return err
+	}
+
+	content, err := s.contentService.Read(ctx, diagram)
+	if err != nil {
+		if errors.Is(err, ErrNoDiagramContent) {
+			return nil
+		}
+		return err
+	}
+	positions, err := shapePositions(content)
+	if err != nil {
+		return err
+	}
+
+	for _, thread := range threads {
+		anchor := *thread.Anchor
+		if pos, ok := positions[anchor.ShapeID]; ok {
+			if pos.X == anchor.X && pos.Y == anchor.Y {
+				continue
+			}
+			anchor.X, anchor.Y = pos.X, pos.Y
+		} else if thread.CreatedAt.Before(diagram.UpdatedAt) {
+			anchor.ShapeID = ""
+		} else {
+			continue
+		}
+
+		if err := s.comments.SetAnchor(ctx, diagram.WorkspaceID, diagram.ID, thread.ID, anchor); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// shapePosition is where a shape sits on the canvas
+type shapePosition struct {
+	X, Y float64
+}
+
+// shapePositions maps the shape IDs of a diagram document to their positions
+func shapePositions(document []byte) (map[string]shapePosition, error) {
+	var doc struct {
+		Shapes []struct {
+			ID     string `json:"id"`
+			Layout struct {
+				X float64 `json:"x"`
+				Y float64 `json:"y"`
+			} `json:"layout"`
+		} `json:"shapes"`
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]shapePosition, len(doc.Shapes))
+	for _, shape := range doc.Shapes {
+		positions[shape.ID] = shapePosition{X: shape.Layout.X, Y: shape.Layout.Y}
+	}
+	return positions, nil
+}
+
+// DeleteForDiagram removes all comments of a diagram (used on permanent deletion)
+func (s *CommentService) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return s.comments.DeleteForDiagram(ctx, workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes all comments of a workspace (used when deleting workspace)
+func (s *CommentService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.comments.DeleteAllForWorkspace(ctx, workspaceID)
+}
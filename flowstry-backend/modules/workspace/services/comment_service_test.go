@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/storage"
+)
+
+func newTestComments(t *testing.T) (*testWorkspace, *CommentService, *DiagramService) {
+	t.Helper()
+	tw := newTestWorkspace(t)
+
+	dir := t.TempDir()
+	fileStorage, err := storage.NewLocalStorage(filepath.Join(dir, "files"), "http://localhost", []byte("signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryption, err := NewEncryptionService(filepath.Join(dir, "master.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diagrams := NewDiagramService(tw.store.Diagrams, fileStorage, NewFolderService(tw.store.Folders, tw.store.Diagrams))
+	content := NewContentService(tw.store.Workspaces, fileStorage, encryption)
+	comments := NewCommentService(tw.store.Comments, tw.store.Users, diagrams, content)
+	diagrams.SetCommentService(comments)
+	return tw, comments, diagrams
+}
+
+func TestCommentThreads(t *testing.T) {
+	tw, comments, _ := newTestComments(t)
+	ctx := context.Background()
+	diagram := tw.diagramBy(t, tw.editor.ID, "Checkout")
+
+	thread, err := comments.Create(ctx, tw.id, diagram.ID, tw.editor.ID, &models.CreateCommentRequest{
+		Body:   "  Should this retry?  ",
+		Anchor: &models.CommentAnchor{ShapeID: "s1", X: 10, Y: 20},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thread.Body != "Should this retry?" || thread.AuthorName != "editor@example.com" {
+		t.Fatalf("thread = %+v", thread.Comment)
+	}
+
+	// Replying to a reply answers the thread
+	reply, err := comments.Reply(ctx, tw.id, diagram.ID, thread.ID, tw.admin.ID, &models.CommentBodyRequest{Body: "Yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := comments.Reply(ctx, tw.id, diagram.ID, reply.ID, tw.editor.ID, &models.CommentBodyRequest{Body: "Done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *nested.ThreadID != thread.ID {
+		t.Errorf("nested reply thread = %s, want %s", nested.ThreadID.Hex(), thread.ID.Hex())
+	}
+
+	if _, err := comments.Create(ctx, tw.id, diagram.ID, tw.editor.ID, &models.CreateCommentRequest{Body: " "}); !errors.Is(err, ErrInvalidComment) {
+		t.Errorf("empty body err = %v", err)
+	}
+	if _, err := comments.Edit(ctx, tw.id, diagram.ID, reply.ID, tw.editor.ID, &models.CommentBodyRequest{Body: "No"}); !errors.Is(err, ErrNotCommentAuthor) {
+		t.Errorf("edit by non-author err = %v", err)
+	}
+	edited, err := comments.Edit(ctx, tw.id, diagram.ID, reply.ID, tw.admin.ID, &models.CommentBodyRequest{Body: "Yes, twice"})
+	if err != nil || edited.EditedAt == nil {
+		t.Fatalf("edit = %+v, %v", edited, err)
+	}
+
+	if _, err := comments.Resolve(ctx, tw.id, diagram.ID, reply.ID, tw.editor.ID); !errors.Is(err, ErrNotCommentThread) {
+		t.Errorf("resolve reply err = %v", err)
+	}
+	resolved, err := comments.Resolve(ctx, tw.id, diagram.ID, thread.ID, tw.editor.ID)
+	if err != nil || resolved.ResolvedAt == nil || *resolved.ResolvedBy != tw.editor.ID || len(resolved.Replies) != 2 {
+		t.Fatalf("resolve = %+v, %v", resolved, err)
+	}
+
+	open := false
+	threads, _, err := comments.ListThreads(ctx, tw.id, diagram.ID, &models.CommentFilter{Resolved: &open}, nil)
+	if err != nil || len(threads) != 0 {
+		t.Fatalf("open threads = %d, %v", len(threads), err)
+	}
+	if _, err := comments.Reopen(ctx, tw.id, diagram.ID, thread.ID, tw.viewer.ID); err != nil {
+		t.Fatal(err)
+	}
+	threads, _, err = comments.ListThreads(ctx, tw.id, diagram.ID, &models.CommentFilter{Resolved: &open}, nil)
+	if err != nil || len(threads) != 1 || threads[0].Replies[1].ID != nested.ID {
+		t.Fatalf("reopened threads = %+v, %v", threads, err)
+	}
+
+	// Only the author deletes their comment unless moderating; a thread goes with its replies
+	if err := comments.Delete(ctx, tw.id, diagram.ID, thread.ID, tw.admin.ID, false); !errors.Is(err, ErrNotCommentAuthor) {
+		t.Errorf("delete by non-author err = %v", err)
+	}
+	if err := comments.Delete(ctx, tw.id, diagram.ID, thread.ID, tw.admin.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.GetThread(ctx, tw.id, diagram.ID, thread.ID); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("deleted thread err = %v", err)
+	}
+	if _, err := comments.Reply(ctx, tw.id, diagram.ID, nested.ID, tw.editor.ID, &models.CommentBodyRequest{Body: "?"}); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("reply to deleted thread err = %v", err)
+	}
+}
+
+func TestCommentReanchor(t *testing.T) {
+	tw, comments, diagrams := newTestComments(t)
+	ctx := context.Background()
+	diagram := tw.diagramBy(t, tw.editor.ID, "Checkout")
+
+	create := func(shapeID string) *models.CommentThread {
+		t.Helper()
+		thread, err := comments.Create(ctx, tw.id, diagram.ID, tw.editor.ID, &models.CreateCommentRequest{
+			Body:   "Look here",
+			Anchor: &models.CommentAnchor{ShapeID: shapeID, X: 10, Y: 20},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return thread
+	}
+	moved := create("moved")
+	deleted := create("deleted")
+
+	saved, err := diagrams.UpdateFile(ctx, tw.editor.ID, diagram.ID, tw.id,
+		[]byte(`{"shapes":[{"id":"moved","layout":{"x":150,"y":75}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Threads started after the save may point at shapes that are not saved yet
+	pending := create("pending")
+
+	if err := comments.Reanchor(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]models.CommentAnchor{
+		moved.ID.Hex():   {ShapeID: "moved", X: 150, Y: 75},
+		deleted.ID.Hex(): {X: 10, Y: 20},
+		pending.ID.Hex(): {ShapeID: "pending", X: 10, Y: 20},
+	}
+	threads, _, err := comments.ListThreads(ctx, tw.id, diagram.ID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, thread := range threads {
+		if *thread.Anchor != want[thread.ID.Hex()] {
+			t.Errorf("anchor of %s = %+v, want %+v", thread.ID.Hex(), *thread.Anchor, want[thread.ID.Hex()])
+		}
+	}
+
+	if err := diagrams.HardDelete(ctx, diagram.ID, tw.id); err != nil {
+		t.Fatal(err)
+	}
+	if threads, _, _ := comments.ListThreads(ctx, tw.id, diagram.ID, nil, nil); len(threads) != 0 {
+		t.Errorf("threads after diagram deletion = %d", len(threads))
+	}
+}
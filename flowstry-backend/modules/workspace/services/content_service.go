@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrWorkspaceNotEncrypted = errors.New("workspace is not encrypted")
+	ErrNoDiagramContent      = errors.New("no file associated with diagram")
+)
+
+// maxDiagramContentSize bounds how much decompressed diagram JSON the server will handle
+const maxDiagramContentSize = 64 * 1024 * 1024
+
+// ContentService reads and writes the encrypted diagram documents on behalf of the server.
+//
+// Stored files are produced by the frontend as gzip(JSON) -> AES-GCM with the workspace key
+// -> [IV][ciphertext]. Depending on the upload path the storage layer may wrap that in another
+// gzip layer, so both layers are detected by their magic bytes.
+type ContentService struct {
+	workspaces        repository.WorkspaceRepository
+	fileStorage       storage.Storage
+	encryptionService *EncryptionService
+}
+
+// NewContentService creates a new content service
+func NewContentService(workspaces repository.WorkspaceRepository, fileStorage storage.Storage, encryptionService *EncryptionService) *ContentService {
+	return &ContentService{
+		workspaces:        workspaces,
+		fileStorage:       fileStorage,
+		encryptionService: encryptionService,
+	}
+}
+
+// workspaceKey loads and decrypts a workspace key without any user access check.
+// Callers are responsible for authorization.
+func (s *ContentService) workspaceKey(ctx context.Context, workspaceID primitive.ObjectID) ([]byte, error) {
+	if s.encryptionService == nil {
+		return nil, errors.New("encryption service not configured")
+	}
+
+	workspace, err := s.workspaces.Get(ctx, workspaceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+
+	if len(workspace.EncryptedKey) == 0 {
+		return nil, ErrWorkspaceNotEncrypted
+	}
+
+	return s.encryptionService.DecryptWorkspaceKey(workspace.EncryptedKey)
+}
+
+// Read downloads a diagram file and returns its decrypted, decompressed JSON document
+func (s *ContentService) Read(ctx context.Context, diagram *models.Diagram) ([]byte, error) {
+	if s.fileStorage == nil {
+		return nil, errors.New("storage not configured")
+	}
+	if diagram.FileURL == "" {
+		return nil, ErrNoDiagramContent
+	}
+
+	key, err := s.workspaceKey(ctx, diagram.WorkspaceID)
+	if err != nil && !errors.Is(err, ErrWorkspaceNotEncrypted) {
+		return nil, err
+	}
+
+	data, err := s.fileStorage.DownloadFile(ctx, diagram.FileURL)
+	if err != nil {
+		// Diagrams created without content have a path but no object yet
+		if diagram.FileSize == 0 {
+			return nil, ErrNoDiagramContent
+		}
+		return nil, err
+	}
+
+	// Workspaces created before encryption was introduced store plain (gzipped) JSON
+	if key == nil {
+		if isGzip(data) {
+			return gunzip(data)
+		}
+		return data, nil
+	}
+
+	return s.Decode(key, data)
+}
+
+// Decode turns a stored diagram file into its JSON document using the given workspace key
+func (s *ContentService) Decode(workspaceKey, data []byte) ([]byte, error) {
+	// Outer gzip layer added by the storage upload path (if any)
+	if isGzip(data) {
+		if unwrapped, err := gunzip(data); err == nil {
+			data = unwrapped
+		}
+	}
+
+	plaintext, err := s.encryptionService.DecryptContent(workspaceKey, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt diagram: %w", err)
+	}
+
+	if isGzip(plaintext) {
+		return gunzip(plaintext)
+	}
+	return plaintext, nil
+}
+
+// Encode compresses and encrypts a JSON diagram document for the given workspace,
+// producing the same format the frontend uploads (unencrypted workspaces get plain gzip)
+func (s *ContentService) Encode(ctx context.Context, workspaceID primitive.ObjectID, document []byte) ([]byte, error) {
+	key, err := s.workspaceKey(ctx, workspaceID)
+	if err != nil && !errors.Is(err, ErrWorkspaceNotEncrypted) {
+		return nil, err
+	}
+	return s.EncodeWithKey(key, document)
+}
+
+// EncodeWithKey compresses a JSON diagram document and encrypts it with key, or only
+// compresses it when key is nil
+func (s *ContentService) EncodeWithKey(key, document []byte) ([]byte, error) {
+	if key != nil && s.encryptionService == nil {
+		return nil, errors.New("encryption service not configured")
+	}
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(document); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return buf.Bytes(), nil
+	}
+	return s.encryptionService.EncryptContent(key, buf.Bytes())
+}
+
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	out, err := io.ReadAll(io.LimitReader(reader, maxDiagramContentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDiagramContentSize {
+		return nil, errors.New("diagram content too large")
+	}
+	return out, nil
+}
@@ -4,15 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/flowstry/flowstry-backend/database"
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
 	"github.com/flowstry/flowstry-backend/storage"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -21,25 +20,83 @@ var (
 
 // DiagramService handles diagram operations
 type DiagramService struct {
-	gcsClient     *storage.GCSClient
-	folderService *FolderService
+	diagrams       repository.DiagramRepository
+	fileStorage    storage.Storage
+	folderService  *FolderService
+	shareService   *ShareService
+	embedService   *EmbedService
+	searchService  *SearchService
+	tagService     *TagService
+	accessService  *AccessService
+	starService    *StarService
+	commentService *CommentService
+	mentionService *MentionService
+	events         *EventBus
 }
 
 // NewDiagramService creates a new diagram service
-func NewDiagramService(gcsClient *storage.GCSClient, folderService *FolderService) *DiagramService {
+func NewDiagramService(diagrams repository.DiagramRepository, fileStorage storage.Storage, folderService *FolderService) *DiagramService {
 	return &DiagramService{
-		gcsClient:     gcsClient,
+		diagrams:      diagrams,
+		fileStorage:   fileStorage,
 		folderService: folderService,
 	}
 }
 
-// Create creates a new diagram with file upload
-func (s *DiagramService) Create(ctx context.Context, userID, workspaceID primitive.ObjectID, req *models.CreateDiagramRequest, fileData []byte) (*models.Diagram, error) {
-	collection := database.GetCollection("diagrams")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// SetShareService sets the share service (for dependency injection)
+func (s *DiagramService) SetShareService(ss *ShareService) {
+	s.shareService = ss
+}
+
+// SetEmbedService sets the embed service (for dependency injection)
+func (s *DiagramService) SetEmbedService(es *EmbedService) {
+	s.embedService = es
+}
+
+// SetSearchService sets the search service (for dependency injection)
+func (s *DiagramService) SetSearchService(ss *SearchService) {
+	s.searchService = ss
+}
+
+// SetTagService sets the tag service (for dependency injection)
+func (s *DiagramService) SetTagService(ts *TagService) {
+	s.tagService = ts
+}
+
+// SetAccessService sets the access service (for dependency injection)
+func (s *DiagramService) SetAccessService(as *AccessService) {
+	s.accessService = as
+}
+
+// SetStarService sets the star service (for dependency injection)
+func (s *DiagramService) SetStarService(ss *StarService) {
+	s.starService = ss
+}
+
+// SetCommentService sets the comment service (for dependency injection)
+func (s *DiagramService) SetCommentService(cs *CommentService) {
+	s.commentService = cs
+}
+
+// SetMentionService sets the mention service (for dependency injection)
+func (s *DiagramService) SetMentionService(ms *MentionService) {
+	s.mentionService = ms
+}
+
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *DiagramService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// emit publishes a change to a diagram
+func (s *DiagramService) emit(ctx context.Context, workspaceID, diagramID primitive.ObjectID, eventType models.EventType, before, after map[string]string) {
+	if s.events != nil {
+		s.events.Emit(ctx, workspaceID, eventType, models.TargetDiagram, diagramID, before, after)
 	}
+}
 
+// Create creates a new diagram with file upload
+func (s *DiagramService) Create(ctx context.Context, userID, workspaceID primitive.ObjectID, req *models.CreateDiagramRequest, fileData []byte) (*models.Diagram, error) {
 	var folderObjectID *primitive.ObjectID
 	if req.FolderID != "" {
 		parsedID, err := primitive.ObjectIDFromHex(req.FolderID)
@@ -63,8 +120,8 @@ func (s *DiagramService) Create(ctx context.Context, userID, workspaceID primiti
 	var fileSize int64
 	var err error
 
-	if s.gcsClient != nil && len(fileData) > 0 {
-		_, fileSize, err = s.gcsClient.UploadFile(ctx, objectName, fileData, "application/octet-stream")
+	if s.fileStorage != nil && len(fileData) > 0 {
+		_, fileSize, err = s.fileStorage.UploadFile(ctx, objectName, fileData, "application/octet-stream")
 		if err != nil {
 			return nil, fmt.Errorf("failed to upload file: %w", err)
 		}
@@ -79,98 +136,123 @@ func (s *DiagramService) Create(ctx context.Context, userID, workspaceID primiti
 		FileURL:     objectName,
 		FileSize:    fileSize,
 		Version:     1,
+		CreatedBy:   &userID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	_, err = collection.InsertOne(ctx, diagram)
+	err = s.diagrams.Create(ctx, diagram)
 	if err != nil {
 		// Clean up uploaded file on failure
-		if s.gcsClient != nil && fileURL != "" {
-			_ = s.gcsClient.DeleteFile(ctx, fileURL)
+		if s.fileStorage != nil && fileURL != "" {
+			_ = s.fileStorage.DeleteFile(ctx, fileURL)
 		}
 		return nil, err
 	}
 
+	if s.searchService != nil && fileSize > 0 {
+		s.searchService.ScheduleIndex(diagram)
+	}
+
+	s.emit(ctx, workspaceID, diagram.ID, models.EventDiagramCreated, nil, map[string]string{
+		"name":      diagram.Name,
+		"folder_id": eventID(diagram.FolderID),
+	})
+	if diagram.Description != "" {
+		s.recordDescriptionMentions(ctx, diagram)
+	}
+	if s.mentionService != nil && fileSize > 0 {
+		s.mentionService.ScheduleContentScan(ctx, diagram)
+	}
 	return diagram, nil
 }
 
-// GetByID retrieves a diagram by ID
-func (s *DiagramService) GetByID(ctx context.Context, diagramID, workspaceID primitive.ObjectID) (*models.Diagram, error) {
-	collection := database.GetCollection("diagrams")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// recordDescriptionMentions updates who a diagram's description mentions; the diagram is saved either way
+func (s *DiagramService) recordDescriptionMentions(ctx context.Context, diagram *models.Diagram) {
+	if s.mentionService == nil {
+		return
 	}
-
-	var diagram models.Diagram
-	err := collection.FindOne(ctx, bson.M{
-		"_id":          diagramID,
-		"workspace_id": workspaceID,
-		"deleted_at":   nil,
-	}).Decode(&diagram)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrDiagramNotFound
-		}
-		return nil, err
+	if _, err := s.mentionService.Record(ctx, diagram.WorkspaceID, diagram.ID, models.MentionInDescription, diagram.ID, diagram.Description); err != nil {
+		fmt.Printf("Warning: Failed to record mentions of diagram %s: %v\n", diagram.ID.Hex(), err)
 	}
-
-	return &diagram, nil
 }
 
-// List retrieves all diagrams in a workspace
-func (s *DiagramService) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error) {
-	collection := database.GetCollection("diagrams")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// GetByID retrieves a diagram by ID
+func (s *DiagramService) GetByID(ctx context.Context, diagramID, workspaceID primitive.ObjectID) (*models.Diagram, error) {
+	diagram, err := s.diagrams.Get(ctx, workspaceID, diagramID, repository.Live)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrDiagramNotFound
 	}
+	return diagram, err
+}
 
-	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	cursor, err := collection.Find(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"deleted_at":   nil,
-	}, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+// diagramPageSpec lists the sort options for diagram listings
+var diagramPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByName, models.SortByCreated, models.SortByUpdated, models.SortBySize},
+	defaultSort:  models.SortByUpdated,
+	defaultOrder: models.SortDesc,
+	unbounded:    true,
+}
 
-	var diagrams []*models.Diagram
-	if err := cursor.All(ctx, &diagrams); err != nil {
-		return nil, err
-	}
+// diagramTrashPageSpec lists the sort options for the diagram trash
+var diagramTrashPageSpec = pageSpec{
+	fields: []models.SortField{
+		models.SortByName, models.SortByCreated, models.SortByUpdated, models.SortBySize, models.SortByDeleted,
+	},
+	defaultSort:  models.SortByDeleted,
+	defaultOrder: models.SortDesc,
+	unbounded:    true,
+}
 
-	return diagrams, nil
+// List retrieves one page of diagrams in a workspace, optionally filtered.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *DiagramService) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.DiagramFilter, page *models.PageRequest) ([]*models.Diagram, string, error) {
+	return s.findPage(ctx, workspaceID, &repository.DiagramQuery{Trash: repository.Live, Filter: filter}, diagramPageSpec, page)
 }
 
-// ListRecent retrieves recent diagrams across multiple workspaces
-func (s *DiagramService) ListRecent(ctx context.Context, workspaceIDs []primitive.ObjectID, limit int64) ([]*models.Diagram, error) {
-	collection := database.GetCollection("diagrams")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// findPage runs a paginated diagrams query
+func (s *DiagramService) findPage(ctx context.Context, workspaceID primitive.ObjectID, query *repository.DiagramQuery, spec pageSpec, page *models.PageRequest) ([]*models.Diagram, string, error) {
+	p, err := spec.resolve(page)
+	if err != nil {
+		return nil, "", err
 	}
-	if len(workspaceIDs) == 0 || limit <= 0 {
-		return []*models.Diagram{}, nil
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
 	}
 
-	opts := options.Find().
-		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
-		SetLimit(limit)
-	cursor, err := collection.Find(ctx, bson.M{
-		"workspace_id": bson.M{"$in": workspaceIDs},
-		"deleted_at":   nil,
-	}, opts)
+	diagrams, err := s.diagrams.List(ctx, workspaceID, query, q)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cursor.Close(ctx)
 
-	var diagrams []*models.Diagram
-	if err := cursor.All(ctx, &diagrams); err != nil {
-		return nil, err
+	diagrams, more := splitPage(diagrams, p.limit)
+	if !more {
+		return diagrams, "", nil
 	}
+	last := diagrams[len(diagrams)-1]
+	return diagrams, p.cursorAfter(diagramSortValue(last, p.sort), last.ID), nil
+}
 
-	return diagrams, nil
+// diagramSortValue returns the value a diagram is ordered by for a sort field
+func diagramSortValue(d *models.Diagram, field models.SortField) interface{} {
+	switch field {
+	case models.SortByName:
+		return d.Name
+	case models.SortByCreated:
+		return d.CreatedAt
+	case models.SortBySize:
+		return d.FileSize
+	case models.SortByDeleted:
+		return d.DeletedAt
+	default:
+		return d.UpdatedAt
+	}
+}
+
+// ListByIDs retrieves the live diagrams among diagramIDs that belong to one of workspaceIDs, unordered
+func (s *DiagramService) ListByIDs(ctx context.Context, workspaceIDs, diagramIDs []primitive.ObjectID, filter *models.DiagramFilter) ([]*models.Diagram, error) {
+	return s.diagrams.ListByIDs(ctx, workspaceIDs, diagramIDs, filter)
 }
 
 // Update updates diagram metadata
@@ -180,37 +262,65 @@ func (s *DiagramService) Update(ctx context.Context, diagramID, workspaceID prim
 		return nil, err
 	}
 
-	collection := database.GetCollection("diagrams")
-
-	update := bson.M{"updated_at": time.Now()}
+	update := &repository.DiagramUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Thumbnail:   req.Thumbnail,
+		Tags:        req.Tags,
+		UpdatedAt:   time.Now(),
+	}
+	before, after := map[string]string{}, map[string]string{}
 	if req.Name != nil {
-		update["name"] = *req.Name
+		changed(before, after, "name", diagram.Name, *req.Name)
 		diagram.Name = *req.Name
 	}
 	if req.Description != nil {
-		update["description"] = *req.Description
+		changed(before, after, "description", diagram.Description, *req.Description)
 		diagram.Description = *req.Description
 	}
 	if req.Thumbnail != nil {
-		update["thumbnail"] = *req.Thumbnail
 		diagram.Thumbnail = *req.Thumbnail
 	}
+	if req.Tags != nil {
+		changed(before, after, "tags", strings.Join(diagram.Tags, ","), strings.Join(*req.Tags, ","))
+		diagram.Tags = *req.Tags
+		if s.tagService != nil {
+			if err := s.tagService.EnsureDefined(ctx, workspaceID, *req.Tags); err != nil {
+				return nil, err
+			}
+		}
+	}
 	if req.FileURL != nil {
-		update["file_url"] = *req.FileURL
+		// Content uploaded through a signed URL is a new revision
 		diagram.FileURL = *req.FileURL
+		diagram.Version++
+		changed(before, after, "version", strconv.Itoa(diagram.Version-1), strconv.Itoa(diagram.Version))
+		update.FileURL = req.FileURL
+		update.Version = &diagram.Version
+		if s.searchService != nil {
+			s.searchService.ScheduleIndex(diagram)
+		}
 	}
 
-
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": diagramID},
-		bson.M{"$set": update},
-	)
-	if err != nil {
+	if err := s.diagrams.Update(ctx, workspaceID, diagramID, update); err != nil {
 		return nil, err
 	}
 
-	diagram.UpdatedAt = time.Now()
+	if len(after) > 0 {
+		s.emit(ctx, workspaceID, diagramID, models.EventDiagramUpdated, before, after)
+	}
+	diagram.UpdatedAt = update.UpdatedAt
+	if _, ok := after["description"]; ok {
+		s.recordDescriptionMentions(ctx, diagram)
+	}
+	if req.FileURL != nil {
+		if s.commentService != nil {
+			s.commentService.ScheduleReanchor(diagram)
+		}
+		if s.mentionService != nil {
+			s.mentionService.ScheduleContentScan(ctx, diagram)
+		}
+	}
 	return diagram, nil
 }
 
@@ -221,39 +331,47 @@ func (s *DiagramService) UpdateFile(ctx context.Context, userID, diagramID, work
 		return nil, err
 	}
 
-	if s.gcsClient == nil {
+	if s.fileStorage == nil {
 		return nil, errors.New("storage not configured")
 	}
 
 	// Upload new file: diagrams/{userID}/{workspaceID}/{diagramID}/diagram.flowstry
 	objectName := fmt.Sprintf("diagrams/%s/%s/%s/diagram.flowstry", userID.Hex(), workspaceID.Hex(), diagramID.Hex())
-	fileURL, fileSize, err := s.gcsClient.UploadFile(ctx, objectName, fileData, "application/octet-stream")
+	fileURL, fileSize, err := s.fileStorage.UploadFile(ctx, objectName, fileData, "application/octet-stream")
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
 
-	collection := database.GetCollection("diagrams")
-
-	update := bson.M{
-		"file_url":   fileURL,
-		"file_size":  fileSize,
-		"version":    diagram.Version + 1,
-		"updated_at": time.Now(),
+	version := diagram.Version + 1
+	update := &repository.DiagramUpdate{
+		FileURL:   &fileURL,
+		FileSize:  &fileSize,
+		Version:   &version,
+		UpdatedAt: time.Now(),
 	}
 
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": diagramID},
-		bson.M{"$set": update},
-	)
-	if err != nil {
+	if err := s.diagrams.Update(ctx, workspaceID, diagramID, update); err != nil {
 		return nil, err
 	}
 
+	s.emit(ctx, workspaceID, diagramID, models.EventDiagramUpdated,
+		map[string]string{"version": strconv.Itoa(diagram.Version)},
+		map[string]string{"version": strconv.Itoa(version)})
+
 	diagram.FileURL = fileURL
 	diagram.FileSize = fileSize
-	diagram.Version++
-	diagram.UpdatedAt = time.Now()
+	diagram.Version = version
+	diagram.UpdatedAt = update.UpdatedAt
+
+	if s.searchService != nil {
+		s.searchService.ScheduleIndex(diagram)
+	}
+	if s.commentService != nil {
+		s.commentService.ScheduleReanchor(diagram)
+	}
+	if s.mentionService != nil {
+		s.mentionService.ScheduleContentScan(ctx, diagram)
+	}
 
 	return diagram, nil
 }
@@ -265,7 +383,7 @@ func (s *DiagramService) Download(ctx context.Context, diagramID, workspaceID pr
 		return nil, nil, err
 	}
 
-	if s.gcsClient == nil {
+	if s.fileStorage == nil {
 		return nil, nil, errors.New("storage not configured")
 	}
 
@@ -273,7 +391,7 @@ func (s *DiagramService) Download(ctx context.Context, diagramID, workspaceID pr
 		return nil, nil, errors.New("no file associated with diagram")
 	}
 
-	data, err := s.gcsClient.DownloadFile(ctx, diagram.FileURL)
+	data, err := s.fileStorage.DownloadFile(ctx, diagram.FileURL)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to download file: %w", err)
 	}
@@ -281,101 +399,170 @@ func (s *DiagramService) Download(ctx context.Context, diagramID, workspaceID pr
 	return data, diagram, nil
 }
 
+// Move moves a diagram into a folder of the same workspace, or to the root when folderHex is ""
+func (s *DiagramService) Move(ctx context.Context, diagramID, workspaceID primitive.ObjectID, folderHex string) (*models.Diagram, error) {
+	if _, err := s.MoveMany(ctx, workspaceID, []primitive.ObjectID{diagramID}, folderHex); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, diagramID, workspaceID)
+}
+
+// MoveMany moves several diagrams into a folder (or to the root when folderHex is "") in one update.
+// Either every diagram is moved or, if any is missing, none is.
+func (s *DiagramService) MoveMany(ctx context.Context, workspaceID primitive.ObjectID, diagramIDs []primitive.ObjectID, folderHex string) (int64, error) {
+	var folderID *primitive.ObjectID
+	if s.folderService != nil {
+		var err error
+		folderID, err = s.folderService.resolveParent(ctx, workspaceID, folderHex)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	unique := make([]primitive.ObjectID, 0, len(diagramIDs))
+	seen := make(map[primitive.ObjectID]bool, len(diagramIDs))
+	for _, id := range diagramIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	diagrams, err := s.diagrams.ListByIDs(ctx, []primitive.ObjectID{workspaceID}, unique, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(diagrams) != len(unique) {
+		return 0, ErrDiagramNotFound
+	}
+
+	moved, err := s.diagrams.SetFolder(ctx, workspaceID, unique, folderID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range diagrams {
+		if eventID(d.FolderID) != eventID(folderID) {
+			s.emit(ctx, workspaceID, d.ID, models.EventDiagramMoved,
+				map[string]string{"folder_id": eventID(d.FolderID)},
+				map[string]string{"folder_id": eventID(folderID)})
+		}
+	}
+	return moved, nil
+}
+
 // Delete soft deletes a diagram
 func (s *DiagramService) Delete(ctx context.Context, diagramID, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("diagrams")
+	diagram, err := s.GetByID(ctx, diagramID, workspaceID)
+	if err != nil {
+		return err
+	}
 
-	result, err := collection.UpdateOne(ctx,
-		bson.M{"_id": diagramID, "workspace_id": workspaceID},
-		bson.M{"$set": bson.M{"deleted_at": time.Now()}},
-	)
+	trashed, err := s.diagrams.Trash(ctx, workspaceID, []primitive.ObjectID{diagramID}, primitive.NewObjectID(), time.Now(), false)
 	if err != nil {
 		return err
 	}
-	if result.MatchedCount == 0 {
+	if trashed == 0 {
 		return ErrDiagramNotFound
 	}
 
+	s.emit(ctx, workspaceID, diagramID, models.EventDiagramDeleted, map[string]string{"name": diagram.Name}, nil)
 	return nil
 }
 
-// Restore restores a soft-deleted diagram
+// Restore restores a soft-deleted diagram. A diagram whose folder is still in trash
+// (or gone) is restored to the workspace root.
 func (s *DiagramService) Restore(ctx context.Context, diagramID, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("diagrams")
-
-	result, err := collection.UpdateOne(ctx,
-		bson.M{"_id": diagramID, "workspace_id": workspaceID},
-		bson.M{"$set": bson.M{"deleted_at": nil}},
-	)
+	diagram, err := s.diagrams.Get(ctx, workspaceID, diagramID, repository.Trashed)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrDiagramNotFound
+		}
 		return err
 	}
-	if result.MatchedCount == 0 {
+
+	toRoot := false
+	if diagram.FolderID != nil && s.folderService != nil {
+		if _, err := s.folderService.GetByID(ctx, *diagram.FolderID, workspaceID); errors.Is(err, ErrFolderNotFound) {
+			toRoot = true
+		}
+	}
+
+	err = s.diagrams.Restore(ctx, workspaceID, diagramID, toRoot)
+	if errors.Is(err, repository.ErrNotFound) {
 		return ErrDiagramNotFound
 	}
+	if err != nil {
+		return err
+	}
 
+	folderID := diagram.FolderID
+	if toRoot {
+		folderID = nil
+	}
+	s.emit(ctx, workspaceID, diagramID, models.EventDiagramRestored, nil, map[string]string{
+		"name":      diagram.Name,
+		"folder_id": eventID(folderID),
+	})
 	return nil
 }
 
 // HardDelete permanently deletes a diagram and its file
 func (s *DiagramService) HardDelete(ctx context.Context, diagramID, workspaceID primitive.ObjectID) error {
 	// Look up even if deleted
-	collection := database.GetCollection("diagrams")
-	var diagram models.Diagram
-	err := collection.FindOne(ctx, bson.M{
-		"_id":          diagramID,
-		"workspace_id": workspaceID,
-	}).Decode(&diagram)
-	
+	diagram, err := s.diagrams.Get(ctx, workspaceID, diagramID, repository.AnyTrash)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return ErrDiagramNotFound
 		}
 		return err
 	}
 
 	// Delete from database
-	_, err = collection.DeleteOne(ctx, bson.M{"_id": diagramID})
-	if err != nil {
+	if err := s.diagrams.Delete(ctx, workspaceID, diagramID); err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return err
 	}
+	s.emit(ctx, workspaceID, diagramID, models.EventDiagramHardDeleted, map[string]string{"name": diagram.Name}, nil)
 
-	// Delete file from GCS
-	if s.gcsClient != nil && diagram.FileURL != "" {
-		_ = s.gcsClient.DeleteFile(ctx, diagram.FileURL)
+	// Delete file and thumbnail from GCS
+	if s.fileStorage != nil && diagram.FileURL != "" {
+		_ = s.fileStorage.DeleteFile(ctx, diagram.FileURL)
 	}
-
-	// Remove from all folders
-	if s.folderService != nil {
-		_ = s.folderService.RemoveDiagramFromAllFolders(ctx, workspaceID, diagramID)
+	if s.fileStorage != nil && diagram.Thumbnail != "" {
+		_ = s.fileStorage.DeleteFile(ctx, diagram.Thumbnail)
 	}
 
-	return nil
-}
-
-// ListTrash retrieves all soft-deleted diagrams in a workspace
-func (s *DiagramService) ListTrash(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error) {
-	collection := database.GetCollection("diagrams")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+	// Revoke public access
+	if s.shareService != nil {
+		_ = s.shareService.DeleteForDiagram(ctx, workspaceID, diagramID)
+	}
+	if s.embedService != nil {
+		_ = s.embedService.DeleteForDiagram(ctx, workspaceID, diagramID)
 	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
-	cursor, err := collection.Find(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"deleted_at":   bson.M{"$ne": nil},
-	}, opts)
-	if err != nil {
-		return nil, err
+	// Drop per-user history and stars
+	if s.accessService != nil {
+		_ = s.accessService.DeleteForDiagram(ctx, workspaceID, diagramID)
+	}
+	if s.starService != nil {
+		_ = s.starService.DeleteForDiagram(ctx, workspaceID, diagramID)
 	}
-	defer cursor.Close(ctx)
 
-	var diagrams []*models.Diagram
-	if err := cursor.All(ctx, &diagrams); err != nil {
-		return nil, err
+	// Comments and mentions go with the diagram
+	if s.commentService != nil {
+		_ = s.commentService.DeleteForDiagram(ctx, workspaceID, diagramID)
 	}
+	if s.mentionService != nil {
+		_ = s.mentionService.DeleteForDiagram(ctx, workspaceID, diagramID)
+	}
+
+	return nil
+}
 
-	return diagrams, nil
+// ListTrash retrieves one page of soft-deleted diagrams in a workspace.
+// Diagrams trashed along with a folder are listed under that folder instead.
+func (s *DiagramService) ListTrash(ctx context.Context, workspaceID primitive.ObjectID, filter *models.DiagramFilter, page *models.PageRequest) ([]*models.Diagram, string, error) {
+	return s.findPage(ctx, workspaceID, &repository.DiagramQuery{Trash: repository.Trashed, Filter: filter}, diagramTrashPageSpec, page)
 }
 
 // SetThumbnail updates the diagram thumbnail
@@ -385,17 +572,10 @@ func (s *DiagramService) SetThumbnail(ctx context.Context, diagramID, workspaceI
 		return err
 	}
 
-	collection := database.GetCollection("diagrams")
-
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": diagramID},
-		bson.M{"$set": bson.M{
-			"thumbnail":  thumbnail,
-			"updated_at": time.Now(),
-		}},
-	)
-	return err
+	return s.diagrams.Update(ctx, workspaceID, diagramID, &repository.DiagramUpdate{
+		Thumbnail: &thumbnail,
+		UpdatedAt: time.Now(),
+	})
 }
 
 // GetUploadURL generates a signed URL for uploading a file
@@ -405,7 +585,7 @@ func (s *DiagramService) GetUploadURL(ctx context.Context, userID, workspaceID,
 		return "", "", err
 	}
 
-	if s.gcsClient == nil {
+	if s.fileStorage == nil {
 		return "", "", errors.New("storage not configured")
 	}
 
@@ -420,9 +600,9 @@ func (s *DiagramService) GetUploadURL(ctx context.Context, userID, workspaceID,
 		// diagrams/{userID}/{workspaceID}/{diagramID}/diagram.flowstry
 		objectName = fmt.Sprintf("diagrams/%s/%s/%s/diagram.flowstry", userID.Hex(), workspaceID.Hex(), diagramID.Hex())
 	}
-	
+
 	// Generate signed URL valid for 15 minutes
-	url, err := s.gcsClient.GetSignedURL(ctx, objectName, "PUT", contentType, 15*time.Minute)
+	url, err := s.fileStorage.GetSignedURL(ctx, objectName, "PUT", contentType, 15*time.Minute)
 	return url, objectName, err
 }
 
@@ -433,7 +613,7 @@ func (s *DiagramService) GetDownloadURL(ctx context.Context, diagramID, workspac
 		return "", err
 	}
 
-	if s.gcsClient == nil {
+	if s.fileStorage == nil {
 		return "", errors.New("storage not configured")
 	}
 
@@ -447,20 +627,19 @@ func (s *DiagramService) GetDownloadURL(ctx context.Context, diagramID, workspac
 	// Or sometimes it's the full public URL?
 	// Based on Create method: objectName := fmt.Sprintf("diagrams/...") -> FileURL = objectName (returned from UploadFile)
 	// So FileURL is the object name.
-	
+
 	// Generate signed URL valid for 60 minutes
-	return s.gcsClient.GetSignedURL(ctx, diagram.FileURL, "GET", "", 60*time.Minute)
+	return s.fileStorage.GetSignedURL(ctx, diagram.FileURL, "GET", "", 60*time.Minute)
 }
 
 // GetThumbnailURL generates a signed URL for viewing the thumbnail
 func (s *DiagramService) GetThumbnailURL(ctx context.Context, thumbnailPath string) (string, error) {
-	if s.gcsClient == nil {
+	if s.fileStorage == nil {
 		return "", errors.New("storage not configured")
 	}
 	if thumbnailPath == "" {
 		return "", nil
 	}
 	// Generate signed URL valid for 60 minutes
-	return s.gcsClient.GetSignedURL(ctx, thumbnailPath, "GET", "", 60*time.Minute)
+	return s.fileStorage.GetSignedURL(ctx, thumbnailPath, "GET", "", 60*time.Minute)
 }
-
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/render"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxCachedSnapshots bounds the in-memory cache of rendered embed snapshots
+const maxCachedSnapshots = 256
+
+var (
+	ErrEmbedTokenNotFound = errors.New("embed token not found")
+	ErrInvalidEmbedURL    = errors.New("invalid embed URL")
+)
+
+// EmbedService handles revocable embed tokens and server-rendered diagram snapshots
+type EmbedService struct {
+	tokens         repository.EmbedTokenRepository
+	diagramService *DiagramService
+	contentService *ContentService
+	publicURL      string
+
+	mu        sync.Mutex
+	snapshots map[string]*render.Result
+}
+
+// NewEmbedService creates a new embed service
+func NewEmbedService(tokens repository.EmbedTokenRepository, diagramService *DiagramService, contentService *ContentService, publicURL string) *EmbedService {
+	return &EmbedService{
+		tokens:         tokens,
+		diagramService: diagramService,
+		contentService: contentService,
+		publicURL:      strings.TrimRight(publicURL, "/"),
+		snapshots:      make(map[string]*render.Result),
+	}
+}
+
+// BuildURL returns the public embed URL for a raw embed token
+func (s *EmbedService) BuildURL(rawToken string) string {
+	return s.publicURL + "/embed/" + rawToken
+}
+
+// ProviderURL returns the base URL of this oEmbed provider
+func (s *EmbedService) ProviderURL() string {
+	return s.publicURL
+}
+
+// TokenFromURL extracts the raw embed token from an embed URL issued by this server
+func (s *EmbedService) TokenFromURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidEmbedURL
+	}
+	base, err := url.Parse(s.publicURL)
+	if err != nil || !strings.EqualFold(parsed.Host, base.Host) {
+		return "", ErrInvalidEmbedURL
+	}
+
+	prefix := strings.TrimRight(base.Path, "/") + "/embed/"
+	if !strings.HasPrefix(parsed.Path, prefix) {
+		return "", ErrInvalidEmbedURL
+	}
+	token := strings.TrimPrefix(parsed.Path, prefix)
+	if token == "" || strings.Contains(token, "/") {
+		return "", ErrInvalidEmbedURL
+	}
+
+	return token, nil
+}
+
+// Create creates a new embed token for a diagram and returns it with the raw token
+func (s *EmbedService) Create(ctx context.Context, workspaceID, diagramID, userID primitive.ObjectID, req *models.CreateEmbedTokenRequest) (*models.EmbedToken, string, error) {
+	// Make sure the diagram exists and is not in trash
+	if _, err := s.diagramService.GetByID(ctx, diagramID, workspaceID); err != nil {
+		return nil, "", err
+	}
+
+	rawToken, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &models.EmbedToken{
+		WorkspaceID: workspaceID,
+		DiagramID:   diagramID,
+		TokenHash:   hashShareToken(rawToken),
+		Label:       strings.TrimSpace(req.Label),
+		CreatedBy:   userID,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.tokens.Create(ctx, token); err != nil {
+		return nil, "", err
+	}
+	return token, rawToken, nil
+}
+
+// List lists all embed tokens of a diagram, newest first
+func (s *EmbedService) List(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.EmbedToken, error) {
+	return s.tokens.ListForDiagram(ctx, workspaceID, diagramID)
+}
+
+// Revoke revokes an embed token so embeds using it stop rendering
+func (s *EmbedService) Revoke(ctx context.Context, workspaceID, diagramID, tokenID primitive.ObjectID) error {
+	err := s.tokens.Revoke(ctx, workspaceID, diagramID, tokenID, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrEmbedTokenNotFound
+	}
+	return err
+}
+
+// Resolve looks up an active embed token by raw token and returns it with its diagram
+func (s *EmbedService) Resolve(ctx context.Context, rawToken string) (*models.EmbedToken, *models.Diagram, error) {
+	token, err := s.tokens.GetByTokenHash(ctx, hashShareToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrEmbedTokenNotFound
+		}
+		return nil, nil, err
+	}
+
+	if token.RevokedAt != nil {
+		return nil, nil, ErrEmbedTokenNotFound
+	}
+
+	diagram, err := s.diagramService.GetByID(ctx, token.DiagramID, token.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, ErrDiagramNotFound) {
+			return nil, nil, ErrEmbedTokenNotFound
+		}
+		return nil, nil, err
+	}
+
+	// Track usage; failures here must not block rendering
+	_ = s.tokens.RecordUse(ctx, token.ID, time.Now())
+
+	return token, diagram, nil
+}
+
+// Render returns an SVG snapshot of the diagram's current version.
+// Snapshots are cached per diagram version and size, so re-renders only happen after edits.
+func (s *EmbedService) Render(ctx context.Context, diagram *models.Diagram, maxWidth, maxHeight int) (*render.Result, error) {
+	cacheKey := fmt.Sprintf("%s:%d:%d:%d", diagram.ID.Hex(), diagram.Version, maxWidth, maxHeight)
+
+	s.mu.Lock()
+	cached, ok := s.snapshots[cacheKey]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	document := []byte(`{}`)
+	if diagram.FileURL != "" {
+		content, err := s.contentService.Read(ctx, diagram)
+		if err != nil && !errors.Is(err, ErrNoDiagramContent) {
+			return nil, err
+		}
+		if err == nil {
+			document = content
+		}
+	}
+
+	result, err := render.SVG(document, render.Options{
+		Title:     diagram.Name,
+		MaxWidth:  maxWidth,
+		MaxHeight: maxHeight,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if len(s.snapshots) >= maxCachedSnapshots {
+		// Drop everything rather than tracking recency; snapshots are cheap to rebuild
+		s.snapshots = make(map[string]*render.Result)
+	}
+	s.snapshots[cacheKey] = result
+	s.mu.Unlock()
+
+	return result, nil
+}
+
+// DeleteForDiagram removes all embed tokens of a diagram (used on permanent deletion)
+func (s *EmbedService) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return s.tokens.DeleteForDiagram(ctx, workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes all embed tokens of a workspace (used when deleting workspace)
+func (s *EmbedService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.tokens.DeleteAllForWorkspace(ctx, workspaceID)
+}
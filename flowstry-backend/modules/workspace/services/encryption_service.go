@@ -6,8 +6,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+
+	"github.com/flowstry/flowstry-backend/utils"
 )
 
 var (
@@ -21,15 +24,23 @@ type EncryptionService struct {
 }
 
 // NewEncryptionService creates a new encryption service
-func NewEncryptionService() (*EncryptionService, error) {
+// ENCRYPTION_MASTER_KEY takes precedence; otherwise the master key is read from keyFile,
+// which is generated on first boot. An empty keyFile requires the environment variable.
+func NewEncryptionService(keyFile string) (*EncryptionService, error) {
 	masterKeyHex := os.Getenv("ENCRYPTION_MASTER_KEY")
 	if masterKeyHex == "" {
-		// fallback for dev/testing if not set, BUT ideally should error in prod
-		// For this implementation, we will require it.
-		// If the user hasn't set it yet, we can't proceed safely.
-		// However, to avoid breaking everything immediately if they haven't set it,
-		// we *could* log a warning, but for security critical features, fail fast is better.
-		return nil, ErrMasterKeyNotSet
+		if keyFile == "" {
+			return nil, ErrMasterKeyNotSet
+		}
+
+		masterKey, created, err := utils.LoadOrCreateKey(keyFile, 32)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			fmt.Printf("Generated encryption master key in %s - back it up, encrypted diagrams cannot be read without it\n", keyFile)
+		}
+		return &EncryptionService{masterKey: masterKey}, nil
 	}
 
 	masterKey, err := hex.DecodeString(masterKeyHex)
@@ -101,3 +112,45 @@ func (s *EncryptionService) DecryptWorkspaceKey(encryptedKey []byte) ([]byte, er
 
 	return plaintext, nil
 }
+
+// EncryptContent encrypts diagram content with a workspace key.
+// Output format matches the frontend: [IV (12 bytes)] [ciphertext]
+func (s *EncryptionService) EncryptContent(workspaceKey, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(workspaceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// DecryptContent decrypts diagram content that was encrypted with a workspace key
+func (s *EncryptionService) DecryptContent(workspaceKey, packed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(workspaceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aesGCM.NonceSize()
+	if len(packed) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := packed[:nonceSize], packed[nonceSize:]
+	return aesGCM.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// actorKey is the context key of the request's Actor
+type actorKey struct{}
+
+// WithActor returns a context whose changes are attributed to actor in emitted events
+func WithActor(ctx context.Context, actor *models.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom returns the actor of ctx, or nil for changes made by the server itself
+func actorFrom(ctx context.Context) *models.Actor {
+	actor, _ := ctx.Value(actorKey{}).(*models.Actor)
+	return actor
+}
+
+// EventHandler receives an event after the change it describes has been made
+type EventHandler func(ctx context.Context, event *models.Event)
+
+// EventBus delivers the events emitted by the workspace services to their subscribers.
+// Handlers run synchronously in subscription order, so they should be quick and must not fail the change.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a handler for every event
+func (b *EventBus) Subscribe(handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Emit delivers an event attributed to the actor of ctx
+func (b *EventBus) Emit(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType, targetType models.TargetType, targetID primitive.ObjectID, before, after map[string]string) {
+	event := &models.Event{
+		Type:        eventType,
+		WorkspaceID: workspaceID,
+		Actor:       actorFrom(ctx),
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Before:      before,
+		After:       after,
+		CreatedAt:   time.Now(),
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
+
+// eventID formats an optional ID for before/after values; "" stands for none (e.g. the workspace root)
+func eventID(id *primitive.ObjectID) string {
+	if id == nil {
+		return ""
+	}
+	return id.Hex()
+}
+
+// changed adds a field to before and after when its value changed
+func changed(before, after map[string]string, field, old, new string) {
+	if old != new {
+		before[field] = old
+		after[field] = new
+	}
+}
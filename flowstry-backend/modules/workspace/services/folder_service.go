@@ -3,109 +3,150 @@ package services
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/flowstry/flowstry-backend/database"
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/flowstry/flowstry-backend/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
-	ErrFolderNotFound = errors.New("folder not found")
+	ErrFolderNotFound      = errors.New("folder not found")
+	ErrInvalidParentFolder = errors.New("parent folder not found in this workspace")
+	ErrFolderCycle         = errors.New("cannot move a folder into itself or one of its subfolders")
+	ErrDiagramNotInFolder  = errors.New("diagram is not in this folder")
 )
 
 // FolderService handles folder (collection) operations
-type FolderService struct{}
+type FolderService struct {
+	folders        repository.FolderRepository
+	diagrams       repository.DiagramRepository
+	diagramService *DiagramService
+	events         *EventBus
+}
 
 // NewFolderService creates a new folder service
-func NewFolderService() *FolderService {
-	return &FolderService{}
+func NewFolderService(folders repository.FolderRepository, diagrams repository.DiagramRepository) *FolderService {
+	return &FolderService{folders: folders, diagrams: diagrams}
 }
 
-// Create creates a new folder
-func (s *FolderService) Create(ctx context.Context, workspaceID primitive.ObjectID, req *models.CreateFolderRequest) (*models.Folder, error) {
-	collection := database.GetCollection("folders")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// SetDiagramService sets the diagram service used to permanently delete folder contents
+func (s *FolderService) SetDiagramService(ds *DiagramService) {
+	s.diagramService = ds
+}
+
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *FolderService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// emit publishes a change to a folder
+func (s *FolderService) emit(ctx context.Context, workspaceID, folderID primitive.ObjectID, eventType models.EventType, before, after map[string]string) {
+	if s.events != nil {
+		s.events.Emit(ctx, workspaceID, eventType, models.TargetFolder, folderID, before, after)
 	}
+}
 
+// Create creates a new folder
+func (s *FolderService) Create(ctx context.Context, userID, workspaceID primitive.ObjectID, req *models.CreateFolderRequest) (*models.Folder, error) {
 	folder := &models.Folder{
 		WorkspaceID: workspaceID,
 		Name:        req.Name,
 		Description: req.Description,
 		Color:       req.Color,
+		CreatedBy:   &userID,
 
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
 
 	// Handle parent folder ID if provided
-	if req.ParentFolderID != "" {
-		parentID, err := primitive.ObjectIDFromHex(req.ParentFolderID)
-		if err == nil {
-			folder.ParentFolderID = &parentID
-		}
+	parentID, err := s.resolveParent(ctx, workspaceID, req.ParentFolderID)
+	if err != nil {
+		return nil, err
 	}
+	folder.ParentFolderID = parentID
 
-	result, err := collection.InsertOne(ctx, folder)
-	if err != nil {
+	if err := s.folders.Create(ctx, folder); err != nil {
 		return nil, err
 	}
 
-	folder.ID = result.InsertedID.(primitive.ObjectID)
+	s.emit(ctx, workspaceID, folder.ID, models.EventFolderCreated, nil, map[string]string{
+		"name":             folder.Name,
+		"parent_folder_id": eventID(folder.ParentFolderID),
+	})
 	return folder, nil
 }
 
-
 // GetByID retrieves a folder by ID
 func (s *FolderService) GetByID(ctx context.Context, folderID, workspaceID primitive.ObjectID) (*models.Folder, error) {
-	collection := database.GetCollection("folders")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+	folder, err := s.folders.Get(ctx, workspaceID, folderID, repository.Live)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFolderNotFound
 	}
+	return folder, err
+}
 
-	var folder models.Folder
-	err := collection.FindOne(ctx, bson.M{
-		"_id":          folderID,
-		"workspace_id": workspaceID,
-		"deleted_at":   nil,
-	}).Decode(&folder)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrFolderNotFound
-		}
-		return nil, err
-	}
+// folderPageSpec lists the sort options for folder listings
+var folderPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByName, models.SortByCreated, models.SortByUpdated},
+	defaultSort:  models.SortByName,
+	defaultOrder: models.SortAsc,
+	unbounded:    true,
+}
 
-	return &folder, nil
+// folderTrashPageSpec lists the sort options for the folder trash
+var folderTrashPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByName, models.SortByCreated, models.SortByUpdated, models.SortByDeleted},
+	defaultSort:  models.SortByDeleted,
+	defaultOrder: models.SortDesc,
+	unbounded:    true,
 }
 
-// List retrieves all folders in a workspace
-func (s *FolderService) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Folder, error) {
-	collection := database.GetCollection("folders")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// List retrieves one page of folders in a workspace, optionally filtered.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *FolderService) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.FolderFilter, page *models.PageRequest) ([]*models.Folder, string, error) {
+	return s.findPage(ctx, workspaceID, &repository.FolderQuery{Trash: repository.Live, Filter: filter}, folderPageSpec, page)
+}
+
+// findPage runs a paginated folders query
+func (s *FolderService) findPage(ctx context.Context, workspaceID primitive.ObjectID, query *repository.FolderQuery, spec pageSpec, page *models.PageRequest) ([]*models.Folder, string, error) {
+	p, err := spec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
 	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
-	cursor, err := collection.Find(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"deleted_at":   nil,
-	}, opts)
+	folders, err := s.folders.List(ctx, workspaceID, query, q)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cursor.Close(ctx)
 
-	var folders []*models.Folder
-	if err := cursor.All(ctx, &folders); err != nil {
-		return nil, err
+	folders, more := splitPage(folders, p.limit)
+	if !more {
+		return folders, "", nil
 	}
+	last := folders[len(folders)-1]
+	return folders, p.cursorAfter(folderSortValue(last, p.sort), last.ID), nil
+}
 
-	return folders, nil
+// folderSortValue returns the value a folder is ordered by for a sort field
+func folderSortValue(f *models.Folder, field models.SortField) interface{} {
+	switch field {
+	case models.SortByName:
+		return f.Name
+	case models.SortByCreated:
+		return f.CreatedAt
+	case models.SortByDeleted:
+		return f.DeletedAt
+	default:
+		return f.UpdatedAt
+	}
 }
 
 // Update updates a folder
@@ -115,112 +156,353 @@ func (s *FolderService) Update(ctx context.Context, folderID, workspaceID primit
 		return nil, err
 	}
 
-	collection := database.GetCollection("folders")
-
-	update := bson.M{"updated_at": time.Now()}
+	update := &repository.FolderUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Color:       req.Color,
+		UpdatedAt:   time.Now(),
+	}
+	before, after := map[string]string{}, map[string]string{}
 	if req.Name != nil {
-		update["name"] = *req.Name
+		changed(before, after, "name", folder.Name, *req.Name)
 		folder.Name = *req.Name
 	}
 	if req.Description != nil {
-		update["description"] = *req.Description
+		changed(before, after, "description", folder.Description, *req.Description)
 		folder.Description = *req.Description
 	}
 	if req.Color != nil {
-		update["color"] = *req.Color
+		changed(before, after, "color", folder.Color, *req.Color)
 		folder.Color = *req.Color
 	}
+	oldParent := eventID(folder.ParentFolderID)
+	if req.ParentFolderID != nil {
+		parentID, err := s.validateMove(ctx, folderID, workspaceID, *req.ParentFolderID)
+		if err != nil {
+			return nil, err
+		}
+		update.SetParent = true
+		update.ParentFolderID = parentID
+		folder.ParentFolderID = parentID
+	}
+
+	if err := s.folders.Update(ctx, workspaceID, folderID, update); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
 
+	if len(after) > 0 {
+		s.emit(ctx, workspaceID, folderID, models.EventFolderUpdated, before, after)
+	}
+	if newParent := eventID(folder.ParentFolderID); newParent != oldParent {
+		s.emit(ctx, workspaceID, folderID, models.EventFolderMoved,
+			map[string]string{"parent_folder_id": oldParent},
+			map[string]string{"parent_folder_id": newParent})
+	}
+	folder.UpdatedAt = update.UpdatedAt
+	return folder, nil
+}
+
+// Move moves a folder under another folder of the same workspace, or to the root when parentHex is ""
+func (s *FolderService) Move(ctx context.Context, folderID, workspaceID primitive.ObjectID, parentHex string) (*models.Folder, error) {
+	return s.Update(ctx, folderID, workspaceID, &models.UpdateFolderRequest{ParentFolderID: &parentHex})
+}
 
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": folderID},
-		bson.M{"$set": update},
-	)
+// resolveParent parses and verifies a parent folder ID ("" means the workspace root)
+func (s *FolderService) resolveParent(ctx context.Context, workspaceID primitive.ObjectID, parentHex string) (*primitive.ObjectID, error) {
+	if parentHex == "" {
+		return nil, nil
+	}
+	parentID, err := primitive.ObjectIDFromHex(parentHex)
 	if err != nil {
+		return nil, ErrInvalidParentFolder
+	}
+	if _, err := s.GetByID(ctx, parentID, workspaceID); err != nil {
+		if errors.Is(err, ErrFolderNotFound) {
+			return nil, ErrInvalidParentFolder
+		}
 		return nil, err
 	}
+	return &parentID, nil
+}
 
-	folder.UpdatedAt = time.Now()
-	return folder, nil
+// validateMove resolves the new parent of a folder and rejects moves that would create a cycle
+func (s *FolderService) validateMove(ctx context.Context, folderID, workspaceID primitive.ObjectID, parentHex string) (*primitive.ObjectID, error) {
+	parentID, err := s.resolveParent(ctx, workspaceID, parentHex)
+	if err != nil || parentID == nil {
+		return parentID, err
+	}
+	if *parentID == folderID {
+		return nil, ErrFolderCycle
+	}
+
+	// The target must not be a descendant, i.e. the folder must not be among the target's ancestors
+	ancestors, err := s.ancestors(ctx, *parentID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range ancestors {
+		if a.ID == folderID {
+			return nil, ErrFolderCycle
+		}
+	}
+	return parentID, nil
+}
+
+// ancestors returns the ancestors of a folder ordered from the workspace root down to its parent
+func (s *FolderService) ancestors(ctx context.Context, folderID, workspaceID primitive.ObjectID) ([]*models.Folder, error) {
+	ancestors, err := s.folders.Ancestors(ctx, workspaceID, folderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFolderNotFound
+	}
+	return ancestors, err
 }
 
-// Delete soft deletes a folder (diagrams remain in workspace)
+// Breadcrumbs returns the path from the workspace root to a folder, including the folder itself
+func (s *FolderService) Breadcrumbs(ctx context.Context, folderID, workspaceID primitive.ObjectID) ([]*models.Breadcrumb, error) {
+	folder, err := s.GetByID(ctx, folderID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.ancestors(ctx, folderID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	crumbs := make([]*models.Breadcrumb, 0, len(ancestors)+1)
+	for _, a := range ancestors {
+		crumbs = append(crumbs, &models.Breadcrumb{ID: a.ID, Name: a.Name})
+	}
+	crumbs = append(crumbs, &models.Breadcrumb{ID: folder.ID, Name: folder.Name})
+	return crumbs, nil
+}
+
+// Tree returns the folder hierarchy of a workspace with direct and recursive diagram counts
+func (s *FolderService) Tree(ctx context.Context, workspaceID primitive.ObjectID) (*models.FolderTreeResponse, error) {
+	folders, err := s.folders.ListAll(ctx, workspaceID, repository.Live)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, rootCount, err := s.diagrams.CountByFolder(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[primitive.ObjectID]*models.FolderTreeNode, len(folders))
+	for _, f := range folders {
+		nodes[f.ID] = &models.FolderTreeNode{
+			ID:             f.ID,
+			ParentFolderID: f.ParentFolderID,
+			Name:           f.Name,
+			Color:          f.Color,
+			DiagramCount:   counts[f.ID],
+			Children:       []*models.FolderTreeNode{},
+		}
+	}
+
+	// Folders whose parent is missing or trashed are shown at the root
+	roots := []*models.FolderTreeNode{}
+	for _, f := range folders {
+		node := nodes[f.ID]
+		if f.ParentFolderID != nil {
+			if parent, ok := nodes[*f.ParentFolderID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	visited := make(map[primitive.ObjectID]bool, len(nodes))
+	var total func(n *models.FolderTreeNode) int64
+	total = func(n *models.FolderTreeNode) int64 {
+		visited[n.ID] = true
+		sortTreeNodes(n.Children)
+		n.TotalDiagramCount = n.DiagramCount
+		for _, child := range n.Children {
+			n.TotalDiagramCount += total(child)
+		}
+		return n.TotalDiagramCount
+	}
+	sortTreeNodes(roots)
+	for _, root := range roots {
+		total(root)
+	}
+
+	// Pre-existing cycles are unreachable from the root; surface them there so nothing disappears
+	for _, f := range folders {
+		if !visited[f.ID] {
+			node := nodes[f.ID]
+			if node.ParentFolderID != nil {
+				if parent, ok := nodes[*node.ParentFolderID]; ok {
+					parent.Children = removeTreeNode(parent.Children, node)
+				}
+			}
+			roots = append(roots, node)
+			total(node)
+		}
+	}
+
+	return &models.FolderTreeResponse{Folders: roots, RootDiagramCount: rootCount}, nil
+}
+
+// sortTreeNodes orders sibling folders by name
+func sortTreeNodes(nodes []*models.FolderTreeNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+}
+
+// removeTreeNode removes one node from a list of siblings
+func removeTreeNode(nodes []*models.FolderTreeNode, node *models.FolderTreeNode) []*models.FolderTreeNode {
+	for i, n := range nodes {
+		if n == node {
+			return append(nodes[:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
+
+// Delete soft deletes a folder together with its subfolders and their diagrams.
+// Everything trashed shares one batch ID so Restore brings back exactly that batch.
 func (s *FolderService) Delete(ctx context.Context, folderID, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("folders")
-	// Soft delete
-	result, err := collection.UpdateOne(ctx,
-		bson.M{"_id": folderID, "workspace_id": workspaceID},
-		bson.M{"$set": bson.M{"deleted_at": time.Now()}},
-	)
+	folder, err := s.GetByID(ctx, folderID, workspaceID)
 	if err != nil {
 		return err
 	}
-	if result.MatchedCount == 0 {
+
+	// Subfolders trashed earlier stay in their own batch
+	descendants, err := s.folders.Descendants(ctx, workspaceID, folderID, nil)
+	if err != nil {
+		return err
+	}
+
+	batchID := primitive.NewObjectID()
+	now := time.Now()
+
+	if err := s.diagrams.TrashInFolders(ctx, workspaceID, append(descendants, folderID), batchID, now); err != nil {
+		return err
+	}
+	if _, err := s.folders.Trash(ctx, workspaceID, descendants, batchID, now, true); err != nil {
+		return err
+	}
+
+	trashed, err := s.folders.Trash(ctx, workspaceID, []primitive.ObjectID{folderID}, batchID, now, false)
+	if err != nil {
+		return err
+	}
+	if trashed == 0 {
 		return ErrFolderNotFound
 	}
+
+	s.emit(ctx, workspaceID, folderID, models.EventFolderDeleted, map[string]string{"name": folder.Name}, nil)
 	return nil
 }
 
-// Restore restores a soft-deleted folder
+// Restore restores a soft-deleted folder along with everything trashed in the same batch
 func (s *FolderService) Restore(ctx context.Context, folderID, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("folders")
-	result, err := collection.UpdateOne(ctx,
-		bson.M{"_id": folderID, "workspace_id": workspaceID},
-		bson.M{"$set": bson.M{"deleted_at": nil}},
-	)
+	folder, err := s.findTrashed(ctx, folderID, workspaceID)
 	if err != nil {
 		return err
 	}
-	if result.MatchedCount == 0 {
-		return ErrFolderNotFound
+
+	if folder.TrashBatchID == nil {
+		// Trashed before batches existed: only the folder itself was deleted
+		if err := s.folders.Restore(ctx, workspaceID, []primitive.ObjectID{folderID}); err != nil {
+			return err
+		}
+	} else {
+		// Restoring any member of a batch restores the whole batch, root included
+		if err := s.folders.RestoreBatch(ctx, workspaceID, *folder.TrashBatchID); err != nil {
+			return err
+		}
+		if err := s.diagrams.RestoreBatch(ctx, workspaceID, *folder.TrashBatchID); err != nil {
+			return err
+		}
 	}
+
+	if err := s.rehomeOrphans(ctx, workspaceID); err != nil {
+		return err
+	}
+
+	s.emit(ctx, workspaceID, folderID, models.EventFolderRestored, nil, map[string]string{"name": folder.Name})
 	return nil
 }
 
-// HardDelete permanently deletes a folder
+// rehomeOrphans moves live folders and diagrams whose parent folder is trashed or gone to the workspace root
+func (s *FolderService) rehomeOrphans(ctx context.Context, workspaceID primitive.ObjectID) error {
+	if err := s.folders.DetachFromMissingParents(ctx, workspaceID); err != nil {
+		return err
+	}
+	return s.diagrams.DetachFromMissingFolders(ctx, workspaceID)
+}
+
+// HardDelete permanently deletes a folder with its subfolders and their diagrams (including storage objects).
+// Items under the folder that were trashed separately are kept and moved to the workspace root.
 func (s *FolderService) HardDelete(ctx context.Context, folderID, workspaceID primitive.ObjectID) error {
 	// Verify folder exists (even if deleted)
-	collection := database.GetCollection("folders")
-	var folder models.Folder
-	err := collection.FindOne(ctx, bson.M{"_id": folderID, "workspace_id": workspaceID}).Decode(&folder)
+	folder, err := s.folders.Get(ctx, workspaceID, folderID, repository.AnyTrash)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return ErrFolderNotFound
 		}
 		return err
 	}
 
-	_, err = collection.DeleteOne(ctx, bson.M{"_id": folderID})
-	return err
-}
-
-// ListTrash retrieves all soft-deleted folders in a workspace
-func (s *FolderService) ListTrash(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Folder, error) {
-	collection := database.GetCollection("folders")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+	// The folder's own batch, or live contents when it was never trashed (or trashed before batches)
+	descendants, err := s.folders.Descendants(ctx, workspaceID, folderID, folder.TrashBatchID)
+	if err != nil {
+		return err
 	}
+	folderIDs := append(descendants, folderID)
 
-	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
-	cursor, err := collection.Find(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"deleted_at":   bson.M{"$ne": nil},
-	}, opts)
+	diagramIDs, err := s.diagrams.InFolders(ctx, workspaceID, folderIDs, folder.TrashBatchID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	for _, diagramID := range diagramIDs {
+		if s.diagramService != nil {
+			if err := s.diagramService.HardDelete(ctx, diagramID, workspaceID); err != nil && !errors.Is(err, ErrDiagramNotFound) {
+				return err
+			}
+		} else if err := s.diagrams.Delete(ctx, workspaceID, diagramID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
 	}
-	defer cursor.Close(ctx)
 
-	var folders []*models.Folder
-	if err := cursor.All(ctx, &folders); err != nil {
-		return nil, err
+	if err := s.folders.DeleteMany(ctx, workspaceID, folderIDs); err != nil {
+		return err
+	}
+
+	s.emit(ctx, workspaceID, folderID, models.EventFolderHardDeleted, map[string]string{"name": folder.Name}, nil)
+
+	// Separately trashed leftovers no longer have a parent
+	if err := s.folders.Unparent(ctx, workspaceID, folderIDs); err != nil {
+		return err
 	}
+	return s.diagrams.Unfile(ctx, workspaceID, folderIDs)
+}
+
+// findTrashed retrieves a soft-deleted folder
+func (s *FolderService) findTrashed(ctx context.Context, folderID, workspaceID primitive.ObjectID) (*models.Folder, error) {
+	folder, err := s.folders.Get(ctx, workspaceID, folderID, repository.Trashed)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFolderNotFound
+	}
+	return folder, err
+}
 
-	return folders, nil
+// ListTrash retrieves one page of soft-deleted folders in a workspace
+func (s *FolderService) ListTrash(ctx context.Context, workspaceID primitive.ObjectID, filter *models.FolderFilter, page *models.PageRequest) ([]*models.Folder, string, error) {
+	// Only deleted roots; their contents come back with them
+	return s.findPage(ctx, workspaceID, &repository.FolderQuery{Trash: repository.Trashed, Filter: filter}, folderTrashPageSpec, page)
 }
 
-// AddDiagrams adds diagrams to a folder
+// AddDiagrams moves diagrams into a folder (a diagram's folder_id is its only folder membership)
 func (s *FolderService) AddDiagrams(ctx context.Context, folderID, workspaceID primitive.ObjectID, diagramIDs []primitive.ObjectID) error {
 	// Verify folder exists
 	_, err := s.GetByID(ctx, folderID, workspaceID)
@@ -228,20 +510,14 @@ func (s *FolderService) AddDiagrams(ctx context.Context, folderID, workspaceID p
 		return err
 	}
 
-	collection := database.GetCollection("folders")
-
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": folderID},
-		bson.M{
-			"$addToSet":  bson.M{"diagram_ids": bson.M{"$each": diagramIDs}},
-			"$set":       bson.M{"updated_at": time.Now()},
-		},
-	)
+	if s.diagramService == nil {
+		return errors.New("diagram service not configured")
+	}
+	_, err = s.diagramService.MoveMany(ctx, workspaceID, diagramIDs, folderID.Hex())
 	return err
 }
 
-// RemoveDiagram removes a diagram from a folder
+// RemoveDiagram moves a diagram out of a folder to the workspace root
 func (s *FolderService) RemoveDiagram(ctx context.Context, folderID, workspaceID, diagramID primitive.ObjectID) error {
 	// Verify folder exists
 	_, err := s.GetByID(ctx, folderID, workspaceID)
@@ -249,33 +525,34 @@ func (s *FolderService) RemoveDiagram(ctx context.Context, folderID, workspaceID
 		return err
 	}
 
-	collection := database.GetCollection("folders")
+	err = s.diagrams.RemoveFromFolder(ctx, workspaceID, diagramID, folderID, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrDiagramNotInFolder
+	}
+	if err != nil {
+		return err
+	}
 
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": folderID},
-		bson.M{
-			"$pull": bson.M{"diagram_ids": diagramID},
-			"$set":  bson.M{"updated_at": time.Now()},
-		},
-	)
-	return err
+	if s.diagramService != nil {
+		s.diagramService.emit(ctx, workspaceID, diagramID, models.EventDiagramMoved,
+			map[string]string{"folder_id": folderID.Hex()},
+			map[string]string{"folder_id": ""})
+	}
+	return nil
 }
 
-// RemoveDiagramFromAllFolders removes a diagram from all folders in a workspace
-func (s *FolderService) RemoveDiagramFromAllFolders(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
-	collection := database.GetCollection("folders")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	_, err := collection.UpdateMany(
-		ctx,
-		bson.M{"workspace_id": workspaceID},
-		bson.M{
-			"$pull": bson.M{"diagram_ids": diagramID},
-			"$set":  bson.M{"updated_at": time.Now()},
-		},
-	)
-	return err
+// AttachDiagramCounts fills in the number of live diagrams directly in each folder
+func (s *FolderService) AttachDiagramCounts(ctx context.Context, workspaceID primitive.ObjectID, folders ...*models.Folder) error {
+	if len(folders) == 0 {
+		return nil
+	}
+
+	counts, _, err := s.diagrams.CountByFolder(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	for _, f := range folders {
+		f.DiagramCount = counts[f.ID]
+	}
+	return nil
 }
@@ -5,14 +5,13 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
-	"github.com/flowstry/flowstry-backend/database"
+	"github.com/flowstry/flowstry-backend/mailer"
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/flowstry/flowstry-backend/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -25,16 +24,46 @@ var (
 
 // InviteService handles workspace invite operations
 type InviteService struct {
+	invites       repository.InviteRepository
+	workspaces    repository.WorkspaceRepository
+	users         repository.UserRepository
 	memberService *MemberService
+	events        *EventBus
+	mailer        *mailer.Mailer
 }
 
 // NewInviteService creates a new invite service
-func NewInviteService(memberService *MemberService) *InviteService {
+func NewInviteService(invites repository.InviteRepository, workspaces repository.WorkspaceRepository, users repository.UserRepository, memberService *MemberService) *InviteService {
 	return &InviteService{
+		invites:       invites,
+		workspaces:    workspaces,
+		users:         users,
 		memberService: memberService,
 	}
 }
 
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *InviteService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// SetMailer sets the mailer that emails invitations (for dependency injection)
+func (s *InviteService) SetMailer(m *mailer.Mailer) {
+	s.mailer = m
+}
+
+// emit publishes an invite change
+func (s *InviteService) emit(ctx context.Context, invite *models.WorkspaceInvite, eventType models.EventType, before, after map[string]string) {
+	if s.events != nil {
+		s.events.Emit(ctx, invite.WorkspaceID, eventType, models.TargetInvite, invite.ID, before, after)
+	}
+}
+
+// inviteValues describes an invite in audit entries
+func inviteValues(invite *models.WorkspaceInvite) map[string]string {
+	return map[string]string{"email": invite.Email, "role": string(invite.Role)}
+}
+
 // generateToken creates a secure random token for invites
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -46,11 +75,6 @@ func generateToken() (string, error) {
 
 // CreateInvite creates a new workspace invite
 func (s *InviteService) CreateInvite(ctx context.Context, workspaceID primitive.ObjectID, email string, role models.WorkspaceRole, invitedBy primitive.ObjectID) (*models.WorkspaceInvite, error) {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
 	// Validate role (cannot invite as owner)
 	if role == models.RoleOwner {
 		return nil, errors.New("cannot invite as owner")
@@ -94,73 +118,66 @@ func (s *InviteService) CreateInvite(ctx context.Context, workspaceID primitive.
 		CreatedAt:   time.Now(),
 	}
 
-	result, err := collection.InsertOne(ctx, invite)
-	if err != nil {
+	if err := s.invites.Create(ctx, invite); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrInviteAlreadyExists
+		}
 		return nil, err
 	}
 
-	invite.ID = result.InsertedID.(primitive.ObjectID)
+	s.emit(ctx, invite, models.EventInviteCreated, nil, inviteValues(invite))
+	s.sendInviteEmail(ctx, invite)
 	return invite, nil
 }
 
-// GetInviteByToken retrieves an invite by its token
-func (s *InviteService) GetInviteByToken(ctx context.Context, token string) (*models.WorkspaceInvite, error) {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// sendInviteEmail queues the invitation email; the invite stays valid if that fails,
+// since the invitee can still find it among their pending invites
+func (s *InviteService) sendInviteEmail(ctx context.Context, invite *models.WorkspaceInvite) {
+	if s.mailer == nil {
+		return
 	}
 
-	var invite models.WorkspaceInvite
-	err := collection.FindOne(ctx, bson.M{"token": token}).Decode(&invite)
+	inviterName := "A teammate"
+	if inviter, err := s.users.Get(ctx, invite.InvitedBy); err == nil {
+		inviterName = inviter.Name
+	}
+	workspace, err := s.workspaces.Get(ctx, invite.WorkspaceID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrInviteNotFound
-		}
-		return nil, err
+		fmt.Printf("Warning: Failed to email invite %s: %v\n", invite.ID.Hex(), err)
+		return
+	}
+
+	err = s.mailer.QueueInvite(ctx, invite.Email, inviterName, workspace.Name, string(invite.Role), invite.Token, invite.ExpiresAt)
+	if err != nil {
+		fmt.Printf("Warning: Failed to email invite %s: %v\n", invite.ID.Hex(), err)
 	}
+}
 
-	return &invite, nil
+// GetInviteByToken retrieves an invite by its token
+func (s *InviteService) GetInviteByToken(ctx context.Context, token string) (*models.WorkspaceInvite, error) {
+	invite, err := s.invites.GetByToken(ctx, token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInviteNotFound
+	}
+	return invite, err
 }
 
 // GetInviteByEmail retrieves an invite by workspace and email
 func (s *InviteService) GetInviteByEmail(ctx context.Context, workspaceID primitive.ObjectID, email string) (*models.WorkspaceInvite, error) {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+	invite, err := s.invites.GetByEmail(ctx, workspaceID, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInviteNotFound
 	}
-
-	var invite models.WorkspaceInvite
-	err := collection.FindOne(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"email":        email,
-	}).Decode(&invite)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrInviteNotFound
-		}
-		return nil, err
-	}
-
-	return &invite, nil
+	return invite, err
 }
 
 // GetInviteByID retrieves an invite by its ID
 func (s *InviteService) GetInviteByID(ctx context.Context, inviteID primitive.ObjectID) (*models.WorkspaceInvite, error) {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+	invite, err := s.invites.Get(ctx, inviteID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInviteNotFound
 	}
-
-	var invite models.WorkspaceInvite
-	err := collection.FindOne(ctx, bson.M{"_id": inviteID}).Decode(&invite)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrInviteNotFound
-		}
-		return nil, err
-	}
-
-	return &invite, nil
+	return invite, err
 }
 
 // AcceptInvite accepts an invite and creates a member record
@@ -199,69 +216,88 @@ func (s *InviteService) AcceptInvite(ctx context.Context, token string, userID p
 	// Delete the invite
 	s.DeleteInvite(ctx, invite.ID)
 
+	s.emit(ctx, invite, models.EventInviteAccepted, inviteValues(invite), nil)
 	return member, nil
 }
 
 // RevokeInvite cancels a pending invite
 func (s *InviteService) RevokeInvite(ctx context.Context, inviteID, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	result, err := collection.DeleteOne(ctx, bson.M{
-		"_id":          inviteID,
-		"workspace_id": workspaceID,
-	})
+	invite, err := s.GetInviteByID(ctx, inviteID)
 	if err != nil {
 		return err
 	}
-	if result.DeletedCount == 0 {
+	if invite.WorkspaceID != workspaceID {
 		return ErrInviteNotFound
 	}
 
+	err = s.invites.DeleteInWorkspace(ctx, workspaceID, inviteID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInviteNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, invite, models.EventInviteRevoked, inviteValues(invite), nil)
 	return nil
 }
 
 // DeleteInvite deletes an invite by ID
 func (s *InviteService) DeleteInvite(ctx context.Context, inviteID primitive.ObjectID) error {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return errors.New("database not connected")
+	err := s.invites.Delete(ctx, inviteID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
 	}
-
-	_, err := collection.DeleteOne(ctx, bson.M{"_id": inviteID})
 	return err
 }
 
-// ListPendingInvites lists all pending invites for a workspace
-func (s *InviteService) ListPendingInvites(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceInviteResponse, error) {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// invitePageSpec lists the sort options for invite listings
+var invitePageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByName, models.SortByCreated},
+	defaultSort:  models.SortByCreated,
+	defaultOrder: models.SortDesc,
+	unbounded:    true,
+}
+
+// ListPendingInvites lists one page of pending invites for a workspace.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *InviteService) ListPendingInvites(ctx context.Context, workspaceID primitive.ObjectID, filter *models.InviteFilter, page *models.PageRequest) ([]*models.WorkspaceInviteResponse, string, error) {
+	p, err := invitePageSpec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Find non-expired invites
-	cursor, err := collection.Find(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"expires_at":   bson.M{"$gt": time.Now()},
-	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
-	if err != nil {
-		return nil, err
+	query := &repository.InviteQuery{ExpiresAfter: time.Now()}
+	if filter != nil {
+		query.InvitedBy = filter.InvitedBy
+		query.Created = filter.Created
 	}
-	defer cursor.Close(ctx)
 
-	var invites []*models.WorkspaceInvite
-	if err := cursor.All(ctx, &invites); err != nil {
-		return nil, err
+	invites, err := s.invites.List(ctx, workspaceID, query, q)
+	if err != nil {
+		return nil, "", err
 	}
 
+	invites, more := splitPage(invites, p.limit)
+
 	responses := make([]*models.WorkspaceInviteResponse, len(invites))
 	for i, inv := range invites {
 		responses[i] = inv.ToResponse()
 	}
 
-	return responses, nil
+	if !more {
+		return responses, "", nil
+	}
+	last := invites[len(invites)-1]
+	if p.sort == models.SortByName {
+		return responses, p.cursorAfter(last.Email, last.ID), nil
+	}
+	return responses, p.cursorAfter(last.CreatedAt, last.ID), nil
 }
 
 // GetInviteDetails retrieves invite details with workspace and inviter names
@@ -276,21 +312,13 @@ func (s *InviteService) GetInviteDetails(ctx context.Context, token string) (*mo
 	}
 
 	// Get workspace name
-	workspaceCollection := database.GetCollection("workspaces")
-	var workspace struct {
-		Name string `bson:"name"`
-	}
-	err = workspaceCollection.FindOne(ctx, bson.M{"_id": invite.WorkspaceID}).Decode(&workspace)
+	workspace, err := s.workspaces.Get(ctx, invite.WorkspaceID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get inviter name
-	usersCollection := database.GetCollection("users")
-	var inviter struct {
-		Name string `bson:"name"`
-	}
-	err = usersCollection.FindOne(ctx, bson.M{"_id": invite.InvitedBy}).Decode(&inviter)
+	inviter, err := s.users.Get(ctx, invite.InvitedBy)
 	if err != nil {
 		return nil, err
 	}
@@ -306,125 +334,40 @@ func (s *InviteService) GetInviteDetails(ctx context.Context, token string) (*mo
 
 // DeleteAllInvites removes all invites for a workspace (used when deleting workspace)
 func (s *InviteService) DeleteAllInvites(ctx context.Context, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	_, err := collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
-	return err
+	return s.invites.DeleteAllForWorkspace(ctx, workspaceID)
 }
 
-// ListUserInvites lists all pending invites for a user's email
+// ListUserInvites lists all pending invites for a user's email, newest first.
+// Invites whose workspace or inviter no longer exists are skipped.
 func (s *InviteService) ListUserInvites(ctx context.Context, email string) ([]*models.WorkspaceInviteResponse, error) {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	// Aggregate to join with workspaces collection for workspace name
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{
-			"email":      email,
-			"expires_at": bson.M{"$gt": time.Now()},
-		}}},
-		{{Key: "$lookup", Value: bson.M{
-			"from":         "workspaces",
-			"localField":   "workspace_id",
-			"foreignField": "_id",
-			"as":           "workspace",
-		}}},
-		{{Key: "$unwind", Value: "$workspace"}},
-		{{Key: "$lookup", Value: bson.M{
-			"from":         "users",
-			"localField":   "invited_by",
-			"foreignField": "_id",
-			"as":           "inviter",
-		}}},
-		{{Key: "$unwind", Value: "$inviter"}},
-		{{Key: "$project", Value: bson.M{
-			"_id":            1,
-			"workspace_id":   1,
-			"workspace_name": "$workspace.name",
-			"email":          1,
-			"role":           1,
-			"token":          1,
-			"invited_by":     1,
-			"inviter_name":   "$inviter.name",
-			"expires_at":     1,
-			"created_at":     1,
-		}}},
-		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
-	}
-
-	cursor, err := collection.Aggregate(ctx, pipeline)
+	invites, err := s.invites.ListForEmail(ctx, email, time.Now())
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var results []struct {
-		ID            primitive.ObjectID   `bson:"_id"`
-		WorkspaceID   primitive.ObjectID   `bson:"workspace_id"`
-		WorkspaceName string               `bson:"workspace_name"`
-		Email         string               `bson:"email"`
-		Role          models.WorkspaceRole `bson:"role"`
-		Token         string               `bson:"token"`
-		InvitedBy     primitive.ObjectID   `bson:"invited_by"`
-		InviterName   string               `bson:"inviter_name"`
-		ExpiresAt     time.Time            `bson:"expires_at"`
-		CreatedAt     time.Time            `bson:"created_at"`
-	}
-
-	if err := cursor.All(ctx, &results); err != nil {
-		return nil, err
-	}
-
-	responses := make([]*models.WorkspaceInviteResponse, len(results))
-	for i, r := range results {
-		responses[i] = &models.WorkspaceInviteResponse{
-			ID:            r.ID,
-			WorkspaceID:   r.WorkspaceID,
-			WorkspaceName: r.WorkspaceName,
-			Email:         r.Email,
-			Role:          r.Role,
-			Token:         r.Token,
-			InvitedBy:     r.InvitedBy,
-			InviterName:   r.InviterName,
-			ExpiresAt:     r.ExpiresAt,
-			CreatedAt:     r.CreatedAt,
+	responses := make([]*models.WorkspaceInviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		workspace, err := s.workspaces.Get(ctx, invite.WorkspaceID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		inviter, err := s.users.Get(ctx, invite.InvitedBy)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
 		}
+
+		response := invite.ToResponse()
+		response.Token = invite.Token
+		response.WorkspaceName = workspace.Name
+		response.InviterName = inviter.Name
+		responses = append(responses, response)
 	}
 
 	return responses, nil
 }
-
-// EnsureIndexes creates necessary indexes for the workspace_invites collection
-func (s *InviteService) EnsureIndexes(ctx context.Context) error {
-	collection := database.GetCollection("workspace_invites")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	indexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "token", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys: bson.D{{Key: "email", Value: 1}},
-		},
-		{
-			// TTL index for auto-expiry
-			Keys:    bson.D{{Key: "expires_at", Value: 1}},
-			Options: options.Index().SetExpireAfterSeconds(0),
-		},
-	}
-
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
-}
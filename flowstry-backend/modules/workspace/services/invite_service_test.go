@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+)
+
+func TestCreateInvite(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	invite, err := tw.invites.CreateInvite(ctx, tw.id, "new@example.com", models.RoleEditor, tw.admin.ID)
+	if err != nil {
+		t.Fatalf("CreateInvite: %v", err)
+	}
+	if invite.Token == "" || !invite.ExpiresAt.After(time.Now()) {
+		t.Errorf("invite has token %q and expiry %v", invite.Token, invite.ExpiresAt)
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		role    models.WorkspaceRole
+		wantErr error
+	}{
+		{"duplicate", "new@example.com", models.RoleViewer, ErrInviteAlreadyExists},
+		{"already a member", tw.viewer.Email, models.RoleEditor, ErrUserAlreadyMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tw.invites.CreateInvite(ctx, tw.id, tt.email, tt.role, tw.admin.ID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	for _, role := range []models.WorkspaceRole{models.RoleOwner, "superuser"} {
+		if _, err := tw.invites.CreateInvite(ctx, tw.id, "other@example.com", role, tw.admin.ID); err == nil {
+			t.Errorf("inviting as %q succeeded", role)
+		}
+	}
+}
+
+func TestAcceptInvite(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	invite, err := tw.invites.CreateInvite(ctx, tw.id, tw.outsider.Email, models.RoleEditor, tw.owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := tw.invites.ListUserInvites(ctx, tw.outsider.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].WorkspaceName != "Team" || pending[0].InviterName != tw.owner.Name {
+		t.Fatalf("ListUserInvites = %+v", pending)
+	}
+
+	if _, err := tw.invites.AcceptInvite(ctx, invite.Token, tw.viewer.ID, tw.viewer.Email); err == nil {
+		t.Error("accepting someone else's invite succeeded")
+	}
+
+	member, err := tw.invites.AcceptInvite(ctx, invite.Token, tw.outsider.ID, tw.outsider.Email)
+	if err != nil {
+		t.Fatalf("AcceptInvite: %v", err)
+	}
+	if member.Role != models.RoleEditor {
+		t.Errorf("member role = %q, want %q", member.Role, models.RoleEditor)
+	}
+	if !tw.members.CanEdit(ctx, tw.id, tw.outsider.ID) {
+		t.Error("accepted editor cannot edit")
+	}
+
+	// The invite is used up
+	if _, err := tw.invites.AcceptInvite(ctx, invite.Token, tw.outsider.ID, tw.outsider.Email); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("accepting twice: err = %v, want %v", err, ErrInviteNotFound)
+	}
+}
+
+func TestAcceptExpiredInvite(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	expired := &models.WorkspaceInvite{
+		WorkspaceID: tw.id,
+		Email:       tw.outsider.Email,
+		Role:        models.RoleViewer,
+		Token:       "expired-token",
+		InvitedBy:   tw.owner.ID,
+		ExpiresAt:   time.Now().Add(-time.Hour),
+		CreatedAt:   time.Now().Add(-8 * 24 * time.Hour),
+	}
+	if err := tw.store.Invites.Create(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, _ := tw.invites.ListUserInvites(ctx, tw.outsider.Email); len(pending) != 0 {
+		t.Errorf("expired invite is listed as pending")
+	}
+	if _, err := tw.invites.AcceptInvite(ctx, expired.Token, tw.outsider.ID, tw.outsider.Email); !errors.Is(err, ErrInviteExpired) {
+		t.Fatalf("err = %v, want %v", err, ErrInviteExpired)
+	}
+	if tw.members.HasAccess(ctx, tw.id, tw.outsider.ID) {
+		t.Error("expired invite granted access")
+	}
+	// Accepting an expired invite deletes it
+	if _, err := tw.invites.GetInviteByToken(ctx, expired.Token); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("expired invite still stored: %v", err)
+	}
+}
+
+func TestRevokeInvite(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	invite, err := tw.invites.CreateInvite(ctx, tw.id, "new@example.com", models.RoleViewer, tw.owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An invite can only be revoked through its own workspace
+	other, err := tw.workspaces.Create(ctx, tw.outsider.ID, &models.CreateWorkspaceRequest{Name: "Other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.invites.RevokeInvite(ctx, invite.ID, other.ID); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("revoking from another workspace: err = %v, want %v", err, ErrInviteNotFound)
+	}
+
+	if err := tw.invites.RevokeInvite(ctx, invite.ID, tw.id); err != nil {
+		t.Fatalf("RevokeInvite: %v", err)
+	}
+	if _, err := tw.invites.AcceptInvite(ctx, invite.Token, tw.outsider.ID, "new@example.com"); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("accepting a revoked invite: err = %v, want %v", err, ErrInviteNotFound)
+	}
+}
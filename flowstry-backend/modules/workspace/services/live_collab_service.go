@@ -6,15 +6,14 @@ import (
 	"strings"
 	"time"
 
-	"github.com/flowstry/flowstry-backend/database"
-	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/repository"
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // LiveCollabService integrates the backend with the live-collab service.
 type LiveCollabService struct {
+	users       repository.UserRepository
 	baseURL     string
 	wsURL       string
 	jwtSecret   []byte
@@ -23,7 +22,7 @@ type LiveCollabService struct {
 }
 
 // NewLiveCollabService creates a new live collaboration service client.
-func NewLiveCollabService(baseURL, wsURL, jwtSecret, jwtIssuer, jwtAudience string) *LiveCollabService {
+func NewLiveCollabService(users repository.UserRepository, baseURL, wsURL, jwtSecret, jwtIssuer, jwtAudience string) *LiveCollabService {
 	if baseURL != "" {
 		baseURL = strings.TrimRight(baseURL, "/")
 	}
@@ -31,6 +30,7 @@ func NewLiveCollabService(baseURL, wsURL, jwtSecret, jwtIssuer, jwtAudience stri
 		wsURL = deriveWSURL(baseURL)
 	}
 	return &LiveCollabService{
+		users:       users,
 		baseURL:     baseURL,
 		wsURL:       strings.TrimRight(wsURL, "/"),
 		jwtSecret:   []byte(jwtSecret),
@@ -46,13 +46,8 @@ func (s *LiveCollabService) GetWSURL() string {
 
 // GetUserProfile returns the user's display name and avatar URL.
 func (s *LiveCollabService) GetUserProfile(ctx context.Context, userID primitive.ObjectID) (string, string, error) {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return "", "", errors.New("database not connected")
-	}
-
-	var user authModels.User
-	if err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
 		return "", "", err
 	}
 
@@ -3,15 +3,13 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
-	"github.com/flowstry/flowstry-backend/database"
 	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/flowstry/flowstry-backend/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -24,20 +22,36 @@ var (
 )
 
 // MemberService handles workspace member operations
-type MemberService struct{}
+type MemberService struct {
+	members    repository.MemberRepository
+	workspaces repository.WorkspaceRepository
+	users      repository.UserRepository
+	events     *EventBus
+}
 
 // NewMemberService creates a new member service
-func NewMemberService() *MemberService {
-	return &MemberService{}
+func NewMemberService(members repository.MemberRepository, workspaces repository.WorkspaceRepository, users repository.UserRepository) *MemberService {
+	return &MemberService{
+		members:    members,
+		workspaces: workspaces,
+		users:      users,
+	}
 }
 
-// AddMember adds a user as a member of a workspace
-func (s *MemberService) AddMember(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.WorkspaceRole) (*models.WorkspaceMember, error) {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *MemberService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// emit publishes a membership change; members are identified by their user ID
+func (s *MemberService) emit(ctx context.Context, workspaceID, userID primitive.ObjectID, eventType models.EventType, before, after map[string]string) {
+	if s.events != nil {
+		s.events.Emit(ctx, workspaceID, eventType, models.TargetMember, userID, before, after)
 	}
+}
 
+// AddMember adds a user as a member of a workspace
+func (s *MemberService) AddMember(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.WorkspaceRole) (*models.WorkspaceMember, error) {
 	if role == models.RoleOwner {
 		ownerID, err := s.getWorkspaceOwnerID(ctx, workspaceID)
 		if err != nil {
@@ -61,124 +75,103 @@ func (s *MemberService) AddMember(ctx context.Context, workspaceID, userID primi
 		JoinedAt:    time.Now(),
 	}
 
-	result, err := collection.InsertOne(ctx, member)
-	if err != nil {
+	if err := s.members.Create(ctx, member); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrMemberAlreadyExists
+		}
 		return nil, err
 	}
 
-	member.ID = result.InsertedID.(primitive.ObjectID)
+	s.emit(ctx, workspaceID, userID, models.EventMemberAdded, nil, map[string]string{"role": string(role)})
 	return member, nil
 }
 
 // RemoveMember removes a user from a workspace
 func (s *MemberService) RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
 	// Cannot remove owner
 	ownerID, err := s.getWorkspaceOwnerID(ctx, workspaceID)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return err
 	}
 	if err == nil && ownerID == userID {
 		return ErrCannotRemoveOwner
 	}
 
-	result, err := collection.DeleteOne(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"user_id":      userID,
-	})
+	member, err := s.GetMember(ctx, workspaceID, userID)
 	if err != nil {
 		return err
 	}
-	if result.DeletedCount == 0 {
+
+	err = s.members.Delete(ctx, workspaceID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return ErrMemberNotFound
 	}
+	if err != nil {
+		return err
+	}
 
+	s.emit(ctx, workspaceID, userID, models.EventMemberRemoved, map[string]string{"role": string(member.Role)}, nil)
 	return nil
 }
 
 // GetMember retrieves a specific member record
 func (s *MemberService) GetMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	var member models.WorkspaceMember
-	err := collection.FindOne(ctx, bson.M{
-		"workspace_id": workspaceID,
-		"user_id":      userID,
-	}).Decode(&member)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrMemberNotFound
-		}
-		return nil, err
+	member, err := s.members.Get(ctx, workspaceID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMemberNotFound
 	}
+	return member, err
+}
 
-	return &member, nil
+// memberPageSpec lists the sort options for member listings
+var memberPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByName, models.SortByCreated},
+	defaultSort:  models.SortByCreated,
+	defaultOrder: models.SortAsc,
+	unbounded:    true,
 }
 
-// ListMembers lists all members of a workspace with user details
-func (s *MemberService) ListMembers(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceMemberResponse, error) {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	// Aggregate to join with users collection
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"workspace_id": workspaceID}}},
-		{{Key: "$lookup", Value: bson.M{
-			"from":         "users",
-			"localField":   "user_id",
-			"foreignField": "_id",
-			"as":           "user",
-		}}},
-		{{Key: "$unwind", Value: "$user"}},
-		{{Key: "$project", Value: bson.M{
-			"_id":        1,
-			"user_id":    1,
-			"role":       1,
-			"joined_at":  1,
-			"email":      "$user.email",
-			"name":       "$user.name",
-			"avatar_url": "$user.avatar_url",
-		}}},
-		{{Key: "$sort", Value: bson.D{
-			{Key: "role", Value: 1}, // owner first, then admin, etc.
-			{Key: "joined_at", Value: 1},
-		}}},
-	}
-
-	cursor, err := collection.Aggregate(ctx, pipeline)
+// ListMembers lists one page of members of a workspace with user details.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *MemberService) ListMembers(ctx context.Context, workspaceID primitive.ObjectID, filter *models.MemberFilter, page *models.PageRequest) ([]*models.WorkspaceMemberResponse, string, error) {
+	p, err := memberPageSpec.resolve(page)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cursor.Close(ctx)
-
-	var results []struct {
-		ID        primitive.ObjectID   `bson:"_id"`
-		UserID    primitive.ObjectID   `bson:"user_id"`
-		Role      models.WorkspaceRole `bson:"role"`
-		JoinedAt  time.Time            `bson:"joined_at"`
-		Email     string               `bson:"email"`
-		Name      string               `bson:"name"`
-		AvatarURL string               `bson:"avatar_url"`
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
 	}
 
-	if err := cursor.All(ctx, &results); err != nil {
-		return nil, err
+	ownerID, err := s.getWorkspaceOwnerID(ctx, workspaceID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, "", err
+	}
+
+	query := &repository.MemberQuery{}
+	if filter != nil {
+		// The workspace owner is authoritative; a stale "owner" role on anyone else reads as admin
+		switch filter.Role {
+		case "":
+		case models.RoleOwner:
+			query.UserID = &ownerID
+		case models.RoleAdmin:
+			query.Roles = []models.WorkspaceRole{models.RoleAdmin, models.RoleOwner}
+			query.ExcludeUserID = &ownerID
+		default:
+			query.Roles = []models.WorkspaceRole{filter.Role}
+		}
+		query.Joined = filter.Joined
+		query.Prefix = strings.TrimSpace(filter.Query)
 	}
 
-	ownerID, err := s.getWorkspaceOwnerID(ctx, workspaceID)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
-		return nil, err
+	results, err := s.members.List(ctx, workspaceID, query, q)
+	if err != nil {
+		return nil, "", err
 	}
 
+	results, more := splitPage(results, p.limit)
+
 	members := make([]*models.WorkspaceMemberResponse, len(results))
 	for i, r := range results {
 		role := r.Role
@@ -198,26 +191,26 @@ func (s *MemberService) ListMembers(ctx context.Context, workspaceID primitive.O
 		}
 	}
 
-	return members, nil
+	if !more {
+		return members, "", nil
+	}
+	last := members[len(members)-1]
+	var next string
+	if p.sort == models.SortByName {
+		next = p.cursorAfter(last.Name, last.ID)
+	} else {
+		next = p.cursorAfter(last.JoinedAt, last.ID)
+	}
+	return members, next, nil
 }
 
 // CountMembers returns the number of members in a workspace.
 func (s *MemberService) CountMembers(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return 0, errors.New("database not connected")
-	}
-
-	return collection.CountDocuments(ctx, bson.M{"workspace_id": workspaceID})
+	return s.members.Count(ctx, workspaceID)
 }
 
 // UpdateRole updates a member's role
 func (s *MemberService) UpdateRole(ctx context.Context, workspaceID, userID primitive.ObjectID, newRole models.WorkspaceRole, actorID primitive.ObjectID) error {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
 	if newRole == models.RoleOwner {
 		return ErrCannotAssignOwner
 	}
@@ -228,7 +221,7 @@ func (s *MemberService) UpdateRole(ctx context.Context, workspaceID, userID prim
 	}
 
 	ownerID, err := s.getWorkspaceOwnerID(ctx, workspaceID)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return err
 	}
 	if err == nil && ownerID == userID {
@@ -255,13 +248,20 @@ func (s *MemberService) UpdateRole(ctx context.Context, workspaceID, userID prim
 		return ErrInsufficientRole
 	}
 
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"workspace_id": workspaceID, "user_id": userID},
-		bson.M{"$set": bson.M{"role": newRole}},
-	)
+	err = s.members.UpdateRole(ctx, workspaceID, userID, newRole)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	if member.Role != newRole {
+		s.emit(ctx, workspaceID, userID, models.EventMemberRoleChanged,
+			map[string]string{"role": string(member.Role)},
+			map[string]string{"role": string(newRole)})
+	}
+	return nil
 }
 
 // GetUserRole returns the user's role in a workspace (empty string if not a member)
@@ -270,7 +270,7 @@ func (s *MemberService) GetUserRole(ctx context.Context, workspaceID, userID pri
 	if err == nil && ownerID == userID {
 		return models.RoleOwner
 	}
-	if err != nil && errors.Is(err, mongo.ErrNoDocuments) {
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return ""
 	}
 
@@ -285,13 +285,7 @@ func (s *MemberService) GetUserRole(ctx context.Context, workspaceID, userID pri
 }
 
 func (s *MemberService) getWorkspaceOwnerID(ctx context.Context, workspaceID primitive.ObjectID) (primitive.ObjectID, error) {
-	workspaceCollection := database.GetCollection("workspaces")
-	if workspaceCollection == nil {
-		return primitive.NilObjectID, errors.New("database not connected")
-	}
-
-	var workspace models.Workspace
-	err := workspaceCollection.FindOne(ctx, bson.M{"_id": workspaceID}).Decode(&workspace)
+	workspace, err := s.workspaces.Get(ctx, workspaceID)
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
@@ -341,51 +335,18 @@ func (s *MemberService) CanDelete(ctx context.Context, workspaceID, userID primi
 
 // DeleteAllMembers removes all members from a workspace (used when deleting workspace)
 func (s *MemberService) DeleteAllMembers(ctx context.Context, workspaceID primitive.ObjectID) error {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	_, err := collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
-	return err
+	return s.members.DeleteAllForWorkspace(ctx, workspaceID)
 }
 
 // GetUserByEmail retrieves a user by their email
 func (s *MemberService) GetUserByEmail(ctx context.Context, email string) (*authModels.User, error) {
-	collection := database.GetCollection("users")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	var user authModels.User
-	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	user, err := s.users.GetByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, nil // User not found is not an error
 		}
 		return nil, err
 	}
 
-	return &user, nil
-}
-
-// EnsureIndexes creates necessary indexes for the workspace_members collection
-func (s *MemberService) EnsureIndexes(ctx context.Context) error {
-	collection := database.GetCollection("workspace_members")
-	if collection == nil {
-		return errors.New("database not connected")
-	}
-
-	indexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys: bson.D{{Key: "user_id", Value: 1}},
-		},
-	}
-
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
+	return user, nil
 }
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/repository/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testWorkspace is a workspace with one member of each role, backed by the in-memory store
+type testWorkspace struct {
+	store      *repository.Store
+	members    *MemberService
+	workspaces *WorkspaceService
+	invites    *InviteService
+	audit      *AuditService
+
+	id                                     primitive.ObjectID
+	owner, admin, editor, viewer, outsider *authModels.User
+}
+
+func newTestWorkspace(t *testing.T) *testWorkspace {
+	t.Helper()
+	ctx := context.Background()
+
+	store := memory.New()
+	tw := &testWorkspace{store: store}
+	tw.members = NewMemberService(store.Members, store.Workspaces, store.Users)
+	tw.invites = NewInviteService(store.Invites, store.Workspaces, store.Users, tw.members)
+	tw.workspaces = NewWorkspaceService(store.Workspaces, store.Members, store.Folders, store.Diagrams)
+	tw.workspaces.SetMemberService(tw.members)
+	tw.workspaces.SetInviteService(tw.invites)
+	tw.audit = NewAuditService(store.Audit)
+	events := NewEventBus()
+	events.Subscribe(tw.audit.Record)
+	tw.members.SetEventBus(events)
+	tw.invites.SetEventBus(events)
+	tw.workspaces.SetEventBus(events)
+
+	tw.owner = tw.addUser(t, "owner@example.com")
+	tw.admin = tw.addUser(t, "admin@example.com")
+	tw.editor = tw.addUser(t, "editor@example.com")
+	tw.viewer = tw.addUser(t, "viewer@example.com")
+	tw.outsider = tw.addUser(t, "outsider@example.com")
+
+	workspace, err := tw.workspaces.Create(ctx, tw.owner.ID, &models.CreateWorkspaceRequest{Name: "Team"})
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	tw.id = workspace.ID
+
+	for user, role := range map[*authModels.User]models.WorkspaceRole{
+		tw.admin:  models.RoleAdmin,
+		tw.editor: models.RoleEditor,
+		tw.viewer: models.RoleViewer,
+	} {
+		if _, err := tw.members.AddMember(ctx, tw.id, user.ID, role); err != nil {
+			t.Fatalf("add %s: %v", role, err)
+		}
+	}
+	return tw
+}
+
+func (tw *testWorkspace) addUser(t *testing.T, email string) *authModels.User {
+	t.Helper()
+	user := &authModels.User{Email: email, Name: email, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := tw.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return user
+}
+
+// user returns the fixture user playing a part
+func (tw *testWorkspace) user(part string) *authModels.User {
+	return map[string]*authModels.User{
+		"owner":    tw.owner,
+		"admin":    tw.admin,
+		"editor":   tw.editor,
+		"viewer":   tw.viewer,
+		"outsider": tw.outsider,
+	}[part]
+}
+
+func TestMemberPermissions(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name                                           string
+		user                                           *authModels.User
+		role                                           models.WorkspaceRole
+		view, edit, create, deleteContent, manage, del bool
+	}{
+		{"owner", tw.owner, models.RoleOwner, true, true, true, true, true, true},
+		{"admin", tw.admin, models.RoleAdmin, true, true, true, true, true, false},
+		{"editor", tw.editor, models.RoleEditor, true, true, true, false, false, false},
+		{"viewer", tw.viewer, models.RoleViewer, true, false, false, false, false, false},
+		{"outsider", tw.outsider, "", false, false, false, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := tt.user.ID
+			if got := tw.members.GetUserRole(ctx, tw.id, id); got != tt.role {
+				t.Errorf("GetUserRole = %q, want %q", got, tt.role)
+			}
+			checks := []struct {
+				name string
+				got  bool
+				want bool
+			}{
+				{"CanView", tw.members.CanView(ctx, tw.id, id), tt.view},
+				{"CanEdit", tw.members.CanEdit(ctx, tw.id, id), tt.edit},
+				{"CanCreate", tw.members.CanCreate(ctx, tw.id, id), tt.create},
+				{"CanDeleteContent", tw.members.CanDeleteContent(ctx, tw.id, id), tt.deleteContent},
+				{"CanManageMembers", tw.members.CanManageMembers(ctx, tw.id, id), tt.manage},
+				{"CanDelete", tw.members.CanDelete(ctx, tw.id, id), tt.del},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStaleOwnerRoleReadsAsAdmin(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	// Only the workspace's user_id is the owner; a leftover "owner" member record is an admin
+	if err := tw.store.Members.UpdateRole(ctx, tw.id, tw.editor.ID, models.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	if got := tw.members.GetUserRole(ctx, tw.id, tw.editor.ID); got != models.RoleAdmin {
+		t.Errorf("GetUserRole = %q, want %q", got, models.RoleAdmin)
+	}
+	if tw.members.CanDelete(ctx, tw.id, tw.editor.ID) {
+		t.Error("stale owner can delete the workspace")
+	}
+}
+
+func TestAddMember(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	if _, err := tw.members.AddMember(ctx, tw.id, tw.viewer.ID, models.RoleEditor); !errors.Is(err, ErrMemberAlreadyExists) {
+		t.Errorf("re-adding a member: err = %v, want %v", err, ErrMemberAlreadyExists)
+	}
+	if _, err := tw.members.AddMember(ctx, tw.id, tw.outsider.ID, models.RoleOwner); !errors.Is(err, ErrCannotAssignOwner) {
+		t.Errorf("adding a second owner: err = %v, want %v", err, ErrCannotAssignOwner)
+	}
+}
+
+func TestUpdateRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   string
+		target  string
+		role    models.WorkspaceRole
+		wantErr error
+	}{
+		{"owner promotes editor to admin", "owner", "editor", models.RoleAdmin, nil},
+		{"owner demotes admin", "owner", "admin", models.RoleViewer, nil},
+		{"admin changes viewer to editor", "admin", "viewer", models.RoleEditor, nil},
+		{"admin cannot promote to admin", "admin", "editor", models.RoleAdmin, ErrInsufficientRole},
+		{"admin cannot change owner", "admin", "owner", models.RoleViewer, ErrCannotRemoveOwner},
+		{"nobody assigns owner", "owner", "admin", models.RoleOwner, ErrCannotAssignOwner},
+		{"cannot change own role", "admin", "admin", models.RoleViewer, ErrCannotChangeOwnRole},
+		{"outsider has no say", "outsider", "viewer", models.RoleEditor, ErrInsufficientRole},
+		{"unknown member", "owner", "outsider", models.RoleEditor, ErrMemberNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := newTestWorkspace(t)
+			ctx := context.Background()
+			actor, target := tw.user(tt.actor), tw.user(tt.target)
+
+			err := tw.members.UpdateRole(ctx, tw.id, target.ID, tt.role, actor.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateRole err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				if got := tw.members.GetUserRole(ctx, tw.id, target.ID); got != tt.role {
+					t.Errorf("role after update = %q, want %q", got, tt.role)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	if err := tw.members.RemoveMember(ctx, tw.id, tw.owner.ID); !errors.Is(err, ErrCannotRemoveOwner) {
+		t.Errorf("removing the owner: err = %v, want %v", err, ErrCannotRemoveOwner)
+	}
+	if err := tw.members.RemoveMember(ctx, tw.id, tw.editor.ID); err != nil {
+		t.Fatalf("removing an editor: %v", err)
+	}
+	if tw.members.HasAccess(ctx, tw.id, tw.editor.ID) {
+		t.Error("removed editor still has access")
+	}
+	if err := tw.members.RemoveMember(ctx, tw.id, tw.editor.ID); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("removing twice: err = %v, want %v", err, ErrMemberNotFound)
+	}
+}
+
+// Listings that returned everything before pagination still do when no limit or cursor is given
+func TestListMembersWithoutLimit(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+	for i := 0; i < int(models.DefaultPageSize); i++ {
+		user := tw.addUser(t, fmt.Sprintf("member%02d@example.com", i))
+		if _, err := tw.members.AddMember(ctx, tw.id, user.ID, models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := int(models.DefaultPageSize) + 4
+
+	all, next, err := tw.members.ListMembers(ctx, tw.id, nil, nil)
+	if err != nil || len(all) != total || next != "" {
+		t.Fatalf("without a limit got %d members (next %q, err %v), want %d", len(all), next, err, total)
+	}
+
+	// Following a cursor without a limit uses the default page size
+	_, next, err = tw.members.ListMembers(ctx, tw.id, nil, &models.PageRequest{Limit: 1})
+	if err != nil || next == "" {
+		t.Fatalf("first page next = %q, err %v", next, err)
+	}
+	rest, next, err := tw.members.ListMembers(ctx, tw.id, nil, &models.PageRequest{Cursor: next})
+	if err != nil || int64(len(rest)) != models.DefaultPageSize || next == "" {
+		t.Errorf("cursor without a limit got %d members (next %q, err %v), want %d", len(rest), next, err, models.DefaultPageSize)
+	}
+}
+
+func TestListMembers(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	all, next, err := tw.members.ListMembers(ctx, tw.id, nil, &models.PageRequest{Sort: models.SortByName, Order: models.SortAsc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || next != "" {
+		t.Fatalf("got %d members (next %q), want 4 on one page", len(all), next)
+	}
+	wantOrder := []string{"admin@example.com", "editor@example.com", "owner@example.com", "viewer@example.com"}
+	for i, m := range all {
+		if m.Name != wantOrder[i] {
+			t.Errorf("member %d = %s, want %s", i, m.Name, wantOrder[i])
+		}
+	}
+
+	// Paging by two walks the same list
+	var paged []*models.WorkspaceMemberResponse
+	page := &models.PageRequest{Limit: 2, Sort: models.SortByName, Order: models.SortAsc}
+	for {
+		members, next, err := tw.members.ListMembers(ctx, tw.id, nil, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paged = append(paged, members...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	if len(paged) != len(all) {
+		t.Fatalf("paged through %d members, want %d", len(paged), len(all))
+	}
+	for i := range paged {
+		if paged[i].ID != all[i].ID {
+			t.Errorf("page item %d = %s, want %s", i, paged[i].Name, all[i].Name)
+		}
+	}
+
+	owners, _, err := tw.members.ListMembers(ctx, tw.id, &models.MemberFilter{Role: models.RoleOwner}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || owners[0].UserID != tw.owner.ID {
+		t.Errorf("owner filter returned %d members, want the owner only", len(owners))
+	}
+}
+
+func TestSearchMembers(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	found, _, err := tw.members.ListMembers(ctx, tw.id, &models.MemberFilter{Query: " AD "},
+		&models.PageRequest{Sort: models.SortByName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].UserID != tw.admin.ID {
+		t.Errorf("search for ad = %+v", found)
+	}
+
+	// Non-members are never found
+	if found, _, _ := tw.members.ListMembers(ctx, tw.id, &models.MemberFilter{Query: "outsider"}, nil); len(found) != 0 {
+		t.Errorf("search for outsider = %+v", found)
+	}
+}
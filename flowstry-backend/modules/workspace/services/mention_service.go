@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxMentions caps the people one text can mention
+	maxMentions = 50
+	// mentionScanTimeout bounds a single background scan of a diagram's shape text
+	mentionScanTimeout = 2 * time.Minute
+)
+
+// mentionPattern matches @[Label](userID) as inserted from the member search, and a typed @email.
+// A typed email must not follow a word character, so plain addresses are not mentions.
+var mentionPattern = regexp.MustCompile(`@\[[^\]\n]*\]\(([0-9a-fA-F]{24})\)|(?:^|[^\w.+-])@([\w.%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+// parseMentions returns the user IDs and emails a text mentions, without repeats
+func parseMentions(text string) ([]primitive.ObjectID, []string) {
+	var ids []primitive.ObjectID
+	var emails []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if len(ids)+len(emails) >= maxMentions {
+			break
+		}
+		if match[1] != "" {
+			id, err := primitive.ObjectIDFromHex(match[1])
+			if err != nil || seen[id.Hex()] {
+				continue
+			}
+			seen[id.Hex()] = true
+			ids = append(ids, id)
+			continue
+		}
+		email := strings.ToLower(match[2])
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return ids, emails
+}
+
+// MentionService resolves @mentions against the members of a workspace, records who is mentioned
+// where and emits an event for every newly mentioned member
+type MentionService struct {
+	mentions       repository.MentionRepository
+	users          repository.UserRepository
+	memberService  *MemberService
+	contentService *ContentService
+	scanSlots      chan struct{}
+	events         *EventBus
+}
+
+// NewMentionService creates a new mention service
+func NewMentionService(mentions repository.MentionRepository, users repository.UserRepository, memberService *MemberService, contentService *ContentService) *MentionService {
+	return &MentionService{
+		mentions:       mentions,
+		users:          users,
+		memberService:  memberService,
+		contentService: contentService,
+		scanSlots:      make(chan struct{}, maxConcurrentIndexing),
+	}
+}
+
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *MentionService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// Resolve parses the mentions of a text. Mentions of members are returned by user ID; mentioned
+// emails of people outside the workspace are returned as invitable.
+func (s *MentionService) Resolve(ctx context.Context, workspaceID primitive.ObjectID, text string) (*models.MentionResult, error) {
+	ids, emails := parseMentions(text)
+	result := &models.MentionResult{Mentioned: []primitive.ObjectID{}}
+	seen := map[primitive.ObjectID]bool{}
+	add := func(userID primitive.ObjectID) {
+		if !seen[userID] {
+			seen[userID] = true
+			result.Mentioned = append(result.Mentioned, userID)
+		}
+	}
+
+	// Mentions of former members are dropped silently
+	for _, id := range ids {
+		if s.memberService.HasAccess(ctx, workspaceID, id) {
+			add(id)
+		}
+	}
+	for _, email := range emails {
+		user, err := s.users.GetByEmail(ctx, email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && s.memberService.HasAccess(ctx, workspaceID, user.ID) {
+			add(user.ID)
+			continue
+		}
+		result.Invitable = append(result.Invitable, email)
+	}
+	return result, nil
+}
+
+// Record stores the members a source mentions and emits EventMemberMentioned for each member
+// it did not mention before. Authors are never notified about mentioning themselves.
+func (s *MentionService) Record(ctx context.Context, workspaceID, diagramID primitive.ObjectID, source models.MentionSource, sourceID primitive.ObjectID, text string) (*models.MentionResult, error) {
+	result, err := s.Resolve(ctx, workspaceID, text)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.mentions.Replace(ctx, workspaceID, diagramID, source, sourceID, result.Mentioned, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	actor := actorFrom(ctx)
+	for _, userID := range added {
+		if s.events == nil || (actor != nil && actor.UserID == userID) {
+			continue
+		}
+		s.events.Emit(ctx, workspaceID, models.EventMemberMentioned, models.TargetMember, userID, nil, map[string]string{
+			"diagram_id": diagramID.Hex(),
+			"source":     string(source),
+			"source_id":  sourceID.Hex(),
+		})
+	}
+	return result, nil
+}
+
+// ScheduleContentScan records the mentions in a diagram's shape text in the background after a save.
+// Events are still attributed to the actor of ctx.
+func (s *MentionService) ScheduleContentScan(ctx context.Context, diagram *models.Diagram) {
+	if diagram == nil {
+		return
+	}
+	d := *diagram
+	actor := actorFrom(ctx)
+
+	go func() {
+		s.scanSlots <- struct{}{}
+		defer func() { <-s.scanSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), mentionScanTimeout)
+		defer cancel()
+		if actor != nil {
+			ctx = WithActor(ctx, actor)
+		}
+
+		if err := s.ScanContent(ctx, &d); err != nil {
+			fmt.Printf("Warning: Failed to record mentions of diagram %s: %v\n", d.ID.Hex(), err)
+		}
+	}()
+}
+
+// ScanContent records the mentions in a diagram's shape text
+func (s *MentionService) ScanContent(ctx context.Context, diagram *models.Diagram) error {
+	content, err := s.contentService.Read(ctx, diagram)
+	if err != nil {
+		if errors.Is(err, ErrNoDiagramContent) {
+			return nil
+		}
+		return err
+	}
+	text, err := extractSearchText(content)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Record(ctx, diagram.WorkspaceID, diagram.ID, models.MentionInContent, diagram.ID, text)
+	return err
+}
+
+// ListForDiagram returns who is mentioned on a diagram, oldest first
+func (s *MentionService) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Mention, error) {
+	mentions, err := s.mentions.ListForDiagram(ctx, workspaceID, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	if mentions == nil {
+		mentions = []*models.Mention{}
+	}
+	return mentions, nil
+}
+
+// DeleteForComments drops the mentions of deleted comments
+func (s *MentionService) DeleteForComments(ctx context.Context, workspaceID primitive.ObjectID, commentIDs []primitive.ObjectID) error {
+	return s.mentions.DeleteForSources(ctx, workspaceID, models.MentionInComment, commentIDs)
+}
+
+// DeleteForDiagram removes all mentions on a diagram (used on permanent deletion)
+func (s *MentionService) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return s.mentions.DeleteForDiagram(ctx, workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes all mentions of a workspace (used when deleting workspace)
+func (s *MentionService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.mentions.DeleteAllForWorkspace(ctx, workspaceID)
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseMentions(t *testing.T) {
+	id := primitive.NewObjectID()
+	text := fmt.Sprintf("@[Ann](%s) and @[Ann again](%s), ask @Bob@Example.com or mail bob@example.com; @bob@example.com",
+		id.Hex(), id.Hex())
+
+	ids, emails := parseMentions(text)
+	if !reflect.DeepEqual(ids, []primitive.ObjectID{id}) {
+		t.Errorf("ids = %v", ids)
+	}
+	if !reflect.DeepEqual(emails, []string{"bob@example.com"}) {
+		t.Errorf("emails = %v", emails)
+	}
+}
+
+func TestMentionNotifications(t *testing.T) {
+	tw, notifications, events := newTestNotifications(t)
+	mentions := NewMentionService(tw.store.Mentions, tw.store.Users, tw.members, nil)
+	mentions.SetEventBus(events)
+	ctx := WithActor(context.Background(), &models.Actor{UserID: tw.editor.ID})
+	diagram := tw.diagramBy(t, tw.editor.ID, "Checkout")
+	commentID := primitive.NewObjectID()
+
+	result, err := mentions.Record(ctx, tw.id, diagram.ID, models.MentionInComment, commentID,
+		fmt.Sprintf("@[Viewer](%s) @editor@example.com @outsider@example.com @new@example.com", tw.viewer.ID.Hex()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Mentioned, []primitive.ObjectID{tw.viewer.ID, tw.editor.ID}) {
+		t.Errorf("mentioned = %v", result.Mentioned)
+	}
+	if !reflect.DeepEqual(result.Invitable, []string{"outsider@example.com", "new@example.com"}) {
+		t.Errorf("invitable = %v", result.Invitable)
+	}
+
+	// Saving the same text again notifies nobody new; the author is never notified
+	if _, err := mentions.Record(ctx, tw.id, diagram.ID, models.MentionInComment, commentID,
+		fmt.Sprintf("@[Viewer](%s), see above", tw.viewer.ID.Hex())); err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(t, notifications, tw.viewer.ID); !reflect.DeepEqual(got, []string{"editor@example.com mentioned you in a comment on Checkout"}) {
+		t.Errorf("viewer notifications = %q", got)
+	}
+	if got := messages(t, notifications, tw.editor.ID); len(got) != 0 {
+		t.Errorf("author notifications = %q", got)
+	}
+
+	// Each text on the diagram is tracked on its own
+	if _, err := mentions.Record(ctx, tw.id, diagram.ID, models.MentionInDescription, diagram.ID,
+		"@admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(t, notifications, tw.admin.ID); !reflect.DeepEqual(got, []string{"editor@example.com mentioned you in Checkout"}) {
+		t.Errorf("admin notifications = %q", got)
+	}
+
+	listed, err := mentions.ListForDiagram(ctx, tw.id, diagram.ID)
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("mentions = %+v, %v", listed, err)
+	}
+	if err := mentions.DeleteForComments(ctx, tw.id, []primitive.ObjectID{commentID}); err != nil {
+		t.Fatal(err)
+	}
+	if listed, _ := mentions.ListForDiagram(ctx, tw.id, diagram.ID); len(listed) != 1 || listed[0].UserID != tw.admin.ID {
+		t.Errorf("mentions after deleting the comment = %+v", listed)
+	}
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/mailer"
+	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidNotificationType = errors.New("invalid notification type")
+)
+
+const (
+	// digestDelay leaves new notifications to the inbox for a while before they are emailed
+	digestDelay = time.Hour
+	// digestBatchSize is how many pending notifications one pass of SendDigests reads at a time
+	digestBatchSize = 500
+)
+
+// NotificationService turns workspace events into per-user notifications and serves users' inboxes
+type NotificationService struct {
+	notifications repository.NotificationRepository
+	users         repository.UserRepository
+	workspaces    repository.WorkspaceRepository
+	diagrams      repository.DiagramRepository
+	memberService *MemberService
+	mailer        *mailer.Mailer
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(notifications repository.NotificationRepository, users repository.UserRepository, workspaces repository.WorkspaceRepository, diagrams repository.DiagramRepository, memberService *MemberService) *NotificationService {
+	return &NotificationService{
+		notifications: notifications,
+		users:         users,
+		workspaces:    workspaces,
+		diagrams:      diagrams,
+		memberService: memberService,
+	}
+}
+
+// SetMailer sets the mailer that emails mentions and digests (for dependency injection)
+func (s *NotificationService) SetMailer(m *mailer.Mailer) {
+	s.mailer = m
+}
+
+// Record notifies the users an event concerns; subscribe it to the workspace EventBus.
+// Like the audit log, failures are logged because the change has already been made.
+func (s *NotificationService) Record(ctx context.Context, event *models.Event) {
+	if err := s.record(ctx, event); err != nil {
+		fmt.Printf("Warning: Failed to send notifications for %s on %s %s: %v\n", event.Type, event.TargetType, event.TargetID.Hex(), err)
+	}
+}
+
+func (s *NotificationService) record(ctx context.Context, event *models.Event) error {
+	var actorID *primitive.ObjectID
+	actorName := "Someone"
+	if event.Actor != nil {
+		actorID = &event.Actor.UserID
+		actorName = s.userName(ctx, event.Actor.UserID, event.Actor.Email)
+	}
+	// Nobody is notified about their own changes
+	notify := func(recipient primitive.ObjectID, notificationType models.NotificationType, message string) error {
+		if actorID != nil && *actorID == recipient {
+			return nil
+		}
+		return s.notify(ctx, recipient, notificationType, event, actorID, actorName, message)
+	}
+
+	switch event.Type {
+	case models.EventWorkspaceDeleted:
+		return s.notifications.DeleteAllForWorkspace(ctx, event.WorkspaceID)
+
+	case models.EventInviteCreated:
+		// Invitees without an account see the invite when they sign up
+		user, err := s.users.GetByEmail(ctx, event.After["email"])
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return notify(user.ID, models.NotificationInvite,
+			fmt.Sprintf("%s invited you to %s as %s", actorName, s.workspaceName(ctx, event.WorkspaceID), event.After["role"]))
+
+	case models.EventMemberRoleChanged:
+		return notify(event.TargetID, models.NotificationRoleChanged,
+			fmt.Sprintf("%s changed your role in %s to %s", actorName, s.workspaceName(ctx, event.WorkspaceID), event.After["role"]))
+
+	case models.EventMemberRemoved:
+		return notify(event.TargetID, models.NotificationMemberRemoved,
+			fmt.Sprintf("%s removed you from %s", actorName, s.workspaceName(ctx, event.WorkspaceID)))
+
+	case models.EventDiagramUpdated, models.EventDiagramDeleted:
+		diagram, err := s.diagrams.Get(ctx, event.WorkspaceID, event.TargetID, repository.AnyTrash)
+		if err != nil {
+			return err
+		}
+		// Creators who have since left the workspace are no longer told about it
+		if diagram.CreatedBy == nil || !s.memberService.HasAccess(ctx, event.WorkspaceID, *diagram.CreatedBy) {
+			return nil
+		}
+		if event.Type == models.EventDiagramDeleted {
+			return notify(*diagram.CreatedBy, models.NotificationDiagramDeleted,
+				fmt.Sprintf("%s moved %s to the trash", actorName, diagram.Name))
+		}
+		return notify(*diagram.CreatedBy, models.NotificationDiagramEdited,
+			fmt.Sprintf("%s edited %s", actorName, diagram.Name))
+
+	case models.EventMemberMentioned:
+		return s.notifyMention(ctx, event, actorID, actorName)
+	}
+	return nil
+}
+
+// notifyMention tells a member they were mentioned, in their inbox and by email.
+// The notification points at the diagram rather than the member.
+func (s *NotificationService) notifyMention(ctx context.Context, event *models.Event, actorID *primitive.ObjectID, actorName string) error {
+	userID := event.TargetID
+	if (actorID != nil && *actorID == userID) || !s.memberService.HasAccess(ctx, event.WorkspaceID, userID) {
+		return nil
+	}
+	diagramID, err := primitive.ObjectIDFromHex(event.After["diagram_id"])
+	if err != nil {
+		return err
+	}
+	diagram, err := s.diagrams.Get(ctx, event.WorkspaceID, diagramID, repository.AnyTrash)
+	if err != nil {
+		return err
+	}
+
+	where := diagram.Name
+	if models.MentionSource(event.After["source"]) == models.MentionInComment {
+		where = "a comment on " + diagram.Name
+	}
+	onDiagram := *event
+	onDiagram.TargetType = models.TargetDiagram
+	onDiagram.TargetID = diagram.ID
+	if err := s.notify(ctx, userID, models.NotificationMentioned, &onDiagram, actorID, actorName,
+		fmt.Sprintf("%s mentioned you in %s", actorName, where)); err != nil {
+		return err
+	}
+
+	if s.mailer == nil {
+		return nil
+	}
+	user, err := s.users.Get(ctx, userID)
+	if err != nil || isMuted(user.Notifications, models.NotificationMentioned) {
+		return err
+	}
+	return s.mailer.QueueMention(ctx, user.Email, actorName, where, s.workspaceName(ctx, event.WorkspaceID),
+		event.WorkspaceID.Hex(), diagram.ID.Hex())
+}
+
+// notify creates a notification unless the recipient muted its type.
+// An edit of a diagram the recipient has an unread edit notification about is folded into it.
+func (s *NotificationService) notify(ctx context.Context, userID primitive.ObjectID, notificationType models.NotificationType, event *models.Event, actorID *primitive.ObjectID, actorName, message string) error {
+	user, err := s.users.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if isMuted(user.Notifications, notificationType) {
+		return nil
+	}
+
+	if notificationType == models.NotificationDiagramEdited {
+		unread, err := s.notifications.FindUnread(ctx, userID, notificationType, event.TargetID)
+		if err == nil {
+			return s.notifications.Bump(ctx, userID, unread.ID, actorID, actorName, message, event.CreatedAt)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+
+	return s.notifications.Create(ctx, &models.Notification{
+		UserID:      userID,
+		WorkspaceID: event.WorkspaceID,
+		Type:        notificationType,
+		ActorID:     actorID,
+		ActorName:   actorName,
+		TargetType:  event.TargetType,
+		TargetID:    event.TargetID,
+		Message:     message,
+		Count:       1,
+		CreatedAt:   event.CreatedAt,
+		UpdatedAt:   event.CreatedAt,
+	})
+}
+
+func isMuted(prefs authModels.NotificationPreferences, notificationType models.NotificationType) bool {
+	for _, muted := range prefs.Muted {
+		if muted == string(notificationType) {
+			return true
+		}
+	}
+	return false
+}
+
+// userName returns a user's display name, falling back to their email
+func (s *NotificationService) userName(ctx context.Context, userID primitive.ObjectID, email string) string {
+	if user, err := s.users.Get(ctx, userID); err == nil {
+		if user.Name != "" {
+			return user.Name
+		}
+		return user.Email
+	}
+	return email
+}
+
+func (s *NotificationService) workspaceName(ctx context.Context, workspaceID primitive.ObjectID) string {
+	if workspace, err := s.workspaces.Get(ctx, workspaceID); err == nil {
+		return workspace.Name
+	}
+	return "a workspace"
+}
+
+// notificationPageSpec lists the sort options for notification listings
+var notificationPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByUpdated},
+	defaultSort:  models.SortByUpdated,
+	defaultOrder: models.SortDesc,
+}
+
+// List lists one page of a user's notifications, most recent first.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *NotificationService) List(ctx context.Context, userID primitive.ObjectID, filter *models.NotificationFilter, page *models.PageRequest) ([]*models.Notification, string, error) {
+	p, err := notificationPageSpec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
+	}
+
+	notifications, err := s.notifications.List(ctx, userID, filter, q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	notifications, more := splitPage(notifications, p.limit)
+	if !more {
+		return notifications, "", nil
+	}
+	last := notifications[len(notifications)-1]
+	return notifications, p.cursorAfter(last.UpdatedAt, last.ID), nil
+}
+
+// CountUnread counts a user's unread notifications
+func (s *NotificationService) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return s.notifications.CountUnread(ctx, userID)
+}
+
+// MarkRead marks some of a user's notifications read and returns how many were unread
+func (s *NotificationService) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	return s.notifications.MarkRead(ctx, userID, ids, time.Now())
+}
+
+// MarkAllRead marks every notification of a user read and returns how many were unread
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return s.notifications.MarkAllRead(ctx, userID, time.Now())
+}
+
+// GetPreferences returns a user's notification preferences
+func (s *NotificationService) GetPreferences(ctx context.Context, userID primitive.ObjectID) (*authModels.NotificationPreferences, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := user.Notifications
+	if prefs.Muted == nil {
+		prefs.Muted = []string{}
+	}
+	return &prefs, nil
+}
+
+// UpdatePreferences replaces the notification types a user has muted
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID primitive.ObjectID, req *models.NotificationPreferencesRequest) (*authModels.NotificationPreferences, error) {
+	prefs := authModels.NotificationPreferences{Muted: []string{}}
+	seen := make(map[models.NotificationType]bool)
+	for _, t := range req.Muted {
+		if !t.IsValid() {
+			return nil, ErrInvalidNotificationType
+		}
+		if !seen[t] {
+			seen[t] = true
+			prefs.Muted = append(prefs.Muted, string(t))
+		}
+	}
+
+	if err := s.users.UpdateNotificationPreferences(ctx, userID, prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// SendDigests emails each user a summary of their unread notifications that were not in a digest yet
+// and returns how many digests were queued. Mentions are left out because they were emailed already.
+func (s *NotificationService) SendDigests(ctx context.Context) (int, error) {
+	if s.mailer == nil {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-digestDelay)
+
+	sent := 0
+	for {
+		pending, err := s.notifications.PendingDigest(ctx, nil, cutoff, digestBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		full := len(pending) == digestBatchSize
+
+		var groups [][]*models.Notification
+		for i, n := range pending {
+			if i == 0 || n.UserID != pending[i-1].UserID {
+				groups = append(groups, nil)
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1], n)
+		}
+		// A full batch may end partway through its last user, so theirs are read in full to send
+		// them a single digest
+		if full {
+			last := len(groups) - 1
+			groups[last], err = s.notifications.PendingDigest(ctx, &groups[last][0].UserID, cutoff, 0)
+			if err != nil {
+				return sent, err
+			}
+		}
+
+		for _, group := range groups {
+			queued, err := s.sendDigest(ctx, group)
+			if err != nil {
+				return sent, err
+			}
+			if queued {
+				sent++
+			}
+		}
+		if !full {
+			return sent, nil
+		}
+	}
+}
+
+// sendDigest emails one user's pending notifications, newest first, and marks them digested
+// whether or not there was anything left to send
+func (s *NotificationService) sendDigest(ctx context.Context, notifications []*models.Notification) (bool, error) {
+	// Notifications of deleted users are marked without sending anything
+	user, err := s.users.Get(ctx, notifications[0].UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		user, err = nil, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ids := make([]primitive.ObjectID, len(notifications))
+	var items []mailer.DigestItem
+	for i := len(notifications) - 1; i >= 0; i-- {
+		n := notifications[i]
+		ids[i] = n.ID
+		if user == nil || n.Type == models.NotificationMentioned || isMuted(user.Notifications, n.Type) {
+			continue
+		}
+		items = append(items, mailer.DigestItem{Message: n.Message, Count: n.Count, At: n.UpdatedAt})
+	}
+
+	if len(items) > 0 {
+		name := user.Name
+		if name == "" {
+			name = user.Email
+		}
+		if err := s.mailer.QueueDigest(ctx, user.Email, name, items); err != nil {
+			return false, err
+		}
+	}
+	return len(items) > 0, s.notifications.MarkDigested(ctx, ids, time.Now())
+}
+
+// StartDigestJob runs SendDigests in the background every interval, skipping runs while connected reports false
+func (s *NotificationService) StartDigestJob(interval time.Duration, connected func() bool) {
+	if interval <= 0 || s.mailer == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// The first digest waits a full interval so that restarts do not send extra ones
+		for {
+			<-ticker.C
+			if !connected() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			sent, err := s.SendDigests(ctx)
+			cancel()
+			if err != nil {
+				fmt.Printf("Warning: Notification digest failed: %v\n", err)
+			} else if sent > 0 {
+				fmt.Printf("Queued %d notification digests\n", sent)
+			}
+		}
+	}()
+}
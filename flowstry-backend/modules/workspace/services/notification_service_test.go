@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/mailer"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestNotifications(t *testing.T) (*testWorkspace, *NotificationService, *EventBus) {
+	t.Helper()
+	tw := newTestWorkspace(t)
+	notifications := NewNotificationService(tw.store.Notifications, tw.store.Users, tw.store.Workspaces, tw.store.Diagrams, tw.members)
+	events := NewEventBus()
+	events.Subscribe(notifications.Record)
+	return tw, notifications, events
+}
+
+func (tw *testWorkspace) diagramBy(t *testing.T, creator primitive.ObjectID, name string) *models.Diagram {
+	t.Helper()
+	diagram := &models.Diagram{WorkspaceID: tw.id, Name: name, CreatedBy: &creator, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := tw.store.Diagrams.Create(context.Background(), diagram); err != nil {
+		t.Fatal(err)
+	}
+	return diagram
+}
+
+func messages(t *testing.T, notifications *NotificationService, userID primitive.ObjectID) []string {
+	t.Helper()
+	list, _, err := notifications.List(context.Background(), userID, &models.NotificationFilter{UnreadOnly: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range list {
+		got = append(got, n.Message)
+	}
+	return got
+}
+
+func TestNotificationsFromEvents(t *testing.T) {
+	tw, notifications, events := newTestNotifications(t)
+	as := func(user primitive.ObjectID) context.Context {
+		return WithActor(context.Background(), &models.Actor{UserID: user})
+	}
+
+	events.Emit(as(tw.admin.ID), tw.id, models.EventInviteCreated, models.TargetInvite, primitive.NewObjectID(),
+		nil, map[string]string{"email": tw.outsider.Email, "role": "viewer"})
+	events.Emit(as(tw.admin.ID), tw.id, models.EventInviteCreated, models.TargetInvite, primitive.NewObjectID(),
+		nil, map[string]string{"email": "nobody@example.com", "role": "viewer"})
+	events.Emit(as(tw.admin.ID), tw.id, models.EventMemberRoleChanged, models.TargetMember, tw.viewer.ID,
+		map[string]string{"role": "viewer"}, map[string]string{"role": "editor"})
+
+	// Edits collapse into the unread notification; the creator's own edits are not notified
+	diagram := tw.diagramBy(t, tw.editor.ID, "Payments Flow")
+	for _, user := range []primitive.ObjectID{tw.admin.ID, tw.editor.ID, tw.owner.ID} {
+		events.Emit(as(user), tw.id, models.EventDiagramUpdated, models.TargetDiagram, diagram.ID, nil, nil)
+	}
+
+	if got := messages(t, notifications, tw.outsider.ID); len(got) != 1 || got[0] != "admin@example.com invited you to Team as viewer" {
+		t.Errorf("outsider = %q", got)
+	}
+	if got := messages(t, notifications, tw.viewer.ID); len(got) != 1 || got[0] != "admin@example.com changed your role in Team to editor" {
+		t.Errorf("viewer = %q", got)
+	}
+	list, _, err := notifications.List(context.Background(), tw.editor.ID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Count != 2 || list[0].Message != "owner@example.com edited Payments Flow" {
+		t.Fatalf("editor = %+v", list)
+	}
+	if got := messages(t, notifications, tw.admin.ID); len(got) != 0 {
+		t.Errorf("admin = %q, want nothing", got)
+	}
+
+	// Once read, the next edit starts a new notification
+	if marked, err := notifications.MarkAllRead(context.Background(), tw.editor.ID); err != nil || marked != 1 {
+		t.Fatalf("MarkAllRead = %d, %v", marked, err)
+	}
+	events.Emit(as(tw.admin.ID), tw.id, models.EventDiagramUpdated, models.TargetDiagram, diagram.ID, nil, nil)
+	if count, err := notifications.CountUnread(context.Background(), tw.editor.ID); err != nil || count != 1 {
+		t.Fatalf("CountUnread = %d, %v", count, err)
+	}
+
+	events.Emit(as(tw.owner.ID), tw.id, models.EventWorkspaceDeleted, models.TargetWorkspace, tw.id, nil, nil)
+	if count, err := notifications.CountUnread(context.Background(), tw.editor.ID); err != nil || count != 0 {
+		t.Fatalf("CountUnread after workspace deletion = %d, %v", count, err)
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	tw, notifications, events := newTestNotifications(t)
+	ctx := context.Background()
+
+	prefs, err := notifications.UpdatePreferences(ctx, tw.editor.ID, &models.NotificationPreferencesRequest{
+		Muted: []models.NotificationType{models.NotificationDiagramDeleted, models.NotificationDiagramDeleted},
+	})
+	if err != nil || len(prefs.Muted) != 1 {
+		t.Fatalf("UpdatePreferences = %+v, %v", prefs, err)
+	}
+	_, err = notifications.UpdatePreferences(ctx, tw.editor.ID, &models.NotificationPreferencesRequest{Muted: []models.NotificationType{"weekly"}})
+	if !errors.Is(err, ErrInvalidNotificationType) {
+		t.Fatalf("unknown type: err = %v", err)
+	}
+
+	muted := tw.diagramBy(t, tw.editor.ID, "Muted")
+	events.Emit(WithActor(ctx, &models.Actor{UserID: tw.admin.ID}), tw.id, models.EventDiagramDeleted, models.TargetDiagram, muted.ID, nil, nil)
+	if got := messages(t, notifications, tw.editor.ID); len(got) != 0 {
+		t.Errorf("muted type notified: %q", got)
+	}
+
+	// Creators who left the workspace are not notified
+	left := tw.diagramBy(t, tw.viewer.ID, "Left")
+	if err := tw.members.RemoveMember(ctx, tw.id, tw.viewer.ID); err != nil {
+		t.Fatal(err)
+	}
+	events.Emit(WithActor(ctx, &models.Actor{UserID: tw.admin.ID}), tw.id, models.EventDiagramDeleted, models.TargetDiagram, left.ID, nil, nil)
+	if got := messages(t, notifications, tw.viewer.ID); len(got) != 0 {
+		t.Errorf("former member notified: %q", got)
+	}
+}
+
+func TestNotificationDigests(t *testing.T) {
+	tw, notifications, _ := newTestNotifications(t)
+	ctx := context.Background()
+
+	mail, err := mailer.New(tw.store.Outbox, nil, "https://app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications.SetMailer(mail)
+
+	notify := func(user primitive.ObjectID, notificationType models.NotificationType, message string, age time.Duration) {
+		t.Helper()
+		at := time.Now().Add(-age)
+		n := &models.Notification{
+			UserID: user, WorkspaceID: tw.id, Type: notificationType, TargetType: models.TargetDiagram,
+			TargetID: primitive.NewObjectID(), Message: message, Count: 1, CreatedAt: at, UpdatedAt: at,
+		}
+		if err := tw.store.Notifications.Create(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notify(tw.editor.ID, models.NotificationDiagramEdited, "admin edited Payments Flow", 3*time.Hour)
+	notify(tw.editor.ID, models.NotificationDiagramDeleted, "admin moved Old Flow to the trash", 2*time.Hour)
+	notify(tw.editor.ID, models.NotificationMentioned, "admin mentioned you in Payments Flow", 2*time.Hour)
+	notify(tw.editor.ID, models.NotificationRoleChanged, "admin changed your role", time.Minute)
+	// Mentions were emailed when they happened, so a user with only mentions gets no digest
+	notify(tw.viewer.ID, models.NotificationMentioned, "admin mentioned you in Payments Flow", 2*time.Hour)
+
+	if sent, err := notifications.SendDigests(ctx); err != nil || sent != 1 {
+		t.Fatalf("SendDigests = %d, %v", sent, err)
+	}
+	emails, err := tw.store.Outbox.Due(ctx, time.Now(), 10)
+	if err != nil || len(emails) != 1 {
+		t.Fatalf("queued emails = %v, %v", emails, err)
+	}
+	email := emails[0]
+	if email.Template != mailer.TemplateDigest || email.To != tw.editor.Email || email.Subject != "You have 2 new notifications on Flowstry" {
+		t.Fatalf("digest = %+v", email)
+	}
+	if strings.Index(email.Text, "Old Flow") > strings.Index(email.Text, "edited Payments Flow") ||
+		strings.Contains(email.Text, "mentioned") || strings.Contains(email.Text, "role") {
+		t.Fatalf("digest text = %q", email.Text)
+	}
+
+	// Notifications are sent once
+	if sent, err := notifications.SendDigests(ctx); err != nil || sent != 0 {
+		t.Fatalf("second SendDigests = %d, %v", sent, err)
+	}
+}
+
+func TestNotificationDigestsSpanningBatches(t *testing.T) {
+	tw, notifications, _ := newTestNotifications(t)
+	ctx := context.Background()
+
+	mail, err := mailer.New(tw.store.Outbox, nil, "https://app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications.SetMailer(mail)
+
+	// One user has more pending notifications than a batch holds, another has one
+	at := time.Now().Add(-2 * time.Hour)
+	for i := 0; i < digestBatchSize+10; i++ {
+		user := tw.editor.ID
+		if i == digestBatchSize+9 {
+			user = tw.viewer.ID
+		}
+		n := &models.Notification{
+			UserID: user, WorkspaceID: tw.id, Type: models.NotificationDiagramEdited, TargetType: models.TargetDiagram,
+			TargetID: primitive.NewObjectID(), Message: fmt.Sprintf("admin edited Flow %d", i), Count: 1, CreatedAt: at, UpdatedAt: at,
+		}
+		if err := tw.store.Notifications.Create(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sent, err := notifications.SendDigests(ctx); err != nil || sent != 2 {
+		t.Fatalf("SendDigests = %d, %v", sent, err)
+	}
+	emails, err := tw.store.Outbox.Due(ctx, time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perUser := map[string]int{}
+	for _, email := range emails {
+		perUser[email.To]++
+	}
+	if len(emails) != 2 || perUser[tw.editor.Email] != 1 || perUser[tw.viewer.Email] != 1 {
+		t.Fatalf("digests per user = %v", perUser)
+	}
+	for _, email := range emails {
+		if email.To == tw.editor.Email && email.Subject != fmt.Sprintf("You have %d new notifications on Flowstry", digestBatchSize+9) {
+			t.Errorf("editor's digest subject = %q", email.Subject)
+		}
+	}
+}
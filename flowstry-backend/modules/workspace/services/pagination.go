@@ -0,0 +1,173 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+	ErrInvalidOrder  = errors.New("invalid sort order")
+)
+
+// pageSpec describes the sort options a listing supports
+type pageSpec struct {
+	// fields lists the allowed sort fields
+	fields       []models.SortField
+	defaultSort  models.SortField
+	defaultOrder models.SortOrder
+	// unbounded listings return every item when neither a limit nor a cursor is given,
+	// as they did before they were paginated
+	unbounded bool
+}
+
+// pageCursor is the decoded form of an opaque next_cursor
+type pageCursor struct {
+	Sort  models.SortField `json:"s"`
+	Order models.SortOrder `json:"o"`
+	Value string           `json:"v"`
+	ID    string           `json:"id"`
+}
+
+// resolvedPage is a validated page request
+type resolvedPage struct {
+	limit int64
+	sort  models.SortField
+	order models.SortOrder
+	after *pageCursor
+}
+
+// allows reports whether a listing can be sorted by field
+func (spec pageSpec) allows(field models.SortField) bool {
+	for _, f := range spec.fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve validates a page request against the spec, filling in defaults
+func (spec pageSpec) resolve(page *models.PageRequest) (*resolvedPage, error) {
+	if page == nil {
+		page = &models.PageRequest{}
+	}
+
+	p := &resolvedPage{
+		limit: page.Limit,
+		sort:  page.Sort,
+		order: page.Order,
+	}
+	if p.limit <= 0 && !(spec.unbounded && page.Cursor == "") {
+		p.limit = models.DefaultPageSize
+	}
+	if p.limit > models.MaxPageSize {
+		p.limit = models.MaxPageSize
+	}
+	if p.sort == "" {
+		p.sort = spec.defaultSort
+	}
+	if !spec.allows(p.sort) {
+		return nil, ErrInvalidSort
+	}
+	if p.order == "" {
+		if page.Sort == "" {
+			p.order = spec.defaultOrder
+		} else if p.sort == models.SortByName {
+			p.order = models.SortAsc
+		} else {
+			p.order = models.SortDesc
+		}
+	}
+	if p.order != models.SortAsc && p.order != models.SortDesc {
+		return nil, ErrInvalidOrder
+	}
+
+	if page.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		var cur pageCursor
+		if err := json.Unmarshal(raw, &cur); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		// A cursor is only valid for the ordering it was issued for
+		if cur.Sort != p.sort || cur.Order != p.order {
+			return nil, ErrInvalidCursor
+		}
+		p.after = &cur
+	}
+
+	return p, nil
+}
+
+// sortValue converts a cursor value back to the type stored under the sort key
+func (p *resolvedPage) sortValue(v string) (interface{}, error) {
+	switch p.sort {
+	case models.SortByName:
+		return v, nil
+	case models.SortBySize:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return time.Parse(time.RFC3339Nano, v)
+	}
+}
+
+// query converts the page to a repository query, asking for one look-ahead item
+func (p *resolvedPage) query() (*repository.PageQuery, error) {
+	q := &repository.PageQuery{Sort: p.sort, Order: p.order}
+	if p.limit > 0 {
+		q.Limit = p.limit + 1
+	}
+	if p.after == nil {
+		return q, nil
+	}
+
+	value, err := p.sortValue(p.after.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(p.after.ID)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	q.After = &repository.Keyset{Value: value, ID: id}
+	return q, nil
+}
+
+// cursorAfter builds the cursor continuing after an item with the given sort value
+func (p *resolvedPage) cursorAfter(value interface{}, id primitive.ObjectID) string {
+	cur := pageCursor{Sort: p.sort, Order: p.order, ID: id.Hex()}
+	switch v := value.(type) {
+	case string:
+		cur.Value = v
+	case int64:
+		cur.Value = strconv.FormatInt(v, 10)
+	case time.Time:
+		cur.Value = v.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if v != nil {
+			cur.Value = v.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// splitPage trims the look-ahead item and reports whether more results exist
+func splitPage[T any](items []T, limit int64) ([]T, bool) {
+	if limit > 0 && int64(len(items)) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxSearchContentSize caps the extracted shape text stored per diagram
+	maxSearchContentSize = 32 * 1024
+	// searchCandidateLimit caps how many documents are scored per collection
+	searchCandidateLimit = 200
+	// maxSearchTerms caps how many words of a query are used
+	maxSearchTerms = 8
+	// indexTimeout bounds a single background indexing run
+	indexTimeout = 2 * time.Minute
+	// maxConcurrentIndexing bounds concurrent background indexing jobs
+	maxConcurrentIndexing = 4
+)
+
+// SearchOptions controls a search request
+type SearchOptions struct {
+	// IncludeContent also matches indexed shape text
+	IncludeContent bool
+	Limit          int
+}
+
+// SearchService ranks diagrams and folders by name, description and (opt-in) shape text
+type SearchService struct {
+	workspaces     repository.WorkspaceRepository
+	folders        repository.FolderRepository
+	diagrams       repository.DiagramRepository
+	contentService *ContentService
+	indexSlots     chan struct{}
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(workspaces repository.WorkspaceRepository, folders repository.FolderRepository, diagrams repository.DiagramRepository, contentService *ContentService) *SearchService {
+	return &SearchService{
+		workspaces:     workspaces,
+		folders:        folders,
+		diagrams:       diagrams,
+		contentService: contentService,
+		indexSlots:     make(chan struct{}, maxConcurrentIndexing),
+	}
+}
+
+// Search returns ranked diagrams and folders matching the query in the given workspaces
+func (s *SearchService) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, query string, opts SearchOptions) ([]*models.SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 || len(workspaceIDs) == 0 {
+		return []*models.SearchResult{}, nil
+	}
+
+	// Every term must match at least one searchable field
+	diagramHits, err := s.diagrams.Search(ctx, workspaceIDs, terms, opts.IncludeContent, searchCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	folderHits, err := s.folders.Search(ctx, workspaceIDs, terms, searchCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	phrase := strings.Join(terms, " ")
+	results := make([]*models.SearchResult, 0, len(diagramHits)+len(folderHits))
+
+	for _, d := range diagramHits {
+		content := ""
+		if opts.IncludeContent {
+			content = d.SearchContent
+		}
+		score, snippet := scoreMatch(phrase, terms, d.Name, d.Description, content)
+		results = append(results, &models.SearchResult{
+			Type:        models.SearchResultDiagram,
+			ID:          d.ID,
+			WorkspaceID: d.WorkspaceID,
+			FolderID:    d.FolderID,
+			Name:        d.Name,
+			Description: d.Description,
+			Snippet:     snippet,
+			Score:       score,
+			UpdatedAt:   d.UpdatedAt,
+		})
+	}
+
+	for _, f := range folderHits {
+		score, _ := scoreMatch(phrase, terms, f.Name, "", "")
+		results = append(results, &models.SearchResult{
+			Type:        models.SearchResultFolder,
+			ID:          f.ID,
+			WorkspaceID: f.WorkspaceID,
+			FolderID:    f.ParentFolderID,
+			Name:        f.Name,
+			Description: f.Description,
+			Score:       score,
+			UpdatedAt:   f.UpdatedAt,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].UpdatedAt.After(results[j].UpdatedAt)
+	})
+
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+// searchTerms normalizes a query into lowercase terms
+func searchTerms(query string) []string {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// scoreMatch ranks a hit: name matches outweigh description matches, which outweigh shape text.
+// It also returns the first line of content matching a term, if any.
+func scoreMatch(phrase string, terms []string, name, description, content string) (int, string) {
+	name = strings.ToLower(name)
+	description = strings.ToLower(description)
+	lowerContent := strings.ToLower(content)
+
+	score := 0
+	switch {
+	case name == phrase:
+		score += 100
+	case strings.HasPrefix(name, phrase):
+		score += 60
+	case strings.Contains(name, phrase):
+		score += 40
+	}
+
+	snippet := ""
+	for _, term := range terms {
+		if strings.Contains(name, term) {
+			score += 20
+			if strings.HasPrefix(name, term) || strings.Contains(name, " "+term) {
+				score += 5
+			}
+		}
+		if strings.Contains(description, term) {
+			score += 8
+		}
+		if idx := strings.Index(lowerContent, term); idx >= 0 {
+			score += 5
+			if snippet == "" {
+				snippet = lineAround(content, idx)
+			}
+		}
+	}
+
+	return score, snippet
+}
+
+// lineAround returns the content line containing the byte offset, truncated for display
+func lineAround(content string, offset int) string {
+	start := strings.LastIndex(content[:offset], "\n") + 1
+	end := strings.Index(content[offset:], "\n")
+	if end < 0 {
+		end = len(content)
+	} else {
+		end += offset
+	}
+
+	line := strings.TrimSpace(content[start:end])
+	if runes := []rune(line); len(runes) > 120 {
+		line = string(runes[:120]) + "…"
+	}
+	return line
+}
+
+// ScheduleIndex re-indexes a diagram's shape text in the background after a save
+func (s *SearchService) ScheduleIndex(diagram *models.Diagram) {
+	if diagram == nil {
+		return
+	}
+	d := *diagram
+
+	go func() {
+		s.indexSlots <- struct{}{}
+		defer func() { <-s.indexSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), indexTimeout)
+		defer cancel()
+
+		if err := s.IndexDiagram(ctx, &d); err != nil {
+			fmt.Printf("Warning: Failed to index diagram %s: %v\n", d.ID.Hex(), err)
+		}
+	}()
+}
+
+// IndexDiagram extracts and stores a diagram's shape text if its workspace opted in
+func (s *SearchService) IndexDiagram(ctx context.Context, diagram *models.Diagram) error {
+	enabled, err := s.contentSearchEnabled(ctx, diagram.WorkspaceID)
+	if err != nil || !enabled {
+		return err
+	}
+
+	content, err := s.contentService.Read(ctx, diagram)
+	if err != nil {
+		if errors.Is(err, ErrNoDiagramContent) {
+			return nil
+		}
+		return err
+	}
+
+	text, err := extractSearchText(content)
+	if err != nil {
+		return err
+	}
+
+	return s.diagrams.SetSearchContent(ctx, diagram.WorkspaceID, diagram.ID, text)
+}
+
+// ScheduleWorkspaceIndexing backfills (enabled) or clears (disabled) a workspace's content index
+func (s *SearchService) ScheduleWorkspaceIndexing(workspaceID primitive.ObjectID, enabled bool) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+
+		if err := s.setWorkspaceIndexing(ctx, workspaceID, enabled); err != nil {
+			fmt.Printf("Warning: Failed to update search index for workspace %s: %v\n", workspaceID.Hex(), err)
+		}
+	}()
+}
+
+func (s *SearchService) setWorkspaceIndexing(ctx context.Context, workspaceID primitive.ObjectID, enabled bool) error {
+	if !enabled {
+		return s.diagrams.ClearSearchContent(ctx, workspaceID)
+	}
+
+	diagrams, err := s.diagrams.ListAll(ctx, workspaceID, repository.Live)
+	if err != nil {
+		return err
+	}
+
+	for _, diagram := range diagrams {
+		if err := s.IndexDiagram(ctx, diagram); err != nil {
+			fmt.Printf("Warning: Failed to index diagram %s: %v\n", diagram.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+func (s *SearchService) contentSearchEnabled(ctx context.Context, workspaceID primitive.ObjectID) (bool, error) {
+	workspace, err := s.workspaces.Get(ctx, workspaceID)
+	if err != nil {
+		return false, err
+	}
+
+	return workspace.ContentSearchEnabled, nil
+}
+
+// extractSearchText collects shape text, frame labels and card names from a diagram document
+func extractSearchText(document []byte) (string, error) {
+	var doc struct {
+		Shapes []struct {
+			Intent struct {
+				Text      string                 `json:"text"`
+				LabelText string                 `json:"labelText"`
+				Data      map[string]interface{} `json:"data"`
+			} `json:"intent"`
+		} `json:"shapes"`
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	add := func(text string) {
+		text = strings.TrimSpace(text)
+		if text == "" || b.Len()+len(text)+1 > maxSearchContentSize {
+			return
+		}
+		b.WriteString(text)
+		b.WriteByte('\n')
+	}
+
+	for _, shape := range doc.Shapes {
+		add(shape.Intent.Text)
+		add(shape.Intent.LabelText)
+		for _, key := range []string{"serviceName", "title", "name", "description"} {
+			if v, ok := shape.Intent.Data[key].(string); ok {
+				add(v)
+			}
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	authServices "github.com/flowstry/flowstry-backend/modules/auth/services"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ShareDownloadURLExpiry is how long the signed download URL handed out by a share link stays valid
+	ShareDownloadURLExpiry = 15 * time.Minute
+	// shareKeySize is the size of the AES-256 key each link's snapshot is encrypted with
+	shareKeySize = 32
+)
+
+var (
+	ErrShareLinkNotFound     = errors.New("share link not found")
+	ErrShareLinkExpired      = errors.New("share link has expired")
+	ErrSharePasswordRequired = errors.New("share link requires a password")
+	ErrSharePasswordInvalid  = errors.New("invalid share link password")
+)
+
+// ShareService handles public read-only share links for diagrams.
+//
+// Diagrams are stored encrypted with their workspace key, which must not leave the workspace.
+// Each link therefore serves a snapshot of the diagram re-encrypted with a random key of its
+// own. The key is returned once, when the link is created, and travels in the URL fragment.
+type ShareService struct {
+	links          repository.ShareLinkRepository
+	diagramService *DiagramService
+	contentService *ContentService
+	fileStorage    storage.Storage
+	frontendURL    string
+}
+
+// NewShareService creates a new share service
+func NewShareService(links repository.ShareLinkRepository, diagramService *DiagramService, contentService *ContentService, fileStorage storage.Storage, frontendURL string) *ShareService {
+	return &ShareService{
+		links:          links,
+		diagramService: diagramService,
+		contentService: contentService,
+		fileStorage:    fileStorage,
+		frontendURL:    strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// hashShareToken hashes a raw share token for storage and lookup
+func hashShareToken(rawToken string) string {
+	hash := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(hash[:])
+}
+
+// BuildURL returns the public URL for a raw share token and snapshot key.
+// The key is a URL fragment (#key=...), which browsers never send to the server, so it
+// stays out of request logs.
+func (s *ShareService) BuildURL(rawToken, key string) string {
+	return s.frontendURL + "/share/" + rawToken + "#key=" + key
+}
+
+// Create creates a new share link serving a snapshot of the diagram's current version.
+// It returns the link with its raw token and the key that decrypts the snapshot.
+func (s *ShareService) Create(ctx context.Context, workspaceID, diagramID, userID primitive.ObjectID, req *models.CreateShareLinkRequest) (*models.ShareLink, string, string, error) {
+	// Make sure the diagram exists and is not in trash
+	diagram, err := s.diagramService.GetByID(ctx, diagramID, workspaceID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	rawToken, err := generateToken()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	link := &models.ShareLink{
+		ID:          primitive.NewObjectID(),
+		WorkspaceID: workspaceID,
+		DiagramID:   diagramID,
+		TokenHash:   hashShareToken(rawToken),
+		CreatedBy:   userID,
+		CreatedAt:   time.Now(),
+	}
+
+	key, err := s.writeSnapshot(ctx, link, diagram)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Hour)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if req.Password != "" {
+		passwordHash, err := authServices.HashPassword(req.Password)
+		if err != nil {
+			_ = s.fileStorage.DeleteFile(ctx, link.SnapshotPath)
+			return nil, "", "", err
+		}
+		link.PasswordHash = passwordHash
+	}
+
+	if err := s.links.Create(ctx, link); err != nil {
+		_ = s.fileStorage.DeleteFile(ctx, link.SnapshotPath)
+		return nil, "", "", err
+	}
+	return link, rawToken, base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// writeSnapshot encrypts the diagram's document with a new random key, stores it as the
+// link's snapshot and returns the key
+func (s *ShareService) writeSnapshot(ctx context.Context, link *models.ShareLink, diagram *models.Diagram) ([]byte, error) {
+	if s.contentService == nil || s.fileStorage == nil {
+		return nil, errors.New("share links need file storage")
+	}
+
+	document, err := s.contentService.Read(ctx, diagram)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, shareKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	data, err := s.contentService.EncodeWithKey(key, document)
+	if err != nil {
+		return nil, err
+	}
+
+	objectName := fmt.Sprintf("shares/%s/%s/diagram.flowstry", link.WorkspaceID.Hex(), link.ID.Hex())
+	path, size, err := s.fileStorage.UploadFile(ctx, objectName, data, "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	link.SnapshotPath = path
+	link.SnapshotSize = size
+	link.Version = diagram.Version
+	return key, nil
+}
+
+// SnapshotURL returns a short-lived URL for downloading a link's encrypted snapshot
+func (s *ShareService) SnapshotURL(ctx context.Context, link *models.ShareLink) (string, error) {
+	return s.fileStorage.GetSignedURL(ctx, link.SnapshotPath, "GET", "", ShareDownloadURLExpiry)
+}
+
+// List lists all share links of a diagram, newest first
+func (s *ShareService) List(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.ShareLink, error) {
+	return s.links.ListForDiagram(ctx, workspaceID, diagramID)
+}
+
+// Revoke revokes a share link so it can no longer be used and deletes its snapshot
+func (s *ShareService) Revoke(ctx context.Context, workspaceID, diagramID, linkID primitive.ObjectID) error {
+	err := s.links.Revoke(ctx, workspaceID, diagramID, linkID, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrShareLinkNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	links, err := s.links.ListForDiagram(ctx, workspaceID, diagramID)
+	if err != nil {
+		return nil
+	}
+	for _, link := range links {
+		if link.ID == linkID {
+			s.deleteSnapshot(ctx, link)
+			break
+		}
+	}
+	return nil
+}
+
+// deleteSnapshot removes a link's snapshot; failures only leave an unreadable file behind
+func (s *ShareService) deleteSnapshot(ctx context.Context, link *models.ShareLink) {
+	if link.SnapshotPath != "" && s.fileStorage != nil {
+		_ = s.fileStorage.DeleteFile(ctx, link.SnapshotPath)
+	}
+}
+
+// Resolve looks up an active share link by raw token, checks the password and
+// returns the link together with its diagram
+func (s *ShareService) Resolve(ctx context.Context, rawToken, password string) (*models.ShareLink, *models.Diagram, error) {
+	link, err := s.links.GetByTokenHash(ctx, hashShareToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrShareLinkNotFound
+		}
+		return nil, nil, err
+	}
+
+	// Links created before snapshots existed have nothing a viewer can decrypt
+	if link.RevokedAt != nil || link.SnapshotPath == "" {
+		return nil, nil, ErrShareLinkNotFound
+	}
+	if link.IsExpired() {
+		return nil, nil, ErrShareLinkExpired
+	}
+
+	if link.PasswordHash != "" {
+		if password == "" {
+			return nil, nil, ErrSharePasswordRequired
+		}
+		if !authServices.VerifyPassword(link.PasswordHash, password) {
+			return nil, nil, ErrSharePasswordInvalid
+		}
+	}
+
+	diagram, err := s.diagramService.GetByID(ctx, link.DiagramID, link.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, ErrDiagramNotFound) {
+			return nil, nil, ErrShareLinkNotFound
+		}
+		return nil, nil, err
+	}
+
+	// Track usage; failures here must not block viewing
+	_ = s.links.RecordView(ctx, link.ID, time.Now())
+
+	return link, diagram, nil
+}
+
+// DeleteForDiagram removes all share links of a diagram and their snapshots (used on permanent deletion)
+func (s *ShareService) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	links, err := s.links.ListForDiagram(ctx, workspaceID, diagramID)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		s.deleteSnapshot(ctx, link)
+	}
+	return s.links.DeleteForDiagram(ctx, workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes all share links of a workspace (used when deleting workspace)
+func (s *ShareService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.links.DeleteAllForWorkspace(ctx, workspaceID)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestShareLinkSnapshots(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	fileStorage, err := storage.NewLocalStorage(filepath.Join(dir, "files"), "http://localhost", []byte("signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := fiber.New()
+	fileStorage.SetupRoutes(files)
+	encryption, err := NewEncryptionService(filepath.Join(dir, "master.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.workspaces.SetEncryptionService(encryption)
+	workspace, err := tw.workspaces.Create(ctx, tw.owner.ID, &models.CreateWorkspaceRequest{Name: "Encrypted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := NewContentService(tw.store.Workspaces, fileStorage, encryption)
+	diagrams := NewDiagramService(tw.store.Diagrams, fileStorage, NewFolderService(tw.store.Folders, tw.store.Diagrams))
+	shares := NewShareService(tw.store.ShareLinks, diagrams, content, fileStorage, "https://app.example.com/")
+	diagrams.SetShareService(shares)
+
+	document := []byte(`{"shapes":[{"id":"s1","text":"Checkout"}]}`)
+	data, err := content.Encode(ctx, workspace.ID, document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, size, err := fileStorage.UploadFile(ctx, "diagrams/checkout/diagram.flowstry", data, "application/octet-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagram := &models.Diagram{WorkspaceID: workspace.ID, Name: "Checkout", FileURL: path, FileSize: size, Version: 4, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := tw.store.Diagrams.Create(ctx, diagram); err != nil {
+		t.Fatal(err)
+	}
+
+	link, token, key, err := shares.Create(ctx, workspace.ID, diagram.ID, tw.owner.ID, &models.CreateShareLinkRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shareURL := shares.BuildURL(token, key)
+	if link.Version != 4 || !strings.HasPrefix(shareURL, "https://app.example.com/share/"+token+"#key=") {
+		t.Fatalf("link = %+v, URL = %s", link, shareURL)
+	}
+
+	// A viewer opens the link, downloads the snapshot and decrypts it with the key from the fragment
+	resolved, _, err := shares.Resolve(ctx, token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloadURL, err := shares.SnapshotURL(ctx, resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := url.Parse(downloadURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := files.Test(httptest.NewRequest(fiber.MethodGet, signed.RequestURI(), nil))
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("download snapshot: %v, %v", resp, err)
+	}
+	snapshot, _ := io.ReadAll(resp.Body)
+
+	fragmentKey, err := base64.RawURLEncoding.DecodeString(strings.SplitN(shareURL, "#key=", 2)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := content.Decode(fragmentKey, snapshot)
+	if err != nil || string(opened) != string(document) {
+		t.Fatalf("decrypted snapshot = %q, %v", opened, err)
+	}
+
+	// The snapshot is not readable with the workspace key, and links get keys of their own
+	workspaceKey, err := content.workspaceKey(ctx, workspace.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := content.Decode(workspaceKey, snapshot); err == nil {
+		t.Error("snapshot decrypted with the workspace key")
+	}
+	_, _, otherKey, err := shares.Create(ctx, workspace.ID, diagram.ID, tw.owner.ID, &models.CreateShareLinkRequest{})
+	if err != nil || otherKey == key {
+		t.Fatalf("second link key = %q, %v", otherKey, err)
+	}
+
+	// Revoking a link deletes its snapshot
+	if err := shares.Revoke(ctx, workspace.ID, diagram.ID, link.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileStorage.DownloadFile(ctx, link.SnapshotPath); err == nil {
+		t.Error("snapshot kept after revoking")
+	}
+	if _, _, err := shares.Resolve(ctx, token, ""); !errors.Is(err, ErrShareLinkNotFound) {
+		t.Errorf("revoked link: err = %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrStarNotFound = errors.New("diagram is not starred")
+)
+
+// StarService handles per-user starred (favorite) diagrams
+type StarService struct {
+	stars          repository.StarRepository
+	diagramService *DiagramService
+}
+
+// NewStarService creates a new star service
+func NewStarService(stars repository.StarRepository, diagramService *DiagramService) *StarService {
+	return &StarService{
+		stars:          stars,
+		diagramService: diagramService,
+	}
+}
+
+// Star stars a diagram for the user (idempotent)
+func (s *StarService) Star(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) (*models.DiagramStar, error) {
+	if _, err := s.diagramService.GetByID(ctx, diagramID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	return s.stars.Star(ctx, &models.DiagramStar{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		DiagramID:   diagramID,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// Unstar removes a diagram from the user's stars
+func (s *StarService) Unstar(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) error {
+	err := s.stars.Unstar(ctx, userID, workspaceID, diagramID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrStarNotFound
+	}
+	return err
+}
+
+// IsStarred reports whether the user starred a diagram
+func (s *StarService) IsStarred(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) (bool, error) {
+	return s.stars.IsStarred(ctx, userID, workspaceID, diagramID)
+}
+
+// StarredSet returns which of the user's diagrams in a workspace are starred
+func (s *StarService) StarredSet(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID) (map[primitive.ObjectID]time.Time, error) {
+	stars, err := s.stars.ListForUser(ctx, userID, workspaceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[primitive.ObjectID]time.Time, len(stars))
+	for _, star := range stars {
+		set[star.DiagramID] = star.CreatedAt
+	}
+	return set, nil
+}
+
+// ListStarred returns the user's starred diagrams across the given workspaces, most recently starred first
+func (s *StarService) ListStarred(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID) ([]*models.Diagram, map[primitive.ObjectID]time.Time, error) {
+	starredAt, err := s.StarredSet(ctx, userID, workspaceIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(starredAt) == 0 {
+		return []*models.Diagram{}, starredAt, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(starredAt))
+	for id := range starredAt {
+		ids = append(ids, id)
+	}
+
+	result, err := s.diagramService.ListByIDs(ctx, workspaceIDs, ids, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sortByTimeDesc(result, starredAt)
+	return result, starredAt, nil
+}
+
+// DeleteForDiagram removes all stars of a diagram (used on permanent deletion)
+func (s *StarService) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return s.stars.DeleteForDiagram(ctx, workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes all stars of a workspace (used when deleting workspace)
+func (s *StarService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.stars.DeleteAllForWorkspace(ctx, workspaceID)
+}
+
+// sortByTimeDesc orders diagrams by the given per-diagram timestamp, newest first
+func sortByTimeDesc(diagrams []*models.Diagram, at map[primitive.ObjectID]time.Time) {
+	sort.SliceStable(diagrams, func(i, j int) bool {
+		return at[diagrams[i].ID].After(at[diagrams[j].ID])
+	})
+}
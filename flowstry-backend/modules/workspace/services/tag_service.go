@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxTagsPerDiagram caps how many tags a single diagram can carry
+	MaxTagsPerDiagram = 20
+	// MaxTagLength caps the length of a tag name
+	MaxTagLength = 40
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTooManyTags = errors.New("too many tags")
+)
+
+// NormalizeTags normalizes and de-duplicates tag names, preserving order
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = utils.NormalizeTag(tag)
+		if tag == "" || len(tag) > MaxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTagsPerDiagram {
+		return nil, ErrTooManyTags
+	}
+	return normalized, nil
+}
+
+// TagService handles workspace tag definitions and tagging diagrams
+type TagService struct {
+	tags     repository.TagRepository
+	diagrams repository.DiagramRepository
+}
+
+// NewTagService creates a new tag service
+func NewTagService(tags repository.TagRepository, diagrams repository.DiagramRepository) *TagService {
+	return &TagService{
+		tags:     tags,
+		diagrams: diagrams,
+	}
+}
+
+// List lists all tag definitions of a workspace with their diagram counts
+func (s *TagService) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.TagResponse, error) {
+	tags, err := s.tags.List(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count usage in one query instead of one query per tag
+	counts, err := s.diagrams.CountTags(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.TagResponse, len(tags))
+	for i, t := range tags {
+		responses[i] = t.ToResponse()
+		responses[i].DiagramCount = counts[t.Name]
+	}
+
+	return responses, nil
+}
+
+// Create creates a tag definition
+func (s *TagService) Create(ctx context.Context, workspaceID primitive.ObjectID, req *models.CreateTagRequest) (*models.WorkspaceTag, error) {
+	name := utils.NormalizeTag(req.Name)
+	if name == "" || len(name) > MaxTagLength {
+		return nil, ErrInvalidTag
+	}
+
+	color := req.Color
+	if color == "" {
+		color = models.DefaultTagColor
+	}
+
+	tag := &models.WorkspaceTag{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Color:       color,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := s.tags.Create(ctx, tag); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Update renames and/or recolors a tag. Renames are applied to every tagged diagram.
+func (s *TagService) Update(ctx context.Context, workspaceID, tagID primitive.ObjectID, req *models.UpdateTagRequest) (*models.WorkspaceTag, error) {
+	tag, err := s.tags.Get(ctx, workspaceID, tagID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	update := &repository.TagUpdate{UpdatedAt: time.Now()}
+	oldName := tag.Name
+	if req.Name != nil {
+		name := utils.NormalizeTag(*req.Name)
+		if name == "" || len(name) > MaxTagLength {
+			return nil, ErrInvalidTag
+		}
+		update.Name = &name
+		tag.Name = name
+	}
+	if req.Color != nil {
+		update.Color = req.Color
+		tag.Color = *req.Color
+	}
+
+	if err := s.tags.Update(ctx, workspaceID, tagID, update); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicate):
+			return nil, ErrTagExists
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	if tag.Name != oldName {
+		if err := s.diagrams.RenameTag(ctx, workspaceID, oldName, tag.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	tag.UpdatedAt = update.UpdatedAt
+	return tag, nil
+}
+
+// Delete deletes a tag definition and removes the tag from all diagrams
+func (s *TagService) Delete(ctx context.Context, workspaceID, tagID primitive.ObjectID) error {
+	tag, err := s.tags.Get(ctx, workspaceID, tagID)
+	if err == nil {
+		err = s.tags.Delete(ctx, workspaceID, tagID)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrTagNotFound
+		}
+		return err
+	}
+
+	return s.diagrams.RemoveTag(ctx, workspaceID, tag.Name)
+}
+
+// EnsureDefined creates definitions (with the default color) for tags that have none yet
+func (s *TagService) EnsureDefined(ctx context.Context, workspaceID primitive.ObjectID, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return s.tags.EnsureDefined(ctx, workspaceID, names, time.Now())
+}
+
+// BulkUpdate adds and removes tags on several diagrams of a workspace
+func (s *TagService) BulkUpdate(ctx context.Context, workspaceID primitive.ObjectID, diagramIDs []primitive.ObjectID, add, remove []string) (int64, error) {
+	var modified int64
+	if len(add) > 0 {
+		if err := s.EnsureDefined(ctx, workspaceID, add); err != nil {
+			return 0, err
+		}
+
+		// Diagrams that would exceed the per-diagram limit are skipped
+		n, err := s.diagrams.AddTags(ctx, workspaceID, diagramIDs, add, MaxTagsPerDiagram, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		modified += n
+	}
+
+	if len(remove) > 0 {
+		n, err := s.diagrams.RemoveTags(ctx, workspaceID, diagramIDs, remove, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		modified += n
+	}
+
+	return modified, nil
+}
+
+// DeleteAllForWorkspace removes all tag definitions of a workspace (used when deleting workspace)
+func (s *TagService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return s.tags.DeleteAllForWorkspace(ctx, workspaceID)
+}
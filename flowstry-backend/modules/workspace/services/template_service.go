@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrTemplateNotFound     = errors.New("template not found")
+	ErrInvalidTemplateScope = errors.New("invalid template scope")
+)
+
+// emptyDiagramDocument is used when a template has no stored content yet
+const emptyDiagramDocument = `{"version":"1.0","shapes":[]}`
+
+// placeholderPattern matches {{name}} tokens in template text
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// TemplateService handles template diagrams and creating diagrams from them
+type TemplateService struct {
+	diagrams       repository.DiagramRepository
+	diagramService *DiagramService
+	contentService *ContentService
+	instanceAdmins map[string]bool
+}
+
+// NewTemplateService creates a new template service.
+// instanceAdminEmails is a comma-separated list of users allowed to publish instance-wide templates.
+func NewTemplateService(diagrams repository.DiagramRepository, diagramService *DiagramService, contentService *ContentService, instanceAdminEmails string) *TemplateService {
+	admins := make(map[string]bool)
+	for _, email := range strings.Split(instanceAdminEmails, ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return &TemplateService{
+		diagrams:       diagrams,
+		diagramService: diagramService,
+		contentService: contentService,
+		instanceAdmins: admins,
+	}
+}
+
+// IsInstanceAdmin checks if the email belongs to an instance administrator
+func (s *TemplateService) IsInstanceAdmin(email string) bool {
+	return email != "" && s.instanceAdmins[strings.ToLower(email)]
+}
+
+// SetScope marks a diagram as a template with the given scope
+func (s *TemplateService) SetScope(ctx context.Context, workspaceID, diagramID primitive.ObjectID, scope models.TemplateScope) (*models.Diagram, error) {
+	if !scope.IsValid() {
+		return nil, ErrInvalidTemplateScope
+	}
+	return s.updateScope(ctx, workspaceID, diagramID, scope)
+}
+
+// Unmark removes the template flag from a diagram
+func (s *TemplateService) Unmark(ctx context.Context, workspaceID, diagramID primitive.ObjectID) (*models.Diagram, error) {
+	return s.updateScope(ctx, workspaceID, diagramID, "")
+}
+
+func (s *TemplateService) updateScope(ctx context.Context, workspaceID, diagramID primitive.ObjectID, scope models.TemplateScope) (*models.Diagram, error) {
+	diagram, err := s.diagramService.GetByID(ctx, diagramID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.diagrams.SetTemplateScope(ctx, workspaceID, diagramID, scope); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrDiagramNotFound
+		}
+		return nil, err
+	}
+
+	diagram.TemplateScope = scope
+	return diagram, nil
+}
+
+// List returns the template gallery for a workspace: its own templates plus instance-wide ones
+func (s *TemplateService) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error) {
+	return s.diagrams.ListTemplates(ctx, workspaceID)
+}
+
+// Get returns a template that is available to the given workspace
+func (s *TemplateService) Get(ctx context.Context, workspaceID, templateID primitive.ObjectID) (*models.Diagram, error) {
+	template, err := s.diagrams.GetTemplate(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	switch template.TemplateScope {
+	case models.TemplateScopeInstance:
+		return template, nil
+	case models.TemplateScopeWorkspace:
+		if template.WorkspaceID == workspaceID {
+			return template, nil
+		}
+	}
+
+	return nil, ErrTemplateNotFound
+}
+
+// Placeholders returns the distinct placeholder names used in a template, sorted
+func (s *TemplateService) Placeholders(ctx context.Context, template *models.Diagram) ([]string, error) {
+	content, err := s.readContent(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllSubmatch(content, -1) {
+		seen[string(match[1])] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Instantiate creates a new diagram in the workspace from a template.
+// The copy gets fresh shape IDs, placeholders filled in and is re-encrypted with the target workspace key.
+func (s *TemplateService) Instantiate(ctx context.Context, userID, workspaceID, templateID primitive.ObjectID, req *models.InstantiateTemplateRequest) (*models.Diagram, error) {
+	template, err := s.Get(ctx, workspaceID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.readContent(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	cloned, err := cloneTemplateDocument(content, req.Name, req.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	fileData, err := s.contentService.Encode(ctx, workspaceID, cloned)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.diagramService.Create(ctx, userID, workspaceID, &models.CreateDiagramRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		FolderID:    req.FolderID,
+	}, fileData)
+}
+
+// readContent reads a template's JSON document, treating missing content as an empty diagram
+func (s *TemplateService) readContent(ctx context.Context, template *models.Diagram) ([]byte, error) {
+	content, err := s.contentService.Read(ctx, template)
+	if errors.Is(err, ErrNoDiagramContent) {
+		return []byte(emptyDiagramDocument), nil
+	}
+	return content, err
+}
+
+// cloneTemplateDocument copies a diagram document with fresh IDs for shapes and groups,
+// rewriting every reference to them, and substitutes placeholders in text fields
+func cloneTemplateDocument(content []byte, name string, values map[string]string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	remap := func(v interface{}) interface{} {
+		if id, ok := v.(string); ok {
+			if newID, found := ids[id]; found {
+				return newID
+			}
+		}
+		return v
+	}
+
+	shapes, _ := doc["shapes"].([]interface{})
+	groups, _ := doc["groups"].(map[string]interface{})
+
+	// First pass: allocate new IDs so forward references resolve
+	for _, raw := range shapes {
+		if shape, ok := raw.(map[string]interface{}); ok {
+			if id, ok := shape["id"].(string); ok {
+				ids[id] = uuid.NewString()
+			}
+		}
+	}
+	for id := range groups {
+		ids[id] = uuid.NewString()
+	}
+
+	// Second pass: rewrite IDs, references and text
+	for _, raw := range shapes {
+		shape, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		shape["id"] = remap(shape["id"])
+
+		if layout, ok := shape["layout"].(map[string]interface{}); ok {
+			for _, key := range []string{"parentId", "frameId"} {
+				if _, present := layout[key]; present {
+					layout[key] = remap(layout[key])
+				}
+			}
+		}
+
+		if intent, ok := shape["intent"].(map[string]interface{}); ok {
+			for _, key := range []string{"startShapeId", "endShapeId"} {
+				if _, present := intent[key]; present {
+					intent[key] = remap(intent[key])
+				}
+			}
+			for _, key := range []string{"childIds", "childFrameIds"} {
+				if list, ok := intent[key].([]interface{}); ok {
+					for i := range list {
+						list[i] = remap(list[i])
+					}
+				}
+			}
+			for _, key := range []string{"text", "labelText"} {
+				if text, ok := intent[key].(string); ok {
+					intent[key] = fillPlaceholders(text, values)
+				}
+			}
+			if data, ok := intent["data"].(map[string]interface{}); ok {
+				intent["data"] = fillPlaceholdersDeep(data, values)
+			}
+		}
+	}
+
+	if groups != nil {
+		clonedGroups := make(map[string]interface{}, len(groups))
+		for id, raw := range groups {
+			if group, ok := raw.(map[string]interface{}); ok {
+				group["parentId"] = remap(group["parentId"])
+			}
+			clonedGroups[ids[id]] = raw
+		}
+		doc["groups"] = clonedGroups
+	}
+
+	if name != "" {
+		doc["name"] = name
+	}
+	// Creation timestamps in the metadata belong to the template, not the copy
+	delete(doc, "metadata")
+
+	return json.Marshal(doc)
+}
+
+// fillPlaceholders replaces {{name}} tokens with their values, leaving unknown tokens untouched
+func fillPlaceholders(text string, values map[string]string) string {
+	if len(values) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(token string) string {
+		name := placeholderPattern.FindStringSubmatch(token)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return token
+	})
+}
+
+// fillPlaceholdersDeep applies fillPlaceholders to every string inside a JSON value
+func fillPlaceholdersDeep(value interface{}, values map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fillPlaceholders(v, values)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = fillPlaceholdersDeep(item, values)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fillPlaceholdersDeep(item, values)
+		}
+		return v
+	}
+	return value
+}
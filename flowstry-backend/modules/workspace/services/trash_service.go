@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidRetention = errors.New("invalid trash retention")
+)
+
+// TrashService applies the trash retention policy and empties trash.
+// Permanent deletion always goes through FolderService.HardDelete / DiagramService.HardDelete
+// so storage objects and dependent records are removed too.
+type TrashService struct {
+	workspaces           repository.WorkspaceRepository
+	folders              repository.FolderRepository
+	diagrams             repository.DiagramRepository
+	diagramService       *DiagramService
+	folderService        *FolderService
+	defaultRetentionDays int
+}
+
+// NewTrashService creates a new trash service. defaultRetentionDays <= 0 keeps trash forever
+// unless a workspace sets its own retention.
+func NewTrashService(workspaces repository.WorkspaceRepository, folders repository.FolderRepository, diagrams repository.DiagramRepository, diagramService *DiagramService, folderService *FolderService, defaultRetentionDays int) *TrashService {
+	return &TrashService{
+		workspaces:           workspaces,
+		folders:              folders,
+		diagrams:             diagrams,
+		diagramService:       diagramService,
+		folderService:        folderService,
+		defaultRetentionDays: defaultRetentionDays,
+	}
+}
+
+// ValidateRetention checks a workspace retention setting
+func ValidateRetention(days int) error {
+	if days < models.TrashRetentionForever || days > models.MaxTrashRetentionDays {
+		return ErrInvalidRetention
+	}
+	return nil
+}
+
+// retentionDays returns the effective retention of a workspace, or 0 when trash is kept forever
+func (s *TrashService) retentionDays(workspaceDays int) int {
+	switch {
+	case workspaceDays == models.TrashRetentionForever:
+		return 0
+	case workspaceDays > 0:
+		return workspaceDays
+	case s.defaultRetentionDays > 0:
+		return s.defaultRetentionDays
+	default:
+		return 0
+	}
+}
+
+// Retention returns how long a workspace keeps trashed items (0 = forever)
+func (s *TrashService) Retention(ctx context.Context, workspaceID primitive.ObjectID) (time.Duration, error) {
+	workspace, err := s.workspaces.Get(ctx, workspaceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrWorkspaceNotFound
+		}
+		return 0, err
+	}
+
+	return time.Duration(s.retentionDays(workspace.TrashRetentionDays)) * 24 * time.Hour, nil
+}
+
+// PurgeAt returns when an item trashed at deletedAt will be purged, or nil if never
+func PurgeAt(deletedAt *time.Time, retention time.Duration) *time.Time {
+	if deletedAt == nil || retention <= 0 {
+		return nil
+	}
+	t := deletedAt.Add(retention)
+	return &t
+}
+
+// Empty permanently deletes everything in a workspace's trash
+func (s *TrashService) Empty(ctx context.Context, workspaceID primitive.ObjectID) (*models.EmptyTrashResponse, error) {
+	return s.purge(ctx, workspaceID, nil)
+}
+
+// PurgeExpired permanently deletes trashed items older than their workspace's retention
+func (s *TrashService) PurgeExpired(ctx context.Context) (*models.EmptyTrashResponse, error) {
+	// Only workspaces that have something in trash
+	folderWorkspaces, err := s.folders.WorkspacesWithTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diagramWorkspaces, err := s.diagrams.WorkspacesWithTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[primitive.ObjectID]bool)
+	for _, id := range append(folderWorkspaces, diagramWorkspaces...) {
+		ids[id] = true
+	}
+
+	total := &models.EmptyTrashResponse{}
+	now := time.Now()
+	for workspaceID := range ids {
+		retention, err := s.Retention(ctx, workspaceID)
+		if errors.Is(err, ErrWorkspaceNotFound) {
+			continue
+		}
+		if err != nil {
+			return total, err
+		}
+		if retention <= 0 {
+			continue
+		}
+
+		cutoff := now.Add(-retention)
+		result, err := s.purge(ctx, workspaceID, &cutoff)
+		if err != nil {
+			return total, err
+		}
+		total.FoldersDeleted += result.FoldersDeleted
+		total.DiagramsDeleted += result.DiagramsDeleted
+	}
+
+	return total, nil
+}
+
+// purge hard-deletes trash roots of a workspace deleted before cutoff (all of them when cutoff is nil).
+// Items trashed along with a folder go with that folder.
+func (s *TrashService) purge(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) (*models.EmptyTrashResponse, error) {
+	result := &models.EmptyTrashResponse{}
+
+	folderIDs, err := s.folders.TrashRoots(ctx, workspaceID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, folderID := range folderIDs {
+		err := s.folderService.HardDelete(ctx, folderID, workspaceID)
+		if err == nil {
+			result.FoldersDeleted++
+		} else if !errors.Is(err, ErrFolderNotFound) {
+			return result, err
+		}
+	}
+
+	diagramIDs, err := s.diagrams.TrashRoots(ctx, workspaceID, cutoff)
+	if err != nil {
+		return result, err
+	}
+	for _, diagramID := range diagramIDs {
+		err := s.diagramService.HardDelete(ctx, diagramID, workspaceID)
+		if err == nil {
+			result.DiagramsDeleted++
+		} else if !errors.Is(err, ErrDiagramNotFound) {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// StartPurgeJob runs PurgeExpired in the background every interval, skipping runs while connected reports false
+func (s *TrashService) StartPurgeJob(interval time.Duration, connected func() bool) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if connected() {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				result, err := s.PurgeExpired(ctx)
+				cancel()
+				if err != nil {
+					fmt.Printf("Warning: Trash purge failed: %v\n", err)
+				} else if result.FoldersDeleted+result.DiagramsDeleted > 0 {
+					fmt.Printf("Trash purge removed %d folders and %d diagrams\n", result.FoldersDeleted, result.DiagramsDeleted)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// staleTrashDiagrams reports a trash root that is already gone, as when another purge got there first
+type staleTrashDiagrams struct {
+	repository.DiagramRepository
+	missing primitive.ObjectID
+}
+
+func (r staleTrashDiagrams) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	ids, err := r.DiagramRepository.TrashRoots(ctx, workspaceID, cutoff)
+	return append(ids, r.missing), err
+}
+
+func TestEmptyTrashCountsOnlyPurgedItems(t *testing.T) {
+	tw := newTestWorkspace(t)
+	ctx := context.Background()
+
+	fileStorage, err := storage.NewLocalStorage(filepath.Join(t.TempDir(), "files"), "http://localhost", []byte("signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders := NewFolderService(tw.store.Folders, tw.store.Diagrams)
+	diagrams := NewDiagramService(tw.store.Diagrams, fileStorage, folders)
+	trash := NewTrashService(tw.store.Workspaces, tw.store.Folders, staleTrashDiagrams{tw.store.Diagrams, primitive.NewObjectID()}, diagrams, folders, 0)
+
+	deletedAt := time.Now().Add(-time.Hour)
+	diagram := &models.Diagram{WorkspaceID: tw.id, Name: "Old", DeletedAt: &deletedAt, CreatedAt: deletedAt, UpdatedAt: deletedAt}
+	if err := tw.store.Diagrams.Create(ctx, diagram); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := trash.Empty(ctx, tw.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FoldersDeleted != 0 || result.DiagramsDeleted != 1 {
+		t.Errorf("result = %+v, want 1 diagram deleted", result)
+	}
+	if _, err := tw.store.Diagrams.Get(ctx, tw.id, diagram.ID, repository.AnyTrash); err == nil {
+		t.Error("trashed diagram kept after emptying trash")
+	}
+}
@@ -0,0 +1,787 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("delivery not found")
+	ErrInvalidWebhookURL    = errors.New("webhook URL must be an absolute http or https URL")
+	ErrPrivateWebhookURL    = errors.New("webhook URL must point to a public address")
+	ErrWebhookEncryption    = errors.New("webhooks need the encryption service to store signing secrets")
+	ErrInvalidWebhookEvents = errors.New("subscribe to at least one supported event type")
+	ErrTooManyWebhooks      = errors.New("workspace has too many webhooks")
+	ErrWebhookDisabled      = errors.New("webhook is disabled")
+	ErrDeliveryPending      = errors.New("delivery is still pending")
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookEventHeader     = "X-Flowstry-Event"
+	WebhookDeliveryHeader  = "X-Flowstry-Delivery"
+	WebhookTimestampHeader = "X-Flowstry-Timestamp"
+	WebhookSignatureHeader = "X-Flowstry-Signature"
+)
+
+const (
+	// maxWebhooksPerWorkspace caps the endpoints one workspace can register
+	maxWebhooksPerWorkspace = 20
+	// maxWebhookURLLength bounds registered URLs
+	maxWebhookURLLength = 2048
+	// webhookSecretPrefix marks signing secrets so they are recognizable when leaked
+	webhookSecretPrefix = "whsec_"
+
+	// webhookMaxAttempts is how many times a delivery is tried before it is marked failed
+	webhookMaxAttempts = 8
+	// webhookFirstRetryDelay doubles after each failed attempt, up to webhookMaxRetryDelay
+	webhookFirstRetryDelay = 30 * time.Second
+	webhookMaxRetryDelay   = time.Hour
+	// webhookMaxFailures is how many failed attempts in a row disable a webhook
+	webhookMaxFailures = 20
+	// webhookTimeout bounds one attempt; a claimed delivery becomes due again after it
+	webhookTimeout = 10 * time.Second
+	// webhookBatchSize is how many due deliveries one pass claims at a time
+	webhookBatchSize = 50
+	// webhookConcurrency is how many deliveries are sent at once, so one slow endpoint does not hold up the rest
+	webhookConcurrency = 8
+	// webhookResponseLimit is how much of a response body the delivery log keeps
+	webhookResponseLimit = 1024
+	// webhookResolveTimeout bounds the DNS lookup made when a URL is registered
+	webhookResolveTimeout = 5 * time.Second
+	// deliveryRetention is how long completed deliveries stay in the log
+	deliveryRetention = 30 * 24 * time.Hour
+)
+
+// WebhookService manages workspace webhooks and delivers subscribed events to them.
+// Events are queued as deliveries and sent in the background, signed with the webhook's secret.
+type WebhookService struct {
+	webhooks   repository.WebhookRepository
+	deliveries repository.WebhookDeliveryRepository
+	encryption *EncryptionService
+	client     *http.Client
+	events     *EventBus
+	// allowAddress decides which IPs deliveries may connect to; tests allow loopback receivers
+	allowAddress func(net.IP) bool
+	// wake nudges the worker when deliveries are queued
+	wake chan struct{}
+}
+
+// NewWebhookService creates a new webhook service; secrets are encrypted with the master key
+func NewWebhookService(webhooks repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, encryption *EncryptionService) *WebhookService {
+	s := &WebhookService{
+		webhooks:     webhooks,
+		deliveries:   deliveries,
+		encryption:   encryption,
+		allowAddress: isPublicIP,
+		wake:         make(chan struct{}, 1),
+	}
+	s.client = &http.Client{
+		Timeout: webhookTimeout,
+		// No proxy, and the address is checked after DNS resolution on every connection,
+		// so a name cannot be re-pointed at an internal address once it was registered
+		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: webhookTimeout, Control: s.checkDialAddress}).DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect counts as a failed delivery rather than being followed
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return s
+}
+
+// SetEventBus sets the bus that change events are emitted on (for dependency injection)
+func (s *WebhookService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// emit publishes a change to a webhook
+func (s *WebhookService) emit(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType, webhookID primitive.ObjectID, before, after map[string]string) {
+	if s.events != nil {
+		s.events.Emit(ctx, workspaceID, eventType, models.TargetWebhook, webhookID, before, after)
+	}
+}
+
+// webhookValues describes a webhook in audit entries
+func webhookValues(webhook *models.Webhook) map[string]string {
+	return map[string]string{
+		"url":     webhook.URL,
+		"events":  joinEvents(webhook.Events),
+		"enabled": strconv.FormatBool(webhook.Enabled),
+	}
+}
+
+func joinEvents(events []models.EventType) string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = string(e)
+	}
+	return strings.Join(names, ",")
+}
+
+// SignWebhook returns the signature header of a delivery: "sha256=" followed by the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook's secret
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// nonPublicNetworks are special-purpose ranges the net.IP predicates do not cover:
+// this-network, carrier-grade NAT, IETF protocol assignments, documentation, benchmarking,
+// reserved, and the IPv6 prefixes that embed an IPv4 address (NAT64, 6to4)
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15",
+	"198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4",
+	"64:ff9b::/96", "64:ff9b:1::/48", "2001:db8::/32", "2002::/16",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// isPublicIP reports whether ip is a public unicast address. Loopback, private, link-local
+// (which includes cloud metadata endpoints) and other special-purpose addresses are not.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDialAddress refuses connections to addresses deliveries may not reach. It runs
+// after DNS resolution, so it also holds when a name resolves differently at delivery time.
+func (s *WebhookService) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !s.allowAddress(ip) {
+		return ErrPrivateWebhookURL
+	}
+	return nil
+}
+
+// validateWebhookURL trims a URL and checks it is an absolute http(s) URL whose host does
+// not resolve to an internal address. A name that does not resolve yet is accepted; the
+// address is checked again on every delivery.
+func (s *WebhookService) validateWebhookURL(ctx context.Context, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > maxWebhookURLLength {
+		return "", ErrInvalidWebhookURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidWebhookURL
+	}
+
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		ctx, cancel := context.WithTimeout(ctx, webhookResolveTimeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil {
+			return raw, nil
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !s.allowAddress(ip) {
+			return "", ErrPrivateWebhookURL
+		}
+	}
+	return raw, nil
+}
+
+// validateWebhookEvents checks the event types and drops repeats
+func validateWebhookEvents(events []models.EventType) ([]models.EventType, error) {
+	var unique []models.EventType
+	seen := map[models.EventType]bool{}
+	for _, e := range events {
+		if !models.IsWebhookEvent(e) {
+			return nil, fmt.Errorf("%w: %q is not supported", ErrInvalidWebhookEvents, e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			unique = append(unique, e)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+	return unique, nil
+}
+
+// newWebhookSecret generates a signing secret and its encrypted form
+func (s *WebhookService) newWebhookSecret() (string, []byte, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	if s.encryption == nil {
+		return "", nil, ErrWebhookEncryption
+	}
+	secret := webhookSecretPrefix + hex.EncodeToString(raw)
+	encrypted, err := s.encryption.EncryptWorkspaceKey([]byte(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, encrypted, nil
+}
+
+// List returns the webhooks of a workspace, oldest first
+func (s *WebhookService) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Webhook, error) {
+	webhooks, err := s.webhooks.List(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []*models.Webhook{}
+	}
+	return webhooks, nil
+}
+
+// Get returns a webhook of a workspace
+func (s *WebhookService) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.Webhook, error) {
+	webhook, err := s.webhooks.Get(ctx, workspaceID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// Create registers a webhook. The signing secret is only returned here and by RotateSecret.
+func (s *WebhookService) Create(ctx context.Context, workspaceID, userID primitive.ObjectID, req *models.CreateWebhookRequest) (*models.WebhookWithSecret, error) {
+	endpoint, err := s.validateWebhookURL(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := validateWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhooks.List(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerWorkspace {
+		return nil, ErrTooManyWebhooks
+	}
+
+	secret, encrypted, err := s.newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhook := &models.Webhook{
+		WorkspaceID:     workspaceID,
+		URL:             endpoint,
+		Description:     strings.TrimSpace(req.Description),
+		Events:          events,
+		EncryptedSecret: encrypted,
+		Enabled:         true,
+		CreatedBy:       userID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.webhooks.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	s.emit(ctx, workspaceID, models.EventWebhookCreated, webhook.ID, nil, webhookValues(webhook))
+	return &models.WebhookWithSecret{Webhook: webhook, Secret: secret}, nil
+}
+
+// Update changes a webhook. Enabling it again clears its failure count.
+func (s *WebhookService) Update(ctx context.Context, workspaceID, id primitive.ObjectID, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &repository.WebhookUpdate{Description: req.Description, Enabled: req.Enabled, UpdatedAt: time.Now()}
+	if req.URL != nil {
+		endpoint, err := s.validateWebhookURL(ctx, *req.URL)
+		if err != nil {
+			return nil, err
+		}
+		update.URL = &endpoint
+	}
+	if req.Events != nil {
+		if update.Events, err = validateWebhookEvents(req.Events); err != nil {
+			return nil, err
+		}
+	}
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		update.Description = &description
+	}
+
+	if err := s.webhooks.Update(ctx, workspaceID, id, update); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	updated, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	before, after := map[string]string{}, map[string]string{}
+	was, is := webhookValues(webhook), webhookValues(updated)
+	for _, field := range []string{"url", "events", "enabled"} {
+		changed(before, after, field, was[field], is[field])
+	}
+	changed(before, after, "description", webhook.Description, updated.Description)
+	if len(after) > 0 {
+		s.emit(ctx, workspaceID, models.EventWebhookUpdated, id, before, after)
+	}
+	return updated, nil
+}
+
+// RotateSecret replaces the signing secret of a webhook and returns the new one
+func (s *WebhookService) RotateSecret(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.WebhookWithSecret, error) {
+	if _, err := s.Get(ctx, workspaceID, id); err != nil {
+		return nil, err
+	}
+
+	secret, encrypted, err := s.newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.webhooks.Update(ctx, workspaceID, id, &repository.WebhookUpdate{EncryptedSecret: encrypted, UpdatedAt: time.Now()}); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	webhook, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.emit(ctx, workspaceID, models.EventWebhookSecretRotated, id, nil, nil)
+	return &models.WebhookWithSecret{Webhook: webhook, Secret: secret}, nil
+}
+
+// Delete removes a webhook with its delivery log
+func (s *WebhookService) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	webhook, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.deliveries.DeleteForWebhook(ctx, workspaceID, id); err != nil {
+		return err
+	}
+	if err := s.webhooks.Delete(ctx, workspaceID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+
+	s.emit(ctx, workspaceID, models.EventWebhookDeleted, id, webhookValues(webhook), nil)
+	return nil
+}
+
+// deliveryPageSpec lists the sort options for delivery listings
+var deliveryPageSpec = pageSpec{
+	fields:       []models.SortField{models.SortByCreated},
+	defaultSort:  models.SortByCreated,
+	defaultOrder: models.SortDesc,
+}
+
+// ListDeliveries lists one page of a webhook's delivery log, newest first by default.
+// It returns the cursor of the next page, or "" on the last page.
+func (s *WebhookService) ListDeliveries(ctx context.Context, workspaceID, webhookID primitive.ObjectID, filter *models.WebhookDeliveryFilter, page *models.PageRequest) ([]*models.WebhookDelivery, string, error) {
+	if _, err := s.Get(ctx, workspaceID, webhookID); err != nil {
+		return nil, "", err
+	}
+	p, err := deliveryPageSpec.resolve(page)
+	if err != nil {
+		return nil, "", err
+	}
+	q, err := p.query()
+	if err != nil {
+		return nil, "", err
+	}
+
+	deliveries, err := s.deliveries.List(ctx, workspaceID, webhookID, filter, q)
+	if err != nil {
+		return nil, "", err
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+
+	deliveries, more := splitPage(deliveries, p.limit)
+	if !more {
+		return deliveries, "", nil
+	}
+	last := deliveries[len(deliveries)-1]
+	return deliveries, p.cursorAfter(last.CreatedAt, last.ID), nil
+}
+
+// GetDelivery returns a delivery of a webhook
+func (s *WebhookService) GetDelivery(ctx context.Context, workspaceID, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	delivery, err := s.deliveries.Get(ctx, workspaceID, webhookID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// Redeliver queues a completed delivery again with the same payload
+func (s *WebhookService) Redeliver(ctx context.Context, workspaceID, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	webhook, err := s.Get(ctx, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Enabled {
+		return nil, ErrWebhookDisabled
+	}
+	original, err := s.GetDelivery(ctx, workspaceID, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == models.WebhookDeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WorkspaceID:   workspaceID,
+		WebhookID:     webhookID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: now,
+		RedeliveryOf:  &original.ID,
+		CreatedAt:     now,
+	}
+	if err := s.deliveries.Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+	s.nudge()
+	return delivery, nil
+}
+
+// Record queues a delivery of an event to every enabled webhook that subscribes to it; subscribe it
+// to the workspace EventBus. Failures are logged, as the change has already happened.
+func (s *WebhookService) Record(ctx context.Context, event *models.Event) {
+	if !models.IsWebhookEvent(event.Type) {
+		return
+	}
+	if err := s.enqueue(ctx, event); err != nil {
+		fmt.Printf("Warning: Failed to queue webhooks for %s on %s %s: %v\n", event.Type, event.TargetType, event.TargetID.Hex(), err)
+	}
+}
+
+func (s *WebhookService) enqueue(ctx context.Context, event *models.Event) error {
+	webhooks, err := s.webhooks.ListSubscribed(ctx, event.WorkspaceID, event.Type)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload := &models.WebhookPayload{
+		ID:          primitive.NewObjectID(),
+		Type:        event.Type,
+		CreatedAt:   event.CreatedAt,
+		WorkspaceID: event.WorkspaceID,
+		Target:      models.WebhookTarget{Type: event.TargetType, ID: event.TargetID},
+		Before:      event.Before,
+		After:       event.After,
+	}
+	if actor := event.Actor; actor != nil {
+		payload.Actor = &models.WebhookActor{ID: actor.UserID, Email: actor.Email}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		err := s.deliveries.Create(ctx, &models.WebhookDelivery{
+			WorkspaceID:   event.WorkspaceID,
+			WebhookID:     webhook.ID,
+			EventType:     event.Type,
+			Payload:       string(body),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.nudge()
+	return nil
+}
+
+// nudge wakes the worker without waiting for its next tick
+func (s *WebhookService) nudge() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverDue sends the deliveries that are due and returns how many succeeded and how many failed for good.
+// Failed attempts are retried with exponential backoff.
+func (s *WebhookService) DeliverDue(ctx context.Context) (succeeded, failed int, err error) {
+	for {
+		now := time.Now()
+		due, err := s.deliveries.Due(ctx, now, webhookBatchSize)
+		if err != nil {
+			return succeeded, failed, err
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var firstErr error
+		slots := make(chan struct{}, webhookConcurrency)
+		for _, delivery := range due {
+			// The lease starts once a slot is free; another instance may have claimed the delivery since Due
+			slots <- struct{}{}
+			claimedAt := time.Now()
+			err := s.deliveries.Claim(ctx, delivery.ID, claimedAt, claimedAt.Add(2*webhookTimeout))
+			if err != nil {
+				<-slots
+				if errors.Is(err, repository.ErrNotFound) {
+					continue
+				}
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				break
+			}
+
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer func() { <-slots; wg.Done() }()
+				status, err := s.deliver(ctx, delivery)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+				case status == models.WebhookDeliverySucceeded:
+					succeeded++
+				case status == models.WebhookDeliveryFailed:
+					failed++
+				}
+			}(delivery)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return succeeded, failed, firstErr
+		}
+		if int64(len(due)) < webhookBatchSize {
+			return succeeded, failed, nil
+		}
+	}
+}
+
+// deliver makes one attempt at a claimed delivery, records its outcome and returns the delivery's new status
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) (models.WebhookDeliveryStatus, error) {
+	attempt := &repository.WebhookAttempt{Attempts: delivery.Attempts, NextAttemptAt: time.Now()}
+	giveUp := func(reason string) (models.WebhookDeliveryStatus, error) {
+		now := time.Now()
+		attempt.Status = models.WebhookDeliveryFailed
+		attempt.LastError = reason
+		attempt.CompletedAt = &now
+		return attempt.Status, s.deliveries.RecordAttempt(ctx, delivery.ID, attempt)
+	}
+
+	webhook, err := s.webhooks.Get(ctx, delivery.WorkspaceID, delivery.WebhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return giveUp("webhook was deleted")
+	}
+	if err != nil {
+		return "", err
+	}
+	if !webhook.Enabled {
+		return giveUp(ErrWebhookDisabled.Error())
+	}
+	if s.encryption == nil {
+		return "", ErrWebhookEncryption
+	}
+	secret, err := s.encryption.DecryptWorkspaceKey(webhook.EncryptedSecret)
+	if err != nil {
+		return "", err
+	}
+
+	attempt.Attempts++
+	attempt.ResponseStatus, attempt.ResponseBody, err = s.send(ctx, webhook, delivery, string(secret))
+	now := time.Now()
+	if err == nil {
+		attempt.Status = models.WebhookDeliverySucceeded
+		attempt.CompletedAt = &now
+		if err := s.deliveries.RecordAttempt(ctx, delivery.ID, attempt); err != nil {
+			return "", err
+		}
+		return attempt.Status, s.webhooks.RecordSuccess(ctx, webhook.ID)
+	}
+
+	fmt.Printf("Warning: Webhook delivery %s to %s failed (attempt %d): %v\n", delivery.ID.Hex(), webhook.URL, attempt.Attempts, err)
+	attempt.LastError = err.Error()
+	disabled, recordErr := s.webhooks.RecordFailure(ctx, webhook.ID, webhookMaxFailures,
+		fmt.Sprintf("%d delivery attempts failed in a row, the last with: %v", webhookMaxFailures, err), now)
+	if recordErr != nil {
+		return "", recordErr
+	}
+	if disabled {
+		fmt.Printf("Warning: Disabled webhook %s after %d failed delivery attempts\n", webhook.URL, webhookMaxFailures)
+		s.emit(ctx, webhook.WorkspaceID, models.EventWebhookDisabled, webhook.ID,
+			map[string]string{"enabled": "true"}, map[string]string{"enabled": "false"})
+	}
+
+	if disabled || attempt.Attempts >= webhookMaxAttempts {
+		attempt.Status = models.WebhookDeliveryFailed
+		attempt.CompletedAt = &now
+	} else {
+		attempt.Status = models.WebhookDeliveryPending
+		attempt.NextAttemptAt = now.Add(webhookRetryDelay(attempt.Attempts))
+	}
+	return attempt.Status, s.deliveries.RecordAttempt(ctx, delivery.ID, attempt)
+}
+
+// send posts a delivery's payload and returns the response status and the start of its body.
+// Anything but a 2xx response is an error.
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, secret string) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Flowstry-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, ErrPrivateWebhookURL) {
+		// Leave out the resolved address, which would tell the caller about internal DNS
+		return 0, "", ErrPrivateWebhookURL
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(excerpt), fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(excerpt), nil
+}
+
+// webhookRetryDelay is the wait after the given number of failed attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookFirstRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxRetryDelay {
+		delay = webhookMaxRetryDelay
+	}
+	return delay
+}
+
+// StartWorker runs DeliverDue in the background every interval, and right away when deliveries are
+// queued, and prunes old deliveries. Runs are skipped while connected reports false.
+func (s *WebhookService) StartWorker(interval time.Duration, connected func() bool) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if connected() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+				succeeded, failed, err := s.DeliverDue(ctx)
+				if err != nil {
+					fmt.Printf("Warning: Webhook delivery run failed: %v\n", err)
+				} else if succeeded+failed > 0 {
+					fmt.Printf("Webhooks delivered %d events, %d failed\n", succeeded, failed)
+				}
+				if _, err := s.deliveries.DeleteCompletedBefore(ctx, time.Now().Add(-deliveryRetention)); err != nil {
+					fmt.Printf("Warning: Failed to prune webhook deliveries: %v\n", err)
+				}
+				cancel()
+			}
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// DeleteAllForWorkspace removes the webhooks and delivery log of a workspace (used when deleting workspace)
+func (s *WebhookService) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	if err := s.deliveries.DeleteAllForWorkspace(ctx, workspaceID); err != nil {
+		return err
+	}
+	return s.webhooks.DeleteAllForWorkspace(ctx, workspaceID)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookReceiver records the requests a test endpoint receives
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func newTestWebhooks(t *testing.T) (*testWorkspace, *WebhookService, *EventBus) {
+	t.Helper()
+	tw := newTestWorkspace(t)
+	encryption, err := NewEncryptionService(filepath.Join(t.TempDir(), "master.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks := NewWebhookService(tw.store.Webhooks, tw.store.Deliveries, encryption)
+	// The test receivers listen on loopback
+	webhooks.allowAddress = func(net.IP) bool { return true }
+	events := NewEventBus()
+	webhooks.SetEventBus(events)
+	events.Subscribe(webhooks.Record)
+	return tw, webhooks, events
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	tw, webhooks, events := newTestWebhooks(t)
+	ctx := WithActor(context.Background(), &models.Actor{UserID: tw.admin.ID, Email: tw.admin.Email})
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if _, err := webhooks.Create(ctx, tw.id, tw.admin.ID, &models.CreateWebhookRequest{URL: "ftp://ci.example.com", Events: models.WebhookEvents}); !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Errorf("ftp URL err = %v", err)
+	}
+	if _, err := webhooks.Create(ctx, tw.id, tw.admin.ID, &models.CreateWebhookRequest{URL: server.URL, Events: []models.EventType{models.EventWorkspaceKeyFetched}}); !errors.Is(err, ErrInvalidWebhookEvents) {
+		t.Errorf("unsupported event err = %v", err)
+	}
+	webhook, err := webhooks.Create(ctx, tw.id, tw.admin.ID, &models.CreateWebhookRequest{
+		URL:    server.URL,
+		Events: []models.EventType{models.EventDiagramUpdated, models.EventDiagramUpdated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhook.Events) != 1 || len(webhook.Secret) == 0 || !webhook.Enabled {
+		t.Fatalf("webhook = %+v", webhook)
+	}
+
+	diagramID := primitive.NewObjectID()
+	events.Emit(ctx, tw.id, models.EventDiagramUpdated, models.TargetDiagram, diagramID, nil, map[string]string{"name": "Checkout"})
+	events.Emit(ctx, tw.id, models.EventDiagramCreated, models.TargetDiagram, diagramID, nil, nil)
+	events.Emit(ctx, primitive.NewObjectID(), models.EventDiagramUpdated, models.TargetDiagram, diagramID, nil, nil)
+
+	succeeded, failed, err := webhooks.DeliverDue(context.Background())
+	if err != nil || succeeded != 1 || failed != 0 {
+		t.Fatalf("DeliverDue = %d, %d, %v", succeeded, failed, err)
+	}
+
+	req, body := receiver.requests[0], receiver.bodies[0]
+	timestamp, _ := strconv.ParseInt(req.Header.Get(WebhookTimestampHeader), 10, 64)
+	if got := req.Header.Get(WebhookSignatureHeader); got != SignWebhook(webhook.Secret, timestamp, body) {
+		t.Errorf("signature = %q", got)
+	}
+	if req.Header.Get(WebhookEventHeader) != "diagram.updated" {
+		t.Errorf("event header = %q", req.Header.Get(WebhookEventHeader))
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Target.ID != diagramID || payload.Actor == nil || payload.Actor.ID != tw.admin.ID || payload.After["name"] != "Checkout" {
+		t.Errorf("payload = %s", body)
+	}
+
+	// A redelivery sends the same payload under a new delivery ID
+	deliveries, _, err := webhooks.ListDeliveries(ctx, tw.id, webhook.ID, nil, nil)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Status != models.WebhookDeliverySucceeded || deliveries[0].ResponseStatus != http.StatusNoContent {
+		t.Fatalf("deliveries = %+v, %v", deliveries, err)
+	}
+	if _, err := webhooks.Redeliver(ctx, tw.id, webhook.ID, deliveries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := webhooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(receiver.bodies) != 2 || string(receiver.bodies[1]) != string(body) ||
+		receiver.requests[1].Header.Get(WebhookDeliveryHeader) == req.Header.Get(WebhookDeliveryHeader) {
+		t.Errorf("redelivery = %s", receiver.bodies[1:])
+	}
+
+	// Rotating the secret signs later deliveries with the new one
+	rotated, err := webhooks.RotateSecret(ctx, tw.id, webhook.ID)
+	if err != nil || rotated.Secret == webhook.Secret {
+		t.Fatalf("rotate = %+v, %v", rotated, err)
+	}
+	events.Emit(ctx, tw.id, models.EventDiagramUpdated, models.TargetDiagram, diagramID, nil, nil)
+	if _, _, err := webhooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	req, body = receiver.requests[2], receiver.bodies[2]
+	timestamp, _ = strconv.ParseInt(req.Header.Get(WebhookTimestampHeader), 10, 64)
+	if req.Header.Get(WebhookSignatureHeader) != SignWebhook(rotated.Secret, timestamp, body) {
+		t.Errorf("signature after rotation = %q", req.Header.Get(WebhookSignatureHeader))
+	}
+}
+
+func TestWebhookRetryAndDisable(t *testing.T) {
+	tw, webhooks, events := newTestWebhooks(t)
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	var disabledEvents int
+	events.Subscribe(func(ctx context.Context, event *models.Event) {
+		if event.Type == models.EventWebhookDisabled {
+			disabledEvents++
+		}
+	})
+
+	webhook, err := webhooks.Create(ctx, tw.id, tw.admin.ID, &models.CreateWebhookRequest{
+		URL: server.URL, Events: []models.EventType{models.EventMemberAdded},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed attempt is retried later with backoff
+	events.Emit(ctx, tw.id, models.EventMemberAdded, models.TargetMember, tw.viewer.ID, nil, nil)
+	if succeeded, failed, err := webhooks.DeliverDue(ctx); err != nil || succeeded+failed != 0 {
+		t.Fatalf("DeliverDue = %d, %d, %v", succeeded, failed, err)
+	}
+	deliveries, _, err := webhooks.ListDeliveries(ctx, tw.id, webhook.ID, nil, nil)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("deliveries = %+v, %v", deliveries, err)
+	}
+	retried := deliveries[0]
+	if retried.Status != models.WebhookDeliveryPending || retried.Attempts != 1 || retried.LastError != "HTTP 500" ||
+		retried.NextAttemptAt.Sub(retried.CreatedAt) < webhookFirstRetryDelay {
+		t.Errorf("retried delivery = %+v", retried)
+	}
+	if _, err := webhooks.Redeliver(ctx, tw.id, webhook.ID, retried.ID); !errors.Is(err, ErrDeliveryPending) {
+		t.Errorf("redeliver pending err = %v", err)
+	}
+	if d := webhookRetryDelay(2); d != 2*webhookFirstRetryDelay {
+		t.Errorf("second retry delay = %v", d)
+	}
+	if d := webhookRetryDelay(webhookMaxAttempts * 2); d != webhookMaxRetryDelay {
+		t.Errorf("retry delay cap = %v", d)
+	}
+
+	// Enough failures in a row disable the webhook
+	for i := 1; i < webhookMaxFailures; i++ {
+		events.Emit(ctx, tw.id, models.EventMemberAdded, models.TargetMember, tw.viewer.ID, nil, nil)
+	}
+	if _, failed, err := webhooks.DeliverDue(ctx); err != nil || failed != 1 {
+		t.Fatalf("DeliverDue failed = %d, %v", failed, err)
+	}
+	disabled, err := webhooks.Get(ctx, tw.id, webhook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disabled.Enabled || disabled.DisabledReason == "" || disabled.ConsecutiveFailures != webhookMaxFailures {
+		t.Errorf("webhook after failures = %+v", disabled)
+	}
+	if disabledEvents != 1 {
+		t.Errorf("disabled events = %d", disabledEvents)
+	}
+
+	// A disabled webhook gets no new deliveries until it is enabled again
+	events.Emit(ctx, tw.id, models.EventMemberAdded, models.TargetMember, tw.viewer.ID, nil, nil)
+	all, _, err := webhooks.ListDeliveries(ctx, tw.id, webhook.ID, nil, &models.PageRequest{Limit: 100})
+	if err != nil || len(all) != webhookMaxFailures {
+		t.Errorf("deliveries while disabled = %d, %v", len(all), err)
+	}
+	enabled := true
+	updated, err := webhooks.Update(ctx, tw.id, webhook.ID, &models.UpdateWebhookRequest{Enabled: &enabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Enabled || updated.ConsecutiveFailures != 0 || updated.DisabledReason != "" {
+		t.Errorf("re-enabled webhook = %+v", updated)
+	}
+}
+
+func TestWebhookPrivateAddresses(t *testing.T) {
+	tw, webhooks, events := newTestWebhooks(t)
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhook, err := webhooks.Create(ctx, tw.id, tw.admin.ID, &models.CreateWebhookRequest{
+		URL: server.URL, Events: []models.EventType{models.EventMemberAdded},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webhooks.allowAddress = isPublicIP
+	for _, endpoint := range []string{
+		"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://[::1]/hook", "http://10.1.2.3/hook",
+		"http://169.254.169.254/latest/meta-data", "http://100.64.0.1/hook", "http://[::ffff:192.168.0.1]/hook",
+	} {
+		if _, err := webhooks.Create(ctx, tw.id, tw.admin.ID, &models.CreateWebhookRequest{URL: endpoint, Events: models.WebhookEvents}); !errors.Is(err, ErrPrivateWebhookURL) {
+			t.Errorf("create %s err = %v", endpoint, err)
+		}
+	}
+	if _, err := webhooks.Update(ctx, tw.id, webhook.ID, &models.UpdateWebhookRequest{URL: &[]string{"http://192.168.1.1/"}[0]}); !errors.Is(err, ErrPrivateWebhookURL) {
+		t.Errorf("update to a private address err = %v", err)
+	}
+	if !isPublicIP(net.ParseIP("93.184.216.34")) || !isPublicIP(net.ParseIP("2606:2800:220:1::")) {
+		t.Error("public addresses are rejected")
+	}
+
+	// A registered name that now resolves to an internal address is refused when connecting
+	events.Emit(ctx, tw.id, models.EventMemberAdded, models.TargetMember, tw.viewer.ID, nil, nil)
+	if succeeded, _, err := webhooks.DeliverDue(ctx); err != nil || succeeded != 0 {
+		t.Fatalf("DeliverDue succeeded = %d, %v", succeeded, err)
+	}
+	deliveries, _, err := webhooks.ListDeliveries(ctx, tw.id, webhook.ID, nil, nil)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("deliveries = %+v, %v", deliveries, err)
+	}
+	if d := deliveries[0]; d.LastError != ErrPrivateWebhookURL.Error() || d.ResponseStatus != 0 || d.ResponseBody != "" {
+		t.Errorf("delivery to a private address = %+v", d)
+	}
+	if len(receiver.requests) != 0 {
+		t.Errorf("receiver got %d requests", len(receiver.requests))
+	}
+}
+
+func TestWebhooksWithoutEncryption(t *testing.T) {
+	tw := newTestWorkspace(t)
+	webhooks := NewWebhookService(tw.store.Webhooks, tw.store.Deliveries, nil)
+	if _, err := webhooks.Create(context.Background(), tw.id, tw.admin.ID, &models.CreateWebhookRequest{
+		URL: "https://hooks.example.com/flowstry", Events: models.WebhookEvents,
+	}); !errors.Is(err, ErrWebhookEncryption) {
+		t.Errorf("create without encryption err = %v", err)
+	}
+}
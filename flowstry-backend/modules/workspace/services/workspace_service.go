@@ -3,13 +3,12 @@ package services
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
-	"github.com/flowstry/flowstry-backend/database"
 	"github.com/flowstry/flowstry-backend/modules/workspace/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/flowstry/flowstry-backend/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -19,14 +18,33 @@ var (
 
 // WorkspaceService handles workspace operations
 type WorkspaceService struct {
+	workspaces        repository.WorkspaceRepository
+	members           repository.MemberRepository
+	folders           repository.FolderRepository
+	diagrams          repository.DiagramRepository
 	memberService     *MemberService
 	inviteService     *InviteService
 	encryptionService *EncryptionService
+	shareService      *ShareService
+	embedService      *EmbedService
+	searchService     *SearchService
+	tagService        *TagService
+	accessService     *AccessService
+	starService       *StarService
+	commentService    *CommentService
+	mentionService    *MentionService
+	webhookService    *WebhookService
+	events            *EventBus
 }
 
 // NewWorkspaceService creates a new workspace service
-func NewWorkspaceService() *WorkspaceService {
-	return &WorkspaceService{}
+func NewWorkspaceService(workspaces repository.WorkspaceRepository, members repository.MemberRepository, folders repository.FolderRepository, diagrams repository.DiagramRepository) *WorkspaceService {
+	return &WorkspaceService{
+		workspaces: workspaces,
+		members:    members,
+		folders:    folders,
+		diagrams:   diagrams,
+	}
 }
 
 // SetMemberService sets the member service (for dependency injection)
@@ -44,13 +62,65 @@ func (s *WorkspaceService) SetEncryptionService(es *EncryptionService) {
 	s.encryptionService = es
 }
 
-// Create creates a new workspace and adds the creator as owner
-func (s *WorkspaceService) Create(ctx context.Context, userID primitive.ObjectID, req *models.CreateWorkspaceRequest) (*models.Workspace, error) {
-	collection := database.GetCollection("workspaces")
-	if collection == nil {
-		return nil, errors.New("database not connected")
+// SetShareService sets the share service
+func (s *WorkspaceService) SetShareService(ss *ShareService) {
+	s.shareService = ss
+}
+
+// SetEmbedService sets the embed service
+func (s *WorkspaceService) SetEmbedService(es *EmbedService) {
+	s.embedService = es
+}
+
+// SetSearchService sets the search service
+func (s *WorkspaceService) SetSearchService(ss *SearchService) {
+	s.searchService = ss
+}
+
+// SetTagService sets the tag service
+func (s *WorkspaceService) SetTagService(ts *TagService) {
+	s.tagService = ts
+}
+
+// SetAccessService sets the access service
+func (s *WorkspaceService) SetAccessService(as *AccessService) {
+	s.accessService = as
+}
+
+// SetStarService sets the star service
+func (s *WorkspaceService) SetStarService(ss *StarService) {
+	s.starService = ss
+}
+
+// SetCommentService sets the comment service
+func (s *WorkspaceService) SetCommentService(cs *CommentService) {
+	s.commentService = cs
+}
+
+// SetMentionService sets the mention service
+func (s *WorkspaceService) SetMentionService(ms *MentionService) {
+	s.mentionService = ms
+}
+
+// SetWebhookService sets the webhook service
+func (s *WorkspaceService) SetWebhookService(ws *WebhookService) {
+	s.webhookService = ws
+}
+
+// SetEventBus sets the bus that change events are emitted on
+func (s *WorkspaceService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// emit publishes a change to a workspace
+func (s *WorkspaceService) emit(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType, before, after map[string]string) {
+	if s.events != nil {
+		s.events.Emit(ctx, workspaceID, eventType, models.TargetWorkspace, workspaceID, before, after)
 	}
+}
 
+// Create creates a new workspace and adds the creator as owner
+func (s *WorkspaceService) Create(ctx context.Context, userID primitive.ObjectID, req *models.CreateWorkspaceRequest) (*models.Workspace, error) {
 	workspace := &models.Workspace{
 		UserID:      userID,
 		Name:        req.Name,
@@ -72,37 +142,25 @@ func (s *WorkspaceService) Create(ctx context.Context, userID primitive.ObjectID
 		workspace.EncryptedKey = encryptedKey
 	}
 
-	result, err := collection.InsertOne(ctx, workspace)
-	if err != nil {
-		return nil, err
+	// The creator joins as owner in the same write
+	owner := &models.WorkspaceMember{
+		UserID:   userID,
+		Role:     models.RoleOwner,
+		JoinedAt: workspace.CreatedAt,
 	}
-
-	workspace.ID = result.InsertedID.(primitive.ObjectID)
-
-	// Add creator as owner member
-	if s.memberService != nil {
-		_, err = s.memberService.AddMember(ctx, workspace.ID, userID, models.RoleOwner)
-		if err != nil {
-			// Rollback workspace creation
-			collection.DeleteOne(ctx, bson.M{"_id": workspace.ID})
-			return nil, err
-		}
+	if err := s.workspaces.CreateWithOwner(ctx, workspace, owner); err != nil {
+		return nil, err
 	}
 
+	s.emit(ctx, workspace.ID, models.EventWorkspaceCreated, nil, map[string]string{"name": workspace.Name})
 	return workspace, nil
 }
 
 // GetByID retrieves a workspace by ID (checks member access)
 func (s *WorkspaceService) GetByID(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.Workspace, error) {
-	collection := database.GetCollection("workspaces")
-	if collection == nil {
-		return nil, errors.New("database not connected")
-	}
-
-	var workspace models.Workspace
-	err := collection.FindOne(ctx, bson.M{"_id": workspaceID}).Decode(&workspace)
+	workspace, err := s.workspaces.Get(ctx, workspaceID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrWorkspaceNotFound
 		}
 		return nil, err
@@ -120,7 +178,7 @@ func (s *WorkspaceService) GetByID(ctx context.Context, workspaceID, userID prim
 		}
 	}
 
-	return &workspace, nil
+	return workspace, nil
 }
 
 // GetByIDWithRole retrieves a workspace and the user's role
@@ -142,49 +200,28 @@ func (s *WorkspaceService) GetByIDWithRole(ctx context.Context, workspaceID, use
 
 // List retrieves all workspaces where user is a member
 func (s *WorkspaceService) List(ctx context.Context, userID primitive.ObjectID) ([]*models.Workspace, map[primitive.ObjectID]models.WorkspaceRole, error) {
-	collection := database.GetCollection("workspaces")
-	if collection == nil {
-		return nil, nil, errors.New("database not connected")
-	}
-
-	memberCollection := database.GetCollection("workspace_members")
 	workspaceIDs := make([]primitive.ObjectID, 0)
 	roleMap := make(map[primitive.ObjectID]models.WorkspaceRole)
 	seen := make(map[primitive.ObjectID]struct{})
 
 	// Get all workspace IDs where user is a member
-	if memberCollection != nil {
-		var memberRecords []models.WorkspaceMember
-		cursor, err := memberCollection.Find(ctx, bson.M{"user_id": userID})
-		if err != nil {
-			return nil, nil, err
-		}
-		defer cursor.Close(ctx)
-
-		if err := cursor.All(ctx, &memberRecords); err != nil {
-			return nil, nil, err
-		}
-
-		for _, m := range memberRecords {
-			if _, ok := seen[m.WorkspaceID]; !ok {
-				seen[m.WorkspaceID] = struct{}{}
-				workspaceIDs = append(workspaceIDs, m.WorkspaceID)
-			}
-			roleMap[m.WorkspaceID] = m.Role
+	memberRecords, err := s.members.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range memberRecords {
+		if _, ok := seen[m.WorkspaceID]; !ok {
+			seen[m.WorkspaceID] = struct{}{}
+			workspaceIDs = append(workspaceIDs, m.WorkspaceID)
 		}
+		roleMap[m.WorkspaceID] = m.Role
 	}
 
 	// Always include workspaces where the user is the owner
-	ownerCursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	ownedWorkspaces, err := s.workspaces.ListOwnedBy(ctx, userID)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer ownerCursor.Close(ctx)
-
-	var ownedWorkspaces []models.Workspace
-	if err := ownerCursor.All(ctx, &ownedWorkspaces); err != nil {
-		return nil, nil, err
-	}
 	for _, w := range ownedWorkspaces {
 		if _, ok := seen[w.ID]; !ok {
 			seen[w.ID] = struct{}{}
@@ -199,35 +236,20 @@ func (s *WorkspaceService) List(ctx context.Context, userID primitive.ObjectID)
 	}
 
 	// Fetch workspaces
-	workspaceCursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": workspaceIDs}})
+	workspaces, err := s.workspaces.ListByIDs(ctx, workspaceIDs)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer workspaceCursor.Close(ctx)
-
-	var workspaces []*models.Workspace
-	if err := workspaceCursor.All(ctx, &workspaces); err != nil {
-		return nil, nil, err
-	}
 
 	// For each workspace, count diagrams and folders
-	diagramsCollection := database.GetCollection("diagrams")
-	foldersCollection := database.GetCollection("folders")
-
 	for _, w := range workspaces {
 		if w.UserID == userID {
 			roleMap[w.ID] = models.RoleOwner
 		} else if roleMap[w.ID] == models.RoleOwner {
 			roleMap[w.ID] = models.RoleAdmin
 		}
-		if diagramsCollection != nil {
-			count, _ := diagramsCollection.CountDocuments(ctx, bson.M{"workspace_id": w.ID})
-			w.DiagramCount = count
-		}
-		if foldersCollection != nil {
-			count, _ := foldersCollection.CountDocuments(ctx, bson.M{"workspace_id": w.ID})
-			w.FolderCount = count
-		}
+		w.DiagramCount, _ = s.diagrams.CountForWorkspace(ctx, w.ID)
+		w.FolderCount, _ = s.folders.CountForWorkspace(ctx, w.ID)
 	}
 
 	return workspaces, roleMap, nil
@@ -246,23 +268,34 @@ func (s *WorkspaceService) Update(ctx context.Context, workspaceID, userID primi
 		return nil, ErrForbidden
 	}
 
-	collection := database.GetCollection("workspaces")
+	update := &repository.WorkspaceUpdate{
+		Name:                 req.Name,
+		Description:          req.Description,
+		ContentSearchEnabled: req.ContentSearchEnabled,
+		UpdatedAt:            time.Now(),
+	}
+	if req.TrashRetentionDays != nil {
+		if err := ValidateRetention(*req.TrashRetentionDays); err != nil {
+			return nil, err
+		}
+		update.TrashRetentionDays = req.TrashRetentionDays
+	}
 
-	update := bson.M{"updated_at": time.Now()}
+	before, after := map[string]string{}, map[string]string{}
 	if req.Name != nil {
-		update["name"] = *req.Name
+		changed(before, after, "name", workspace.Name, *req.Name)
 	}
 	if req.Description != nil {
-		update["description"] = *req.Description
+		changed(before, after, "description", workspace.Description, *req.Description)
+	}
+	if req.TrashRetentionDays != nil {
+		changed(before, after, "trash_retention_days", strconv.Itoa(workspace.TrashRetentionDays), strconv.Itoa(*req.TrashRetentionDays))
+	}
+	if req.ContentSearchEnabled != nil {
+		changed(before, after, "content_search_enabled", strconv.FormatBool(workspace.ContentSearchEnabled), strconv.FormatBool(*req.ContentSearchEnabled))
 	}
 
-
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": workspaceID},
-		bson.M{"$set": update},
-	)
-	if err != nil {
+	if err := s.workspaces.Update(ctx, workspaceID, update); err != nil {
 		return nil, err
 	}
 
@@ -273,16 +306,29 @@ func (s *WorkspaceService) Update(ctx context.Context, workspaceID, userID primi
 	if req.Description != nil {
 		workspace.Description = *req.Description
 	}
+	if req.TrashRetentionDays != nil {
+		workspace.TrashRetentionDays = *req.TrashRetentionDays
+	}
+	if req.ContentSearchEnabled != nil && *req.ContentSearchEnabled != workspace.ContentSearchEnabled {
+		workspace.ContentSearchEnabled = *req.ContentSearchEnabled
+		// Backfill or drop the shape text index in the background
+		if s.searchService != nil {
+			s.searchService.ScheduleWorkspaceIndexing(workspaceID, workspace.ContentSearchEnabled)
+		}
+	}
 
-	workspace.UpdatedAt = time.Now()
+	workspace.UpdatedAt = update.UpdatedAt
 
+	if len(after) > 0 {
+		s.emit(ctx, workspaceID, models.EventWorkspaceUpdated, before, after)
+	}
 	return workspace, nil
 }
 
 // Delete deletes a workspace and all its contents (Owner only)
 func (s *WorkspaceService) Delete(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
 	// Verify owner access
-	_, err := s.GetByID(ctx, workspaceID, userID)
+	workspace, err := s.GetByID(ctx, workspaceID, userID)
 	if err != nil {
 		return err
 	}
@@ -292,25 +338,14 @@ func (s *WorkspaceService) Delete(ctx context.Context, workspaceID, userID primi
 		return ErrForbidden
 	}
 
-	collection := database.GetCollection("workspaces")
-
 	// Delete workspace
-	_, err = collection.DeleteOne(ctx, bson.M{"_id": workspaceID})
-	if err != nil {
+	if err := s.workspaces.Delete(ctx, workspaceID); err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return err
 	}
 
-	// Delete associated folders
-	foldersCollection := database.GetCollection("folders")
-	if foldersCollection != nil {
-		_, _ = foldersCollection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
-	}
-
-	// Delete associated diagrams
-	diagramsCollection := database.GetCollection("diagrams")
-	if diagramsCollection != nil {
-		_, _ = diagramsCollection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
-	}
+	// Delete associated folders and diagrams
+	_ = s.folders.DeleteAllForWorkspace(ctx, workspaceID)
+	_ = s.diagrams.DeleteAllForWorkspace(ctx, workspaceID)
 
 	// Delete all members
 	if s.memberService != nil {
@@ -322,6 +357,44 @@ func (s *WorkspaceService) Delete(ctx context.Context, workspaceID, userID primi
 		_ = s.inviteService.DeleteAllInvites(ctx, workspaceID)
 	}
 
+	// Delete all share links
+	if s.shareService != nil {
+		_ = s.shareService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+
+	// Delete all embed tokens
+	if s.embedService != nil {
+		_ = s.embedService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+
+	// Delete all tag definitions
+	if s.tagService != nil {
+		_ = s.tagService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+
+	// Delete recently-opened history and stars
+	if s.accessService != nil {
+		_ = s.accessService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+	if s.starService != nil {
+		_ = s.starService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+
+	// Delete all comments and mentions
+	if s.commentService != nil {
+		_ = s.commentService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+	if s.mentionService != nil {
+		_ = s.mentionService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+
+	// Delete webhooks with their delivery log
+	if s.webhookService != nil {
+		_ = s.webhookService.DeleteAllForWorkspace(ctx, workspaceID)
+	}
+
+	// The audit log outlives the workspace
+	s.emit(ctx, workspaceID, models.EventWorkspaceDeleted, map[string]string{"name": workspace.Name}, nil)
 	return nil
 }
 
@@ -331,7 +404,6 @@ func (s *WorkspaceService) VerifyAccess(ctx context.Context, workspaceID, userID
 	return err
 }
 
-
 // GetWorkspaceKey retrieves the decrypted workspace key (Admin+ only)
 func (s *WorkspaceService) GetWorkspaceKey(ctx context.Context, workspaceID, userID primitive.ObjectID) ([]byte, error) {
 	workspace, err := s.GetByID(ctx, workspaceID, userID)
@@ -350,7 +422,7 @@ func (s *WorkspaceService) GetWorkspaceKey(ctx context.Context, workspaceID, use
 			return nil, ErrForbidden
 		}
 	}
-	
+
 	if len(workspace.EncryptedKey) == 0 {
 		return nil, errors.New("workspace is not encrypted")
 	}
@@ -359,6 +431,11 @@ func (s *WorkspaceService) GetWorkspaceKey(ctx context.Context, workspaceID, use
 		return nil, errors.New("encryption service not configured")
 	}
 
-	return s.encryptionService.DecryptWorkspaceKey(workspace.EncryptedKey)
-}
+	key, err := s.encryptionService.DecryptWorkspaceKey(workspace.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
 
+	s.emit(ctx, workspaceID, models.EventWorkspaceKeyFetched, nil, nil)
+	return key, nil
+}
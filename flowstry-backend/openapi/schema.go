@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is an OpenAPI 3.0 schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Description          string             `json:"description,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	fileType     = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// schemaRegistry derives schemas from Go types the way encoding/json marshals them.
+// Named structs become components referenced by name; the first type to claim a name keeps it
+// and later ones are prefixed with their module (AuthUser, WorkspaceUser).
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of t; tag is the struct tag naming fields ("json", or "form" for forms)
+func (r *schemaRegistry) of(t reflect.Type, tag string) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return r.of(t.Elem(), tag)
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.of(t.Elem(), tag)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.of(t.Elem(), tag)}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t, tag)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.component(t, tag)}
+	}
+	panic(fmt.Sprintf("openapi: cannot describe %s", t))
+}
+
+// component registers the named struct t and returns its component name
+func (r *schemaRegistry) component(t reflect.Type, tag string) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := r.components[name]; taken {
+		name = modulePrefix(t.PkgPath()) + name
+	}
+	r.names[t] = name
+	// Register before describing the fields so self-referencing types resolve
+	r.components[name] = &Schema{}
+	*r.components[name] = *r.object(t, tag)
+	return name
+}
+
+// object describes the exported fields of a struct, flattening embedded structs
+func (r *schemaRegistry) object(t reflect.Type, tag string) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	r.addFields(s, t, tag)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type, tag string) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts := parseTag(field.Tag.Get(tag))
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		ft := field.Type
+		if field.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := r.of(ft, tag)
+		omitempty := strings.Contains(opts, "omitempty")
+		if !omitempty {
+			s.Required = append(s.Required, name)
+			// encoding/json writes null for nil pointers, slices and maps
+			switch ft.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				if tag == "json" {
+					schema = nullable(schema)
+				}
+			}
+		}
+		s.Properties[name] = schema
+	}
+
+	// Fields of embedded structs are promoted unless the outer struct declares them
+	for _, et := range embedded {
+		inner := r.object(et, tag)
+		for name, schema := range inner.Properties {
+			if _, ok := s.Properties[name]; ok {
+				continue
+			}
+			s.Properties[name] = schema
+			for _, required := range inner.Required {
+				if required == name {
+					s.Required = append(s.Required, name)
+				}
+			}
+		}
+	}
+}
+
+// nullable allows null in place of schema; references are wrapped since siblings of $ref are ignored
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+	}
+	schema.Nullable = true
+	return schema
+}
+
+func parseTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// modulePrefix names the module of a package path (".../modules/auth/models" is "Auth")
+func modulePrefix(pkgPath string) string {
+	parts := strings.Split(pkgPath, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] != "models" && parts[i] != "" {
+			return strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return ""
+}
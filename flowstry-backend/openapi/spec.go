@@ -0,0 +1,449 @@
+// Package openapi documents the versioned API. Routes are registered through a Group together with
+// an Operation describing their parameters, request body and response; the Spec renders them as the
+// OpenAPI 3 document served at /openapi.json. Request and response schemas are derived from the
+// model types with reflection, so the document follows the models as they change.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flowstry/flowstry-backend/middleware"
+	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Operation documents one route
+type Operation struct {
+	// Summary is a one-line description of the route
+	Summary string
+	// Description optionally explains the route further
+	Description string
+	// Params lists the query and header parameters the handler reads; path parameters are
+	// taken from the route pattern
+	Params []Param
+	// Body is a value of the JSON request body model
+	Body interface{}
+	// Form is a value of a struct describing a multipart/form-data body by its form tags;
+	// *multipart.FileHeader fields are file parts
+	Form interface{}
+	// Response is a value of the data returned in the success envelope; without one the
+	// route answers with a message
+	Response interface{}
+	// Raw sends Response as the whole body instead of wrapping it in the envelope
+	Raw bool
+	// Produces lists the media types of a non-JSON response body (a file, an image, a redirect
+	// when empty with a 3xx Status)
+	Produces []string
+	// Status is the success status (200 when zero)
+	Status int
+	// Paginated listings take the limit, cursor, sort and order parameters and return a
+	// pagination cursor next to the data
+	Paginated bool
+	// Public routes need no authentication
+	Public bool
+	// Scope is the personal access token scope the route requires. It is enforced right
+	// before the route's last handler, so after any authentication the route mounts itself.
+	Scope authModels.TokenScope
+}
+
+// Param documents a query or header parameter
+type Param struct {
+	Name        string
+	Description string
+	// In is "query" (the default) or "header"
+	In string
+	// Type is "string" (the default), "integer" or "boolean"
+	Type     string
+	Enum     []string
+	Required bool
+}
+
+// PageParams are the parameters of paginated listings
+var PageParams = []Param{
+	{Name: "limit", Type: "integer", Description: "Page size (the listing's default when omitted)"},
+	{Name: "cursor", Description: "Opaque cursor of the next page, from pagination.next_cursor"},
+	{Name: "sort", Description: "Field to sort by"},
+	{Name: "order", Enum: []string{"asc", "desc"}, Description: "Sort order"},
+}
+
+// Spec collects the documented operations of one API version
+type Spec struct {
+	title    string
+	version  string
+	basePath string
+
+	operations []*operation
+	// roots are the first path segments of all operations, used to serve unversioned paths
+	roots map[string]bool
+
+	once     sync.Once
+	document []byte
+}
+
+type operation struct {
+	Operation
+	method string
+	path   string
+	tag    string
+	id     string
+}
+
+// New creates the spec of the API served under basePath (such as "/v1")
+func New(title, version, basePath string) *Spec {
+	return &Spec{title: title, version: version, basePath: basePath, roots: map[string]bool{}}
+}
+
+// BasePath returns the path prefix of the API version
+func (s *Spec) BasePath() string {
+	return s.basePath
+}
+
+// Group registers documented routes under prefix on router, which serves the spec's base path.
+// Handlers run before every route of the group; tag groups the operations in the document.
+func (s *Spec) Group(router fiber.Router, prefix, tag string, handlers ...fiber.Handler) *Group {
+	return &Group{spec: s, router: router.Group(prefix, handlers...), prefix: prefix, tag: tag}
+}
+
+// Group registers routes under a common prefix and documents them
+type Group struct {
+	spec   *Spec
+	router fiber.Router
+	prefix string
+	tag    string
+}
+
+// Tagged returns a view of the group that files its operations under another tag
+func (g *Group) Tagged(tag string) *Group {
+	return &Group{spec: g.spec, router: g.router, prefix: g.prefix, tag: tag}
+}
+
+// Get registers a GET route
+func (g *Group) Get(path string, op Operation, handlers ...fiber.Handler) {
+	g.add(http.MethodGet, path, op, handlers)
+}
+
+// Post registers a POST route
+func (g *Group) Post(path string, op Operation, handlers ...fiber.Handler) {
+	g.add(http.MethodPost, path, op, handlers)
+}
+
+// Put registers a PUT route
+func (g *Group) Put(path string, op Operation, handlers ...fiber.Handler) {
+	g.add(http.MethodPut, path, op, handlers)
+}
+
+// Delete registers a DELETE route
+func (g *Group) Delete(path string, op Operation, handlers ...fiber.Handler) {
+	g.add(http.MethodDelete, path, op, handlers)
+}
+
+func (g *Group) add(method, path string, op Operation, handlers []fiber.Handler) {
+	if op.Scope != "" {
+		// RequireScope passes requests without a token, so it must run once the token is loaded
+		last := len(handlers) - 1
+		handlers = append(append(handlers[:last:last], middleware.RequireScope(op.Scope)), handlers[last])
+	}
+	g.router.Add(method, path, handlers...)
+
+	full := strings.TrimSuffix(g.prefix+path, "/")
+	g.spec.roots[strings.SplitN(strings.TrimPrefix(full, "/"), "/", 2)[0]] = true
+	g.spec.operations = append(g.spec.operations, &operation{
+		Operation: op,
+		method:    method,
+		path:      full,
+		tag:       g.tag,
+		id:        operationID(handlers[len(handlers)-1]),
+	})
+}
+
+// operationID names an operation after its handler: (*DiagramController).List is "diagramList"
+func operationID(handler fiber.Handler) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name[strings.LastIndex(name, ".(")+1:], "-fm")
+	name = strings.NewReplacer("(*", "", ")", "", "Controller.", "").Replace(name)
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// Routes lists the documented routes as "METHOD /path" with the base path and fiber's
+// :param syntax, in registration order
+func (s *Spec) Routes() []string {
+	routes := make([]string, len(s.operations))
+	for i, op := range s.operations {
+		routes[i] = op.method + " " + s.basePath + op.path
+	}
+	return routes
+}
+
+// JSON renders the OpenAPI document; call it once every route is registered
+func (s *Spec) JSON() []byte {
+	s.once.Do(func() {
+		document, err := json.MarshalIndent(s.build(), "", "  ")
+		if err != nil {
+			panic("openapi: " + err.Error())
+		}
+		s.document = append(document, '\n')
+	})
+	return s.document
+}
+
+// Document returns the OpenAPI document as a generic JSON value
+func (s *Spec) Document() map[string]interface{} {
+	var document map[string]interface{}
+	_ = json.Unmarshal(s.JSON(), &document)
+	return document
+}
+
+// Handler serves the OpenAPI document
+func (s *Spec) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(s.JSON())
+	}
+}
+
+// Unversioned serves requests for a documented path without the version prefix as the versioned
+// route, so clients and links from before versioning keep working. Register it before any other
+// middleware: routing restarts on the rewritten path and runs every handler from the start.
+func (s *Spec) Unversioned() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		root := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+		if !s.roots[root] {
+			return c.Next()
+		}
+		c.Path(s.basePath + path)
+		return c.RestartRouting()
+	}
+}
+
+// build assembles the document
+func (s *Spec) build() map[string]interface{} {
+	schemas := newSchemaRegistry()
+	schemas.of(reflect.TypeOf(utils.Pagination{}), "json")
+	schemas.components["Message"] = &Schema{
+		Type:     "object",
+		Required: []string{"message", "success"},
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+		},
+	}
+	schemas.components["Error"] = &Schema{
+		Type:     "object",
+		Required: []string{"error"},
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"error":   {Type: "string"},
+		},
+	}
+
+	paths := map[string]map[string]interface{}{}
+	tags := map[string]bool{}
+	for _, op := range s.operations {
+		path := openAPIPath(op.path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(op.method)] = op.describe(schemas)
+		tags[op.tag] = true
+	}
+
+	tagList := []map[string]string{}
+	for tag := range tags {
+		tagList = append(tagList, map[string]string{"name": tag})
+	}
+	sort.Slice(tagList, func(i, j int) bool { return tagList[i]["name"] < tagList[j]["name"] })
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   s.title,
+			"version": s.version,
+			"description": "Responses wrap their data in an envelope: {\"success\": true, \"data\": ...}, with " +
+				"\"pagination\" on listings and {\"success\": false, \"error\": \"...\"} on errors. " +
+				"Authenticate with the access_token cookie of a session or a bearer token; personal access " +
+				"tokens (fpat_...) need the scope named by each operation.",
+		},
+		"servers": []map[string]string{{"url": s.basePath}},
+		"tags":    tagList,
+		"paths":   paths,
+		"security": []map[string][]string{
+			{"bearerAuth": {}},
+			{"cookieAuth": {}},
+		},
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "An access token or a personal access token",
+				},
+				"cookieAuth": map[string]string{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": "access_token",
+				},
+			},
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(&Schema{Ref: "#/components/schemas/Error"}),
+				},
+			},
+		},
+	}
+}
+
+// describe renders the operation object
+func (op *operation) describe(schemas *schemaRegistry) map[string]interface{} {
+	out := map[string]interface{}{
+		"operationId": op.id,
+		"summary":     op.Summary,
+		"tags":        []string{op.tag},
+	}
+
+	description := op.Description
+	if op.Scope != "" {
+		if description != "" {
+			description += "\n\n"
+		}
+		description += "Personal access tokens need the `" + string(op.Scope) + "` scope."
+		out["x-token-scope"] = op.Scope
+	}
+	if description != "" {
+		out["description"] = description
+	}
+	if op.Public {
+		out["security"] = []map[string][]string{}
+	}
+
+	params := []map[string]interface{}{}
+	for _, segment := range strings.Split(op.path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, map[string]interface{}{
+				"name":     segment[1:],
+				"in":       "path",
+				"required": true,
+				"schema":   &Schema{Type: "string"},
+			})
+		}
+	}
+	queryParams := op.Params
+	if op.Paginated {
+		queryParams = append(append([]Param{}, op.Params...), PageParams...)
+	}
+	for _, p := range queryParams {
+		params = append(params, p.describe())
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	content := map[string]interface{}{}
+	if op.Body != nil {
+		content[fiber.MIMEApplicationJSON] = map[string]*Schema{"schema": schemas.of(reflect.TypeOf(op.Body), "json")}
+	}
+	if op.Form != nil {
+		content[fiber.MIMEMultipartForm] = map[string]*Schema{"schema": schemas.of(reflect.TypeOf(op.Form), "form")}
+	}
+	if len(content) > 0 {
+		out["requestBody"] = map[string]interface{}{"required": true, "content": content}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case len(op.Produces) > 0:
+		media := map[string]interface{}{}
+		for _, mediaType := range op.Produces {
+			media[mediaType] = map[string]*Schema{"schema": {Type: "string", Format: "binary"}}
+		}
+		success["content"] = media
+	case status >= 300 && status < 400:
+		success["headers"] = map[string]interface{}{
+			"Location": map[string]*Schema{"schema": {Type: "string"}},
+		}
+	case op.Raw:
+		success["content"] = jsonContent(schemas.of(reflect.TypeOf(op.Response), "json"))
+	case op.Response != nil:
+		success["content"] = jsonContent(envelope(schemas.of(reflect.TypeOf(op.Response), "json"), op.Paginated))
+	default:
+		success["content"] = jsonContent(&Schema{Ref: "#/components/schemas/Message"})
+	}
+	out["responses"] = map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default":            map[string]string{"$ref": "#/components/responses/Error"},
+	}
+	return out
+}
+
+func (p Param) describe() map[string]interface{} {
+	in, typ := p.In, p.Type
+	if in == "" {
+		in = "query"
+	}
+	if typ == "" {
+		typ = "string"
+	}
+	out := map[string]interface{}{
+		"name":   p.Name,
+		"in":     in,
+		"schema": &Schema{Type: typ, Enum: p.Enum},
+	}
+	if p.Description != "" {
+		out["description"] = p.Description
+	}
+	if p.Required {
+		out["required"] = true
+	}
+	return out
+}
+
+// envelope wraps data in the success envelope of utils.Response
+func envelope(data *Schema, paginated bool) *Schema {
+	s := &Schema{
+		Type:     "object",
+		Required: []string{"data", "success"},
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"data":    data,
+		},
+	}
+	if paginated {
+		s.Required = []string{"data", "pagination", "success"}
+		s.Properties["pagination"] = &Schema{Ref: "#/components/schemas/Pagination"}
+	}
+	return s
+}
+
+func jsonContent(schema *Schema) map[string]interface{} {
+	return map[string]interface{}{
+		fiber.MIMEApplicationJSON: map[string]*Schema{"schema": schema},
+	}
+}
+
+// openAPIPath converts fiber's :param segments to {param}
+func openAPIPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/config"
+	authServices "github.com/flowstry/flowstry-backend/modules/auth/services"
+	workspaceServices "github.com/flowstry/flowstry-backend/modules/workspace/services"
+	"github.com/flowstry/flowstry-backend/openapi"
+	"github.com/flowstry/flowstry-backend/repository/memory"
+	"github.com/flowstry/flowstry-backend/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite openapi.json from the registered routes")
+
+// newTestAPI wires the API like main does, over the memory store and local storage
+func newTestAPI(t *testing.T) (*fiber.App, *openapi.Spec) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{
+		FrontendURL:        "http://localhost:3000",
+		PublicURL:          "http://localhost:8080",
+		JWTSecret:          "secret",
+		AccessTokenExpiry:  time.Hour,
+		RefreshTokenExpiry: time.Hour,
+		EncryptionKeyFile:  filepath.Join(dir, "master.key"),
+		RateLimitGlobal:    10000,
+		RateLimitAuth:      10000,
+		TrashRetentionDays: 30,
+		LiveCollabWSURL:    "ws://localhost:8081/ws",
+	}
+	store := memory.New()
+	fileStorage, err := storage.NewLocalStorage(filepath.Join(dir, "files"), cfg.PublicURL, []byte("signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authService := authServices.NewAuthService(cfg, store.Users, store.RefreshTokens, store.AccessTokens)
+	liveCollabService := workspaceServices.NewLiveCollabService(store.Users, "", cfg.LiveCollabWSURL, "live-secret", "flowstry-backend", "live-collab-service")
+
+	app := fiber.New()
+	spec := openapi.New("Flowstry API", apiVersion, "/v1")
+	app.Use(spec.Unversioned())
+	setupAPI(app, spec, cfg, store, authService, authServices.NewGoogleService(cfg), fileStorage, liveCollabService, nil)
+	return app, spec
+}
+
+// TestOpenAPIDocument keeps the committed openapi.json in step with the routes; run
+// go test -run TestOpenAPIDocument -update after changing a route or a model
+func TestOpenAPIDocument(t *testing.T) {
+	_, spec := newTestAPI(t)
+	if *update {
+		if err := os.WriteFile("openapi.json", spec.JSON(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	committed, err := os.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, spec.JSON()) {
+		t.Error("openapi.json is out of date; run go test -run TestOpenAPIDocument -update")
+	}
+}
+
+// TestOpenAPIRoutes checks that every versioned route is documented, exactly once
+func TestOpenAPIRoutes(t *testing.T) {
+	app, spec := newTestAPI(t)
+
+	documented := map[string]bool{}
+	for _, route := range spec.Routes() {
+		if documented[route] {
+			t.Errorf("%s is documented twice", route)
+		}
+		documented[route] = true
+	}
+
+	ids := map[string]bool{}
+	for _, item := range spec.Document()["paths"].(map[string]interface{}) {
+		for _, op := range item.(map[string]interface{}) {
+			id := op.(map[string]interface{})["operationId"].(string)
+			if ids[id] {
+				t.Errorf("operationId %s is used twice", id)
+			}
+			ids[id] = true
+		}
+	}
+
+	served := 0
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == http.MethodHead || !strings.HasPrefix(route.Path, spec.BasePath()+"/") {
+			continue
+		}
+		served++
+		// Fiber matches with or without the trailing slash of a group's root route
+		if !documented[route.Method+" "+strings.TrimSuffix(route.Path, "/")] {
+			t.Errorf("%s %s is not documented", route.Method, route.Path)
+		}
+	}
+	if served != len(documented) {
+		t.Errorf("%d versioned routes are served, %d documented", served, len(documented))
+	}
+}
+
+// contract sends requests through the app and checks each JSON response against the document
+type contract struct {
+	t       *testing.T
+	app     *fiber.App
+	doc     map[string]interface{}
+	token   string
+	covered map[string]bool
+}
+
+// multipartBody is a request body sent as multipart/form-data
+type multipartBody struct {
+	fields map[string]string
+	file   []byte
+}
+
+func (ct *contract) call(method, path string, body interface{}, want int) map[string]interface{} {
+	ct.t.Helper()
+
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case multipartBody:
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for name, value := range b.fields {
+			_ = w.WriteField(name, value)
+		}
+		if b.file != nil {
+			part, _ := w.CreateFormFile("file", "diagram.flowstry")
+			_, _ = part.Write(b.file)
+		}
+		_ = w.Close()
+		reader, contentType = &buf, w.FormDataContentType()
+	default:
+		data, _ := json.Marshal(b)
+		reader, contentType = bytes.NewReader(data), fiber.MIMEApplicationJSON
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set(fiber.HeaderContentType, contentType)
+	}
+	if ct.token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+ct.token)
+	}
+	resp, err := ct.app.Test(req, 10000)
+	if err != nil {
+		ct.t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != want {
+		ct.t.Fatalf("%s %s: status = %d, want %d: %s", method, path, resp.StatusCode, want, raw)
+	}
+
+	pathTemplate, op := ct.operation(method, path)
+	if op == nil {
+		ct.t.Fatalf("%s %s is not documented", method, path)
+	}
+	ct.covered[method+" "+pathTemplate] = true
+
+	documented, ok := op["responses"].(map[string]interface{})[strconv.Itoa(want)].(map[string]interface{})
+	if !ok {
+		ct.t.Fatalf("%s %s: status %d is not documented", method, pathTemplate, want)
+	}
+	content, _ := documented["content"].(map[string]interface{})
+	media, ok := content[fiber.MIMEApplicationJSON].(map[string]interface{})
+	if !ok || !strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return nil
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		ct.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	for _, problem := range ct.validate(media["schema"].(map[string]interface{}), decoded, "body") {
+		ct.t.Errorf("%s %s: %s", method, pathTemplate, problem)
+	}
+	return decoded
+}
+
+// embedETags checks that snapshots of different sizes are not revalidated with each other's ETag
+func (ct *contract) embedETags(path string) {
+	ct.t.Helper()
+
+	get := func(query, ifNoneMatch string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, path+query, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := ct.app.Test(req, 10000)
+		if err != nil {
+			ct.t.Fatal(err)
+		}
+		return resp
+	}
+
+	etag := get("", "").Header.Get(fiber.HeaderETag)
+	if resp := get("", etag); resp.StatusCode != http.StatusNotModified {
+		ct.t.Fatalf("revalidating the same size: status = %d", resp.StatusCode)
+	}
+	resized := get("?maxwidth=200&maxheight=100", etag)
+	if resized.StatusCode != http.StatusOK || resized.Header.Get(fiber.HeaderETag) == etag {
+		ct.t.Fatalf("revalidating another size: status = %d, ETag = %s", resized.StatusCode, resized.Header.Get(fiber.HeaderETag))
+	}
+}
+
+// data calls a route and returns the data of its envelope
+func (ct *contract) data(method, path string, body interface{}, want int) map[string]interface{} {
+	ct.t.Helper()
+	data, _ := ct.call(method, path, body, want)["data"].(map[string]interface{})
+	return data
+}
+
+// operation finds the documented operation serving a request path, preferring literal segments
+func (ct *contract) operation(method, path string) (string, map[string]interface{}) {
+	path = strings.TrimPrefix(strings.SplitN(path, "?", 2)[0], "/v1")
+	segments := strings.Split(path, "/")
+
+	best, bestLiterals := "", -1
+	for template := range ct.doc["paths"].(map[string]interface{}) {
+		parts := strings.Split(template, "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		literals := 0
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") {
+				continue
+			}
+			if part != segments[i] {
+				literals = -1
+				break
+			}
+			literals++
+		}
+		item := ct.doc["paths"].(map[string]interface{})[template].(map[string]interface{})
+		if literals > bestLiterals && item[strings.ToLower(method)] != nil {
+			best, bestLiterals = template, literals
+		}
+	}
+	if best == "" {
+		return "", nil
+	}
+	item := ct.doc["paths"].(map[string]interface{})[best].(map[string]interface{})
+	return best, item[strings.ToLower(method)].(map[string]interface{})
+}
+
+// validate lists where value departs from schema; undocumented properties count as drift
+func (ct *contract) validate(schema map[string]interface{}, value interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		components := ct.doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		return ct.validate(components[name].(map[string]interface{}), value, at)
+	}
+	if value == nil {
+		if schema["nullable"] == true || len(schema) == 0 {
+			return nil
+		}
+		return []string{at + " is null"}
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		var problems []string
+		for _, sub := range allOf {
+			problems = append(problems, ct.validate(sub.(map[string]interface{}), value, at)...)
+		}
+		return problems
+	}
+
+	var problems []string
+	mismatch := func() []string { return []string{fmt.Sprintf("%s is %T, documented as %v", at, value, schema["type"])} }
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, at+"."+name.(string)+" is missing")
+			}
+		}
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, v := range object {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				problems = append(problems, ct.validate(property, v, at+"."+name)...)
+			} else if additional != nil {
+				problems = append(problems, ct.validate(additional, v, at+"."+name)...)
+			} else {
+				problems = append(problems, at+"."+name+" is not documented")
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for i, item := range items {
+			problems = append(problems, ct.validate(schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return mismatch()
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return mismatch()
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return mismatch()
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return mismatch()
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// TestOpenAPIContract walks through the API and checks that handlers answer as documented
+func TestOpenAPIContract(t *testing.T) {
+	app, spec := newTestAPI(t)
+	ct := &contract{t: t, app: app, doc: spec.Document(), covered: map[string]bool{}}
+
+	// Signing up returns the session as cookies
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/signup", strings.NewReader(
+		`{"email":"owner@example.com","password":"correct-horse","name":"Owner"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "access_token" {
+			ct.token = cookie.Value
+		}
+	}
+	if resp.StatusCode != http.StatusCreated || ct.token == "" {
+		t.Fatalf("signup: status = %d", resp.StatusCode)
+	}
+	ct.call(http.MethodPost, "/v1/auth/signin", map[string]string{"email": "owner@example.com", "password": "correct-horse"}, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/auth/me", nil, http.StatusOK)
+	ct.call(http.MethodPut, "/v1/auth/preferences", map[string]string{"theme": "dark"}, http.StatusOK)
+	token := ct.data(http.MethodPost, "/v1/auth/tokens", map[string]interface{}{"name": "ci", "scopes": []string{"diagrams:read"}}, http.StatusCreated)
+	ct.call(http.MethodGet, "/v1/auth/tokens", nil, http.StatusOK)
+
+	// Workspaces, folders and diagrams
+	workspace := ct.data(http.MethodPost, "/v1/workspaces", map[string]string{"name": "Platform"}, http.StatusCreated)
+	ws := "/v1/workspaces/" + workspace["id"].(string)
+	ct.call(http.MethodGet, "/v1/workspaces", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws, nil, http.StatusOK)
+	ct.call(http.MethodPut, ws, map[string]string{"description": "Platform team"}, http.StatusOK)
+	// Diagram files are encrypted by the client with the workspace key
+	key, _ := base64.StdEncoding.DecodeString(ct.data(http.MethodGet, ws+"/key", nil, http.StatusOK)["key"].(string))
+	encrypt := func(content string) []byte {
+		packed, err := (&workspaceServices.EncryptionService{}).EncryptContent(key, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return packed
+	}
+
+	folder := ct.data(http.MethodPost, ws+"/folders", map[string]string{"name": "Services"}, http.StatusCreated)
+	folderPath := ws + "/folders/" + folder["id"].(string)
+	ct.call(http.MethodGet, ws+"/folders", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/folders/tree", nil, http.StatusOK)
+	ct.call(http.MethodGet, folderPath, nil, http.StatusOK)
+	ct.call(http.MethodPut, folderPath, map[string]string{"color": "#ff0000"}, http.StatusOK)
+	ct.call(http.MethodGet, folderPath+"/breadcrumbs", nil, http.StatusOK)
+
+	diagram := ct.data(http.MethodPost, ws+"/diagrams", multipartBody{
+		fields: map[string]string{"name": "Checkout", "folder_id": folder["id"].(string)},
+		file:   encrypt(`{"shapes":[{"id":"s1","type":"text","text":"Checkout"}]}`),
+	}, http.StatusCreated)
+	diagramID := diagram["id"].(string)
+	d := ws + "/diagrams/" + diagramID
+	ct.call(http.MethodGet, ws+"/diagrams?limit=10", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/files", nil, http.StatusOK)
+	ct.call(http.MethodGet, d, nil, http.StatusOK)
+	ct.call(http.MethodPut, d, map[string]interface{}{"name": "Checkout flow", "tags": []string{"payments"}}, http.StatusOK)
+	ct.call(http.MethodPut, d, multipartBody{file: encrypt(`{"shapes":[]}`)}, http.StatusOK)
+	ct.call(http.MethodGet, d+"/download", nil, http.StatusOK)
+	ct.call(http.MethodGet, d+"/download-url", nil, http.StatusOK)
+	ct.call(http.MethodGet, d+"/upload-url?type=thumbnail", nil, http.StatusOK)
+	ct.call(http.MethodPost, d+"/star", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/workspaces/starred", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/workspaces/recents", nil, http.StatusOK)
+	ct.call(http.MethodDelete, d+"/star", nil, http.StatusOK)
+	ct.call(http.MethodPost, d+"/move", map[string]string{"folder_id": ""}, http.StatusOK)
+	ct.call(http.MethodPost, ws+"/diagrams/move", map[string]interface{}{"diagram_ids": []string{diagramID}, "folder_id": folder["id"]}, http.StatusOK)
+	ct.call(http.MethodGet, d+"/live", nil, http.StatusOK)
+
+	// Tags and templates
+	ct.call(http.MethodPost, ws+"/tags", map[string]string{"name": "infra"}, http.StatusCreated)
+	ct.call(http.MethodPost, ws+"/diagrams/tags", map[string]interface{}{"diagram_ids": []string{diagramID}, "add": []string{"infra"}}, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/tags", nil, http.StatusOK)
+	ct.call(http.MethodPut, d+"/template", map[string]string{"scope": "workspace"}, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/templates", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/templates/"+diagramID, nil, http.StatusOK)
+	ct.call(http.MethodDelete, d+"/template", nil, http.StatusOK)
+
+	// Comments and mentions
+	thread := ct.data(http.MethodPost, d+"/comments", map[string]interface{}{
+		"body": "Should this retry?", "anchor": map[string]interface{}{"shape_id": "s1", "x": 1, "y": 2},
+	}, http.StatusCreated)
+	c := d + "/comments/" + thread["id"].(string)
+	ct.call(http.MethodPost, c+"/replies", map[string]string{"body": "Yes @owner@example.com"}, http.StatusCreated)
+	ct.call(http.MethodPut, c, map[string]string{"body": "Should this retry twice?"}, http.StatusOK)
+	ct.call(http.MethodPost, c+"/resolve", nil, http.StatusOK)
+	ct.call(http.MethodPost, c+"/reopen", nil, http.StatusOK)
+	ct.call(http.MethodGet, c, nil, http.StatusOK)
+	ct.call(http.MethodGet, d+"/comments", nil, http.StatusOK)
+	ct.call(http.MethodGet, d+"/mentions", nil, http.StatusOK)
+	ct.call(http.MethodPost, ws+"/mentions/resolve", map[string]string{"text": "@owner@example.com"}, http.StatusOK)
+
+	// Sharing
+	share := ct.data(http.MethodPost, d+"/shares", map[string]int{"expires_in": 24}, http.StatusCreated)
+	ct.call(http.MethodGet, d+"/shares", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/share/"+share["token"].(string), nil, http.StatusOK)
+	embed := ct.data(http.MethodPost, d+"/embeds", map[string]string{"label": "Wiki"}, http.StatusCreated)
+	ct.call(http.MethodGet, "/v1/embed/"+embed["token"].(string), nil, http.StatusOK)
+	ct.embedETags("/v1/embed/" + embed["token"].(string))
+	ct.call(http.MethodGet, d+"/embeds", nil, http.StatusOK)
+
+	// Members, invites, feeds and webhooks
+	ct.call(http.MethodGet, ws+"/members", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/members/search?q=own", nil, http.StatusOK)
+	ct.call(http.MethodPost, ws+"/invites", map[string]string{"email": "editor@example.com", "role": "editor"}, http.StatusCreated)
+	ct.call(http.MethodGet, ws+"/invites", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/invites", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/activity", nil, http.StatusOK)
+	ct.call(http.MethodGet, d+"/activity", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/audit", nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/audit/export?format=jsonl", nil, http.StatusOK)
+	webhook := ct.data(http.MethodPost, ws+"/webhooks", map[string]interface{}{
+		"url": "https://hooks.example.com/flowstry", "events": []string{"diagram.updated"},
+	}, http.StatusCreated)
+	hook := "/v1/workspaces/" + workspace["id"].(string) + "/webhooks/" + webhook["id"].(string)
+	ct.call(http.MethodGet, ws+"/webhooks", nil, http.StatusOK)
+	ct.call(http.MethodGet, hook, nil, http.StatusOK)
+	ct.call(http.MethodPut, hook, map[string]interface{}{"description": "CI"}, http.StatusOK)
+	ct.call(http.MethodPost, hook+"/rotate-secret", nil, http.StatusOK)
+	ct.call(http.MethodGet, hook+"/deliveries", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/notifications", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/notifications/unread-count", nil, http.StatusOK)
+	ct.call(http.MethodPost, "/v1/notifications/read-all", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/notifications/preferences", nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/search?q=checkout", nil, http.StatusOK)
+
+	// Trash
+	ct.call(http.MethodDelete, d, nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/trash", nil, http.StatusOK)
+	ct.call(http.MethodPost, d+"/restore", nil, http.StatusOK)
+	ct.call(http.MethodDelete, folderPath, nil, http.StatusOK)
+	ct.call(http.MethodGet, ws+"/folders/trash", nil, http.StatusOK)
+	ct.call(http.MethodDelete, ws+"/trash", nil, http.StatusOK)
+
+	// Unversioned paths are served as /v1, and token scopes are enforced per operation
+	ct.call(http.MethodGet, "/workspaces", nil, http.StatusOK)
+	ct.token = token["token"].(string)
+	ct.call(http.MethodGet, ws, nil, http.StatusOK)
+	ct.call(http.MethodGet, "/v1/auth/me", nil, http.StatusOK)
+	// Routes that mount AuthMiddleware themselves check the scope after it
+	for _, route := range []struct{ method, path string }{
+		{http.MethodDelete, ws},
+		{http.MethodPut, "/v1/auth/preferences"},
+		{http.MethodPut, "/v1/notifications/preferences"},
+		{http.MethodPost, "/v1/notifications/read-all"},
+	} {
+		req = httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+ct.token)
+		if resp, err := app.Test(req); err != nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("read-only token on %s %s: %v %v", route.method, route.path, resp.StatusCode, err)
+		}
+	}
+
+	t.Logf("exercised %d of %d operations", len(ct.covered), len(spec.Routes()))
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityRepository persists workspace activity feeds
+type ActivityRepository interface {
+	Append(ctx context.Context, entry *models.ActivityEntry) error
+	// Latest returns the most recently updated entry about a target (ErrNotFound when there is none)
+	Latest(ctx context.Context, workspaceID, targetID primitive.ObjectID) (*models.ActivityEntry, error)
+	// Extend adds one change to a session entry and moves its updated_at forward
+	Extend(ctx context.Context, workspaceID, entryID primitive.ObjectID, updatedAt time.Time) error
+	// List pages through a workspace's feed; only SortByUpdated is supported
+	List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.ActivityFilter, page *PageQuery) ([]*models.ActivityEntry, error)
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditRepository persists the audit log. Entries are only ever appended, and they are
+// kept when the workspace they describe is deleted.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+	// List pages through a workspace's entries; only SortByCreated is supported
+	List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.AuditFilter, page *PageQuery) ([]*models.AuditEntry, error)
+}
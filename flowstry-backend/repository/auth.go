@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository persists user accounts. Emails are unique.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByGoogleID(ctx context.Context, googleID string) (*models.User, error)
+	// LinkGoogle attaches a Google account to an existing user
+	LinkGoogle(ctx context.Context, id primitive.ObjectID, googleID, avatarURL string) error
+	UpdatePreferences(ctx context.Context, id primitive.ObjectID, prefs models.UserPreferences) error
+	UpdateNotificationPreferences(ctx context.Context, id primitive.ObjectID, prefs models.NotificationPreferences) error
+}
+
+// RefreshTokenRepository persists hashed refresh tokens
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	Revoke(ctx context.Context, hash string) error
+	RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// PersonalAccessTokenRepository persists hashed personal access tokens
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error)
+	// ListForUser returns the tokens of a user, newest first
+	ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error)
+	// Touch records when a token was last used
+	Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// Delete removes a token of a user
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentUpdate lists the comment fields to change; nil fields are left alone
+type CommentUpdate struct {
+	Body     *string
+	EditedAt *time.Time
+	// Resolved sets (true) or clears (false) the resolution; a resolution is stamped with ResolvedBy and UpdatedAt
+	Resolved   *bool
+	ResolvedBy primitive.ObjectID
+	UpdatedAt  time.Time
+}
+
+// CommentRepository persists diagram comments
+type CommentRepository interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	Get(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) (*models.Comment, error)
+	// ListThreads pages through the thread roots of a diagram; SortByCreated and SortByUpdated are supported
+	ListThreads(ctx context.Context, workspaceID, diagramID primitive.ObjectID, filter *models.CommentFilter, page *PageQuery) ([]*models.Comment, error)
+	// ListReplies returns the replies to the given threads, oldest first
+	ListReplies(ctx context.Context, workspaceID, diagramID primitive.ObjectID, threadIDs []primitive.ObjectID) ([]*models.Comment, error)
+	// ListShapeAnchored returns the thread roots of a diagram that are anchored to a shape, unordered
+	ListShapeAnchored(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Comment, error)
+	Update(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, update *CommentUpdate) error
+	// SetAnchor moves a thread's anchor without counting as an update
+	SetAnchor(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, anchor models.CommentAnchor) error
+	// Delete removes a comment and, for a thread root, its replies; ErrNotFound if there is none
+	Delete(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) error
+	DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FolderQuery narrows a folder listing. Trashed listings only return trash roots:
+// folders trashed along with an ancestor come back with it and are not listed.
+type FolderQuery struct {
+	Trash  Trash
+	Filter *models.FolderFilter
+}
+
+// FolderUpdate lists the folder fields to change; nil fields are left alone
+type FolderUpdate struct {
+	Name        *string
+	Description *string
+	Color       *string
+	// SetParent moves the folder under ParentFolderID (the workspace root when nil)
+	SetParent      bool
+	ParentFolderID *primitive.ObjectID
+	UpdatedAt      time.Time
+}
+
+// FolderRepository persists folders
+type FolderRepository interface {
+	Create(ctx context.Context, folder *models.Folder) error
+	Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash Trash) (*models.Folder, error)
+	List(ctx context.Context, workspaceID primitive.ObjectID, query *FolderQuery, page *PageQuery) ([]*models.Folder, error)
+	// ListAll returns every folder of a workspace in the given trash state, unordered
+	ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash Trash) ([]*models.Folder, error)
+	CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error)
+	// Update changes a live folder
+	Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *FolderUpdate) error
+
+	// Ancestors returns the ancestors of a folder ordered from the workspace root down to its parent
+	Ancestors(ctx context.Context, workspaceID, id primitive.ObjectID) ([]*models.Folder, error)
+	// Descendants returns the IDs of all subfolders of a folder, following only live folders
+	// and, when batchID is set, folders trashed in that batch
+	Descendants(ctx context.Context, workspaceID, id primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error)
+
+	// Trash moves live folders to trash in one batch. withParent marks them as trashed along
+	// with an ancestor. It returns the number of folders trashed.
+	Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error)
+	// Restore brings trashed folders back
+	Restore(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error
+	// RestoreBatch brings back every folder trashed in a batch
+	RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error
+	// DetachFromMissingParents moves live folders whose parent is trashed or gone to the workspace root
+	DetachFromMissingParents(ctx context.Context, workspaceID primitive.ObjectID) error
+	// Unparent moves every folder directly under one of parentIDs to the workspace root
+	Unparent(ctx context.Context, workspaceID primitive.ObjectID, parentIDs []primitive.ObjectID) error
+	// TrashRoots returns the trash roots deleted before cutoff (all of them when cutoff is nil)
+	TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error)
+	// WorkspacesWithTrash returns the workspaces that have folders in trash
+	WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error)
+
+	// Search returns up to limit live folders of the workspaces whose name contains every term
+	// (case-insensitively), most recently updated first
+	Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, limit int64) ([]*models.Folder, error)
+
+	DeleteMany(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// DiagramQuery narrows a diagram listing. Trashed listings only return trash roots:
+// diagrams trashed along with a folder come back with it and are not listed.
+type DiagramQuery struct {
+	Trash  Trash
+	Filter *models.DiagramFilter
+}
+
+// DiagramUpdate lists the diagram fields to change; nil fields are left alone
+type DiagramUpdate struct {
+	Name        *string
+	Description *string
+	Thumbnail   *string
+	Tags        *[]string
+	FileURL     *string
+	FileSize    *int64
+	Version     *int
+	UpdatedAt   time.Time
+}
+
+// DiagramRepository persists diagram metadata. Listings leave out the search index text.
+type DiagramRepository interface {
+	Create(ctx context.Context, diagram *models.Diagram) error
+	Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash Trash) (*models.Diagram, error)
+	List(ctx context.Context, workspaceID primitive.ObjectID, query *DiagramQuery, page *PageQuery) ([]*models.Diagram, error)
+	// ListByIDs returns the live diagrams among ids that belong to one of workspaceIDs and match filter, unordered
+	ListByIDs(ctx context.Context, workspaceIDs, ids []primitive.ObjectID, filter *models.DiagramFilter) ([]*models.Diagram, error)
+	CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error)
+	// CountLive counts how many of ids are live diagrams of the workspace
+	CountLive(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) (int64, error)
+	// CountByFolder counts live diagrams per folder, plus those outside any folder
+	CountByFolder(ctx context.Context, workspaceID primitive.ObjectID) (map[primitive.ObjectID]int64, int64, error)
+	// Update changes a diagram
+	Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *DiagramUpdate) error
+
+	// SetFolder moves live diagrams into a folder (the workspace root when folderID is nil).
+	// It returns the number of diagrams matched.
+	SetFolder(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, folderID *primitive.ObjectID, at time.Time) (int64, error)
+	// RemoveFromFolder moves a live diagram out of folderID to the workspace root; ErrNotFound if it was not there
+	RemoveFromFolder(ctx context.Context, workspaceID, id, folderID primitive.ObjectID, at time.Time) error
+	// InFolders returns the IDs of diagrams in any of folderIDs that are live or, when batchID is set, trashed in that batch
+	InFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error)
+	// Unfile moves every diagram in one of folderIDs to the workspace root
+	Unfile(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID) error
+	// DetachFromMissingFolders moves live diagrams whose folder is trashed or gone to the workspace root
+	DetachFromMissingFolders(ctx context.Context, workspaceID primitive.ObjectID) error
+
+	// Trash moves live diagrams to trash in one batch. withParent marks them as trashed along with their folder.
+	// It returns the number of diagrams trashed.
+	Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error)
+	// TrashInFolders trashes the live diagrams of folderIDs along with them
+	TrashInFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID primitive.ObjectID, at time.Time) error
+	// Restore brings a trashed diagram back, to the workspace root when toRoot is set
+	Restore(ctx context.Context, workspaceID, id primitive.ObjectID, toRoot bool) error
+	// RestoreBatch brings back every diagram trashed in a batch
+	RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error
+	// TrashRoots returns the trash roots deleted before cutoff (all of them when cutoff is nil)
+	TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error)
+	// WorkspacesWithTrash returns the workspaces that have diagrams in trash
+	WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error)
+
+	// CountTags counts the live diagrams carrying each tag
+	CountTags(ctx context.Context, workspaceID primitive.ObjectID) (map[string]int64, error)
+	// RenameTag replaces a tag on every diagram of the workspace carrying it
+	RenameTag(ctx context.Context, workspaceID primitive.ObjectID, oldName, newName string) error
+	// RemoveTag removes a tag from every diagram of the workspace
+	RemoveTag(ctx context.Context, workspaceID primitive.ObjectID, name string) error
+	// AddTags adds tags to the live diagrams among ids, skipping diagrams that would end up
+	// with more than max tags. It returns the number of diagrams updated.
+	AddTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, max int, at time.Time) (int64, error)
+	// RemoveTags removes tags from the live diagrams among ids and returns the number of diagrams updated
+	RemoveTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, at time.Time) (int64, error)
+
+	// SetTemplateScope offers a diagram as a template; an empty scope withdraws it
+	SetTemplateScope(ctx context.Context, workspaceID, id primitive.ObjectID, scope models.TemplateScope) error
+	// ListTemplates returns the live templates offered to a workspace, its own and instance-wide ones, ordered by name
+	ListTemplates(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error)
+	// GetTemplate returns a live template by ID, whatever its workspace
+	GetTemplate(ctx context.Context, id primitive.ObjectID) (*models.Diagram, error)
+
+	// Search returns up to limit live diagrams of the workspaces where every term is contained
+	// (case-insensitively) in the name, the description or, with includeContent, the search text,
+	// most recently updated first. includeContent also returns the search text.
+	Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, includeContent bool, limit int64) ([]*models.Diagram, error)
+	// SetSearchContent stores the search text of a diagram
+	SetSearchContent(ctx context.Context, workspaceID, id primitive.ObjectID, text string) error
+	// ClearSearchContent drops the search text of every diagram of a workspace
+	ClearSearchContent(ctx context.Context, workspaceID primitive.ObjectID) error
+	// ListAll returns every diagram of a workspace in the given trash state, unordered
+	ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash Trash) ([]*models.Diagram, error)
+
+	Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// TagUpdate lists the tag fields to change; nil fields are left alone
+type TagUpdate struct {
+	Name      *string
+	Color     *string
+	UpdatedAt time.Time
+}
+
+// TagRepository persists workspace tag definitions. Names are unique within a workspace.
+type TagRepository interface {
+	Create(ctx context.Context, tag *models.WorkspaceTag) error
+	Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.WorkspaceTag, error)
+	// List returns the tags of a workspace ordered by name
+	List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceTag, error)
+	Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *TagUpdate) error
+	// EnsureDefined creates tags with the default color for the names that have none yet
+	EnsureDefined(ctx context.Context, workspaceID primitive.ObjectID, names []string, at time.Time) error
+	Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
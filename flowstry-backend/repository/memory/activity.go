@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityRepository keeps activity feed entries in memory
+type ActivityRepository struct{ db *db }
+
+// Append inserts an activity entry
+func (r *ActivityRepository) Append(ctx context.Context, entry *models.ActivityEntry) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	r.db.activity[entry.ID] = copyActivityEntry(*entry)
+	return nil
+}
+
+// copyActivityEntry detaches an activity entry from the caller's pointers and maps
+func copyActivityEntry(e models.ActivityEntry) models.ActivityEntry {
+	e.ActorID = copyID(e.ActorID)
+	e.Details = copyValues(e.Details)
+	return e
+}
+
+// Latest returns the most recently updated entry about a target
+func (r *ActivityRepository) Latest(ctx context.Context, workspaceID, targetID primitive.ObjectID) (*models.ActivityEntry, error) {
+	entries, err := r.List(ctx, workspaceID, &models.ActivityFilter{TargetID: &targetID},
+		&repository.PageQuery{Sort: models.SortByUpdated, Order: models.SortDesc, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// Extend adds one change to a session entry
+func (r *ActivityRepository) Extend(ctx context.Context, workspaceID, entryID primitive.ObjectID, updatedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	e, ok := r.db.activity[entryID]
+	if !ok || e.WorkspaceID != workspaceID {
+		return repository.ErrNotFound
+	}
+	e.Count++
+	e.UpdatedAt = updatedAt
+	r.db.activity[entryID] = e
+	return nil
+}
+
+// List pages through a workspace's activity feed
+func (r *ActivityRepository) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.ActivityFilter, page *repository.PageQuery) ([]*models.ActivityEntry, error) {
+	r.db.mu.RLock()
+	var entries []models.ActivityEntry
+	for _, e := range r.db.activity {
+		if e.WorkspaceID == workspaceID && matchesActivity(&e, filter) {
+			entries = append(entries, copyActivityEntry(e))
+		}
+	}
+	r.db.mu.RUnlock()
+
+	entries, err := paginate(entries, page,
+		func(e models.ActivityEntry) primitive.ObjectID { return e.ID },
+		func(e models.ActivityEntry, field models.SortField) (interface{}, bool) {
+			if field == models.SortByUpdated {
+				return e.UpdatedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.ActivityEntry, len(entries))
+	for i := range entries {
+		result[i] = &entries[i]
+	}
+	return result, nil
+}
+
+func matchesActivity(e *models.ActivityEntry, filter *models.ActivityFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.ActorID != nil && !sameID(e.ActorID, *filter.ActorID) {
+		return false
+	}
+	return filter.TargetID == nil || e.TargetID == *filter.TargetID
+}
+
+// DeleteAllForWorkspace deletes a workspace's activity feed
+func (r *ActivityRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, e := range r.db.activity {
+		if e.WorkspaceID == workspaceID {
+			delete(r.db.activity, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditRepository keeps audit entries in memory
+type AuditRepository struct{ db *db }
+
+// Append inserts an audit entry
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	r.db.audit[entry.ID] = copyAuditEntry(*entry)
+	return nil
+}
+
+// copyAuditEntry detaches an audit entry from the caller's pointers and maps
+func copyAuditEntry(e models.AuditEntry) models.AuditEntry {
+	e.ActorID = copyID(e.ActorID)
+	e.Before = copyValues(e.Before)
+	e.After = copyValues(e.After)
+	return e
+}
+
+func copyValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	c := make(map[string]string, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+// List pages through a workspace's audit entries
+func (r *AuditRepository) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.AuditFilter, page *repository.PageQuery) ([]*models.AuditEntry, error) {
+	r.db.mu.RLock()
+	var entries []models.AuditEntry
+	for _, e := range r.db.audit {
+		if e.WorkspaceID == workspaceID && matchesAudit(&e, filter) {
+			entries = append(entries, copyAuditEntry(e))
+		}
+	}
+	r.db.mu.RUnlock()
+
+	entries, err := paginate(entries, page,
+		func(e models.AuditEntry) primitive.ObjectID { return e.ID },
+		func(e models.AuditEntry, field models.SortField) (interface{}, bool) {
+			if field == models.SortByCreated {
+				return e.CreatedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.AuditEntry, len(entries))
+	for i := range entries {
+		result[i] = &entries[i]
+	}
+	return result, nil
+}
+
+func matchesAudit(e *models.AuditEntry, filter *models.AuditFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.Actions) > 0 {
+		found := false
+		for _, action := range filter.Actions {
+			if e.Action == action {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.ActorID != nil && !sameID(e.ActorID, *filter.ActorID) {
+		return false
+	}
+	if filter.TargetID != nil && e.TargetID != *filter.TargetID {
+		return false
+	}
+	return inRange(e.CreatedAt, filter.Created)
+}
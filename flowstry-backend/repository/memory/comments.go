@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentRepository keeps comments in memory
+type CommentRepository struct{ db *db }
+
+// Create inserts a comment
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if comment.ID.IsZero() {
+		comment.ID = primitive.NewObjectID()
+	}
+	r.db.comments[comment.ID] = copyComment(*comment)
+	return nil
+}
+
+// copyComment detaches a comment from the caller's pointers
+func copyComment(c models.Comment) models.Comment {
+	c.ThreadID = copyID(c.ThreadID)
+	c.ResolvedAt = copyTime(c.ResolvedAt)
+	c.ResolvedBy = copyID(c.ResolvedBy)
+	c.EditedAt = copyTime(c.EditedAt)
+	if c.Anchor != nil {
+		anchor := *c.Anchor
+		c.Anchor = &anchor
+	}
+	return c
+}
+
+// Get retrieves a comment of a diagram
+func (r *CommentRepository) Get(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) (*models.Comment, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	c, ok := r.db.comments[id]
+	if !ok || c.WorkspaceID != workspaceID || c.DiagramID != diagramID {
+		return nil, repository.ErrNotFound
+	}
+	c = copyComment(c)
+	return &c, nil
+}
+
+// ListThreads pages through the thread roots of a diagram
+func (r *CommentRepository) ListThreads(ctx context.Context, workspaceID, diagramID primitive.ObjectID, filter *models.CommentFilter, page *repository.PageQuery) ([]*models.Comment, error) {
+	r.db.mu.RLock()
+	var threads []models.Comment
+	for _, c := range r.db.comments {
+		if c.WorkspaceID == workspaceID && c.DiagramID == diagramID && c.IsThread() && matchesCommentFilter(&c, filter) {
+			threads = append(threads, copyComment(c))
+		}
+	}
+	r.db.mu.RUnlock()
+
+	threads, err := paginate(threads, page,
+		func(c models.Comment) primitive.ObjectID { return c.ID },
+		func(c models.Comment, field models.SortField) (interface{}, bool) {
+			switch field {
+			case models.SortByCreated:
+				return c.CreatedAt, true
+			case models.SortByUpdated:
+				return c.UpdatedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.Comment, len(threads))
+	for i := range threads {
+		result[i] = &threads[i]
+	}
+	return result, nil
+}
+
+func matchesCommentFilter(c *models.Comment, filter *models.CommentFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.Resolved != nil && *filter.Resolved != (c.ResolvedAt != nil) {
+		return false
+	}
+	if filter.ShapeID != "" && (c.Anchor == nil || c.Anchor.ShapeID != filter.ShapeID) {
+		return false
+	}
+	return true
+}
+
+// ListReplies returns the replies to the given threads, oldest first
+func (r *CommentRepository) ListReplies(ctx context.Context, workspaceID, diagramID primitive.ObjectID, threadIDs []primitive.ObjectID) ([]*models.Comment, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	threads := idSet(threadIDs)
+	var replies []*models.Comment
+	for _, c := range r.db.comments {
+		if c.WorkspaceID == workspaceID && c.DiagramID == diagramID && c.ThreadID != nil && threads[*c.ThreadID] {
+			c = copyComment(c)
+			replies = append(replies, &c)
+		}
+	}
+	sort.Slice(replies, func(i, j int) bool {
+		return newestFirst(replies[j].CreatedAt, replies[i].CreatedAt, replies[j].ID, replies[i].ID)
+	})
+	return replies, nil
+}
+
+// ListShapeAnchored returns the thread roots of a diagram that are anchored to a shape
+func (r *CommentRepository) ListShapeAnchored(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Comment, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var threads []*models.Comment
+	for _, c := range r.db.comments {
+		if c.WorkspaceID == workspaceID && c.DiagramID == diagramID && c.IsThread() && c.Anchor != nil && c.Anchor.ShapeID != "" {
+			c = copyComment(c)
+			threads = append(threads, &c)
+		}
+	}
+	return threads, nil
+}
+
+// Update changes a comment
+func (r *CommentRepository) Update(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, update *repository.CommentUpdate) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.comments[id]
+	if !ok || c.WorkspaceID != workspaceID || c.DiagramID != diagramID {
+		return repository.ErrNotFound
+	}
+	if update.Body != nil {
+		c.Body = *update.Body
+	}
+	if update.EditedAt != nil {
+		c.EditedAt = copyTime(update.EditedAt)
+	}
+	if update.Resolved != nil {
+		if *update.Resolved {
+			c.ResolvedAt = copyTime(&update.UpdatedAt)
+			c.ResolvedBy = copyID(&update.ResolvedBy)
+		} else {
+			c.ResolvedAt = nil
+			c.ResolvedBy = nil
+		}
+	}
+	c.UpdatedAt = update.UpdatedAt
+	r.db.comments[id] = c
+	return nil
+}
+
+// SetAnchor moves a thread's anchor
+func (r *CommentRepository) SetAnchor(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, anchor models.CommentAnchor) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.comments[id]
+	if !ok || c.WorkspaceID != workspaceID || c.DiagramID != diagramID {
+		return repository.ErrNotFound
+	}
+	c.Anchor = &anchor
+	r.db.comments[id] = c
+	return nil
+}
+
+// Delete removes a comment and, for a thread root, its replies
+func (r *CommentRepository) Delete(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) error {
+	c, err := r.Get(ctx, workspaceID, diagramID, id)
+	if err != nil {
+		return err
+	}
+	r.deleteWhere(func(other *models.Comment) bool {
+		return other.ID == c.ID || (c.IsThread() && sameID(other.ThreadID, c.ID))
+	})
+	return nil
+}
+
+// DeleteForDiagram removes every comment of a diagram
+func (r *CommentRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	r.deleteWhere(func(c *models.Comment) bool { return c.WorkspaceID == workspaceID && c.DiagramID == diagramID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every comment of a workspace
+func (r *CommentRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(c *models.Comment) bool { return c.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *CommentRepository) deleteWhere(match func(*models.Comment) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, c := range r.db.comments {
+		if match(&c) {
+			delete(r.db.comments, id)
+		}
+	}
+}
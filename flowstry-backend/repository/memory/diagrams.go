@@ -0,0 +1,595 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiagramRepository keeps diagram metadata in memory
+type DiagramRepository struct{ db *db }
+
+// diagramSortValue returns the value a diagram listing is ordered by
+func diagramSortValue(d models.Diagram, field models.SortField) (interface{}, bool) {
+	switch field {
+	case models.SortByName:
+		return d.Name, true
+	case models.SortByCreated:
+		return d.CreatedAt, true
+	case models.SortByUpdated:
+		return d.UpdatedAt, true
+	case models.SortBySize:
+		return d.FileSize, true
+	case models.SortByDeleted:
+		return timeValue(d.DeletedAt), true
+	}
+	return nil, false
+}
+
+// Create inserts a diagram
+func (r *DiagramRepository) Create(ctx context.Context, diagram *models.Diagram) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if diagram.ID.IsZero() {
+		diagram.ID = primitive.NewObjectID()
+	}
+	r.db.diagrams[diagram.ID] = copyDiagram(*diagram)
+	return nil
+}
+
+// copyDiagram detaches a diagram from the caller's pointers and slices
+func copyDiagram(d models.Diagram) models.Diagram {
+	d.FolderID = copyID(d.FolderID)
+	d.CreatedBy = copyID(d.CreatedBy)
+	d.DeletedAt = copyTime(d.DeletedAt)
+	d.TrashBatchID = copyID(d.TrashBatchID)
+	if d.Tags != nil {
+		d.Tags = append([]string(nil), d.Tags...)
+	}
+	return d
+}
+
+// Get retrieves a diagram of a workspace
+func (r *DiagramRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash repository.Trash) (*models.Diagram, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	d, ok := r.db.diagrams[id]
+	if !ok || d.WorkspaceID != workspaceID || !matchesTrash(d.DeletedAt, trash) {
+		return nil, repository.ErrNotFound
+	}
+	d = copyDiagram(d)
+	return &d, nil
+}
+
+// List pages through the diagrams of a workspace
+func (r *DiagramRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.DiagramQuery, page *repository.PageQuery) ([]*models.Diagram, error) {
+	diagrams := r.find(func(d *models.Diagram) bool {
+		if d.WorkspaceID != workspaceID {
+			return false
+		}
+		if query == nil {
+			return true
+		}
+		if !matchesTrash(d.DeletedAt, query.Trash) {
+			return false
+		}
+		if query.Trash == repository.Trashed && d.DeletedWithParent {
+			return false
+		}
+		return matchesDiagramFilter(d, query.Filter)
+	})
+
+	diagrams, err := paginate(diagrams, page, func(d models.Diagram) primitive.ObjectID { return d.ID }, diagramSortValue)
+	if err != nil {
+		return nil, err
+	}
+	return diagramListing(diagrams), nil
+}
+
+// matchesDiagramFilter reports whether a diagram passes the optional listing filters
+func matchesDiagramFilter(d *models.Diagram, filter *models.DiagramFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.Query != "" && !containsFold(d.Name, filter.Query) {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		found := false
+		for _, t := range d.Tags {
+			found = found || t == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.RootOnly {
+		if d.FolderID != nil {
+			return false
+		}
+	} else if filter.FolderID != nil && !sameID(d.FolderID, *filter.FolderID) {
+		return false
+	}
+	if filter.CreatedBy != nil && !sameID(d.CreatedBy, *filter.CreatedBy) {
+		return false
+	}
+	return inRange(d.CreatedAt, filter.Created) && inRange(d.UpdatedAt, filter.Updated)
+}
+
+// ListByIDs returns the live diagrams among ids within the given workspaces
+func (r *DiagramRepository) ListByIDs(ctx context.Context, workspaceIDs, ids []primitive.ObjectID, filter *models.DiagramFilter) ([]*models.Diagram, error) {
+	workspaces, set := idSet(workspaceIDs), idSet(ids)
+	return diagramListing(r.find(func(d *models.Diagram) bool {
+		return set[d.ID] && workspaces[d.WorkspaceID] && d.DeletedAt == nil && matchesDiagramFilter(d, filter)
+	})), nil
+}
+
+func (r *DiagramRepository) find(match func(*models.Diagram) bool) []models.Diagram {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var diagrams []models.Diagram
+	for _, d := range r.db.diagrams {
+		if match(&d) {
+			diagrams = append(diagrams, copyDiagram(d))
+		}
+	}
+	return diagrams
+}
+
+// diagramListing leaves the search index text out of listed diagrams
+func diagramListing(diagrams []models.Diagram) []*models.Diagram {
+	result := make([]*models.Diagram, len(diagrams))
+	for i := range diagrams {
+		diagrams[i].SearchContent = ""
+		result[i] = &diagrams[i]
+	}
+	return result
+}
+
+// CountForWorkspace counts every diagram of a workspace, trashed ones included
+func (r *DiagramRepository) CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	return int64(len(r.find(func(d *models.Diagram) bool { return d.WorkspaceID == workspaceID }))), nil
+}
+
+// CountLive counts how many of ids are live diagrams of the workspace
+func (r *DiagramRepository) CountLive(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	set := idSet(ids)
+	return int64(len(r.find(func(d *models.Diagram) bool {
+		return set[d.ID] && d.WorkspaceID == workspaceID && d.DeletedAt == nil
+	}))), nil
+}
+
+// CountByFolder counts live diagrams per folder
+func (r *DiagramRepository) CountByFolder(ctx context.Context, workspaceID primitive.ObjectID) (map[primitive.ObjectID]int64, int64, error) {
+	counts := map[primitive.ObjectID]int64{}
+	var root int64
+	for _, d := range r.find(func(d *models.Diagram) bool { return d.WorkspaceID == workspaceID && d.DeletedAt == nil }) {
+		if d.FolderID == nil {
+			root++
+		} else {
+			counts[*d.FolderID]++
+		}
+	}
+	return counts, root, nil
+}
+
+// Update changes the given fields of a diagram
+func (r *DiagramRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.DiagramUpdate) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	d, ok := r.db.diagrams[id]
+	if !ok || d.WorkspaceID != workspaceID {
+		return repository.ErrNotFound
+	}
+	if update.Name != nil {
+		d.Name = *update.Name
+	}
+	if update.Description != nil {
+		d.Description = *update.Description
+	}
+	if update.Thumbnail != nil {
+		d.Thumbnail = *update.Thumbnail
+	}
+	if update.Tags != nil {
+		d.Tags = append([]string(nil), *update.Tags...)
+	}
+	if update.FileURL != nil {
+		d.FileURL = *update.FileURL
+	}
+	if update.FileSize != nil {
+		d.FileSize = *update.FileSize
+	}
+	if update.Version != nil {
+		d.Version = *update.Version
+	}
+	d.UpdatedAt = update.UpdatedAt
+	r.db.diagrams[id] = d
+	return nil
+}
+
+// SetFolder moves live diagrams into a folder
+func (r *DiagramRepository) SetFolder(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, folderID *primitive.ObjectID, at time.Time) (int64, error) {
+	set := idSet(ids)
+	return r.update(
+		func(d *models.Diagram) bool { return set[d.ID] && d.WorkspaceID == workspaceID && d.DeletedAt == nil },
+		func(d *models.Diagram) {
+			d.FolderID = copyID(folderID)
+			d.UpdatedAt = at
+		}), nil
+}
+
+// RemoveFromFolder moves a live diagram out of a folder to the workspace root
+func (r *DiagramRepository) RemoveFromFolder(ctx context.Context, workspaceID, id, folderID primitive.ObjectID, at time.Time) error {
+	n := r.update(
+		func(d *models.Diagram) bool {
+			return d.ID == id && d.WorkspaceID == workspaceID && sameID(d.FolderID, folderID) && d.DeletedAt == nil
+		},
+		func(d *models.Diagram) {
+			d.FolderID = nil
+			d.UpdatedAt = at
+		})
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// InFolders returns the diagrams filed in any of folderIDs
+func (r *DiagramRepository) InFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	folders := idSet(folderIDs)
+	var ids []primitive.ObjectID
+	for _, d := range r.find(func(d *models.Diagram) bool {
+		if d.WorkspaceID != workspaceID || d.FolderID == nil || !folders[*d.FolderID] {
+			return false
+		}
+		return d.DeletedAt == nil || (batchID != nil && sameID(d.TrashBatchID, *batchID))
+	}) {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+// Unfile moves every diagram in one of folderIDs to the workspace root
+func (r *DiagramRepository) Unfile(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID) error {
+	folders := idSet(folderIDs)
+	r.update(
+		func(d *models.Diagram) bool {
+			return d.WorkspaceID == workspaceID && d.FolderID != nil && folders[*d.FolderID]
+		},
+		func(d *models.Diagram) { d.FolderID = nil })
+	return nil
+}
+
+// DetachFromMissingFolders moves live diagrams whose folder is not live to the workspace root
+func (r *DiagramRepository) DetachFromMissingFolders(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, d := range r.db.diagrams {
+		if d.WorkspaceID != workspaceID || d.DeletedAt != nil || d.FolderID == nil {
+			continue
+		}
+		folder, ok := r.db.folders[*d.FolderID]
+		if !ok || folder.WorkspaceID != workspaceID || folder.DeletedAt != nil {
+			d.FolderID = nil
+			r.db.diagrams[id] = d
+		}
+	}
+	return nil
+}
+
+// Trash moves live diagrams to trash
+func (r *DiagramRepository) Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	set := idSet(ids)
+	return r.update(
+		func(d *models.Diagram) bool { return set[d.ID] && d.WorkspaceID == workspaceID && d.DeletedAt == nil },
+		func(d *models.Diagram) { trashDiagram(d, batchID, at, withParent) }), nil
+}
+
+// TrashInFolders trashes the live diagrams of folderIDs along with them
+func (r *DiagramRepository) TrashInFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID primitive.ObjectID, at time.Time) error {
+	folders := idSet(folderIDs)
+	r.update(
+		func(d *models.Diagram) bool {
+			return d.WorkspaceID == workspaceID && d.FolderID != nil && folders[*d.FolderID] && d.DeletedAt == nil
+		},
+		func(d *models.Diagram) { trashDiagram(d, batchID, at, true) })
+	return nil
+}
+
+func trashDiagram(d *models.Diagram, batchID primitive.ObjectID, at time.Time, withParent bool) {
+	d.DeletedAt = &at
+	d.TrashBatchID = &batchID
+	d.DeletedWithParent = withParent
+}
+
+func restoreDiagram(d *models.Diagram) {
+	d.DeletedAt = nil
+	d.TrashBatchID = nil
+	d.DeletedWithParent = false
+}
+
+// Restore brings a trashed diagram back
+func (r *DiagramRepository) Restore(ctx context.Context, workspaceID, id primitive.ObjectID, toRoot bool) error {
+	n := r.update(
+		func(d *models.Diagram) bool { return d.ID == id && d.WorkspaceID == workspaceID && d.DeletedAt != nil },
+		func(d *models.Diagram) {
+			restoreDiagram(d)
+			if toRoot {
+				d.FolderID = nil
+			}
+		})
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// RestoreBatch brings back every diagram trashed in a batch
+func (r *DiagramRepository) RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error {
+	r.update(
+		func(d *models.Diagram) bool { return d.WorkspaceID == workspaceID && sameID(d.TrashBatchID, batchID) },
+		restoreDiagram)
+	return nil
+}
+
+// update changes every diagram that matches and returns how many did
+func (r *DiagramRepository) update(match func(*models.Diagram) bool, change func(*models.Diagram)) int64 {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var n int64
+	for id, d := range r.db.diagrams {
+		if match(&d) {
+			change(&d)
+			r.db.diagrams[id] = d
+			n++
+		}
+	}
+	return n
+}
+
+// TrashRoots returns the diagram trash roots deleted before cutoff
+func (r *DiagramRepository) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, d := range r.find(func(d *models.Diagram) bool {
+		return d.WorkspaceID == workspaceID && isTrashRoot(d.DeletedAt, d.DeletedWithParent, cutoff)
+	}) {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+// WorkspacesWithTrash returns the workspaces that have diagrams in trash
+func (r *DiagramRepository) WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error) {
+	seen := map[primitive.ObjectID]bool{}
+	var ids []primitive.ObjectID
+	for _, d := range r.find(func(d *models.Diagram) bool { return d.DeletedAt != nil }) {
+		if !seen[d.WorkspaceID] {
+			seen[d.WorkspaceID] = true
+			ids = append(ids, d.WorkspaceID)
+		}
+	}
+	return ids, nil
+}
+
+// CountTags counts the live diagrams carrying each tag
+func (r *DiagramRepository) CountTags(ctx context.Context, workspaceID primitive.ObjectID) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, d := range r.find(func(d *models.Diagram) bool { return d.WorkspaceID == workspaceID && d.DeletedAt == nil }) {
+		for _, tag := range d.Tags {
+			counts[tag]++
+		}
+	}
+	return counts, nil
+}
+
+// hasTag reports whether a diagram carries a tag
+func hasTag(d *models.Diagram, tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutTags returns tags minus the removed ones
+func withoutTags(tags []string, removed map[string]bool) []string {
+	kept := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if !removed[t] {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// RenameTag replaces a tag on every diagram of the workspace carrying it
+func (r *DiagramRepository) RenameTag(ctx context.Context, workspaceID primitive.ObjectID, oldName, newName string) error {
+	r.update(func(d *models.Diagram) bool {
+		return d.WorkspaceID == workspaceID && hasTag(d, oldName)
+	}, func(d *models.Diagram) {
+		if !hasTag(d, newName) {
+			d.Tags = append(d.Tags, newName)
+		}
+		d.Tags = withoutTags(d.Tags, map[string]bool{oldName: true})
+	})
+	return nil
+}
+
+// RemoveTag removes a tag from every diagram of the workspace
+func (r *DiagramRepository) RemoveTag(ctx context.Context, workspaceID primitive.ObjectID, name string) error {
+	r.update(func(d *models.Diagram) bool {
+		return d.WorkspaceID == workspaceID && hasTag(d, name)
+	}, func(d *models.Diagram) {
+		d.Tags = withoutTags(d.Tags, map[string]bool{name: true})
+	})
+	return nil
+}
+
+// AddTags adds tags to live diagrams that stay within max tags
+func (r *DiagramRepository) AddTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, max int, at time.Time) (int64, error) {
+	set := idSet(ids)
+	missing := func(d *models.Diagram) []string {
+		var add []string
+		for _, tag := range tags {
+			if !hasTag(d, tag) {
+				add = append(add, tag)
+			}
+		}
+		return add
+	}
+	return r.update(func(d *models.Diagram) bool {
+		return set[d.ID] && d.WorkspaceID == workspaceID && d.DeletedAt == nil && len(d.Tags)+len(missing(d)) <= max
+	}, func(d *models.Diagram) {
+		d.Tags = append(d.Tags, missing(d)...)
+		d.UpdatedAt = at
+	}), nil
+}
+
+// RemoveTags removes tags from live diagrams
+func (r *DiagramRepository) RemoveTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, at time.Time) (int64, error) {
+	set, removed := idSet(ids), map[string]bool{}
+	for _, tag := range tags {
+		removed[tag] = true
+	}
+	return r.update(func(d *models.Diagram) bool {
+		return set[d.ID] && d.WorkspaceID == workspaceID && d.DeletedAt == nil
+	}, func(d *models.Diagram) {
+		d.Tags = withoutTags(d.Tags, removed)
+		d.UpdatedAt = at
+	}), nil
+}
+
+// SetTemplateScope offers a diagram as a template, or withdraws it for an empty scope
+func (r *DiagramRepository) SetTemplateScope(ctx context.Context, workspaceID, id primitive.ObjectID, scope models.TemplateScope) error {
+	if r.update(func(d *models.Diagram) bool {
+		return d.ID == id && d.WorkspaceID == workspaceID
+	}, func(d *models.Diagram) {
+		d.TemplateScope = scope
+	}) == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// isTemplateFor reports whether a live diagram is a template offered to a workspace
+func isTemplateFor(d *models.Diagram, workspaceID primitive.ObjectID) bool {
+	if d.DeletedAt != nil {
+		return false
+	}
+	return d.TemplateScope == models.TemplateScopeInstance ||
+		d.TemplateScope == models.TemplateScopeWorkspace && d.WorkspaceID == workspaceID
+}
+
+// ListTemplates returns the live templates offered to a workspace ordered by name
+func (r *DiagramRepository) ListTemplates(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error) {
+	templates := diagramListing(r.find(func(d *models.Diagram) bool { return isTemplateFor(d, workspaceID) }))
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// GetTemplate retrieves a live template by ID in any workspace
+func (r *DiagramRepository) GetTemplate(ctx context.Context, id primitive.ObjectID) (*models.Diagram, error) {
+	found := diagramListing(r.find(func(d *models.Diagram) bool {
+		return d.ID == id && d.DeletedAt == nil && d.TemplateScope.IsValid()
+	}))
+	if len(found) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return found[0], nil
+}
+
+// Search scans the live diagrams of the workspaces for every term
+func (r *DiagramRepository) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, includeContent bool, limit int64) ([]*models.Diagram, error) {
+	workspaces := idSet(workspaceIDs)
+	hits := r.find(func(d *models.Diagram) bool {
+		if !workspaces[d.WorkspaceID] || d.DeletedAt != nil {
+			return false
+		}
+		for _, term := range terms {
+			if !containsFold(d.Name, term) && !containsFold(d.Description, term) &&
+				!(includeContent && containsFold(d.SearchContent, term)) {
+				return false
+			}
+		}
+		return true
+	})
+	if !includeContent {
+		diagramListing(hits)
+	}
+
+	diagrams := make([]*models.Diagram, len(hits))
+	for i := range hits {
+		diagrams[i] = &hits[i]
+	}
+	sort.Slice(diagrams, func(i, j int) bool {
+		return newestFirst(diagrams[i].UpdatedAt, diagrams[j].UpdatedAt, diagrams[i].ID, diagrams[j].ID)
+	})
+	if limit > 0 && int64(len(diagrams)) > limit {
+		diagrams = diagrams[:limit]
+	}
+	return diagrams, nil
+}
+
+// SetSearchContent stores the search text of a diagram
+func (r *DiagramRepository) SetSearchContent(ctx context.Context, workspaceID, id primitive.ObjectID, text string) error {
+	r.update(func(d *models.Diagram) bool {
+		return d.ID == id && d.WorkspaceID == workspaceID
+	}, func(d *models.Diagram) {
+		d.SearchContent = text
+	})
+	return nil
+}
+
+// ClearSearchContent drops the search text of every diagram of a workspace
+func (r *DiagramRepository) ClearSearchContent(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.update(func(d *models.Diagram) bool {
+		return d.WorkspaceID == workspaceID && d.SearchContent != ""
+	}, func(d *models.Diagram) {
+		d.SearchContent = ""
+	})
+	return nil
+}
+
+// ListAll returns every diagram of a workspace in the given trash state
+func (r *DiagramRepository) ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash repository.Trash) ([]*models.Diagram, error) {
+	return diagramListing(r.find(func(d *models.Diagram) bool {
+		return d.WorkspaceID == workspaceID && matchesTrash(d.DeletedAt, trash)
+	})), nil
+}
+
+// Delete permanently removes a diagram
+func (r *DiagramRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	d, ok := r.db.diagrams[id]
+	if !ok || d.WorkspaceID != workspaceID {
+		return repository.ErrNotFound
+	}
+	delete(r.db.diagrams, id)
+	return nil
+}
+
+// DeleteAllForWorkspace permanently removes every diagram of a workspace
+func (r *DiagramRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, d := range r.db.diagrams {
+		if d.WorkspaceID == workspaceID {
+			delete(r.db.diagrams, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FolderRepository keeps folders in memory
+type FolderRepository struct{ db *db }
+
+// folderSortValue returns the value a folder listing is ordered by
+func folderSortValue(f models.Folder, field models.SortField) (interface{}, bool) {
+	switch field {
+	case models.SortByName:
+		return f.Name, true
+	case models.SortByCreated:
+		return f.CreatedAt, true
+	case models.SortByUpdated:
+		return f.UpdatedAt, true
+	case models.SortByDeleted:
+		return timeValue(f.DeletedAt), true
+	}
+	return nil, false
+}
+
+// Create inserts a folder
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if folder.ID.IsZero() {
+		folder.ID = primitive.NewObjectID()
+	}
+	r.db.folders[folder.ID] = copyFolder(*folder)
+	return nil
+}
+
+// copyFolder detaches a folder from the caller's pointers
+func copyFolder(f models.Folder) models.Folder {
+	f.ParentFolderID = copyID(f.ParentFolderID)
+	f.CreatedBy = copyID(f.CreatedBy)
+	f.DeletedAt = copyTime(f.DeletedAt)
+	f.TrashBatchID = copyID(f.TrashBatchID)
+	return f
+}
+
+// Get retrieves a folder of a workspace
+func (r *FolderRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash repository.Trash) (*models.Folder, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	f, ok := r.db.folders[id]
+	if !ok || f.WorkspaceID != workspaceID || !matchesTrash(f.DeletedAt, trash) {
+		return nil, repository.ErrNotFound
+	}
+	f = copyFolder(f)
+	return &f, nil
+}
+
+// List pages through the folders of a workspace
+func (r *FolderRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.FolderQuery, page *repository.PageQuery) ([]*models.Folder, error) {
+	folders := r.find(workspaceID, func(f *models.Folder) bool {
+		if query == nil {
+			return true
+		}
+		if !matchesTrash(f.DeletedAt, query.Trash) {
+			return false
+		}
+		if query.Trash == repository.Trashed && f.DeletedWithParent {
+			return false
+		}
+		return matchesFolderFilter(f, query.Filter)
+	})
+
+	folders, err := paginate(folders, page, func(f models.Folder) primitive.ObjectID { return f.ID }, folderSortValue)
+	if err != nil {
+		return nil, err
+	}
+	return folderPointers(folders), nil
+}
+
+// matchesFolderFilter reports whether a folder passes the optional listing filters
+func matchesFolderFilter(f *models.Folder, filter *models.FolderFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.Query != "" && !containsFold(f.Name, filter.Query) {
+		return false
+	}
+	if filter.RootOnly {
+		if f.ParentFolderID != nil {
+			return false
+		}
+	} else if filter.ParentID != nil && !sameID(f.ParentFolderID, *filter.ParentID) {
+		return false
+	}
+	if filter.CreatedBy != nil && !sameID(f.CreatedBy, *filter.CreatedBy) {
+		return false
+	}
+	return inRange(f.CreatedAt, filter.Created) && inRange(f.UpdatedAt, filter.Updated)
+}
+
+// ListAll retrieves every folder of a workspace in a trash state
+func (r *FolderRepository) ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash repository.Trash) ([]*models.Folder, error) {
+	folders := r.find(workspaceID, func(f *models.Folder) bool { return matchesTrash(f.DeletedAt, trash) })
+	return folderPointers(folders), nil
+}
+
+func (r *FolderRepository) find(workspaceID primitive.ObjectID, match func(*models.Folder) bool) []models.Folder {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var folders []models.Folder
+	for _, f := range r.db.folders {
+		if f.WorkspaceID == workspaceID && match(&f) {
+			folders = append(folders, copyFolder(f))
+		}
+	}
+	return folders
+}
+
+func folderPointers(folders []models.Folder) []*models.Folder {
+	result := make([]*models.Folder, len(folders))
+	for i := range folders {
+		result[i] = &folders[i]
+	}
+	return result
+}
+
+// CountForWorkspace counts every folder of a workspace, trashed ones included
+func (r *FolderRepository) CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	return int64(len(r.find(workspaceID, func(*models.Folder) bool { return true }))), nil
+}
+
+// Update changes the given fields of a live folder
+func (r *FolderRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.FolderUpdate) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	f, ok := r.db.folders[id]
+	if !ok || f.WorkspaceID != workspaceID || f.DeletedAt != nil {
+		return repository.ErrNotFound
+	}
+	if update.Name != nil {
+		f.Name = *update.Name
+	}
+	if update.Description != nil {
+		f.Description = *update.Description
+	}
+	if update.Color != nil {
+		f.Color = *update.Color
+	}
+	if update.SetParent {
+		f.ParentFolderID = copyID(update.ParentFolderID)
+	}
+	f.UpdatedAt = update.UpdatedAt
+	r.db.folders[id] = f
+	return nil
+}
+
+// Ancestors walks up parent_folder_id from a folder
+func (r *FolderRepository) Ancestors(ctx context.Context, workspaceID, id primitive.ObjectID) ([]*models.Folder, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	f, ok := r.db.folders[id]
+	if !ok || f.WorkspaceID != workspaceID {
+		return nil, repository.ErrNotFound
+	}
+
+	var ancestors []*models.Folder
+	seen := map[primitive.ObjectID]bool{id: true}
+	for parentID := f.ParentFolderID; parentID != nil && !seen[*parentID]; {
+		parent, ok := r.db.folders[*parentID]
+		if !ok || parent.WorkspaceID != workspaceID {
+			break
+		}
+		seen[parent.ID] = true
+		parent = copyFolder(parent)
+		ancestors = append([]*models.Folder{&parent}, ancestors...)
+		parentID = parent.ParentFolderID
+	}
+	return ancestors, nil
+}
+
+// Descendants walks down parent_folder_id from a folder, breadth first
+func (r *FolderRepository) Descendants(ctx context.Context, workspaceID, id primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	seen := map[primitive.ObjectID]bool{id: true}
+	for queue := []primitive.ObjectID{id}; len(queue) > 0; queue = queue[1:] {
+		for _, f := range r.db.folders {
+			if f.WorkspaceID != workspaceID || seen[f.ID] || !sameID(f.ParentFolderID, queue[0]) {
+				continue
+			}
+			if f.DeletedAt != nil && (batchID == nil || !sameID(f.TrashBatchID, *batchID)) {
+				continue
+			}
+			seen[f.ID] = true
+			ids = append(ids, f.ID)
+			queue = append(queue, f.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Trash moves live folders to trash
+func (r *FolderRepository) Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var n int64
+	for _, id := range ids {
+		f, ok := r.db.folders[id]
+		if !ok || f.WorkspaceID != workspaceID || f.DeletedAt != nil {
+			continue
+		}
+		trashFolder(&f, batchID, at, withParent)
+		r.db.folders[id] = f
+		n++
+	}
+	return n, nil
+}
+
+func trashFolder(f *models.Folder, batchID primitive.ObjectID, at time.Time, withParent bool) {
+	f.DeletedAt = &at
+	f.TrashBatchID = &batchID
+	f.DeletedWithParent = withParent
+}
+
+func restoreFolder(f *models.Folder) {
+	f.DeletedAt = nil
+	f.TrashBatchID = nil
+	f.DeletedWithParent = false
+}
+
+// Restore brings trashed folders back
+func (r *FolderRepository) Restore(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error {
+	set := idSet(ids)
+	r.update(workspaceID, func(f *models.Folder) bool { return set[f.ID] }, restoreFolder)
+	return nil
+}
+
+// RestoreBatch brings back every folder trashed in a batch
+func (r *FolderRepository) RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error {
+	r.update(workspaceID, func(f *models.Folder) bool { return sameID(f.TrashBatchID, batchID) }, restoreFolder)
+	return nil
+}
+
+// DetachFromMissingParents moves live folders whose parent is not live to the workspace root
+func (r *FolderRepository) DetachFromMissingParents(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, f := range r.db.folders {
+		if f.WorkspaceID != workspaceID || f.DeletedAt != nil || f.ParentFolderID == nil {
+			continue
+		}
+		parent, ok := r.db.folders[*f.ParentFolderID]
+		if !ok || parent.WorkspaceID != workspaceID || parent.DeletedAt != nil {
+			f.ParentFolderID = nil
+			r.db.folders[id] = f
+		}
+	}
+	return nil
+}
+
+// Unparent moves the folders under parentIDs to the workspace root
+func (r *FolderRepository) Unparent(ctx context.Context, workspaceID primitive.ObjectID, parentIDs []primitive.ObjectID) error {
+	set := idSet(parentIDs)
+	r.update(workspaceID,
+		func(f *models.Folder) bool { return f.ParentFolderID != nil && set[*f.ParentFolderID] },
+		func(f *models.Folder) { f.ParentFolderID = nil })
+	return nil
+}
+
+// update changes every folder of a workspace that matches
+func (r *FolderRepository) update(workspaceID primitive.ObjectID, match func(*models.Folder) bool, change func(*models.Folder)) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, f := range r.db.folders {
+		if f.WorkspaceID == workspaceID && match(&f) {
+			change(&f)
+			r.db.folders[id] = f
+		}
+	}
+}
+
+// TrashRoots returns the folder trash roots deleted before cutoff
+func (r *FolderRepository) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, f := range r.find(workspaceID, func(f *models.Folder) bool { return isTrashRoot(f.DeletedAt, f.DeletedWithParent, cutoff) }) {
+		ids = append(ids, f.ID)
+	}
+	return ids, nil
+}
+
+// isTrashRoot reports whether an item was trashed on its own before cutoff (any time when nil)
+func isTrashRoot(deletedAt *time.Time, withParent bool, cutoff *time.Time) bool {
+	return deletedAt != nil && !withParent && (cutoff == nil || deletedAt.Before(*cutoff))
+}
+
+// WorkspacesWithTrash returns the workspaces that have folders in trash
+func (r *FolderRepository) WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	seen := map[primitive.ObjectID]bool{}
+	var ids []primitive.ObjectID
+	for _, f := range r.db.folders {
+		if f.DeletedAt != nil && !seen[f.WorkspaceID] {
+			seen[f.WorkspaceID] = true
+			ids = append(ids, f.WorkspaceID)
+		}
+	}
+	return ids, nil
+}
+
+// Search scans the live folders of the workspaces for names containing every term
+func (r *FolderRepository) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, limit int64) ([]*models.Folder, error) {
+	var hits []*models.Folder
+	for _, workspaceID := range workspaceIDs {
+		hits = append(hits, folderPointers(r.find(workspaceID, func(f *models.Folder) bool {
+			if f.DeletedAt != nil {
+				return false
+			}
+			for _, term := range terms {
+				if !containsFold(f.Name, term) {
+					return false
+				}
+			}
+			return true
+		}))...)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		return newestFirst(hits[i].UpdatedAt, hits[j].UpdatedAt, hits[i].ID, hits[j].ID)
+	})
+	if limit > 0 && int64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// DeleteMany permanently removes folders
+func (r *FolderRepository) DeleteMany(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error {
+	set := idSet(ids)
+	r.deleteWhere(func(f *models.Folder) bool { return f.WorkspaceID == workspaceID && set[f.ID] })
+	return nil
+}
+
+// DeleteAllForWorkspace permanently removes every folder of a workspace
+func (r *FolderRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(f *models.Folder) bool { return f.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *FolderRepository) deleteWhere(match func(*models.Folder) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, f := range r.db.folders {
+		if match(&f) {
+			delete(r.db.folders, id)
+		}
+	}
+}
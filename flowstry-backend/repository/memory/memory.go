@@ -0,0 +1,223 @@
+// Package memory implements the repositories in process, for tests.
+// Records are copied in and out so callers never share state with the store.
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// New returns an empty in-memory store
+func New() *repository.Store {
+	db := &db{
+		users:         map[primitive.ObjectID]authModels.User{},
+		refreshTokens: map[primitive.ObjectID]authModels.RefreshToken{},
+		accessTokens:  map[primitive.ObjectID]authModels.PersonalAccessToken{},
+		workspaces:    map[primitive.ObjectID]models.Workspace{},
+		members:       map[primitive.ObjectID]models.WorkspaceMember{},
+		invites:       map[primitive.ObjectID]models.WorkspaceInvite{},
+		folders:       map[primitive.ObjectID]models.Folder{},
+		diagrams:      map[primitive.ObjectID]models.Diagram{},
+		tags:          map[primitive.ObjectID]models.WorkspaceTag{},
+		shareLinks:    map[primitive.ObjectID]models.ShareLink{},
+		embedTokens:   map[primitive.ObjectID]models.EmbedToken{},
+		access:        map[primitive.ObjectID]models.DiagramAccess{},
+		stars:         map[primitive.ObjectID]models.DiagramStar{},
+		audit:         map[primitive.ObjectID]models.AuditEntry{},
+		activity:      map[primitive.ObjectID]models.ActivityEntry{},
+		comments:      map[primitive.ObjectID]models.Comment{},
+		mentions:      map[primitive.ObjectID]models.Mention{},
+		notifications: map[primitive.ObjectID]models.Notification{},
+		outbox:        map[primitive.ObjectID]repository.OutboxEmail{},
+		webhooks:      map[primitive.ObjectID]models.Webhook{},
+		deliveries:    map[primitive.ObjectID]models.WebhookDelivery{},
+	}
+	return &repository.Store{
+		Users:         &UserRepository{db},
+		RefreshTokens: &RefreshTokenRepository{db},
+		AccessTokens:  &PersonalAccessTokenRepository{db},
+		Workspaces:    &WorkspaceRepository{db},
+		Members:       &MemberRepository{db},
+		Invites:       &InviteRepository{db},
+		Folders:       &FolderRepository{db},
+		Diagrams:      &DiagramRepository{db},
+		Tags:          &TagRepository{db},
+		ShareLinks:    &ShareLinkRepository{db},
+		EmbedTokens:   &EmbedTokenRepository{db},
+		Access:        &AccessRepository{db},
+		Stars:         &StarRepository{db},
+		Audit:         &AuditRepository{db},
+		Activity:      &ActivityRepository{db},
+		Comments:      &CommentRepository{db},
+		Mentions:      &MentionRepository{db},
+		Notifications: &NotificationRepository{db},
+		Outbox:        &OutboxRepository{db},
+		Webhooks:      &WebhookRepository{db},
+		Deliveries:    &WebhookDeliveryRepository{db},
+		Connected:     func() bool { return true },
+	}
+}
+
+// db holds every record of one store behind a single lock
+type db struct {
+	mu            sync.RWMutex
+	users         map[primitive.ObjectID]authModels.User
+	refreshTokens map[primitive.ObjectID]authModels.RefreshToken
+	accessTokens  map[primitive.ObjectID]authModels.PersonalAccessToken
+	workspaces    map[primitive.ObjectID]models.Workspace
+	members       map[primitive.ObjectID]models.WorkspaceMember
+	invites       map[primitive.ObjectID]models.WorkspaceInvite
+	folders       map[primitive.ObjectID]models.Folder
+	diagrams      map[primitive.ObjectID]models.Diagram
+	tags          map[primitive.ObjectID]models.WorkspaceTag
+	shareLinks    map[primitive.ObjectID]models.ShareLink
+	embedTokens   map[primitive.ObjectID]models.EmbedToken
+	access        map[primitive.ObjectID]models.DiagramAccess
+	stars         map[primitive.ObjectID]models.DiagramStar
+	audit         map[primitive.ObjectID]models.AuditEntry
+	activity      map[primitive.ObjectID]models.ActivityEntry
+	comments      map[primitive.ObjectID]models.Comment
+	mentions      map[primitive.ObjectID]models.Mention
+	notifications map[primitive.ObjectID]models.Notification
+	outbox        map[primitive.ObjectID]repository.OutboxEmail
+	webhooks      map[primitive.ObjectID]models.Webhook
+	deliveries    map[primitive.ObjectID]models.WebhookDelivery
+}
+
+var errUnsupportedSort = errors.New("unsupported sort field")
+
+// compare orders two sort values of the same kind; strings compare case-insensitively
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b.(string)))
+	case int64:
+		switch b := b.(int64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// timeValue turns an optional timestamp into a sort value; unset sorts first
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// paginate orders items by the page's sort value, tie-broken by ID, and returns one page.
+// sortValue reports false for sort fields the listing does not support.
+func paginate[T any](items []T, page *repository.PageQuery, id func(T) primitive.ObjectID, sortValue func(T, models.SortField) (interface{}, bool)) ([]T, error) {
+	var zero T
+	if _, ok := sortValue(zero, page.Sort); !ok {
+		return nil, errUnsupportedSort
+	}
+
+	dir := -1
+	if page.Order == models.SortAsc {
+		dir = 1
+	}
+	cmp := func(v interface{}, vid primitive.ObjectID, w interface{}, wid primitive.ObjectID) int {
+		if c := compare(v, w); c != 0 {
+			return c * dir
+		}
+		return bytes.Compare(vid[:], wid[:]) * dir
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		vi, _ := sortValue(items[i], page.Sort)
+		vj, _ := sortValue(items[j], page.Sort)
+		return cmp(vi, id(items[i]), vj, id(items[j])) < 0
+	})
+
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		if page.After != nil {
+			v, _ := sortValue(item, page.Sort)
+			if cmp(v, id(item), page.After.Value, page.After.ID) <= 0 {
+				continue
+			}
+		}
+		if page.Limit > 0 && int64(len(result)) == page.Limit {
+			break
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// inRange reports whether t falls within the date range
+func inRange(t time.Time, r models.DateRange) bool {
+	if r.After != nil && t.Before(*r.After) {
+		return false
+	}
+	if r.Before != nil && !t.Before(*r.Before) {
+		return false
+	}
+	return true
+}
+
+// matchesTrash reports whether an item with the given deletion time is in the trash state
+func matchesTrash(deletedAt *time.Time, trash repository.Trash) bool {
+	switch trash {
+	case repository.Live:
+		return deletedAt == nil
+	case repository.Trashed:
+		return deletedAt != nil
+	}
+	return true
+}
+
+// containsFold reports whether s contains substr, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// idSet turns a list of IDs into a set
+func idSet(ids []primitive.ObjectID) map[primitive.ObjectID]bool {
+	set := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// sameID reports whether an optional ID equals id
+func sameID(a *primitive.ObjectID, id primitive.ObjectID) bool {
+	return a != nil && *a == id
+}
+
+// copyID returns a copy of an optional ID so records never alias caller memory
+func copyID(id *primitive.ObjectID) *primitive.ObjectID {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	return &c
+}
+
+// copyTime returns a copy of an optional timestamp
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Store { return New() })
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MentionRepository keeps mentions in memory
+type MentionRepository struct{ db *db }
+
+// Replace sets the users a source mentions and returns those it did not mention before
+func (r *MentionRepository) Replace(ctx context.Context, workspaceID, diagramID primitive.ObjectID, source models.MentionSource, sourceID primitive.ObjectID, userIDs []primitive.ObjectID, now time.Time) ([]primitive.ObjectID, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	wanted := idSet(userIDs)
+	existing := map[primitive.ObjectID]bool{}
+	for id, m := range r.db.mentions {
+		if m.WorkspaceID != workspaceID || m.Source != source || m.SourceID != sourceID {
+			continue
+		}
+		if wanted[m.UserID] {
+			existing[m.UserID] = true
+		} else {
+			delete(r.db.mentions, id)
+		}
+	}
+
+	var added []primitive.ObjectID
+	for _, userID := range userIDs {
+		if existing[userID] {
+			continue
+		}
+		existing[userID] = true
+		id := primitive.NewObjectID()
+		r.db.mentions[id] = models.Mention{
+			ID:          id,
+			WorkspaceID: workspaceID,
+			DiagramID:   diagramID,
+			Source:      source,
+			SourceID:    sourceID,
+			UserID:      userID,
+			CreatedAt:   now,
+		}
+		added = append(added, userID)
+	}
+	return added, nil
+}
+
+// ListForDiagram returns the mentions on a diagram, oldest first
+func (r *MentionRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Mention, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var mentions []*models.Mention
+	for _, m := range r.db.mentions {
+		if m.WorkspaceID == workspaceID && m.DiagramID == diagramID {
+			m := m
+			mentions = append(mentions, &m)
+		}
+	}
+	sort.Slice(mentions, func(i, j int) bool {
+		return newestFirst(mentions[j].CreatedAt, mentions[i].CreatedAt, mentions[j].ID, mentions[i].ID)
+	})
+	return mentions, nil
+}
+
+// DeleteForSources drops the mentions of the given sources
+func (r *MentionRepository) DeleteForSources(ctx context.Context, workspaceID primitive.ObjectID, source models.MentionSource, sourceIDs []primitive.ObjectID) error {
+	sources := idSet(sourceIDs)
+	r.deleteWhere(func(m *models.Mention) bool {
+		return m.WorkspaceID == workspaceID && m.Source == source && sources[m.SourceID]
+	})
+	return nil
+}
+
+// DeleteForDiagram removes every mention on a diagram
+func (r *MentionRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	r.deleteWhere(func(m *models.Mention) bool { return m.WorkspaceID == workspaceID && m.DiagramID == diagramID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every mention of a workspace
+func (r *MentionRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(m *models.Mention) bool { return m.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *MentionRepository) deleteWhere(match func(*models.Mention) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, m := range r.db.mentions {
+		if match(&m) {
+			delete(r.db.mentions, id)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationRepository keeps notifications in memory
+type NotificationRepository struct{ db *db }
+
+// Create inserts a notification
+func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	r.db.notifications[notification.ID] = copyNotification(*notification)
+	return nil
+}
+
+// copyNotification detaches a notification from the caller's pointers
+func copyNotification(n models.Notification) models.Notification {
+	n.ActorID = copyID(n.ActorID)
+	n.ReadAt = copyTime(n.ReadAt)
+	n.DigestedAt = copyTime(n.DigestedAt)
+	return n
+}
+
+// FindUnread returns a user's unread notification of a type about a target
+func (r *NotificationRepository) FindUnread(ctx context.Context, userID primitive.ObjectID, notificationType models.NotificationType, targetID primitive.ObjectID) (*models.Notification, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, n := range r.db.notifications {
+		if n.UserID == userID && n.Type == notificationType && n.TargetID == targetID && n.ReadAt == nil {
+			n = copyNotification(n)
+			return &n, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Bump folds another change into an unread notification
+func (r *NotificationRepository) Bump(ctx context.Context, userID, id primitive.ObjectID, actorID *primitive.ObjectID, actorName, message string, updatedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	n, ok := r.db.notifications[id]
+	if !ok || n.UserID != userID || n.ReadAt != nil {
+		return repository.ErrNotFound
+	}
+	n.Count++
+	n.ActorID = copyID(actorID)
+	n.ActorName = actorName
+	n.Message = message
+	n.UpdatedAt = updatedAt
+	n.DigestedAt = nil
+	r.db.notifications[id] = n
+	return nil
+}
+
+// List pages through a user's notifications
+func (r *NotificationRepository) List(ctx context.Context, userID primitive.ObjectID, filter *models.NotificationFilter, page *repository.PageQuery) ([]*models.Notification, error) {
+	r.db.mu.RLock()
+	var notifications []models.Notification
+	for _, n := range r.db.notifications {
+		if n.UserID == userID && (filter == nil || !filter.UnreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, copyNotification(n))
+		}
+	}
+	r.db.mu.RUnlock()
+
+	notifications, err := paginate(notifications, page,
+		func(n models.Notification) primitive.ObjectID { return n.ID },
+		func(n models.Notification, field models.SortField) (interface{}, bool) {
+			if field == models.SortByUpdated {
+				return n.UpdatedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.Notification, len(notifications))
+	for i := range notifications {
+		result[i] = &notifications[i]
+	}
+	return result, nil
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var count int64
+	for _, n := range r.db.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MarkRead marks some of a user's notifications read
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, readAt time.Time) (int64, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return r.markRead(userID, func(n *models.Notification) bool { return wanted[n.ID] }, readAt), nil
+}
+
+// MarkAllRead marks every notification of a user read
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID primitive.ObjectID, readAt time.Time) (int64, error) {
+	return r.markRead(userID, func(*models.Notification) bool { return true }, readAt), nil
+}
+
+func (r *NotificationRepository) markRead(userID primitive.ObjectID, match func(*models.Notification) bool, readAt time.Time) int64 {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var marked int64
+	for id, n := range r.db.notifications {
+		if n.UserID == userID && n.ReadAt == nil && match(&n) {
+			n.ReadAt = copyTime(&readAt)
+			r.db.notifications[id] = n
+			marked++
+		}
+	}
+	return marked
+}
+
+// PendingDigest returns unread notifications not yet in a digest, by user and then update time
+func (r *NotificationRepository) PendingDigest(ctx context.Context, userID *primitive.ObjectID, updatedBefore time.Time, limit int64) ([]*models.Notification, error) {
+	r.db.mu.RLock()
+	var pending []*models.Notification
+	for _, n := range r.db.notifications {
+		if userID != nil && n.UserID != *userID {
+			continue
+		}
+		if n.ReadAt == nil && n.DigestedAt == nil && n.UpdatedAt.Before(updatedBefore) {
+			n = copyNotification(n)
+			pending = append(pending, &n)
+		}
+	}
+	r.db.mu.RUnlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		if a.UserID != b.UserID {
+			return a.UserID.Hex() < b.UserID.Hex()
+		}
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		}
+		return a.ID.Hex() < b.ID.Hex()
+	})
+	if limit > 0 && int64(len(pending)) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+// MarkDigested records that notifications were emailed in a digest
+func (r *NotificationRepository) MarkDigested(ctx context.Context, ids []primitive.ObjectID, digestedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, id := range ids {
+		if n, ok := r.db.notifications[id]; ok {
+			n.DigestedAt = copyTime(&digestedAt)
+			r.db.notifications[id] = n
+		}
+	}
+	return nil
+}
+
+// DeleteAllForWorkspace deletes the notifications about a workspace
+func (r *NotificationRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, n := range r.db.notifications {
+		if n.WorkspaceID == workspaceID {
+			delete(r.db.notifications, id)
+		}
+	}
+	return nil
+}
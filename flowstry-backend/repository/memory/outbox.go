@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxRepository keeps queued emails in memory
+type OutboxRepository struct{ db *db }
+
+// Enqueue inserts an email
+func (r *OutboxRepository) Enqueue(ctx context.Context, email *repository.OutboxEmail) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if email.ID.IsZero() {
+		email.ID = primitive.NewObjectID()
+	}
+	e := *email
+	e.SentAt = copyTime(e.SentAt)
+	r.db.outbox[e.ID] = e
+	return nil
+}
+
+// Due returns pending emails whose next attempt is due
+func (r *OutboxRepository) Due(ctx context.Context, now time.Time, limit int64) ([]*repository.OutboxEmail, error) {
+	r.db.mu.RLock()
+	var emails []*repository.OutboxEmail
+	for _, e := range r.db.outbox {
+		if e.Status == repository.OutboxPending && !e.NextAttemptAt.After(now) {
+			e.SentAt = copyTime(e.SentAt)
+			emails = append(emails, &e)
+		}
+	}
+	r.db.mu.RUnlock()
+
+	sort.Slice(emails, func(i, j int) bool {
+		if !emails[i].NextAttemptAt.Equal(emails[j].NextAttemptAt) {
+			return emails[i].NextAttemptAt.Before(emails[j].NextAttemptAt)
+		}
+		return emails[i].ID.Hex() < emails[j].ID.Hex()
+	})
+	if limit > 0 && int64(len(emails)) > limit {
+		emails = emails[:limit]
+	}
+	return emails, nil
+}
+
+// Claim leases a due email
+func (r *OutboxRepository) Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error {
+	return r.update(id, func(e *repository.OutboxEmail) bool {
+		if e.Status != repository.OutboxPending || e.NextAttemptAt.After(now) {
+			return false
+		}
+		e.NextAttemptAt = leaseUntil
+		return true
+	})
+}
+
+// MarkSent records the delivery of an email
+func (r *OutboxRepository) MarkSent(ctx context.Context, id primitive.ObjectID, attempts int, sentAt time.Time) error {
+	return r.update(id, func(e *repository.OutboxEmail) bool {
+		e.Status = repository.OutboxSent
+		e.Attempts = attempts
+		e.SentAt = copyTime(&sentAt)
+		return true
+	})
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *OutboxRepository) MarkRetry(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.update(id, func(e *repository.OutboxEmail) bool {
+		e.Attempts = attempts
+		e.NextAttemptAt = nextAttemptAt
+		e.LastError = lastError
+		return true
+	})
+}
+
+// MarkFailed gives up on an email
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, attempts int, lastError string) error {
+	return r.update(id, func(e *repository.OutboxEmail) bool {
+		e.Status = repository.OutboxFailed
+		e.Attempts = attempts
+		e.LastError = lastError
+		return true
+	})
+}
+
+// update applies change to an email; change reports whether the email matched
+func (r *OutboxRepository) update(id primitive.ObjectID, change func(*repository.OutboxEmail) bool) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	e, ok := r.db.outbox[id]
+	if !ok || !change(&e) {
+		return repository.ErrNotFound
+	}
+	r.db.outbox[id] = e
+	return nil
+}
+
+// DeleteSentBefore prunes delivered emails
+func (r *OutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var deleted int64
+	for id, e := range r.db.outbox {
+		if e.Status == repository.OutboxSent && e.SentAt != nil && e.SentAt.Before(before) {
+			delete(r.db.outbox, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
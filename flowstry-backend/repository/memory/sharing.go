@@ -0,0 +1,399 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newestFirst orders records by creation time, newest first, tie-broken by ID
+func newestFirst(a, b time.Time, aID, bID primitive.ObjectID) bool {
+	if !a.Equal(b) {
+		return a.After(b)
+	}
+	return bytes.Compare(aID[:], bID[:]) > 0
+}
+
+// ShareLinkRepository keeps share links in memory
+type ShareLinkRepository struct{ db *db }
+
+// Create inserts a share link; token hashes are unique
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, l := range r.db.shareLinks {
+		if l.TokenHash == link.TokenHash {
+			return repository.ErrDuplicate
+		}
+	}
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	r.db.shareLinks[link.ID] = copyShareLink(*link)
+	return nil
+}
+
+// copyShareLink detaches a share link from the caller's pointers
+func copyShareLink(l models.ShareLink) models.ShareLink {
+	l.ExpiresAt = copyTime(l.ExpiresAt)
+	l.RevokedAt = copyTime(l.RevokedAt)
+	l.LastViewedAt = copyTime(l.LastViewedAt)
+	return l
+}
+
+// GetByTokenHash retrieves a share link by its token hash
+func (r *ShareLinkRepository) GetByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, l := range r.db.shareLinks {
+		if l.TokenHash == hash {
+			l = copyShareLink(l)
+			return &l, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// ListForDiagram returns the share links of a diagram, newest first
+func (r *ShareLinkRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.ShareLink, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var links []*models.ShareLink
+	for _, l := range r.db.shareLinks {
+		if l.WorkspaceID == workspaceID && l.DiagramID == diagramID {
+			l = copyShareLink(l)
+			links = append(links, &l)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return newestFirst(links[i].CreatedAt, links[j].CreatedAt, links[i].ID, links[j].ID)
+	})
+	return links, nil
+}
+
+// Revoke revokes an active share link of a diagram
+func (r *ShareLinkRepository) Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	l, ok := r.db.shareLinks[id]
+	if !ok || l.WorkspaceID != workspaceID || l.DiagramID != diagramID || l.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	l.RevokedAt = &at
+	r.db.shareLinks[id] = l
+	return nil
+}
+
+// RecordView counts a view of a share link
+func (r *ShareLinkRepository) RecordView(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if l, ok := r.db.shareLinks[id]; ok {
+		l.LastViewedAt = &at
+		l.ViewCount++
+		r.db.shareLinks[id] = l
+	}
+	return nil
+}
+
+// DeleteForDiagram removes every share link of a diagram
+func (r *ShareLinkRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	r.deleteWhere(func(l *models.ShareLink) bool { return l.WorkspaceID == workspaceID && l.DiagramID == diagramID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every share link of a workspace
+func (r *ShareLinkRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(l *models.ShareLink) bool { return l.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *ShareLinkRepository) deleteWhere(match func(*models.ShareLink) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, l := range r.db.shareLinks {
+		if match(&l) {
+			delete(r.db.shareLinks, id)
+		}
+	}
+}
+
+// EmbedTokenRepository keeps embed tokens in memory
+type EmbedTokenRepository struct{ db *db }
+
+// Create inserts an embed token; token hashes are unique
+func (r *EmbedTokenRepository) Create(ctx context.Context, token *models.EmbedToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, t := range r.db.embedTokens {
+		if t.TokenHash == token.TokenHash {
+			return repository.ErrDuplicate
+		}
+	}
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	r.db.embedTokens[token.ID] = copyEmbedToken(*token)
+	return nil
+}
+
+// copyEmbedToken detaches an embed token from the caller's pointers
+func copyEmbedToken(t models.EmbedToken) models.EmbedToken {
+	t.RevokedAt = copyTime(t.RevokedAt)
+	t.LastUsedAt = copyTime(t.LastUsedAt)
+	return t
+}
+
+// GetByTokenHash retrieves an embed token by its hash
+func (r *EmbedTokenRepository) GetByTokenHash(ctx context.Context, hash string) (*models.EmbedToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, t := range r.db.embedTokens {
+		if t.TokenHash == hash {
+			t = copyEmbedToken(t)
+			return &t, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// ListForDiagram returns the embed tokens of a diagram, newest first
+func (r *EmbedTokenRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.EmbedToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var tokens []*models.EmbedToken
+	for _, t := range r.db.embedTokens {
+		if t.WorkspaceID == workspaceID && t.DiagramID == diagramID {
+			t = copyEmbedToken(t)
+			tokens = append(tokens, &t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return newestFirst(tokens[i].CreatedAt, tokens[j].CreatedAt, tokens[i].ID, tokens[j].ID)
+	})
+	return tokens, nil
+}
+
+// Revoke revokes an active embed token of a diagram
+func (r *EmbedTokenRepository) Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.embedTokens[id]
+	if !ok || t.WorkspaceID != workspaceID || t.DiagramID != diagramID || t.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	t.RevokedAt = &at
+	r.db.embedTokens[id] = t
+	return nil
+}
+
+// RecordUse marks an embed token as used
+func (r *EmbedTokenRepository) RecordUse(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if t, ok := r.db.embedTokens[id]; ok {
+		t.LastUsedAt = &at
+		r.db.embedTokens[id] = t
+	}
+	return nil
+}
+
+// DeleteForDiagram removes every embed token of a diagram
+func (r *EmbedTokenRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	r.deleteWhere(func(t *models.EmbedToken) bool { return t.WorkspaceID == workspaceID && t.DiagramID == diagramID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every embed token of a workspace
+func (r *EmbedTokenRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(t *models.EmbedToken) bool { return t.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *EmbedTokenRepository) deleteWhere(match func(*models.EmbedToken) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, t := range r.db.embedTokens {
+		if match(&t) {
+			delete(r.db.embedTokens, id)
+		}
+	}
+}
+
+// AccessRepository keeps diagram opens in memory
+type AccessRepository struct{ db *db }
+
+// RecordOpen upserts the user's access record of a diagram
+func (r *AccessRepository) RecordOpen(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, a := range r.db.access {
+		if a.UserID == userID && a.DiagramID == diagramID {
+			a.WorkspaceID = workspaceID
+			a.LastOpenedAt = at
+			a.OpenCount++
+			r.db.access[id] = a
+			return nil
+		}
+	}
+	id := primitive.NewObjectID()
+	r.db.access[id] = models.DiagramAccess{
+		ID:           id,
+		UserID:       userID,
+		WorkspaceID:  workspaceID,
+		DiagramID:    diagramID,
+		LastOpenedAt: at,
+		OpenCount:    1,
+	}
+	return nil
+}
+
+// ListRecent returns the user's most recent access records in the given workspaces
+func (r *AccessRepository) ListRecent(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID, limit int64) ([]*models.DiagramAccess, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	workspaces := idSet(workspaceIDs)
+	var records []*models.DiagramAccess
+	for _, a := range r.db.access {
+		if a.UserID == userID && workspaces[a.WorkspaceID] {
+			a := a
+			records = append(records, &a)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return newestFirst(records[i].LastOpenedAt, records[j].LastOpenedAt, records[i].ID, records[j].ID)
+	})
+	if limit > 0 && int64(len(records)) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// DeleteForDiagram removes every access record of a diagram
+func (r *AccessRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	r.deleteWhere(func(a *models.DiagramAccess) bool { return a.WorkspaceID == workspaceID && a.DiagramID == diagramID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every access record of a workspace
+func (r *AccessRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(a *models.DiagramAccess) bool { return a.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *AccessRepository) deleteWhere(match func(*models.DiagramAccess) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, a := range r.db.access {
+		if match(&a) {
+			delete(r.db.access, id)
+		}
+	}
+}
+
+// StarRepository keeps starred diagrams in memory
+type StarRepository struct{ db *db }
+
+// Star stores a star unless the user already starred the diagram
+func (r *StarRepository) Star(ctx context.Context, star *models.DiagramStar) (*models.DiagramStar, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, s := range r.db.stars {
+		if s.UserID == star.UserID && s.DiagramID == star.DiagramID {
+			return &s, nil
+		}
+	}
+	stored := *star
+	if stored.ID.IsZero() {
+		stored.ID = primitive.NewObjectID()
+	}
+	r.db.stars[stored.ID] = stored
+	return &stored, nil
+}
+
+// Unstar removes a star
+func (r *StarRepository) Unstar(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, s := range r.db.stars {
+		if s.UserID == userID && s.WorkspaceID == workspaceID && s.DiagramID == diagramID {
+			delete(r.db.stars, id)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// ListForUser returns the user's stars in the given workspaces
+func (r *StarRepository) ListForUser(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID) ([]*models.DiagramStar, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	workspaces := idSet(workspaceIDs)
+	var stars []*models.DiagramStar
+	for _, s := range r.db.stars {
+		if s.UserID == userID && workspaces[s.WorkspaceID] {
+			s := s
+			stars = append(stars, &s)
+		}
+	}
+	return stars, nil
+}
+
+// IsStarred reports whether the user starred a diagram
+func (r *StarRepository) IsStarred(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, s := range r.db.stars {
+		if s.UserID == userID && s.WorkspaceID == workspaceID && s.DiagramID == diagramID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteForDiagram removes every star of a diagram
+func (r *StarRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	r.deleteWhere(func(s *models.DiagramStar) bool { return s.WorkspaceID == workspaceID && s.DiagramID == diagramID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every star of a workspace
+func (r *StarRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(s *models.DiagramStar) bool { return s.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *StarRepository) deleteWhere(match func(*models.DiagramStar) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, s := range r.db.stars {
+		if match(&s) {
+			delete(r.db.stars, id)
+		}
+	}
+}
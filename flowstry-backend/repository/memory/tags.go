@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TagRepository keeps tag definitions in memory
+type TagRepository struct{ db *db }
+
+// Create inserts a tag; names are unique within a workspace
+func (r *TagRepository) Create(ctx context.Context, tag *models.WorkspaceTag) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if r.named(tag.WorkspaceID, tag.Name, primitive.NilObjectID) {
+		return repository.ErrDuplicate
+	}
+	if tag.ID.IsZero() {
+		tag.ID = primitive.NewObjectID()
+	}
+	r.db.tags[tag.ID] = *tag
+	return nil
+}
+
+// named reports whether a tag other than except carries the name; the caller holds the lock
+func (r *TagRepository) named(workspaceID primitive.ObjectID, name string, except primitive.ObjectID) bool {
+	for id, t := range r.db.tags {
+		if id != except && t.WorkspaceID == workspaceID && t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Get retrieves a tag of a workspace
+func (r *TagRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.WorkspaceTag, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	t, ok := r.db.tags[id]
+	if !ok || t.WorkspaceID != workspaceID {
+		return nil, repository.ErrNotFound
+	}
+	return &t, nil
+}
+
+// List returns the tags of a workspace ordered by name
+func (r *TagRepository) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceTag, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var tags []*models.WorkspaceTag
+	for _, t := range r.db.tags {
+		if t.WorkspaceID == workspaceID {
+			t := t
+			tags = append(tags, &t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// Update changes the given fields of a tag
+func (r *TagRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.TagUpdate) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.tags[id]
+	if !ok || t.WorkspaceID != workspaceID {
+		return repository.ErrNotFound
+	}
+	if update.Name != nil {
+		if r.named(workspaceID, *update.Name, id) {
+			return repository.ErrDuplicate
+		}
+		t.Name = *update.Name
+	}
+	if update.Color != nil {
+		t.Color = *update.Color
+	}
+	t.UpdatedAt = update.UpdatedAt
+	r.db.tags[id] = t
+	return nil
+}
+
+// EnsureDefined creates default-colored tags for names that have none
+func (r *TagRepository) EnsureDefined(ctx context.Context, workspaceID primitive.ObjectID, names []string, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, name := range names {
+		if r.named(workspaceID, name, primitive.NilObjectID) {
+			continue
+		}
+		id := primitive.NewObjectID()
+		r.db.tags[id] = models.WorkspaceTag{
+			ID:          id,
+			WorkspaceID: workspaceID,
+			Name:        name,
+			Color:       models.DefaultTagColor,
+			CreatedAt:   at,
+			UpdatedAt:   at,
+		}
+	}
+	return nil
+}
+
+// Delete removes a tag
+func (r *TagRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.tags[id]
+	if !ok || t.WorkspaceID != workspaceID {
+		return repository.ErrNotFound
+	}
+	delete(r.db.tags, id)
+	return nil
+}
+
+// DeleteAllForWorkspace removes every tag of a workspace
+func (r *TagRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, t := range r.db.tags {
+		if t.WorkspaceID == workspaceID {
+			delete(r.db.tags, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository keeps users in memory
+type UserRepository struct{ db *db }
+
+// Create inserts a user; emails are unique
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, u := range r.db.users {
+		if u.Email == user.Email {
+			return repository.ErrDuplicate
+		}
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	r.db.users[user.ID] = copyUser(*user)
+	return nil
+}
+
+// Get retrieves a user by ID
+func (r *UserRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(func(u *models.User) bool { return u.ID == id })
+}
+
+// GetByEmail retrieves a user by email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(func(u *models.User) bool { return u.Email == email })
+}
+
+// GetByGoogleID retrieves a user by linked Google account
+func (r *UserRepository) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
+	return r.findOne(func(u *models.User) bool { return u.GoogleID != "" && u.GoogleID == googleID })
+}
+
+func (r *UserRepository) findOne(match func(*models.User) bool) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, u := range r.db.users {
+		if match(&u) {
+			u = copyUser(u)
+			return &u, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// LinkGoogle attaches a Google account to a user
+func (r *UserRepository) LinkGoogle(ctx context.Context, id primitive.ObjectID, googleID, avatarURL string) error {
+	return r.update(id, func(u *models.User) {
+		u.GoogleID = googleID
+		u.AuthProvider = models.AuthProviderGoogle
+		u.AvatarURL = avatarURL
+	})
+}
+
+// UpdatePreferences replaces a user's preferences
+func (r *UserRepository) UpdatePreferences(ctx context.Context, id primitive.ObjectID, prefs models.UserPreferences) error {
+	return r.update(id, func(u *models.User) { u.Preferences = prefs })
+}
+
+// UpdateNotificationPreferences replaces a user's notification preferences
+func (r *UserRepository) UpdateNotificationPreferences(ctx context.Context, id primitive.ObjectID, prefs models.NotificationPreferences) error {
+	return r.update(id, func(u *models.User) {
+		u.Notifications = models.NotificationPreferences{Muted: append([]string(nil), prefs.Muted...)}
+	})
+}
+
+// copyUser detaches a user from the caller's slices
+func copyUser(u models.User) models.User {
+	u.Notifications.Muted = append([]string(nil), u.Notifications.Muted...)
+	return u
+}
+
+// update changes a user in place; like the Mongo repository, a missing user is not an error
+func (r *UserRepository) update(id primitive.ObjectID, change func(*models.User)) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	u, ok := r.db.users[id]
+	if !ok {
+		return nil
+	}
+	change(&u)
+	u.UpdatedAt = time.Now()
+	r.db.users[id] = u
+	return nil
+}
+
+// RefreshTokenRepository keeps hashed refresh tokens in memory
+type RefreshTokenRepository struct{ db *db }
+
+// Create inserts a refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	r.db.refreshTokens[token.ID] = *token
+	return nil
+}
+
+// GetByHash retrieves a refresh token by its hash
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, t := range r.db.refreshTokens {
+		if t.Token == hash {
+			return &t, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Revoke revokes a refresh token
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, hash string) error {
+	r.revoke(func(t *models.RefreshToken) bool { return t.Token == hash })
+	return nil
+}
+
+// RevokeAllForUser revokes every refresh token of a user
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	r.revoke(func(t *models.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *RefreshTokenRepository) revoke(match func(*models.RefreshToken) bool) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, t := range r.db.refreshTokens {
+		if match(&t) {
+			t.Revoked = true
+			r.db.refreshTokens[id] = t
+		}
+	}
+}
+
+// PersonalAccessTokenRepository keeps hashed personal access tokens in memory
+type PersonalAccessTokenRepository struct{ db *db }
+
+func copyAccessToken(t models.PersonalAccessToken) *models.PersonalAccessToken {
+	t.WorkspaceID = copyID(t.WorkspaceID)
+	t.Scopes = append([]models.TokenScope(nil), t.Scopes...)
+	t.LastUsedAt = copyTime(t.LastUsedAt)
+	return &t
+}
+
+// Create inserts a personal access token; hashes are unique
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, t := range r.db.accessTokens {
+		if t.Token == token.Token {
+			return repository.ErrDuplicate
+		}
+	}
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	r.db.accessTokens[token.ID] = *copyAccessToken(*token)
+	return nil
+}
+
+// GetByHash retrieves a personal access token by its hash
+func (r *PersonalAccessTokenRepository) GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, t := range r.db.accessTokens {
+		if t.Token == hash {
+			return copyAccessToken(t), nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// ListForUser returns the tokens of a user, newest first
+func (r *PersonalAccessTokenRepository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var tokens []*models.PersonalAccessToken
+	for _, t := range r.db.accessTokens {
+		if t.UserID == userID {
+			tokens = append(tokens, copyAccessToken(t))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return newestFirst(tokens[i].CreatedAt, tokens[j].CreatedAt, tokens[i].ID, tokens[j].ID)
+	})
+	return tokens, nil
+}
+
+// Touch records when a token was last used
+func (r *PersonalAccessTokenRepository) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.accessTokens[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	t.LastUsedAt = &at
+	r.db.accessTokens[id] = t
+	return nil
+}
+
+// Delete removes a token of a user
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if t, ok := r.db.accessTokens[id]; !ok || t.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(r.db.accessTokens, id)
+	return nil
+}
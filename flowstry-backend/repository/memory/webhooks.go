@@ -0,0 +1,336 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookRepository keeps webhooks in memory
+type WebhookRepository struct{ db *db }
+
+// copyWebhook detaches a webhook from the caller's slices
+func copyWebhook(w models.Webhook) models.Webhook {
+	w.Events = append([]models.EventType(nil), w.Events...)
+	w.EncryptedSecret = append([]byte(nil), w.EncryptedSecret...)
+	return w
+}
+
+// Create inserts a webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if webhook.ID.IsZero() {
+		webhook.ID = primitive.NewObjectID()
+	}
+	r.db.webhooks[webhook.ID] = copyWebhook(*webhook)
+	return nil
+}
+
+// Get retrieves a webhook of a workspace
+func (r *WebhookRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.Webhook, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	w, ok := r.db.webhooks[id]
+	if !ok || w.WorkspaceID != workspaceID {
+		return nil, repository.ErrNotFound
+	}
+	w = copyWebhook(w)
+	return &w, nil
+}
+
+// List returns the webhooks of a workspace, oldest first
+func (r *WebhookRepository) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Webhook, error) {
+	return r.find(func(w *models.Webhook) bool { return w.WorkspaceID == workspaceID }), nil
+}
+
+// ListSubscribed returns the enabled webhooks of a workspace that subscribe to an event type
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType) ([]*models.Webhook, error) {
+	return r.find(func(w *models.Webhook) bool {
+		return w.WorkspaceID == workspaceID && w.Enabled && w.Subscribes(eventType)
+	}), nil
+}
+
+func (r *WebhookRepository) find(match func(*models.Webhook) bool) []*models.Webhook {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var webhooks []*models.Webhook
+	for _, w := range r.db.webhooks {
+		if match(&w) {
+			w = copyWebhook(w)
+			webhooks = append(webhooks, &w)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return newestFirst(webhooks[j].CreatedAt, webhooks[i].CreatedAt, webhooks[j].ID, webhooks[i].ID)
+	})
+	return webhooks
+}
+
+// Update changes a webhook
+func (r *WebhookRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.WebhookUpdate) error {
+	return r.update(id, func(w *models.Webhook) bool {
+		if w.WorkspaceID != workspaceID {
+			return false
+		}
+		if update.URL != nil {
+			w.URL = *update.URL
+		}
+		if update.Description != nil {
+			w.Description = *update.Description
+		}
+		if update.Events != nil {
+			w.Events = append([]models.EventType(nil), update.Events...)
+		}
+		if update.Enabled != nil {
+			w.Enabled = *update.Enabled
+			if w.Enabled {
+				w.ConsecutiveFailures = 0
+				w.DisabledReason = ""
+			}
+		}
+		if update.EncryptedSecret != nil {
+			w.EncryptedSecret = append([]byte(nil), update.EncryptedSecret...)
+		}
+		w.UpdatedAt = update.UpdatedAt
+		return true
+	})
+}
+
+// RecordSuccess clears the failure count of a webhook
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id primitive.ObjectID) error {
+	return r.update(id, func(w *models.Webhook) bool {
+		w.ConsecutiveFailures = 0
+		return true
+	})
+}
+
+// RecordFailure counts a failed attempt and disables the webhook at maxFailures
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id primitive.ObjectID, maxFailures int, reason string, now time.Time) (bool, error) {
+	disabled := false
+	err := r.update(id, func(w *models.Webhook) bool {
+		w.ConsecutiveFailures++
+		if w.Enabled && w.ConsecutiveFailures >= maxFailures {
+			w.Enabled = false
+			w.DisabledReason = reason
+			w.UpdatedAt = now
+			disabled = true
+		}
+		return true
+	})
+	return disabled, err
+}
+
+// update applies change to a webhook; change reports whether the webhook matched
+func (r *WebhookRepository) update(id primitive.ObjectID, change func(*models.Webhook) bool) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	w, ok := r.db.webhooks[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	w = copyWebhook(w)
+	if !change(&w) {
+		return repository.ErrNotFound
+	}
+	r.db.webhooks[id] = w
+	return nil
+}
+
+// Delete removes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	w, ok := r.db.webhooks[id]
+	if !ok || w.WorkspaceID != workspaceID {
+		return repository.ErrNotFound
+	}
+	delete(r.db.webhooks, id)
+	return nil
+}
+
+// DeleteAllForWorkspace removes every webhook of a workspace
+func (r *WebhookRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, w := range r.db.webhooks {
+		if w.WorkspaceID == workspaceID {
+			delete(r.db.webhooks, id)
+		}
+	}
+	return nil
+}
+
+// WebhookDeliveryRepository keeps webhook deliveries in memory
+type WebhookDeliveryRepository struct{ db *db }
+
+// copyDelivery detaches a delivery from the caller's pointers
+func copyDelivery(d models.WebhookDelivery) models.WebhookDelivery {
+	d.RedeliveryOf = copyID(d.RedeliveryOf)
+	d.CompletedAt = copyTime(d.CompletedAt)
+	return d
+}
+
+// Create inserts a delivery
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	r.db.deliveries[delivery.ID] = copyDelivery(*delivery)
+	return nil
+}
+
+// Get retrieves a delivery of a webhook
+func (r *WebhookDeliveryRepository) Get(ctx context.Context, workspaceID, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	d, ok := r.db.deliveries[id]
+	if !ok || d.WorkspaceID != workspaceID || d.WebhookID != webhookID {
+		return nil, repository.ErrNotFound
+	}
+	d = copyDelivery(d)
+	return &d, nil
+}
+
+// List pages through the deliveries of a webhook
+func (r *WebhookDeliveryRepository) List(ctx context.Context, workspaceID, webhookID primitive.ObjectID, filter *models.WebhookDeliveryFilter, page *repository.PageQuery) ([]*models.WebhookDelivery, error) {
+	r.db.mu.RLock()
+	var deliveries []models.WebhookDelivery
+	for _, d := range r.db.deliveries {
+		if d.WorkspaceID != workspaceID || d.WebhookID != webhookID {
+			continue
+		}
+		if filter != nil && filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(d))
+	}
+	r.db.mu.RUnlock()
+
+	deliveries, err := paginate(deliveries, page,
+		func(d models.WebhookDelivery) primitive.ObjectID { return d.ID },
+		func(d models.WebhookDelivery, field models.SortField) (interface{}, bool) {
+			if field == models.SortByCreated {
+				return d.CreatedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.WebhookDelivery, len(deliveries))
+	for i := range deliveries {
+		result[i] = &deliveries[i]
+	}
+	return result, nil
+}
+
+// Due returns pending deliveries whose next attempt is due
+func (r *WebhookDeliveryRepository) Due(ctx context.Context, now time.Time, limit int64) ([]*models.WebhookDelivery, error) {
+	r.db.mu.RLock()
+	var deliveries []*models.WebhookDelivery
+	for _, d := range r.db.deliveries {
+		if d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			d = copyDelivery(d)
+			deliveries = append(deliveries, &d)
+		}
+	}
+	r.db.mu.RUnlock()
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].NextAttemptAt.Equal(deliveries[j].NextAttemptAt) {
+			return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+		}
+		return deliveries[i].ID.Hex() < deliveries[j].ID.Hex()
+	})
+	if limit > 0 && int64(len(deliveries)) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// Claim leases a due delivery
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error {
+	return r.update(id, func(d *models.WebhookDelivery) bool {
+		if d.Status != models.WebhookDeliveryPending || d.NextAttemptAt.After(now) {
+			return false
+		}
+		d.NextAttemptAt = leaseUntil
+		return true
+	})
+}
+
+// RecordAttempt stores the outcome of an attempt
+func (r *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt *repository.WebhookAttempt) error {
+	return r.update(id, func(d *models.WebhookDelivery) bool {
+		d.Status = attempt.Status
+		d.Attempts = attempt.Attempts
+		d.NextAttemptAt = attempt.NextAttemptAt
+		d.ResponseStatus = attempt.ResponseStatus
+		d.ResponseBody = attempt.ResponseBody
+		d.LastError = attempt.LastError
+		d.CompletedAt = copyTime(attempt.CompletedAt)
+		return true
+	})
+}
+
+// update applies change to a delivery; change reports whether the delivery matched
+func (r *WebhookDeliveryRepository) update(id primitive.ObjectID, change func(*models.WebhookDelivery) bool) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	d, ok := r.db.deliveries[id]
+	if !ok || !change(&d) {
+		return repository.ErrNotFound
+	}
+	r.db.deliveries[id] = d
+	return nil
+}
+
+// DeleteCompletedBefore prunes deliveries completed before a time
+func (r *WebhookDeliveryRepository) DeleteCompletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	return r.deleteWhere(func(d *models.WebhookDelivery) bool {
+		return d.CompletedAt != nil && d.CompletedAt.Before(before)
+	}), nil
+}
+
+// DeleteForWebhook removes every delivery of a webhook
+func (r *WebhookDeliveryRepository) DeleteForWebhook(ctx context.Context, workspaceID, webhookID primitive.ObjectID) error {
+	r.deleteWhere(func(d *models.WebhookDelivery) bool { return d.WorkspaceID == workspaceID && d.WebhookID == webhookID })
+	return nil
+}
+
+// DeleteAllForWorkspace removes every delivery of a workspace
+func (r *WebhookDeliveryRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.deleteWhere(func(d *models.WebhookDelivery) bool { return d.WorkspaceID == workspaceID })
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) deleteWhere(match func(*models.WebhookDelivery) bool) int64 {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var deleted int64
+	for id, d := range r.db.deliveries {
+		if match(&d) {
+			delete(r.db.deliveries, id)
+			deleted++
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,457 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkspaceRepository keeps workspaces in memory
+type WorkspaceRepository struct{ db *db }
+
+// Create inserts a workspace
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	r.db.workspaces[workspace.ID] = *workspace
+	return nil
+}
+
+// CreateWithOwner inserts a workspace and its owner's membership under one lock
+func (r *WorkspaceRepository) CreateWithOwner(ctx context.Context, workspace *models.Workspace, owner *models.WorkspaceMember) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	if owner.ID.IsZero() {
+		owner.ID = primitive.NewObjectID()
+	}
+	if _, taken := r.db.workspaces[workspace.ID]; taken {
+		return repository.ErrDuplicate
+	}
+	if _, taken := r.db.members[owner.ID]; taken {
+		return repository.ErrDuplicate
+	}
+	owner.WorkspaceID = workspace.ID
+	r.db.workspaces[workspace.ID] = *workspace
+	r.db.members[owner.ID] = *owner
+	return nil
+}
+
+// Get retrieves a workspace by ID
+func (r *WorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	w, ok := r.db.workspaces[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &w, nil
+}
+
+// ListByIDs retrieves the workspaces with the given IDs
+func (r *WorkspaceRepository) ListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Workspace, error) {
+	set := idSet(ids)
+	return r.find(func(w *models.Workspace) bool { return set[w.ID] }), nil
+}
+
+// ListOwnedBy retrieves the workspaces owned by a user
+func (r *WorkspaceRepository) ListOwnedBy(ctx context.Context, userID primitive.ObjectID) ([]*models.Workspace, error) {
+	return r.find(func(w *models.Workspace) bool { return w.UserID == userID }), nil
+}
+
+func (r *WorkspaceRepository) find(match func(*models.Workspace) bool) []*models.Workspace {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var workspaces []*models.Workspace
+	for _, w := range r.db.workspaces {
+		if match(&w) {
+			w := w
+			workspaces = append(workspaces, &w)
+		}
+	}
+	return workspaces
+}
+
+// Update changes the given workspace fields
+func (r *WorkspaceRepository) Update(ctx context.Context, id primitive.ObjectID, update *repository.WorkspaceUpdate) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	w, ok := r.db.workspaces[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if update.Name != nil {
+		w.Name = *update.Name
+	}
+	if update.Description != nil {
+		w.Description = *update.Description
+	}
+	if update.ContentSearchEnabled != nil {
+		w.ContentSearchEnabled = *update.ContentSearchEnabled
+	}
+	if update.TrashRetentionDays != nil {
+		w.TrashRetentionDays = *update.TrashRetentionDays
+	}
+	w.UpdatedAt = update.UpdatedAt
+	r.db.workspaces[id] = w
+	return nil
+}
+
+// Delete removes a workspace
+func (r *WorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.workspaces, id)
+	return nil
+}
+
+// MemberRepository keeps workspace memberships in memory
+type MemberRepository struct{ db *db }
+
+// Create inserts a membership; a user is a member of a workspace at most once
+func (r *MemberRepository) Create(ctx context.Context, member *models.WorkspaceMember) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, m := range r.db.members {
+		if m.WorkspaceID == member.WorkspaceID && m.UserID == member.UserID {
+			return repository.ErrDuplicate
+		}
+	}
+	if member.ID.IsZero() {
+		member.ID = primitive.NewObjectID()
+	}
+	r.db.members[member.ID] = *member
+	return nil
+}
+
+// Get retrieves the membership of a user in a workspace
+func (r *MemberRepository) Get(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	if _, m, ok := r.lookup(workspaceID, userID); ok {
+		return &m, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+// lookup finds a membership; the caller holds the lock
+func (r *MemberRepository) lookup(workspaceID, userID primitive.ObjectID) (primitive.ObjectID, models.WorkspaceMember, bool) {
+	for id, m := range r.db.members {
+		if m.WorkspaceID == workspaceID && m.UserID == userID {
+			return id, m, true
+		}
+	}
+	return primitive.NilObjectID, models.WorkspaceMember{}, false
+}
+
+// List pages through a workspace's members joined with their user profiles
+func (r *MemberRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.MemberQuery, page *repository.PageQuery) ([]*repository.MemberDetail, error) {
+	r.db.mu.RLock()
+	var details []repository.MemberDetail
+	for _, m := range r.db.members {
+		if m.WorkspaceID != workspaceID || !matchesMember(&m, query) {
+			continue
+		}
+		user, ok := r.db.users[m.UserID]
+		if !ok || (query != nil && !matchesPrefix(query.Prefix, user.Email, user.Name)) {
+			continue
+		}
+		details = append(details, repository.MemberDetail{
+			WorkspaceMember: m,
+			Email:           user.Email,
+			Name:            user.Name,
+			AvatarURL:       user.AvatarURL,
+		})
+	}
+	r.db.mu.RUnlock()
+
+	details, err := paginate(details, page,
+		func(d repository.MemberDetail) primitive.ObjectID { return d.ID },
+		func(d repository.MemberDetail, field models.SortField) (interface{}, bool) {
+			switch field {
+			case models.SortByName:
+				return d.Name, true
+			case models.SortByCreated:
+				return d.JoinedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*repository.MemberDetail, len(details))
+	for i := range details {
+		members[i] = &details[i]
+	}
+	return members, nil
+}
+
+// matchesMember reports whether a membership passes the listing query
+func matchesMember(m *models.WorkspaceMember, query *repository.MemberQuery) bool {
+	if query == nil {
+		return true
+	}
+	if len(query.Roles) > 0 {
+		found := false
+		for _, role := range query.Roles {
+			found = found || m.Role == role
+		}
+		if !found {
+			return false
+		}
+	}
+	switch {
+	case query.UserID != nil && m.UserID != *query.UserID:
+		return false
+	case query.UserID == nil && sameID(query.ExcludeUserID, m.UserID):
+		return false
+	}
+	return inRange(m.JoinedAt, query.Joined)
+}
+
+// matchesPrefix reports whether the email, the name or a word of the name starts with prefix, ignoring case
+func matchesPrefix(prefix, email, name string) bool {
+	prefix = strings.ToLower(prefix)
+	if strings.HasPrefix(strings.ToLower(email), prefix) {
+		return true
+	}
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(name), prefix)
+}
+
+// ListForUser retrieves every membership of a user
+func (r *MemberRepository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceMember, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var members []*models.WorkspaceMember
+	for _, m := range r.db.members {
+		if m.UserID == userID {
+			m := m
+			members = append(members, &m)
+		}
+	}
+	return members, nil
+}
+
+// Count returns the number of members of a workspace
+func (r *MemberRepository) Count(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var n int64
+	for _, m := range r.db.members {
+		if m.WorkspaceID == workspaceID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// UpdateRole changes the role of a member
+func (r *MemberRepository) UpdateRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.WorkspaceRole) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id, m, ok := r.lookup(workspaceID, userID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	m.Role = role
+	r.db.members[id] = m
+	return nil
+}
+
+// Delete removes a member from a workspace
+func (r *MemberRepository) Delete(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id, _, ok := r.lookup(workspaceID, userID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.db.members, id)
+	return nil
+}
+
+// DeleteAllForWorkspace removes every member of a workspace
+func (r *MemberRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, m := range r.db.members {
+		if m.WorkspaceID == workspaceID {
+			delete(r.db.members, id)
+		}
+	}
+	return nil
+}
+
+// InviteRepository keeps workspace invites in memory
+type InviteRepository struct{ db *db }
+
+// Create inserts an invite; tokens and (workspace, email) pairs are unique
+func (r *InviteRepository) Create(ctx context.Context, invite *models.WorkspaceInvite) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, i := range r.db.invites {
+		if i.Token == invite.Token || (i.WorkspaceID == invite.WorkspaceID && i.Email == invite.Email) {
+			return repository.ErrDuplicate
+		}
+	}
+	if invite.ID.IsZero() {
+		invite.ID = primitive.NewObjectID()
+	}
+	r.db.invites[invite.ID] = *invite
+	return nil
+}
+
+// Get retrieves an invite by ID
+func (r *InviteRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.WorkspaceInvite, error) {
+	return r.findOne(func(i *models.WorkspaceInvite) bool { return i.ID == id })
+}
+
+// GetByToken retrieves an invite by token
+func (r *InviteRepository) GetByToken(ctx context.Context, token string) (*models.WorkspaceInvite, error) {
+	return r.findOne(func(i *models.WorkspaceInvite) bool { return i.Token == token })
+}
+
+// GetByEmail retrieves the invite of an email to a workspace
+func (r *InviteRepository) GetByEmail(ctx context.Context, workspaceID primitive.ObjectID, email string) (*models.WorkspaceInvite, error) {
+	return r.findOne(func(i *models.WorkspaceInvite) bool {
+		return i.WorkspaceID == workspaceID && i.Email == email
+	})
+}
+
+func (r *InviteRepository) findOne(match func(*models.WorkspaceInvite) bool) (*models.WorkspaceInvite, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, i := range r.db.invites {
+		if match(&i) {
+			return &i, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// List pages through a workspace's invites
+func (r *InviteRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.InviteQuery, page *repository.PageQuery) ([]*models.WorkspaceInvite, error) {
+	invites := r.find(func(i *models.WorkspaceInvite) bool {
+		if i.WorkspaceID != workspaceID {
+			return false
+		}
+		if query == nil {
+			return true
+		}
+		return i.ExpiresAt.After(query.ExpiresAfter) &&
+			(query.InvitedBy == nil || i.InvitedBy == *query.InvitedBy) &&
+			inRange(i.CreatedAt, query.Created)
+	})
+
+	invites, err := paginate(invites, page,
+		func(i models.WorkspaceInvite) primitive.ObjectID { return i.ID },
+		func(i models.WorkspaceInvite, field models.SortField) (interface{}, bool) {
+			switch field {
+			case models.SortByName:
+				return i.Email, true
+			case models.SortByCreated:
+				return i.CreatedAt, true
+			}
+			return nil, false
+		})
+	if err != nil {
+		return nil, err
+	}
+	return invitePointers(invites), nil
+}
+
+// ListForEmail retrieves the valid invites addressed to an email, newest first
+func (r *InviteRepository) ListForEmail(ctx context.Context, email string, now time.Time) ([]*models.WorkspaceInvite, error) {
+	invites := r.find(func(i *models.WorkspaceInvite) bool {
+		return i.Email == email && i.ExpiresAt.After(now)
+	})
+	sort.Slice(invites, func(a, b int) bool { return invites[a].CreatedAt.After(invites[b].CreatedAt) })
+	return invitePointers(invites), nil
+}
+
+func (r *InviteRepository) find(match func(*models.WorkspaceInvite) bool) []models.WorkspaceInvite {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var invites []models.WorkspaceInvite
+	for _, i := range r.db.invites {
+		if match(&i) {
+			invites = append(invites, i)
+		}
+	}
+	return invites
+}
+
+func invitePointers(invites []models.WorkspaceInvite) []*models.WorkspaceInvite {
+	result := make([]*models.WorkspaceInvite, len(invites))
+	for i := range invites {
+		result[i] = &invites[i]
+	}
+	return result
+}
+
+// Delete removes an invite
+func (r *InviteRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.deleteOne(id, nil)
+}
+
+// DeleteInWorkspace removes an invite of a workspace
+func (r *InviteRepository) DeleteInWorkspace(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	return r.deleteOne(id, &workspaceID)
+}
+
+func (r *InviteRepository) deleteOne(id primitive.ObjectID, workspaceID *primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	i, ok := r.db.invites[id]
+	if !ok || (workspaceID != nil && i.WorkspaceID != *workspaceID) {
+		return repository.ErrNotFound
+	}
+	delete(r.db.invites, id)
+	return nil
+}
+
+// DeleteAllForWorkspace removes every invite of a workspace
+func (r *InviteRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, i := range r.db.invites {
+		if i.WorkspaceID == workspaceID {
+			delete(r.db.invites, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MentionRepository persists who is mentioned where. A source mentions a user at most once.
+type MentionRepository interface {
+	// Replace sets the users a source mentions and returns those it did not mention before
+	Replace(ctx context.Context, workspaceID, diagramID primitive.ObjectID, source models.MentionSource, sourceID primitive.ObjectID, userIDs []primitive.ObjectID, now time.Time) ([]primitive.ObjectID, error)
+	// ListForDiagram returns the mentions on a diagram, oldest first
+	ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Mention, error)
+	// DeleteForSources drops the mentions of the given sources
+	DeleteForSources(ctx context.Context, workspaceID primitive.ObjectID, source models.MentionSource, sourceIDs []primitive.ObjectID) error
+	DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
@@ -0,0 +1,109 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ActivityRepository stores activity feed entries in the activity collection
+type ActivityRepository struct{}
+
+// activitySortKeys maps activity sort fields to document keys
+var activitySortKeys = sortKeys{
+	models.SortByUpdated: "updated_at",
+}
+
+// Append inserts an activity entry
+func (r *ActivityRepository) Append(ctx context.Context, entry *models.ActivityEntry) error {
+	collection, err := collection("activity")
+	if err != nil {
+		return err
+	}
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, entry)
+	return mapError(err)
+}
+
+// Latest returns the most recently updated entry about a target
+func (r *ActivityRepository) Latest(ctx context.Context, workspaceID, targetID primitive.ObjectID) (*models.ActivityEntry, error) {
+	collection, err := collection("activity")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}})
+	var entry models.ActivityEntry
+	if err := collection.FindOne(ctx, bson.M{"workspace_id": workspaceID, "target_id": targetID}, opts).Decode(&entry); err != nil {
+		return nil, mapError(err)
+	}
+	return &entry, nil
+}
+
+// Extend adds one change to a session entry
+func (r *ActivityRepository) Extend(ctx context.Context, workspaceID, entryID primitive.ObjectID, updatedAt time.Time) error {
+	collection, err := collection("activity")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": entryID, "workspace_id": workspaceID},
+		bson.M{"$inc": bson.M{"count": 1}, "$set": bson.M{"updated_at": updatedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// List pages through a workspace's activity feed
+func (r *ActivityRepository) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.ActivityFilter, page *repository.PageQuery) ([]*models.ActivityEntry, error) {
+	collection, err := collection("activity")
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"workspace_id": workspaceID}
+	if filter != nil {
+		if filter.ActorID != nil {
+			query["actor_id"] = *filter.ActorID
+		}
+		if filter.TargetID != nil {
+			query["target_id"] = *filter.TargetID
+		}
+	}
+
+	opts, err := applyPage(query, activitySortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.ActivityEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteAllForWorkspace deletes a workspace's activity feed
+func (r *ActivityRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "activity", bson.M{"workspace_id": workspaceID})
+}
@@ -0,0 +1,71 @@
+package mongorepo
+
+import (
+	"context"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditRepository stores audit entries in the audit_log collection
+type AuditRepository struct{}
+
+// auditSortKeys maps audit sort fields to document keys
+var auditSortKeys = sortKeys{
+	models.SortByCreated: "created_at",
+}
+
+// Append inserts an audit entry
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	collection, err := collection("audit_log")
+	if err != nil {
+		return err
+	}
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, entry)
+	return mapError(err)
+}
+
+// List pages through a workspace's audit entries
+func (r *AuditRepository) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.AuditFilter, page *repository.PageQuery) ([]*models.AuditEntry, error) {
+	collection, err := collection("audit_log")
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"workspace_id": workspaceID}
+	if filter != nil {
+		if len(filter.Actions) > 0 {
+			query["action"] = bson.M{"$in": filter.Actions}
+		}
+		if filter.ActorID != nil {
+			query["actor_id"] = *filter.ActorID
+		}
+		if filter.TargetID != nil {
+			query["target_id"] = *filter.TargetID
+		}
+		applyDateRange(query, "created_at", filter.Created)
+	}
+
+	opts, err := applyPage(query, auditSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
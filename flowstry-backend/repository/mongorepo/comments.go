@@ -0,0 +1,177 @@
+package mongorepo
+
+import (
+	"context"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CommentRepository stores comments in the comments collection
+type CommentRepository struct{}
+
+// commentSortKeys maps thread sort fields to document keys
+var commentSortKeys = sortKeys{
+	models.SortByCreated: "created_at",
+	models.SortByUpdated: "updated_at",
+}
+
+// Create inserts a comment
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	collection, err := collection("comments")
+	if err != nil {
+		return err
+	}
+
+	if comment.ID.IsZero() {
+		comment.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, comment)
+	return mapError(err)
+}
+
+// Get retrieves a comment of a diagram
+func (r *CommentRepository) Get(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) (*models.Comment, error) {
+	collection, err := collection("comments")
+	if err != nil {
+		return nil, err
+	}
+
+	var comment models.Comment
+	err = collection.FindOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID, "diagram_id": diagramID}).Decode(&comment)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &comment, nil
+}
+
+// ListThreads pages through the thread roots of a diagram
+func (r *CommentRepository) ListThreads(ctx context.Context, workspaceID, diagramID primitive.ObjectID, filter *models.CommentFilter, page *repository.PageQuery) ([]*models.Comment, error) {
+	query := bson.M{"workspace_id": workspaceID, "diagram_id": diagramID, "thread_id": nil}
+	if filter != nil {
+		if filter.Resolved != nil {
+			if *filter.Resolved {
+				query["resolved_at"] = bson.M{"$ne": nil}
+			} else {
+				query["resolved_at"] = nil
+			}
+		}
+		if filter.ShapeID != "" {
+			query["anchor.shape_id"] = filter.ShapeID
+		}
+	}
+
+	opts, err := applyPage(query, commentSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, query, opts)
+}
+
+// ListReplies returns the replies to the given threads, oldest first
+func (r *CommentRepository) ListReplies(ctx context.Context, workspaceID, diagramID primitive.ObjectID, threadIDs []primitive.ObjectID) ([]*models.Comment, error) {
+	query := bson.M{"workspace_id": workspaceID, "diagram_id": diagramID, "thread_id": bson.M{"$in": threadIDs}}
+	return r.find(ctx, query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+}
+
+// ListShapeAnchored returns the thread roots of a diagram that are anchored to a shape
+func (r *CommentRepository) ListShapeAnchored(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Comment, error) {
+	query := bson.M{"workspace_id": workspaceID, "diagram_id": diagramID, "thread_id": nil, "anchor.shape_id": bson.M{"$exists": true}}
+	return r.find(ctx, query, options.Find())
+}
+
+func (r *CommentRepository) find(ctx context.Context, query bson.M, opts *options.FindOptions) ([]*models.Comment, error) {
+	collection, err := collection("comments")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var comments []*models.Comment
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Update changes a comment
+func (r *CommentRepository) Update(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, update *repository.CommentUpdate) error {
+	set := bson.M{"updated_at": update.UpdatedAt}
+	if update.Body != nil {
+		set["body"] = *update.Body
+	}
+	if update.EditedAt != nil {
+		set["edited_at"] = *update.EditedAt
+	}
+	change := bson.M{"$set": set}
+	if update.Resolved != nil {
+		if *update.Resolved {
+			set["resolved_at"] = update.UpdatedAt
+			set["resolved_by"] = update.ResolvedBy
+		} else {
+			change["$unset"] = bson.M{"resolved_at": "", "resolved_by": ""}
+		}
+	}
+	return r.updateOne(ctx, workspaceID, diagramID, id, change)
+}
+
+// SetAnchor moves a thread's anchor
+func (r *CommentRepository) SetAnchor(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, anchor models.CommentAnchor) error {
+	return r.updateOne(ctx, workspaceID, diagramID, id, bson.M{"$set": bson.M{"anchor": anchor}})
+}
+
+func (r *CommentRepository) updateOne(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, change bson.M) error {
+	collection, err := collection("comments")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID, "diagram_id": diagramID}, change)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a comment and, for a thread root, its replies
+func (r *CommentRepository) Delete(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) error {
+	collection, err := collection("comments")
+	if err != nil {
+		return err
+	}
+
+	// Replies point at their root, so matching thread_id as well removes a whole thread
+	result, err := collection.DeleteMany(ctx, bson.M{
+		"workspace_id": workspaceID,
+		"diagram_id":   diagramID,
+		"$or":          bson.A{bson.M{"_id": id}, bson.M{"thread_id": id}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteForDiagram removes every comment of a diagram
+func (r *CommentRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteWhere(ctx, "comments", bson.M{"workspace_id": workspaceID, "diagram_id": diagramID})
+}
+
+// DeleteAllForWorkspace removes every comment of a workspace
+func (r *CommentRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "comments", bson.M{"workspace_id": workspaceID})
+}
@@ -0,0 +1,727 @@
+package mongorepo
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DiagramRepository stores diagram metadata in the diagrams collection
+type DiagramRepository struct{}
+
+// diagramSortKeys maps diagram sort fields to document keys
+var diagramSortKeys = sortKeys{
+	models.SortByName:    "name",
+	models.SortByCreated: "created_at",
+	models.SortByUpdated: "updated_at",
+	models.SortBySize:    "file_size",
+	models.SortByDeleted: "deleted_at",
+}
+
+// diagramListProjection leaves the (potentially large) search index out of listings
+var diagramListProjection = bson.M{"search_content": 0}
+
+// Create inserts a diagram
+func (r *DiagramRepository) Create(ctx context.Context, diagram *models.Diagram) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	if diagram.ID.IsZero() {
+		diagram.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, diagram)
+	return mapError(err)
+}
+
+// Get retrieves a diagram of a workspace
+func (r *DiagramRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash repository.Trash) (*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": id, "workspace_id": workspaceID}
+	applyTrash(filter, trash)
+
+	var diagram models.Diagram
+	if err := collection.FindOne(ctx, filter).Decode(&diagram); err != nil {
+		return nil, mapError(err)
+	}
+	return &diagram, nil
+}
+
+// List pages through the diagrams of a workspace
+func (r *DiagramRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.DiagramQuery, page *repository.PageQuery) ([]*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workspace_id": workspaceID}
+	if query != nil {
+		applyTrash(filter, query.Trash)
+		if query.Trash == repository.Trashed {
+			filter["deleted_with_parent"] = bson.M{"$ne": true}
+		}
+		applyDiagramFilter(filter, query.Filter)
+	}
+
+	opts, err := applyPage(filter, diagramSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts.SetProjection(diagramListProjection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var diagrams []*models.Diagram
+	if err := cursor.All(ctx, &diagrams); err != nil {
+		return nil, err
+	}
+	return diagrams, nil
+}
+
+// applyDiagramFilter adds the optional listing filters to a diagrams query
+func applyDiagramFilter(query bson.M, filter *models.DiagramFilter) {
+	if filter == nil {
+		return
+	}
+	if filter.Query != "" {
+		query["name"] = termPattern(filter.Query)
+	}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	if filter.RootOnly {
+		query["folder_id"] = nil
+	} else if filter.FolderID != nil {
+		query["folder_id"] = *filter.FolderID
+	}
+	if filter.CreatedBy != nil {
+		query["created_by"] = *filter.CreatedBy
+	}
+	applyDateRange(query, "created_at", filter.Created)
+	applyDateRange(query, "updated_at", filter.Updated)
+}
+
+// ListByIDs returns the live diagrams among ids within the given workspaces
+func (r *DiagramRepository) ListByIDs(ctx context.Context, workspaceIDs, ids []primitive.ObjectID, filter *models.DiagramFilter) ([]*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{
+		"_id":          bson.M{"$in": ids},
+		"workspace_id": bson.M{"$in": workspaceIDs},
+		"deleted_at":   nil,
+	}
+	applyDiagramFilter(query, filter)
+
+	cursor, err := collection.Find(ctx, query, options.Find().SetProjection(diagramListProjection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var diagrams []*models.Diagram
+	if err := cursor.All(ctx, &diagrams); err != nil {
+		return nil, err
+	}
+	return diagrams, nil
+}
+
+// CountForWorkspace counts every diagram of a workspace, trashed ones included
+func (r *DiagramRepository) CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.M{"workspace_id": workspaceID})
+}
+
+// CountLive counts how many of ids are live diagrams of the workspace
+func (r *DiagramRepository) CountLive(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.M{
+		"_id":          bson.M{"$in": ids},
+		"workspace_id": workspaceID,
+		"deleted_at":   nil,
+	})
+}
+
+// CountByFolder counts live diagrams per folder in one aggregation
+func (r *DiagramRepository) CountByFolder(ctx context.Context, workspaceID primitive.ObjectID) (map[primitive.ObjectID]int64, int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"workspace_id": workspaceID, "deleted_at": nil}},
+		bson.M{"$group": bson.M{"_id": "$folder_id", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID    *primitive.ObjectID `bson:"_id"`
+		Count int64               `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[primitive.ObjectID]int64, len(rows))
+	var root int64
+	for _, row := range rows {
+		if row.ID == nil {
+			root += row.Count
+		} else {
+			counts[*row.ID] = row.Count
+		}
+	}
+	return counts, root, nil
+}
+
+// Update changes the given fields of a diagram
+func (r *DiagramRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.DiagramUpdate) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"updated_at": update.UpdatedAt}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Thumbnail != nil {
+		set["thumbnail"] = *update.Thumbnail
+	}
+	if update.Tags != nil {
+		set["tags"] = *update.Tags
+	}
+	if update.FileURL != nil {
+		set["file_url"] = *update.FileURL
+	}
+	if update.FileSize != nil {
+		set["file_size"] = *update.FileSize
+	}
+	if update.Version != nil {
+		set["version"] = *update.Version
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "workspace_id": workspaceID},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// SetFolder moves live diagrams into a folder in one update
+func (r *DiagramRepository) SetFolder(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, folderID *primitive.ObjectID, at time.Time) (int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "workspace_id": workspaceID, "deleted_at": nil},
+		bson.M{"$set": bson.M{"folder_id": folderID, "updated_at": at}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// RemoveFromFolder moves a live diagram out of a folder to the workspace root
+func (r *DiagramRepository) RemoveFromFolder(ctx context.Context, workspaceID, id, folderID primitive.ObjectID, at time.Time) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "workspace_id": workspaceID, "folder_id": folderID, "deleted_at": nil},
+		bson.M{"$set": bson.M{"folder_id": nil, "updated_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// InFolders returns the diagrams filed in any of folderIDs
+func (r *DiagramRepository) InFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workspace_id": workspaceID, "folder_id": bson.M{"$in": folderIDs}, "deleted_at": nil}
+	if batchID != nil {
+		delete(filter, "deleted_at")
+		filter["$or"] = bson.A{
+			bson.M{"deleted_at": nil},
+			bson.M{"trash_batch_id": *batchID},
+		}
+	}
+	return distinctIDs(ctx, collection, "_id", filter)
+}
+
+// Unfile moves every diagram in one of folderIDs to the workspace root
+func (r *DiagramRepository) Unfile(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"workspace_id": workspaceID, "folder_id": bson.M{"$in": folderIDs}},
+		bson.M{"$set": bson.M{"folder_id": nil}},
+	)
+	return err
+}
+
+// DetachFromMissingFolders moves live diagrams whose folder is not live to the workspace root
+func (r *DiagramRepository) DetachFromMissingFolders(ctx context.Context, workspaceID primitive.ObjectID) error {
+	diagrams, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+	folders, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	live, err := folders.Distinct(ctx, "_id", bson.M{"workspace_id": workspaceID, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+
+	_, err = diagrams.UpdateMany(ctx, bson.M{
+		"workspace_id": workspaceID,
+		"deleted_at":   nil,
+		"folder_id":    bson.M{"$nin": append(live, nil)},
+	}, bson.M{"$set": bson.M{"folder_id": nil}})
+	return err
+}
+
+// Trash moves live diagrams to trash
+func (r *DiagramRepository) Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "workspace_id": workspaceID, "deleted_at": nil},
+		trashUpdate(batchID, at, withParent),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// TrashInFolders trashes the live diagrams of folderIDs along with them
+func (r *DiagramRepository) TrashInFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID primitive.ObjectID, at time.Time) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"workspace_id": workspaceID, "folder_id": bson.M{"$in": folderIDs}, "deleted_at": nil},
+		trashUpdate(batchID, at, true),
+	)
+	return err
+}
+
+// Restore brings a trashed diagram back
+func (r *DiagramRepository) Restore(ctx context.Context, workspaceID, id primitive.ObjectID, toRoot bool) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"deleted_at": nil}
+	if toRoot {
+		set["folder_id"] = nil
+	}
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "workspace_id": workspaceID, "deleted_at": bson.M{"$ne": nil}},
+		bson.M{"$set": set, "$unset": bson.M{"trash_batch_id": "", "deleted_with_parent": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// RestoreBatch brings back every diagram trashed in a batch
+func (r *DiagramRepository) RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{"workspace_id": workspaceID, "trash_batch_id": batchID}, restoreUpdate)
+	return err
+}
+
+// TrashRoots returns the diagram trash roots deleted before cutoff
+func (r *DiagramRepository) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+	return distinctIDs(ctx, collection, "_id", trashRootsQuery(workspaceID, cutoff))
+}
+
+// WorkspacesWithTrash returns the workspaces that have diagrams in trash
+func (r *DiagramRepository) WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+	return distinctIDs(ctx, collection, "workspace_id", bson.M{"deleted_at": bson.M{"$ne": nil}})
+}
+
+// CountTags counts live diagrams per tag in one aggregation
+func (r *DiagramRepository) CountTags(ctx context.Context, workspaceID primitive.ObjectID) (map[string]int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"workspace_id": workspaceID, "deleted_at": nil, "tags.0": bson.M{"$exists": true}}},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Tag   string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Tag] = row.Count
+	}
+	return counts, nil
+}
+
+// RenameTag replaces a tag on every diagram of the workspace carrying it
+func (r *DiagramRepository) RenameTag(ctx context.Context, workspaceID primitive.ObjectID, oldName, newName string) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	// $addToSet and $pull cannot target the same field in one update
+	filter := bson.M{"workspace_id": workspaceID, "tags": oldName}
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$addToSet": bson.M{"tags": newName}}); err != nil {
+		return err
+	}
+	_, err = collection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"tags": oldName}})
+	return err
+}
+
+// RemoveTag removes a tag from every diagram of the workspace
+func (r *DiagramRepository) RemoveTag(ctx context.Context, workspaceID primitive.ObjectID, name string) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"workspace_id": workspaceID, "tags": name},
+		bson.M{"$pull": bson.M{"tags": name}},
+	)
+	return err
+}
+
+// AddTags adds tags to live diagrams that stay within max tags
+func (r *DiagramRepository) AddTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, max int, at time.Time) (int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.UpdateMany(ctx, bson.M{
+		"_id":          bson.M{"$in": ids},
+		"workspace_id": workspaceID,
+		"deleted_at":   nil,
+		"$expr": bson.M{"$lte": bson.A{
+			bson.M{"$size": bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, tags}}},
+			max,
+		}},
+	}, bson.M{
+		"$addToSet": bson.M{"tags": bson.M{"$each": tags}},
+		"$set":      bson.M{"updated_at": at},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RemoveTags removes tags from live diagrams
+func (r *DiagramRepository) RemoveTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, at time.Time) (int64, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "workspace_id": workspaceID, "deleted_at": nil},
+		bson.M{
+			"$pull": bson.M{"tags": bson.M{"$in": tags}},
+			"$set":  bson.M{"updated_at": at},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// SetTemplateScope sets or, for an empty scope, unsets the template scope of a diagram
+func (r *DiagramRepository) SetTemplateScope(ctx context.Context, workspaceID, id primitive.ObjectID, scope models.TemplateScope) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"template_scope": scope}}
+	if scope == "" {
+		update = bson.M{"$unset": bson.M{"template_scope": ""}}
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ListTemplates returns the live templates offered to a workspace
+func (r *DiagramRepository) ListTemplates(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "name", Value: 1}}).
+		SetProjection(diagramListProjection)
+	cursor, err := collection.Find(ctx, bson.M{
+		"deleted_at": nil,
+		"$or": bson.A{
+			bson.M{"template_scope": models.TemplateScopeInstance},
+			bson.M{"template_scope": models.TemplateScopeWorkspace, "workspace_id": workspaceID},
+		},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var templates []*models.Diagram
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTemplate retrieves a live template by ID in any workspace
+func (r *DiagramRepository) GetTemplate(ctx context.Context, id primitive.ObjectID) (*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	var template models.Diagram
+	err = collection.FindOne(ctx,
+		bson.M{"_id": id, "deleted_at": nil, "template_scope": bson.M{"$in": bson.A{models.TemplateScopeWorkspace, models.TemplateScopeInstance}}},
+		options.FindOne().SetProjection(diagramListProjection),
+	).Decode(&template)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &template, nil
+}
+
+// Search finds live diagrams matching every term with case-insensitive regexes
+func (r *DiagramRepository) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, includeContent bool, limit int64) ([]*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	// Every term must match at least one searchable field
+	clauses := bson.A{}
+	for _, term := range terms {
+		pattern := termPattern(term)
+		fields := bson.A{
+			bson.M{"name": pattern},
+			bson.M{"description": pattern},
+		}
+		if includeContent {
+			fields = append(fields, bson.M{"search_content": pattern})
+		}
+		clauses = append(clauses, bson.M{"$or": fields})
+	}
+	query := bson.M{"workspace_id": bson.M{"$in": workspaceIDs}, "deleted_at": nil}
+	if len(clauses) > 0 {
+		query["$and"] = clauses
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetLimit(limit)
+	if !includeContent {
+		opts.SetProjection(diagramListProjection)
+	}
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var diagrams []*models.Diagram
+	if err := cursor.All(ctx, &diagrams); err != nil {
+		return nil, err
+	}
+	return diagrams, nil
+}
+
+// termPattern matches a search term anywhere in a field, case-insensitively
+func termPattern(term string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(term), Options: "i"}
+}
+
+// SetSearchContent stores the search text of a diagram
+func (r *DiagramRepository) SetSearchContent(ctx context.Context, workspaceID, id primitive.ObjectID, text string) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": id, "workspace_id": workspaceID},
+		bson.M{"$set": bson.M{"search_content": text}},
+	)
+	return err
+}
+
+// ClearSearchContent unsets the search text of every diagram of a workspace
+func (r *DiagramRepository) ClearSearchContent(ctx context.Context, workspaceID primitive.ObjectID) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"workspace_id": workspaceID, "search_content": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"search_content": ""}},
+	)
+	return err
+}
+
+// ListAll returns every diagram of a workspace in the given trash state
+func (r *DiagramRepository) ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash repository.Trash) ([]*models.Diagram, error) {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workspace_id": workspaceID}
+	applyTrash(filter, trash)
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(diagramListProjection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var diagrams []*models.Diagram
+	if err := cursor.All(ctx, &diagrams); err != nil {
+		return nil, err
+	}
+	return diagrams, nil
+}
+
+// Delete permanently removes a diagram
+func (r *DiagramRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAllForWorkspace permanently removes every diagram of a workspace
+func (r *DiagramRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	collection, err := collection("diagrams")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
+	return err
+}
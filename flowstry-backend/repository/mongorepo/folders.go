@@ -0,0 +1,464 @@
+package mongorepo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FolderRepository stores folders in the folders collection
+type FolderRepository struct{}
+
+// folderSortKeys maps folder sort fields to document keys
+var folderSortKeys = sortKeys{
+	models.SortByName:    "name",
+	models.SortByCreated: "created_at",
+	models.SortByUpdated: "updated_at",
+	models.SortByDeleted: "deleted_at",
+}
+
+// Create inserts a folder
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	if folder.ID.IsZero() {
+		folder.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, folder)
+	return mapError(err)
+}
+
+// Get retrieves a folder of a workspace
+func (r *FolderRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash repository.Trash) (*models.Folder, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": id, "workspace_id": workspaceID}
+	applyTrash(filter, trash)
+
+	var folder models.Folder
+	if err := collection.FindOne(ctx, filter).Decode(&folder); err != nil {
+		return nil, mapError(err)
+	}
+	return &folder, nil
+}
+
+// List pages through the folders of a workspace
+func (r *FolderRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.FolderQuery, page *repository.PageQuery) ([]*models.Folder, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workspace_id": workspaceID}
+	if query != nil {
+		applyTrash(filter, query.Trash)
+		if query.Trash == repository.Trashed {
+			filter["deleted_with_parent"] = bson.M{"$ne": true}
+		}
+		applyFolderFilter(filter, query.Filter)
+	}
+
+	opts, err := applyPage(filter, folderSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// applyFolderFilter adds the optional listing filters to a folders query
+func applyFolderFilter(query bson.M, filter *models.FolderFilter) {
+	if filter == nil {
+		return
+	}
+	if filter.Query != "" {
+		query["name"] = termPattern(filter.Query)
+	}
+	if filter.RootOnly {
+		query["parent_folder_id"] = nil
+	} else if filter.ParentID != nil {
+		query["parent_folder_id"] = *filter.ParentID
+	}
+	if filter.CreatedBy != nil {
+		query["created_by"] = *filter.CreatedBy
+	}
+	applyDateRange(query, "created_at", filter.Created)
+	applyDateRange(query, "updated_at", filter.Updated)
+}
+
+// ListAll retrieves every folder of a workspace in a trash state
+func (r *FolderRepository) ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash repository.Trash) ([]*models.Folder, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workspace_id": workspaceID}
+	applyTrash(filter, trash)
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// CountForWorkspace counts every folder of a workspace, trashed ones included
+func (r *FolderRepository) CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.M{"workspace_id": workspaceID})
+}
+
+// Update changes the given fields of a live folder
+func (r *FolderRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.FolderUpdate) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"updated_at": update.UpdatedAt}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Color != nil {
+		set["color"] = *update.Color
+	}
+	if update.SetParent {
+		set["parent_folder_id"] = update.ParentFolderID
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "workspace_id": workspaceID, "deleted_at": nil},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Ancestors walks up parent_folder_id with $graphLookup
+func (r *FolderRepository) Ancestors(ctx context.Context, workspaceID, id primitive.ObjectID) ([]*models.Folder, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": id, "workspace_id": workspaceID}}},
+		{{Key: "$graphLookup", Value: bson.M{
+			"from":                    "folders",
+			"startWith":               "$parent_folder_id",
+			"connectFromField":        "parent_folder_id",
+			"connectToField":          "_id",
+			"as":                      "ancestors",
+			"depthField":              "depth",
+			"restrictSearchWithMatch": bson.M{"workspace_id": workspaceID},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Ancestors []struct {
+			models.Folder `bson:",inline"`
+			Depth         int64 `bson:"depth"`
+		} `bson:"ancestors"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	found := results[0].Ancestors
+	sort.Slice(found, func(i, j int) bool { return found[i].Depth > found[j].Depth })
+
+	ancestors := make([]*models.Folder, 0, len(found))
+	for i := range found {
+		// A pre-existing cycle can lead back to the folder itself
+		if found[i].ID != id {
+			ancestors = append(ancestors, &found[i].Folder)
+		}
+	}
+	return ancestors, nil
+}
+
+// Descendants walks down parent_folder_id with $graphLookup
+func (r *FolderRepository) Descendants(ctx context.Context, workspaceID, id primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+
+	restrict := bson.M{"workspace_id": workspaceID, "deleted_at": nil}
+	if batchID != nil {
+		restrict = bson.M{"workspace_id": workspaceID, "$or": bson.A{
+			bson.M{"deleted_at": nil},
+			bson.M{"trash_batch_id": *batchID},
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": id, "workspace_id": workspaceID}}},
+		{{Key: "$graphLookup", Value: bson.M{
+			"from":                    "folders",
+			"startWith":               "$_id",
+			"connectFromField":        "_id",
+			"connectToField":          "parent_folder_id",
+			"as":                      "descendants",
+			"restrictSearchWithMatch": restrict,
+		}}},
+		{{Key: "$project", Value: bson.M{"descendants._id": 1}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Descendants []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		} `bson:"descendants"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	if len(results) > 0 {
+		for _, d := range results[0].Descendants {
+			// A pre-existing cycle can lead back to the folder itself
+			if d.ID != id {
+				ids = append(ids, d.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Trash moves live folders to trash
+func (r *FolderRepository) Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "workspace_id": workspaceID, "deleted_at": nil},
+		trashUpdate(batchID, at, withParent),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// trashUpdate is the update moving a document to trash
+func trashUpdate(batchID primitive.ObjectID, at time.Time, withParent bool) bson.M {
+	if withParent {
+		return bson.M{"$set": bson.M{
+			"deleted_at":          at,
+			"trash_batch_id":      batchID,
+			"deleted_with_parent": true,
+		}}
+	}
+	return bson.M{
+		"$set":   bson.M{"deleted_at": at, "trash_batch_id": batchID},
+		"$unset": bson.M{"deleted_with_parent": ""},
+	}
+}
+
+// restoreUpdate is the update bringing a document back from trash
+var restoreUpdate = bson.M{
+	"$set":   bson.M{"deleted_at": nil},
+	"$unset": bson.M{"trash_batch_id": "", "deleted_with_parent": ""},
+}
+
+// Restore brings trashed folders back
+func (r *FolderRepository) Restore(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "workspace_id": workspaceID}, restoreUpdate)
+	return err
+}
+
+// RestoreBatch brings back every folder trashed in a batch
+func (r *FolderRepository) RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{"workspace_id": workspaceID, "trash_batch_id": batchID}, restoreUpdate)
+	return err
+}
+
+// DetachFromMissingParents moves live folders whose parent is not live to the workspace root
+func (r *FolderRepository) DetachFromMissingParents(ctx context.Context, workspaceID primitive.ObjectID) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	live, err := collection.Distinct(ctx, "_id", bson.M{"workspace_id": workspaceID, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{
+		"workspace_id":     workspaceID,
+		"deleted_at":       nil,
+		"parent_folder_id": bson.M{"$nin": append(live, nil)},
+	}, bson.M{"$set": bson.M{"parent_folder_id": nil}})
+	return err
+}
+
+// Unparent moves the folders under parentIDs to the workspace root
+func (r *FolderRepository) Unparent(ctx context.Context, workspaceID primitive.ObjectID, parentIDs []primitive.ObjectID) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"workspace_id": workspaceID, "parent_folder_id": bson.M{"$in": parentIDs}},
+		bson.M{"$set": bson.M{"parent_folder_id": nil}},
+	)
+	return err
+}
+
+// TrashRoots returns the folder trash roots deleted before cutoff
+func (r *FolderRepository) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+	return distinctIDs(ctx, collection, "_id", trashRootsQuery(workspaceID, cutoff))
+}
+
+// WorkspacesWithTrash returns the workspaces that have folders in trash
+func (r *FolderRepository) WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+	return distinctIDs(ctx, collection, "workspace_id", bson.M{"deleted_at": bson.M{"$ne": nil}})
+}
+
+// Search finds live folders whose name matches every term
+func (r *FolderRepository) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, limit int64) ([]*models.Folder, error) {
+	collection, err := collection("folders")
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"workspace_id": bson.M{"$in": workspaceIDs}, "deleted_at": nil}
+	for _, term := range terms {
+		addCondition(query, bson.M{"name": termPattern(term)})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetLimit(limit)
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// distinctIDs returns the distinct ObjectID values of a field
+func distinctIDs(ctx context.Context, collection *mongo.Collection, field string, filter bson.M) ([]primitive.ObjectID, error) {
+	raw, err := collection.Distinct(ctx, field, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(raw))
+	for _, v := range raw {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// DeleteMany permanently removes folders
+func (r *FolderRepository) DeleteMany(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "workspace_id": workspaceID})
+	return err
+}
+
+// DeleteAllForWorkspace permanently removes every folder of a workspace
+func (r *FolderRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	collection, err := collection("folders")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
+	return err
+}
@@ -0,0 +1,81 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MentionRepository stores mentions in the mentions collection
+type MentionRepository struct{}
+
+// Replace sets the users a source mentions and returns those it did not mention before
+func (r *MentionRepository) Replace(ctx context.Context, workspaceID, diagramID primitive.ObjectID, source models.MentionSource, sourceID primitive.ObjectID, userIDs []primitive.ObjectID, now time.Time) ([]primitive.ObjectID, error) {
+	collection, err := collection("mentions")
+	if err != nil {
+		return nil, err
+	}
+
+	kept := append([]primitive.ObjectID{}, userIDs...)
+	_, err = collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID, "source": source, "source_id": sourceID,
+		"user_id": bson.M{"$nin": kept}})
+	if err != nil {
+		return nil, err
+	}
+
+	var added []primitive.ObjectID
+	for _, userID := range userIDs {
+		filter := bson.M{"workspace_id": workspaceID, "source": source, "source_id": sourceID, "user_id": userID}
+		result, err := collection.UpdateOne(ctx, filter, bson.M{"$setOnInsert": bson.M{
+			"diagram_id": diagramID,
+			"created_at": now,
+		}}, options.Update().SetUpsert(true))
+		if err != nil {
+			return nil, mapError(err)
+		}
+		if result.UpsertedCount > 0 {
+			added = append(added, userID)
+		}
+	}
+	return added, nil
+}
+
+// ListForDiagram returns the mentions on a diagram, oldest first
+func (r *MentionRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Mention, error) {
+	collection, err := collection("mentions")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"workspace_id": workspaceID, "diagram_id": diagramID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mentions []*models.Mention
+	if err := cursor.All(ctx, &mentions); err != nil {
+		return nil, err
+	}
+	return mentions, nil
+}
+
+// DeleteForSources drops the mentions of the given sources
+func (r *MentionRepository) DeleteForSources(ctx context.Context, workspaceID primitive.ObjectID, source models.MentionSource, sourceIDs []primitive.ObjectID) error {
+	return deleteWhere(ctx, "mentions", bson.M{"workspace_id": workspaceID, "source": source, "source_id": bson.M{"$in": sourceIDs}})
+}
+
+// DeleteForDiagram removes every mention on a diagram
+func (r *MentionRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteWhere(ctx, "mentions", bson.M{"workspace_id": workspaceID, "diagram_id": diagramID})
+}
+
+// DeleteAllForWorkspace removes every mention of a workspace
+func (r *MentionRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "mentions", bson.M{"workspace_id": workspaceID})
+}
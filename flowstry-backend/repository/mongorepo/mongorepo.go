@@ -0,0 +1,159 @@
+// Package mongorepo implements the repositories on MongoDB
+package mongorepo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/database"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// New returns the MongoDB-backed repositories. Collections are resolved on each call,
+// so the store can be created before the database is connected.
+func New() *repository.Store {
+	return &repository.Store{
+		Users:         &UserRepository{},
+		RefreshTokens: &RefreshTokenRepository{},
+		AccessTokens:  &PersonalAccessTokenRepository{},
+		Workspaces:    &WorkspaceRepository{},
+		Members:       &MemberRepository{},
+		Invites:       &InviteRepository{},
+		Folders:       &FolderRepository{},
+		Diagrams:      &DiagramRepository{},
+		Tags:          &TagRepository{},
+		ShareLinks:    &ShareLinkRepository{},
+		EmbedTokens:   &EmbedTokenRepository{},
+		Access:        &AccessRepository{},
+		Stars:         &StarRepository{},
+		Audit:         &AuditRepository{},
+		Activity:      &ActivityRepository{},
+		Comments:      &CommentRepository{},
+		Mentions:      &MentionRepository{},
+		Notifications: &NotificationRepository{},
+		Outbox:        &OutboxRepository{},
+		Webhooks:      &WebhookRepository{},
+		Deliveries:    &WebhookDeliveryRepository{},
+		Connected:     database.IsConnected,
+	}
+}
+
+var errUnsupportedSort = errors.New("unsupported sort field")
+
+// collection returns a collection, or an error when the database is not connected
+func collection(name string) (*mongo.Collection, error) {
+	c := database.GetCollection(name)
+	if c == nil {
+		return nil, errors.New("database not connected")
+	}
+	return c, nil
+}
+
+// mapError converts driver errors to repository errors
+func mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return repository.ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return repository.ErrDuplicate
+	default:
+		return err
+	}
+}
+
+// nameCollation makes name ordering case-insensitive (applies to the keyset comparison too)
+var nameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// sortKeys maps the sort fields a collection supports to document keys
+type sortKeys map[models.SortField]string
+
+// keysetCondition returns the condition continuing after page.After, or nil on the first page
+func keysetCondition(key string, page *repository.PageQuery) bson.M {
+	if page.After == nil {
+		return nil
+	}
+	op := "$lt"
+	if page.Order == models.SortAsc {
+		op = "$gt"
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{key: bson.M{op: page.After.Value}},
+		bson.M{key: page.After.Value, "_id": bson.M{op: page.After.ID}},
+	}}
+}
+
+// sortDoc returns the sort document, tie-broken by _id so the order is total
+func sortDoc(key string, order models.SortOrder) bson.D {
+	dir := -1
+	if order == models.SortAsc {
+		dir = 1
+	}
+	return bson.D{{Key: key, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// applyPage adds the keyset condition to a query and returns the matching find options
+func applyPage(query bson.M, keys sortKeys, page *repository.PageQuery) (*options.FindOptions, error) {
+	key, ok := keys[page.Sort]
+	if !ok {
+		return nil, errUnsupportedSort
+	}
+	if cond := keysetCondition(key, page); cond != nil {
+		addCondition(query, cond)
+	}
+
+	opts := options.Find().SetSort(sortDoc(key, page.Order)).SetLimit(page.Limit)
+	if page.Sort == models.SortByName {
+		opts.SetCollation(nameCollation)
+	}
+	return opts, nil
+}
+
+// addCondition ANDs an extra condition onto a query
+func addCondition(query bson.M, cond bson.M) {
+	and, _ := query["$and"].(bson.A)
+	query["$and"] = append(and, cond)
+}
+
+// applyDateRange restricts a timestamp field to the given range
+func applyDateRange(query bson.M, key string, r models.DateRange) {
+	if r.IsZero() {
+		return
+	}
+	cond := bson.M{}
+	if r.After != nil {
+		cond["$gte"] = *r.After
+	}
+	if r.Before != nil {
+		cond["$lt"] = *r.Before
+	}
+	addCondition(query, bson.M{key: cond})
+}
+
+// applyTrash restricts a query to live or trashed documents
+func applyTrash(query bson.M, trash repository.Trash) {
+	switch trash {
+	case repository.Live:
+		query["deleted_at"] = nil
+	case repository.Trashed:
+		query["deleted_at"] = bson.M{"$ne": nil}
+	}
+}
+
+// trashRootsQuery matches the trash roots of a workspace deleted before cutoff (any time when nil)
+func trashRootsQuery(workspaceID interface{}, cutoff *time.Time) bson.M {
+	deletedAt := bson.M{"$ne": nil}
+	if cutoff != nil {
+		deletedAt = bson.M{"$ne": nil, "$lt": *cutoff}
+	}
+	return bson.M{
+		"workspace_id":        workspaceID,
+		"deleted_at":          deletedAt,
+		"deleted_with_parent": bson.M{"$ne": true},
+	}
+}
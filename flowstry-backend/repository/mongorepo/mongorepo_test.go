@@ -0,0 +1,49 @@
+package mongorepo
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/database"
+	"github.com/flowstry/flowstry-backend/database/migrations"
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/repository/repotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testURIEnv names the MongoDB server the integration tests run against, e.g.
+// mongodb://localhost:27017
+const testURIEnv = "FLOWSTRY_TEST_MONGODB_URI"
+
+func TestMongo(t *testing.T) {
+	uri := os.Getenv(testURIEnv)
+	if uri == "" {
+		t.Skipf("%s not set", testURIEnv)
+	}
+	repotest.Run(t, func(t *testing.T) *repository.Store {
+		openTestDatabase(t, uri)
+		return New()
+	})
+}
+
+// openTestDatabase connects the shared database to a fresh, migrated database that is
+// dropped when the test ends. The repositories resolve collections through the shared
+// connection, so the subtests using it must not run in parallel.
+func openTestDatabase(t *testing.T, uri string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := database.Connect(uri, "flowstry_test_"+primitive.NewObjectID().Hex()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db := database.GetDatabase()
+	t.Cleanup(func() {
+		_ = db.Drop(ctx)
+		database.Disconnect()
+	})
+
+	if _, err := migrations.NewRunner(db).Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationRepository stores notifications in the notifications collection
+type NotificationRepository struct{}
+
+// notificationSortKeys maps notification sort fields to document keys
+var notificationSortKeys = sortKeys{
+	models.SortByUpdated: "updated_at",
+}
+
+// Create inserts a notification
+func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	collection, err := collection("notifications")
+	if err != nil {
+		return err
+	}
+
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, notification)
+	return mapError(err)
+}
+
+// FindUnread returns a user's unread notification of a type about a target
+func (r *NotificationRepository) FindUnread(ctx context.Context, userID primitive.ObjectID, notificationType models.NotificationType, targetID primitive.ObjectID) (*models.Notification, error) {
+	collection, err := collection("notifications")
+	if err != nil {
+		return nil, err
+	}
+
+	var notification models.Notification
+	err = collection.FindOne(ctx, bson.M{
+		"user_id":   userID,
+		"type":      notificationType,
+		"target_id": targetID,
+		"read_at":   bson.M{"$exists": false},
+	}).Decode(&notification)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &notification, nil
+}
+
+// Bump folds another change into an unread notification
+func (r *NotificationRepository) Bump(ctx context.Context, userID, id primitive.ObjectID, actorID *primitive.ObjectID, actorName, message string, updatedAt time.Time) error {
+	collection, err := collection("notifications")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "user_id": userID, "read_at": bson.M{"$exists": false}},
+		bson.M{
+			"$inc":   bson.M{"count": 1},
+			"$set":   bson.M{"actor_id": actorID, "actor_name": actorName, "message": message, "updated_at": updatedAt},
+			"$unset": bson.M{"digested_at": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// List pages through a user's notifications
+func (r *NotificationRepository) List(ctx context.Context, userID primitive.ObjectID, filter *models.NotificationFilter, page *repository.PageQuery) ([]*models.Notification, error) {
+	collection, err := collection("notifications")
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"user_id": userID}
+	if filter != nil && filter.UnreadOnly {
+		query["read_at"] = bson.M{"$exists": false}
+	}
+
+	opts, err := applyPage(query, notificationSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []*models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	collection, err := collection("notifications")
+	if err != nil {
+		return 0, err
+	}
+
+	return collection.CountDocuments(ctx, bson.M{"user_id": userID, "read_at": bson.M{"$exists": false}})
+}
+
+// MarkRead marks some of a user's notifications read
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, readAt time.Time) (int64, error) {
+	return r.markRead(ctx, bson.M{"user_id": userID, "_id": bson.M{"$in": ids}}, readAt)
+}
+
+// MarkAllRead marks every notification of a user read
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID primitive.ObjectID, readAt time.Time) (int64, error) {
+	return r.markRead(ctx, bson.M{"user_id": userID}, readAt)
+}
+
+func (r *NotificationRepository) markRead(ctx context.Context, query bson.M, readAt time.Time) (int64, error) {
+	collection, err := collection("notifications")
+	if err != nil {
+		return 0, err
+	}
+
+	query["read_at"] = bson.M{"$exists": false}
+	result, err := collection.UpdateMany(ctx, query, bson.M{"$set": bson.M{"read_at": readAt}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteAllForWorkspace deletes the notifications about a workspace
+func (r *NotificationRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "notifications", bson.M{"workspace_id": workspaceID})
+}
+
+// PendingDigest returns unread notifications not yet in a digest, by user and then update time
+func (r *NotificationRepository) PendingDigest(ctx context.Context, userID *primitive.ObjectID, updatedBefore time.Time, limit int64) ([]*models.Notification, error) {
+	collection, err := collection("notifications")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	filter := bson.M{
+		"read_at":     bson.M{"$exists": false},
+		"digested_at": bson.M{"$exists": false},
+		"updated_at":  bson.M{"$lt": updatedBefore},
+	}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []*models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkDigested records that notifications were emailed in a digest
+func (r *NotificationRepository) MarkDigested(ctx context.Context, ids []primitive.ObjectID, digestedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	collection, err := collection("notifications")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"digested_at": digestedAt}})
+	return err
+}
@@ -0,0 +1,118 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OutboxRepository stores queued emails in the email_outbox collection
+type OutboxRepository struct{}
+
+// Enqueue inserts an email
+func (r *OutboxRepository) Enqueue(ctx context.Context, email *repository.OutboxEmail) error {
+	collection, err := collection("email_outbox")
+	if err != nil {
+		return err
+	}
+
+	if email.ID.IsZero() {
+		email.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, email)
+	return mapError(err)
+}
+
+// Due returns pending emails whose next attempt is due
+func (r *OutboxRepository) Due(ctx context.Context, now time.Time, limit int64) ([]*repository.OutboxEmail, error) {
+	collection, err := collection("email_outbox")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	cursor, err := collection.Find(ctx, bson.M{
+		"status":          repository.OutboxPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var emails []*repository.OutboxEmail
+	if err := cursor.All(ctx, &emails); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// Claim leases a due email
+func (r *OutboxRepository) Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error {
+	return r.update(ctx,
+		bson.M{"_id": id, "status": repository.OutboxPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"next_attempt_at": leaseUntil})
+}
+
+// MarkSent records the delivery of an email
+func (r *OutboxRepository) MarkSent(ctx context.Context, id primitive.ObjectID, attempts int, sentAt time.Time) error {
+	return r.update(ctx, bson.M{"_id": id}, bson.M{
+		"status":   repository.OutboxSent,
+		"attempts": attempts,
+		"sent_at":  sentAt,
+	})
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *OutboxRepository) MarkRetry(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.update(ctx, bson.M{"_id": id}, bson.M{
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	})
+}
+
+// MarkFailed gives up on an email
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, attempts int, lastError string) error {
+	return r.update(ctx, bson.M{"_id": id}, bson.M{
+		"status":     repository.OutboxFailed,
+		"attempts":   attempts,
+		"last_error": lastError,
+	})
+}
+
+func (r *OutboxRepository) update(ctx context.Context, filter, set bson.M) error {
+	collection, err := collection("email_outbox")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSentBefore prunes delivered emails
+func (r *OutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	collection, err := collection("email_outbox")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteMany(ctx, bson.M{"status": repository.OutboxSent, "sent_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,359 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShareLinkRepository stores share links in the share_links collection
+type ShareLinkRepository struct{}
+
+// Create inserts a share link
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	collection, err := collection("share_links")
+	if err != nil {
+		return err
+	}
+
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, link)
+	return mapError(err)
+}
+
+// GetByTokenHash retrieves a share link by its token hash
+func (r *ShareLinkRepository) GetByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error) {
+	collection, err := collection("share_links")
+	if err != nil {
+		return nil, err
+	}
+
+	var link models.ShareLink
+	if err := collection.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&link); err != nil {
+		return nil, mapError(err)
+	}
+	return &link, nil
+}
+
+// ListForDiagram returns the share links of a diagram, newest first
+func (r *ShareLinkRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.ShareLink, error) {
+	collection, err := collection("share_links")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"workspace_id": workspaceID, "diagram_id": diagramID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []*models.ShareLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Revoke revokes an active share link of a diagram
+func (r *ShareLinkRepository) Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	return revoke(ctx, "share_links", workspaceID, diagramID, id, at)
+}
+
+// RecordView counts a view of a share link
+func (r *ShareLinkRepository) RecordView(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	collection, err := collection("share_links")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"last_viewed_at": at},
+		"$inc": bson.M{"view_count": 1},
+	})
+	return err
+}
+
+// DeleteForDiagram removes every share link of a diagram
+func (r *ShareLinkRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteWhere(ctx, "share_links", bson.M{"workspace_id": workspaceID, "diagram_id": diagramID})
+}
+
+// DeleteAllForWorkspace removes every share link of a workspace
+func (r *ShareLinkRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "share_links", bson.M{"workspace_id": workspaceID})
+}
+
+// EmbedTokenRepository stores embed tokens in the embed_tokens collection
+type EmbedTokenRepository struct{}
+
+// Create inserts an embed token
+func (r *EmbedTokenRepository) Create(ctx context.Context, token *models.EmbedToken) error {
+	collection, err := collection("embed_tokens")
+	if err != nil {
+		return err
+	}
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, token)
+	return mapError(err)
+}
+
+// GetByTokenHash retrieves an embed token by its hash
+func (r *EmbedTokenRepository) GetByTokenHash(ctx context.Context, hash string) (*models.EmbedToken, error) {
+	collection, err := collection("embed_tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.EmbedToken
+	if err := collection.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&token); err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+// ListForDiagram returns the embed tokens of a diagram, newest first
+func (r *EmbedTokenRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.EmbedToken, error) {
+	collection, err := collection("embed_tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"workspace_id": workspaceID, "diagram_id": diagramID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.EmbedToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke revokes an active embed token of a diagram
+func (r *EmbedTokenRepository) Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	return revoke(ctx, "embed_tokens", workspaceID, diagramID, id, at)
+}
+
+// RecordUse marks an embed token as used
+func (r *EmbedTokenRepository) RecordUse(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	collection, err := collection("embed_tokens")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": at}})
+	return err
+}
+
+// DeleteForDiagram removes every embed token of a diagram
+func (r *EmbedTokenRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteWhere(ctx, "embed_tokens", bson.M{"workspace_id": workspaceID, "diagram_id": diagramID})
+}
+
+// DeleteAllForWorkspace removes every embed token of a workspace
+func (r *EmbedTokenRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "embed_tokens", bson.M{"workspace_id": workspaceID})
+}
+
+// revoke sets revoked_at on an unrevoked share link or embed token of a diagram
+func revoke(ctx context.Context, name string, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	collection, err := collection(name)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "workspace_id": workspaceID, "diagram_id": diagramID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// deleteWhere removes every document of a collection matching filter
+func deleteWhere(ctx context.Context, name string, filter bson.M) error {
+	collection, err := collection(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, filter)
+	return err
+}
+
+// AccessRepository stores diagram opens in the diagram_access collection
+type AccessRepository struct{}
+
+// RecordOpen upserts the user's access record of a diagram
+func (r *AccessRepository) RecordOpen(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID, at time.Time) error {
+	collection, err := collection("diagram_access")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "diagram_id": diagramID},
+		bson.M{
+			"$set": bson.M{"workspace_id": workspaceID, "last_opened_at": at},
+			"$inc": bson.M{"open_count": 1},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mapError(err)
+}
+
+// ListRecent returns the user's most recent access records in the given workspaces
+func (r *AccessRepository) ListRecent(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID, limit int64) ([]*models.DiagramAccess, error) {
+	collection, err := collection("diagram_access")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_opened_at", Value: -1}}).
+		SetLimit(limit)
+	cursor, err := collection.Find(ctx, bson.M{
+		"user_id":      userID,
+		"workspace_id": bson.M{"$in": workspaceIDs},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*models.DiagramAccess
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// DeleteForDiagram removes every access record of a diagram
+func (r *AccessRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteWhere(ctx, "diagram_access", bson.M{"workspace_id": workspaceID, "diagram_id": diagramID})
+}
+
+// DeleteAllForWorkspace removes every access record of a workspace
+func (r *AccessRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "diagram_access", bson.M{"workspace_id": workspaceID})
+}
+
+// StarRepository stores starred diagrams in the diagram_stars collection
+type StarRepository struct{}
+
+// Star upserts a star, keeping the original one if the diagram was already starred
+func (r *StarRepository) Star(ctx context.Context, star *models.DiagramStar) (*models.DiagramStar, error) {
+	collection, err := collection("diagram_stars")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var stored models.DiagramStar
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": star.UserID, "diagram_id": star.DiagramID},
+		bson.M{"$setOnInsert": bson.M{
+			"user_id":      star.UserID,
+			"workspace_id": star.WorkspaceID,
+			"diagram_id":   star.DiagramID,
+			"created_at":   star.CreatedAt,
+		}},
+		opts,
+	).Decode(&stored)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &stored, nil
+}
+
+// Unstar removes a star
+func (r *StarRepository) Unstar(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) error {
+	collection, err := collection("diagram_stars")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{
+		"user_id":      userID,
+		"workspace_id": workspaceID,
+		"diagram_id":   diagramID,
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ListForUser returns the user's stars in the given workspaces
+func (r *StarRepository) ListForUser(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID) ([]*models.DiagramStar, error) {
+	collection, err := collection("diagram_stars")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"user_id":      userID,
+		"workspace_id": bson.M{"$in": workspaceIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stars []*models.DiagramStar
+	if err := cursor.All(ctx, &stars); err != nil {
+		return nil, err
+	}
+	return stars, nil
+}
+
+// IsStarred reports whether the user starred a diagram
+func (r *StarRepository) IsStarred(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) (bool, error) {
+	collection, err := collection("diagram_stars")
+	if err != nil {
+		return false, err
+	}
+
+	n, err := collection.CountDocuments(ctx, bson.M{
+		"user_id":      userID,
+		"workspace_id": workspaceID,
+		"diagram_id":   diagramID,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// DeleteForDiagram removes every star of a diagram
+func (r *StarRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteWhere(ctx, "diagram_stars", bson.M{"workspace_id": workspaceID, "diagram_id": diagramID})
+}
+
+// DeleteAllForWorkspace removes every star of a workspace
+func (r *StarRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "diagram_stars", bson.M{"workspace_id": workspaceID})
+}
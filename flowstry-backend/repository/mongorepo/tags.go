@@ -0,0 +1,144 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TagRepository stores tag definitions in the workspace_tags collection
+type TagRepository struct{}
+
+// Create inserts a tag
+func (r *TagRepository) Create(ctx context.Context, tag *models.WorkspaceTag) error {
+	collection, err := collection("workspace_tags")
+	if err != nil {
+		return err
+	}
+
+	if tag.ID.IsZero() {
+		tag.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, tag)
+	return mapError(err)
+}
+
+// Get retrieves a tag of a workspace
+func (r *TagRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.WorkspaceTag, error) {
+	collection, err := collection("workspace_tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var tag models.WorkspaceTag
+	if err := collection.FindOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID}).Decode(&tag); err != nil {
+		return nil, mapError(err)
+	}
+	return &tag, nil
+}
+
+// List returns the tags of a workspace ordered by name
+func (r *TagRepository) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceTag, error) {
+	collection, err := collection("workspace_tags")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"workspace_id": workspaceID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tags []*models.WorkspaceTag
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Update changes the given fields of a tag
+func (r *TagRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.TagUpdate) error {
+	collection, err := collection("workspace_tags")
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"updated_at": update.UpdatedAt}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Color != nil {
+		set["color"] = *update.Color
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID}, bson.M{"$set": set})
+	if err != nil {
+		return mapError(err)
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// EnsureDefined upserts a default-colored tag for each name in one bulk write
+func (r *TagRepository) EnsureDefined(ctx context.Context, workspaceID primitive.ObjectID, names []string, at time.Time) error {
+	if len(names) == 0 {
+		return nil
+	}
+	collection, err := collection("workspace_tags")
+	if err != nil {
+		return err
+	}
+
+	writes := make([]mongo.WriteModel, len(names))
+	for i, name := range names {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"workspace_id": workspaceID, "name": name}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{
+				"workspace_id": workspaceID,
+				"name":         name,
+				"color":        models.DefaultTagColor,
+				"created_at":   at,
+				"updated_at":   at,
+			}}).
+			SetUpsert(true)
+	}
+
+	_, err = collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent request defined the same tag
+		return nil
+	}
+	return err
+}
+
+// Delete removes a tag
+func (r *TagRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	collection, err := collection("workspace_tags")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAllForWorkspace removes every tag of a workspace
+func (r *TagRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "workspace_tags", bson.M{"workspace_id": workspaceID})
+}
@@ -0,0 +1,249 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserRepository stores users in the users collection
+type UserRepository struct{}
+
+// Create inserts a user
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	collection, err := collection("users")
+	if err != nil {
+		return err
+	}
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, user)
+	return mapError(err)
+}
+
+// Get retrieves a user by ID
+func (r *UserRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetByEmail retrieves a user by email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+// GetByGoogleID retrieves a user by linked Google account
+func (r *UserRepository) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"google_id": googleID})
+}
+
+func (r *UserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	collection, err := collection("users")
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := collection.FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, mapError(err)
+	}
+	return &user, nil
+}
+
+// LinkGoogle attaches a Google account to a user
+func (r *UserRepository) LinkGoogle(ctx context.Context, id primitive.ObjectID, googleID, avatarURL string) error {
+	collection, err := collection("users")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"google_id":     googleID,
+			"auth_provider": models.AuthProviderGoogle,
+			"avatar_url":    avatarURL,
+			"updated_at":    time.Now(),
+		}},
+	)
+	return err
+}
+
+// UpdatePreferences replaces a user's preferences
+func (r *UserRepository) UpdatePreferences(ctx context.Context, id primitive.ObjectID, prefs models.UserPreferences) error {
+	collection, err := collection("users")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"preferences": prefs,
+			"updated_at":  time.Now(),
+		}},
+	)
+	return err
+}
+
+// UpdateNotificationPreferences replaces a user's notification preferences
+func (r *UserRepository) UpdateNotificationPreferences(ctx context.Context, id primitive.ObjectID, prefs models.NotificationPreferences) error {
+	collection, err := collection("users")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"notification_preferences": prefs,
+			"updated_at":               time.Now(),
+		}},
+	)
+	return err
+}
+
+// RefreshTokenRepository stores hashed refresh tokens in the refresh_tokens collection
+type RefreshTokenRepository struct{}
+
+// Create inserts a refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	collection, err := collection("refresh_tokens")
+	if err != nil {
+		return err
+	}
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, token)
+	return mapError(err)
+}
+
+// GetByHash retrieves a refresh token by its hash
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	collection, err := collection("refresh_tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.RefreshToken
+	if err := collection.FindOne(ctx, bson.M{"token": hash}).Decode(&token); err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+// Revoke revokes a refresh token
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, hash string) error {
+	collection, err := collection("refresh_tokens")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"token": hash}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+// RevokeAllForUser revokes every refresh token of a user
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	collection, err := collection("refresh_tokens")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+// PersonalAccessTokenRepository stores hashed personal access tokens in the personal_access_tokens collection
+type PersonalAccessTokenRepository struct{}
+
+// Create inserts a personal access token
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	collection, err := collection("personal_access_tokens")
+	if err != nil {
+		return err
+	}
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, token)
+	return mapError(err)
+}
+
+// GetByHash retrieves a personal access token by its hash
+func (r *PersonalAccessTokenRepository) GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	collection, err := collection("personal_access_tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.PersonalAccessToken
+	if err := collection.FindOne(ctx, bson.M{"token": hash}).Decode(&token); err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+// ListForUser returns the tokens of a user, newest first
+func (r *PersonalAccessTokenRepository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	collection, err := collection("personal_access_tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.PersonalAccessToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Touch records when a token was last used
+func (r *PersonalAccessTokenRepository) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	collection, err := collection("personal_access_tokens")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a token of a user
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	collection, err := collection("personal_access_tokens")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
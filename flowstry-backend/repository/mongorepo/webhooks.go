@@ -0,0 +1,317 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookRepository stores webhooks in the webhooks collection
+type WebhookRepository struct{}
+
+// Create inserts a webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	collection, err := collection("webhooks")
+	if err != nil {
+		return err
+	}
+
+	if webhook.ID.IsZero() {
+		webhook.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, webhook)
+	return mapError(err)
+}
+
+// Get retrieves a webhook of a workspace
+func (r *WebhookRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.Webhook, error) {
+	collection, err := collection("webhooks")
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook models.Webhook
+	if err := collection.FindOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID}).Decode(&webhook); err != nil {
+		return nil, mapError(err)
+	}
+	return &webhook, nil
+}
+
+// List returns the webhooks of a workspace, oldest first
+func (r *WebhookRepository) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Webhook, error) {
+	return r.find(ctx, bson.M{"workspace_id": workspaceID})
+}
+
+// ListSubscribed returns the enabled webhooks of a workspace that subscribe to an event type
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType) ([]*models.Webhook, error) {
+	return r.find(ctx, bson.M{"workspace_id": workspaceID, "enabled": true, "events": eventType})
+}
+
+func (r *WebhookRepository) find(ctx context.Context, query bson.M) ([]*models.Webhook, error) {
+	collection, err := collection("webhooks")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []*models.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Update changes a webhook
+func (r *WebhookRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.WebhookUpdate) error {
+	set := bson.M{"updated_at": update.UpdatedAt}
+	if update.URL != nil {
+		set["url"] = *update.URL
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Events != nil {
+		set["events"] = update.Events
+	}
+	if update.EncryptedSecret != nil {
+		set["secret"] = update.EncryptedSecret
+	}
+	change := bson.M{"$set": set}
+	if update.Enabled != nil {
+		set["enabled"] = *update.Enabled
+		if *update.Enabled {
+			set["consecutive_failures"] = 0
+			change["$unset"] = bson.M{"disabled_reason": ""}
+		}
+	}
+	_, err := r.updateOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID}, change)
+	return err
+}
+
+// RecordSuccess clears the failure count of a webhook
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.updateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"consecutive_failures": 0}})
+	return err
+}
+
+// RecordFailure counts a failed attempt and disables the webhook at maxFailures
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id primitive.ObjectID, maxFailures int, reason string, now time.Time) (bool, error) {
+	if _, err := r.updateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"consecutive_failures": 1}}); err != nil {
+		return false, err
+	}
+
+	// Only the call that flips enabled reports the webhook as disabled
+	modified, err := r.updateOne(ctx,
+		bson.M{"_id": id, "enabled": true, "consecutive_failures": bson.M{"$gte": maxFailures}},
+		bson.M{"$set": bson.M{"enabled": false, "disabled_reason": reason, "updated_at": now}})
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return modified, err
+}
+
+// updateOne applies a change to one webhook and reports whether it modified it
+func (r *WebhookRepository) updateOne(ctx context.Context, filter, change bson.M) (bool, error) {
+	collection, err := collection("webhooks")
+	if err != nil {
+		return false, err
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, change)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		return false, repository.ErrNotFound
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// Delete removes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	collection, err := collection("webhooks")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAllForWorkspace removes every webhook of a workspace
+func (r *WebhookRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "webhooks", bson.M{"workspace_id": workspaceID})
+}
+
+// WebhookDeliveryRepository stores webhook deliveries in the webhook_deliveries collection
+type WebhookDeliveryRepository struct{}
+
+// deliverySortKeys maps delivery sort fields to document keys
+var deliverySortKeys = sortKeys{
+	models.SortByCreated: "created_at",
+}
+
+// Create inserts a delivery
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	collection, err := collection("webhook_deliveries")
+	if err != nil {
+		return err
+	}
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, delivery)
+	return mapError(err)
+}
+
+// Get retrieves a delivery of a webhook
+func (r *WebhookDeliveryRepository) Get(ctx context.Context, workspaceID, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	collection, err := collection("webhook_deliveries")
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	err = collection.FindOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID, "webhook_id": webhookID}).Decode(&delivery)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &delivery, nil
+}
+
+// List pages through the deliveries of a webhook
+func (r *WebhookDeliveryRepository) List(ctx context.Context, workspaceID, webhookID primitive.ObjectID, filter *models.WebhookDeliveryFilter, page *repository.PageQuery) ([]*models.WebhookDelivery, error) {
+	query := bson.M{"workspace_id": workspaceID, "webhook_id": webhookID}
+	if filter != nil && filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	opts, err := applyPage(query, deliverySortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, query, opts)
+}
+
+// Due returns pending deliveries whose next attempt is due
+func (r *WebhookDeliveryRepository) Due(ctx context.Context, now time.Time, limit int64) ([]*models.WebhookDelivery, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	return r.find(ctx, bson.M{
+		"status":          models.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}, opts)
+}
+
+func (r *WebhookDeliveryRepository) find(ctx context.Context, query bson.M, opts *options.FindOptions) ([]*models.WebhookDelivery, error) {
+	collection, err := collection("webhook_deliveries")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []*models.WebhookDelivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Claim leases a due delivery
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error {
+	return r.update(ctx,
+		bson.M{"_id": id, "status": models.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": leaseUntil}})
+}
+
+// RecordAttempt stores the outcome of an attempt
+func (r *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt *repository.WebhookAttempt) error {
+	set := bson.M{
+		"status":          attempt.Status,
+		"attempts":        attempt.Attempts,
+		"next_attempt_at": attempt.NextAttemptAt,
+	}
+	unset := bson.M{}
+	optional := func(key string, value interface{}, empty bool) {
+		if empty {
+			unset[key] = ""
+		} else {
+			set[key] = value
+		}
+	}
+	optional("response_status", attempt.ResponseStatus, attempt.ResponseStatus == 0)
+	optional("response_body", attempt.ResponseBody, attempt.ResponseBody == "")
+	optional("last_error", attempt.LastError, attempt.LastError == "")
+	optional("completed_at", attempt.CompletedAt, attempt.CompletedAt == nil)
+
+	change := bson.M{"$set": set}
+	if len(unset) > 0 {
+		change["$unset"] = unset
+	}
+	return r.update(ctx, bson.M{"_id": id}, change)
+}
+
+func (r *WebhookDeliveryRepository) update(ctx context.Context, filter, change bson.M) error {
+	collection, err := collection("webhook_deliveries")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, change)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteCompletedBefore prunes deliveries completed before a time
+func (r *WebhookDeliveryRepository) DeleteCompletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	collection, err := collection("webhook_deliveries")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteMany(ctx, bson.M{"completed_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteForWebhook removes every delivery of a webhook
+func (r *WebhookDeliveryRepository) DeleteForWebhook(ctx context.Context, workspaceID, webhookID primitive.ObjectID) error {
+	return deleteWhere(ctx, "webhook_deliveries", bson.M{"workspace_id": workspaceID, "webhook_id": webhookID})
+}
+
+// DeleteAllForWorkspace removes every delivery of a workspace
+func (r *WebhookDeliveryRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteWhere(ctx, "webhook_deliveries", bson.M{"workspace_id": workspaceID})
+}
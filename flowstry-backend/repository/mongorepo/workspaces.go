@@ -0,0 +1,479 @@
+package mongorepo
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WorkspaceRepository stores workspaces in the workspaces collection
+type WorkspaceRepository struct{}
+
+// Create inserts a workspace
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	collection, err := collection("workspaces")
+	if err != nil {
+		return err
+	}
+
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, workspace)
+	return mapError(err)
+}
+
+// CreateWithOwner inserts a workspace and its owner's membership. Standalone servers have no
+// multi-document transactions, so the workspace is removed again when the membership fails.
+func (r *WorkspaceRepository) CreateWithOwner(ctx context.Context, workspace *models.Workspace, owner *models.WorkspaceMember) error {
+	if err := r.Create(ctx, workspace); err != nil {
+		return err
+	}
+
+	owner.WorkspaceID = workspace.ID
+	if err := (&MemberRepository{}).Create(ctx, owner); err != nil {
+		_ = r.Delete(ctx, workspace.ID)
+		return err
+	}
+	return nil
+}
+
+// Get retrieves a workspace by ID
+func (r *WorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	collection, err := collection("workspaces")
+	if err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&workspace); err != nil {
+		return nil, mapError(err)
+	}
+	return &workspace, nil
+}
+
+// ListByIDs retrieves the workspaces with the given IDs
+func (r *WorkspaceRepository) ListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Workspace, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+// ListOwnedBy retrieves the workspaces owned by a user
+func (r *WorkspaceRepository) ListOwnedBy(ctx context.Context, userID primitive.ObjectID) ([]*models.Workspace, error) {
+	return r.find(ctx, bson.M{"user_id": userID})
+}
+
+func (r *WorkspaceRepository) find(ctx context.Context, filter bson.M) ([]*models.Workspace, error) {
+	collection, err := collection("workspaces")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var workspaces []*models.Workspace
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// Update changes the given workspace fields
+func (r *WorkspaceRepository) Update(ctx context.Context, id primitive.ObjectID, update *repository.WorkspaceUpdate) error {
+	collection, err := collection("workspaces")
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"updated_at": update.UpdatedAt}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.ContentSearchEnabled != nil {
+		set["content_search_enabled"] = *update.ContentSearchEnabled
+	}
+	if update.TrashRetentionDays != nil {
+		set["trash_retention_days"] = *update.TrashRetentionDays
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a workspace document
+func (r *WorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	collection, err := collection("workspaces")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// MemberRepository stores memberships in the workspace_members collection
+type MemberRepository struct{}
+
+// memberSortKeys maps member sort fields to keys of the joined member document
+var memberSortKeys = sortKeys{
+	models.SortByName:    "name",
+	models.SortByCreated: "joined_at",
+}
+
+// Create inserts a membership
+func (r *MemberRepository) Create(ctx context.Context, member *models.WorkspaceMember) error {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return err
+	}
+
+	if member.ID.IsZero() {
+		member.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, member)
+	return mapError(err)
+}
+
+// Get retrieves the membership of a user in a workspace
+func (r *MemberRepository) Get(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return nil, err
+	}
+
+	var member models.WorkspaceMember
+	err = collection.FindOne(ctx, bson.M{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	}).Decode(&member)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &member, nil
+}
+
+// List pages through a workspace's members joined with their user profiles
+func (r *MemberRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.MemberQuery, page *repository.PageQuery) ([]*repository.MemberDetail, error) {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := memberSortKeys[page.Sort]
+	if !ok {
+		return nil, errUnsupportedSort
+	}
+
+	match := bson.M{"workspace_id": workspaceID}
+	if query != nil {
+		if len(query.Roles) > 0 {
+			match["role"] = bson.M{"$in": query.Roles}
+		}
+		switch {
+		case query.UserID != nil:
+			match["user_id"] = *query.UserID
+		case query.ExcludeUserID != nil:
+			match["user_id"] = bson.M{"$ne": *query.ExcludeUserID}
+		}
+		applyDateRange(match, "joined_at", query.Joined)
+	}
+
+	// Keyset and prefix conditions are applied after the projection since they reference the user
+	after := bson.M{}
+	if cond := keysetCondition(key, page); cond != nil {
+		after = cond
+	}
+	if query != nil && query.Prefix != "" {
+		prefix := regexp.QuoteMeta(query.Prefix)
+		after = bson.M{"$and": bson.A{after, bson.M{"$or": bson.A{
+			bson.M{"email": primitive.Regex{Pattern: "^" + prefix, Options: "i"}},
+			bson.M{"name": primitive.Regex{Pattern: `(^|\s)` + prefix, Options: "i"}},
+		}}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.M{
+			"_id":          1,
+			"workspace_id": 1,
+			"user_id":      1,
+			"role":         1,
+			"joined_at":    1,
+			"email":        "$user.email",
+			"name":         "$user.name",
+			"avatar_url":   "$user.avatar_url",
+		}}},
+		{{Key: "$match", Value: after}},
+		{{Key: "$sort", Value: sortDoc(key, page.Order)}},
+	}
+	if page.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: page.Limit}})
+	}
+
+	opts := options.Aggregate()
+	if page.Sort == models.SortByName {
+		opts.SetCollation(nameCollation)
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		models.WorkspaceMember `bson:",inline"`
+		Email                  string `bson:"email"`
+		Name                   string `bson:"name"`
+		AvatarURL              string `bson:"avatar_url"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	members := make([]*repository.MemberDetail, len(results))
+	for i, r := range results {
+		members[i] = &repository.MemberDetail{
+			WorkspaceMember: r.WorkspaceMember,
+			Email:           r.Email,
+			Name:            r.Name,
+			AvatarURL:       r.AvatarURL,
+		}
+	}
+	return members, nil
+}
+
+// ListForUser retrieves every membership of a user
+func (r *MemberRepository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceMember, error) {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var members []*models.WorkspaceMember
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Count returns the number of members of a workspace
+func (r *MemberRepository) Count(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.M{"workspace_id": workspaceID})
+}
+
+// UpdateRole changes the role of a member
+func (r *MemberRepository) UpdateRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.WorkspaceRole) error {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"workspace_id": workspaceID, "user_id": userID},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a member from a workspace
+func (r *MemberRepository) Delete(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"workspace_id": workspaceID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAllForWorkspace removes every member of a workspace
+func (r *MemberRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	collection, err := collection("workspace_members")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
+	return err
+}
+
+// InviteRepository stores invites in the workspace_invites collection
+type InviteRepository struct{}
+
+// inviteSortKeys maps invite sort fields to document keys
+var inviteSortKeys = sortKeys{
+	models.SortByName:    "email",
+	models.SortByCreated: "created_at",
+}
+
+// Create inserts an invite
+func (r *InviteRepository) Create(ctx context.Context, invite *models.WorkspaceInvite) error {
+	collection, err := collection("workspace_invites")
+	if err != nil {
+		return err
+	}
+
+	if invite.ID.IsZero() {
+		invite.ID = primitive.NewObjectID()
+	}
+	_, err = collection.InsertOne(ctx, invite)
+	return mapError(err)
+}
+
+// Get retrieves an invite by ID
+func (r *InviteRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.WorkspaceInvite, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetByToken retrieves an invite by token
+func (r *InviteRepository) GetByToken(ctx context.Context, token string) (*models.WorkspaceInvite, error) {
+	return r.findOne(ctx, bson.M{"token": token})
+}
+
+// GetByEmail retrieves the invite of an email to a workspace
+func (r *InviteRepository) GetByEmail(ctx context.Context, workspaceID primitive.ObjectID, email string) (*models.WorkspaceInvite, error) {
+	return r.findOne(ctx, bson.M{"workspace_id": workspaceID, "email": email})
+}
+
+func (r *InviteRepository) findOne(ctx context.Context, filter bson.M) (*models.WorkspaceInvite, error) {
+	collection, err := collection("workspace_invites")
+	if err != nil {
+		return nil, err
+	}
+
+	var invite models.WorkspaceInvite
+	if err := collection.FindOne(ctx, filter).Decode(&invite); err != nil {
+		return nil, mapError(err)
+	}
+	return &invite, nil
+}
+
+// List pages through a workspace's invites
+func (r *InviteRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.InviteQuery, page *repository.PageQuery) ([]*models.WorkspaceInvite, error) {
+	filter := bson.M{"workspace_id": workspaceID}
+	if query != nil {
+		filter["expires_at"] = bson.M{"$gt": query.ExpiresAfter}
+		if query.InvitedBy != nil {
+			filter["invited_by"] = *query.InvitedBy
+		}
+		applyDateRange(filter, "created_at", query.Created)
+	}
+
+	opts, err := applyPage(filter, inviteSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, filter, opts)
+}
+
+// ListForEmail retrieves the valid invites addressed to an email, newest first
+func (r *InviteRepository) ListForEmail(ctx context.Context, email string, now time.Time) ([]*models.WorkspaceInvite, error) {
+	return r.find(ctx,
+		bson.M{"email": email, "expires_at": bson.M{"$gt": now}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+}
+
+func (r *InviteRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*models.WorkspaceInvite, error) {
+	collection, err := collection("workspace_invites")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invites []*models.WorkspaceInvite
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Delete removes an invite
+func (r *InviteRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.deleteOne(ctx, bson.M{"_id": id})
+}
+
+// DeleteInWorkspace removes an invite of a workspace
+func (r *InviteRepository) DeleteInWorkspace(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	return r.deleteOne(ctx, bson.M{"_id": id, "workspace_id": workspaceID})
+}
+
+func (r *InviteRepository) deleteOne(ctx context.Context, filter bson.M) error {
+	collection, err := collection("workspace_invites")
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAllForWorkspace removes every invite of a workspace
+func (r *InviteRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	collection, err := collection("workspace_invites")
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
+	return err
+}
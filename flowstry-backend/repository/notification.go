@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationRepository persists users' in-app notifications
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	// FindUnread returns a user's unread notification of a type about a target (ErrNotFound when there is none)
+	FindUnread(ctx context.Context, userID primitive.ObjectID, notificationType models.NotificationType, targetID primitive.ObjectID) (*models.Notification, error)
+	// Bump folds another change into an unread notification: it counts one more, names the latest actor and moves updated_at forward
+	Bump(ctx context.Context, userID, id primitive.ObjectID, actorID *primitive.ObjectID, actorName, message string, updatedAt time.Time) error
+	// List pages through a user's notifications; only SortByUpdated is supported
+	List(ctx context.Context, userID primitive.ObjectID, filter *models.NotificationFilter, page *PageQuery) ([]*models.Notification, error)
+	CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// MarkRead marks some of a user's notifications read and returns how many were unread
+	MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, readAt time.Time) (int64, error)
+	// MarkAllRead marks every notification of a user read and returns how many were unread
+	MarkAllRead(ctx context.Context, userID primitive.ObjectID, readAt time.Time) (int64, error)
+	// PendingDigest returns up to limit (0 for all) unread notifications, not yet in a digest, last
+	// updated before updatedBefore, ordered by user and then update time; a userID narrows it to one user
+	PendingDigest(ctx context.Context, userID *primitive.ObjectID, updatedBefore time.Time, limit int64) ([]*models.Notification, error)
+	// MarkDigested records that notifications were emailed in a digest
+	MarkDigested(ctx context.Context, ids []primitive.ObjectID, digestedAt time.Time) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
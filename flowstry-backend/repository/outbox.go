@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxStatus is the delivery state of a queued email
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending" // Waiting for its next attempt
+	OutboxSent    OutboxStatus = "sent"
+	OutboxFailed  OutboxStatus = "failed" // Gave up after the last attempt
+)
+
+// OutboxEmail is a rendered email waiting in the outbox until the mailer delivers it
+type OutboxEmail struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	Template      string             `bson:"template"`
+	To            string             `bson:"to"`
+	Subject       string             `bson:"subject"`
+	Text          string             `bson:"text"`
+	HTML          string             `bson:"html"`
+	Status        OutboxStatus       `bson:"status"`
+	Attempts      int                `bson:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"`
+	LastError     string             `bson:"last_error,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at"`
+	SentAt        *time.Time         `bson:"sent_at,omitempty"`
+}
+
+// OutboxRepository persists the email outbox
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, email *OutboxEmail) error
+	// Due returns up to limit pending emails whose next attempt is at or before now, oldest attempt first
+	Due(ctx context.Context, now time.Time, limit int64) ([]*OutboxEmail, error)
+	// Claim leases a due email until leaseUntil by moving its next attempt there, so other
+	// instances skip it while it is being sent. It returns ErrNotFound when the email is no longer due.
+	Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error
+	MarkSent(ctx context.Context, id primitive.ObjectID, attempts int, sentAt time.Time) error
+	// MarkRetry records a failed attempt and schedules the next one
+	MarkRetry(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt time.Time, lastError string) error
+	// MarkFailed records the last failed attempt of an email that will not be retried
+	MarkFailed(ctx context.Context, id primitive.ObjectID, attempts int, lastError string) error
+	// DeleteSentBefore prunes emails delivered before a time and returns how many were deleted
+	DeleteSentBefore(ctx context.Context, before time.Time) (int64, error)
+}
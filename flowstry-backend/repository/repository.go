@@ -0,0 +1,73 @@
+// Package repository defines the persistence interfaces the services depend on.
+// mongorepo implements them on MongoDB, sqlrepo on PostgreSQL; memory implements them in process for tests.
+package repository
+
+import (
+	"errors"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when no record matches
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write violates a uniqueness constraint
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// Store bundles the repositories of one backend
+type Store struct {
+	Users         UserRepository
+	RefreshTokens RefreshTokenRepository
+	AccessTokens  PersonalAccessTokenRepository
+	Workspaces    WorkspaceRepository
+	Members       MemberRepository
+	Invites       InviteRepository
+	Folders       FolderRepository
+	Diagrams      DiagramRepository
+	Tags          TagRepository
+	ShareLinks    ShareLinkRepository
+	EmbedTokens   EmbedTokenRepository
+	Access        AccessRepository
+	Stars         StarRepository
+	Audit         AuditRepository
+	Activity      ActivityRepository
+	Comments      CommentRepository
+	Mentions      MentionRepository
+	Notifications NotificationRepository
+	Outbox        OutboxRepository
+	Webhooks      WebhookRepository
+	Deliveries    WebhookDeliveryRepository
+
+	// Connected reports whether the backing database is reachable
+	Connected func() bool
+}
+
+// Keyset is the position after which a page starts: the sort value of the last item and its ID
+type Keyset struct {
+	// Value is a string (name sorts), int64 (size) or time.Time
+	Value interface{}
+	ID    primitive.ObjectID
+}
+
+// PageQuery asks for one page of a listing ordered by Sort, tie-broken by ID.
+// Name sorts are case-insensitive.
+type PageQuery struct {
+	Sort  models.SortField
+	Order models.SortOrder
+	After *Keyset
+	Limit int64
+}
+
+// Trash selects live or trashed items
+type Trash int
+
+const (
+	// Live matches items that are not in trash
+	Live Trash = iota
+	// Trashed matches items in trash
+	Trashed
+	// AnyTrash matches both
+	AnyTrash
+)
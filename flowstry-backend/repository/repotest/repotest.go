@@ -0,0 +1,1283 @@
+// Package repotest is a conformance suite every repository backend must pass
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	authModels "github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Run runs the suite; newStore returns an empty store for each subtest
+func Run(t *testing.T, newStore func(t *testing.T) *repository.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, f *fixture)
+	}{
+		{"Users", testUsers},
+		{"PersonalAccessTokens", testPersonalAccessTokens},
+		{"CreateWithOwner", testCreateWithOwner},
+		{"MemberPaging", testMemberPaging},
+		{"Invites", testInvites},
+		{"FolderTree", testFolderTree},
+		{"TrashAndRestore", testTrashAndRestore},
+		{"DiagramTags", testDiagramTags},
+		{"Search", testSearch},
+		{"Templates", testTemplates},
+		{"TagDefinitions", testTagDefinitions},
+		{"ShareLinks", testShareLinks},
+		{"AccessAndStars", testAccessAndStars},
+		{"AuditLog", testAuditLog},
+		{"Activity", testActivity},
+		{"Comments", testComments},
+		{"Mentions", testMentions},
+		{"Notifications", testNotifications},
+		{"NotificationDigests", testNotificationDigests},
+		{"Outbox", testOutbox},
+		{"Webhooks", testWebhooks},
+		{"WebhookDeliveries", testWebhookDeliveries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newFixture(t, newStore(t)))
+		})
+	}
+}
+
+// fixture is a store holding one user owning one workspace
+type fixture struct {
+	ctx   context.Context
+	store *repository.Store
+	now   time.Time
+	owner *authModels.User
+	ws    *models.Workspace
+}
+
+func newFixture(t *testing.T, store *repository.Store) *fixture {
+	t.Helper()
+	f := &fixture{
+		ctx:   context.Background(),
+		store: store,
+		// MongoDB keeps milliseconds, Postgres microseconds
+		now: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	f.owner = f.user(t, "owner@example.com", "Owner")
+	f.ws = &models.Workspace{UserID: f.owner.ID, Name: "Team", EncryptedKey: []byte("key"), CreatedAt: f.now, UpdatedAt: f.now}
+	owner := &models.WorkspaceMember{UserID: f.owner.ID, Role: models.RoleOwner, JoinedAt: f.now}
+	if err := store.Workspaces.CreateWithOwner(f.ctx, f.ws, owner); err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	return f
+}
+
+func (f *fixture) user(t *testing.T, email, name string) *authModels.User {
+	t.Helper()
+	u := &authModels.User{Email: email, Name: name, AuthProvider: authModels.AuthProviderEmail, CreatedAt: f.now, UpdatedAt: f.now}
+	if err := f.store.Users.Create(f.ctx, u); err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return u
+}
+
+func (f *fixture) folder(t *testing.T, name string, parent *models.Folder) *models.Folder {
+	t.Helper()
+	folder := &models.Folder{ID: primitive.NewObjectID(), WorkspaceID: f.ws.ID, Name: name, CreatedAt: f.now, UpdatedAt: f.now}
+	if parent != nil {
+		folder.ParentFolderID = &parent.ID
+	}
+	if err := f.store.Folders.Create(f.ctx, folder); err != nil {
+		t.Fatalf("create folder %s: %v", name, err)
+	}
+	return folder
+}
+
+func (f *fixture) diagram(t *testing.T, name string, folder *models.Folder, tags ...string) *models.Diagram {
+	t.Helper()
+	d := &models.Diagram{ID: primitive.NewObjectID(), WorkspaceID: f.ws.ID, Name: name, Version: 1, Tags: tags, CreatedAt: f.now, UpdatedAt: f.now}
+	if folder != nil {
+		d.FolderID = &folder.ID
+	}
+	if err := f.store.Diagrams.Create(f.ctx, d); err != nil {
+		t.Fatalf("create diagram %s: %v", name, err)
+	}
+	return d
+}
+
+func testUsers(t *testing.T, f *fixture) {
+	got, err := f.store.Users.GetByEmail(f.ctx, "owner@example.com")
+	if err != nil || got.ID != f.owner.ID || !got.CreatedAt.Equal(f.now) {
+		t.Fatalf("GetByEmail = %+v, %v", got, err)
+	}
+	dup := &authModels.User{Email: "owner@example.com", Name: "Again", CreatedAt: f.now, UpdatedAt: f.now}
+	if err := f.store.Users.Create(f.ctx, dup); !errors.Is(err, repository.ErrDuplicate) {
+		t.Fatalf("duplicate email: err = %v, want ErrDuplicate", err)
+	}
+	if _, err := f.store.Users.Get(f.ctx, primitive.NewObjectID()); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("missing user: err = %v, want ErrNotFound", err)
+	}
+
+	if err := f.store.Users.LinkGoogle(f.ctx, f.owner.ID, "google-1", "https://avatar"); err != nil {
+		t.Fatalf("LinkGoogle: %v", err)
+	}
+	got, err = f.store.Users.GetByGoogleID(f.ctx, "google-1")
+	if err != nil || got.ID != f.owner.ID || got.AvatarURL != "https://avatar" {
+		t.Fatalf("GetByGoogleID = %+v, %v", got, err)
+	}
+
+	prefs := authModels.NotificationPreferences{Muted: []string{"diagram_edited"}}
+	if err := f.store.Users.UpdateNotificationPreferences(f.ctx, f.owner.ID, prefs); err != nil {
+		t.Fatalf("UpdateNotificationPreferences: %v", err)
+	}
+	got, err = f.store.Users.Get(f.ctx, f.owner.ID)
+	if err != nil || len(got.Notifications.Muted) != 1 || got.Notifications.Muted[0] != "diagram_edited" {
+		t.Fatalf("notification preferences = %+v, %v", got.Notifications, err)
+	}
+}
+
+func testPersonalAccessTokens(t *testing.T, f *fixture) {
+	other := f.user(t, "other@example.com", "Other")
+	ci := &authModels.PersonalAccessToken{
+		UserID: f.owner.ID, Name: "CI", Token: "hash-ci", WorkspaceID: &f.ws.ID,
+		Scopes:    []authModels.TokenScope{authModels.ScopeDiagramsRead, authModels.ScopeMembersManage},
+		ExpiresAt: f.now.Add(time.Hour), CreatedAt: f.now,
+	}
+	script := &authModels.PersonalAccessToken{
+		UserID: f.owner.ID, Name: "Script", Token: "hash-script", ExpiresAt: f.now.Add(time.Hour), CreatedAt: f.now.Add(time.Second),
+	}
+	for _, token := range []*authModels.PersonalAccessToken{ci, script} {
+		if err := f.store.AccessTokens.Create(f.ctx, token); err != nil {
+			t.Fatalf("Create %s: %v", token.Name, err)
+		}
+	}
+	dup := &authModels.PersonalAccessToken{UserID: other.ID, Name: "Dup", Token: "hash-ci", ExpiresAt: f.now, CreatedAt: f.now}
+	if err := f.store.AccessTokens.Create(f.ctx, dup); !errors.Is(err, repository.ErrDuplicate) {
+		t.Fatalf("duplicate hash: err = %v, want ErrDuplicate", err)
+	}
+
+	got, err := f.store.AccessTokens.GetByHash(f.ctx, "hash-ci")
+	if err != nil || got.ID != ci.ID || got.WorkspaceID == nil || *got.WorkspaceID != f.ws.ID || !got.ExpiresAt.Equal(ci.ExpiresAt) || got.LastUsedAt != nil {
+		t.Fatalf("GetByHash = %+v, %v", got, err)
+	}
+	if len(got.Scopes) != 2 || got.Scopes[0] != authModels.ScopeDiagramsRead || got.Scopes[1] != authModels.ScopeMembersManage {
+		t.Fatalf("scopes = %v", got.Scopes)
+	}
+	if _, err := f.store.AccessTokens.GetByHash(f.ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("missing hash: err = %v, want ErrNotFound", err)
+	}
+
+	used := f.now.Add(time.Minute)
+	if err := f.store.AccessTokens.Touch(f.ctx, ci.ID, used); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	tokens, err := f.store.AccessTokens.ListForUser(f.ctx, f.owner.ID)
+	if err != nil || len(tokens) != 2 || tokens[0].ID != script.ID || tokens[1].LastUsedAt == nil || !tokens[1].LastUsedAt.Equal(used) {
+		t.Fatalf("ListForUser = %+v, %v", tokens, err)
+	}
+
+	if err := f.store.AccessTokens.Delete(f.ctx, other.ID, ci.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Delete by another user: err = %v, want ErrNotFound", err)
+	}
+	if err := f.store.AccessTokens.Delete(f.ctx, f.owner.ID, ci.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := f.store.AccessTokens.GetByHash(f.ctx, "hash-ci"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("deleted token: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testCreateWithOwner(t *testing.T, f *fixture) {
+	member, err := f.store.Members.Get(f.ctx, f.ws.ID, f.owner.ID)
+	if err != nil || member.Role != models.RoleOwner {
+		t.Fatalf("owner membership = %+v, %v", member, err)
+	}
+
+	// A second owner membership for the same user fails, and takes the workspace with it
+	ws := &models.Workspace{ID: primitive.NewObjectID(), UserID: f.owner.ID, Name: "Other", CreatedAt: f.now, UpdatedAt: f.now}
+	owner := &models.WorkspaceMember{ID: member.ID, UserID: f.owner.ID, Role: models.RoleOwner, JoinedAt: f.now}
+	if err := f.store.Workspaces.CreateWithOwner(f.ctx, ws, owner); err == nil {
+		t.Fatal("CreateWithOwner with a taken member ID succeeded")
+	}
+	if _, err := f.store.Workspaces.Get(f.ctx, ws.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("workspace after failed CreateWithOwner: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testMemberPaging(t *testing.T, f *fixture) {
+	for _, name := range []string{"carol", "alice", "Bob"} {
+		u := f.user(t, name+"@example.com", name)
+		m := &models.WorkspaceMember{WorkspaceID: f.ws.ID, UserID: u.ID, Role: models.RoleEditor, JoinedAt: f.now}
+		if err := f.store.Members.Create(f.ctx, m); err != nil {
+			t.Fatalf("create member: %v", err)
+		}
+	}
+
+	query := &repository.MemberQuery{Roles: []models.WorkspaceRole{models.RoleEditor}}
+	page := &repository.PageQuery{Sort: models.SortByName, Order: models.SortAsc, Limit: 2}
+	first, err := f.store.Members.List(f.ctx, f.ws.ID, query, page)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if names(first) != "alice,Bob" {
+		t.Fatalf("first page = %s, want alice,Bob", names(first))
+	}
+	last := first[len(first)-1]
+	page.After = &repository.Keyset{Value: last.Name, ID: last.ID}
+	second, err := f.store.Members.List(f.ctx, f.ws.ID, query, page)
+	if err != nil {
+		t.Fatalf("List after: %v", err)
+	}
+	if names(second) != "carol" {
+		t.Fatalf("second page = %s, want carol", names(second))
+	}
+
+	if n, err := f.store.Members.Count(f.ctx, f.ws.ID); err != nil || n != 4 {
+		t.Fatalf("Count = %d, %v; want 4", n, err)
+	}
+
+	// Prefixes match the email, the name or a later word of the name; LIKE wildcards are literal
+	dana := f.user(t, "d.bolt@example.com", "Dana Bolt")
+	if err := f.store.Members.Create(f.ctx, &models.WorkspaceMember{WorkspaceID: f.ws.ID, UserID: dana.ID, Role: models.RoleViewer, JoinedAt: f.now}); err != nil {
+		t.Fatalf("create member: %v", err)
+	}
+	page = &repository.PageQuery{Sort: models.SortByName, Order: models.SortAsc, Limit: 10}
+	for prefix, want := range map[string]string{"bo": "Bob,Dana Bolt", "D.B": "Dana Bolt", "car": "carol", "ol": "", "%": ""} {
+		got, err := f.store.Members.List(f.ctx, f.ws.ID, &repository.MemberQuery{Prefix: prefix}, page)
+		if err != nil {
+			t.Fatalf("List prefix %q: %v", prefix, err)
+		}
+		if names(got) != want {
+			t.Errorf("prefix %q = %s, want %s", prefix, names(got), want)
+		}
+	}
+}
+
+func names(members []*repository.MemberDetail) string {
+	s := ""
+	for i, m := range members {
+		if i > 0 {
+			s += ","
+		}
+		s += m.Name
+	}
+	return s
+}
+
+func testInvites(t *testing.T, f *fixture) {
+	for i, email := range []string{"old@example.com", "new@example.com"} {
+		invite := &models.WorkspaceInvite{
+			WorkspaceID: f.ws.ID,
+			Email:       email,
+			Role:        models.RoleViewer,
+			Token:       email,
+			InvitedBy:   f.owner.ID,
+			ExpiresAt:   f.now.Add(time.Duration(i*2-1) * time.Hour),
+			CreatedAt:   f.now,
+		}
+		if err := f.store.Invites.Create(f.ctx, invite); err != nil {
+			t.Fatalf("create invite: %v", err)
+		}
+	}
+
+	pending, err := f.store.Invites.List(f.ctx, f.ws.ID, &repository.InviteQuery{ExpiresAfter: f.now}, &repository.PageQuery{Sort: models.SortByName, Order: models.SortAsc})
+	if err != nil || len(pending) != 1 || pending[0].Email != "new@example.com" {
+		t.Fatalf("pending invites = %v, %v", pending, err)
+	}
+	dup := &models.WorkspaceInvite{WorkspaceID: f.ws.ID, Email: "new@example.com", Token: "other", InvitedBy: f.owner.ID, ExpiresAt: f.now, CreatedAt: f.now}
+	if err := f.store.Invites.Create(f.ctx, dup); !errors.Is(err, repository.ErrDuplicate) {
+		t.Fatalf("duplicate invite: err = %v, want ErrDuplicate", err)
+	}
+	if err := f.store.Invites.DeleteInWorkspace(f.ctx, primitive.NewObjectID(), pending[0].ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("delete in other workspace: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testFolderTree(t *testing.T, f *fixture) {
+	root := f.folder(t, "root", nil)
+	child := f.folder(t, "child", root)
+	grandchild := f.folder(t, "grandchild", child)
+	f.folder(t, "sibling", nil)
+
+	ancestors, err := f.store.Folders.Ancestors(f.ctx, f.ws.ID, grandchild.ID)
+	if err != nil {
+		t.Fatalf("Ancestors: %v", err)
+	}
+	if len(ancestors) != 2 || ancestors[0].ID != root.ID || ancestors[1].ID != child.ID {
+		t.Fatalf("Ancestors = %v, want root, child", ancestors)
+	}
+
+	descendants, err := f.store.Folders.Descendants(f.ctx, f.ws.ID, root.ID, nil)
+	if err != nil {
+		t.Fatalf("Descendants: %v", err)
+	}
+	assertIDs(t, "Descendants", descendants, child.ID, grandchild.ID)
+
+	if _, err := f.store.Folders.Ancestors(f.ctx, f.ws.ID, primitive.NewObjectID()); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Ancestors of a missing folder: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testTrashAndRestore(t *testing.T, f *fixture) {
+	folder := f.folder(t, "folder", nil)
+	sub := f.folder(t, "sub", folder)
+	inside := f.diagram(t, "inside", sub)
+	outside := f.diagram(t, "outside", nil)
+
+	batch := primitive.NewObjectID()
+	if n, err := f.store.Folders.Trash(f.ctx, f.ws.ID, []primitive.ObjectID{folder.ID}, batch, f.now, false); err != nil || n != 1 {
+		t.Fatalf("Trash root = %d, %v", n, err)
+	}
+	if _, err := f.store.Folders.Trash(f.ctx, f.ws.ID, []primitive.ObjectID{sub.ID}, batch, f.now, true); err != nil {
+		t.Fatalf("Trash sub: %v", err)
+	}
+	if err := f.store.Diagrams.TrashInFolders(f.ctx, f.ws.ID, []primitive.ObjectID{sub.ID}, batch, f.now); err != nil {
+		t.Fatalf("TrashInFolders: %v", err)
+	}
+
+	trashed, err := f.store.Folders.List(f.ctx, f.ws.ID, &repository.FolderQuery{Trash: repository.Trashed}, &repository.PageQuery{Sort: models.SortByName, Order: models.SortAsc})
+	if err != nil || len(trashed) != 1 || trashed[0].ID != folder.ID {
+		t.Fatalf("trashed folders = %v, %v; want only the root", trashed, err)
+	}
+	if _, err := f.store.Diagrams.Get(f.ctx, f.ws.ID, inside.ID, repository.Live); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("trashed diagram is live: err = %v", err)
+	}
+	live, err := f.store.Diagrams.Get(f.ctx, f.ws.ID, outside.ID, repository.Live)
+	if err != nil || live.DeletedAt != nil {
+		t.Fatalf("untouched diagram = %+v, %v", live, err)
+	}
+
+	roots, err := f.store.Folders.TrashRoots(f.ctx, f.ws.ID, nil)
+	if err != nil {
+		t.Fatalf("TrashRoots: %v", err)
+	}
+	assertIDs(t, "TrashRoots", roots, folder.ID)
+
+	if err := f.store.Folders.RestoreBatch(f.ctx, f.ws.ID, batch); err != nil {
+		t.Fatalf("RestoreBatch folders: %v", err)
+	}
+	if err := f.store.Diagrams.RestoreBatch(f.ctx, f.ws.ID, batch); err != nil {
+		t.Fatalf("RestoreBatch diagrams: %v", err)
+	}
+	restored, err := f.store.Diagrams.Get(f.ctx, f.ws.ID, inside.ID, repository.Live)
+	if err != nil || restored.FolderID == nil || *restored.FolderID != sub.ID {
+		t.Fatalf("restored diagram = %+v, %v", restored, err)
+	}
+}
+
+func testDiagramTags(t *testing.T, f *fixture) {
+	a := f.diagram(t, "a", nil, "red")
+	b := f.diagram(t, "b", nil, "red", "blue", "green")
+
+	n, err := f.store.Diagrams.AddTags(f.ctx, f.ws.ID, []primitive.ObjectID{a.ID, b.ID}, []string{"yellow"}, 3, f.now)
+	if err != nil || n != 1 {
+		t.Fatalf("AddTags = %d, %v; want 1 (b is full)", n, err)
+	}
+	if err := f.store.Diagrams.RenameTag(f.ctx, f.ws.ID, "red", "blue"); err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+
+	got, err := f.store.Diagrams.Get(f.ctx, f.ws.ID, a.ID, repository.Live)
+	if err != nil {
+		t.Fatalf("Get a: %v", err)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "yellow" || got.Tags[1] != "blue" {
+		t.Fatalf("a tags = %v, want [yellow blue]", got.Tags)
+	}
+	got, err = f.store.Diagrams.Get(f.ctx, f.ws.ID, b.ID, repository.Live)
+	if err != nil {
+		t.Fatalf("Get b: %v", err)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "blue" || got.Tags[1] != "green" {
+		t.Fatalf("b tags = %v, want [blue green]", got.Tags)
+	}
+
+	counts, err := f.store.Diagrams.CountTags(f.ctx, f.ws.ID)
+	if err != nil || counts["blue"] != 2 || counts["yellow"] != 1 || counts["red"] != 0 {
+		t.Fatalf("CountTags = %v, %v", counts, err)
+	}
+
+	filtered, err := f.store.Diagrams.List(f.ctx, f.ws.ID,
+		&repository.DiagramQuery{Filter: &models.DiagramFilter{Tags: []string{"blue", "green"}}},
+		&repository.PageQuery{Sort: models.SortByName, Order: models.SortAsc})
+	if err != nil || len(filtered) != 1 || filtered[0].ID != b.ID {
+		t.Fatalf("diagrams tagged blue and green = %v, %v", filtered, err)
+	}
+}
+
+func testSearch(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Network 100%", nil)
+	f.diagram(t, "Network map", nil)
+	if err := f.store.Diagrams.SetSearchContent(f.ctx, f.ws.ID, d.ID, "load balancer"); err != nil {
+		t.Fatalf("SetSearchContent: %v", err)
+	}
+
+	ws := []primitive.ObjectID{f.ws.ID}
+	found, err := f.store.Diagrams.Search(f.ctx, ws, []string{"100%"}, false, 10)
+	if err != nil || len(found) != 1 || found[0].ID != d.ID {
+		t.Fatalf("search for a literal %% = %v, %v", found, err)
+	}
+	found, err = f.store.Diagrams.Search(f.ctx, ws, []string{"network", "balancer"}, true, 10)
+	if err != nil || len(found) != 1 || found[0].SearchContent != "load balancer" {
+		t.Fatalf("content search = %v, %v", found, err)
+	}
+	found, err = f.store.Diagrams.Search(f.ctx, ws, []string{"balancer"}, false, 10)
+	if err != nil || len(found) != 0 {
+		t.Fatalf("search without content = %v, %v", found, err)
+	}
+}
+
+func testTemplates(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Template", nil)
+	if err := f.store.Diagrams.SetTemplateScope(f.ctx, f.ws.ID, d.ID, models.TemplateScopeInstance); err != nil {
+		t.Fatalf("SetTemplateScope: %v", err)
+	}
+	templates, err := f.store.Diagrams.ListTemplates(f.ctx, primitive.NewObjectID())
+	if err != nil || len(templates) != 1 || templates[0].ID != d.ID {
+		t.Fatalf("instance templates from another workspace = %v, %v", templates, err)
+	}
+	if err := f.store.Diagrams.SetTemplateScope(f.ctx, f.ws.ID, primitive.NewObjectID(), models.TemplateScopeWorkspace); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("SetTemplateScope of a missing diagram: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testTagDefinitions(t *testing.T, f *fixture) {
+	if err := f.store.Tags.EnsureDefined(f.ctx, f.ws.ID, []string{"beta", "alpha"}, f.now); err != nil {
+		t.Fatalf("EnsureDefined: %v", err)
+	}
+	if err := f.store.Tags.EnsureDefined(f.ctx, f.ws.ID, []string{"alpha"}, f.now); err != nil {
+		t.Fatalf("EnsureDefined again: %v", err)
+	}
+	tags, err := f.store.Tags.List(f.ctx, f.ws.ID)
+	if err != nil || len(tags) != 2 || tags[0].Name != "alpha" || tags[0].Color != models.DefaultTagColor {
+		t.Fatalf("tags = %v, %v", tags, err)
+	}
+
+	name := "beta"
+	err = f.store.Tags.Update(f.ctx, f.ws.ID, tags[0].ID, &repository.TagUpdate{Name: &name, UpdatedAt: f.now})
+	if !errors.Is(err, repository.ErrDuplicate) {
+		t.Fatalf("rename onto an existing tag: err = %v, want ErrDuplicate", err)
+	}
+	err = f.store.Tags.Update(f.ctx, f.ws.ID, primitive.NewObjectID(), &repository.TagUpdate{UpdatedAt: f.now})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("update a missing tag: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testShareLinks(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Shared", nil)
+	link := &models.ShareLink{
+		WorkspaceID: f.ws.ID, DiagramID: d.ID, TokenHash: "hash", CreatedBy: f.owner.ID, CreatedAt: f.now,
+		SnapshotPath: "shares/snapshot", SnapshotSize: 42, Version: 3,
+	}
+	if err := f.store.ShareLinks.Create(f.ctx, link); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := f.store.ShareLinks.RecordView(f.ctx, link.ID, f.now); err != nil {
+		t.Fatalf("RecordView: %v", err)
+	}
+	got, err := f.store.ShareLinks.GetByTokenHash(f.ctx, "hash")
+	if err != nil || got.ViewCount != 1 || got.LastViewedAt == nil ||
+		got.SnapshotPath != "shares/snapshot" || got.SnapshotSize != 42 || got.Version != 3 {
+		t.Fatalf("viewed link = %+v, %v", got, err)
+	}
+
+	if err := f.store.ShareLinks.Revoke(f.ctx, f.ws.ID, d.ID, link.ID, f.now); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := f.store.ShareLinks.Revoke(f.ctx, f.ws.ID, d.ID, link.ID, f.now); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Revoke twice: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testAccessAndStars(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Opened", nil)
+	for i := 0; i < 2; i++ {
+		if err := f.store.Access.RecordOpen(f.ctx, f.owner.ID, f.ws.ID, d.ID, f.now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("RecordOpen: %v", err)
+		}
+	}
+	recent, err := f.store.Access.ListRecent(f.ctx, f.owner.ID, []primitive.ObjectID{f.ws.ID}, 10)
+	if err != nil || len(recent) != 1 || recent[0].OpenCount != 2 || !recent[0].LastOpenedAt.Equal(f.now.Add(time.Minute)) {
+		t.Fatalf("recent = %v, %v", recent, err)
+	}
+
+	first, err := f.store.Stars.Star(f.ctx, &models.DiagramStar{UserID: f.owner.ID, WorkspaceID: f.ws.ID, DiagramID: d.ID, CreatedAt: f.now})
+	if err != nil {
+		t.Fatalf("Star: %v", err)
+	}
+	again, err := f.store.Stars.Star(f.ctx, &models.DiagramStar{UserID: f.owner.ID, WorkspaceID: f.ws.ID, DiagramID: d.ID, CreatedAt: f.now.Add(time.Hour)})
+	if err != nil || again.ID != first.ID || !again.CreatedAt.Equal(f.now) {
+		t.Fatalf("starring again = %+v, %v; want the original star", again, err)
+	}
+	if starred, err := f.store.Stars.IsStarred(f.ctx, f.owner.ID, f.ws.ID, d.ID); err != nil || !starred {
+		t.Fatalf("IsStarred = %v, %v; want true", starred, err)
+	}
+	if starred, err := f.store.Stars.IsStarred(f.ctx, primitive.NewObjectID(), f.ws.ID, d.ID); err != nil || starred {
+		t.Fatalf("IsStarred for another user = %v, %v; want false", starred, err)
+	}
+	if err := f.store.Stars.Unstar(f.ctx, f.owner.ID, f.ws.ID, d.ID); err != nil {
+		t.Fatalf("Unstar: %v", err)
+	}
+	if starred, err := f.store.Stars.IsStarred(f.ctx, f.owner.ID, f.ws.ID, d.ID); err != nil || starred {
+		t.Fatalf("IsStarred after Unstar = %v, %v; want false", starred, err)
+	}
+	if err := f.store.Stars.Unstar(f.ctx, f.owner.ID, f.ws.ID, d.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Unstar twice: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testAuditLog(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Audited", nil)
+	actions := []models.EventType{models.EventDiagramCreated, models.EventDiagramUpdated, models.EventDiagramDeleted}
+	var ids []primitive.ObjectID
+	for i, action := range actions {
+		entry := &models.AuditEntry{
+			WorkspaceID: f.ws.ID, ActorID: &f.owner.ID, ActorEmail: f.owner.Email, IP: "10.0.0.1",
+			Action: action, TargetType: models.TargetDiagram, TargetID: d.ID, CreatedAt: f.now.Add(time.Duration(i) * time.Minute),
+		}
+		if action == models.EventDiagramUpdated {
+			entry.Before = map[string]string{"name": "Draft"}
+			entry.After = map[string]string{"name": "Audited"}
+		}
+		if err := f.store.Audit.Append(f.ctx, entry); err != nil {
+			t.Fatalf("Append %s: %v", action, err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	// A server-side entry has no actor
+	purge := &models.AuditEntry{WorkspaceID: f.ws.ID, Action: models.EventFolderHardDeleted, TargetType: models.TargetFolder, TargetID: primitive.NewObjectID(), CreatedAt: f.now}
+	if err := f.store.Audit.Append(f.ctx, purge); err != nil {
+		t.Fatalf("Append without actor: %v", err)
+	}
+
+	page := &repository.PageQuery{Sort: models.SortByCreated, Order: models.SortDesc, Limit: 2}
+	filter := &models.AuditFilter{TargetID: &d.ID}
+	first, err := f.store.Audit.List(f.ctx, f.ws.ID, filter, page)
+	if err != nil || len(first) != 2 || first[0].ID != ids[2] || first[1].ID != ids[1] {
+		t.Fatalf("first page = %v, %v", first, err)
+	}
+	if first[1].Before["name"] != "Draft" || first[1].After["name"] != "Audited" || !sameID(first[1].ActorID, f.owner.ID) {
+		t.Fatalf("updated entry = %+v", first[1])
+	}
+	page.After = &repository.Keyset{Value: first[1].CreatedAt, ID: first[1].ID}
+	rest, err := f.store.Audit.List(f.ctx, f.ws.ID, filter, page)
+	if err != nil || len(rest) != 1 || rest[0].ID != ids[0] || rest[0].Before != nil {
+		t.Fatalf("second page = %v, %v", rest, err)
+	}
+
+	all := &repository.PageQuery{Sort: models.SortByCreated, Order: models.SortDesc}
+	deleted, err := f.store.Audit.List(f.ctx, f.ws.ID, &models.AuditFilter{Actions: []models.EventType{models.EventDiagramDeleted, models.EventFolderHardDeleted}}, all)
+	if err != nil || len(deleted) != 2 {
+		t.Fatalf("deletions = %v, %v", deleted, err)
+	}
+	byOwner, err := f.store.Audit.List(f.ctx, f.ws.ID, &models.AuditFilter{ActorID: &f.owner.ID}, all)
+	if err != nil || len(byOwner) != 3 {
+		t.Fatalf("entries by owner = %v, %v", byOwner, err)
+	}
+
+	// Entries outlive the workspace they describe
+	if err := f.store.Workspaces.Delete(f.ctx, f.ws.ID); err != nil {
+		t.Fatalf("delete workspace: %v", err)
+	}
+	kept, err := f.store.Audit.List(f.ctx, f.ws.ID, nil, all)
+	if err != nil || len(kept) != 4 {
+		t.Fatalf("entries after workspace deletion = %v, %v", kept, err)
+	}
+}
+
+func testActivity(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Payments Flow", nil)
+	other := f.diagram(t, "Checkout", nil)
+
+	created := &models.ActivityEntry{
+		WorkspaceID: f.ws.ID, ActorID: &f.owner.ID, ActorName: "Ana", Type: models.EventDiagramCreated,
+		TargetType: models.TargetDiagram, TargetID: d.ID, TargetName: d.Name, Count: 1, CreatedAt: f.now, UpdatedAt: f.now,
+	}
+	edited := &models.ActivityEntry{
+		WorkspaceID: f.ws.ID, ActorID: &f.owner.ID, ActorName: "Ana", Type: models.EventDiagramUpdated,
+		TargetType: models.TargetDiagram, TargetID: d.ID, TargetName: d.Name, Count: 1,
+		CreatedAt: f.now.Add(time.Minute), UpdatedAt: f.now.Add(time.Minute),
+	}
+	moved := &models.ActivityEntry{
+		WorkspaceID: f.ws.ID, Type: models.EventDiagramMoved, TargetType: models.TargetDiagram, TargetID: other.ID,
+		TargetName: other.Name, Details: map[string]string{"folder_name": "Archive"}, Count: 1,
+		CreatedAt: f.now.Add(2 * time.Minute), UpdatedAt: f.now.Add(2 * time.Minute),
+	}
+	for _, e := range []*models.ActivityEntry{created, edited, moved} {
+		if err := f.store.Activity.Append(f.ctx, e); err != nil {
+			t.Fatalf("Append %s: %v", e.Type, err)
+		}
+	}
+
+	latest, err := f.store.Activity.Latest(f.ctx, f.ws.ID, d.ID)
+	if err != nil || latest.ID != edited.ID {
+		t.Fatalf("Latest = %v, %v", latest, err)
+	}
+	if _, err := f.store.Activity.Latest(f.ctx, f.ws.ID, primitive.NewObjectID()); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Latest for an unknown target: err = %v", err)
+	}
+
+	// Extending the edit session moves it back to the top of the feed
+	if err := f.store.Activity.Extend(f.ctx, f.ws.ID, edited.ID, f.now.Add(3*time.Minute)); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	if err := f.store.Activity.Extend(f.ctx, primitive.NewObjectID(), edited.ID, f.now); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Extend in another workspace: err = %v", err)
+	}
+
+	page := &repository.PageQuery{Sort: models.SortByUpdated, Order: models.SortDesc, Limit: 2}
+	first, err := f.store.Activity.List(f.ctx, f.ws.ID, nil, page)
+	if err != nil || len(first) != 2 || first[0].ID != edited.ID || first[1].ID != moved.ID {
+		t.Fatalf("first page = %v, %v", first, err)
+	}
+	if first[0].Count != 2 || !first[0].UpdatedAt.Equal(f.now.Add(3*time.Minute)) {
+		t.Fatalf("extended entry = %+v", first[0])
+	}
+	if first[1].ActorID != nil || first[1].Details["folder_name"] != "Archive" {
+		t.Fatalf("moved entry = %+v", first[1])
+	}
+	page.After = &repository.Keyset{Value: first[1].UpdatedAt, ID: first[1].ID}
+	rest, err := f.store.Activity.List(f.ctx, f.ws.ID, nil, page)
+	if err != nil || len(rest) != 1 || rest[0].ID != created.ID || rest[0].Details != nil {
+		t.Fatalf("second page = %v, %v", rest, err)
+	}
+
+	all := &repository.PageQuery{Sort: models.SortByUpdated, Order: models.SortDesc}
+	forDiagram, err := f.store.Activity.List(f.ctx, f.ws.ID, &models.ActivityFilter{TargetID: &d.ID}, all)
+	if err != nil || len(forDiagram) != 2 {
+		t.Fatalf("diagram feed = %v, %v", forDiagram, err)
+	}
+	byOwner, err := f.store.Activity.List(f.ctx, f.ws.ID, &models.ActivityFilter{ActorID: &f.owner.ID}, all)
+	if err != nil || len(byOwner) != 2 {
+		t.Fatalf("feed by owner = %v, %v", byOwner, err)
+	}
+
+	if err := f.store.Activity.DeleteAllForWorkspace(f.ctx, f.ws.ID); err != nil {
+		t.Fatalf("DeleteAllForWorkspace: %v", err)
+	}
+	if left, err := f.store.Activity.List(f.ctx, f.ws.ID, nil, all); err != nil || len(left) != 0 {
+		t.Fatalf("feed after deletion = %v, %v", left, err)
+	}
+}
+
+func testComments(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Review", nil)
+	comment := func(body string, thread *models.Comment, anchor *models.CommentAnchor, at time.Time) *models.Comment {
+		t.Helper()
+		c := &models.Comment{
+			WorkspaceID: f.ws.ID, DiagramID: d.ID, AuthorID: f.owner.ID, AuthorName: "Owner", Body: body,
+			Anchor: anchor, CreatedAt: at, UpdatedAt: at,
+		}
+		if thread != nil {
+			c.ThreadID = &thread.ID
+		}
+		if err := f.store.Comments.Create(f.ctx, c); err != nil {
+			t.Fatalf("Create %q: %v", body, err)
+		}
+		return c
+	}
+
+	onShape := comment("On the gateway", nil, &models.CommentAnchor{ShapeID: "shape-1", X: 10.5, Y: -4}, f.now)
+	onCanvas := comment("Over here", nil, &models.CommentAnchor{X: 0, Y: 0}, f.now.Add(time.Minute))
+	general := comment("Overall looks good", nil, nil, f.now.Add(2*time.Minute))
+	reply := comment("Agreed", onShape, nil, f.now.Add(3*time.Minute))
+	comment("Fixed", onShape, nil, f.now.Add(4*time.Minute))
+
+	got, err := f.store.Comments.Get(f.ctx, f.ws.ID, d.ID, onCanvas.ID)
+	if err != nil || got.Anchor == nil || got.Anchor.ShapeID != "" || got.Anchor.X != 0 || !got.IsThread() {
+		t.Fatalf("Get canvas thread = %+v, %v", got, err)
+	}
+	if got, err := f.store.Comments.Get(f.ctx, f.ws.ID, d.ID, general.ID); err != nil || got.Anchor != nil {
+		t.Fatalf("Get unanchored thread = %+v, %v", got, err)
+	}
+	if _, err := f.store.Comments.Get(f.ctx, f.ws.ID, primitive.NewObjectID(), onShape.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get on another diagram: err = %v", err)
+	}
+
+	page := &repository.PageQuery{Sort: models.SortByCreated, Order: models.SortAsc, Limit: 2}
+	first, err := f.store.Comments.ListThreads(f.ctx, f.ws.ID, d.ID, nil, page)
+	if err != nil || len(first) != 2 || first[0].ID != onShape.ID || first[1].ID != onCanvas.ID {
+		t.Fatalf("first page = %v, %v", first, err)
+	}
+	page.After = &repository.Keyset{Value: first[1].CreatedAt, ID: first[1].ID}
+	rest, err := f.store.Comments.ListThreads(f.ctx, f.ws.ID, d.ID, nil, page)
+	if err != nil || len(rest) != 1 || rest[0].ID != general.ID {
+		t.Fatalf("second page = %v, %v", rest, err)
+	}
+
+	replies, err := f.store.Comments.ListReplies(f.ctx, f.ws.ID, d.ID, []primitive.ObjectID{onShape.ID, onCanvas.ID})
+	if err != nil || len(replies) != 2 || replies[0].ID != reply.ID || !sameID(replies[0].ThreadID, onShape.ID) {
+		t.Fatalf("ListReplies = %v, %v", replies, err)
+	}
+	anchored, err := f.store.Comments.ListShapeAnchored(f.ctx, f.ws.ID, d.ID)
+	if err != nil || len(anchored) != 1 || anchored[0].ID != onShape.ID {
+		t.Fatalf("ListShapeAnchored = %v, %v", anchored, err)
+	}
+
+	// Editing and resolving
+	body, resolved := "On the API gateway", true
+	edited := f.now.Add(5 * time.Minute)
+	if err := f.store.Comments.Update(f.ctx, f.ws.ID, d.ID, onShape.ID, &repository.CommentUpdate{Body: &body, EditedAt: &edited, UpdatedAt: edited}); err != nil {
+		t.Fatalf("Update body: %v", err)
+	}
+	if err := f.store.Comments.Update(f.ctx, f.ws.ID, d.ID, onShape.ID, &repository.CommentUpdate{Resolved: &resolved, ResolvedBy: f.owner.ID, UpdatedAt: edited}); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	got, err = f.store.Comments.Get(f.ctx, f.ws.ID, d.ID, onShape.ID)
+	if err != nil || got.Body != body || got.EditedAt == nil || got.ResolvedAt == nil || !sameID(got.ResolvedBy, f.owner.ID) || !got.UpdatedAt.Equal(edited) {
+		t.Fatalf("edited and resolved = %+v, %v", got, err)
+	}
+
+	all := &repository.PageQuery{Sort: models.SortByUpdated, Order: models.SortDesc}
+	open, err := f.store.Comments.ListThreads(f.ctx, f.ws.ID, d.ID, &models.CommentFilter{Resolved: new(bool)}, all)
+	if err != nil || len(open) != 2 {
+		t.Fatalf("open threads = %v, %v", open, err)
+	}
+	byShape, err := f.store.Comments.ListThreads(f.ctx, f.ws.ID, d.ID, &models.CommentFilter{Resolved: &resolved, ShapeID: "shape-1"}, all)
+	if err != nil || len(byShape) != 1 || byShape[0].ID != onShape.ID {
+		t.Fatalf("resolved threads on shape-1 = %v, %v", byShape, err)
+	}
+
+	resolved = false
+	if err := f.store.Comments.Update(f.ctx, f.ws.ID, d.ID, onShape.ID, &repository.CommentUpdate{Resolved: &resolved, UpdatedAt: edited}); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	if got, err := f.store.Comments.Get(f.ctx, f.ws.ID, d.ID, onShape.ID); err != nil || got.ResolvedAt != nil || got.ResolvedBy != nil {
+		t.Fatalf("reopened = %+v, %v", got, err)
+	}
+
+	// Falling back to coordinates once the shape is gone
+	if err := f.store.Comments.SetAnchor(f.ctx, f.ws.ID, d.ID, onShape.ID, models.CommentAnchor{X: 40, Y: 80}); err != nil {
+		t.Fatalf("SetAnchor: %v", err)
+	}
+	got, err = f.store.Comments.Get(f.ctx, f.ws.ID, d.ID, onShape.ID)
+	if err != nil || got.Anchor == nil || got.Anchor.ShapeID != "" || got.Anchor.X != 40 || !got.UpdatedAt.Equal(edited) {
+		t.Fatalf("detached = %+v, %v", got, err)
+	}
+	if anchored, err := f.store.Comments.ListShapeAnchored(f.ctx, f.ws.ID, d.ID); err != nil || len(anchored) != 0 {
+		t.Fatalf("ListShapeAnchored after detaching = %v, %v", anchored, err)
+	}
+
+	// Deleting a thread removes its replies
+	if err := f.store.Comments.Delete(f.ctx, f.ws.ID, d.ID, onShape.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := f.store.Comments.Get(f.ctx, f.ws.ID, d.ID, reply.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("reply after deleting its thread: err = %v", err)
+	}
+	if err := f.store.Comments.Delete(f.ctx, f.ws.ID, d.ID, onShape.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Delete twice: err = %v", err)
+	}
+
+	if err := f.store.Comments.DeleteForDiagram(f.ctx, f.ws.ID, d.ID); err != nil {
+		t.Fatalf("DeleteForDiagram: %v", err)
+	}
+	if left, err := f.store.Comments.ListThreads(f.ctx, f.ws.ID, d.ID, nil, all); err != nil || len(left) != 0 {
+		t.Fatalf("threads after DeleteForDiagram = %v, %v", left, err)
+	}
+}
+
+func testMentions(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Flow", nil)
+	alice := f.user(t, "alice@example.com", "Alice")
+	bob := f.user(t, "bob@example.com", "Bob")
+	comment := primitive.NewObjectID()
+
+	replace := func(source models.MentionSource, sourceID primitive.ObjectID, at time.Time, users ...primitive.ObjectID) string {
+		t.Helper()
+		added, err := f.store.Mentions.Replace(f.ctx, f.ws.ID, d.ID, source, sourceID, users, at)
+		if err != nil {
+			t.Fatalf("Replace: %v", err)
+		}
+		var ids []string
+		for _, id := range added {
+			ids = append(ids, id.Hex())
+		}
+		return strings.Join(ids, ",")
+	}
+
+	if got := replace(models.MentionInDescription, d.ID, f.now, alice.ID); got != alice.ID.Hex() {
+		t.Fatalf("first Replace added %s, want alice", got)
+	}
+	// Only users the source did not mention yet are reported; the rest are dropped
+	if got := replace(models.MentionInDescription, d.ID, f.now.Add(time.Second), alice.ID, bob.ID); got != bob.ID.Hex() {
+		t.Fatalf("second Replace added %s, want bob", got)
+	}
+	if got := replace(models.MentionInDescription, d.ID, f.now.Add(2*time.Second), bob.ID); got != "" {
+		t.Fatalf("third Replace added %s, want none", got)
+	}
+	replace(models.MentionInComment, comment, f.now.Add(3*time.Second), alice.ID)
+
+	mentions, err := f.store.Mentions.ListForDiagram(f.ctx, f.ws.ID, d.ID)
+	if err != nil || len(mentions) != 2 {
+		t.Fatalf("ListForDiagram = %v, %v", mentions, err)
+	}
+	if m := mentions[0]; m.UserID != bob.ID || m.Source != models.MentionInDescription || m.SourceID != d.ID || !m.CreatedAt.Equal(f.now.Add(time.Second)) {
+		t.Fatalf("first mention = %+v", m)
+	}
+	if m := mentions[1]; m.UserID != alice.ID || m.Source != models.MentionInComment || m.SourceID != comment {
+		t.Fatalf("second mention = %+v", m)
+	}
+
+	if err := f.store.Mentions.DeleteForSources(f.ctx, f.ws.ID, models.MentionInComment, []primitive.ObjectID{comment}); err != nil {
+		t.Fatalf("DeleteForSources: %v", err)
+	}
+	if got := replace(models.MentionInDescription, d.ID, f.now, nil...); got != "" {
+		t.Fatalf("clearing Replace added %s", got)
+	}
+	if mentions, err := f.store.Mentions.ListForDiagram(f.ctx, f.ws.ID, d.ID); err != nil || len(mentions) != 0 {
+		t.Fatalf("mentions after clearing = %v, %v", mentions, err)
+	}
+
+	replace(models.MentionInContent, d.ID, f.now, alice.ID)
+	if err := f.store.Mentions.DeleteForDiagram(f.ctx, f.ws.ID, d.ID); err != nil {
+		t.Fatalf("DeleteForDiagram: %v", err)
+	}
+	if mentions, err := f.store.Mentions.ListForDiagram(f.ctx, f.ws.ID, d.ID); err != nil || len(mentions) != 0 {
+		t.Fatalf("mentions after DeleteForDiagram = %v, %v", mentions, err)
+	}
+}
+
+func testNotifications(t *testing.T, f *fixture) {
+	d := f.diagram(t, "Payments Flow", nil)
+	other := f.user(t, "other@example.com", "Other")
+
+	invite := &models.Notification{
+		UserID: f.owner.ID, WorkspaceID: f.ws.ID, Type: models.NotificationInvite, TargetType: models.TargetInvite,
+		TargetID: primitive.NewObjectID(), Message: "invited", Count: 1, CreatedAt: f.now, UpdatedAt: f.now,
+	}
+	edited := &models.Notification{
+		UserID: f.owner.ID, WorkspaceID: f.ws.ID, Type: models.NotificationDiagramEdited, ActorID: &other.ID,
+		ActorName: "Other", TargetType: models.TargetDiagram, TargetID: d.ID, Message: "edited", Count: 1,
+		CreatedAt: f.now.Add(time.Minute), UpdatedAt: f.now.Add(time.Minute),
+	}
+	theirs := &models.Notification{
+		UserID: other.ID, WorkspaceID: f.ws.ID, Type: models.NotificationRoleChanged, TargetType: models.TargetMember,
+		TargetID: other.ID, Message: "role", Count: 1, CreatedAt: f.now, UpdatedAt: f.now,
+	}
+	for _, n := range []*models.Notification{invite, edited, theirs} {
+		if err := f.store.Notifications.Create(f.ctx, n); err != nil {
+			t.Fatalf("Create %s: %v", n.Type, err)
+		}
+	}
+
+	found, err := f.store.Notifications.FindUnread(f.ctx, f.owner.ID, models.NotificationDiagramEdited, d.ID)
+	if err != nil || found.ID != edited.ID || !sameID(found.ActorID, other.ID) {
+		t.Fatalf("FindUnread = %+v, %v", found, err)
+	}
+	if _, err := f.store.Notifications.FindUnread(f.ctx, other.ID, models.NotificationDiagramEdited, d.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("FindUnread for another user: err = %v", err)
+	}
+
+	// Bumping moves the notification forward and names the latest actor
+	if err := f.store.Notifications.Bump(f.ctx, f.owner.ID, edited.ID, nil, "Someone", "edited twice", f.now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Bump: %v", err)
+	}
+	if err := f.store.Notifications.Bump(f.ctx, other.ID, edited.ID, nil, "", "", f.now); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Bump another user's notification: err = %v", err)
+	}
+
+	page := &repository.PageQuery{Sort: models.SortByUpdated, Order: models.SortDesc, Limit: 1}
+	first, err := f.store.Notifications.List(f.ctx, f.owner.ID, nil, page)
+	if err != nil || len(first) != 1 || first[0].ID != edited.ID {
+		t.Fatalf("first page = %v, %v", first, err)
+	}
+	if first[0].Count != 2 || first[0].ActorID != nil || first[0].Message != "edited twice" ||
+		!first[0].UpdatedAt.Equal(f.now.Add(2*time.Minute)) {
+		t.Fatalf("bumped notification = %+v", first[0])
+	}
+	page.After = &repository.Keyset{Value: first[0].UpdatedAt, ID: first[0].ID}
+	rest, err := f.store.Notifications.List(f.ctx, f.owner.ID, nil, page)
+	if err != nil || len(rest) != 1 || rest[0].ID != invite.ID {
+		t.Fatalf("second page = %v, %v", rest, err)
+	}
+
+	if count, err := f.store.Notifications.CountUnread(f.ctx, f.owner.ID); err != nil || count != 2 {
+		t.Fatalf("CountUnread = %d, %v", count, err)
+	}
+	marked, err := f.store.Notifications.MarkRead(f.ctx, f.owner.ID, []primitive.ObjectID{edited.ID, theirs.ID}, f.now)
+	if err != nil || marked != 1 {
+		t.Fatalf("MarkRead = %d, %v", marked, err)
+	}
+	if _, err := f.store.Notifications.FindUnread(f.ctx, f.owner.ID, models.NotificationDiagramEdited, d.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("FindUnread after MarkRead: err = %v", err)
+	}
+
+	all := &repository.PageQuery{Sort: models.SortByUpdated, Order: models.SortDesc}
+	unread, err := f.store.Notifications.List(f.ctx, f.owner.ID, &models.NotificationFilter{UnreadOnly: true}, all)
+	if err != nil || len(unread) != 1 || unread[0].ID != invite.ID || unread[0].ReadAt != nil {
+		t.Fatalf("unread = %v, %v", unread, err)
+	}
+	if marked, err := f.store.Notifications.MarkAllRead(f.ctx, f.owner.ID, f.now); err != nil || marked != 1 {
+		t.Fatalf("MarkAllRead = %d, %v", marked, err)
+	}
+	read, err := f.store.Notifications.List(f.ctx, f.owner.ID, nil, all)
+	if err != nil || len(read) != 2 || read[0].ReadAt == nil || !read[0].ReadAt.Equal(f.now) {
+		t.Fatalf("read notifications = %v, %v", read, err)
+	}
+	if count, err := f.store.Notifications.CountUnread(f.ctx, other.ID); err != nil || count != 1 {
+		t.Fatalf("other user's unread count = %d, %v", count, err)
+	}
+
+	if err := f.store.Notifications.DeleteAllForWorkspace(f.ctx, f.ws.ID); err != nil {
+		t.Fatalf("DeleteAllForWorkspace: %v", err)
+	}
+	if left, err := f.store.Notifications.List(f.ctx, other.ID, nil, all); err != nil || len(left) != 0 {
+		t.Fatalf("notifications after deletion = %v, %v", left, err)
+	}
+}
+
+func testNotificationDigests(t *testing.T, f *fixture) {
+	other := f.user(t, "other@example.com", "Other")
+	notify := func(user primitive.ObjectID, updated time.Time) *models.Notification {
+		n := &models.Notification{
+			UserID: user, WorkspaceID: f.ws.ID, Type: models.NotificationDiagramEdited, TargetType: models.TargetDiagram,
+			TargetID: primitive.NewObjectID(), Message: "edited", Count: 1, CreatedAt: updated, UpdatedAt: updated,
+		}
+		if err := f.store.Notifications.Create(f.ctx, n); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return n
+	}
+	newer := notify(f.owner.ID, f.now.Add(-time.Minute))
+	older := notify(f.owner.ID, f.now.Add(-2*time.Minute))
+	theirs := notify(other.ID, f.now.Add(-time.Minute))
+	read := notify(f.owner.ID, f.now.Add(-time.Minute))
+	recent := notify(f.owner.ID, f.now)
+	if _, err := f.store.Notifications.MarkRead(f.ctx, f.owner.ID, []primitive.ObjectID{read.ID}, f.now); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+
+	// Pending notifications come grouped by user, oldest first, skipping read and recent ones
+	pending, err := f.store.Notifications.PendingDigest(f.ctx, nil, f.now, 10)
+	if err != nil || len(pending) != 3 {
+		t.Fatalf("PendingDigest = %v, %v", pending, err)
+	}
+	byUser := map[primitive.ObjectID][]primitive.ObjectID{}
+	for i, n := range pending {
+		if i > 0 && n.UserID != pending[i-1].UserID && byUser[n.UserID] != nil {
+			t.Fatalf("PendingDigest does not group by user: %v", pending)
+		}
+		byUser[n.UserID] = append(byUser[n.UserID], n.ID)
+	}
+	if ids := byUser[f.owner.ID]; len(ids) != 2 || ids[0] != older.ID || ids[1] != newer.ID {
+		t.Fatalf("owner's pending = %v", ids)
+	}
+	if ids := byUser[other.ID]; len(ids) != 1 || ids[0] != theirs.ID {
+		t.Fatalf("other's pending = %v", ids)
+	}
+	if limited, err := f.store.Notifications.PendingDigest(f.ctx, nil, f.now, 1); err != nil || len(limited) != 1 {
+		t.Fatalf("PendingDigest with limit = %v, %v", limited, err)
+	}
+	if mine, err := f.store.Notifications.PendingDigest(f.ctx, &other.ID, f.now, 0); err != nil || len(mine) != 1 || mine[0].ID != theirs.ID {
+		t.Fatalf("PendingDigest for one user = %v, %v", mine, err)
+	}
+
+	if err := f.store.Notifications.MarkDigested(f.ctx, []primitive.ObjectID{older.ID, newer.ID, theirs.ID}, f.now); err != nil {
+		t.Fatalf("MarkDigested: %v", err)
+	}
+	if err := f.store.Notifications.MarkDigested(f.ctx, nil, f.now); err != nil {
+		t.Fatalf("MarkDigested with no IDs: %v", err)
+	}
+	if pending, err := f.store.Notifications.PendingDigest(f.ctx, nil, f.now.Add(time.Minute), 10); err != nil ||
+		len(pending) != 1 || pending[0].ID != recent.ID {
+		t.Fatalf("PendingDigest after MarkDigested = %v, %v", pending, err)
+	}
+
+	// A bumped notification has news, so it is due for the next digest
+	if err := f.store.Notifications.Bump(f.ctx, f.owner.ID, newer.ID, nil, "Someone", "edited twice", f.now); err != nil {
+		t.Fatalf("Bump: %v", err)
+	}
+	pending, err = f.store.Notifications.PendingDigest(f.ctx, nil, f.now.Add(time.Minute), 10)
+	if err != nil || len(pending) != 2 || pending[0].ID != recent.ID && pending[1].ID != recent.ID {
+		t.Fatalf("PendingDigest after Bump = %v, %v", pending, err)
+	}
+	for _, n := range pending {
+		if n.ID == newer.ID && n.Message != "edited twice" {
+			t.Fatalf("bumped notification = %+v", n)
+		}
+	}
+}
+
+func testOutbox(t *testing.T, f *fixture) {
+	email := func(to string, due time.Time) *repository.OutboxEmail {
+		e := &repository.OutboxEmail{
+			Template: "invite", To: to, Subject: "Hi", Text: "text", HTML: "<p>html</p>",
+			Status: repository.OutboxPending, NextAttemptAt: due, CreatedAt: f.now,
+		}
+		if err := f.store.Outbox.Enqueue(f.ctx, e); err != nil {
+			t.Fatalf("Enqueue %s: %v", to, err)
+		}
+		return e
+	}
+	later := email("later@example.com", f.now.Add(time.Minute))
+	first := email("first@example.com", f.now.Add(-time.Minute))
+	second := email("second@example.com", f.now)
+
+	due, err := f.store.Outbox.Due(f.ctx, f.now, 10)
+	if err != nil || len(due) != 2 || due[0].ID != first.ID || due[1].ID != second.ID {
+		t.Fatalf("Due = %v, %v", due, err)
+	}
+	if due[0].To != "first@example.com" || due[0].HTML != "<p>html</p>" || due[0].SentAt != nil {
+		t.Fatalf("due email = %+v", due[0])
+	}
+	if limited, err := f.store.Outbox.Due(f.ctx, f.now, 1); err != nil || len(limited) != 1 {
+		t.Fatalf("Due with limit = %v, %v", limited, err)
+	}
+
+	// A claimed email is no longer due, so a second claim loses
+	if err := f.store.Outbox.Claim(f.ctx, first.ID, f.now, f.now.Add(time.Minute)); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if err := f.store.Outbox.Claim(f.ctx, first.ID, f.now, f.now.Add(time.Minute)); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("second Claim: err = %v", err)
+	}
+	if err := f.store.Outbox.Claim(f.ctx, later.ID, f.now, f.now.Add(time.Minute)); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Claim before due: err = %v", err)
+	}
+
+	if err := f.store.Outbox.MarkSent(f.ctx, first.ID, 1, f.now); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	if err := f.store.Outbox.MarkRetry(f.ctx, second.ID, 1, f.now.Add(2*time.Minute), "connection refused"); err != nil {
+		t.Fatalf("MarkRetry: %v", err)
+	}
+	if err := f.store.Outbox.MarkFailed(f.ctx, later.ID, 8, "mailbox unavailable"); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if err := f.store.Outbox.MarkSent(f.ctx, primitive.NewObjectID(), 1, f.now); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("MarkSent unknown email: err = %v", err)
+	}
+
+	due, err = f.store.Outbox.Due(f.ctx, f.now.Add(time.Hour), 10)
+	if err != nil || len(due) != 1 || due[0].ID != second.ID {
+		t.Fatalf("Due after delivery = %v, %v", due, err)
+	}
+	if due[0].Attempts != 1 || due[0].LastError != "connection refused" || !due[0].NextAttemptAt.Equal(f.now.Add(2*time.Minute)) {
+		t.Fatalf("retried email = %+v", due[0])
+	}
+
+	if n, err := f.store.Outbox.DeleteSentBefore(f.ctx, f.now); err != nil || n != 0 {
+		t.Fatalf("DeleteSentBefore now = %d, %v", n, err)
+	}
+	if n, err := f.store.Outbox.DeleteSentBefore(f.ctx, f.now.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("DeleteSentBefore = %d, %v", n, err)
+	}
+}
+
+func testWebhooks(t *testing.T, f *fixture) {
+	create := func(url string, enabled bool, events ...models.EventType) *models.Webhook {
+		w := &models.Webhook{
+			WorkspaceID: f.ws.ID, URL: url, Events: events, EncryptedSecret: []byte{1, 2, 3}, Enabled: enabled,
+			CreatedBy: f.owner.ID, CreatedAt: f.now, UpdatedAt: f.now,
+		}
+		if err := f.store.Webhooks.Create(f.ctx, w); err != nil {
+			t.Fatalf("Create %s: %v", url, err)
+		}
+		f.now = f.now.Add(time.Second)
+		return w
+	}
+	ci := create("https://ci.example.com/hook", true, models.EventDiagramUpdated, models.EventDiagramCreated)
+	chat := create("https://chat.example.com/hook", true, models.EventCommentCreated)
+	paused := create("https://paused.example.com/hook", false, models.EventDiagramUpdated)
+
+	got, err := f.store.Webhooks.Get(f.ctx, f.ws.ID, ci.ID)
+	if err != nil || got.URL != ci.URL || len(got.Events) != 2 || string(got.EncryptedSecret) != "\x01\x02\x03" || !got.Enabled {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if _, err := f.store.Webhooks.Get(f.ctx, primitive.NewObjectID(), ci.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get in another workspace: err = %v", err)
+	}
+
+	all, err := f.store.Webhooks.List(f.ctx, f.ws.ID)
+	if err != nil || len(all) != 3 || all[0].ID != ci.ID || all[2].ID != paused.ID {
+		t.Fatalf("List = %v, %v", all, err)
+	}
+	subscribed, err := f.store.Webhooks.ListSubscribed(f.ctx, f.ws.ID, models.EventDiagramUpdated)
+	if err != nil || len(subscribed) != 1 || subscribed[0].ID != ci.ID {
+		t.Fatalf("ListSubscribed = %v, %v", subscribed, err)
+	}
+
+	// Failures disable the webhook once, when they reach the limit; a success starts the count over
+	for i, want := range []bool{false, true, false} {
+		disabled, err := f.store.Webhooks.RecordFailure(f.ctx, chat.ID, 2, "HTTP 500", f.now)
+		if err != nil || disabled != want {
+			t.Fatalf("RecordFailure %d = %v, %v", i+1, disabled, err)
+		}
+	}
+	got, _ = f.store.Webhooks.Get(f.ctx, f.ws.ID, chat.ID)
+	if got.Enabled || got.ConsecutiveFailures != 3 || got.DisabledReason != "HTTP 500" {
+		t.Fatalf("disabled webhook = %+v", got)
+	}
+	if subscribed, _ := f.store.Webhooks.ListSubscribed(f.ctx, f.ws.ID, models.EventCommentCreated); len(subscribed) != 0 {
+		t.Fatalf("ListSubscribed after disabling = %v", subscribed)
+	}
+	if _, err := f.store.Webhooks.RecordFailure(f.ctx, ci.ID, 10, "timeout", f.now); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	if err := f.store.Webhooks.RecordSuccess(f.ctx, ci.ID); err != nil {
+		t.Fatalf("RecordSuccess: %v", err)
+	}
+	if got, _ := f.store.Webhooks.Get(f.ctx, f.ws.ID, ci.ID); got.ConsecutiveFailures != 0 {
+		t.Fatalf("failures after success = %d", got.ConsecutiveFailures)
+	}
+
+	// Enabling clears the failures
+	enabled, url := true, "https://chat.example.com/v2"
+	err = f.store.Webhooks.Update(f.ctx, f.ws.ID, chat.ID, &repository.WebhookUpdate{
+		URL: &url, Enabled: &enabled, Events: []models.EventType{models.EventCommentCreated, models.EventMemberAdded},
+		EncryptedSecret: []byte{9}, UpdatedAt: f.now,
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ = f.store.Webhooks.Get(f.ctx, f.ws.ID, chat.ID)
+	if !got.Enabled || got.ConsecutiveFailures != 0 || got.DisabledReason != "" || got.URL != url ||
+		len(got.Events) != 2 || string(got.EncryptedSecret) != "\x09" || !got.UpdatedAt.Equal(f.now) {
+		t.Fatalf("updated webhook = %+v", got)
+	}
+	if err := f.store.Webhooks.Update(f.ctx, primitive.NewObjectID(), chat.ID, &repository.WebhookUpdate{UpdatedAt: f.now}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update in another workspace: err = %v", err)
+	}
+
+	if err := f.store.Webhooks.Delete(f.ctx, f.ws.ID, paused.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := f.store.Webhooks.Delete(f.ctx, f.ws.ID, paused.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("second Delete: err = %v", err)
+	}
+	if err := f.store.Webhooks.DeleteAllForWorkspace(f.ctx, f.ws.ID); err != nil {
+		t.Fatalf("DeleteAllForWorkspace: %v", err)
+	}
+	if all, _ := f.store.Webhooks.List(f.ctx, f.ws.ID); len(all) != 0 {
+		t.Fatalf("List after DeleteAllForWorkspace = %v", all)
+	}
+}
+
+func testWebhookDeliveries(t *testing.T, f *fixture) {
+	webhook := &models.Webhook{
+		WorkspaceID: f.ws.ID, URL: "https://ci.example.com/hook", Events: []models.EventType{models.EventDiagramUpdated},
+		EncryptedSecret: []byte{1}, Enabled: true, CreatedBy: f.owner.ID, CreatedAt: f.now, UpdatedAt: f.now,
+	}
+	if err := f.store.Webhooks.Create(f.ctx, webhook); err != nil {
+		t.Fatalf("Create webhook: %v", err)
+	}
+
+	deliver := func(due time.Time) *models.WebhookDelivery {
+		d := &models.WebhookDelivery{
+			WorkspaceID: f.ws.ID, WebhookID: webhook.ID, EventType: models.EventDiagramUpdated, Payload: `{"type":"diagram.updated"}`,
+			Status: models.WebhookDeliveryPending, NextAttemptAt: due, CreatedAt: f.now,
+		}
+		if err := f.store.Deliveries.Create(f.ctx, d); err != nil {
+			t.Fatalf("Create delivery: %v", err)
+		}
+		f.now = f.now.Add(time.Second)
+		return d
+	}
+	first := deliver(f.now.Add(-time.Minute))
+	second := deliver(f.now)
+	later := deliver(f.now.Add(time.Hour))
+
+	due, err := f.store.Deliveries.Due(f.ctx, f.now, 10)
+	if err != nil || len(due) != 2 || due[0].ID != first.ID || due[1].ID != second.ID {
+		t.Fatalf("Due = %v, %v", due, err)
+	}
+	if due[0].Payload != first.Payload || due[0].EventType != models.EventDiagramUpdated || due[0].CompletedAt != nil {
+		t.Fatalf("due delivery = %+v", due[0])
+	}
+
+	if err := f.store.Deliveries.Claim(f.ctx, first.ID, f.now, f.now.Add(time.Minute)); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if err := f.store.Deliveries.Claim(f.ctx, first.ID, f.now, f.now.Add(time.Minute)); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("second Claim: err = %v", err)
+	}
+
+	completed := f.now
+	err = f.store.Deliveries.RecordAttempt(f.ctx, first.ID, &repository.WebhookAttempt{
+		Status: models.WebhookDeliverySucceeded, Attempts: 1, NextAttemptAt: f.now,
+		ResponseStatus: 204, CompletedAt: &completed,
+	})
+	if err != nil {
+		t.Fatalf("RecordAttempt: %v", err)
+	}
+	err = f.store.Deliveries.RecordAttempt(f.ctx, second.ID, &repository.WebhookAttempt{
+		Status: models.WebhookDeliveryPending, Attempts: 1, NextAttemptAt: f.now.Add(time.Minute),
+		ResponseStatus: 502, ResponseBody: "bad gateway", LastError: "HTTP 502",
+	})
+	if err != nil {
+		t.Fatalf("RecordAttempt retry: %v", err)
+	}
+
+	got, err := f.store.Deliveries.Get(f.ctx, f.ws.ID, webhook.ID, second.ID)
+	if err != nil || got.Attempts != 1 || got.ResponseStatus != 502 || got.ResponseBody != "bad gateway" || got.LastError != "HTTP 502" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if _, err := f.store.Deliveries.Get(f.ctx, f.ws.ID, primitive.NewObjectID(), second.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get of another webhook: err = %v", err)
+	}
+
+	// A redelivery points at the original
+	redelivery := &models.WebhookDelivery{
+		WorkspaceID: f.ws.ID, WebhookID: webhook.ID, EventType: first.EventType, Payload: first.Payload,
+		Status: models.WebhookDeliveryPending, NextAttemptAt: f.now, RedeliveryOf: &first.ID, CreatedAt: f.now,
+	}
+	if err := f.store.Deliveries.Create(f.ctx, redelivery); err != nil {
+		t.Fatalf("Create redelivery: %v", err)
+	}
+	if got, _ := f.store.Deliveries.Get(f.ctx, f.ws.ID, webhook.ID, redelivery.ID); !sameID(got.RedeliveryOf, first.ID) {
+		t.Fatalf("redelivery = %+v", got)
+	}
+
+	page := &repository.PageQuery{Sort: models.SortByCreated, Order: models.SortDesc, Limit: 10}
+	listed, err := f.store.Deliveries.List(f.ctx, f.ws.ID, webhook.ID, nil, page)
+	if err != nil || len(listed) != 4 || listed[0].ID != redelivery.ID || listed[3].ID != first.ID {
+		t.Fatalf("List = %v, %v", listed, err)
+	}
+	listed, err = f.store.Deliveries.List(f.ctx, f.ws.ID, webhook.ID,
+		&models.WebhookDeliveryFilter{Status: models.WebhookDeliverySucceeded}, page)
+	if err != nil || len(listed) != 1 || listed[0].ID != first.ID || listed[0].CompletedAt == nil {
+		t.Fatalf("List succeeded = %v, %v", listed, err)
+	}
+
+	if n, err := f.store.Deliveries.DeleteCompletedBefore(f.ctx, completed.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("DeleteCompletedBefore = %d, %v", n, err)
+	}
+	if err := f.store.Deliveries.DeleteForWebhook(f.ctx, f.ws.ID, webhook.ID); err != nil {
+		t.Fatalf("DeleteForWebhook: %v", err)
+	}
+	if due, _ := f.store.Deliveries.Due(f.ctx, later.NextAttemptAt, 10); len(due) != 0 {
+		t.Fatalf("Due after DeleteForWebhook = %v", due)
+	}
+}
+
+func sameID(a *primitive.ObjectID, id primitive.ObjectID) bool {
+	return a != nil && *a == id
+}
+
+func assertIDs(t *testing.T, what string, got []primitive.ObjectID, want ...primitive.ObjectID) {
+	t.Helper()
+	hex := func(ids []primitive.ObjectID) []string {
+		s := make([]string, len(ids))
+		for i, id := range ids {
+			s[i] = id.Hex()
+		}
+		sort.Strings(s)
+		return s
+	}
+	g, w := hex(got), hex(want)
+	if len(g) != len(w) {
+		t.Fatalf("%s = %v, want %v", what, g, w)
+	}
+	for i := range g {
+		if g[i] != w[i] {
+			t.Fatalf("%s = %v, want %v", what, g, w)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLinkRepository persists public share links. Token hashes are unique.
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *models.ShareLink) error
+	GetByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error)
+	// ListForDiagram returns the links of a diagram, newest first
+	ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.ShareLink, error)
+	// Revoke revokes an active link of a diagram; ErrNotFound if there is none
+	Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error
+	// RecordView counts a view of a link
+	RecordView(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// EmbedTokenRepository persists embed tokens. Token hashes are unique.
+type EmbedTokenRepository interface {
+	Create(ctx context.Context, token *models.EmbedToken) error
+	GetByTokenHash(ctx context.Context, hash string) (*models.EmbedToken, error)
+	// ListForDiagram returns the tokens of a diagram, newest first
+	ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.EmbedToken, error)
+	// Revoke revokes an active token of a diagram; ErrNotFound if there is none
+	Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error
+	// RecordUse marks a token as used
+	RecordUse(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// AccessRepository persists when each user last opened each diagram
+type AccessRepository interface {
+	// RecordOpen upserts the user's access record of a diagram and counts the open
+	RecordOpen(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID, at time.Time) error
+	// ListRecent returns up to limit of the user's access records in the given workspaces, most recent first
+	ListRecent(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID, limit int64) ([]*models.DiagramAccess, error)
+	DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// StarRepository persists starred diagrams. A user stars a diagram at most once.
+type StarRepository interface {
+	// Star stores a star unless the user already starred the diagram, and returns the stored star
+	Star(ctx context.Context, star *models.DiagramStar) (*models.DiagramStar, error)
+	// Unstar removes a star; ErrNotFound if the diagram was not starred
+	Unstar(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) error
+	// IsStarred reports whether the user starred a diagram
+	IsStarred(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) (bool, error)
+	// ListForUser returns the user's stars in the given workspaces, unordered
+	ListForUser(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID) ([]*models.DiagramStar, error)
+	DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
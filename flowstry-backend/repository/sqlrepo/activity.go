@@ -0,0 +1,109 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityRepository stores activity feed entries in the activity table
+type ActivityRepository struct {
+	db *sql.DB
+}
+
+const activityColumns = "id, workspace_id, actor_id, actor_name, type, target_type, target_id, target_name, details, count, created_at, updated_at"
+
+// activitySortColumns maps activity sort fields to columns
+var activitySortColumns = sortColumns{
+	models.SortByUpdated: "updated_at",
+}
+
+// Append inserts an activity entry
+func (r *ActivityRepository) Append(ctx context.Context, entry *models.ActivityEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	details, err := valuesArg(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO activity ("+activityColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		entry.ID.Hex(), entry.WorkspaceID.Hex(), idArg(entry.ActorID), entry.ActorName, string(entry.Type),
+		string(entry.TargetType), entry.TargetID.Hex(), entry.TargetName, details, entry.Count, entry.CreatedAt, entry.UpdatedAt)
+	return mapError(err)
+}
+
+// Latest returns the most recently updated entry about a target
+func (r *ActivityRepository) Latest(ctx context.Context, workspaceID, targetID primitive.ObjectID) (*models.ActivityEntry, error) {
+	entries, err := r.find(ctx, " WHERE workspace_id = $1 AND target_id = $2 ORDER BY updated_at DESC, id DESC LIMIT 1",
+		workspaceID.Hex(), targetID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// Extend adds one change to a session entry
+func (r *ActivityRepository) Extend(ctx context.Context, workspaceID, entryID primitive.ObjectID, updatedAt time.Time) error {
+	return affected(r.db.ExecContext(ctx, "UPDATE activity SET count = count + 1, updated_at = $1 WHERE id = $2 AND workspace_id = $3",
+		updatedAt, entryID.Hex(), workspaceID.Hex()))
+}
+
+// List pages through a workspace's activity feed
+func (r *ActivityRepository) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.ActivityFilter, page *repository.PageQuery) ([]*models.ActivityEntry, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	if filter != nil {
+		if filter.ActorID != nil {
+			q.where("actor_id = " + q.arg(filter.ActorID.Hex()))
+		}
+		if filter.TargetID != nil {
+			q.where("target_id = " + q.arg(filter.TargetID.Hex()))
+		}
+	}
+	order, err := q.page(activitySortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.clause()+order, q.args...)
+}
+
+func (r *ActivityRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.ActivityEntry, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+activityColumns+" FROM activity"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.ActivityEntry
+	for rows.Next() {
+		var e models.ActivityEntry
+		var eventType, targetType string
+		var details sql.NullString
+		err := rows.Scan(objectID{&e.ID}, objectID{&e.WorkspaceID}, nullID{&e.ActorID}, &e.ActorName, &eventType,
+			&targetType, objectID{&e.TargetID}, &e.TargetName, &details, &e.Count, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Type = models.EventType(eventType)
+		e.TargetType = models.TargetType(targetType)
+		if e.Details, err = scanValues(details); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteAllForWorkspace deletes a workspace's activity feed
+func (r *ActivityRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "activity", workspaceID)
+}
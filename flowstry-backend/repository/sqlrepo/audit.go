@@ -0,0 +1,120 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditRepository stores audit entries in the audit_log table
+type AuditRepository struct {
+	db *sql.DB
+}
+
+const auditColumns = "id, workspace_id, actor_id, actor_email, ip, user_agent, action, target_type, target_id, before_values, after_values, created_at"
+
+// auditSortColumns maps audit sort fields to columns
+var auditSortColumns = sortColumns{
+	models.SortByCreated: "created_at",
+}
+
+// Append inserts an audit entry
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	before, err := valuesArg(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := valuesArg(entry.After)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO audit_log ("+auditColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		entry.ID.Hex(), entry.WorkspaceID.Hex(), idArg(entry.ActorID), entry.ActorEmail, entry.IP, entry.UserAgent,
+		string(entry.Action), string(entry.TargetType), entry.TargetID.Hex(), before, after, entry.CreatedAt)
+	return mapError(err)
+}
+
+// valuesArg stores a before/after map as JSON, or NULL when empty
+func valuesArg(values map[string]string) (interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// List pages through a workspace's audit entries
+func (r *AuditRepository) List(ctx context.Context, workspaceID primitive.ObjectID, filter *models.AuditFilter, page *repository.PageQuery) ([]*models.AuditEntry, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	if filter != nil {
+		if len(filter.Actions) > 0 {
+			actions := make([]string, len(filter.Actions))
+			for i, action := range filter.Actions {
+				actions[i] = string(action)
+			}
+			q.inStrings("action", actions)
+		}
+		if filter.ActorID != nil {
+			q.where("actor_id = " + q.arg(filter.ActorID.Hex()))
+		}
+		if filter.TargetID != nil {
+			q.where("target_id = " + q.arg(filter.TargetID.Hex()))
+		}
+		q.dateRange("created_at", filter.Created)
+	}
+	order, err := q.page(auditSortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+auditColumns+" FROM audit_log"+q.clause()+order, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var action, targetType string
+		var before, after sql.NullString
+		err := rows.Scan(objectID{&e.ID}, objectID{&e.WorkspaceID}, nullID{&e.ActorID}, &e.ActorEmail, &e.IP, &e.UserAgent,
+			&action, &targetType, objectID{&e.TargetID}, &before, &after, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Action = models.EventType(action)
+		e.TargetType = models.TargetType(targetType)
+		if e.Before, err = scanValues(before); err != nil {
+			return nil, err
+		}
+		if e.After, err = scanValues(after); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+func scanValues(column sql.NullString) (map[string]string, error) {
+	if !column.Valid {
+		return nil, nil
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(column.String), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,171 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentRepository stores comments in the comments table
+type CommentRepository struct {
+	db *sql.DB
+}
+
+const commentColumns = "id, workspace_id, diagram_id, thread_id, author_id, author_name, body, shape_id, anchor_x, anchor_y, " +
+	"resolved_at, resolved_by, edited_at, created_at, updated_at"
+
+// commentSortColumns maps thread sort fields to columns
+var commentSortColumns = sortColumns{
+	models.SortByCreated: "created_at",
+	models.SortByUpdated: "updated_at",
+}
+
+// Create inserts a comment
+func (r *CommentRepository) Create(ctx context.Context, c *models.Comment) error {
+	if c.ID.IsZero() {
+		c.ID = primitive.NewObjectID()
+	}
+	shapeID, x, y := anchorArgs(c.Anchor)
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO comments ("+commentColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)",
+		c.ID.Hex(), c.WorkspaceID.Hex(), c.DiagramID.Hex(), idArg(c.ThreadID), c.AuthorID.Hex(), c.AuthorName, c.Body,
+		shapeID, x, y, c.ResolvedAt, idArg(c.ResolvedBy), c.EditedAt, c.CreatedAt, c.UpdatedAt)
+	return mapError(err)
+}
+
+// anchorArgs converts an optional anchor to its nullable columns
+func anchorArgs(anchor *models.CommentAnchor) (interface{}, interface{}, interface{}) {
+	if anchor == nil {
+		return nil, nil, nil
+	}
+	return textArg(anchor.ShapeID), anchor.X, anchor.Y
+}
+
+// Get retrieves a comment of a diagram
+func (r *CommentRepository) Get(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) (*models.Comment, error) {
+	comments, err := r.find(ctx, " WHERE id = $1 AND workspace_id = $2 AND diagram_id = $3",
+		id.Hex(), workspaceID.Hex(), diagramID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return comments[0], nil
+}
+
+// ListThreads pages through the thread roots of a diagram
+func (r *CommentRepository) ListThreads(ctx context.Context, workspaceID, diagramID primitive.ObjectID, filter *models.CommentFilter, page *repository.PageQuery) ([]*models.Comment, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("diagram_id = " + q.arg(diagramID.Hex()))
+	q.where("thread_id IS NULL")
+	if filter != nil {
+		if filter.Resolved != nil {
+			if *filter.Resolved {
+				q.where("resolved_at IS NOT NULL")
+			} else {
+				q.where("resolved_at IS NULL")
+			}
+		}
+		if filter.ShapeID != "" {
+			q.where("shape_id = " + q.arg(filter.ShapeID))
+		}
+	}
+	order, err := q.page(commentSortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.clause()+order, q.args...)
+}
+
+// ListReplies returns the replies to the given threads, oldest first
+func (r *CommentRepository) ListReplies(ctx context.Context, workspaceID, diagramID primitive.ObjectID, threadIDs []primitive.ObjectID) ([]*models.Comment, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("diagram_id = " + q.arg(diagramID.Hex()))
+	q.in("thread_id", threadIDs)
+	return r.find(ctx, q.clause()+" ORDER BY created_at, id", q.args...)
+}
+
+// ListShapeAnchored returns the thread roots of a diagram that are anchored to a shape
+func (r *CommentRepository) ListShapeAnchored(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Comment, error) {
+	return r.find(ctx, " WHERE workspace_id = $1 AND diagram_id = $2 AND thread_id IS NULL AND shape_id IS NOT NULL",
+		workspaceID.Hex(), diagramID.Hex())
+}
+
+func (r *CommentRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+commentColumns+" FROM comments"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		var c models.Comment
+		var shapeID sql.NullString
+		var x, y sql.NullFloat64
+		err := rows.Scan(objectID{&c.ID}, objectID{&c.WorkspaceID}, objectID{&c.DiagramID}, nullID{&c.ThreadID},
+			objectID{&c.AuthorID}, &c.AuthorName, &c.Body, &shapeID, &x, &y,
+			&c.ResolvedAt, nullID{&c.ResolvedBy}, &c.EditedAt, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if x.Valid && y.Valid {
+			c.Anchor = &models.CommentAnchor{ShapeID: shapeID.String, X: x.Float64, Y: y.Float64}
+		}
+		comments = append(comments, &c)
+	}
+	return comments, rows.Err()
+}
+
+// Update changes a comment
+func (r *CommentRepository) Update(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, update *repository.CommentUpdate) error {
+	q := &builder{}
+	set := "updated_at = " + q.arg(update.UpdatedAt)
+	if update.Body != nil {
+		set += ", body = " + q.arg(*update.Body)
+	}
+	if update.EditedAt != nil {
+		set += ", edited_at = " + q.arg(*update.EditedAt)
+	}
+	if update.Resolved != nil {
+		if *update.Resolved {
+			set += ", resolved_at = " + q.arg(update.UpdatedAt) + ", resolved_by = " + q.arg(update.ResolvedBy.Hex())
+		} else {
+			set += ", resolved_at = NULL, resolved_by = NULL"
+		}
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("diagram_id = " + q.arg(diagramID.Hex()))
+	return affected(r.db.ExecContext(ctx, "UPDATE comments SET "+set+q.clause(), q.args...))
+}
+
+// SetAnchor moves a thread's anchor
+func (r *CommentRepository) SetAnchor(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, anchor models.CommentAnchor) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE comments SET shape_id = $1, anchor_x = $2, anchor_y = $3 WHERE id = $4 AND workspace_id = $5 AND diagram_id = $6",
+		textArg(anchor.ShapeID), anchor.X, anchor.Y, id.Hex(), workspaceID.Hex(), diagramID.Hex()))
+}
+
+// Delete removes a comment and, for a thread root, its replies
+func (r *CommentRepository) Delete(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx,
+		"DELETE FROM comments WHERE workspace_id = $1 AND diagram_id = $2 AND (id = $3 OR thread_id = $3)",
+		workspaceID.Hex(), diagramID.Hex(), id.Hex()))
+}
+
+// DeleteForDiagram removes every comment of a diagram
+func (r *CommentRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteForDiagram(ctx, r.db, "comments", workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes every comment of a workspace
+func (r *CommentRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "comments", workspaceID)
+}
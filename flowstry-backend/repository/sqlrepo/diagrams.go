@@ -0,0 +1,638 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiagramRepository stores diagram metadata in the diagrams table and their tags in diagram_tags
+type DiagramRepository struct {
+	db *sql.DB
+}
+
+// diagramSortColumns maps diagram sort fields to columns
+var diagramSortColumns = sortColumns{
+	models.SortByName:    "name",
+	models.SortByCreated: "created_at",
+	models.SortByUpdated: "updated_at",
+	models.SortBySize:    "file_size",
+	models.SortByDeleted: "deleted_at",
+}
+
+// diagramColumns leaves out the (potentially large) search index, which listings do not return
+const diagramColumns = "id, workspace_id, folder_id, name, description, file_url, file_size, thumbnail, version, " +
+	"template_scope, created_by, deleted_at, trash_batch_id, deleted_with_parent, created_at, updated_at"
+
+// Create inserts a diagram with its tags
+func (r *DiagramRepository) Create(ctx context.Context, diagram *models.Diagram) error {
+	if diagram.ID.IsZero() {
+		diagram.ID = primitive.NewObjectID()
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO diagrams ("+diagramColumns+", search_content) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)",
+			diagram.ID.Hex(), diagram.WorkspaceID.Hex(), idArg(diagram.FolderID), diagram.Name, diagram.Description,
+			diagram.FileURL, diagram.FileSize, diagram.Thumbnail, diagram.Version, string(diagram.TemplateScope),
+			idArg(diagram.CreatedBy), diagram.DeletedAt, idArg(diagram.TrashBatchID), diagram.DeletedWithParent,
+			diagram.CreatedAt, diagram.UpdatedAt, diagram.SearchContent)
+		if err != nil {
+			return mapError(err)
+		}
+		return replaceTags(ctx, tx, diagram.ID, diagram.Tags)
+	})
+}
+
+// replaceTags stores the tags of a diagram in order, dropping repeats
+func replaceTags(ctx context.Context, tx *sql.Tx, id primitive.ObjectID, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM diagram_tags WHERE diagram_id = $1", id.Hex()); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		_, err := tx.ExecContext(ctx, "INSERT INTO diagram_tags (diagram_id, tag, position) VALUES ($1, $2, $3)",
+			id.Hex(), tag, len(seen))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get retrieves a diagram of a workspace, search text included
+func (r *DiagramRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash repository.Trash) (*models.Diagram, error) {
+	q := &builder{}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.trash("", trash)
+
+	diagrams, err := r.find(ctx, true, q.clause(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(diagrams) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return diagrams[0], nil
+}
+
+// List pages through the diagrams of a workspace
+func (r *DiagramRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.DiagramQuery, page *repository.PageQuery) ([]*models.Diagram, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	if query != nil {
+		q.trash("", query.Trash)
+		if query.Trash == repository.Trashed {
+			q.where("NOT deleted_with_parent")
+		}
+		applyDiagramFilter(q, query.Filter)
+	}
+
+	order, err := q.page(diagramSortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, false, q.clause()+order, q.args...)
+}
+
+// applyDiagramFilter adds the optional listing filters to a diagrams query
+func applyDiagramFilter(q *builder, filter *models.DiagramFilter) {
+	if filter == nil {
+		return
+	}
+	if filter.Query != "" {
+		q.where(q.contains("name", filter.Query))
+	}
+	if len(filter.Tags) > 0 {
+		tags := distinct(filter.Tags)
+		q.where(fmt.Sprintf("id IN (SELECT diagram_id FROM diagram_tags WHERE tag IN %s GROUP BY diagram_id HAVING COUNT(*) = %d)",
+			q.list(tags), len(tags)))
+	}
+	if filter.RootOnly {
+		q.where("folder_id IS NULL")
+	} else if filter.FolderID != nil {
+		q.where("folder_id = " + q.arg(filter.FolderID.Hex()))
+	}
+	if filter.CreatedBy != nil {
+		q.where("created_by = " + q.arg(filter.CreatedBy.Hex()))
+	}
+	q.dateRange("created_at", filter.Created)
+	q.dateRange("updated_at", filter.Updated)
+}
+
+// distinct drops repeated strings, keeping the first occurrence
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ListByIDs returns the live diagrams among ids within the given workspaces
+func (r *DiagramRepository) ListByIDs(ctx context.Context, workspaceIDs, ids []primitive.ObjectID, filter *models.DiagramFilter) ([]*models.Diagram, error) {
+	q := &builder{}
+	q.in("id", ids)
+	q.in("workspace_id", workspaceIDs)
+	q.where("deleted_at IS NULL")
+	applyDiagramFilter(q, filter)
+	return r.find(ctx, false, q.clause(), q.args...)
+}
+
+// find queries diagrams and loads their tags. withContent also reads the search text.
+func (r *DiagramRepository) find(ctx context.Context, withContent bool, tail string, args ...interface{}) ([]*models.Diagram, error) {
+	columns := diagramColumns
+	if withContent {
+		columns += ", search_content"
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+columns+" FROM diagrams"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var diagrams []*models.Diagram
+	for rows.Next() {
+		var d models.Diagram
+		var scope string
+		dest := []interface{}{objectID{&d.ID}, objectID{&d.WorkspaceID}, nullID{&d.FolderID}, &d.Name, &d.Description,
+			&d.FileURL, &d.FileSize, &d.Thumbnail, &d.Version, &scope, nullID{&d.CreatedBy}, &d.DeletedAt,
+			nullID{&d.TrashBatchID}, &d.DeletedWithParent, &d.CreatedAt, &d.UpdatedAt}
+		if withContent {
+			dest = append(dest, &d.SearchContent)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		d.TemplateScope = models.TemplateScope(scope)
+		diagrams = append(diagrams, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadTags(ctx, diagrams); err != nil {
+		return nil, err
+	}
+	return diagrams, nil
+}
+
+// loadTags fills in the tags of diagrams in one query
+func (r *DiagramRepository) loadTags(ctx context.Context, diagrams []*models.Diagram) error {
+	if len(diagrams) == 0 {
+		return nil
+	}
+	byID := make(map[primitive.ObjectID]*models.Diagram, len(diagrams))
+	ids := make([]primitive.ObjectID, len(diagrams))
+	for i, d := range diagrams {
+		byID[d.ID] = d
+		ids[i] = d.ID
+	}
+
+	q := &builder{}
+	q.in("diagram_id", ids)
+	rows, err := r.db.QueryContext(ctx, "SELECT diagram_id, tag FROM diagram_tags"+q.clause()+" ORDER BY position", q.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id primitive.ObjectID
+		var tag string
+		if err := rows.Scan(objectID{&id}, &tag); err != nil {
+			return err
+		}
+		d := byID[id]
+		d.Tags = append(d.Tags, tag)
+	}
+	return rows.Err()
+}
+
+// CountForWorkspace counts every diagram of a workspace, trashed ones included
+func (r *DiagramRepository) CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM diagrams WHERE workspace_id = $1", workspaceID.Hex()).Scan(&n)
+	return n, err
+}
+
+// CountLive counts how many of ids are live diagrams of the workspace
+func (r *DiagramRepository) CountLive(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	q := &builder{}
+	q.in("id", ids)
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("deleted_at IS NULL")
+
+	var n int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM diagrams"+q.clause(), q.args...).Scan(&n)
+	return n, err
+}
+
+// CountByFolder counts live diagrams per folder in one grouped query
+func (r *DiagramRepository) CountByFolder(ctx context.Context, workspaceID primitive.ObjectID) (map[primitive.ObjectID]int64, int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT folder_id, COUNT(*) FROM diagrams WHERE workspace_id = $1 AND deleted_at IS NULL GROUP BY folder_id",
+		workspaceID.Hex())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	counts := map[primitive.ObjectID]int64{}
+	var root int64
+	for rows.Next() {
+		var folderID *primitive.ObjectID
+		var n int64
+		if err := rows.Scan(nullID{&folderID}, &n); err != nil {
+			return nil, 0, err
+		}
+		if folderID == nil {
+			root += n
+		} else {
+			counts[*folderID] = n
+		}
+	}
+	return counts, root, rows.Err()
+}
+
+// Update changes the given fields of a diagram
+func (r *DiagramRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.DiagramUpdate) error {
+	q := &builder{}
+	set := []string{"updated_at = " + q.arg(update.UpdatedAt)}
+	if update.Name != nil {
+		set = append(set, "name = "+q.arg(*update.Name))
+	}
+	if update.Description != nil {
+		set = append(set, "description = "+q.arg(*update.Description))
+	}
+	if update.Thumbnail != nil {
+		set = append(set, "thumbnail = "+q.arg(*update.Thumbnail))
+	}
+	if update.FileURL != nil {
+		set = append(set, "file_url = "+q.arg(*update.FileURL))
+	}
+	if update.FileSize != nil {
+		set = append(set, "file_size = "+q.arg(*update.FileSize))
+	}
+	if update.Version != nil {
+		set = append(set, "version = "+q.arg(*update.Version))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := affected(tx.ExecContext(ctx, "UPDATE diagrams SET "+strings.Join(set, ", ")+q.clause(), q.args...)); err != nil {
+			return err
+		}
+		if update.Tags != nil {
+			return replaceTags(ctx, tx, id, *update.Tags)
+		}
+		return nil
+	})
+}
+
+// SetFolder moves live diagrams into a folder in one update
+func (r *DiagramRepository) SetFolder(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, folderID *primitive.ObjectID, at time.Time) (int64, error) {
+	q := &builder{}
+	set := fmt.Sprintf("folder_id = %s, updated_at = %s", q.arg(idArg(folderID)), q.arg(at))
+	q.in("id", ids)
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("deleted_at IS NULL")
+	return count(r.db.ExecContext(ctx, "UPDATE diagrams SET "+set+q.clause(), q.args...))
+}
+
+// RemoveFromFolder moves a live diagram out of a folder to the workspace root
+func (r *DiagramRepository) RemoveFromFolder(ctx context.Context, workspaceID, id, folderID primitive.ObjectID, at time.Time) error {
+	return affected(r.db.ExecContext(ctx, `UPDATE diagrams SET folder_id = NULL, updated_at = $1
+		WHERE id = $2 AND workspace_id = $3 AND folder_id = $4 AND deleted_at IS NULL`,
+		at, id.Hex(), workspaceID.Hex(), folderID.Hex()))
+}
+
+// InFolders returns the diagrams filed in any of folderIDs
+func (r *DiagramRepository) InFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.in("folder_id", folderIDs)
+	if batchID != nil {
+		q.where("(deleted_at IS NULL OR trash_batch_id = " + q.arg(batchID.Hex()) + ")")
+	} else {
+		q.where("deleted_at IS NULL")
+	}
+	return scanIDs(r.db.QueryContext(ctx, "SELECT id FROM diagrams"+q.clause(), q.args...))
+}
+
+// Unfile moves every diagram in one of folderIDs to the workspace root
+func (r *DiagramRepository) Unfile(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID) error {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.in("folder_id", folderIDs)
+	_, err := r.db.ExecContext(ctx, "UPDATE diagrams SET folder_id = NULL"+q.clause(), q.args...)
+	return err
+}
+
+// DetachFromMissingFolders moves live diagrams whose folder is not live to the workspace root
+func (r *DiagramRepository) DetachFromMissingFolders(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE diagrams SET folder_id = NULL
+		WHERE workspace_id = $1 AND deleted_at IS NULL AND folder_id IS NOT NULL
+		AND folder_id NOT IN (SELECT id FROM folders WHERE workspace_id = $1 AND deleted_at IS NULL)`,
+		workspaceID.Hex())
+	return err
+}
+
+// Trash moves live diagrams to trash
+func (r *DiagramRepository) Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return trashRows(ctx, r.db, "diagrams", workspaceID, ids, batchID, at, withParent)
+}
+
+// TrashInFolders trashes the live diagrams of folderIDs along with them
+func (r *DiagramRepository) TrashInFolders(ctx context.Context, workspaceID primitive.ObjectID, folderIDs []primitive.ObjectID, batchID primitive.ObjectID, at time.Time) error {
+	q := &builder{}
+	set := fmt.Sprintf("deleted_at = %s, trash_batch_id = %s, deleted_with_parent = TRUE", q.arg(at), q.arg(batchID.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.in("folder_id", folderIDs)
+	q.where("deleted_at IS NULL")
+	_, err := r.db.ExecContext(ctx, "UPDATE diagrams SET "+set+q.clause(), q.args...)
+	return err
+}
+
+// Restore brings a trashed diagram back
+func (r *DiagramRepository) Restore(ctx context.Context, workspaceID, id primitive.ObjectID, toRoot bool) error {
+	set := restoreSet
+	if toRoot {
+		set += ", folder_id = NULL"
+	}
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE diagrams SET "+set+" WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NOT NULL",
+		id.Hex(), workspaceID.Hex()))
+}
+
+// RestoreBatch brings back every diagram trashed in a batch
+func (r *DiagramRepository) RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE diagrams SET "+restoreSet+" WHERE workspace_id = $1 AND trash_batch_id = $2",
+		workspaceID.Hex(), batchID.Hex())
+	return err
+}
+
+// TrashRoots returns the diagram trash roots deleted before cutoff
+func (r *DiagramRepository) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	return trashRoots(ctx, r.db, "diagrams", workspaceID, cutoff)
+}
+
+// WorkspacesWithTrash returns the workspaces that have diagrams in trash
+func (r *DiagramRepository) WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error) {
+	return scanIDs(r.db.QueryContext(ctx, "SELECT DISTINCT workspace_id FROM diagrams WHERE deleted_at IS NOT NULL"))
+}
+
+// workspaceDiagrams selects the IDs of a workspace's diagrams, for tag statements
+const workspaceDiagrams = "diagram_id IN (SELECT id FROM diagrams WHERE workspace_id = $1)"
+
+// CountTags counts live diagrams per tag in one grouped query
+func (r *DiagramRepository) CountTags(ctx context.Context, workspaceID primitive.ObjectID) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT t.tag, COUNT(*) FROM diagram_tags t JOIN diagrams d ON d.id = t.diagram_id
+		WHERE d.workspace_id = $1 AND d.deleted_at IS NULL GROUP BY t.tag`, workspaceID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var tag string
+		var n int64
+		if err := rows.Scan(&tag, &n); err != nil {
+			return nil, err
+		}
+		counts[tag] = n
+	}
+	return counts, rows.Err()
+}
+
+// RenameTag replaces a tag on every diagram of the workspace carrying it. Like adding the new
+// name and removing the old one, the renamed tag moves to the end of each diagram's list.
+func (r *DiagramRepository) RenameTag(ctx context.Context, workspaceID primitive.ObjectID, oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Diagrams that already carry the new name just lose the old one
+		_, err := tx.ExecContext(ctx, `DELETE FROM diagram_tags WHERE `+workspaceDiagrams+` AND tag = $2
+			AND diagram_id IN (SELECT diagram_id FROM diagram_tags WHERE tag = $3)`,
+			workspaceID.Hex(), oldName, newName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE diagram_tags SET tag = $3,
+			position = (SELECT MAX(t.position) + 1 FROM diagram_tags t WHERE t.diagram_id = diagram_tags.diagram_id)
+			WHERE `+workspaceDiagrams+` AND tag = $2`,
+			workspaceID.Hex(), oldName, newName)
+		return err
+	})
+}
+
+// RemoveTag removes a tag from every diagram of the workspace
+func (r *DiagramRepository) RemoveTag(ctx context.Context, workspaceID primitive.ObjectID, name string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM diagram_tags WHERE "+workspaceDiagrams+" AND tag = $2",
+		workspaceID.Hex(), name)
+	return err
+}
+
+// AddTags adds tags to live diagrams that stay within max tags
+func (r *DiagramRepository) AddTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, max int, at time.Time) (int64, error) {
+	tags = distinct(tags)
+	var updated int64
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		current, err := liveTags(ctx, tx, workspaceID, ids)
+		if err != nil {
+			return err
+		}
+
+		for id, existing := range current {
+			have := make(map[string]bool, len(existing))
+			for _, tag := range existing {
+				have[tag] = true
+			}
+			var add []string
+			for _, tag := range tags {
+				if !have[tag] {
+					add = append(add, tag)
+				}
+			}
+			if len(existing)+len(add) > max {
+				continue
+			}
+
+			for _, tag := range add {
+				_, err := tx.ExecContext(ctx, `INSERT INTO diagram_tags (diagram_id, tag, position)
+					SELECT $1, $2, COALESCE(MAX(position), 0) + 1 FROM diagram_tags WHERE diagram_id = $1`,
+					id.Hex(), tag)
+				if err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE diagrams SET updated_at = $1 WHERE id = $2", at, id.Hex()); err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// liveTags returns the current tags of the live diagrams among ids, keyed by diagram
+func liveTags(ctx context.Context, tx *sql.Tx, workspaceID primitive.ObjectID, ids []primitive.ObjectID) (map[primitive.ObjectID][]string, error) {
+	q := &builder{}
+	q.in("d.id", ids)
+	q.where("d.workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("d.deleted_at IS NULL")
+	rows, err := tx.QueryContext(ctx, `SELECT d.id, t.tag FROM diagrams d
+		LEFT JOIN diagram_tags t ON t.diagram_id = d.id`+q.clause()+" ORDER BY t.position", q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	current := map[primitive.ObjectID][]string{}
+	for rows.Next() {
+		var id primitive.ObjectID
+		var tag sql.NullString
+		if err := rows.Scan(objectID{&id}, &tag); err != nil {
+			return nil, err
+		}
+		if tag.Valid {
+			current[id] = append(current[id], tag.String)
+		} else {
+			current[id] = nil
+		}
+	}
+	return current, rows.Err()
+}
+
+// RemoveTags removes tags from live diagrams
+func (r *DiagramRepository) RemoveTags(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, tags []string, at time.Time) (int64, error) {
+	var updated int64
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		q := &builder{}
+		set := "updated_at = " + q.arg(at)
+		q.in("id", ids)
+		q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+		q.where("deleted_at IS NULL")
+		n, err := count(tx.ExecContext(ctx, "UPDATE diagrams SET "+set+q.clause(), q.args...))
+		if err != nil {
+			return err
+		}
+		updated = n
+
+		d := &builder{}
+		d.in("diagram_id", ids)
+		d.where("diagram_id IN (SELECT id FROM diagrams WHERE workspace_id = " + d.arg(workspaceID.Hex()) + " AND deleted_at IS NULL)")
+		d.inStrings("tag", tags)
+		_, err = tx.ExecContext(ctx, "DELETE FROM diagram_tags"+d.clause(), d.args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// SetTemplateScope sets or, for an empty scope, clears the template scope of a diagram
+func (r *DiagramRepository) SetTemplateScope(ctx context.Context, workspaceID, id primitive.ObjectID, scope models.TemplateScope) error {
+	return affected(r.db.ExecContext(ctx, "UPDATE diagrams SET template_scope = $1 WHERE id = $2 AND workspace_id = $3",
+		string(scope), id.Hex(), workspaceID.Hex()))
+}
+
+// ListTemplates returns the live templates offered to a workspace
+func (r *DiagramRepository) ListTemplates(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Diagram, error) {
+	return r.find(ctx, false, ` WHERE deleted_at IS NULL
+		AND (template_scope = $1 OR (template_scope = $2 AND workspace_id = $3))
+		ORDER BY name, id`,
+		string(models.TemplateScopeInstance), string(models.TemplateScopeWorkspace), workspaceID.Hex())
+}
+
+// GetTemplate retrieves a live template by ID in any workspace
+func (r *DiagramRepository) GetTemplate(ctx context.Context, id primitive.ObjectID) (*models.Diagram, error) {
+	templates, err := r.find(ctx, false, " WHERE id = $1 AND deleted_at IS NULL AND template_scope IN ($2, $3)",
+		id.Hex(), string(models.TemplateScopeWorkspace), string(models.TemplateScopeInstance))
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return templates[0], nil
+}
+
+// Search finds live diagrams matching every term with case-insensitive LIKE patterns
+func (r *DiagramRepository) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, includeContent bool, limit int64) ([]*models.Diagram, error) {
+	q := &builder{}
+	q.in("workspace_id", workspaceIDs)
+	q.where("deleted_at IS NULL")
+
+	// Every term must match at least one searchable field
+	fields := []string{"name", "description"}
+	if includeContent {
+		fields = append(fields, "search_content")
+	}
+	for _, term := range terms {
+		matches := make([]string, len(fields))
+		for i, field := range fields {
+			matches[i] = q.contains(field, term)
+		}
+		q.where("(" + strings.Join(matches, " OR ") + ")")
+	}
+
+	return r.find(ctx, includeContent, q.clause()+" ORDER BY updated_at DESC"+limitClause(limit), q.args...)
+}
+
+// SetSearchContent stores the search text of a diagram
+func (r *DiagramRepository) SetSearchContent(ctx context.Context, workspaceID, id primitive.ObjectID, text string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE diagrams SET search_content = $1 WHERE id = $2 AND workspace_id = $3",
+		text, id.Hex(), workspaceID.Hex())
+	return err
+}
+
+// ClearSearchContent drops the search text of every diagram of a workspace
+func (r *DiagramRepository) ClearSearchContent(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE diagrams SET search_content = '' WHERE workspace_id = $1 AND search_content <> ''",
+		workspaceID.Hex())
+	return err
+}
+
+// ListAll returns every diagram of a workspace in the given trash state
+func (r *DiagramRepository) ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash repository.Trash) ([]*models.Diagram, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.trash("", trash)
+	return r.find(ctx, false, q.clause(), q.args...)
+}
+
+// Delete permanently removes a diagram and its tags
+func (r *DiagramRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx, "DELETE FROM diagrams WHERE id = $1 AND workspace_id = $2", id.Hex(), workspaceID.Hex()))
+}
+
+// DeleteAllForWorkspace permanently removes every diagram of a workspace
+func (r *DiagramRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM diagrams WHERE workspace_id = $1", workspaceID.Hex())
+	return err
+}
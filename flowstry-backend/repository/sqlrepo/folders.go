@@ -0,0 +1,343 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FolderRepository stores folders in the folders table
+type FolderRepository struct {
+	db *sql.DB
+}
+
+// folderSortColumns maps folder sort fields to columns
+var folderSortColumns = sortColumns{
+	models.SortByName:    "name",
+	models.SortByCreated: "created_at",
+	models.SortByUpdated: "updated_at",
+	models.SortByDeleted: "deleted_at",
+}
+
+const folderColumns = "id, workspace_id, parent_folder_id, name, description, color, created_by, " +
+	"deleted_at, trash_batch_id, deleted_with_parent, created_at, updated_at"
+
+// Create inserts a folder
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	if folder.ID.IsZero() {
+		folder.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO folders ("+folderColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		folder.ID.Hex(), folder.WorkspaceID.Hex(), idArg(folder.ParentFolderID), folder.Name, folder.Description,
+		folder.Color, idArg(folder.CreatedBy), folder.DeletedAt, idArg(folder.TrashBatchID), folder.DeletedWithParent,
+		folder.CreatedAt, folder.UpdatedAt)
+	return mapError(err)
+}
+
+// Get retrieves a folder of a workspace
+func (r *FolderRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID, trash repository.Trash) (*models.Folder, error) {
+	q := &builder{}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.trash("", trash)
+
+	folders, err := r.find(ctx, q.clause(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return folders[0], nil
+}
+
+// List pages through the folders of a workspace
+func (r *FolderRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.FolderQuery, page *repository.PageQuery) ([]*models.Folder, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	if query != nil {
+		q.trash("", query.Trash)
+		if query.Trash == repository.Trashed {
+			q.where("NOT deleted_with_parent")
+		}
+		applyFolderFilter(q, query.Filter)
+	}
+
+	order, err := q.page(folderSortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.clause()+order, q.args...)
+}
+
+// applyFolderFilter adds the optional listing filters to a folders query
+func applyFolderFilter(q *builder, filter *models.FolderFilter) {
+	if filter == nil {
+		return
+	}
+	if filter.Query != "" {
+		q.where(q.contains("name", filter.Query))
+	}
+	if filter.RootOnly {
+		q.where("parent_folder_id IS NULL")
+	} else if filter.ParentID != nil {
+		q.where("parent_folder_id = " + q.arg(filter.ParentID.Hex()))
+	}
+	if filter.CreatedBy != nil {
+		q.where("created_by = " + q.arg(filter.CreatedBy.Hex()))
+	}
+	q.dateRange("created_at", filter.Created)
+	q.dateRange("updated_at", filter.Updated)
+}
+
+// ListAll retrieves every folder of a workspace in a trash state
+func (r *FolderRepository) ListAll(ctx context.Context, workspaceID primitive.ObjectID, trash repository.Trash) ([]*models.Folder, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.trash("", trash)
+	return r.find(ctx, q.clause(), q.args...)
+}
+
+func (r *FolderRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.Folder, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+folderColumns+" FROM folders"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []*models.Folder
+	for rows.Next() {
+		f, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// scanFolder reads a row of folderColumns
+func scanFolder(rows *sql.Rows) (*models.Folder, error) {
+	var f models.Folder
+	err := rows.Scan(objectID{&f.ID}, objectID{&f.WorkspaceID}, nullID{&f.ParentFolderID}, &f.Name, &f.Description,
+		&f.Color, nullID{&f.CreatedBy}, &f.DeletedAt, nullID{&f.TrashBatchID}, &f.DeletedWithParent,
+		&f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// CountForWorkspace counts every folder of a workspace, trashed ones included
+func (r *FolderRepository) CountForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM folders WHERE workspace_id = $1", workspaceID.Hex()).Scan(&n)
+	return n, err
+}
+
+// Update changes the given fields of a live folder
+func (r *FolderRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.FolderUpdate) error {
+	q := &builder{}
+	set := []string{"updated_at = " + q.arg(update.UpdatedAt)}
+	if update.Name != nil {
+		set = append(set, "name = "+q.arg(*update.Name))
+	}
+	if update.Description != nil {
+		set = append(set, "description = "+q.arg(*update.Description))
+	}
+	if update.Color != nil {
+		set = append(set, "color = "+q.arg(*update.Color))
+	}
+	if update.SetParent {
+		set = append(set, "parent_folder_id = "+q.arg(idArg(update.ParentFolderID)))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("deleted_at IS NULL")
+
+	return affected(r.db.ExecContext(ctx, "UPDATE folders SET "+strings.Join(set, ", ")+q.clause(), q.args...))
+}
+
+// Ancestors collects the parent chain with a recursive query. UNION stops at rows already
+// visited, so a pre-existing cycle cannot loop; the chain is then ordered by walking it.
+func (r *FolderRepository) Ancestors(ctx context.Context, workspaceID, id primitive.ObjectID) ([]*models.Folder, error) {
+	rows, err := r.db.QueryContext(ctx, `WITH RECURSIVE chain (id, parent_folder_id) AS (
+			SELECT id, parent_folder_id FROM folders WHERE id = $1 AND workspace_id = $2
+			UNION
+			SELECT f.id, f.parent_folder_id FROM folders f JOIN chain c ON f.id = c.parent_folder_id
+			WHERE f.workspace_id = $2
+		)
+		SELECT `+prefixColumns("f.", folderColumns)+` FROM folders f JOIN chain c ON f.id = c.id`,
+		id.Hex(), workspaceID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[primitive.ObjectID]*models.Folder{}
+	for rows.Next() {
+		f, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[f.ID] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	folder, ok := byID[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	var ancestors []*models.Folder
+	seen := map[primitive.ObjectID]bool{id: true}
+	for parent := folder.ParentFolderID; parent != nil && !seen[*parent]; {
+		next, ok := byID[*parent]
+		if !ok {
+			break
+		}
+		seen[next.ID] = true
+		ancestors = append([]*models.Folder{next}, ancestors...)
+		parent = next.ParentFolderID
+	}
+	return ancestors, nil
+}
+
+// prefixColumns qualifies every column of a column list with a table alias
+func prefixColumns(prefix, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, part := range parts {
+		parts[i] = prefix + part
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Descendants collects the subfolders with a recursive query
+func (r *FolderRepository) Descendants(ctx context.Context, workspaceID, id primitive.ObjectID, batchID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	q := &builder{}
+	start, workspace := q.arg(id.Hex()), q.arg(workspaceID.Hex())
+	follow := "f.deleted_at IS NULL"
+	if batchID != nil {
+		follow = "(f.deleted_at IS NULL OR f.trash_batch_id = " + q.arg(batchID.Hex()) + ")"
+	}
+
+	return scanIDs(r.db.QueryContext(ctx, fmt.Sprintf(`WITH RECURSIVE tree (id) AS (
+			SELECT id FROM folders WHERE id = %[1]s AND workspace_id = %[2]s
+			UNION
+			SELECT f.id FROM folders f JOIN tree t ON f.parent_folder_id = t.id
+			WHERE f.workspace_id = %[2]s AND %[3]s
+		)
+		SELECT id FROM tree WHERE id <> %[1]s`, start, workspace, follow), q.args...))
+}
+
+// Trash moves live folders to trash
+func (r *FolderRepository) Trash(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return trashRows(ctx, r.db, "folders", workspaceID, ids, batchID, at, withParent)
+}
+
+// trashRows moves the live rows among ids of a content table to trash
+func trashRows(ctx context.Context, db *sql.DB, table string, workspaceID primitive.ObjectID, ids []primitive.ObjectID, batchID primitive.ObjectID, at time.Time, withParent bool) (int64, error) {
+	q := &builder{}
+	set := fmt.Sprintf("deleted_at = %s, trash_batch_id = %s, deleted_with_parent = %s",
+		q.arg(at), q.arg(batchID.Hex()), q.arg(withParent))
+	q.in("id", ids)
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("deleted_at IS NULL")
+	return count(db.ExecContext(ctx, "UPDATE "+table+" SET "+set+q.clause(), q.args...))
+}
+
+// restoreSet is the assignment bringing a row back from trash
+const restoreSet = "deleted_at = NULL, trash_batch_id = NULL, deleted_with_parent = FALSE"
+
+// Restore brings trashed folders back
+func (r *FolderRepository) Restore(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error {
+	q := &builder{}
+	q.in("id", ids)
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	_, err := r.db.ExecContext(ctx, "UPDATE folders SET "+restoreSet+q.clause(), q.args...)
+	return err
+}
+
+// RestoreBatch brings back every folder trashed in a batch
+func (r *FolderRepository) RestoreBatch(ctx context.Context, workspaceID, batchID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE folders SET "+restoreSet+" WHERE workspace_id = $1 AND trash_batch_id = $2",
+		workspaceID.Hex(), batchID.Hex())
+	return err
+}
+
+// DetachFromMissingParents moves live folders whose parent is not live to the workspace root
+func (r *FolderRepository) DetachFromMissingParents(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE folders SET parent_folder_id = NULL
+		WHERE workspace_id = $1 AND deleted_at IS NULL AND parent_folder_id IS NOT NULL
+		AND parent_folder_id NOT IN (SELECT id FROM folders WHERE workspace_id = $1 AND deleted_at IS NULL)`,
+		workspaceID.Hex())
+	return err
+}
+
+// Unparent moves the folders under parentIDs to the workspace root
+func (r *FolderRepository) Unparent(ctx context.Context, workspaceID primitive.ObjectID, parentIDs []primitive.ObjectID) error {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.in("parent_folder_id", parentIDs)
+	_, err := r.db.ExecContext(ctx, "UPDATE folders SET parent_folder_id = NULL"+q.clause(), q.args...)
+	return err
+}
+
+// TrashRoots returns the folder trash roots deleted before cutoff
+func (r *FolderRepository) TrashRoots(ctx context.Context, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	return trashRoots(ctx, r.db, "folders", workspaceID, cutoff)
+}
+
+// trashRoots returns the trash roots of a content table deleted before cutoff (any time when nil)
+func trashRoots(ctx context.Context, db *sql.DB, table string, workspaceID primitive.ObjectID, cutoff *time.Time) ([]primitive.ObjectID, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("deleted_at IS NOT NULL")
+	q.where("NOT deleted_with_parent")
+	if cutoff != nil {
+		q.where("deleted_at < " + q.arg(*cutoff))
+	}
+	return scanIDs(db.QueryContext(ctx, "SELECT id FROM "+table+q.clause(), q.args...))
+}
+
+// WorkspacesWithTrash returns the workspaces that have folders in trash
+func (r *FolderRepository) WorkspacesWithTrash(ctx context.Context) ([]primitive.ObjectID, error) {
+	return scanIDs(r.db.QueryContext(ctx, "SELECT DISTINCT workspace_id FROM folders WHERE deleted_at IS NOT NULL"))
+}
+
+// Search finds live folders whose name contains every term
+func (r *FolderRepository) Search(ctx context.Context, workspaceIDs []primitive.ObjectID, terms []string, limit int64) ([]*models.Folder, error) {
+	q := &builder{}
+	q.in("workspace_id", workspaceIDs)
+	q.where("deleted_at IS NULL")
+	for _, term := range terms {
+		q.where(q.contains("name", term))
+	}
+	return r.find(ctx, q.clause()+" ORDER BY updated_at DESC"+limitClause(limit), q.args...)
+}
+
+// DeleteMany permanently removes folders
+func (r *FolderRepository) DeleteMany(ctx context.Context, workspaceID primitive.ObjectID, ids []primitive.ObjectID) error {
+	q := &builder{}
+	q.in("id", ids)
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	_, err := r.db.ExecContext(ctx, "DELETE FROM folders"+q.clause(), q.args...)
+	return err
+}
+
+// DeleteAllForWorkspace permanently removes every folder of a workspace
+func (r *FolderRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM folders WHERE workspace_id = $1", workspaceID.Hex())
+	return err
+}
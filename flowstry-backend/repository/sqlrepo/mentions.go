@@ -0,0 +1,95 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MentionRepository stores mentions in the mentions table
+type MentionRepository struct {
+	db *sql.DB
+}
+
+// Replace sets the users a source mentions and returns those it did not mention before
+func (r *MentionRepository) Replace(ctx context.Context, workspaceID, diagramID primitive.ObjectID, source models.MentionSource, sourceID primitive.ObjectID, userIDs []primitive.ObjectID, now time.Time) ([]primitive.ObjectID, error) {
+	var added []primitive.ObjectID
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		q := &builder{}
+		q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+		q.where("source = " + q.arg(string(source)))
+		q.where("source_id = " + q.arg(sourceID.Hex()))
+		if len(userIDs) > 0 {
+			q.where("user_id NOT IN " + q.list(hexes(userIDs)))
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM mentions"+q.clause(), q.args...); err != nil {
+			return err
+		}
+
+		for _, userID := range userIDs {
+			result, err := tx.ExecContext(ctx, `INSERT INTO mentions (id, workspace_id, diagram_id, source, source_id, user_id, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (source, source_id, user_id) DO NOTHING`,
+				primitive.NewObjectID().Hex(), workspaceID.Hex(), diagramID.Hex(), string(source), sourceID.Hex(), userID.Hex(), now)
+			if err != nil {
+				return mapError(err)
+			}
+			if n, err := result.RowsAffected(); err != nil {
+				return err
+			} else if n > 0 {
+				added = append(added, userID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ListForDiagram returns the mentions on a diagram, oldest first
+func (r *MentionRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.Mention, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, workspace_id, diagram_id, source, source_id, user_id, created_at FROM mentions
+		WHERE workspace_id = $1 AND diagram_id = $2 ORDER BY created_at, id`, workspaceID.Hex(), diagramID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentions []*models.Mention
+	for rows.Next() {
+		var m models.Mention
+		var source string
+		err := rows.Scan(objectID{&m.ID}, objectID{&m.WorkspaceID}, objectID{&m.DiagramID}, &source, objectID{&m.SourceID},
+			objectID{&m.UserID}, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.Source = models.MentionSource(source)
+		mentions = append(mentions, &m)
+	}
+	return mentions, rows.Err()
+}
+
+// DeleteForSources drops the mentions of the given sources
+func (r *MentionRepository) DeleteForSources(ctx context.Context, workspaceID primitive.ObjectID, source models.MentionSource, sourceIDs []primitive.ObjectID) error {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("source = " + q.arg(string(source)))
+	q.in("source_id", sourceIDs)
+	_, err := r.db.ExecContext(ctx, "DELETE FROM mentions"+q.clause(), q.args...)
+	return err
+}
+
+// DeleteForDiagram removes every mention on a diagram
+func (r *MentionRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteForDiagram(ctx, r.db, "mentions", workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes every mention of a workspace
+func (r *MentionRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "mentions", workspaceID)
+}
@@ -0,0 +1,284 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/database/migrations"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID keys the advisory lock that serializes migration runs
+const migrationLockID = 7216345010
+
+// sqlMigration is a pair of NNNN_name.up.sql / NNNN_name.down.sql scripts
+type sqlMigration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// Migrator applies the embedded SQL migrations of the database's dialect. Applied versions are
+// recorded in the schema_migrations table; each migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	sqlite     bool
+	migrations []sqlMigration
+}
+
+// NewMigrator creates a migrator for the embedded migrations
+func NewMigrator(db *sql.DB) *Migrator {
+	dir := "migrations/postgres"
+	if isSQLite(db) {
+		dir = "migrations/sqlite"
+	}
+	list, err := loadMigrations(migrationFiles, dir)
+	if err != nil {
+		// The scripts are compiled in, so this only fails on a malformed file name
+		panic(err)
+	}
+	return &Migrator{db: db, sqlite: isSQLite(db), migrations: list}
+}
+
+// loadMigrations reads and pairs the migration scripts of dir, ordered by version
+func loadMigrations(files fs.FS, dir string) ([]sqlMigration, error) {
+	paths, err := fs.Glob(files, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*sqlMigration{}
+	for _, path := range paths {
+		base := strings.TrimPrefix(path, dir+"/")
+		stem, direction := strings.TrimSuffix(base, ".sql"), ""
+		switch {
+		case strings.HasSuffix(stem, ".up"):
+			stem, direction = strings.TrimSuffix(stem, ".up"), "up"
+		case strings.HasSuffix(stem, ".down"):
+			stem, direction = strings.TrimSuffix(stem, ".down"), "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql", base)
+		}
+
+		prefix, name, ok := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name", base)
+		}
+
+		script, err := fs.ReadFile(files, path)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &sqlMigration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	list := make([]sqlMigration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.version, m.name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	return list, nil
+}
+
+// Up applies every pending migration in version order and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]migrations.Status, error) {
+	var applied []migrations.Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.version]; ok {
+				continue
+			}
+			appliedAt := time.Now()
+			err := inConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+					mig.version, mig.name, appliedAt)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.version, mig.name, err)
+			}
+			applied = append(applied, migrations.Status{Version: mig.version, Name: mig.name, AppliedAt: &appliedAt})
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations, newest first, and returns the ones reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]migrations.Status, error) {
+	var reverted []migrations.Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.version]; !ok {
+				continue
+			}
+			if mig.down == "" {
+				return fmt.Errorf("migration %d_%s: %w", mig.version, mig.name, migrations.ErrIrreversible)
+			}
+			err := inConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", mig.version, mig.name, err)
+			}
+			reverted = append(reverted, migrations.Status{Version: mig.version, Name: mig.name})
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied (nil when pending)
+func (m *Migrator) Status(ctx context.Context) ([]migrations.Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := m.ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]migrations.Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := migrations.Status{Version: mig.version, Name: mig.name}
+		if appliedAt, ok := done[mig.version]; ok {
+			s.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock.
+// SQLite has a single connection, which already keeps other runs out.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.sqlite {
+		if err := m.ensureMigrationsTable(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockID).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return migrations.ErrLocked
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if err := m.ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) ensureMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	timeType := "TIMESTAMPTZ"
+	if m.sqlite {
+		timeType = "DATETIME"
+	}
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at `+timeType+` NOT NULL
+	)`)
+	return err
+}
+
+// applied returns when each recorded migration was applied, keyed by version
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[int]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.version] = true
+	}
+
+	done := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		if !known[version] {
+			return nil, fmt.Errorf("%w (version %d)", migrations.ErrUnknownVersion, version)
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// inConnTx runs fn in a transaction on conn, committing when it succeeds
+func inConnTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
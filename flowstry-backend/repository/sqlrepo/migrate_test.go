@@ -0,0 +1,31 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// testMigrateDownAndUp reverts every migration of a migrated database and applies them again
+func testMigrateDownAndUp(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	m := NewMigrator(db)
+
+	reverted, err := m.Down(ctx, len(m.migrations))
+	if err != nil || len(reverted) != len(m.migrations) {
+		t.Fatalf("Down = %v, %v", reverted, err)
+	}
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != len(m.migrations) {
+		t.Fatalf("Up = %v, %v", applied, err)
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			t.Fatalf("migration %d not applied", s.Version)
+		}
+	}
+}
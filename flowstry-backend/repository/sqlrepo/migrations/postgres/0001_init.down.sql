@@ -0,0 +1,13 @@
+DROP TABLE diagram_stars;
+DROP TABLE diagram_access;
+DROP TABLE embed_tokens;
+DROP TABLE share_links;
+DROP TABLE workspace_tags;
+DROP TABLE diagram_tags;
+DROP TABLE diagrams;
+DROP TABLE folders;
+DROP TABLE workspace_invites;
+DROP TABLE workspace_members;
+DROP TABLE workspaces;
+DROP TABLE refresh_tokens;
+DROP TABLE users;
@@ -0,0 +1,170 @@
+-- IDs are 24-character ObjectID hex strings, the same IDs the MongoDB backend uses
+
+CREATE TABLE users (
+    id            TEXT PRIMARY KEY,
+    email         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL DEFAULT '',
+    name          TEXT NOT NULL,
+    avatar_url    TEXT NOT NULL DEFAULT '',
+    auth_provider TEXT NOT NULL,
+    google_id     TEXT,
+    preferences   TEXT NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX users_google_id ON users (google_id) WHERE google_id IS NOT NULL;
+
+CREATE TABLE refresh_tokens (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token      TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked    BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX refresh_tokens_user ON refresh_tokens (user_id);
+
+CREATE TABLE workspaces (
+    id                     TEXT PRIMARY KEY,
+    user_id                TEXT NOT NULL,
+    name                   TEXT NOT NULL,
+    description            TEXT NOT NULL DEFAULT '',
+    encrypted_key          BYTEA,
+    content_search_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    trash_retention_days   INTEGER NOT NULL DEFAULT 0,
+    created_at             TIMESTAMPTZ NOT NULL,
+    updated_at             TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX workspaces_user ON workspaces (user_id);
+
+CREATE TABLE workspace_members (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id      TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role         TEXT NOT NULL,
+    joined_at    TIMESTAMPTZ NOT NULL,
+    UNIQUE (workspace_id, user_id)
+);
+CREATE INDEX workspace_members_user ON workspace_members (user_id);
+
+CREATE TABLE workspace_invites (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    email        TEXT NOT NULL,
+    role         TEXT NOT NULL,
+    token        TEXT NOT NULL UNIQUE,
+    invited_by   TEXT NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    UNIQUE (workspace_id, email)
+);
+CREATE INDEX workspace_invites_email ON workspace_invites (email);
+
+CREATE TABLE folders (
+    id                  TEXT PRIMARY KEY,
+    workspace_id        TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    parent_folder_id    TEXT,
+    name                TEXT NOT NULL,
+    description         TEXT NOT NULL DEFAULT '',
+    color               TEXT NOT NULL DEFAULT '',
+    created_by          TEXT,
+    deleted_at          TIMESTAMPTZ,
+    trash_batch_id      TEXT,
+    deleted_with_parent BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ NOT NULL,
+    updated_at          TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX folders_workspace_parent ON folders (workspace_id, parent_folder_id);
+CREATE INDEX folders_workspace_deleted ON folders (workspace_id, deleted_at);
+CREATE INDEX folders_trash_batch ON folders (workspace_id, trash_batch_id) WHERE trash_batch_id IS NOT NULL;
+
+CREATE TABLE diagrams (
+    id                  TEXT PRIMARY KEY,
+    workspace_id        TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    folder_id           TEXT,
+    name                TEXT NOT NULL,
+    description         TEXT NOT NULL DEFAULT '',
+    file_url            TEXT NOT NULL DEFAULT '',
+    file_size           BIGINT NOT NULL DEFAULT 0,
+    thumbnail           TEXT NOT NULL DEFAULT '',
+    version             INTEGER NOT NULL DEFAULT 0,
+    template_scope      TEXT NOT NULL DEFAULT '',
+    created_by          TEXT,
+    search_content      TEXT NOT NULL DEFAULT '',
+    deleted_at          TIMESTAMPTZ,
+    trash_batch_id      TEXT,
+    deleted_with_parent BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ NOT NULL,
+    updated_at          TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX diagrams_workspace_folder ON diagrams (workspace_id, folder_id);
+CREATE INDEX diagrams_workspace_deleted ON diagrams (workspace_id, deleted_at);
+CREATE INDEX diagrams_trash_batch ON diagrams (workspace_id, trash_batch_id) WHERE trash_batch_id IS NOT NULL;
+CREATE INDEX diagrams_templates ON diagrams (template_scope) WHERE template_scope <> '';
+
+-- Tags of a diagram in the order they were added
+CREATE TABLE diagram_tags (
+    diagram_id TEXT NOT NULL REFERENCES diagrams (id) ON DELETE CASCADE,
+    tag        TEXT NOT NULL,
+    position   INTEGER NOT NULL,
+    PRIMARY KEY (diagram_id, tag)
+);
+CREATE INDEX diagram_tags_tag ON diagram_tags (tag);
+
+CREATE TABLE workspace_tags (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    color        TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL,
+    UNIQUE (workspace_id, name)
+);
+
+CREATE TABLE share_links (
+    id             TEXT PRIMARY KEY,
+    workspace_id   TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    diagram_id     TEXT NOT NULL,
+    token_hash     TEXT NOT NULL UNIQUE,
+    password_hash  TEXT NOT NULL DEFAULT '',
+    created_by     TEXT NOT NULL,
+    expires_at     TIMESTAMPTZ,
+    revoked_at     TIMESTAMPTZ,
+    last_viewed_at TIMESTAMPTZ,
+    view_count     BIGINT NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX share_links_diagram ON share_links (workspace_id, diagram_id);
+
+CREATE TABLE embed_tokens (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    diagram_id   TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE,
+    label        TEXT NOT NULL DEFAULT '',
+    created_by   TEXT NOT NULL,
+    revoked_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX embed_tokens_diagram ON embed_tokens (workspace_id, diagram_id);
+
+CREATE TABLE diagram_access (
+    id             TEXT PRIMARY KEY,
+    user_id        TEXT NOT NULL,
+    workspace_id   TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    diagram_id     TEXT NOT NULL,
+    last_opened_at TIMESTAMPTZ NOT NULL,
+    open_count     BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (user_id, diagram_id)
+);
+CREATE INDEX diagram_access_recent ON diagram_access (user_id, last_opened_at DESC);
+
+CREATE TABLE diagram_stars (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    diagram_id   TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, diagram_id)
+);
@@ -0,0 +1,21 @@
+-- Notification preferences live apart from the UI preferences
+ALTER TABLE users ADD COLUMN notification_preferences TEXT NOT NULL DEFAULT '{}';
+
+-- Repeated edits of a diagram collapse into the unread notification about it, so inboxes are ordered by updated_at
+CREATE TABLE notifications (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    type         TEXT NOT NULL,
+    actor_id     TEXT,
+    actor_name   TEXT NOT NULL DEFAULT '',
+    target_type  TEXT NOT NULL,
+    target_id    TEXT NOT NULL,
+    message      TEXT NOT NULL,
+    count        INTEGER NOT NULL DEFAULT 1,
+    read_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX notifications_user_updated ON notifications (user_id, updated_at DESC, id DESC);
+CREATE INDEX notifications_user_unread ON notifications (user_id, type, target_id) WHERE read_at IS NULL;
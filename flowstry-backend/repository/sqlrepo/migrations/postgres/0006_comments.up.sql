@@ -0,0 +1,20 @@
+-- Thread roots carry the anchor and resolution; replies reference their root through thread_id.
+-- A thread without anchor_x is about the diagram as a whole.
+CREATE TABLE comments (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    diagram_id   TEXT NOT NULL,
+    thread_id    TEXT REFERENCES comments (id) ON DELETE CASCADE,
+    author_id    TEXT NOT NULL,
+    author_name  TEXT NOT NULL DEFAULT '',
+    body         TEXT NOT NULL,
+    shape_id     TEXT,
+    anchor_x     DOUBLE PRECISION,
+    anchor_y     DOUBLE PRECISION,
+    resolved_at  TIMESTAMPTZ,
+    resolved_by  TEXT,
+    edited_at    TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX comments_diagram ON comments (workspace_id, diagram_id, thread_id, created_at);
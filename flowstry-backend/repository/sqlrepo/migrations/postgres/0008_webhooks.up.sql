@@ -0,0 +1,37 @@
+-- events holds the subscribed event types as a JSON array; secret is encrypted with the master key.
+-- Pending deliveries are the delivery queue; next_attempt_at doubles as the lease of a sending instance.
+CREATE TABLE webhooks (
+    id                   TEXT PRIMARY KEY,
+    workspace_id         TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    url                  TEXT NOT NULL,
+    description          TEXT NOT NULL DEFAULT '',
+    events               TEXT NOT NULL,
+    secret               BYTEA NOT NULL,
+    enabled              BOOLEAN NOT NULL,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_reason      TEXT NOT NULL DEFAULT '',
+    created_by           TEXT NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL,
+    updated_at           TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX webhooks_workspace ON webhooks (workspace_id, created_at);
+
+CREATE TABLE webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    workspace_id    TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    webhook_id      TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type      TEXT NOT NULL,
+    payload         TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    response_body   TEXT NOT NULL DEFAULT '',
+    last_error      TEXT NOT NULL DEFAULT '',
+    redelivery_of   TEXT,
+    created_at      TIMESTAMPTZ NOT NULL,
+    completed_at    TIMESTAMPTZ
+);
+CREATE INDEX webhook_deliveries_webhook ON webhook_deliveries (workspace_id, webhook_id, created_at);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_completed ON webhook_deliveries (completed_at);
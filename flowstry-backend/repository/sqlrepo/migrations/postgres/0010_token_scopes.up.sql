@@ -0,0 +1,4 @@
+-- scopes holds the granted scopes as a JSON array. Tokens created before scopes existed had the
+-- full power of their user, so they keep every scope.
+ALTER TABLE personal_access_tokens ADD COLUMN scopes TEXT NOT NULL DEFAULT '[]';
+UPDATE personal_access_tokens SET scopes = '["diagrams:read","diagrams:write","members:manage","workspaces:admin"]';
@@ -0,0 +1,4 @@
+-- Share links serve a copy of the diagram encrypted with a key of their own
+ALTER TABLE share_links ADD COLUMN snapshot_path TEXT NOT NULL DEFAULT '';
+ALTER TABLE share_links ADD COLUMN snapshot_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE share_links ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
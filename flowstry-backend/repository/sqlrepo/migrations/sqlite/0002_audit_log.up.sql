@@ -0,0 +1,18 @@
+-- Entries are append-only and outlive their workspace, so workspace_id has no foreign key
+
+CREATE TABLE audit_log (
+    id            TEXT PRIMARY KEY,
+    workspace_id  TEXT NOT NULL,
+    actor_id      TEXT,
+    actor_email   TEXT NOT NULL DEFAULT '',
+    ip            TEXT NOT NULL DEFAULT '',
+    user_agent    TEXT NOT NULL DEFAULT '',
+    action        TEXT NOT NULL,
+    target_type   TEXT NOT NULL,
+    target_id     TEXT NOT NULL,
+    before_values TEXT,
+    after_values  TEXT,
+    created_at    DATETIME NOT NULL
+);
+CREATE INDEX audit_log_workspace_created ON audit_log (workspace_id, created_at DESC, id DESC);
+CREATE INDEX audit_log_workspace_target ON audit_log (workspace_id, target_id);
@@ -0,0 +1,18 @@
+-- Consecutive saves extend one entry, so the feed is ordered by updated_at
+
+CREATE TABLE activity (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    actor_id     TEXT,
+    actor_name   TEXT NOT NULL DEFAULT '',
+    type         TEXT NOT NULL,
+    target_type  TEXT NOT NULL,
+    target_id    TEXT NOT NULL,
+    target_name  TEXT NOT NULL DEFAULT '',
+    details      TEXT,
+    count        INTEGER NOT NULL DEFAULT 1,
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL
+);
+CREATE INDEX activity_workspace_updated ON activity (workspace_id, updated_at DESC, id DESC);
+CREATE INDEX activity_workspace_target ON activity (workspace_id, target_id, updated_at DESC);
@@ -0,0 +1,18 @@
+-- Rendered emails wait here until the mailer delivers them; next_attempt_at doubles as the lease of a sending instance
+
+CREATE TABLE email_outbox (
+    id              TEXT PRIMARY KEY,
+    template        TEXT NOT NULL,
+    recipient       TEXT NOT NULL,
+    subject         TEXT NOT NULL,
+    text_body       TEXT NOT NULL,
+    html_body       TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      DATETIME NOT NULL,
+    sent_at         DATETIME
+);
+CREATE INDEX email_outbox_due ON email_outbox (status, next_attempt_at);
+CREATE INDEX email_outbox_sent ON email_outbox (status, sent_at);
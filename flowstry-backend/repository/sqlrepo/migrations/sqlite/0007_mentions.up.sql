@@ -0,0 +1,12 @@
+-- A source is a diagram (for its description or shape text) or a comment; see models.MentionSource.
+CREATE TABLE mentions (
+    id           TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    diagram_id   TEXT NOT NULL,
+    source       TEXT NOT NULL,
+    source_id    TEXT NOT NULL,
+    user_id      TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at   DATETIME NOT NULL,
+    UNIQUE (source, source_id, user_id)
+);
+CREATE INDEX mentions_diagram ON mentions (workspace_id, diagram_id, created_at);
@@ -0,0 +1,12 @@
+-- token holds the SHA-256 hash of the token; workspace_id restricts the token to one workspace
+CREATE TABLE personal_access_tokens (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    token        TEXT NOT NULL UNIQUE,
+    workspace_id TEXT,
+    expires_at   DATETIME NOT NULL,
+    last_used_at DATETIME,
+    created_at   DATETIME NOT NULL
+);
+CREATE INDEX personal_access_tokens_user ON personal_access_tokens (user_id, created_at);
@@ -0,0 +1,2 @@
+DROP INDEX notifications_digest;
+ALTER TABLE notifications DROP COLUMN digested_at;
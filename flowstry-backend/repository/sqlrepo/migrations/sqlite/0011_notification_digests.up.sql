@@ -0,0 +1,3 @@
+-- digested_at is set when a notification is emailed in a digest and cleared when it is bumped
+ALTER TABLE notifications ADD COLUMN digested_at DATETIME;
+CREATE INDEX notifications_digest ON notifications (user_id, updated_at) WHERE read_at IS NULL AND digested_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE share_links DROP COLUMN version;
+ALTER TABLE share_links DROP COLUMN snapshot_size;
+ALTER TABLE share_links DROP COLUMN snapshot_path;
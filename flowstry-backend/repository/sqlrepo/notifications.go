@@ -0,0 +1,150 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationRepository stores notifications in the notifications table
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+const notificationColumns = "id, user_id, workspace_id, type, actor_id, actor_name, target_type, target_id, message, count, read_at, created_at, updated_at, digested_at"
+
+// notificationSortColumns maps notification sort fields to columns
+var notificationSortColumns = sortColumns{
+	models.SortByUpdated: "updated_at",
+}
+
+// Create inserts a notification
+func (r *NotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	if n.ID.IsZero() {
+		n.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO notifications ("+notificationColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		n.ID.Hex(), n.UserID.Hex(), n.WorkspaceID.Hex(), string(n.Type), idArg(n.ActorID), n.ActorName,
+		string(n.TargetType), n.TargetID.Hex(), n.Message, n.Count, n.ReadAt, n.CreatedAt, n.UpdatedAt, n.DigestedAt)
+	return mapError(err)
+}
+
+// FindUnread returns a user's unread notification of a type about a target
+func (r *NotificationRepository) FindUnread(ctx context.Context, userID primitive.ObjectID, notificationType models.NotificationType, targetID primitive.ObjectID) (*models.Notification, error) {
+	notifications, err := r.find(ctx, " WHERE user_id = $1 AND type = $2 AND target_id = $3 AND read_at IS NULL LIMIT 1",
+		userID.Hex(), string(notificationType), targetID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return notifications[0], nil
+}
+
+// Bump folds another change into an unread notification
+func (r *NotificationRepository) Bump(ctx context.Context, userID, id primitive.ObjectID, actorID *primitive.ObjectID, actorName, message string, updatedAt time.Time) error {
+	return affected(r.db.ExecContext(ctx, `UPDATE notifications
+		SET count = count + 1, actor_id = $1, actor_name = $2, message = $3, updated_at = $4, digested_at = NULL
+		WHERE id = $5 AND user_id = $6 AND read_at IS NULL`,
+		idArg(actorID), actorName, message, updatedAt, id.Hex(), userID.Hex()))
+}
+
+// List pages through a user's notifications
+func (r *NotificationRepository) List(ctx context.Context, userID primitive.ObjectID, filter *models.NotificationFilter, page *repository.PageQuery) ([]*models.Notification, error) {
+	q := &builder{}
+	q.where("user_id = " + q.arg(userID.Hex()))
+	if filter != nil && filter.UnreadOnly {
+		q.where("read_at IS NULL")
+	}
+	order, err := q.page(notificationSortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.clause()+order, q.args...)
+}
+
+func (r *NotificationRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+notificationColumns+" FROM notifications"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		var n models.Notification
+		var notificationType, targetType string
+		err := rows.Scan(objectID{&n.ID}, objectID{&n.UserID}, objectID{&n.WorkspaceID}, &notificationType, nullID{&n.ActorID},
+			&n.ActorName, &targetType, objectID{&n.TargetID}, &n.Message, &n.Count, &n.ReadAt, &n.CreatedAt, &n.UpdatedAt, &n.DigestedAt)
+		if err != nil {
+			return nil, err
+		}
+		n.Type = models.NotificationType(notificationType)
+		n.TargetType = models.TargetType(targetType)
+		notifications = append(notifications, &n)
+	}
+	return notifications, rows.Err()
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL",
+		userID.Hex()).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks some of a user's notifications read
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, readAt time.Time) (int64, error) {
+	q := &builder{}
+	set := "read_at = " + q.arg(readAt)
+	q.where("user_id = " + q.arg(userID.Hex()))
+	q.where("read_at IS NULL")
+	q.in("id", ids)
+	return r.markRead(ctx, "UPDATE notifications SET "+set+q.clause(), q.args...)
+}
+
+// MarkAllRead marks every notification of a user read
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID primitive.ObjectID, readAt time.Time) (int64, error) {
+	return r.markRead(ctx, "UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL", readAt, userID.Hex())
+}
+
+func (r *NotificationRepository) markRead(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PendingDigest returns unread notifications not yet in a digest, by user and then update time
+func (r *NotificationRepository) PendingDigest(ctx context.Context, userID *primitive.ObjectID, updatedBefore time.Time, limit int64) ([]*models.Notification, error) {
+	q := &builder{}
+	q.where("read_at IS NULL")
+	q.where("digested_at IS NULL")
+	q.where("updated_at < " + q.arg(updatedBefore))
+	if userID != nil {
+		q.where("user_id = " + q.arg(userID.Hex()))
+	}
+	return r.find(ctx, q.clause()+" ORDER BY user_id, updated_at, id"+limitClause(limit), q.args...)
+}
+
+// MarkDigested records that notifications were emailed in a digest
+func (r *NotificationRepository) MarkDigested(ctx context.Context, ids []primitive.ObjectID, digestedAt time.Time) error {
+	q := &builder{}
+	set := "digested_at = " + q.arg(digestedAt)
+	q.in("id", ids)
+	_, err := r.db.ExecContext(ctx, "UPDATE notifications SET "+set+q.clause(), q.args...)
+	return err
+}
+
+// DeleteAllForWorkspace deletes the notifications about a workspace
+func (r *NotificationRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "notifications", workspaceID)
+}
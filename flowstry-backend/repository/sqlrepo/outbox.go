@@ -0,0 +1,92 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxRepository stores queued emails in the email_outbox table
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+const outboxColumns = "id, template, recipient, subject, text_body, html_body, status, attempts, next_attempt_at, last_error, created_at, sent_at"
+
+// Enqueue inserts an email
+func (r *OutboxRepository) Enqueue(ctx context.Context, e *repository.OutboxEmail) error {
+	if e.ID.IsZero() {
+		e.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO email_outbox ("+outboxColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		e.ID.Hex(), e.Template, e.To, e.Subject, e.Text, e.HTML, string(e.Status), e.Attempts,
+		e.NextAttemptAt, e.LastError, e.CreatedAt, e.SentAt)
+	return mapError(err)
+}
+
+// Due returns pending emails whose next attempt is due
+func (r *OutboxRepository) Due(ctx context.Context, now time.Time, limit int64) ([]*repository.OutboxEmail, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+outboxColumns+` FROM email_outbox
+		WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, id LIMIT $3`,
+		string(repository.OutboxPending), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*repository.OutboxEmail
+	for rows.Next() {
+		var e repository.OutboxEmail
+		var status string
+		err := rows.Scan(objectID{&e.ID}, &e.Template, &e.To, &e.Subject, &e.Text, &e.HTML, &status, &e.Attempts,
+			&e.NextAttemptAt, &e.LastError, &e.CreatedAt, &e.SentAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Status = repository.OutboxStatus(status)
+		emails = append(emails, &e)
+	}
+	return emails, rows.Err()
+}
+
+// Claim leases a due email
+func (r *OutboxRepository) Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE email_outbox SET next_attempt_at = $1 WHERE id = $2 AND status = $3 AND next_attempt_at <= $4",
+		leaseUntil, id.Hex(), string(repository.OutboxPending), now))
+}
+
+// MarkSent records the delivery of an email
+func (r *OutboxRepository) MarkSent(ctx context.Context, id primitive.ObjectID, attempts int, sentAt time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE email_outbox SET status = $1, attempts = $2, sent_at = $3 WHERE id = $4",
+		string(repository.OutboxSent), attempts, sentAt, id.Hex()))
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *OutboxRepository) MarkRetry(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE email_outbox SET attempts = $1, next_attempt_at = $2, last_error = $3 WHERE id = $4",
+		attempts, nextAttemptAt, lastError, id.Hex()))
+}
+
+// MarkFailed gives up on an email
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, attempts int, lastError string) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE email_outbox SET status = $1, attempts = $2, last_error = $3 WHERE id = $4",
+		string(repository.OutboxFailed), attempts, lastError, id.Hex()))
+}
+
+// DeleteSentBefore prunes delivered emails
+func (r *OutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM email_outbox WHERE status = $1 AND sent_at < $2",
+		string(repository.OutboxSent), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
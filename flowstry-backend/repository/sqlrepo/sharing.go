@@ -0,0 +1,320 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLinkRepository stores share links in the share_links table
+type ShareLinkRepository struct {
+	db *sql.DB
+}
+
+const shareLinkColumns = "id, workspace_id, diagram_id, token_hash, password_hash, created_by, " +
+	"expires_at, revoked_at, last_viewed_at, view_count, created_at, snapshot_path, snapshot_size, version"
+
+// Create inserts a share link
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO share_links ("+shareLinkColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		link.ID.Hex(), link.WorkspaceID.Hex(), link.DiagramID.Hex(), link.TokenHash, link.PasswordHash,
+		link.CreatedBy.Hex(), link.ExpiresAt, link.RevokedAt, link.LastViewedAt, link.ViewCount, link.CreatedAt,
+		link.SnapshotPath, link.SnapshotSize, link.Version)
+	return mapError(err)
+}
+
+// GetByTokenHash retrieves a share link by its token hash
+func (r *ShareLinkRepository) GetByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error) {
+	links, err := r.find(ctx, " WHERE token_hash = $1", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return links[0], nil
+}
+
+// ListForDiagram returns the share links of a diagram, newest first
+func (r *ShareLinkRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.ShareLink, error) {
+	return r.find(ctx, " WHERE workspace_id = $1 AND diagram_id = $2 ORDER BY created_at DESC",
+		workspaceID.Hex(), diagramID.Hex())
+}
+
+func (r *ShareLinkRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.ShareLink, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+shareLinkColumns+" FROM share_links"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*models.ShareLink
+	for rows.Next() {
+		var l models.ShareLink
+		err := rows.Scan(objectID{&l.ID}, objectID{&l.WorkspaceID}, objectID{&l.DiagramID}, &l.TokenHash, &l.PasswordHash,
+			objectID{&l.CreatedBy}, &l.ExpiresAt, &l.RevokedAt, &l.LastViewedAt, &l.ViewCount, &l.CreatedAt,
+			&l.SnapshotPath, &l.SnapshotSize, &l.Version)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, &l)
+	}
+	return links, rows.Err()
+}
+
+// Revoke revokes an active share link of a diagram
+func (r *ShareLinkRepository) Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	return revoke(ctx, r.db, "share_links", workspaceID, diagramID, id, at)
+}
+
+// RecordView counts a view of a share link
+func (r *ShareLinkRepository) RecordView(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE share_links SET last_viewed_at = $1, view_count = view_count + 1 WHERE id = $2", at, id.Hex())
+	return err
+}
+
+// DeleteForDiagram removes every share link of a diagram
+func (r *ShareLinkRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteForDiagram(ctx, r.db, "share_links", workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes every share link of a workspace
+func (r *ShareLinkRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "share_links", workspaceID)
+}
+
+// EmbedTokenRepository stores embed tokens in the embed_tokens table
+type EmbedTokenRepository struct {
+	db *sql.DB
+}
+
+const embedTokenColumns = "id, workspace_id, diagram_id, token_hash, label, created_by, revoked_at, last_used_at, created_at"
+
+// Create inserts an embed token
+func (r *EmbedTokenRepository) Create(ctx context.Context, token *models.EmbedToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO embed_tokens ("+embedTokenColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		token.ID.Hex(), token.WorkspaceID.Hex(), token.DiagramID.Hex(), token.TokenHash, token.Label,
+		token.CreatedBy.Hex(), token.RevokedAt, token.LastUsedAt, token.CreatedAt)
+	return mapError(err)
+}
+
+// GetByTokenHash retrieves an embed token by its hash
+func (r *EmbedTokenRepository) GetByTokenHash(ctx context.Context, hash string) (*models.EmbedToken, error) {
+	tokens, err := r.find(ctx, " WHERE token_hash = $1", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return tokens[0], nil
+}
+
+// ListForDiagram returns the embed tokens of a diagram, newest first
+func (r *EmbedTokenRepository) ListForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) ([]*models.EmbedToken, error) {
+	return r.find(ctx, " WHERE workspace_id = $1 AND diagram_id = $2 ORDER BY created_at DESC",
+		workspaceID.Hex(), diagramID.Hex())
+}
+
+func (r *EmbedTokenRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.EmbedToken, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+embedTokenColumns+" FROM embed_tokens"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.EmbedToken
+	for rows.Next() {
+		var t models.EmbedToken
+		err := rows.Scan(objectID{&t.ID}, objectID{&t.WorkspaceID}, objectID{&t.DiagramID}, &t.TokenHash, &t.Label,
+			objectID{&t.CreatedBy}, &t.RevokedAt, &t.LastUsedAt, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke revokes an active embed token of a diagram
+func (r *EmbedTokenRepository) Revoke(ctx context.Context, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	return revoke(ctx, r.db, "embed_tokens", workspaceID, diagramID, id, at)
+}
+
+// RecordUse marks an embed token as used
+func (r *EmbedTokenRepository) RecordUse(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE embed_tokens SET last_used_at = $1 WHERE id = $2", at, id.Hex())
+	return err
+}
+
+// DeleteForDiagram removes every embed token of a diagram
+func (r *EmbedTokenRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteForDiagram(ctx, r.db, "embed_tokens", workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes every embed token of a workspace
+func (r *EmbedTokenRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "embed_tokens", workspaceID)
+}
+
+// revoke sets revoked_at on an unrevoked share link or embed token of a diagram
+func revoke(ctx context.Context, db *sql.DB, table string, workspaceID, diagramID, id primitive.ObjectID, at time.Time) error {
+	return affected(db.ExecContext(ctx,
+		"UPDATE "+table+" SET revoked_at = $1 WHERE id = $2 AND workspace_id = $3 AND diagram_id = $4 AND revoked_at IS NULL",
+		at, id.Hex(), workspaceID.Hex(), diagramID.Hex()))
+}
+
+// deleteForDiagram removes every row of a table referring to a diagram
+func deleteForDiagram(ctx context.Context, db *sql.DB, table string, workspaceID, diagramID primitive.ObjectID) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE workspace_id = $1 AND diagram_id = $2",
+		workspaceID.Hex(), diagramID.Hex())
+	return err
+}
+
+// deleteForWorkspace removes every row of a table belonging to a workspace
+func deleteForWorkspace(ctx context.Context, db *sql.DB, table string, workspaceID primitive.ObjectID) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE workspace_id = $1", workspaceID.Hex())
+	return err
+}
+
+// AccessRepository stores diagram opens in the diagram_access table
+type AccessRepository struct {
+	db *sql.DB
+}
+
+// RecordOpen upserts the user's access record of a diagram
+func (r *AccessRepository) RecordOpen(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO diagram_access (id, user_id, workspace_id, diagram_id, last_opened_at, open_count)
+		VALUES ($1, $2, $3, $4, $5, 1)
+		ON CONFLICT (user_id, diagram_id) DO UPDATE SET workspace_id = excluded.workspace_id,
+			last_opened_at = excluded.last_opened_at, open_count = diagram_access.open_count + 1`,
+		primitive.NewObjectID().Hex(), userID.Hex(), workspaceID.Hex(), diagramID.Hex(), at)
+	return mapError(err)
+}
+
+// ListRecent returns the user's most recent access records in the given workspaces
+func (r *AccessRepository) ListRecent(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID, limit int64) ([]*models.DiagramAccess, error) {
+	q := &builder{}
+	q.where("user_id = " + q.arg(userID.Hex()))
+	q.in("workspace_id", workspaceIDs)
+	rows, err := r.db.QueryContext(ctx, "SELECT id, user_id, workspace_id, diagram_id, last_opened_at, open_count FROM diagram_access"+
+		q.clause()+" ORDER BY last_opened_at DESC"+limitClause(limit), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*models.DiagramAccess
+	for rows.Next() {
+		var a models.DiagramAccess
+		err := rows.Scan(objectID{&a.ID}, objectID{&a.UserID}, objectID{&a.WorkspaceID}, objectID{&a.DiagramID},
+			&a.LastOpenedAt, &a.OpenCount)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &a)
+	}
+	return records, rows.Err()
+}
+
+// DeleteForDiagram removes every access record of a diagram
+func (r *AccessRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteForDiagram(ctx, r.db, "diagram_access", workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes every access record of a workspace
+func (r *AccessRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "diagram_access", workspaceID)
+}
+
+// StarRepository stores starred diagrams in the diagram_stars table
+type StarRepository struct {
+	db *sql.DB
+}
+
+// Star inserts a star unless the diagram was already starred, then returns the stored one
+func (r *StarRepository) Star(ctx context.Context, star *models.DiagramStar) (*models.DiagramStar, error) {
+	id := star.ID
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO diagram_stars (id, user_id, workspace_id, diagram_id, created_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (user_id, diagram_id) DO NOTHING`,
+		id.Hex(), star.UserID.Hex(), star.WorkspaceID.Hex(), star.DiagramID.Hex(), star.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	stars, err := r.find(ctx, " WHERE user_id = $1 AND diagram_id = $2", star.UserID.Hex(), star.DiagramID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(stars) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return stars[0], nil
+}
+
+// Unstar removes a star
+func (r *StarRepository) Unstar(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx,
+		"DELETE FROM diagram_stars WHERE user_id = $1 AND workspace_id = $2 AND diagram_id = $3",
+		userID.Hex(), workspaceID.Hex(), diagramID.Hex()))
+}
+
+// ListForUser returns the user's stars in the given workspaces
+func (r *StarRepository) ListForUser(ctx context.Context, userID primitive.ObjectID, workspaceIDs []primitive.ObjectID) ([]*models.DiagramStar, error) {
+	q := &builder{}
+	q.where("user_id = " + q.arg(userID.Hex()))
+	q.in("workspace_id", workspaceIDs)
+	return r.find(ctx, q.clause(), q.args...)
+}
+
+// IsStarred reports whether the user starred a diagram
+func (r *StarRepository) IsStarred(ctx context.Context, userID, workspaceID, diagramID primitive.ObjectID) (bool, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM diagram_stars WHERE user_id = $1 AND workspace_id = $2 AND diagram_id = $3",
+		userID.Hex(), workspaceID.Hex(), diagramID.Hex(),
+	).Scan(&n)
+	return n > 0, err
+}
+
+func (r *StarRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.DiagramStar, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, user_id, workspace_id, diagram_id, created_at FROM diagram_stars"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stars []*models.DiagramStar
+	for rows.Next() {
+		var s models.DiagramStar
+		if err := rows.Scan(objectID{&s.ID}, objectID{&s.UserID}, objectID{&s.WorkspaceID}, objectID{&s.DiagramID}, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		stars = append(stars, &s)
+	}
+	return stars, rows.Err()
+}
+
+// DeleteForDiagram removes every star of a diagram
+func (r *StarRepository) DeleteForDiagram(ctx context.Context, workspaceID, diagramID primitive.ObjectID) error {
+	return deleteForDiagram(ctx, r.db, "diagram_stars", workspaceID, diagramID)
+}
+
+// DeleteAllForWorkspace removes every star of a workspace
+func (r *StarRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "diagram_stars", workspaceID)
+}
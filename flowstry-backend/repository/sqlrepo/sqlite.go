@@ -0,0 +1,172 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteDriverName is the database/sql name of the SQLite driver adapted to the Postgres-flavored queries
+const sqliteDriverName = "flowstry-sqlite"
+
+// sqliteTimeFormat stores times as fixed-width UTC text, so comparing and ordering them as strings is chronological.
+// The driver parses it back into a time.Time for DATETIME columns.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000000-07:00"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqliteDriver{base: &sqlite.Driver{}})
+}
+
+// OpenSQLite opens the SQLite database file at path, creating it and its directory if needed.
+// SQLite allows one writer at a time, so the pool holds a single connection.
+func OpenSQLite(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(sqliteDriverName, "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// isSQLite reports whether db was opened by OpenSQLite
+func isSQLite(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqliteDriver)
+	return ok
+}
+
+// sqliteDriver adapts the SQLite driver to the statements of this package: $n placeholders become ?n,
+// times are stored in sqliteTimeFormat and unique violations report the Postgres SQLSTATE.
+type sqliteDriver struct {
+	base driver.Driver
+}
+
+func (d *sqliteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{Conn: conn}, nil
+}
+
+type sqliteConn struct {
+	driver.Conn
+}
+
+// placeholder matches a Postgres positional parameter; no statement of this package has a $ inside a literal
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+func rebind(query string) string {
+	return placeholder.ReplaceAllString(query, "?$1")
+}
+
+func sqliteArgs(args []driver.NamedValue) []driver.NamedValue {
+	converted := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		if t, ok := arg.Value.(time.Time); ok {
+			arg.Value = t.UTC().Format(sqliteTimeFormat)
+		}
+		converted[i] = arg
+	}
+	return converted
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	result, err := execer.ExecContext(ctx, rebind(query), sqliteArgs(args))
+	return result, sqliteError(err)
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := queryer.QueryContext(ctx, rebind(query), sqliteArgs(args))
+	return rows, sqliteError(err)
+}
+
+func (c *sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, rebind(query))
+	} else {
+		stmt, err = c.Conn.Prepare(rebind(query))
+	}
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	return &sqliteStmt{Stmt: stmt}, nil
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *sqliteConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+type sqliteStmt struct {
+	driver.Stmt
+}
+
+func (s *sqliteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return nil, errors.New("sqlite statement does not support ExecContext")
+	}
+	result, err := execer.ExecContext(ctx, sqliteArgs(args))
+	return result, sqliteError(err)
+}
+
+func (s *sqliteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return nil, errors.New("sqlite statement does not support QueryContext")
+	}
+	rows, err := queryer.QueryContext(ctx, sqliteArgs(args))
+	return rows, sqliteError(err)
+}
+
+// uniqueViolation is a SQLite constraint error reported with the Postgres SQLSTATE mapError checks
+type uniqueViolation struct {
+	err error
+}
+
+func (e *uniqueViolation) Error() string    { return e.err.Error() }
+func (e *uniqueViolation) Unwrap() error    { return e.err }
+func (e *uniqueViolation) SQLState() string { return "23505" }
+
+func sqliteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return &uniqueViolation{err: err}
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/flowstry/flowstry-backend/repository"
+	"github.com/flowstry/flowstry-backend/repository/repotest"
+)
+
+func TestSQLite(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Store { return New(openTestSQLite(t)) })
+}
+
+func TestSQLiteMigrateDownAndUp(t *testing.T) {
+	testMigrateDownAndUp(t, openTestSQLite(t))
+}
+
+// openTestSQLite migrates a fresh database file in the test's temporary directory
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "flowstry.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := NewMigrator(db).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,335 @@
+// Package sqlrepo implements the repositories on PostgreSQL and SQLite through database/sql.
+// IDs keep the ObjectID format of the other backends and are stored as 24-character hex strings.
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pingTimeout bounds the connectivity check behind Store.Connected
+const pingTimeout = 2 * time.Second
+
+// Open opens a connection pool to the PostgreSQL database at dsn. It does not connect yet.
+func Open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	return db, nil
+}
+
+// New returns the repositories backed by db
+func New(db *sql.DB) *repository.Store {
+	return &repository.Store{
+		Users:         &UserRepository{db: db},
+		RefreshTokens: &RefreshTokenRepository{db: db},
+		AccessTokens:  &PersonalAccessTokenRepository{db: db},
+		Workspaces:    &WorkspaceRepository{db: db},
+		Members:       &MemberRepository{db: db},
+		Invites:       &InviteRepository{db: db},
+		Folders:       &FolderRepository{db: db},
+		Diagrams:      &DiagramRepository{db: db},
+		Tags:          &TagRepository{db: db},
+		ShareLinks:    &ShareLinkRepository{db: db},
+		EmbedTokens:   &EmbedTokenRepository{db: db},
+		Access:        &AccessRepository{db: db},
+		Stars:         &StarRepository{db: db},
+		Audit:         &AuditRepository{db: db},
+		Activity:      &ActivityRepository{db: db},
+		Comments:      &CommentRepository{db: db},
+		Mentions:      &MentionRepository{db: db},
+		Notifications: &NotificationRepository{db: db},
+		Outbox:        &OutboxRepository{db: db},
+		Webhooks:      &WebhookRepository{db: db},
+		Deliveries:    &WebhookDeliveryRepository{db: db},
+		Connected: func() bool {
+			ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			defer cancel()
+			return db.PingContext(ctx) == nil
+		},
+	}
+}
+
+var errUnsupportedSort = errors.New("unsupported sort field")
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// inTx runs fn in a transaction, committing when it succeeds
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// mapError converts driver errors to repository errors
+func mapError(err error) error {
+	var state interface{ SQLState() string }
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return repository.ErrNotFound
+	case errors.As(err, &state) && state.SQLState() == "23505":
+		return repository.ErrDuplicate
+	default:
+		return err
+	}
+}
+
+// affected returns ErrNotFound when a statement changed no rows
+func affected(result sql.Result, err error) error {
+	if err != nil {
+		return mapError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// count returns the number of rows a statement changed
+func count(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return result.RowsAffected()
+}
+
+// builder accumulates the conditions and positional arguments of a statement
+type builder struct {
+	conds []string
+	args  []interface{}
+}
+
+// arg adds an argument and returns its placeholder
+func (q *builder) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where ANDs a condition onto the statement
+func (q *builder) where(cond string) {
+	q.conds = append(q.conds, cond)
+}
+
+// in restricts a column to a list of IDs; an empty list matches nothing
+func (q *builder) in(column string, ids []primitive.ObjectID) {
+	q.inStrings(column, hexes(ids))
+}
+
+// hexes converts IDs to their hex strings
+func hexes(ids []primitive.ObjectID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.Hex()
+	}
+	return s
+}
+
+// inStrings restricts a column to a list of strings; an empty list matches nothing
+func (q *builder) inStrings(column string, values []string) {
+	if len(values) == 0 {
+		q.where("1 = 0")
+		return
+	}
+	q.where(column + " IN " + q.list(values))
+}
+
+// list adds values as arguments and returns their parenthesized placeholders
+func (q *builder) list(values []string) string {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = q.arg(v)
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// contains matches a column containing term, case-insensitively
+func (q *builder) contains(column, term string) string {
+	return q.like(column, "%"+escapeLike(term)+"%")
+}
+
+// startsWith matches a column starting with prefix, case-insensitively
+func (q *builder) startsWith(column, prefix string) string {
+	return q.like(column, escapeLike(prefix)+"%")
+}
+
+func (q *builder) like(column, pattern string) string {
+	return "LOWER(" + column + ") LIKE " + q.arg(pattern) + ` ESCAPE '\'`
+}
+
+// escapeLike lowercases term and escapes the LIKE wildcards in it
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(term))
+}
+
+// dateRange restricts a timestamp column to the given range
+func (q *builder) dateRange(column string, r models.DateRange) {
+	if r.After != nil {
+		q.where(column + " >= " + q.arg(*r.After))
+	}
+	if r.Before != nil {
+		q.where(column + " < " + q.arg(*r.Before))
+	}
+}
+
+// trash restricts a table to live or trashed rows
+func (q *builder) trash(prefix string, trash repository.Trash) {
+	switch trash {
+	case repository.Live:
+		q.where(prefix + "deleted_at IS NULL")
+	case repository.Trashed:
+		q.where(prefix + "deleted_at IS NOT NULL")
+	}
+}
+
+// clause returns the WHERE clause, or "" without conditions
+func (q *builder) clause() string {
+	if len(q.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conds, " AND ")
+}
+
+// sortColumns maps the sort fields a table supports to columns
+type sortColumns map[models.SortField]string
+
+// page adds the keyset condition of page and returns the ORDER BY and LIMIT clauses,
+// tie-broken by idColumn so the order is total. Name sorts are case-insensitive.
+func (q *builder) page(columns sortColumns, idColumn string, page *repository.PageQuery) (string, error) {
+	column, ok := columns[page.Sort]
+	if !ok {
+		return "", errUnsupportedSort
+	}
+	if page.Sort == models.SortByName {
+		column = "LOWER(" + column + ")"
+	}
+
+	op, dir := "<", "DESC"
+	if page.Order == models.SortAsc {
+		op, dir = ">", "ASC"
+	}
+	if page.After != nil {
+		value := page.After.Value
+		if s, ok := value.(string); ok && page.Sort == models.SortByName {
+			value = strings.ToLower(s)
+		}
+		v, id := q.arg(value), q.arg(page.After.ID.Hex())
+		q.where(fmt.Sprintf("(%s %s %s OR (%s = %s AND %s %s %s))", column, op, v, column, v, idColumn, op, id))
+	}
+
+	return fmt.Sprintf(" ORDER BY %s %s, %s %s", column, dir, idColumn, dir) + limitClause(page.Limit), nil
+}
+
+// limitClause returns the LIMIT clause, or "" for no limit
+func limitClause(limit int64) string {
+	if limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d", limit)
+}
+
+// objectID scans a hex ID column into an ObjectID
+type objectID struct{ dst *primitive.ObjectID }
+
+func (s objectID) Scan(src interface{}) error {
+	hex, err := asString(src)
+	if err != nil {
+		return err
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return err
+	}
+	*s.dst = id
+	return nil
+}
+
+// nullID scans a nullable hex ID column into an optional ObjectID
+type nullID struct{ dst **primitive.ObjectID }
+
+func (s nullID) Scan(src interface{}) error {
+	if src == nil {
+		*s.dst = nil
+		return nil
+	}
+	var id primitive.ObjectID
+	if err := (objectID{&id}).Scan(src); err != nil {
+		return err
+	}
+	*s.dst = &id
+	return nil
+}
+
+func asString(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case string:
+		return strings.TrimSpace(v), nil
+	case []byte:
+		return strings.TrimSpace(string(v)), nil
+	default:
+		return "", fmt.Errorf("cannot scan %T into an ID", src)
+	}
+}
+
+// idArg converts an optional ObjectID to a nullable argument
+func idArg(id *primitive.ObjectID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.Hex()
+}
+
+// textArg stores an empty string as NULL (for optional unique columns)
+func textArg(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// scanIDs reads a single-column result of IDs
+func scanIDs(rows *sql.Rows, err error) ([]primitive.ObjectID, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []primitive.ObjectID
+	for rows.Next() {
+		var id primitive.ObjectID
+		if err := rows.Scan(objectID{&id}); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,110 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TagRepository stores tag definitions in the workspace_tags table
+type TagRepository struct {
+	db *sql.DB
+}
+
+const tagColumns = "id, workspace_id, name, color, created_at, updated_at"
+
+// Create inserts a tag
+func (r *TagRepository) Create(ctx context.Context, tag *models.WorkspaceTag) error {
+	if tag.ID.IsZero() {
+		tag.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO workspace_tags ("+tagColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		tag.ID.Hex(), tag.WorkspaceID.Hex(), tag.Name, tag.Color, tag.CreatedAt, tag.UpdatedAt)
+	return mapError(err)
+}
+
+// Get retrieves a tag of a workspace
+func (r *TagRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.WorkspaceTag, error) {
+	tags, err := r.find(ctx, " WHERE id = $1 AND workspace_id = $2", id.Hex(), workspaceID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return tags[0], nil
+}
+
+// List returns the tags of a workspace ordered by name
+func (r *TagRepository) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceTag, error) {
+	return r.find(ctx, " WHERE workspace_id = $1 ORDER BY name", workspaceID.Hex())
+}
+
+func (r *TagRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.WorkspaceTag, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+tagColumns+" FROM workspace_tags"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*models.WorkspaceTag
+	for rows.Next() {
+		var t models.WorkspaceTag
+		if err := rows.Scan(objectID{&t.ID}, objectID{&t.WorkspaceID}, &t.Name, &t.Color, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &t)
+	}
+	return tags, rows.Err()
+}
+
+// Update changes the given fields of a tag
+func (r *TagRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.TagUpdate) error {
+	q := &builder{}
+	set := []string{"updated_at = " + q.arg(update.UpdatedAt)}
+	if update.Name != nil {
+		set = append(set, "name = "+q.arg(*update.Name))
+	}
+	if update.Color != nil {
+		set = append(set, "color = "+q.arg(*update.Color))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+
+	return affected(r.db.ExecContext(ctx, "UPDATE workspace_tags SET "+strings.Join(set, ", ")+q.clause(), q.args...))
+}
+
+// EnsureDefined inserts a default-colored tag for each name that has none, ignoring existing ones
+func (r *TagRepository) EnsureDefined(ctx context.Context, workspaceID primitive.ObjectID, names []string, at time.Time) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	q := &builder{}
+	rows := make([]string, len(names))
+	for i, name := range names {
+		rows[i] = "(" + strings.Join([]string{
+			q.arg(primitive.NewObjectID().Hex()), q.arg(workspaceID.Hex()), q.arg(name),
+			q.arg(models.DefaultTagColor), q.arg(at), q.arg(at),
+		}, ", ") + ")"
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO workspace_tags ("+tagColumns+") VALUES "+strings.Join(rows, ", ")+
+		" ON CONFLICT (workspace_id, name) DO NOTHING", q.args...)
+	return err
+}
+
+// Delete removes a tag
+func (r *TagRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx, "DELETE FROM workspace_tags WHERE id = $1 AND workspace_id = $2",
+		id.Hex(), workspaceID.Hex()))
+}
+
+// DeleteAllForWorkspace removes every tag of a workspace
+func (r *TagRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "workspace_tags", workspaceID)
+}
@@ -0,0 +1,229 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/auth/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository stores users in the users table
+type UserRepository struct {
+	db *sql.DB
+}
+
+const userColumns = "id, email, password_hash, name, avatar_url, auth_provider, COALESCE(google_id, ''), preferences, notification_preferences, created_at, updated_at"
+
+// Create inserts a user
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	prefs, err := json.Marshal(user.Preferences)
+	if err != nil {
+		return err
+	}
+	notifications, err := json.Marshal(user.Notifications)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO users
+		(id, email, password_hash, name, avatar_url, auth_provider, google_id, preferences, notification_preferences, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		user.ID.Hex(), user.Email, user.PasswordHash, user.Name, user.AvatarURL, string(user.AuthProvider),
+		textArg(user.GoogleID), string(prefs), string(notifications), user.CreatedAt, user.UpdatedAt)
+	return mapError(err)
+}
+
+// Get retrieves a user by ID
+func (r *UserRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, "id = $1", id.Hex())
+}
+
+// GetByEmail retrieves a user by email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, "email = $1", email)
+}
+
+// GetByGoogleID retrieves a user by linked Google account
+func (r *UserRepository) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
+	return r.findOne(ctx, "google_id = $1", googleID)
+}
+
+func (r *UserRepository) findOne(ctx context.Context, cond string, arg interface{}) (*models.User, error) {
+	var user models.User
+	var provider, prefs, notifications string
+	err := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE "+cond, arg).Scan(
+		objectID{&user.ID}, &user.Email, &user.PasswordHash, &user.Name, &user.AvatarURL,
+		&provider, &user.GoogleID, &prefs, &notifications, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	user.AuthProvider = models.AuthProvider(provider)
+	if err := json.Unmarshal([]byte(prefs), &user.Preferences); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(notifications), &user.Notifications); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkGoogle attaches a Google account to a user
+func (r *UserRepository) LinkGoogle(ctx context.Context, id primitive.ObjectID, googleID, avatarURL string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET google_id = $1, auth_provider = $2, avatar_url = $3, updated_at = $4 WHERE id = $5",
+		textArg(googleID), string(models.AuthProviderGoogle), avatarURL, time.Now(), id.Hex())
+	return mapError(err)
+}
+
+// UpdatePreferences replaces a user's preferences
+func (r *UserRepository) UpdatePreferences(ctx context.Context, id primitive.ObjectID, prefs models.UserPreferences) error {
+	encoded, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE users SET preferences = $1, updated_at = $2 WHERE id = $3",
+		string(encoded), time.Now(), id.Hex())
+	return err
+}
+
+// UpdateNotificationPreferences replaces a user's notification preferences
+func (r *UserRepository) UpdateNotificationPreferences(ctx context.Context, id primitive.ObjectID, prefs models.NotificationPreferences) error {
+	encoded, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE users SET notification_preferences = $1, updated_at = $2 WHERE id = $3",
+		string(encoded), time.Now(), id.Hex())
+	return err
+}
+
+// RefreshTokenRepository stores hashed refresh tokens in the refresh_tokens table
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+// Create inserts a refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (id, user_id, token, expires_at, created_at, revoked) VALUES ($1, $2, $3, $4, $5, $6)",
+		token.ID.Hex(), token.UserID.Hex(), token.Token, token.ExpiresAt, token.CreatedAt, token.Revoked)
+	return mapError(err)
+}
+
+// GetByHash retrieves a refresh token by its hash
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, user_id, token, expires_at, created_at, revoked FROM refresh_tokens WHERE token = $1", hash,
+	).Scan(objectID{&token.ID}, objectID{&token.UserID}, &token.Token, &token.ExpiresAt, &token.CreatedAt, &token.Revoked)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+// Revoke revokes a refresh token
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, hash string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked = TRUE WHERE token = $1", hash)
+	return err
+}
+
+// RevokeAllForUser revokes every refresh token of a user
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = $1", userID.Hex())
+	return err
+}
+
+// PersonalAccessTokenRepository stores hashed personal access tokens in the personal_access_tokens table
+type PersonalAccessTokenRepository struct {
+	db *sql.DB
+}
+
+const accessTokenColumns = "id, user_id, name, token, workspace_id, scopes, expires_at, last_used_at, created_at"
+
+// Create inserts a personal access token
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []models.TokenScope{}
+	}
+	encoded, err := json.Marshal(scopes)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO personal_access_tokens ("+accessTokenColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		token.ID.Hex(), token.UserID.Hex(), token.Name, token.Token, idArg(token.WorkspaceID), string(encoded),
+		token.ExpiresAt, token.LastUsedAt, token.CreatedAt)
+	return mapError(err)
+}
+
+// GetByHash retrieves a personal access token by its hash
+func (r *PersonalAccessTokenRepository) GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	tokens, err := r.find(ctx, " WHERE token = $1", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return tokens[0], nil
+}
+
+// ListForUser returns the tokens of a user, newest first
+func (r *PersonalAccessTokenRepository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	return r.find(ctx, " WHERE user_id = $1 ORDER BY created_at DESC, id DESC", userID.Hex())
+}
+
+func (r *PersonalAccessTokenRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.PersonalAccessToken, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+accessTokenColumns+" FROM personal_access_tokens"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.PersonalAccessToken
+	for rows.Next() {
+		var t models.PersonalAccessToken
+		var scopes string
+		err := rows.Scan(objectID{&t.ID}, objectID{&t.UserID}, &t.Name, &t.Token, nullID{&t.WorkspaceID}, &scopes,
+			&t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &t)
+	}
+	return tokens, rows.Err()
+}
+
+// Touch records when a token was last used
+func (r *PersonalAccessTokenRepository) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return affected(r.db.ExecContext(ctx, "UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2", at, id.Hex()))
+}
+
+// Delete removes a token of a user
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx, "DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2", id.Hex(), userID.Hex()))
+}
@@ -0,0 +1,278 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookRepository stores webhooks in the webhooks table
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+const webhookColumns = "id, workspace_id, url, description, events, secret, enabled, consecutive_failures, disabled_reason, " +
+	"created_by, created_at, updated_at"
+
+// eventsArg stores subscribed event types as a JSON array
+func eventsArg(events []models.EventType) (string, error) {
+	if events == nil {
+		events = []models.EventType{}
+	}
+	encoded, err := json.Marshal(events)
+	return string(encoded), err
+}
+
+// Create inserts a webhook
+func (r *WebhookRepository) Create(ctx context.Context, w *models.Webhook) error {
+	if w.ID.IsZero() {
+		w.ID = primitive.NewObjectID()
+	}
+	events, err := eventsArg(w.Events)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO webhooks ("+webhookColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		w.ID.Hex(), w.WorkspaceID.Hex(), w.URL, w.Description, events, w.EncryptedSecret, w.Enabled, w.ConsecutiveFailures,
+		w.DisabledReason, w.CreatedBy.Hex(), w.CreatedAt, w.UpdatedAt)
+	return mapError(err)
+}
+
+// Get retrieves a webhook of a workspace
+func (r *WebhookRepository) Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.Webhook, error) {
+	webhooks, err := r.find(ctx, " WHERE id = $1 AND workspace_id = $2", id.Hex(), workspaceID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return webhooks[0], nil
+}
+
+// List returns the webhooks of a workspace, oldest first
+func (r *WebhookRepository) List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Webhook, error) {
+	return r.find(ctx, " WHERE workspace_id = $1 ORDER BY created_at, id", workspaceID.Hex())
+}
+
+// ListSubscribed returns the enabled webhooks of a workspace that subscribe to an event type.
+// Subscriptions are matched after loading, as a workspace has few webhooks.
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType) ([]*models.Webhook, error) {
+	webhooks, err := r.find(ctx, " WHERE workspace_id = $1 AND enabled = $2 ORDER BY created_at, id", workspaceID.Hex(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscribed []*models.Webhook
+	for _, w := range webhooks {
+		if w.Subscribes(eventType) {
+			subscribed = append(subscribed, w)
+		}
+	}
+	return subscribed, nil
+}
+
+func (r *WebhookRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		var w models.Webhook
+		var events string
+		err := rows.Scan(objectID{&w.ID}, objectID{&w.WorkspaceID}, &w.URL, &w.Description, &events, &w.EncryptedSecret,
+			&w.Enabled, &w.ConsecutiveFailures, &w.DisabledReason, objectID{&w.CreatedBy}, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, &w)
+	}
+	return webhooks, rows.Err()
+}
+
+// Update changes a webhook
+func (r *WebhookRepository) Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *repository.WebhookUpdate) error {
+	q := &builder{}
+	set := "updated_at = " + q.arg(update.UpdatedAt)
+	if update.URL != nil {
+		set += ", url = " + q.arg(*update.URL)
+	}
+	if update.Description != nil {
+		set += ", description = " + q.arg(*update.Description)
+	}
+	if update.Events != nil {
+		events, err := eventsArg(update.Events)
+		if err != nil {
+			return err
+		}
+		set += ", events = " + q.arg(events)
+	}
+	if update.EncryptedSecret != nil {
+		set += ", secret = " + q.arg(update.EncryptedSecret)
+	}
+	if update.Enabled != nil {
+		set += ", enabled = " + q.arg(*update.Enabled)
+		if *update.Enabled {
+			set += ", consecutive_failures = 0, disabled_reason = ''"
+		}
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	return affected(r.db.ExecContext(ctx, "UPDATE webhooks SET "+set+q.clause(), q.args...))
+}
+
+// RecordSuccess clears the failure count of a webhook
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx, "UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1", id.Hex()))
+}
+
+// RecordFailure counts a failed attempt and disables the webhook at maxFailures
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id primitive.ObjectID, maxFailures int, reason string, now time.Time) (bool, error) {
+	err := affected(r.db.ExecContext(ctx,
+		"UPDATE webhooks SET consecutive_failures = consecutive_failures + 1 WHERE id = $1", id.Hex()))
+	if err != nil {
+		return false, err
+	}
+
+	// Only the call that flips enabled reports the webhook as disabled
+	disabled, err := count(r.db.ExecContext(ctx,
+		"UPDATE webhooks SET enabled = $1, disabled_reason = $2, updated_at = $3 WHERE id = $4 AND enabled = $5 AND consecutive_failures >= $6",
+		false, reason, now, id.Hex(), true, maxFailures))
+	return disabled > 0, err
+}
+
+// Delete removes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND workspace_id = $2", id.Hex(), workspaceID.Hex()))
+}
+
+// DeleteAllForWorkspace removes every webhook of a workspace
+func (r *WebhookRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "webhooks", workspaceID)
+}
+
+// WebhookDeliveryRepository stores webhook deliveries in the webhook_deliveries table
+type WebhookDeliveryRepository struct {
+	db *sql.DB
+}
+
+const deliveryColumns = "id, workspace_id, webhook_id, event_type, payload, status, attempts, next_attempt_at, " +
+	"response_status, response_body, last_error, redelivery_of, created_at, completed_at"
+
+// deliverySortColumns maps delivery sort fields to columns
+var deliverySortColumns = sortColumns{
+	models.SortByCreated: "created_at",
+}
+
+// Create inserts a delivery
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, d *models.WebhookDelivery) error {
+	if d.ID.IsZero() {
+		d.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO webhook_deliveries ("+deliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		d.ID.Hex(), d.WorkspaceID.Hex(), d.WebhookID.Hex(), string(d.EventType), d.Payload, string(d.Status), d.Attempts,
+		d.NextAttemptAt, d.ResponseStatus, d.ResponseBody, d.LastError, idArg(d.RedeliveryOf), d.CreatedAt, d.CompletedAt)
+	return mapError(err)
+}
+
+// Get retrieves a delivery of a webhook
+func (r *WebhookDeliveryRepository) Get(ctx context.Context, workspaceID, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	deliveries, err := r.find(ctx, " WHERE id = $1 AND workspace_id = $2 AND webhook_id = $3",
+		id.Hex(), workspaceID.Hex(), webhookID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return deliveries[0], nil
+}
+
+// List pages through the deliveries of a webhook
+func (r *WebhookDeliveryRepository) List(ctx context.Context, workspaceID, webhookID primitive.ObjectID, filter *models.WebhookDeliveryFilter, page *repository.PageQuery) ([]*models.WebhookDelivery, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	q.where("webhook_id = " + q.arg(webhookID.Hex()))
+	if filter != nil && filter.Status != "" {
+		q.where("status = " + q.arg(string(filter.Status)))
+	}
+	order, err := q.page(deliverySortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.clause()+order, q.args...)
+}
+
+// Due returns pending deliveries whose next attempt is due
+func (r *WebhookDeliveryRepository) Due(ctx context.Context, now time.Time, limit int64) ([]*models.WebhookDelivery, error) {
+	return r.find(ctx, " WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, id LIMIT $3",
+		string(models.WebhookDeliveryPending), now, limit)
+}
+
+func (r *WebhookDeliveryRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var eventType, status string
+		err := rows.Scan(objectID{&d.ID}, objectID{&d.WorkspaceID}, objectID{&d.WebhookID}, &eventType, &d.Payload, &status,
+			&d.Attempts, &d.NextAttemptAt, &d.ResponseStatus, &d.ResponseBody, &d.LastError, nullID{&d.RedeliveryOf},
+			&d.CreatedAt, &d.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		d.EventType = models.EventType(eventType)
+		d.Status = models.WebhookDeliveryStatus(status)
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Claim leases a due delivery
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = $2 AND status = $3 AND next_attempt_at <= $4",
+		leaseUntil, id.Hex(), string(models.WebhookDeliveryPending), now))
+}
+
+// RecordAttempt stores the outcome of an attempt
+func (r *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, a *repository.WebhookAttempt) error {
+	return affected(r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3,
+		response_status = $4, response_body = $5, last_error = $6, completed_at = $7 WHERE id = $8`,
+		string(a.Status), a.Attempts, a.NextAttemptAt, a.ResponseStatus, a.ResponseBody, a.LastError, a.CompletedAt, id.Hex()))
+}
+
+// DeleteCompletedBefore prunes deliveries completed before a time
+func (r *WebhookDeliveryRepository) DeleteCompletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	return count(r.db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE completed_at < $1", before))
+}
+
+// DeleteForWebhook removes every delivery of a webhook
+func (r *WebhookDeliveryRepository) DeleteForWebhook(ctx context.Context, workspaceID, webhookID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE workspace_id = $1 AND webhook_id = $2",
+		workspaceID.Hex(), webhookID.Hex())
+	return err
+}
+
+// DeleteAllForWorkspace removes every delivery of a workspace
+func (r *WebhookDeliveryRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	return deleteForWorkspace(ctx, r.db, "webhook_deliveries", workspaceID)
+}
@@ -0,0 +1,370 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"github.com/flowstry/flowstry-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkspaceRepository stores workspaces in the workspaces table
+type WorkspaceRepository struct {
+	db *sql.DB
+}
+
+const workspaceColumns = "id, user_id, name, description, encrypted_key, content_search_enabled, trash_retention_days, created_at, updated_at"
+
+// Create inserts a workspace
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	return createWorkspace(ctx, r.db, workspace)
+}
+
+// CreateWithOwner inserts a workspace and its owner's membership in one transaction
+func (r *WorkspaceRepository) CreateWithOwner(ctx context.Context, workspace *models.Workspace, owner *models.WorkspaceMember) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := createWorkspace(ctx, tx, workspace); err != nil {
+			return err
+		}
+		owner.WorkspaceID = workspace.ID
+		return createMember(ctx, tx, owner)
+	})
+}
+
+func createWorkspace(ctx context.Context, q querier, workspace *models.Workspace) error {
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	_, err := q.ExecContext(ctx, "INSERT INTO workspaces ("+workspaceColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		workspace.ID.Hex(), workspace.UserID.Hex(), workspace.Name, workspace.Description, workspace.EncryptedKey,
+		workspace.ContentSearchEnabled, workspace.TrashRetentionDays, workspace.CreatedAt, workspace.UpdatedAt)
+	return mapError(err)
+}
+
+// Get retrieves a workspace by ID
+func (r *WorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	workspaces, err := r.find(ctx, " WHERE id = $1", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(workspaces) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return workspaces[0], nil
+}
+
+// ListByIDs retrieves the workspaces with the given IDs
+func (r *WorkspaceRepository) ListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Workspace, error) {
+	q := &builder{}
+	q.in("id", ids)
+	return r.find(ctx, q.clause(), q.args...)
+}
+
+// ListOwnedBy retrieves the workspaces owned by a user
+func (r *WorkspaceRepository) ListOwnedBy(ctx context.Context, userID primitive.ObjectID) ([]*models.Workspace, error) {
+	return r.find(ctx, " WHERE user_id = $1", userID.Hex())
+}
+
+func (r *WorkspaceRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.Workspace, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+workspaceColumns+" FROM workspaces"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*models.Workspace
+	for rows.Next() {
+		var w models.Workspace
+		err := rows.Scan(objectID{&w.ID}, objectID{&w.UserID}, &w.Name, &w.Description, &w.EncryptedKey,
+			&w.ContentSearchEnabled, &w.TrashRetentionDays, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, &w)
+	}
+	return workspaces, rows.Err()
+}
+
+// Update changes the given workspace fields
+func (r *WorkspaceRepository) Update(ctx context.Context, id primitive.ObjectID, update *repository.WorkspaceUpdate) error {
+	q := &builder{}
+	set := []string{"updated_at = " + q.arg(update.UpdatedAt)}
+	if update.Name != nil {
+		set = append(set, "name = "+q.arg(*update.Name))
+	}
+	if update.Description != nil {
+		set = append(set, "description = "+q.arg(*update.Description))
+	}
+	if update.ContentSearchEnabled != nil {
+		set = append(set, "content_search_enabled = "+q.arg(*update.ContentSearchEnabled))
+	}
+	if update.TrashRetentionDays != nil {
+		set = append(set, "trash_retention_days = "+q.arg(*update.TrashRetentionDays))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+
+	return affected(r.db.ExecContext(ctx, "UPDATE workspaces SET "+strings.Join(set, ", ")+q.clause(), q.args...))
+}
+
+// Delete removes a workspace; its members, content and sharing records go with it
+func (r *WorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM workspaces WHERE id = $1", id.Hex())
+	return err
+}
+
+// MemberRepository stores memberships in the workspace_members table
+type MemberRepository struct {
+	db *sql.DB
+}
+
+// memberSortColumns maps member sort fields to columns of the member-user join
+var memberSortColumns = sortColumns{
+	models.SortByName:    "u.name",
+	models.SortByCreated: "m.joined_at",
+}
+
+const memberColumns = "id, workspace_id, user_id, role, joined_at"
+
+// Create inserts a membership
+func (r *MemberRepository) Create(ctx context.Context, member *models.WorkspaceMember) error {
+	return createMember(ctx, r.db, member)
+}
+
+func createMember(ctx context.Context, q querier, member *models.WorkspaceMember) error {
+	if member.ID.IsZero() {
+		member.ID = primitive.NewObjectID()
+	}
+	_, err := q.ExecContext(ctx, "INSERT INTO workspace_members ("+memberColumns+") VALUES ($1, $2, $3, $4, $5)",
+		member.ID.Hex(), member.WorkspaceID.Hex(), member.UserID.Hex(), string(member.Role), member.JoinedAt)
+	return mapError(err)
+}
+
+// Get retrieves the membership of a user in a workspace
+func (r *MemberRepository) Get(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	members, err := r.find(ctx, "workspace_id = $1 AND user_id = $2", workspaceID.Hex(), userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return members[0], nil
+}
+
+// List pages through a workspace's members joined with their user profiles
+func (r *MemberRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.MemberQuery, page *repository.PageQuery) ([]*repository.MemberDetail, error) {
+	q := &builder{}
+	q.where("m.workspace_id = " + q.arg(workspaceID.Hex()))
+	if query != nil {
+		if len(query.Roles) > 0 {
+			roles := make([]string, len(query.Roles))
+			for i, role := range query.Roles {
+				roles[i] = string(role)
+			}
+			q.inStrings("m.role", roles)
+		}
+		switch {
+		case query.UserID != nil:
+			q.where("m.user_id = " + q.arg(query.UserID.Hex()))
+		case query.ExcludeUserID != nil:
+			q.where("m.user_id <> " + q.arg(query.ExcludeUserID.Hex()))
+		}
+		q.dateRange("m.joined_at", query.Joined)
+		if query.Prefix != "" {
+			q.where("(" + q.startsWith("u.email", query.Prefix) + " OR " + q.startsWith("u.name", query.Prefix) +
+				" OR " + q.like("u.name", "% "+escapeLike(query.Prefix)+"%") + ")")
+		}
+	}
+	order, err := q.page(memberSortColumns, "m.id", page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT m.id, m.workspace_id, m.user_id, m.role, m.joined_at, u.email, u.name, u.avatar_url
+		FROM workspace_members m JOIN users u ON u.id = m.user_id`+q.clause()+order, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*repository.MemberDetail
+	for rows.Next() {
+		var m repository.MemberDetail
+		var role string
+		err := rows.Scan(objectID{&m.ID}, objectID{&m.WorkspaceID}, objectID{&m.UserID}, &role, &m.JoinedAt,
+			&m.Email, &m.Name, &m.AvatarURL)
+		if err != nil {
+			return nil, err
+		}
+		m.Role = models.WorkspaceRole(role)
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+// ListForUser retrieves every membership of a user
+func (r *MemberRepository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceMember, error) {
+	return r.find(ctx, "user_id = $1", userID.Hex())
+}
+
+func (r *MemberRepository) find(ctx context.Context, cond string, args ...interface{}) ([]*models.WorkspaceMember, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+memberColumns+" FROM workspace_members WHERE "+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*models.WorkspaceMember
+	for rows.Next() {
+		var m models.WorkspaceMember
+		var role string
+		if err := rows.Scan(objectID{&m.ID}, objectID{&m.WorkspaceID}, objectID{&m.UserID}, &role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		m.Role = models.WorkspaceRole(role)
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+// Count returns the number of members of a workspace
+func (r *MemberRepository) Count(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM workspace_members WHERE workspace_id = $1", workspaceID.Hex()).Scan(&n)
+	return n, err
+}
+
+// UpdateRole changes the role of a member
+func (r *MemberRepository) UpdateRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.WorkspaceRole) error {
+	return affected(r.db.ExecContext(ctx,
+		"UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3",
+		string(role), workspaceID.Hex(), userID.Hex()))
+}
+
+// Delete removes a member from a workspace
+func (r *MemberRepository) Delete(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx,
+		"DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID.Hex(), userID.Hex()))
+}
+
+// DeleteAllForWorkspace removes every member of a workspace
+func (r *MemberRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM workspace_members WHERE workspace_id = $1", workspaceID.Hex())
+	return err
+}
+
+// InviteRepository stores invites in the workspace_invites table
+type InviteRepository struct {
+	db *sql.DB
+}
+
+// inviteSortColumns maps invite sort fields to columns
+var inviteSortColumns = sortColumns{
+	models.SortByName:    "email",
+	models.SortByCreated: "created_at",
+}
+
+const inviteColumns = "id, workspace_id, email, role, token, invited_by, expires_at, created_at"
+
+// Create inserts an invite
+func (r *InviteRepository) Create(ctx context.Context, invite *models.WorkspaceInvite) error {
+	if invite.ID.IsZero() {
+		invite.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO workspace_invites ("+inviteColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		invite.ID.Hex(), invite.WorkspaceID.Hex(), invite.Email, string(invite.Role), invite.Token,
+		invite.InvitedBy.Hex(), invite.ExpiresAt, invite.CreatedAt)
+	return mapError(err)
+}
+
+// Get retrieves an invite by ID
+func (r *InviteRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.WorkspaceInvite, error) {
+	return r.findOne(ctx, "id = $1", id.Hex())
+}
+
+// GetByToken retrieves an invite by token
+func (r *InviteRepository) GetByToken(ctx context.Context, token string) (*models.WorkspaceInvite, error) {
+	return r.findOne(ctx, "token = $1", token)
+}
+
+// GetByEmail retrieves the invite of an email to a workspace
+func (r *InviteRepository) GetByEmail(ctx context.Context, workspaceID primitive.ObjectID, email string) (*models.WorkspaceInvite, error) {
+	return r.findOne(ctx, "workspace_id = $1 AND email = $2", workspaceID.Hex(), email)
+}
+
+func (r *InviteRepository) findOne(ctx context.Context, cond string, args ...interface{}) (*models.WorkspaceInvite, error) {
+	invites, err := r.find(ctx, " WHERE "+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(invites) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return invites[0], nil
+}
+
+// List pages through a workspace's invites
+func (r *InviteRepository) List(ctx context.Context, workspaceID primitive.ObjectID, query *repository.InviteQuery, page *repository.PageQuery) ([]*models.WorkspaceInvite, error) {
+	q := &builder{}
+	q.where("workspace_id = " + q.arg(workspaceID.Hex()))
+	if query != nil {
+		q.where("expires_at > " + q.arg(query.ExpiresAfter))
+		if query.InvitedBy != nil {
+			q.where("invited_by = " + q.arg(query.InvitedBy.Hex()))
+		}
+		q.dateRange("created_at", query.Created)
+	}
+	order, err := q.page(inviteSortColumns, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.clause()+order, q.args...)
+}
+
+// ListForEmail retrieves the valid invites addressed to an email, newest first
+func (r *InviteRepository) ListForEmail(ctx context.Context, email string, now time.Time) ([]*models.WorkspaceInvite, error) {
+	return r.find(ctx, " WHERE email = $1 AND expires_at > $2 ORDER BY created_at DESC", email, now)
+}
+
+func (r *InviteRepository) find(ctx context.Context, tail string, args ...interface{}) ([]*models.WorkspaceInvite, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+inviteColumns+" FROM workspace_invites"+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []*models.WorkspaceInvite
+	for rows.Next() {
+		var i models.WorkspaceInvite
+		var role string
+		err := rows.Scan(objectID{&i.ID}, objectID{&i.WorkspaceID}, &i.Email, &role, &i.Token,
+			objectID{&i.InvitedBy}, &i.ExpiresAt, &i.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		i.Role = models.WorkspaceRole(role)
+		invites = append(invites, &i)
+	}
+	return invites, rows.Err()
+}
+
+// Delete removes an invite
+func (r *InviteRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx, "DELETE FROM workspace_invites WHERE id = $1", id.Hex()))
+}
+
+// DeleteInWorkspace removes an invite of a workspace
+func (r *InviteRepository) DeleteInWorkspace(ctx context.Context, workspaceID, id primitive.ObjectID) error {
+	return affected(r.db.ExecContext(ctx,
+		"DELETE FROM workspace_invites WHERE id = $1 AND workspace_id = $2", id.Hex(), workspaceID.Hex()))
+}
+
+// DeleteAllForWorkspace removes every invite of a workspace
+func (r *InviteRepository) DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM workspace_invites WHERE workspace_id = $1", workspaceID.Hex())
+	return err
+}
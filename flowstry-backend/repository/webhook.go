@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookUpdate lists the webhook fields to change; nil fields are left alone.
+// Enabling a webhook clears its failure count and disabled reason.
+type WebhookUpdate struct {
+	URL             *string
+	Description     *string
+	Events          []models.EventType
+	Enabled         *bool
+	EncryptedSecret []byte
+	UpdatedAt       time.Time
+}
+
+// WebhookRepository persists the webhooks of workspaces
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Get(ctx context.Context, workspaceID, id primitive.ObjectID) (*models.Webhook, error)
+	// List returns the webhooks of a workspace, oldest first
+	List(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.Webhook, error)
+	// ListSubscribed returns the enabled webhooks of a workspace that subscribe to an event type
+	ListSubscribed(ctx context.Context, workspaceID primitive.ObjectID, eventType models.EventType) ([]*models.Webhook, error)
+	Update(ctx context.Context, workspaceID, id primitive.ObjectID, update *WebhookUpdate) error
+	// RecordSuccess clears the failure count of a webhook
+	RecordSuccess(ctx context.Context, id primitive.ObjectID) error
+	// RecordFailure counts a failed attempt. When the count reaches maxFailures an enabled webhook is
+	// disabled with the reason; it reports whether this call disabled it.
+	RecordFailure(ctx context.Context, id primitive.ObjectID, maxFailures int, reason string, now time.Time) (bool, error)
+	Delete(ctx context.Context, workspaceID, id primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// WebhookAttempt is the outcome of one delivery attempt
+type WebhookAttempt struct {
+	Status         models.WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	ResponseBody   string
+	LastError      string
+	// CompletedAt is set once the delivery succeeded or failed for good
+	CompletedAt *time.Time
+}
+
+// WebhookDeliveryRepository persists webhook deliveries; pending deliveries double as the delivery queue
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	Get(ctx context.Context, workspaceID, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error)
+	// List pages through the deliveries of a webhook; only SortByCreated is supported
+	List(ctx context.Context, workspaceID, webhookID primitive.ObjectID, filter *models.WebhookDeliveryFilter, page *PageQuery) ([]*models.WebhookDelivery, error)
+	// Due returns up to limit pending deliveries whose next attempt is at or before now, oldest attempt first
+	Due(ctx context.Context, now time.Time, limit int64) ([]*models.WebhookDelivery, error)
+	// Claim leases a due delivery until leaseUntil by moving its next attempt there, so other
+	// instances skip it while it is being sent. It returns ErrNotFound when the delivery is no longer due.
+	Claim(ctx context.Context, id primitive.ObjectID, now, leaseUntil time.Time) error
+	// RecordAttempt stores the outcome of an attempt
+	RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt *WebhookAttempt) error
+	// DeleteCompletedBefore prunes deliveries completed before a time and returns how many were deleted
+	DeleteCompletedBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteForWebhook(ctx context.Context, workspaceID, webhookID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/modules/workspace/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkspaceUpdate lists the workspace fields to change; nil fields are left alone
+type WorkspaceUpdate struct {
+	Name                 *string
+	Description          *string
+	ContentSearchEnabled *bool
+	TrashRetentionDays   *int
+	UpdatedAt            time.Time
+}
+
+// WorkspaceRepository persists workspaces
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *models.Workspace) error
+	// CreateWithOwner creates a workspace together with its owner's membership; either both are stored or neither
+	CreateWithOwner(ctx context.Context, workspace *models.Workspace, owner *models.WorkspaceMember) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error)
+	ListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Workspace, error)
+	ListOwnedBy(ctx context.Context, userID primitive.ObjectID) ([]*models.Workspace, error)
+	Update(ctx context.Context, id primitive.ObjectID, update *WorkspaceUpdate) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// MemberQuery narrows a member listing
+type MemberQuery struct {
+	// Roles keeps members holding one of these roles (any role when empty)
+	Roles         []models.WorkspaceRole
+	UserID        *primitive.ObjectID
+	ExcludeUserID *primitive.ObjectID
+	Joined        models.DateRange
+	// Prefix keeps members whose email, name or a word of their name starts with it, case-insensitively
+	Prefix string
+}
+
+// MemberDetail is a member joined with its user's profile
+type MemberDetail struct {
+	models.WorkspaceMember
+	Email     string
+	Name      string
+	AvatarURL string
+}
+
+// MemberRepository persists workspace memberships. A user is a member of a workspace at most once.
+type MemberRepository interface {
+	Create(ctx context.Context, member *models.WorkspaceMember) error
+	Get(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error)
+	// List pages through members with their user profiles; SortByName orders by the user's name.
+	// Members whose user no longer exists are skipped.
+	List(ctx context.Context, workspaceID primitive.ObjectID, query *MemberQuery, page *PageQuery) ([]*MemberDetail, error)
+	ListForUser(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceMember, error)
+	Count(ctx context.Context, workspaceID primitive.ObjectID) (int64, error)
+	UpdateRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.WorkspaceRole) error
+	Delete(ctx context.Context, workspaceID, userID primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
+
+// InviteQuery narrows a pending invite listing
+type InviteQuery struct {
+	// ExpiresAfter keeps invites still valid at this time
+	ExpiresAfter time.Time
+	InvitedBy    *primitive.ObjectID
+	Created      models.DateRange
+}
+
+// InviteRepository persists workspace invites. Tokens are unique, as are (workspace, email) pairs.
+type InviteRepository interface {
+	Create(ctx context.Context, invite *models.WorkspaceInvite) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.WorkspaceInvite, error)
+	GetByToken(ctx context.Context, token string) (*models.WorkspaceInvite, error)
+	GetByEmail(ctx context.Context, workspaceID primitive.ObjectID, email string) (*models.WorkspaceInvite, error)
+	// List pages through a workspace's invites; SortByName orders by email
+	List(ctx context.Context, workspaceID primitive.ObjectID, query *InviteQuery, page *PageQuery) ([]*models.WorkspaceInvite, error)
+	// ListForEmail returns the invites addressed to an email that are still valid at now, newest first
+	ListForEmail(ctx context.Context, email string, now time.Time) ([]*models.WorkspaceInvite, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteInWorkspace deletes an invite only if it belongs to the workspace
+	DeleteInWorkspace(ctx context.Context, workspaceID, id primitive.ObjectID) error
+	DeleteAllForWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+}
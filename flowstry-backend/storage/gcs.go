@@ -1,8 +1,6 @@
 package storage
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -36,34 +34,9 @@ func (g *GCSClient) UploadFile(ctx context.Context, objectName string, data []by
 	bucket := g.client.Bucket(g.bucketName)
 	obj := bucket.Object(objectName)
 
-	var uploadData []byte
-	var compressedSize int64
-
-	// Check if data is already gzip compressed (magic bytes: 0x1f 0x8b)
-	isAlreadyCompressed := len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
-
-	if isAlreadyCompressed {
-		// Data is already compressed, use as-is
-		uploadData = data
-		compressedSize = int64(len(data))
-	} else {
-		// Compress data with maximum compression
-		var compressedBuf bytes.Buffer
-		gzipWriter, err := gzip.NewWriterLevel(&compressedBuf, gzip.BestCompression)
-		if err != nil {
-			return "", 0, fmt.Errorf("failed to create gzip writer: %w", err)
-		}
-
-		if _, err := gzipWriter.Write(data); err != nil {
-			return "", 0, fmt.Errorf("failed to write to gzip: %w", err)
-		}
-
-		if err := gzipWriter.Close(); err != nil {
-			return "", 0, fmt.Errorf("failed to close gzip writer: %w", err)
-		}
-
-		uploadData = compressedBuf.Bytes()
-		compressedSize = int64(len(uploadData))
+	uploadData, err := compress(data)
+	if err != nil {
+		return "", 0, err
 	}
 
 	// Write to GCS
@@ -79,7 +52,7 @@ func (g *GCSClient) UploadFile(ctx context.Context, objectName string, data []by
 		return "", 0, fmt.Errorf("failed to close GCS writer: %w", err)
 	}
 
-	return objectName, compressedSize, nil
+	return objectName, int64(len(uploadData)), nil
 }
 
 // DownloadFile downloads a file from GCS and returns raw bytes (no decompression)
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flowstry/flowstry-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalStorage keeps files in a directory on disk, for single-node deployments without GCS.
+// Signed URLs point at this API, which serves them through the routes registered by SetupRoutes.
+type LocalStorage struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+}
+
+// localRoutePrefix is the path under which signed URLs are served
+const localRoutePrefix = "/files/"
+
+// fileMeta is stored next to each file, as GCS keeps object metadata
+type fileMeta struct {
+	ContentType     string `json:"content_type"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+}
+
+// NewLocalStorage creates a local storage rooted at dir; publicURL is the externally reachable URL of this API
+func NewLocalStorage(dir, publicURL string, signingKey []byte) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &LocalStorage{
+		dir:        dir,
+		baseURL:    strings.TrimRight(publicURL, "/") + localRoutePrefix,
+		signingKey: signingKey,
+	}, nil
+}
+
+// path resolves an object name inside the storage directory
+func (l *LocalStorage) path(objectName string) (string, error) {
+	clean := filepath.Clean("/" + objectName)
+	if clean == "/" {
+		return "", errors.New("invalid object name")
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
+
+// UploadFile stores data with gzip compression
+// If data is already gzip compressed (starts with gzip magic bytes), it's stored as-is
+func (l *LocalStorage) UploadFile(ctx context.Context, objectName string, data []byte, contentType string) (string, int64, error) {
+	uploadData, err := compress(data)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := l.write(objectName, uploadData, fileMeta{ContentType: contentType, ContentEncoding: "gzip"}); err != nil {
+		return "", 0, err
+	}
+
+	return objectName, int64(len(uploadData)), nil
+}
+
+func (l *LocalStorage) write(objectName string, data []byte, meta fileMeta) error {
+	path, err := l.path(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path+".meta", metaData); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// DownloadFile returns the stored bytes (no decompression)
+func (l *LocalStorage) DownloadFile(ctx context.Context, objectName string) ([]byte, error) {
+	path, err := l.path(objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return data, nil
+}
+
+// DeleteFile removes a file and its metadata
+func (l *LocalStorage) DeleteFile(ctx context.Context, objectName string) error {
+	path, err := l.path(objectName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	if err := os.Remove(path + ".meta"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file metadata: %w", err)
+	}
+
+	return nil
+}
+
+// GetSignedURL generates a URL of this API that allows method on the object until expiry
+func (l *LocalStorage) GetSignedURL(ctx context.Context, objectName string, method string, contentType string, expiry time.Duration) (string, error) {
+	if _, err := l.path(objectName); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", l.sign(method, objectName, contentType, expires))
+
+	return l.baseURL + objectName + "?" + query.Encode(), nil
+}
+
+func (l *LocalStorage) sign(method, objectName, contentType, expires string) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(method + "\n" + objectName + "\n" + contentType + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks that a request carries a valid, unexpired signature for method on the object
+func (l *LocalStorage) verify(c *fiber.Ctx, method, objectName, contentType string) bool {
+	expires := c.Query("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+
+	expected := l.sign(method, objectName, contentType, expires)
+	return hmac.Equal([]byte(expected), []byte(c.Query("signature")))
+}
+
+// Close is a no-op; files are written synchronously
+func (l *LocalStorage) Close() error {
+	return nil
+}
+
+// SetupRoutes serves the signed URLs generated by GetSignedURL
+func (l *LocalStorage) SetupRoutes(app *fiber.App) {
+	files := app.Group(strings.TrimSuffix(localRoutePrefix, "/"))
+	files.Get("/*", l.serveFile)
+	files.Put("/*", l.receiveFile)
+}
+
+func (l *LocalStorage) serveFile(c *fiber.Ctx) error {
+	objectName := c.Params("*")
+	if !l.verify(c, fiber.MethodGet, objectName, "") {
+		return utils.Forbidden(c, "Invalid or expired signature")
+	}
+
+	path, err := l.path(objectName)
+	if err != nil {
+		return utils.BadRequest(c, err.Error())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return utils.NotFound(c, "File not found")
+		}
+		return utils.InternalError(c, "Failed to read file")
+	}
+
+	meta := fileMeta{ContentType: "application/octet-stream"}
+	if metaData, err := os.ReadFile(path + ".meta"); err == nil {
+		_ = json.Unmarshal(metaData, &meta)
+	}
+
+	c.Set(fiber.HeaderContentType, meta.ContentType)
+	if meta.ContentEncoding != "" {
+		c.Set(fiber.HeaderContentEncoding, meta.ContentEncoding)
+	}
+	return c.Send(data)
+}
+
+func (l *LocalStorage) receiveFile(c *fiber.Ctx) error {
+	objectName := c.Params("*")
+	contentType := c.Get(fiber.HeaderContentType)
+	if !l.verify(c, fiber.MethodPut, objectName, contentType) {
+		return utils.Forbidden(c, "Invalid or expired signature")
+	}
+
+	if err := l.write(objectName, c.Body(), fileMeta{ContentType: contentType}); err != nil {
+		return utils.InternalError(c, "Failed to store file")
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
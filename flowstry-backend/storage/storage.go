@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Storage stores diagram files and thumbnails by object name
+type Storage interface {
+	// UploadFile stores data gzip-compressed and returns the object name and stored size
+	UploadFile(ctx context.Context, objectName string, data []byte, contentType string) (string, int64, error)
+	// DownloadFile returns the stored (still compressed) bytes of an object
+	DownloadFile(ctx context.Context, objectName string) ([]byte, error)
+	DeleteFile(ctx context.Context, objectName string) error
+	// GetSignedURL returns a URL that allows method on the object until expiry
+	GetSignedURL(ctx context.Context, objectName string, method string, contentType string, expiry time.Duration) (string, error)
+	Close() error
+}
+
+// compress gzips data with maximum compression
+// If data is already gzip compressed (starts with gzip magic bytes), it's returned as-is
+func compress(data []byte) ([]byte, error) {
+	// Check if data is already gzip compressed (magic bytes: 0x1f 0x8b)
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		return data, nil
+	}
+
+	var compressedBuf bytes.Buffer
+	gzipWriter, err := gzip.NewWriterLevel(&compressedBuf, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+
+	if _, err := gzipWriter.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write to gzip: %w", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+
+	return compressedBuf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadOrCreateKey reads a hex-encoded key of size bytes from path, generating and saving a random one
+// on first use. created reports whether the key was just generated.
+func LoadOrCreateKey(path string, size int) (key []byte, created bool, err error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err = hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid key file %s: %w", path, err)
+		}
+		if len(key) != size {
+			return nil, false, fmt.Errorf("invalid key file %s: expected %d bytes, got %d", path, size, len(key))
+		}
+		return key, false, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	key = make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, false, err
+	}
+
+	// O_EXCL keeps a concurrently started process from overwriting a key that is already in use
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return LoadOrCreateKey(path, size)
+		}
+		return nil, false, err
+	}
+	if _, err := file.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		file.Close()
+		return nil, false, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, false, err
+	}
+	return key, true, nil
+}
@@ -8,6 +8,14 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
 	Message string      `json:"message,omitempty"`
+	// Pagination is set on cursor-paginated listings
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes where a paginated listing continues
+type Pagination struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
 }
 
 // SuccessResponse sends a success response
@@ -18,6 +26,18 @@ func SuccessResponse(c *fiber.Ctx, data interface{}) error {
 	})
 }
 
+// PaginatedResponse sends a success response for one page of a listing
+func PaginatedResponse(c *fiber.Ctx, data interface{}, nextCursor string) error {
+	return c.JSON(Response{
+		Success: true,
+		Data:    data,
+		Pagination: &Pagination{
+			NextCursor: nextCursor,
+			HasMore:    nextCursor != "",
+		},
+	})
+}
+
 // SuccessMessageResponse sends a success response with a message
 func SuccessMessageResponse(c *fiber.Ctx, message string) error {
 	return c.JSON(Response{
@@ -52,3 +52,15 @@ func SanitizeString(s string, maxLen int) string {
 	}
 	return s
 }
+
+var hexColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ValidateHexColor checks if a color is a #RRGGBB hex color
+func ValidateHexColor(color string) bool {
+	return hexColorRegex.MatchString(color)
+}
+
+// NormalizeTag lowercases a tag and collapses inner whitespace
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}